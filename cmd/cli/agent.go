@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"strings"
+	"syscall"
+
+	"github.com/quan-to/chevron/internal/localagent"
+	"github.com/quan-to/chevron/pkg/client"
+)
+
+// isGPGShim returns if the binary was called through a gpg named link, like chevron-gpg
+func isGPGShim(program string) bool {
+	name := path.Base(program)
+
+	return name == "gpg" || name == "gpg2" || strings.HasPrefix(name, "chevron-gpg")
+}
+
+// RunGPGShim runs the gpg compatible command line that talks to the agent
+func RunGPGShim(args []string) {
+	os.Exit(localagent.RunGPG(localagent.DefaultSocketPath(), args, os.Stdin, os.Stdout, os.Stderr))
+}
+
+// RunAgent runs the signing agent until it receives SIGINT or SIGTERM
+func RunAgent(socketPath, defaultKey, serverURL, token string) {
+	if serverURL == "" {
+		panic("No server URL for the agent. Use --server-url, CHEVRON_URL or a profile\n")
+	}
+
+	agent := localagent.MakeAgent(nil, client.MakeClient(nil, serverURL, client.WithToken(token)), defaultKey)
+
+	if err := agent.Listen(socketPath); err != nil {
+		panic(fmt.Sprintf("Error starting agent: %s\n", err))
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-stop
+		_ = agent.Close()
+	}()
+
+	_, _ = fmt.Fprintf(os.Stderr, "Chevron agent listening at %s\n", socketPath)
+
+	if err := agent.Serve(); err != nil {
+		panic(fmt.Sprintf("Error running agent: %s\n", err))
+	}
+}
@@ -8,14 +8,33 @@ import (
 	"os"
 	"strings"
 
-	"github.com/quan-to/chevron/internal/etc/magicbuilder"
+	"github.com/quan-to/chevron/internal/gnupg"
 	"github.com/quan-to/chevron/internal/tools"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
+// readKeyPassword returns the key password read from the file descriptor or keyPassword if the descriptor is -1
+func readKeyPassword(keyPassword string, keyPasswordFd int) string {
+	if keyPasswordFd == -1 {
+		return keyPassword
+	}
+
+	// Load from FD
+	_, _ = fmt.Fprintf(os.Stderr, "Reading key password from FD %d\n", keyPasswordFd)
+
+	f := os.NewFile(uintptr(keyPasswordFd), "kp")
+	d, err := ioutil.ReadAll(f)
+	if err != nil {
+		panic(err)
+	}
+
+	return strings.Trim(string(d), "\n\r")
+}
+
 func ImportKey(filename, keyPassword string, keyPasswordFd int) {
 	var data []byte
 	var err error
-	pgpMan := magicbuilder.MakePGP(nil, mem)
+	pgpMan := getPGPManager()
 	pgpMan.LoadKeys(ctx)
 
 	if filename == "-" {
@@ -49,18 +68,7 @@ func ImportKey(filename, keyPassword string, keyPasswordFd int) {
 		panic(fmt.Sprintf("Error loading file %s: %s\n", filename, err))
 	}
 
-	if keyPasswordFd != -1 {
-		// Load from FD
-		_, _ = fmt.Fprintf(os.Stderr, "Reading key password from FD %d\n", keyPasswordFd)
-
-		f := os.NewFile(uintptr(keyPasswordFd), "kp")
-		d, err := ioutil.ReadAll(f)
-		if err != nil {
-			panic(err)
-		}
-
-		keyPassword = strings.Trim(string(d), "\n\r")
-	}
+	keyPassword = readKeyPassword(keyPassword, keyPasswordFd)
 
 	fps, _ := tools.GetFingerPrintsFromKey(string(data))
 
@@ -94,3 +102,42 @@ func ImportKey(filename, keyPassword string, keyPasswordFd int) {
 		_, _ = fmt.Fprintf(os.Stderr, "Cannot import public key %s: %s\n", v, err)
 	}
 }
+
+// ImportGnuPGHome imports the public and private keys of a GnuPG home directory and prints a report.
+// The key password is used for every private key. Without it the password is asked for each private key when running in a terminal
+func ImportGnuPGHome(home, keyPassword string, keyPasswordFd int) {
+	pgpMan := getPGPManager()
+	pgpMan.LoadKeys(ctx)
+
+	keyPassword = readKeyPassword(keyPassword, keyPasswordFd)
+	interactive := terminal.IsTerminal(int(os.Stdin.Fd()))
+
+	passphrase := func(fingerPrint, identifier string) (string, error) {
+		if keyPassword != "" || !interactive {
+			return keyPassword, nil
+		}
+
+		_, _ = fmt.Fprintf(os.Stderr, "Private key %s (%s). Leave the password empty to skip it.\n", fingerPrint, identifier)
+		return askPassword(), nil
+	}
+
+	report, err := gnupg.MakeImporter(nil, pgpMan, passphrase).Import(ctx, home)
+	if err != nil {
+		panic(fmt.Sprintf("Error importing GnuPG home %s: %s\n", home, err))
+	}
+
+	fmt.Printf("%-10s %-8s %-18s %-40s %s\n", "Status", "Type", "Fingerprint", "Identifier", "Reason")
+	for _, r := range report.Results {
+		keyType := "public"
+		if r.Private {
+			keyType = "private"
+		}
+		fmt.Printf("%-10s %-8s %-18s %-40s %s\n", r.Status, keyType, r.FingerPrint, r.Identifier, r.Reason)
+	}
+
+	fmt.Printf("Imported %d, skipped %d, failed %d.\n", report.Count(gnupg.StatusImported), report.Count(gnupg.StatusSkipped), report.Count(gnupg.StatusFailed))
+
+	if report.Count(gnupg.StatusFailed) > 0 {
+		os.Exit(1)
+	}
+}
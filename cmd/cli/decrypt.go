@@ -7,15 +7,13 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
-
-	"github.com/quan-to/chevron/internal/etc/magicbuilder"
 )
 
 func Decrypt(input, output string) {
 	var err error
 	var data []byte
 
-	pgpMan := magicbuilder.MakePGP(nil, mem)
+	pgpMan := getPGPManager()
 	pgpMan.LoadKeys(ctx)
 
 	if input == "-" {
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DeleteKey removes the specified key from the default key backend
+func DeleteKey(name string, yes bool) {
+	pgpMan := getPGPManager()
+	pgpMan.LoadKeys(ctx)
+
+	kInfo := findKey(pgpMan, name)
+
+	if kInfo == nil {
+		panic(fmt.Sprintf("Cannot find key with \"%s\"\n", name))
+	}
+
+	if !yes {
+		keyType := "public"
+		if kInfo.ContainsPrivateKey {
+			keyType = "private"
+		}
+
+		_, _ = fmt.Fprintf(os.Stderr, "Delete %s key %s (%s)? [y/N] ", keyType, kInfo.FingerPrint, kInfo.Identifier)
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if strings.ToLower(strings.TrimSpace(answer)) != "y" {
+			_, _ = fmt.Fprintln(os.Stderr, "Aborted")
+			os.Exit(1)
+		}
+	}
+
+	err := pgpMan.DeleteKey(ctx, kInfo.FingerPrint)
+	if err != nil {
+		panic(fmt.Sprintf("Error deleting key %s: %s\n", kInfo.FingerPrint, err))
+	}
+
+	_, _ = fmt.Fprintf(os.Stderr, "Key %s deleted\n", kInfo.FingerPrint)
+}
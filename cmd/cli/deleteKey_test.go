@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/quan-to/chevron/test"
+)
+
+func TestDeleteKey(t *testing.T) {
+	pgpMan := getPGPManager()
+	err := pgpMan.SaveKey(test.TestPublicKey2FingerPrint, test.TestPublicKey2, nil)
+	if err != nil {
+		t.Fatalf("Error saving the test key: %s", err)
+	}
+
+	pgpMan.LoadKeys(ctx)
+	if findKey(pgpMan, test.TestPublicKey2FingerPrint) == nil {
+		t.Fatalf("Expected the key %s to be loaded", test.TestPublicKey2FingerPrint)
+	}
+
+	DeleteKey(test.TestPublicKey2FingerPrint, true)
+
+	pgpMan = getPGPManager()
+	pgpMan.LoadKeys(ctx)
+	if findKey(pgpMan, test.TestPublicKey2FingerPrint) != nil {
+		t.Errorf("Expected the key %s to be deleted", test.TestPublicKey2FingerPrint)
+	}
+
+	if findKey(pgpMan, test.TestKeyFingerprint) == nil {
+		t.Errorf("Expected the key %s to be kept", test.TestKeyFingerprint)
+	}
+
+	assertPanic(t, func() {
+		DeleteKey(test.TestPublicKey2FingerPrint, true)
+	}, "Expected a panic for an unknown key")
+}
@@ -2,34 +2,16 @@ package main
 
 import (
 	"fmt"
-	"os"
 	"strings"
-	"syscall"
-
-	"github.com/quan-to/chevron/internal/etc/magicbuilder"
-	"github.com/quan-to/chevron/pkg/models"
-
-	"golang.org/x/crypto/ssh/terminal"
 )
 
 // ExportKey exports the specified public / secret key
 func ExportKey(name, password string, secret bool) {
 	var err error
-	pgpMan := magicbuilder.MakePGP(nil, mem)
+	pgpMan := getPGPManager()
 	pgpMan.LoadKeys(ctx)
 
-	// First Search the key
-	keys := pgpMan.GetLoadedKeys()
-
-	var kInfo *models.KeyInfo
-
-	for _, v := range keys {
-		if strings.Contains(v.FingerPrint, strings.ToUpper(name)) || strings.Contains(strings.ToLower(v.Identifier), strings.ToLower(name)) {
-			// Thats our key!
-			kInfo = &v
-			break
-		}
-	}
+	kInfo := findKey(pgpMan, name)
 
 	if kInfo == nil {
 		panic(fmt.Sprintf("Cannot find key with \"%s\"\n", name))
@@ -43,13 +25,7 @@ func ExportKey(name, password string, secret bool) {
 
 	if secret {
 		if password == "" {
-			_, _ = fmt.Fprint(os.Stderr, "Please enter the password: ")
-			bytePassword, err := terminal.ReadPassword(int(syscall.Stdin))
-			if err != nil {
-				panic(fmt.Sprintf("Error reading password: %s", err))
-			}
-			password = string(bytePassword)
-			fmt.Println("")
+			password = askPassword()
 		}
 
 		k, err = pgpMan.GetPrivateKeyASCII(ctx, kInfo.FingerPrint, password)
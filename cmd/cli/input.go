@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/quan-to/chevron/pkg/interfaces"
+	"github.com/quan-to/chevron/pkg/models"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// readInput reads the whole content of the file. Use - to read from stdin
+func readInput(filename string) ([]byte, error) {
+	if filename == "-" {
+		_, _ = fmt.Fprintf(os.Stderr, "Reading from stdin:\n")
+		return ioutil.ReadAll(os.Stdin)
+	}
+
+	return ioutil.ReadFile(filename)
+}
+
+// writeOutput writes the data to the file. Use - to write to stdout
+func writeOutput(filename string, data []byte) error {
+	if filename == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+
+	return ioutil.WriteFile(filename, data, 0644)
+}
+
+// findKey searches the loaded keys for a fingerprint or identifier containing name
+func findKey(pgpMan interfaces.PGPManager, name string) *models.KeyInfo {
+	for _, v := range pgpMan.GetLoadedKeys() {
+		if strings.Contains(v.FingerPrint, strings.ToUpper(name)) || strings.Contains(strings.ToLower(v.Identifier), strings.ToLower(name)) {
+			k := v
+			return &k
+		}
+	}
+
+	return nil
+}
+
+// askPassword prompts the user for the key password
+func askPassword() string {
+	_, _ = fmt.Fprint(os.Stderr, "Please enter the password: ")
+	bytePassword, err := terminal.ReadPassword(int(syscall.Stdin))
+	if err != nil {
+		panic(fmt.Sprintf("Error reading password: %s", err))
+	}
+	_, _ = fmt.Fprintln(os.Stderr, "")
+
+	return string(bytePassword)
+}
@@ -2,13 +2,11 @@ package main
 
 import (
 	"fmt"
-
-	"github.com/quan-to/chevron/internal/etc/magicbuilder"
 )
 
 // ListKeys list the Public / Private keys stored in the default backend
 func ListKeys() {
-	pgpMan := magicbuilder.MakePGP(nil, mem)
+	pgpMan := getPGPManager()
 	pgpMan.LoadKeys(ctx)
 
 	keys := pgpMan.GetLoadedKeys()
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/quan-to/chevron/internal/backendmigrate"
+)
+
+// MigrateBackend copies all keys and their metadata from one key backend to another
+func MigrateBackend(opts backendmigrate.Options) {
+	src, err := backendmigrate.MakeBackend(nil, opts.From)
+	if err != nil {
+		panic(fmt.Sprintf("Error initializing source backend: %s", err))
+	}
+
+	dst, err := backendmigrate.MakeBackend(nil, opts.To)
+	if err != nil {
+		panic(fmt.Sprintf("Error initializing destination backend: %s", err))
+	}
+
+	if opts.DryRun {
+		_, _ = fmt.Fprintf(os.Stderr, "Dry run enabled. Nothing will be written to %s\n", dst.Path())
+	}
+
+	_, _ = fmt.Fprintf(os.Stderr, "Migrating keys from %s to %s\n", src.Path(), dst.Path())
+
+	result, err := backendmigrate.Migrate(src, dst, opts, func(name string, skipped bool, err error) {
+		switch {
+		case err != nil:
+			_, _ = fmt.Fprintf(os.Stderr, "FAILED   %s: %s\n", name, err)
+		case skipped:
+			_, _ = fmt.Fprintf(os.Stderr, "SKIPPED  %s: already in destination\n", name)
+		case opts.DryRun:
+			_, _ = fmt.Fprintf(os.Stderr, "DRY-RUN  %s\n", name)
+		default:
+			_, _ = fmt.Fprintf(os.Stderr, "MIGRATED %s\n", name)
+		}
+	})
+	if err != nil {
+		panic(fmt.Sprintf("Error migrating keys: %s", err))
+	}
+
+	_, _ = fmt.Fprintf(os.Stderr, "Done. %d migrated, %d skipped, %d failed.\n", result.Migrated, result.Skipped, result.Failed)
+
+	if result.Failed > 0 {
+		os.Exit(1)
+	}
+}
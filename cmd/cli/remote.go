@@ -0,0 +1,384 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/quan-to/chevron/internal/tools"
+	"github.com/quan-to/chevron/pkg/client"
+	"github.com/quan-to/chevron/pkg/models"
+	"github.com/quan-to/chevron/pkg/openpgp"
+	"github.com/quan-to/chevron/pkg/openpgp/armor"
+	"github.com/quan-to/chevron/pkg/openpgp/packet"
+)
+
+// errRemoteNotSupported is returned by the operations that cannot be done through the REST API
+var errRemoteNotSupported = errors.New("operation not supported in remote mode")
+
+// remoteProfile is an entry of the profile file
+type remoteProfile struct {
+	URL   string `json:"url"`
+	Token string `json:"token"`
+}
+
+// defaultProfileFile returns the default location of the profile file
+func defaultProfileFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".chevron-cli.json"
+	}
+
+	return path.Join(home, ".chevron-cli.json")
+}
+
+// loadRemoteProfile reads the named profile from the profile file.
+// A missing profile file is not an error
+func loadRemoteProfile(filename, name string) (*remoteProfile, error) {
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return &remoteProfile{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := map[string]remoteProfile{}
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("invalid profile file %s: %v", filename, err)
+	}
+
+	profile, ok := profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile %q not found in %s", name, filename)
+	}
+
+	return &profile, nil
+}
+
+// stagedKey is a key read by LoadKey that was not sent to the server yet
+type stagedKey struct {
+	armored string
+	entity  *openpgp.Entity
+}
+
+// remotePGP implements interfaces.PGPManager over the Chevron REST API
+type remotePGP struct {
+	sync.Mutex
+	api    *client.Client
+	staged map[string]stagedKey
+}
+
+// makeRemotePGP creates a PGP Manager that runs the operations in the Chevron server at baseURL
+func makeRemotePGP(baseURL, token string) *remotePGP {
+	return &remotePGP{
+		api:    client.MakeClient(nil, baseURL, client.WithToken(token)),
+		staged: map[string]stagedKey{},
+	}
+}
+
+func (r *remotePGP) getStaged(fingerprint string) (stagedKey, bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	key, ok := r.staged[r.FixFingerPrint(fingerprint)]
+
+	return key, ok
+}
+
+// LoadKeys does nothing. The keys are loaded by the server
+func (r *remotePGP) LoadKeys(ctx context.Context) {}
+
+// LoadKeyWithMetadata stages the key to be sent to the server by SaveKey. The metadata is ignored
+func (r *remotePGP) LoadKeyWithMetadata(ctx context.Context, armoredKey, metadata string) (int, error) {
+	return r.LoadKey(ctx, armoredKey)
+}
+
+// LoadKey stages the key to be sent to the server by SaveKey
+// Returns the number of private keys
+func (r *remotePGP) LoadKey(ctx context.Context, armoredKey string) (int, error) {
+	entities, err := tools.ReadKey(armoredKey)
+	if err != nil {
+		return 0, err
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	privateKeys := 0
+	for _, e := range entities {
+		key := stagedKey{
+			armored: armoredKey,
+			entity:  e,
+		}
+
+		r.staged[tools.ByteFingerPrint2FP16(e.PrimaryKey.Fingerprint[:])] = key
+		for _, sub := range e.Subkeys {
+			r.staged[tools.ByteFingerPrint2FP16(sub.PublicKey.Fingerprint[:])] = key
+		}
+
+		if e.PrivateKey != nil {
+			privateKeys++
+		}
+	}
+
+	return privateKeys, nil
+}
+
+// FixFingerPrint fixes and trims the fingerprint to 16 Char Hex
+func (r *remotePGP) FixFingerPrint(fingerprint string) string {
+	return tools.FPto16(fingerprint)
+}
+
+// IsKeyLocked returns true unless the server reports the private key as decrypted
+func (r *remotePGP) IsKeyLocked(fingerprint string) bool {
+	info := r.GetPrivateKeyInfo(context.Background(), fingerprint)
+
+	return info == nil || !info.PrivateKeyIsDecrypted
+}
+
+// UnlockKey unlocks the key in the server
+func (r *remotePGP) UnlockKey(ctx context.Context, fingerprint, password string) error {
+	return r.api.UnlockKey(ctx, models.GPGUnlockKeyData{
+		FingerPrint: fingerprint,
+		Password:    password,
+	})
+}
+
+func keyInfosOrEmpty(name string, keys []models.KeyInfo, err error) []models.KeyInfo {
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error fetching %s: %s\n", name, err)
+		return make([]models.KeyInfo, 0)
+	}
+
+	return keys
+}
+
+// GetLoadedPrivateKeys returns the private keys loaded in the server
+func (r *remotePGP) GetLoadedPrivateKeys(ctx context.Context) []models.KeyInfo {
+	keys, err := r.api.PrivateKeys(ctx)
+
+	return keyInfosOrEmpty("private keys", keys, err)
+}
+
+// GetLoadedKeys returns the private keys loaded in the server followed by its cached public keys
+func (r *remotePGP) GetLoadedKeys() []models.KeyInfo {
+	ctx := context.Background()
+	keys := r.GetLoadedPrivateKeys(ctx)
+
+	seen := map[string]bool{}
+	for _, k := range keys {
+		seen[k.FingerPrint] = true
+	}
+
+	for _, k := range r.GetCachedKeys(ctx) {
+		if !seen[k.FingerPrint] {
+			keys = append(keys, k)
+		}
+	}
+
+	return keys
+}
+
+// SaveKey sends the key to the server. Private keys are sent with its password and stored in the server key backend
+func (r *remotePGP) SaveKey(fingerprint, armoredData string, password interface{}) error {
+	ctx := context.Background()
+
+	if password != nil {
+		_, err := r.api.AddPrivateKey(ctx, models.KeyRingAddPrivateKeyData{
+			EncryptedPrivateKey: armoredData,
+			SaveToDisk:          true,
+			Password:            password,
+		})
+		return err
+	}
+
+	return r.api.AddKey(ctx, models.SKSAddKey{
+		PublicKey: armoredData,
+	})
+}
+
+// DeleteKey removes the private key from the server
+func (r *remotePGP) DeleteKey(ctx context.Context, fingerprint string) error {
+	_, err := r.api.DeletePrivateKey(ctx, models.KeyRingDeletePrivateKeyData{
+		FingerPrint: fingerprint,
+	})
+
+	return err
+}
+
+// SignData signs the data in the server. Only SHA512 is supported by the server
+func (r *remotePGP) SignData(ctx context.Context, fingerprint string, data []byte, hashAlgorithm crypto.Hash) (string, error) {
+	if hashAlgorithm != crypto.SHA512 {
+		return "", fmt.Errorf("the remote server only signs with SHA512")
+	}
+
+	return r.api.Sign(ctx, models.GPGSignData{
+		FingerPrint: fingerprint,
+		Base64Data:  base64.StdEncoding.EncodeToString(data),
+	})
+}
+
+// GetPublicKeyEntity returns the public key entity
+func (r *remotePGP) GetPublicKeyEntity(ctx context.Context, fingerprint string) *openpgp.Entity {
+	if key, ok := r.getStaged(fingerprint); ok {
+		return key.entity
+	}
+
+	armored, err := r.GetPublicKeyASCII(ctx, fingerprint)
+	if err != nil {
+		return nil
+	}
+
+	ent, err := tools.ReadKeyToEntity(armored)
+	if err != nil {
+		return nil
+	}
+
+	return ent
+}
+
+// GetPublicKey returns the public key
+func (r *remotePGP) GetPublicKey(ctx context.Context, fingerprint string) *packet.PublicKey {
+	ent := r.GetPublicKeyEntity(ctx, fingerprint)
+	if ent == nil {
+		return nil
+	}
+
+	return ent.PrimaryKey
+}
+
+// GetPublicKeyASCII returns the public key in ASCII Armored format
+func (r *remotePGP) GetPublicKeyASCII(ctx context.Context, fingerprint string) (string, error) {
+	if key, ok := r.getStaged(fingerprint); ok {
+		serialized := bytes.NewBuffer(nil)
+		if err := key.entity.Serialize(serialized); err != nil {
+			return "", err
+		}
+
+		buf := bytes.NewBuffer(nil)
+		w, err := armor.Encode(buf, openpgp.PublicKeyType, nil)
+		if err != nil {
+			return "", err
+		}
+		_, _ = w.Write(serialized.Bytes())
+		if err := w.Close(); err != nil {
+			return "", err
+		}
+
+		return buf.String(), nil
+	}
+
+	return r.api.SKSGetKey(ctx, fingerprint)
+}
+
+// GetPrivateKeyASCII returns a private key staged by LoadKey if the password is valid.
+// Private keys stored in the server cannot be exported
+func (r *remotePGP) GetPrivateKeyASCII(ctx context.Context, fingerprint, password string) (string, error) {
+	key, ok := r.getStaged(fingerprint)
+	if !ok || key.entity.PrivateKey == nil {
+		return "", fmt.Errorf("private keys cannot be exported from a remote server")
+	}
+
+	if key.entity.PrivateKey.Encrypted {
+		pk := *key.entity.PrivateKey
+		if err := pk.Decrypt([]byte(password)); err != nil {
+			return "", err
+		}
+	}
+
+	return key.armored, nil
+}
+
+// GetPrivateKeyASCIIReencrypt is not supported in remote mode
+func (r *remotePGP) GetPrivateKeyASCIIReencrypt(ctx context.Context, fingerprint, currentPassword, newPassword string) (string, error) {
+	return "", errRemoteNotSupported
+}
+
+// VerifySignatureStringData verifies the signature in the server
+func (r *remotePGP) VerifySignatureStringData(ctx context.Context, data string, signature string) (bool, error) {
+	return r.VerifySignature(ctx, []byte(data), signature)
+}
+
+// VerifySignature verifies the signature in the server
+func (r *remotePGP) VerifySignature(ctx context.Context, data []byte, signature string) (bool, error) {
+	err := r.api.VerifySignature(ctx, models.GPGVerifySignatureData{
+		Base64Data: base64.StdEncoding.EncodeToString(data),
+		Signature:  signature,
+	})
+
+	return err == nil, err
+}
+
+// GeneratePGPKey generates a key in the server. The key is not stored by the server
+func (r *remotePGP) GeneratePGPKey(ctx context.Context, identifier, password string, numBits int) (string, error) {
+	return r.api.GenerateKey(ctx, models.GPGGenerateKeyData{
+		Identifier: identifier,
+		Password:   password,
+		Bits:       numBits,
+	})
+}
+
+// Encrypt encrypts the data in the server
+func (r *remotePGP) Encrypt(ctx context.Context, filename, fingerprint string, data []byte, dataOnly bool) (string, error) {
+	return r.api.Encrypt(ctx, models.GPGEncryptData{
+		FingerPrint: fingerprint,
+		Base64Data:  base64.StdEncoding.EncodeToString(data),
+		Filename:    filename,
+		DataOnly:    dataOnly,
+	})
+}
+
+// Decrypt decrypts the data in the server with any of its unlocked private keys
+func (r *remotePGP) Decrypt(ctx context.Context, data string, dataOnly bool) (*models.GPGDecryptedData, error) {
+	return r.api.Decrypt(ctx, models.GPGDecryptData{
+		AsciiArmoredData: data,
+		DataOnly:         dataOnly,
+	})
+}
+
+// GetCachedKeys returns the public keys cached in the server
+func (r *remotePGP) GetCachedKeys(ctx context.Context) []models.KeyInfo {
+	keys, err := r.api.CachedKeys(ctx)
+
+	return keyInfosOrEmpty("cached keys", keys, err)
+}
+
+// SetKeysBase64Encoded does nothing. The storage format is defined by the server
+func (r *remotePGP) SetKeysBase64Encoded(bool) {}
+
+// MinKeyBits returns 0 since the minimum is enforced by the server
+func (r *remotePGP) MinKeyBits() int {
+	return 0
+}
+
+// GenerateTestKey is not supported in remote mode
+func (r *remotePGP) GenerateTestKey() (string, error) {
+	return "", errRemoteNotSupported
+}
+
+// GetPrivate returns nil since private keys never leave the server
+func (r *remotePGP) GetPrivate(ctx context.Context, fingerprint string) openpgp.EntityList {
+	return nil
+}
+
+// GetPrivateKeyInfo returns the information of the private key loaded in the server
+func (r *remotePGP) GetPrivateKeyInfo(ctx context.Context, fingerprint string) *models.KeyInfo {
+	fingerprint = r.FixFingerPrint(fingerprint)
+
+	for _, k := range r.GetLoadedPrivateKeys(ctx) {
+		if k.FingerPrint == fingerprint {
+			return &k
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/quan-to/chevron/internal/tools"
+	"github.com/quan-to/chevron/pkg/openpgp/armor"
+)
+
+// Signature output formats
+const (
+	signFormatArmored = "armored"
+	signFormatBinary  = "binary"
+	signFormatQuanto  = "quanto"
+)
+
+var signHashes = map[string]crypto.Hash{
+	"SHA256": crypto.SHA256,
+	"SHA384": crypto.SHA384,
+	"SHA512": crypto.SHA512,
+}
+
+// SignFile creates a detached signature of the input with the specified key
+func SignFile(input, output, name, password, format, hashName string) {
+	hash, ok := signHashes[strings.ToUpper(hashName)]
+	if !ok {
+		panic(fmt.Sprintf("Unsupported hash \"%s\"\n", hashName))
+	}
+
+	pgpMan := getPGPManager()
+	pgpMan.LoadKeys(ctx)
+
+	kInfo := findKey(pgpMan, name)
+
+	if kInfo == nil {
+		panic(fmt.Sprintf("Cannot find key with \"%s\"\n", name))
+	}
+
+	if !kInfo.ContainsPrivateKey {
+		panic(fmt.Sprintf("The key identified with \"%s\" does not have a private key (found fingerPrint: %s)\n", name, kInfo.FingerPrint))
+	}
+
+	if pgpMan.IsKeyLocked(kInfo.FingerPrint) {
+		if password == "" {
+			password = askPassword()
+		}
+
+		err := pgpMan.UnlockKey(ctx, kInfo.FingerPrint, password)
+		if err != nil {
+			panic(fmt.Sprintf("Error unlocking key %s: %s\n", kInfo.FingerPrint, err))
+		}
+	}
+
+	data, err := readInput(input)
+	if err != nil {
+		panic(fmt.Sprintf("Error reading %s: %s\n", input, err))
+	}
+
+	_, _ = fmt.Fprintf(os.Stderr, "Signing with %s\n", kInfo.FingerPrint)
+
+	signature, err := pgpMan.SignData(ctx, kInfo.FingerPrint, data, hash)
+	if err != nil {
+		panic(fmt.Sprintf("Error signing data: %s\n", err))
+	}
+
+	var out []byte
+
+	switch format {
+	case signFormatBinary:
+		block, err := armor.Decode(strings.NewReader(signature))
+		if err != nil {
+			panic(err)
+		}
+		out, err = ioutil.ReadAll(block.Body)
+		if err != nil {
+			panic(err)
+		}
+	case signFormatQuanto:
+		out = []byte(tools.GPG2Quanto(signature, kInfo.FingerPrint, strings.ToUpper(hashName)) + "\n")
+	default:
+		out = []byte(signature + "\n")
+	}
+
+	err = writeOutput(output, out)
+	if err != nil {
+		panic(fmt.Sprintf("Error writing %s: %s\n", output, err))
+	}
+}
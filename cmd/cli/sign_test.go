@@ -0,0 +1,88 @@
+package main
+
+import (
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/quan-to/chevron/pkg/openpgp/armor"
+	"github.com/quan-to/chevron/test"
+)
+
+func TestSignFile(t *testing.T) {
+	input := writeTestFile(t, "sign-input.txt", []byte(test.TestSignatureData))
+	pgpMan := getPGPManager()
+	pgpMan.LoadKeys(ctx)
+
+	for _, format := range []string{signFormatArmored, signFormatBinary, signFormatQuanto} {
+		output := path.Join(testFolder, "sign-output."+format)
+		SignFile(input, output, test.TestKeyFingerprint, test.TestKeyPassword, format, "sha512")
+
+		data, err := ioutil.ReadFile(output)
+		if err != nil {
+			t.Fatalf("Error reading the %s signature: %s", format, err)
+		}
+
+		switch format {
+		case signFormatArmored:
+			if !strings.HasPrefix(string(data), "-----BEGIN PGP SIGNATURE-----") {
+				t.Errorf("Expected an armored signature, got %q", data)
+			}
+		case signFormatBinary:
+			if len(data) == 0 || data[0]&0x80 == 0 {
+				t.Errorf("Expected a binary signature packet, got %q", data)
+			}
+		case signFormatQuanto:
+			if !strings.HasPrefix(string(data), test.TestKeyFingerprint+"_SHA512_") {
+				t.Errorf("Expected a Quanto signature, got %q", data)
+			}
+		}
+
+		signature, err := signatureToArmored(data)
+		if err != nil {
+			t.Fatalf("Error converting the %s signature: %s", format, err)
+		}
+
+		valid, err := pgpMan.VerifySignature(ctx, []byte(test.TestSignatureData), signature)
+		if err != nil || !valid {
+			t.Errorf("Expected the %s signature to be valid. Got %v: %v", format, valid, err)
+		}
+	}
+}
+
+func TestSignFileErrors(t *testing.T) {
+	input := writeTestFile(t, "sign-input.txt", []byte(test.TestSignatureData))
+	output := path.Join(testFolder, "sign-output.sig")
+
+	assertPanic(t, func() {
+		SignFile(input, output, test.TestKeyFingerprint, test.TestKeyPassword, signFormatArmored, "md5")
+	}, "Expected a panic for an unsupported hash")
+
+	assertPanic(t, func() {
+		SignFile(input, output, "not a key", test.TestKeyPassword, signFormatArmored, "sha512")
+	}, "Expected a panic for an unknown key")
+
+	assertPanic(t, func() {
+		SignFile(input, output, test.TestKeyFingerprint, "wrong password", signFormatArmored, "sha512")
+	}, "Expected a panic for a wrong password")
+
+	assertPanic(t, func() {
+		SignFile(path.Join(testFolder, "missing.txt"), output, test.TestKeyFingerprint, test.TestKeyPassword, signFormatArmored, "sha512")
+	}, "Expected a panic for a missing input")
+}
+
+// armoredBody returns the packet bytes of an armored block
+func armoredBody(t *testing.T, armored string) []byte {
+	block, err := armor.Decode(strings.NewReader(armored))
+	if err != nil {
+		t.Fatalf("Error decoding %q: %s", armored, err)
+	}
+
+	data, err := ioutil.ReadAll(block.Body)
+	if err != nil {
+		t.Fatalf("Error reading %q: %s", armored, err)
+	}
+
+	return data
+}
@@ -2,10 +2,16 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
 
+	"github.com/quan-to/chevron/internal/backendmigrate"
+	"github.com/quan-to/chevron/internal/config"
+	"github.com/quan-to/chevron/internal/etc/magicbuilder"
+	"github.com/quan-to/chevron/internal/localagent"
 	"github.com/quan-to/chevron/internal/tools"
 	"github.com/quan-to/chevron/pkg/database/memory"
+	"github.com/quan-to/chevron/pkg/interfaces"
 
 	"github.com/quan-to/slog"
 	"gopkg.in/alecthomas/kingpin.v2"
@@ -13,10 +19,32 @@ import (
 
 var ctx = context.Background()
 var mem *memory.DbDriver
+var remote *remotePGP
+
+// getPGPManager returns the remote PGP Manager in remote mode or the local one otherwise
+func getPGPManager() interfaces.PGPManager {
+	if remote != nil {
+		return remote
+	}
+
+	return magicbuilder.MakePGP(nil, mem)
+}
 
 func main() {
+	if isGPGShim(os.Args[0]) {
+		RunGPGShim(os.Args[1:])
+	}
+
 	debugMode := kingpin.Flag("debug", "Enable debug mode").Bool()
 
+	// region Remote
+	remoteMode := kingpin.Flag("remote", "Run sign, verify, encrypt, decrypt, list-keys, export, import and delete-key in a Chevron server").Envar("CHEVRON_REMOTE").Bool()
+	remoteURL := kingpin.Flag("server-url", "Chevron server URL for remote mode").Envar("CHEVRON_URL").String()
+	remoteToken := kingpin.Flag("token", "Bearer token sent to the Chevron server in remote mode").Envar("CHEVRON_TOKEN").String()
+	remoteProfileName := kingpin.Flag("profile", "Profile with the server URL and token for remote mode").Envar("CHEVRON_PROFILE").Default("default").String()
+	remoteProfileFile := kingpin.Flag("profile-file", "JSON file with the remote mode profiles").Envar("CHEVRON_PROFILE_FILE").Default(defaultProfileFile()).String()
+	// endregion
+
 	// region Generate
 	gen := kingpin.Command("gen", "Generate GPG Key")
 	genBits := gen.Flag("bits", "Number of bits").Default("4096").Uint16()
@@ -56,6 +84,7 @@ func main() {
 	importInput := cmdImport.Flag("input", "Filename of the input (use - to stdin)").Default("-").String()
 	keyPassword := cmdImport.Flag("keyPassword", "Key Password (required only for private keys)").Default("").String()
 	keyPasswordFd := cmdImport.Flag("keyPasswordFd", "File Descriptor for Key Password input").Default("-1").Int()
+	importGnuPGHome := cmdImport.Flag("gnupg-home", "Import all keys from a GnuPG home directory (pubring.kbx, pubring.gpg and private-keys-v1.d) instead of the input").String()
 	// endregion
 
 	// region Decrypt
@@ -64,8 +93,61 @@ func main() {
 	decryptOutput := decrypt.Flag("output", "Filename of the output (use - to stdout)").Default("-").String()
 	// endregion
 
+	// region Sign
+	sign := kingpin.Command("sign", "Create a detached signature")
+	signKey := sign.Arg("fingerPrint or email", "Finger Print or email for the key to sign with").Required().String()
+	signInput := sign.Flag("input", "Filename of the input (use - to stdin)").Default("-").String()
+	signOutput := sign.Flag("output", "Filename of the output (use - to stdout)").Default("-").String()
+	signFormat := sign.Flag("format", "Signature format").Default(signFormatArmored).Enum(signFormatArmored, signFormatBinary, signFormatQuanto)
+	signHash := sign.Flag("hash", "Hash algorithm").Default("SHA512").Enum("SHA256", "SHA384", "SHA512")
+	signPassword := sign.Flag("password", "Key Password (if not provided, it will be prompted)").Default("").String()
+	// endregion
+
+	// region Verify
+	verify := kingpin.Command("verify", "Verify a detached signature. Exits with status 1 if the signature is not valid")
+	verifySignature := verify.Arg("signature", "Filename of the signature in GPG (armored or binary) or Quanto format").Required().String()
+	verifyInput := verify.Flag("input", "Filename of the signed data (use - to stdin)").Default("-").String()
+	// endregion
+
+	// region Delete Key
+	deleteKey := kingpin.Command("delete-key", "Delete a key from the default key backend")
+	deleteKeyName := deleteKey.Arg("fingerPrint or email", "Finger Print or email for the key you want to delete").Required().String()
+	deleteKeyYes := deleteKey.Flag("yes", "Do not ask for confirmation").Short('y').Bool()
+	// endregion
+
+	// region UI
+	_ = kingpin.Command("ui", "Interactive terminal interface to browse and manage keys")
+	// endregion
+
+	// region Agent
+	agent := kingpin.Command("agent", "Run a signing agent in a Unix socket that forwards the requests of the gpg shim to a Chevron server")
+	agentSocket := agent.Flag("socket", "Unix socket path").Envar(localagent.SocketEnvironmentVariable).Default(localagent.DefaultSocketPath()).String()
+	agentKey := agent.Flag("key", "Default key fingerprint or email when the gpg shim does not specify one").Envar("CHEVRON_AGENT_KEY").String()
+	// endregion
+
+	// region Backend Migrate
+	backend := kingpin.Command("backend", "Key Backend Operations")
+	backendMigrate := backend.Command("migrate", "Migrate private keys and metadata between key backends")
+	migrateFrom := backendMigrate.Flag("from", "Source backend type").Default("disk").Enum("disk", "vault")
+	migrateFromFolder := backendMigrate.Flag("from-folder", "Source folder (disk backend only)").Default(config.PrivateKeyFolder).String()
+	migrateFromPrefix := backendMigrate.Flag("from-prefix", "Source key prefix").Default(config.KeyPrefix).String()
+	migrateFromBase64 := backendMigrate.Flag("from-base64", "Keys in source backend are base64 encoded").Bool()
+	migrateTo := backendMigrate.Flag("to", "Destination backend type").Default("vault").Enum("disk", "vault")
+	migrateToFolder := backendMigrate.Flag("to-folder", "Destination folder (disk backend only)").Default(config.PrivateKeyFolder).String()
+	migrateToPrefix := backendMigrate.Flag("to-prefix", "Destination key prefix").Default(config.KeyPrefix).String()
+	migrateToBase64 := backendMigrate.Flag("to-base64", "Store keys base64 encoded in destination backend").Bool()
+	migrateDryRun := backendMigrate.Flag("dry-run", "Only show what would be migrated").Bool()
+	migrateResume := backendMigrate.Flag("resume", "Skip keys already present and valid in the destination backend").Bool()
+	migrateKeyPassword := backendMigrate.Flag("password", "Current key password used for re-encryption when not stored in key metadata").Default("").String()
+	migrateNewPassword := backendMigrate.Flag("new-password", "If set, re-encrypt every key with this password during the migration").Default("").String()
+	// endregion
+
 	selectedCmd := kingpin.Parse()
 
+	if err := config.LoadError(); err != nil {
+		kingpin.Fatalf("%s", err)
+	}
+
 	slog.SetDefaultOutput(os.Stderr)
 	if !*debugMode {
 		slog.SetInfo(false)
@@ -81,6 +163,29 @@ func main() {
 	mem = memory.MakeMemoryDBDriver(nil)
 	ctx = context.WithValue(ctx, tools.CtxDatabaseHandler, mem)
 
+	if *remoteMode || selectedCmd == "agent" {
+		profile, err := loadRemoteProfile(*remoteProfileFile, *remoteProfileName)
+		if err != nil {
+			panic(fmt.Sprintf("Error loading remote profile: %s\n", err))
+		}
+
+		if *remoteURL == "" {
+			*remoteURL = profile.URL
+		}
+
+		if *remoteToken == "" {
+			*remoteToken = profile.Token
+		}
+	}
+
+	if *remoteMode {
+		if *remoteURL == "" {
+			panic("No server URL for remote mode. Use --server-url, CHEVRON_URL or a profile\n")
+		}
+
+		remote = makeRemotePGP(*remoteURL, *remoteToken)
+	}
+
 	switch selectedCmd {
 	case "gen":
 		GenerateFlow(*genPassword, *genOutput, *genIdentifier, int(*genBits))
@@ -88,13 +193,46 @@ func main() {
 		BenchmarkGeneration(*benchGenRuns, int(*benchGenBits))
 	case "list-keys":
 		ListKeys()
+	case "ui":
+		RunUI()
 	case "export":
 		ExportKey(*exportName, *exportPass, *exportSecret)
 	case "encrypt":
 		EncryptFile(*encryptInput, *encryptOutput, *encryptRecipient)
 	case "import":
-		ImportKey(*importInput, *keyPassword, *keyPasswordFd)
+		if *importGnuPGHome != "" {
+			ImportGnuPGHome(*importGnuPGHome, *keyPassword, *keyPasswordFd)
+		} else {
+			ImportKey(*importInput, *keyPassword, *keyPasswordFd)
+		}
 	case "decrypt":
 		Decrypt(*decryptInput, *decryptOutput)
+	case "sign":
+		SignFile(*signInput, *signOutput, *signKey, *signPassword, *signFormat, *signHash)
+	case "verify":
+		VerifyFile(*verifyInput, *verifySignature)
+	case "delete-key":
+		DeleteKey(*deleteKeyName, *deleteKeyYes)
+	case "agent":
+		RunAgent(*agentSocket, *agentKey, *remoteURL, *remoteToken)
+	case "backend migrate":
+		MigrateBackend(backendmigrate.Options{
+			From: backendmigrate.BackendOptions{
+				Type:          *migrateFrom,
+				Folder:        *migrateFromFolder,
+				Prefix:        *migrateFromPrefix,
+				Base64Encoded: *migrateFromBase64,
+			},
+			To: backendmigrate.BackendOptions{
+				Type:          *migrateTo,
+				Folder:        *migrateToFolder,
+				Prefix:        *migrateToPrefix,
+				Base64Encoded: *migrateToBase64,
+			},
+			DryRun:      *migrateDryRun,
+			Resume:      *migrateResume,
+			Password:    *migrateKeyPassword,
+			NewPassword: *migrateNewPassword,
+		})
 	}
 }
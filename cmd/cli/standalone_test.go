@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/bouk/monkey"
+	"github.com/quan-to/chevron/internal/config"
+	"github.com/quan-to/chevron/internal/tools"
+	"github.com/quan-to/chevron/pkg/database/memory"
+	"github.com/quan-to/chevron/test"
+
+	"github.com/quan-to/slog"
+)
+
+var testFolder string
+
+func TestMain(m *testing.M) {
+	slog.SetTestMode()
+
+	keyFolder, err := test.MakeKeyFolder("../../test/data")
+	if err != nil {
+		slog.Fatal("Error creating the test key folder: %s", err)
+	}
+
+	testFolder, err = ioutil.TempDir("", "chevron-cli-test")
+	if err != nil {
+		slog.Fatal("Error creating the test folder: %s", err)
+	}
+
+	config.PrivateKeyFolder = keyFolder
+	config.KeyPrefix = "testkey_"
+	config.KeysBase64Encoded = false
+	config.VaultStorage = false
+
+	mem = memory.MakeMemoryDBDriver(nil)
+	ctx = context.WithValue(context.Background(), tools.CtxDatabaseHandler, mem)
+
+	code := m.Run()
+	slog.UnsetTestMode()
+	_ = os.RemoveAll(keyFolder)
+	_ = os.RemoveAll(testFolder)
+	os.Exit(code)
+}
+
+// writeTestFile writes data to a file in the test folder and returns its path
+func writeTestFile(t *testing.T, name string, data []byte) string {
+	filename := path.Join(testFolder, name)
+	if err := ioutil.WriteFile(filename, data, 0644); err != nil {
+		t.Fatalf("Error writing %s: %s", filename, err)
+	}
+
+	return filename
+}
+
+func assertPanic(t *testing.T, f func(), message string) {
+	fakeExit := func(int) {
+		panic("os.Exit called")
+	}
+	patch := monkey.Patch(os.Exit, fakeExit)
+	defer patch.Unpatch()
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf(message)
+		}
+	}()
+	f()
+}
@@ -0,0 +1,550 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quan-to/chevron/internal/tools"
+	"github.com/quan-to/chevron/pkg/interfaces"
+	"github.com/quan-to/chevron/pkg/models"
+	"github.com/quan-to/chevron/pkg/openpgp"
+	"github.com/quan-to/slog"
+)
+
+const uiHelp = "↑/↓ move  / filter  u unlock  e export  d delete  g generate  r refresh  q quit"
+
+// uiKey is a primary key shown in the interface
+type uiKey struct {
+	info   models.KeyInfo
+	entity *openpgp.Entity
+	locked bool
+}
+
+// keyBrowser is the interactive interface of the ui command
+type keyBrowser struct {
+	pgpMan   interfaces.PGPManager
+	term     *uiTerminal
+	keys     []uiKey
+	filter   string
+	selected int
+	offset   int
+	status   string
+	prompt   string
+}
+
+// RunUI runs the interactive key management interface
+func RunUI() {
+	pgpMan := getPGPManager()
+
+	term, err := openUITerminal()
+	if err != nil {
+		panic(err)
+	}
+
+	// Logs would break the screen
+	slog.SetDefaultOutput(ioutil.Discard)
+
+	defer func() {
+		term.close()
+		slog.SetDefaultOutput(os.Stderr)
+		if r := recover(); r != nil {
+			panic(r)
+		}
+	}()
+
+	b := &keyBrowser{
+		pgpMan: pgpMan,
+		term:   term,
+	}
+
+	b.status = "Loading keys..."
+	b.render()
+	pgpMan.LoadKeys(ctx)
+	b.refresh()
+
+	b.run()
+}
+
+// refresh reloads the keys from the PGP Manager. Subkeys are shown in the details of its primary key
+func (b *keyBrowser) refresh() {
+	b.keys = make([]uiKey, 0)
+
+	for _, info := range b.pgpMan.GetLoadedKeys() {
+		entity := b.pgpMan.GetPublicKeyEntity(ctx, info.FingerPrint)
+		if entity != nil && tools.ByteFingerPrint2FP16(entity.PrimaryKey.Fingerprint[:]) != info.FingerPrint {
+			continue
+		}
+
+		b.keys = append(b.keys, uiKey{
+			info:   info,
+			entity: entity,
+			locked: info.ContainsPrivateKey && b.pgpMan.IsKeyLocked(info.FingerPrint),
+		})
+	}
+
+	sort.SliceStable(b.keys, func(i, j int) bool {
+		a, c := strings.ToLower(b.keys[i].info.Identifier), strings.ToLower(b.keys[j].info.Identifier)
+		if a == c {
+			return b.keys[i].info.FingerPrint < b.keys[j].info.FingerPrint
+		}
+		return a < c
+	})
+
+	b.status = fmt.Sprintf("%d keys loaded", len(b.keys))
+	b.clampSelection()
+}
+
+// visible returns the keys that match the filter
+func (b *keyBrowser) visible() []uiKey {
+	if b.filter == "" {
+		return b.keys
+	}
+
+	filter := strings.ToLower(b.filter)
+	keys := make([]uiKey, 0)
+
+	for _, k := range b.keys {
+		match := strings.Contains(strings.ToLower(k.info.Identifier), filter) || strings.Contains(strings.ToLower(k.info.FingerPrint), filter)
+		if k.entity != nil {
+			for name := range k.entity.Identities {
+				match = match || strings.Contains(strings.ToLower(name), filter)
+			}
+		}
+		if match {
+			keys = append(keys, k)
+		}
+	}
+
+	return keys
+}
+
+// current returns the selected key or nil
+func (b *keyBrowser) current() *uiKey {
+	keys := b.visible()
+	if b.selected < 0 || b.selected >= len(keys) {
+		return nil
+	}
+
+	return &keys[b.selected]
+}
+
+func (b *keyBrowser) clampSelection() {
+	n := len(b.visible())
+	if b.selected >= n {
+		b.selected = n - 1
+	}
+	if b.selected < 0 {
+		b.selected = 0
+	}
+}
+
+func (b *keyBrowser) selectFingerPrint(fingerPrint string) {
+	for i, k := range b.visible() {
+		if k.info.FingerPrint == fingerPrint {
+			b.selected = i
+			return
+		}
+	}
+}
+
+func (b *keyBrowser) run() {
+	for {
+		k, err := b.term.readKey()
+		if err != nil {
+			return
+		}
+
+		listHeight := b.listHeight()
+
+		switch k {
+		case 'q', keyInterrupt:
+			return
+		case keyUp, 'k':
+			b.selected--
+		case keyDown, 'j':
+			b.selected++
+		case keyPageUp:
+			b.selected -= listHeight
+		case keyPageDown:
+			b.selected += listHeight
+		case keyHome:
+			b.selected = 0
+		case keyEnd:
+			b.selected = len(b.visible()) - 1
+		case keyEscape:
+			b.filter = ""
+		case '/':
+			b.filterKeys()
+		case 'r':
+			b.pgpMan.LoadKeys(ctx)
+			b.refresh()
+		case 'u':
+			b.unlockKey()
+		case 'e':
+			b.exportKey()
+		case 'd':
+			b.deleteKey()
+		case 'g':
+			b.generateKey()
+		}
+
+		b.clampSelection()
+		b.render()
+	}
+}
+
+// ask shows a prompt in the bottom of the screen. Returns false if cancelled with escape
+func (b *keyBrowser) ask(label, initial string, hidden bool, onChange func(string)) (string, bool) {
+	value := []rune(initial)
+
+	defer func() {
+		b.prompt = ""
+	}()
+
+	for {
+		shown := string(value)
+		if hidden {
+			shown = strings.Repeat("*", len(value))
+		}
+		b.prompt = label + shown + "█"
+		b.render()
+
+		k, err := b.term.readKey()
+		if err != nil {
+			return "", false
+		}
+
+		switch k {
+		case keyEnter:
+			return string(value), true
+		case keyEscape, keyInterrupt:
+			return "", false
+		case keyBackspace:
+			if len(value) > 0 {
+				value = value[:len(value)-1]
+			}
+		default:
+			if k > 0 {
+				value = append(value, k)
+			}
+		}
+
+		if onChange != nil {
+			onChange(string(value))
+		}
+	}
+}
+
+// confirm asks a yes / no question
+func (b *keyBrowser) confirm(question string) bool {
+	answer, ok := b.ask(question+" [y/N] ", "", false, nil)
+
+	return ok && strings.ToLower(strings.TrimSpace(answer)) == "y"
+}
+
+func (b *keyBrowser) filterKeys() {
+	previous := b.filter
+
+	_, ok := b.ask("Filter: ", b.filter, false, func(value string) {
+		b.filter = value
+		b.selected = 0
+	})
+
+	if !ok {
+		b.filter = previous
+	}
+}
+
+func (b *keyBrowser) unlockKey() {
+	key := b.current()
+	if key == nil {
+		return
+	}
+
+	if !key.info.ContainsPrivateKey {
+		b.status = fmt.Sprintf("Key %s does not have a private key", key.info.FingerPrint)
+		return
+	}
+
+	password, ok := b.ask(fmt.Sprintf("Password for %s: ", key.info.FingerPrint), "", true, nil)
+	if !ok {
+		b.status = "Cancelled"
+		return
+	}
+
+	if err := b.pgpMan.UnlockKey(ctx, key.info.FingerPrint, password); err != nil {
+		b.status = fmt.Sprintf("Error unlocking %s: %s", key.info.FingerPrint, err)
+		return
+	}
+
+	fingerPrint := key.info.FingerPrint
+	b.refresh()
+	b.selectFingerPrint(fingerPrint)
+	b.status = fmt.Sprintf("Key %s unlocked", fingerPrint)
+}
+
+func (b *keyBrowser) exportKey() {
+	key := b.current()
+	if key == nil {
+		return
+	}
+
+	filename, ok := b.ask("Export public key to file: ", key.info.FingerPrint+".asc", false, nil)
+	if !ok || filename == "" || filename == "-" {
+		b.status = "Cancelled"
+		return
+	}
+
+	armored, err := b.pgpMan.GetPublicKeyASCII(ctx, key.info.FingerPrint)
+	if err == nil {
+		err = writeOutput(filename, []byte(armored))
+	}
+
+	if err != nil {
+		b.status = fmt.Sprintf("Error exporting %s: %s", key.info.FingerPrint, err)
+		return
+	}
+
+	b.status = fmt.Sprintf("Public key %s exported to %s", key.info.FingerPrint, filename)
+}
+
+func (b *keyBrowser) deleteKey() {
+	key := b.current()
+	if key == nil {
+		return
+	}
+
+	kind := "public"
+	if key.info.ContainsPrivateKey {
+		kind = "private"
+	}
+
+	if !b.confirm(fmt.Sprintf("Delete %s key %s (%s)?", kind, key.info.FingerPrint, key.info.Identifier)) {
+		b.status = "Cancelled"
+		return
+	}
+
+	if err := b.pgpMan.DeleteKey(ctx, key.info.FingerPrint); err != nil {
+		b.status = fmt.Sprintf("Error deleting %s: %s", key.info.FingerPrint, err)
+		return
+	}
+
+	fingerPrint := key.info.FingerPrint
+	b.pgpMan.LoadKeys(ctx)
+	b.refresh()
+	b.status = fmt.Sprintf("Key %s deleted", fingerPrint)
+}
+
+func (b *keyBrowser) generateKey() {
+	identifier, ok := b.ask("Identifier (Name <email>): ", "", false, nil)
+	if !ok || strings.TrimSpace(identifier) == "" {
+		b.status = "Cancelled"
+		return
+	}
+
+	password, ok := b.ask("Password: ", "", true, nil)
+	if !ok || password == "" {
+		b.status = "Cancelled"
+		return
+	}
+
+	confirmation, ok := b.ask("Repeat the password: ", "", true, nil)
+	if !ok {
+		b.status = "Cancelled"
+		return
+	}
+
+	if confirmation != password {
+		b.status = "The passwords do not match"
+		return
+	}
+
+	bitsValue, ok := b.ask("Bits: ", "4096", false, nil)
+	if !ok {
+		b.status = "Cancelled"
+		return
+	}
+
+	bits, err := strconv.Atoi(bitsValue)
+	if err != nil {
+		b.status = fmt.Sprintf("Invalid number of bits %q", bitsValue)
+		return
+	}
+
+	b.status = "Generating key. This might take a while..."
+	b.render()
+
+	key, err := b.pgpMan.GeneratePGPKey(ctx, identifier, password, bits)
+	if err != nil {
+		b.status = fmt.Sprintf("Error creating key: %s", err)
+		return
+	}
+
+	fingerPrint, _ := tools.GetFingerPrintFromKey(key)
+
+	if err := b.pgpMan.SaveKey(fingerPrint, key, password); err != nil {
+		b.status = fmt.Sprintf("Error saving key %s: %s", fingerPrint, err)
+		return
+	}
+
+	b.pgpMan.LoadKeys(ctx)
+	b.refresh()
+	b.filter = ""
+	b.selectFingerPrint(fingerPrint)
+	b.status = fmt.Sprintf("Key %s generated", fingerPrint)
+}
+
+// details returns the lines with the details of the selected key
+func (b *keyBrowser) details() []string {
+	key := b.current()
+	if key == nil {
+		return []string{"No key selected"}
+	}
+
+	lines := make([]string, 0)
+	e := key.entity
+
+	if e == nil {
+		return append(lines, fmt.Sprintf("Fingerprint: %s", key.info.FingerPrint), "Public key not available")
+	}
+
+	locked := "no private key"
+	if key.info.ContainsPrivateKey {
+		locked = "unlocked"
+		if key.locked {
+			locked = "locked"
+		}
+	}
+
+	bits, _ := e.PrimaryKey.BitLength()
+	lines = append(lines,
+		fmt.Sprintf("Fingerprint: %X", e.PrimaryKey.Fingerprint[:]),
+		fmt.Sprintf("Algorithm: %s %d bits   Created: %s   Expires: %s   Private key: %s",
+			models.PublicKeyAlgorithmName(e.PrimaryKey.PubKeyAlgo), bits, formatDate(&e.PrimaryKey.CreationTime), formatDate(models.KeyExpiration(e)), locked),
+		"Identities:",
+	)
+
+	names := make([]string, 0, len(e.Identities))
+	for name := range e.Identities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		lines = append(lines, "  "+name)
+	}
+
+	if len(e.Subkeys) == 0 {
+		return lines
+	}
+
+	lines = append(lines, "Subkeys:")
+	for _, sub := range e.Subkeys {
+		subBits, _ := sub.PublicKey.BitLength()
+		var expires *time.Time
+		if sub.Sig != nil && sub.Sig.KeyLifetimeSecs != nil && *sub.Sig.KeyLifetimeSecs != 0 {
+			t := sub.PublicKey.CreationTime.Add(time.Duration(*sub.Sig.KeyLifetimeSecs) * time.Second)
+			expires = &t
+		}
+		lines = append(lines, fmt.Sprintf("  %s  %s %d bits  Expires: %s",
+			tools.ByteFingerPrint2FP16(sub.PublicKey.Fingerprint[:]), models.PublicKeyAlgorithmName(sub.PublicKey.PubKeyAlgo), subBits, formatDate(expires)))
+	}
+
+	return lines
+}
+
+func formatDate(t *time.Time) string {
+	if t == nil {
+		return "never"
+	}
+
+	return t.UTC().Format("2006-01-02")
+}
+
+// listHeight returns how many keys fit in the screen
+func (b *keyBrowser) listHeight() int {
+	_, h := b.term.size()
+
+	// Title, list header, separator, details, status and help
+	height := h - 5 - len(b.details())
+	if height < 3 {
+		height = 3
+	}
+
+	return height
+}
+
+func yesNo(v bool) string {
+	if v {
+		return "yes"
+	}
+
+	return "no"
+}
+
+func (b *keyBrowser) render() {
+	keys := b.visible()
+	listHeight := b.listHeight()
+
+	if b.selected < b.offset {
+		b.offset = b.selected
+	}
+	if b.selected >= b.offset+listHeight {
+		b.offset = b.selected - listHeight + 1
+	}
+	if b.offset < 0 {
+		b.offset = 0
+	}
+
+	mode := "local"
+	if remote != nil {
+		mode = "remote"
+	}
+
+	title := fmt.Sprintf("Chevron keys (%s) - %d of %d", mode, len(keys), len(b.keys))
+	if b.filter != "" {
+		title += fmt.Sprintf(" matching %q", b.filter)
+	}
+
+	lines := []string{
+		title,
+		fmt.Sprintf("  %-18s %5s  %-8s %-7s %s", "Fingerprint", "Bits", "Private", "Locked", "Identifier"),
+	}
+
+	for i := b.offset; i < b.offset+listHeight; i++ {
+		if i >= len(keys) {
+			lines = append(lines, "")
+			continue
+		}
+
+		k := keys[i]
+		locked := ""
+		if k.info.ContainsPrivateKey {
+			locked = yesNo(k.locked)
+		}
+
+		line := fmt.Sprintf("  %-18s %5d  %-8s %-7s %s", k.info.FingerPrint, k.info.Bits, yesNo(k.info.ContainsPrivateKey), locked, k.info.Identifier)
+		if i == b.selected {
+			line = highlightMark + "> " + line[2:]
+		}
+		lines = append(lines, line)
+	}
+
+	w, _ := b.term.size()
+	lines = append(lines, strings.Repeat("─", w))
+	lines = append(lines, b.details()...)
+
+	if b.prompt != "" {
+		lines = append(lines, b.prompt)
+	} else {
+		lines = append(lines, b.status)
+	}
+	lines = append(lines, uiHelp)
+
+	b.term.draw(lines)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// Keys returned by readKey that are not printable characters
+const (
+	keyUp rune = -(iota + 1)
+	keyDown
+	keyPageUp
+	keyPageDown
+	keyHome
+	keyEnd
+	keyEnter
+	keyEscape
+	keyBackspace
+	keyInterrupt
+	keyUnknown
+)
+
+// uiTerminal draws full screen in a terminal in raw mode using ANSI escape sequences
+type uiTerminal struct {
+	fd       int
+	oldState *terminal.State
+	in       *bufio.Reader
+	out      *bufio.Writer
+}
+
+// openUITerminal puts the terminal in raw mode and switches to the alternate screen
+func openUITerminal() (*uiTerminal, error) {
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		return nil, fmt.Errorf("the interface requires a terminal")
+	}
+
+	oldState, err := terminal.MakeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &uiTerminal{
+		fd:       fd,
+		oldState: oldState,
+		in:       bufio.NewReader(os.Stdin),
+		out:      bufio.NewWriter(os.Stdout),
+	}
+
+	_, _ = t.out.WriteString("\x1b[?1049h\x1b[?25l")
+	_ = t.out.Flush()
+
+	return t, nil
+}
+
+// close restores the terminal state
+func (t *uiTerminal) close() {
+	_, _ = t.out.WriteString("\x1b[0m\x1b[?25h\x1b[?1049l")
+	_ = t.out.Flush()
+	_ = terminal.Restore(t.fd, t.oldState)
+}
+
+// size returns the terminal width and height
+func (t *uiTerminal) size() (int, int) {
+	w, h, err := terminal.GetSize(t.fd)
+	if err != nil || w <= 0 || h <= 0 {
+		return 80, 24
+	}
+
+	return w, h
+}
+
+// draw replaces the screen content with the lines. Lines starting with highlightMark are shown in reverse video
+func (t *uiTerminal) draw(lines []string) {
+	w, h := t.size()
+
+	_, _ = t.out.WriteString("\x1b[H")
+	for i, line := range lines {
+		if i >= h {
+			break
+		}
+
+		highlight := strings.HasPrefix(line, highlightMark)
+		if highlight {
+			line = strings.TrimPrefix(line, highlightMark)
+			_, _ = t.out.WriteString("\x1b[7m")
+		}
+
+		_, _ = t.out.WriteString(fitWidth(line, w))
+
+		if highlight {
+			_, _ = t.out.WriteString("\x1b[0m")
+		}
+
+		_, _ = t.out.WriteString("\x1b[K")
+		if i < h-1 && i < len(lines)-1 {
+			_, _ = t.out.WriteString("\r\n")
+		}
+	}
+	_, _ = t.out.WriteString("\x1b[J")
+	_ = t.out.Flush()
+}
+
+// highlightMark marks a line to be drawn highlighted
+const highlightMark = "\x00"
+
+// fitWidth truncates or pads the line to the width
+func fitWidth(line string, width int) string {
+	n := utf8.RuneCountInString(line)
+	if n > width {
+		return string([]rune(line)[:width])
+	}
+
+	return line + strings.Repeat(" ", width-n)
+}
+
+// readKey reads a key press. Printable characters are returned as is
+func (t *uiTerminal) readKey() (rune, error) {
+	r, _, err := t.in.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+
+	switch r {
+	case '\r', '\n':
+		return keyEnter, nil
+	case 127, 8:
+		return keyBackspace, nil
+	case 3, 4:
+		return keyInterrupt, nil
+	case 27:
+		// A single escape is a key press, escape sequences arrive at once
+		if t.in.Buffered() == 0 {
+			return keyEscape, nil
+		}
+		return t.readEscapeSequence()
+	}
+
+	if r < 32 {
+		return keyUnknown, nil
+	}
+
+	return r, nil
+}
+
+func (t *uiTerminal) readEscapeSequence() (rune, error) {
+	kind, err := t.in.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+
+	if kind != '[' && kind != 'O' {
+		return keyUnknown, nil
+	}
+
+	seq := make([]byte, 0)
+	for {
+		c, err := t.in.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		seq = append(seq, c)
+		if (c >= 'A' && c <= 'Z') || c == '~' || t.in.Buffered() == 0 {
+			break
+		}
+	}
+
+	switch string(seq) {
+	case "A":
+		return keyUp, nil
+	case "B":
+		return keyDown, nil
+	case "5~":
+		return keyPageUp, nil
+	case "6~":
+		return keyPageDown, nil
+	case "H", "1~", "7~":
+		return keyHome, nil
+	case "F", "4~", "8~":
+		return keyEnd, nil
+	}
+
+	return keyUnknown, nil
+}
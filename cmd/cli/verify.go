@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/quan-to/chevron/internal/tools"
+	"github.com/quan-to/chevron/pkg/openpgp/armor"
+)
+
+// signatureToArmored converts an armored, binary or Quanto signature to an armored GPG signature
+func signatureToArmored(data []byte) (string, error) {
+	s := strings.TrimSpace(string(data))
+
+	if strings.HasPrefix(s, "-----BEGIN PGP SIGNATURE-----") {
+		return s, nil
+	}
+
+	// Binary packets always have the high bit of the first byte set
+	if len(s) > 0 && data[0]&0x80 == 0 && !strings.ContainsAny(s, " \n") && strings.Count(s, "_")+strings.Count(s, "$") >= 2 {
+		if sig := tools.Quanto2GPG(s); sig != "" {
+			return sig, nil
+		}
+	}
+
+	var b bytes.Buffer
+	w, err := armor.Encode(&b, "PGP SIGNATURE", nil)
+	if err != nil {
+		return "", err
+	}
+	_, _ = w.Write(data)
+	if err = w.Close(); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// VerifyFile checks the detached signature of the input
+// Exits with status 1 if the signature is not valid
+func VerifyFile(input, signatureFile string) {
+	pgpMan := getPGPManager()
+	pgpMan.LoadKeys(ctx)
+
+	sigData, err := readInput(signatureFile)
+	if err != nil {
+		panic(fmt.Sprintf("Error reading signature %s: %s\n", signatureFile, err))
+	}
+
+	signature, err := signatureToArmored(sigData)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid signature %s: %s\n", signatureFile, err))
+	}
+
+	data, err := readInput(input)
+	if err != nil {
+		panic(fmt.Sprintf("Error reading %s: %s\n", input, err))
+	}
+
+	fingerPrint, _ := tools.GetFingerPrintFromSignature(signature)
+
+	valid, err := pgpMan.VerifySignature(ctx, data, signature)
+	if err != nil || !valid {
+		if err == nil {
+			err = fmt.Errorf("signature does not match")
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "BAD signature from %s: %s\n", fingerPrint, err)
+		os.Exit(1)
+	}
+
+	_, _ = fmt.Fprintf(os.Stderr, "Good signature from %s\n", fingerPrint)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/quan-to/chevron/internal/tools"
+	"github.com/quan-to/chevron/test"
+)
+
+func TestSignatureToArmored(t *testing.T) {
+	binary := armoredBody(t, test.TestSignatureSignature)
+	quanto := tools.GPG2Quanto(test.TestSignatureSignature, test.TestKeyFingerprint, "SHA512")
+
+	// A binary packet that also contains the Quanto separators
+	binaryWithSeparators := append(append([]byte{}, binary...), []byte("_$_")...)
+
+	cases := []struct {
+		name     string
+		input    []byte
+		expected []byte
+	}{
+		{"armored", []byte(test.TestSignatureSignature), binary},
+		{"armored with spaces", []byte("\n  " + test.TestSignatureSignature + "  \n"), binary},
+		{"binary", binary, binary},
+		{"binary with separators", binaryWithSeparators, binaryWithSeparators},
+		{"quanto", []byte(quanto), binary},
+		{"quanto with new line", []byte(quanto + "\n"), binary},
+		{"quanto with dollar", []byte(strings.Replace(quanto, "_", "$", -1)), binary},
+		{"not quanto", []byte("not_a_quanto signature"), []byte("not_a_quanto signature")},
+		{"quanto without signature", []byte("ABCD_SHA512_"), []byte("ABCD_SHA512_")},
+	}
+
+	for _, c := range cases {
+		signature, err := signatureToArmored(c.input)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", c.name, err)
+			continue
+		}
+
+		if !strings.HasPrefix(signature, "-----BEGIN PGP SIGNATURE-----") {
+			t.Errorf("%s: expected an armored signature, got %q", c.name, signature)
+			continue
+		}
+
+		if body := armoredBody(t, signature); !bytes.Equal(body, c.expected) {
+			t.Errorf("%s: expected the signature packet %x, got %x", c.name, c.expected, body)
+		}
+	}
+}
+
+func TestVerifyFile(t *testing.T) {
+	input := writeTestFile(t, "verify-input.txt", []byte(test.TestSignatureData))
+
+	for _, format := range []string{signFormatArmored, signFormatBinary, signFormatQuanto} {
+		signature := path.Join(testFolder, "verify-signature."+format)
+		SignFile(input, signature, test.TestKeyFingerprint, test.TestKeyPassword, format, "sha512")
+
+		// Exits with status 1 if the signature is not valid
+		VerifyFile(input, signature)
+	}
+}
+
+func TestVerifyFileErrors(t *testing.T) {
+	input := writeTestFile(t, "verify-input.txt", []byte(test.TestSignatureData))
+	changed := writeTestFile(t, "verify-changed.txt", []byte(test.TestSignatureData+"!"))
+	signature := writeTestFile(t, "verify-signature.asc", []byte(test.TestSignatureSignature))
+
+	assertPanic(t, func() {
+		VerifyFile(changed, signature)
+	}, "Expected a bad signature for changed data")
+
+	assertPanic(t, func() {
+		VerifyFile(input, path.Join(testFolder, "missing.asc"))
+	}, "Expected a panic for a missing signature")
+
+	assertPanic(t, func() {
+		VerifyFile(path.Join(testFolder, "missing.txt"), signature)
+	}, "Expected a panic for a missing input")
+}
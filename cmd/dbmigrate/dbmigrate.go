@@ -5,6 +5,7 @@ import (
 
 	"github.com/alecthomas/kong"
 	"github.com/mewkiz/pkg/osutil"
+	"github.com/quan-to/chevron/internal/config"
 	"github.com/quan-to/chevron/pkg/models"
 	"github.com/quan-to/slog"
 )
@@ -42,6 +43,10 @@ func main() {
 		kong.Name("dbmigrate"),
 		kong.Description("Database migration tool for Chevron.\nThe configuration JSON files has the same fields as environment variables"))
 
+	if err := config.LoadError(); err != nil {
+		ctx.Fatalf("%s", err)
+	}
+
 	if !osutil.Exists(cli.FromConfigFile) {
 		ctx.Fatalf("No such file: %s", cli.FromConfigFile)
 	}
@@ -2,30 +2,91 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"os/signal"
+	"sort"
 	"syscall"
 
 	_ "github.com/quan-to/chevron/cmd/server/init"
 	"github.com/quan-to/chevron/internal/agent"
+	"github.com/quan-to/chevron/internal/cluster"
 	"github.com/quan-to/chevron/internal/config"
 	"github.com/quan-to/chevron/internal/etc/magicbuilder"
-	"github.com/quan-to/chevron/internal/kubernetes"
 	"github.com/quan-to/chevron/internal/server"
 	"github.com/quan-to/chevron/internal/tools"
 	"github.com/quan-to/slog"
+	"gopkg.in/alecthomas/kingpin.v2"
 )
 
 var log = slog.Scope("QRS").Tag(tools.DefaultTag)
 
 func main() {
-	var stop chan bool
-	var err error
+	configFile := kingpin.Flag("config", "YAML, TOML or JSON configuration file").Envar(config.FileEnvironmentVariable).String()
+	overrides := kingpin.Flag("set", "Override a configuration option, for example --set HTTP_PORT=5100. Can be repeated").PlaceHolder("NAME=VALUE").StringMap()
+
+	// region Run
+	_ = kingpin.Command("run", "Run the server").Default()
+	// endregion
+	// region Config
+	configCmd := kingpin.Command("config", "Configuration tools")
+	_ = configCmd.Command("check", "Validate the configuration and exit")
+	configDump := configCmd.Command("dump", "Print the effective configuration")
+	configDumpRedact := configDump.Flag("redact", "Hide passwords and tokens").Bool()
+	configDumpFormat := configDump.Flag("format", "Output format").Default("yaml").Enum("yaml", "json")
+	// endregion
+
+	selectedCmd := kingpin.Parse()
 
-	if os.Getenv("SHOW_LINES") == "true" {
-		slog.SetShowLines(true)
+	opts := config.Options{
+		File:        *configFile,
+		Environment: os.Environ(),
+		Flags:       *overrides,
 	}
 
+	switch selectedCmd {
+	case "config check":
+		c, err := config.Load(opts)
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		deprecations := c.Deprecations()
+		names := make([]string, 0, len(deprecations))
+		for name := range deprecations {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("warning: %s is deprecated. Use %s instead\n", name, deprecations[name])
+		}
+		fmt.Println("Configuration OK")
+	case "config dump":
+		c, err := config.Load(opts)
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		data, err := c.Dump(*configDumpFormat, *configDumpRedact)
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+	default:
+		if err := config.SetupFrom(opts); err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		slog.SetLogFormat(config.LogFormat)
+		runServer()
+	}
+}
+
+func runServer() {
+	var stop chan bool
+	var err error
+
 	ctx := context.Background()
 
 	dbh, err := agent.MakeDatabaseHandler(log)
@@ -39,29 +100,40 @@ func main() {
 
 	gpg.LoadKeys(ctx)
 
+	// Unlock the keys with the passwords stored in the master key backend
+	sm.UnlockLocalKeys(ctx, gpg)
+
 	if config.SingleKeyMode {
 		stop, err = server.RunRemoteSignerServerSingleKey(log, sm, gpg, dbh)
 		if err != nil {
 			log.Fatal("Error starting in single-key mode: %s", err)
 		}
 	} else {
-		stop = server.RunRemoteSignerServer(log, sm, gpg, dbh)
+		stop, err = server.RunRemoteSignerServer(log, sm, gpg, dbh)
+		if err != nil {
+			log.Fatal("Error starting server: %s", err)
+		}
 	}
 
 	localStop := make(chan bool)
-	kubeStop := make(chan bool)
+	clusterStop := make(chan bool, 1)
+
+	discovery, err := cluster.MakePeerDiscovery(log)
+	if err != nil {
+		log.Fatal("Error starting cluster peer discovery: %s", err)
+	}
 
-	if kubernetes.InKubernetes() {
-		go kubernetes.KubeRoutine(kubeStop)
+	if discovery != nil {
+		go cluster.Routine(clusterStop, cluster.MakePasswordExchange(log, sm), discovery, gpg)
 	}
 
-	c := make(chan os.Signal)
+	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 
 	go func() {
 		<-c // Wait for SIGTERM (Ctrl + C)
-		if kubernetes.InKubernetes() {
-			kubeStop <- true // Send Stop signal to Kubernetes Routine
+		if discovery != nil {
+			clusterStop <- true // Send Stop signal to Cluster Routine
 		}
 		stop <- true      // Send stop signal to HTTP
 		<-stop            // Wait HTTP to Cleanup
@@ -4,6 +4,7 @@ go 1.12
 
 require (
 	bou.ke/monkey v1.0.2
+	github.com/BurntSushi/toml v0.4.1
 	github.com/DATA-DOG/go-sqlmock v1.5.0
 	github.com/alecthomas/kong v0.2.12
 	github.com/alecthomas/template v0.0.0-20190718012654-fb15b899a751
@@ -44,9 +45,10 @@ require (
 	golang.org/x/crypto v0.0.0-20200709230013-948cd5f35899
 	golang.org/x/exp v0.0.0-20201215153530-b5a6e247da10 // indirect
 	golang.org/x/net v0.0.0-20201224014010-6772e930b67b // indirect
-	golang.org/x/sync v0.0.0-20201207232520-09787c993a3a // indirect
+	golang.org/x/sync v0.0.0-20201207232520-09787c993a3a
 	golang.org/x/text v0.3.5 // indirect
 	golang.org/x/tools v0.0.0-20210112183307-1e6ecd4bf1b0 // indirect
 	gopkg.in/alecthomas/kingpin.v2 v2.2.6
 	gopkg.in/rethinkdb/rethinkdb-go.v6 v6.1.1
+	gopkg.in/yaml.v2 v2.4.0
 )
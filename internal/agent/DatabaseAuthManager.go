@@ -22,6 +22,8 @@ type DBAuth interface {
 	GetUser(username string) (um *models.User, err error)
 	AddUser(um models.User) (string, error)
 	UpdateUser(um models.User) error
+	ListUsers(pageStart, pageEnd int) ([]models.User, error)
+	DeleteUser(um models.User) error
 }
 
 // NewDatabaseAuthManager creates an instance of Auth Manager that uses RethinkDB as storage
@@ -38,22 +40,11 @@ func NewDatabaseAuthManager(logger slog.Instance, dbAuth DBAuth) *DatabaseAuthMa
 		dbAuth: dbAuth,
 	}
 
-	if !ram.UserExists("admin") {
-		ram.log.Warn("User admin does not exists. Creating default")
-		ram.addDefaultAdmin()
-	}
+	bootstrapAdmin(ram.log, ram)
 
 	return ram
 }
 
-func (ram *DatabaseAuthManager) addDefaultAdmin() {
-	err := ram.LoginAdd("admin", "admin", "Administrator", config.AgentKeyFingerPrint)
-
-	if err != nil {
-		ram.log.Fatal("Error adding default admin: %v", err)
-	}
-}
-
 // UserExists checks if a user with specified username exists in AuthManager
 func (ram *DatabaseAuthManager) UserExists(username string) bool {
 	ram.Lock()
@@ -75,7 +66,7 @@ func (ram *DatabaseAuthManager) LoginAuth(username, password string) (fingerPrin
 
 	um, err := ram.dbAuth.GetUser(username)
 
-	if err != nil || um == nil {
+	if err != nil || um == nil || um.Disabled {
 		return "", "", fmt.Errorf("invalid username or password")
 	}
 
@@ -123,6 +114,7 @@ func (ram *DatabaseAuthManager) LoginAdd(username, password, fullname, fingerpri
 		Password:    encodedPassword,
 		FullName:    fullname,
 		CreatedAt:   time.Now(),
+		Roles:       []string{models.RoleSigner},
 	})
 
 	return err
@@ -149,3 +141,233 @@ func (ram *DatabaseAuthManager) ChangePassword(username, password string) error
 
 	return ram.dbAuth.UpdateUser(*um)
 }
+
+// ListUsers returns the users sorted by username in the specified page. Passwords are not returned
+func (ram *DatabaseAuthManager) ListUsers(pageStart, pageEnd int) ([]models.User, error) {
+	ram.Lock()
+	defer ram.Unlock()
+
+	users, err := ram.dbAuth.ListUsers(pageStart, pageEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range users {
+		users[i].Password = ""
+		users[i].TOTPSecret = ""
+	}
+
+	return users, nil
+}
+
+// GetUser returns the specified user. The password is not returned
+func (ram *DatabaseAuthManager) GetUser(username string) (*models.User, error) {
+	ram.Lock()
+	defer ram.Unlock()
+
+	um, err := ram.dbAuth.GetUser(username)
+
+	if err != nil || um == nil {
+		return nil, fmt.Errorf("user does not exists")
+	}
+
+	um.Password = ""
+	um.TOTPSecret = ""
+
+	return um, nil
+}
+
+// SetUserDisabled disables or enables the specified user. Disabled users cannot login
+func (ram *DatabaseAuthManager) SetUserDisabled(username string, disabled bool) error {
+	ram.Lock()
+	defer ram.Unlock()
+
+	um, err := ram.dbAuth.GetUser(username)
+
+	if err != nil || um == nil {
+		return fmt.Errorf("user does not exists")
+	}
+
+	um.Disabled = disabled
+
+	return ram.dbAuth.UpdateUser(*um)
+}
+
+// DeleteUser deletes the specified user
+func (ram *DatabaseAuthManager) DeleteUser(username string) error {
+	ram.Lock()
+	defer ram.Unlock()
+
+	um, err := ram.dbAuth.GetUser(username)
+
+	if err != nil || um == nil {
+		return fmt.Errorf("user does not exists")
+	}
+
+	return ram.dbAuth.DeleteUser(*um)
+}
+
+// UpdateFingerprint changes the fingerprint of the key the specified user has access
+func (ram *DatabaseAuthManager) UpdateFingerprint(username, fingerprint string) error {
+	ram.Lock()
+	defer ram.Unlock()
+
+	um, err := ram.dbAuth.GetUser(username)
+
+	if err != nil || um == nil {
+		return fmt.Errorf("user does not exists")
+	}
+
+	um.Fingerprint = fingerprint
+
+	return ram.dbAuth.UpdateUser(*um)
+}
+
+// GrantRole adds the specified role to the user
+func (ram *DatabaseAuthManager) GrantRole(username, role string) error {
+	if !models.IsValidRole(role) {
+		return fmt.Errorf("invalid role %q", role)
+	}
+
+	ram.Lock()
+	defer ram.Unlock()
+
+	um, err := ram.dbAuth.GetUser(username)
+
+	if err != nil || um == nil {
+		return fmt.Errorf("user does not exists")
+	}
+
+	if um.HasRole(role) {
+		return nil
+	}
+
+	um.Roles = addRole(um.Roles, role)
+
+	return ram.dbAuth.UpdateUser(*um)
+}
+
+// RevokeRole removes the specified role from the user
+func (ram *DatabaseAuthManager) RevokeRole(username, role string) error {
+	if !models.IsValidRole(role) {
+		return fmt.Errorf("invalid role %q", role)
+	}
+
+	ram.Lock()
+	defer ram.Unlock()
+
+	um, err := ram.dbAuth.GetUser(username)
+
+	if err != nil || um == nil {
+		return fmt.Errorf("user does not exists")
+	}
+
+	if !um.HasRole(role) {
+		return nil
+	}
+
+	um.Roles = removeRole(um.Roles, role)
+
+	return ram.dbAuth.UpdateUser(*um)
+}
+
+// EnrollTOTP generates a new TOTP secret for the user and returns it
+// The enrollment is only active after being confirmed by ConfirmTOTP
+func (ram *DatabaseAuthManager) EnrollTOTP(username string) (string, error) {
+	ram.Lock()
+	defer ram.Unlock()
+
+	um, err := ram.dbAuth.GetUser(username)
+
+	if err != nil || um == nil {
+		return "", fmt.Errorf("user does not exists")
+	}
+
+	if um.TOTPEnabled {
+		return "", fmt.Errorf("user already enrolled in TOTP")
+	}
+
+	secret, encrypted, err := newTOTPSecret()
+	if err != nil {
+		return "", err
+	}
+
+	um.TOTPSecret = encrypted
+
+	err = ram.dbAuth.UpdateUser(*um)
+	if err != nil {
+		return "", err
+	}
+
+	return secret, nil
+}
+
+// ConfirmTOTP activates the pending TOTP enrollment of the user if the code is valid
+func (ram *DatabaseAuthManager) ConfirmTOTP(username, code string) error {
+	ram.Lock()
+	defer ram.Unlock()
+
+	um, err := ram.dbAuth.GetUser(username)
+
+	if err != nil || um == nil {
+		return fmt.Errorf("user does not exists")
+	}
+
+	if um.TOTPEnabled {
+		return fmt.Errorf("user already enrolled in TOTP")
+	}
+
+	step, err := checkTOTPCode(um.TOTPSecret, code, um.TOTPLastStep)
+	if err != nil {
+		return err
+	}
+
+	um.TOTPEnabled = true
+	um.TOTPLastStep = step
+
+	return ram.dbAuth.UpdateUser(*um)
+}
+
+// VerifyTOTP checks if the code is valid for the user TOTP enrollment
+// Each code is only accepted once
+func (ram *DatabaseAuthManager) VerifyTOTP(username, code string) error {
+	ram.Lock()
+	defer ram.Unlock()
+
+	um, err := ram.dbAuth.GetUser(username)
+
+	if err != nil || um == nil {
+		return fmt.Errorf("user does not exists")
+	}
+
+	if !um.TOTPEnabled {
+		return fmt.Errorf("user is not enrolled in TOTP")
+	}
+
+	step, err := checkTOTPCode(um.TOTPSecret, code, um.TOTPLastStep)
+	if err != nil {
+		return err
+	}
+
+	um.TOTPLastStep = step
+
+	return ram.dbAuth.UpdateUser(*um)
+}
+
+// DisableTOTP removes the TOTP enrollment of the user
+func (ram *DatabaseAuthManager) DisableTOTP(username string) error {
+	ram.Lock()
+	defer ram.Unlock()
+
+	um, err := ram.dbAuth.GetUser(username)
+
+	if err != nil || um == nil {
+		return fmt.Errorf("user does not exists")
+	}
+
+	um.TOTPSecret = ""
+	um.TOTPEnabled = false
+	um.TOTPLastStep = 0
+
+	return ram.dbAuth.UpdateUser(*um)
+}
@@ -22,6 +22,8 @@ type DBToken interface {
 	RemoveUserToken(token string) (err error)
 	GetUserToken(token string) (ut *models.UserToken, err error)
 	InvalidateUserTokens() (int, error)
+	FetchUserTokens(username string) ([]models.UserToken, error)
+	RemoveUserTokens(username string) (int, error)
 }
 
 // MakeDatabaseTokenManager creates an instance of TokenManager that stores data in RethinkDB
@@ -119,3 +121,30 @@ func (rtm *DatabaseTokenManager) InvalidateToken(token string) error {
 
 	return rtm.dbToken.RemoveUserToken(token)
 }
+
+// ActiveTokens returns the tokens of the specified user that are not expired
+// The token values are not returned
+func (rtm *DatabaseTokenManager) ActiveTokens(username string) ([]models.UserToken, error) {
+	tokens, err := rtm.dbToken.FetchUserTokens(username)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	active := make([]models.UserToken, 0, len(tokens))
+
+	for _, ut := range tokens {
+		if now.After(ut.Expiration) {
+			continue
+		}
+		ut.Token = ""
+		active = append(active, ut)
+	}
+
+	return active, nil
+}
+
+// RevokeUserTokens removes all tokens of the specified user from the database
+func (rtm *DatabaseTokenManager) RevokeUserTokens(username string) (int, error) {
+	return rtm.dbToken.RemoveUserTokens(username)
+}
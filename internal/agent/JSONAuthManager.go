@@ -5,10 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"sort"
 	"sync"
 
 	"github.com/mewkiz/pkg/osutil"
 	"github.com/quan-to/chevron/internal/config"
+	"github.com/quan-to/chevron/pkg/models"
 	"github.com/quan-to/slog"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -17,10 +19,26 @@ const jamFileName = "users.json"
 const jamFilePerm = 0600
 
 type jsonUser struct {
-	Username    string
-	Password    string
-	FullName    string
-	FingerPrint string
+	Username     string
+	Password     string
+	FullName     string
+	FingerPrint  string
+	Disabled     bool     `json:",omitempty"`
+	Roles        []string `json:",omitempty"`
+	TOTPSecret   string   `json:",omitempty"`
+	TOTPEnabled  bool     `json:",omitempty"`
+	TOTPLastStep int64    `json:",omitempty"`
+}
+
+func (u jsonUser) toUser() models.User {
+	return models.User{
+		Username:    u.Username,
+		FullName:    u.FullName,
+		Fingerprint: u.FingerPrint,
+		Disabled:    u.Disabled,
+		Roles:       append([]string{}, u.Roles...),
+		TOTPEnabled: u.TOTPEnabled,
+	}
 }
 
 type JSONAuthManager struct {
@@ -40,6 +58,7 @@ func MakeJSONAuthManager(logger slog.Instance) *JSONAuthManager {
 	logger.Info("Creating JSON Auth Manager")
 	jam := JSONAuthManager{log: logger}
 	jam.loadFile()
+	bootstrapAdmin(jam.log, &jam)
 	return &jam
 }
 
@@ -65,18 +84,6 @@ func (jam *JSONAuthManager) loadFile() {
 	}
 
 	jam.log.Info("Loaded %d users from %s", len(jam.users), jamFileName)
-
-	if len(jam.users) == 0 {
-		jam.addDefaultAdmin()
-	}
-}
-
-func (jam *JSONAuthManager) addDefaultAdmin() {
-	err := jam.LoginAdd("admin", "admin", "Administrator", config.AgentKeyFingerPrint)
-
-	if err != nil {
-		jam.log.Fatal("Error adding default admin: %v", err)
-	}
 }
 
 func (jam *JSONAuthManager) flushFile() {
@@ -103,7 +110,7 @@ func (jam *JSONAuthManager) LoginAuth(username, password string) (fingerPrint, f
 
 	user, exists := jam.users[username]
 
-	if !exists {
+	if !exists || user.Disabled {
 		return "", "", fmt.Errorf("invalid username or password")
 	}
 
@@ -148,6 +155,7 @@ func (jam *JSONAuthManager) LoginAdd(username, password, fullname, fingerprint s
 		FullName:    fullname,
 		FingerPrint: fp,
 		Password:    encodedPassword,
+		Roles:       []string{models.RoleSigner},
 	}
 
 	jam.flushFile()
@@ -179,3 +187,278 @@ func (jam *JSONAuthManager) ChangePassword(username, password string) error {
 
 	return nil
 }
+
+// ListUsers returns the users sorted by username in the specified page. Passwords are not returned
+func (jam *JSONAuthManager) ListUsers(pageStart, pageEnd int) ([]models.User, error) {
+	jam.Lock()
+	defer jam.Unlock()
+
+	if pageStart < 0 {
+		pageStart = models.DefaultPageStart
+	}
+
+	if pageEnd < 0 {
+		pageEnd = models.DefaultPageEnd
+	}
+
+	users := make([]models.User, 0, len(jam.users))
+	for _, user := range jam.users {
+		users = append(users, user.toUser())
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Username < users[j].Username
+	})
+
+	if pageStart >= len(users) || pageEnd <= pageStart {
+		return []models.User{}, nil
+	}
+
+	if pageEnd > len(users) {
+		pageEnd = len(users)
+	}
+
+	return users[pageStart:pageEnd], nil
+}
+
+// GetUser returns the specified user. The password is not returned
+func (jam *JSONAuthManager) GetUser(username string) (*models.User, error) {
+	jam.Lock()
+	defer jam.Unlock()
+
+	user, exists := jam.users[username]
+
+	if !exists {
+		return nil, fmt.Errorf("user does not exists")
+	}
+
+	um := user.toUser()
+
+	return &um, nil
+}
+
+// SetUserDisabled disables or enables the specified user. Disabled users cannot login
+func (jam *JSONAuthManager) SetUserDisabled(username string, disabled bool) error {
+	jam.Lock()
+	defer jam.Unlock()
+
+	user, exists := jam.users[username]
+
+	if !exists {
+		return fmt.Errorf("user does not exists")
+	}
+
+	user.Disabled = disabled
+
+	jam.users[username] = user
+
+	jam.flushFile()
+
+	return nil
+}
+
+// DeleteUser deletes the specified user
+func (jam *JSONAuthManager) DeleteUser(username string) error {
+	jam.Lock()
+	defer jam.Unlock()
+
+	_, exists := jam.users[username]
+
+	if !exists {
+		return fmt.Errorf("user does not exists")
+	}
+
+	delete(jam.users, username)
+
+	jam.flushFile()
+
+	return nil
+}
+
+// UpdateFingerprint changes the fingerprint of the key the specified user has access
+func (jam *JSONAuthManager) UpdateFingerprint(username, fingerprint string) error {
+	jam.Lock()
+	defer jam.Unlock()
+
+	user, exists := jam.users[username]
+
+	if !exists {
+		return fmt.Errorf("user does not exists")
+	}
+
+	user.FingerPrint = fingerprint
+
+	jam.users[username] = user
+
+	jam.flushFile()
+
+	return nil
+}
+
+// GrantRole adds the specified role to the user
+func (jam *JSONAuthManager) GrantRole(username, role string) error {
+	if !models.IsValidRole(role) {
+		return fmt.Errorf("invalid role %q", role)
+	}
+
+	jam.Lock()
+	defer jam.Unlock()
+
+	user, exists := jam.users[username]
+
+	if !exists {
+		return fmt.Errorf("user does not exists")
+	}
+
+	if user.toUser().HasRole(role) {
+		return nil
+	}
+
+	user.Roles = addRole(user.Roles, role)
+
+	jam.users[username] = user
+
+	jam.flushFile()
+
+	return nil
+}
+
+// RevokeRole removes the specified role from the user
+func (jam *JSONAuthManager) RevokeRole(username, role string) error {
+	if !models.IsValidRole(role) {
+		return fmt.Errorf("invalid role %q", role)
+	}
+
+	jam.Lock()
+	defer jam.Unlock()
+
+	user, exists := jam.users[username]
+
+	if !exists {
+		return fmt.Errorf("user does not exists")
+	}
+
+	if !user.toUser().HasRole(role) {
+		return nil
+	}
+
+	user.Roles = removeRole(user.Roles, role)
+
+	jam.users[username] = user
+
+	jam.flushFile()
+
+	return nil
+}
+
+// EnrollTOTP generates a new TOTP secret for the user and returns it
+// The enrollment is only active after being confirmed by ConfirmTOTP
+func (jam *JSONAuthManager) EnrollTOTP(username string) (string, error) {
+	jam.Lock()
+	defer jam.Unlock()
+
+	user, exists := jam.users[username]
+
+	if !exists {
+		return "", fmt.Errorf("user does not exists")
+	}
+
+	if user.TOTPEnabled {
+		return "", fmt.Errorf("user already enrolled in TOTP")
+	}
+
+	secret, encrypted, err := newTOTPSecret()
+	if err != nil {
+		return "", err
+	}
+
+	user.TOTPSecret = encrypted
+
+	jam.users[username] = user
+
+	jam.flushFile()
+
+	return secret, nil
+}
+
+// ConfirmTOTP activates the pending TOTP enrollment of the user if the code is valid
+func (jam *JSONAuthManager) ConfirmTOTP(username, code string) error {
+	jam.Lock()
+	defer jam.Unlock()
+
+	user, exists := jam.users[username]
+
+	if !exists {
+		return fmt.Errorf("user does not exists")
+	}
+
+	if user.TOTPEnabled {
+		return fmt.Errorf("user already enrolled in TOTP")
+	}
+
+	step, err := checkTOTPCode(user.TOTPSecret, code, user.TOTPLastStep)
+	if err != nil {
+		return err
+	}
+
+	user.TOTPEnabled = true
+	user.TOTPLastStep = step
+
+	jam.users[username] = user
+
+	jam.flushFile()
+
+	return nil
+}
+
+// VerifyTOTP checks if the code is valid for the user TOTP enrollment
+// Each code is only accepted once
+func (jam *JSONAuthManager) VerifyTOTP(username, code string) error {
+	jam.Lock()
+	defer jam.Unlock()
+
+	user, exists := jam.users[username]
+
+	if !exists {
+		return fmt.Errorf("user does not exists")
+	}
+
+	if !user.TOTPEnabled {
+		return fmt.Errorf("user is not enrolled in TOTP")
+	}
+
+	step, err := checkTOTPCode(user.TOTPSecret, code, user.TOTPLastStep)
+	if err != nil {
+		return err
+	}
+
+	user.TOTPLastStep = step
+
+	jam.users[username] = user
+
+	jam.flushFile()
+
+	return nil
+}
+
+// DisableTOTP removes the TOTP enrollment of the user
+func (jam *JSONAuthManager) DisableTOTP(username string) error {
+	jam.Lock()
+	defer jam.Unlock()
+
+	user, exists := jam.users[username]
+
+	if !exists {
+		return fmt.Errorf("user does not exists")
+	}
+
+	user.TOTPSecret = ""
+	user.TOTPEnabled = false
+	user.TOTPLastStep = 0
+
+	jam.users[username] = user
+
+	jam.flushFile()
+
+	return nil
+}
@@ -0,0 +1,274 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/quan-to/chevron/internal/config"
+	"github.com/quan-to/chevron/internal/jwt"
+	"github.com/quan-to/chevron/pkg/database/memory"
+	"github.com/quan-to/chevron/pkg/interfaces"
+	"github.com/quan-to/chevron/pkg/models"
+	"github.com/quan-to/slog"
+)
+
+type jwtUser struct {
+	id          string
+	username    string
+	fullname    string
+	token       string
+	fingerPrint string
+	roles       []string
+	createdAt   time.Time
+	expiration  time.Time
+	claims      jwt.Claims
+}
+
+func (ju *jwtUser) GetId() string {
+	return ju.id
+}
+
+func (ju *jwtUser) GetUsername() string {
+	return ju.username
+}
+
+func (ju *jwtUser) GetToken() string {
+	return ju.token
+}
+
+func (ju *jwtUser) GetFullName() string {
+	return ju.fullname
+}
+
+func (ju *jwtUser) GetUserdata() interface{} {
+	return ju.claims
+}
+
+func (ju *jwtUser) GetCreatedAt() time.Time {
+	return ju.createdAt
+}
+
+func (ju *jwtUser) GetFingerPrint() string {
+	return ju.fingerPrint
+}
+
+func (ju *jwtUser) GetRoles() []string {
+	return ju.roles
+}
+
+// JWTTokenManager is a TokenManager that accepts JWTs signed by an external identity provider
+// Tokens that are not JWTs, like the ones generated by agentAdmin Login, are handled by the local TokenManager
+type JWTTokenManager struct {
+	log      slog.Instance
+	verifier *jwt.Verifier
+	local    interfaces.TokenManager
+	// revocations stores the invalidated JWTs, so they are shared by all instances and survive restarts
+	revocations TokenRevocationRepository
+}
+
+// MakeJWTTokenManager creates a TokenManager that validates JWTs against the JWKS specified in AGENT_JWT_JWKS
+// Non JWT tokens are delegated to local. If revocations is nil, the revoked JWTs are only kept in memory
+func MakeJWTTokenManager(logger slog.Instance, local interfaces.TokenManager, revocations TokenRevocationRepository) *JWTTokenManager {
+	if logger == nil {
+		logger = slog.Scope("JWT-TM")
+	} else {
+		logger = logger.SubScope("JWT-TM")
+	}
+
+	logger.Info("Creating JWT Token Manager using keys from %s", config.AgentJWTJWKS)
+
+	keys := jwt.MakeKeySource(logger, config.AgentJWTJWKS, config.AgentJWTJWKSRefresh)
+	err := keys.Refresh()
+	if err != nil {
+		logger.Error("Error loading JWKS. Will retry on first token: %s", err)
+	}
+
+	if revocations == nil {
+		revocations = memory.MakeMemoryDBDriver(logger)
+	}
+
+	return &JWTTokenManager{
+		log:         logger,
+		verifier:    jwt.MakeVerifier(keys, config.AgentJWTIssuer, config.AgentJWTAudience),
+		local:       local,
+		revocations: revocations,
+	}
+}
+
+func tokenHash(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
+
+// verifyJWT validates the token and maps its claims to an user
+func (jtm *JWTTokenManager) verifyJWT(token string) (*jwtUser, error) {
+	claims, err := jtm.verifier.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+
+	username := claims.String(config.AgentJWTUsernameClaim)
+	if username == "" {
+		username = claims.String("sub")
+	}
+
+	if username == "" {
+		return nil, fmt.Errorf("token has no username")
+	}
+
+	var roles []string
+	for _, role := range claims.Strings(config.AgentJWTRolesClaim) {
+		if models.IsValidRole(role) {
+			roles = append(roles, role)
+		}
+	}
+
+	if len(roles) == 0 {
+		roles = []string{models.RoleSigner}
+	}
+
+	fullname := claims.String("name")
+	if fullname == "" {
+		fullname = username
+	}
+
+	// Tokens without iat are considered issued before any user revocation
+	issuedAt, _ := claims.Time("iat")
+	createdAt := issuedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	expiration, _ := claims.Time("exp")
+
+	u := &jwtUser{
+		id:          claims.String("sub"),
+		username:    username,
+		fullname:    fullname,
+		token:       token,
+		fingerPrint: claims.String(config.AgentJWTFingerprintClaim),
+		roles:       roles,
+		createdAt:   createdAt,
+		expiration:  expiration,
+		claims:      claims,
+	}
+
+	err = jtm.checkRevoked(token, username, issuedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// checkRevoked returns an error if the token or all tokens of the user issued until issuedAt were invalidated
+// Tokens are rejected if the revocations cannot be checked
+func (jtm *JWTTokenManager) checkRevoked(token, username string, issuedAt time.Time) error {
+	_, err := jtm.revocations.GetTokenRevocation(tokenHash(token))
+	if err == nil {
+		return fmt.Errorf("token was invalidated")
+	}
+	if !strings.EqualFold(err.Error(), "not found") {
+		return fmt.Errorf("error checking token revocation: %v", err)
+	}
+
+	tr, err := jtm.revocations.GetUserTokenRevocation(strings.ToLower(username))
+	if err == nil && !issuedAt.After(tr.CreatedAt) {
+		return fmt.Errorf("token was invalidated")
+	}
+	if err != nil && !strings.EqualFold(err.Error(), "not found") {
+		return fmt.Errorf("error checking user token revocation: %v", err)
+	}
+
+	return nil
+}
+
+// AddUser adds a user to the local Token Manager and returns a login token
+func (jtm *JWTTokenManager) AddUser(user interfaces.UserData) string {
+	return jtm.local.AddUser(user)
+}
+
+// AddUserWithExpiration adds an user to the local Token Manager that will expires in `expiration` seconds.
+func (jtm *JWTTokenManager) AddUserWithExpiration(user interfaces.UserData, expiration int) string {
+	return jtm.local.AddUserWithExpiration(user, expiration)
+}
+
+// Verify verifies if the specified token is valid
+func (jtm *JWTTokenManager) Verify(token string) error {
+	if !jwt.IsJWT(token) {
+		return jtm.local.Verify(token)
+	}
+
+	_, err := jtm.verifyJWT(token)
+	if err != nil {
+		jtm.log.Debug("Invalid JWT: %s", err)
+	}
+
+	return err
+}
+
+// GetUserData returns the user data for the specified token
+func (jtm *JWTTokenManager) GetUserData(token string) interfaces.UserData {
+	if !jwt.IsJWT(token) {
+		return jtm.local.GetUserData(token)
+	}
+
+	u, err := jtm.verifyJWT(token)
+	if err != nil {
+		return nil
+	}
+
+	return u
+}
+
+// InvalidateToken invalidates the specified token
+// JWTs are stored as revoked until they expire
+func (jtm *JWTTokenManager) InvalidateToken(token string) error {
+	if !jwt.IsJWT(token) {
+		return jtm.local.InvalidateToken(token)
+	}
+
+	u, err := jtm.verifyJWT(token)
+	if err != nil {
+		return fmt.Errorf("not exists")
+	}
+
+	_, err = jtm.revocations.AddTokenRevocation(models.TokenRevocation{
+		TokenHash:  tokenHash(token),
+		Username:   strings.ToLower(u.username),
+		CreatedAt:  time.Now(),
+		Expiration: u.expiration.Add(jwt.DefaultLeeway),
+	})
+	if err != nil {
+		return fmt.Errorf("error revoking token: %v", err)
+	}
+
+	_, err = jtm.revocations.InvalidateTokenRevocations()
+	if err != nil {
+		jtm.log.Error("Error removing expired token revocations: %s", err)
+	}
+
+	return nil
+}
+
+// ActiveTokens returns the local tokens of the specified user that are not expired
+// JWTs are not stored so they are not listed
+func (jtm *JWTTokenManager) ActiveTokens(username string) ([]models.UserToken, error) {
+	return jtm.local.ActiveTokens(username)
+}
+
+// RevokeUserTokens invalidates all local tokens of the specified user and rejects the JWTs issued before now
+func (jtm *JWTTokenManager) RevokeUserTokens(username string) (int, error) {
+	_, err := jtm.revocations.AddTokenRevocation(models.TokenRevocation{
+		Username:  strings.ToLower(username),
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error revoking tokens of user %s: %v", username, err)
+	}
+
+	return jtm.local.RevokeUserTokens(username)
+}
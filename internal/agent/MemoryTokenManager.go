@@ -2,12 +2,15 @@ package agent
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	remote_signer "github.com/quan-to/chevron/internal/config"
 	"github.com/quan-to/chevron/pkg/interfaces"
+	"github.com/quan-to/chevron/pkg/models"
 	"github.com/quan-to/slog"
 )
 
@@ -150,3 +153,48 @@ func (mtm *MemoryTokenManager) GetUserData(token string) interfaces.UserData {
 
 	return mtm.storedTokens[token]
 }
+
+// ActiveTokens returns the tokens of the specified user that are not expired
+// The token values are not returned
+func (mtm *MemoryTokenManager) ActiveTokens(username string) ([]models.UserToken, error) {
+	mtm.lock.Lock()
+	defer mtm.lock.Unlock()
+
+	now := time.Now()
+	tokens := make([]models.UserToken, 0)
+
+	for _, u := range mtm.storedTokens {
+		if !strings.EqualFold(u.username, username) || now.After(u.expiration) {
+			continue
+		}
+		tokens = append(tokens, models.UserToken{
+			Fingerprint: u.fingerPrint,
+			Username:    u.username,
+			Fullname:    u.fullname,
+			CreatedAt:   u.createdAt,
+			Expiration:  u.expiration,
+		})
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.Before(tokens[j].CreatedAt)
+	})
+
+	return tokens, nil
+}
+
+// RevokeUserTokens removes all tokens of the specified user from the internal memory
+func (mtm *MemoryTokenManager) RevokeUserTokens(username string) (int, error) {
+	mtm.lock.Lock()
+	defer mtm.lock.Unlock()
+
+	n := 0
+	for token, u := range mtm.storedTokens {
+		if strings.EqualFold(u.username, username) {
+			delete(mtm.storedTokens, token)
+			n++
+		}
+	}
+
+	return n, nil
+}
@@ -0,0 +1,106 @@
+package agent
+
+import (
+	"github.com/quan-to/chevron/internal/config"
+	"github.com/quan-to/chevron/pkg/interfaces"
+	"github.com/quan-to/chevron/pkg/models"
+	"github.com/quan-to/slog"
+)
+
+const bootstrapPageSize = 100
+
+// legacyAdminUsername is the user that had admin access before user roles were introduced
+const legacyAdminUsername = "admin"
+
+// bootstrapAdmin ensures that at least one user has the admin role.
+// If no admin exists, the user specified by AGENT_ADMIN_USERNAME is promoted to admin,
+// being created with AGENT_ADMIN_PASSWORD if it does not exists yet
+func bootstrapAdmin(log slog.Instance, am interfaces.AuthManager) {
+	migrateLegacyAdmin(log, am)
+
+	hasAdmin, err := adminExists(am)
+	if err != nil {
+		log.Fatal("Error checking for admin users: %v", err)
+	}
+
+	if hasAdmin {
+		return
+	}
+
+	username := config.AgentAdminUsername
+	if username == "" {
+		log.Warn("There is no user with admin role and AGENT_ADMIN_USERNAME is not set. User administration will not be available")
+		return
+	}
+
+	if !am.UserExists(username) {
+		if config.AgentAdminPassword == "" {
+			log.Fatal("Admin user %s does not exists and AGENT_ADMIN_PASSWORD is not set", username)
+		}
+
+		log.Warn("Admin user %s does not exists. Creating it", username)
+		err = am.LoginAdd(username, config.AgentAdminPassword, "Administrator", config.AgentKeyFingerPrint)
+		if err != nil {
+			log.Fatal("Error adding admin user %s: %v", username, err)
+		}
+	}
+
+	log.Warn("Granting admin role to %s", username)
+	err = am.GrantRole(username, models.RoleAdmin)
+	if err != nil {
+		log.Fatal("Error granting admin role to %s: %v", username, err)
+	}
+}
+
+// migrateLegacyAdmin grants the admin role to the user that was the administrator before user roles were introduced.
+// Only users created before the roles have no stored roles, since revoking roles always keeps one. So this runs once
+func migrateLegacyAdmin(log slog.Instance, am interfaces.AuthManager) {
+	um, err := am.GetUser(legacyAdminUsername)
+	if err != nil || um == nil || len(um.Roles) > 0 {
+		return
+	}
+
+	log.Warn("User %s was the administrator before user roles. Granting admin role", legacyAdminUsername)
+	err = am.GrantRole(legacyAdminUsername, models.RoleAdmin)
+	if err != nil {
+		log.Fatal("Error granting admin role to %s: %v", legacyAdminUsername, err)
+	}
+}
+
+func adminExists(am interfaces.AuthManager) (bool, error) {
+	for pageStart := 0; ; pageStart += bootstrapPageSize {
+		users, err := am.ListUsers(pageStart, pageStart+bootstrapPageSize)
+		if err != nil {
+			return false, err
+		}
+
+		for _, user := range users {
+			if user.HasRole(models.RoleAdmin) && !user.Disabled {
+				return true, nil
+			}
+		}
+
+		if len(users) < bootstrapPageSize {
+			return false, nil
+		}
+	}
+}
+
+func addRole(roles []string, role string) []string {
+	return append(append([]string{}, roles...), role)
+}
+
+// removeRole removes role from the roles. Users keep at least the signer role,
+// so no stored roles always means a user created before roles were introduced
+func removeRole(roles []string, role string) []string {
+	res := make([]string, 0, len(roles))
+	for _, r := range roles {
+		if r != role {
+			res = append(res, r)
+		}
+	}
+	if len(res) == 0 {
+		res = append(res, models.RoleSigner)
+	}
+	return res
+}
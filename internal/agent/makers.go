@@ -35,6 +35,9 @@ type GPGRepository interface {
 	FetchGPGKeysWithoutSubKeys() (res []models.GPGKey, err error)
 	DeleteGPGKey(key models.GPGKey) error
 	UpdateGPGKey(key models.GPGKey) (err error)
+	RestoreGPGKey(key models.GPGKey) error
+	FetchGPGKeyHistory(fingerprint string) ([]models.GPGKeyHistory, error)
+	SearchGPGKeys(query models.GPGKeySearchQuery) (*models.GPGKeySearchResult, error)
 }
 
 type UserRepository interface {
@@ -45,6 +48,18 @@ type UserRepository interface {
 	InvalidateUserTokens() (int, error)
 	AddUser(um models.User) (string, error)
 	UpdateUser(um models.User) error
+	ListUsers(pageStart, pageEnd int) ([]models.User, error)
+	DeleteUser(um models.User) error
+	FetchUserTokens(username string) ([]models.UserToken, error)
+	RemoveUserTokens(username string) (int, error)
+}
+
+// TokenRevocationRepository stores the revocations of the JWTs issued by an external identity provider
+type TokenRevocationRepository interface {
+	AddTokenRevocation(tr models.TokenRevocation) (string, error)
+	GetTokenRevocation(tokenHash string) (*models.TokenRevocation, error)
+	GetUserTokenRevocation(username string) (*models.TokenRevocation, error)
+	InvalidateTokenRevocations() (int, error)
 }
 
 type HealthChecker interface {
@@ -55,6 +70,7 @@ type DatabaseHandler interface {
 	MigrationHandler
 	GPGRepository
 	UserRepository
+	TokenRevocationRepository
 	HealthChecker
 }
 
@@ -83,6 +99,9 @@ func makePostgresDBHandler(logger slog.Instance) (*pg.PostgreSQLDBDriver, error)
 	if err != nil {
 		return nil, err
 	}
+	if config.DatabaseTokenManager && !config.EnableRedis {
+		rdb.StartTokenReaper(config.DatabaseTokenReaperInterval)
+	}
 	return rdb, nil
 }
 
@@ -114,31 +133,12 @@ func MakeDatabaseHandler(logger slog.Instance) (dbh DatabaseHandler, err error)
 	if config.EnableRedis {
 		logger.Info("Redis enabled. Wrapping cache layer")
 		redisDriver := cache.MakeRedisDriver(dbh, logger)
-		var tlsConfig *tls.Config
-		if config.RedisTLSEnabled {
-			tlsConfig = &tls.Config{
-				InsecureSkipVerify: true,
-			}
-		}
-
-		if config.RedisClusterMode {
-			cluster := redis.NewClusterClient(&redis.ClusterOptions{
-				Addrs:     []string{config.RedisHost},
-				Username:  config.RedisUser,
-				Password:  config.RedisPass,
-				TLSConfig: tlsConfig,
-			})
-			err = redisDriver.Setup(cluster, config.RedisMaxLocalObjects, config.RedisLocalObjectTTL)
-		} else {
-			client := redis.NewClient(&redis.Options{
-				Addr:      config.RedisHost,
-				Username:  config.RedisUser,
-				Password:  config.RedisPass,
-				TLSConfig: tlsConfig,
-			})
-			err = redisDriver.Setup(client, config.RedisMaxLocalObjects, config.RedisLocalObjectTTL)
+		err = redisDriver.Setup(MakeRedisClient(), config.RedisMaxLocalObjects, config.RedisLocalObjectTTL)
+		if err != nil {
+			return nil, err
 		}
 
+		err = redisDriver.StartInvalidationListener(config.RedisInvalidationChannel)
 		if err != nil {
 			return nil, err
 		}
@@ -148,13 +148,52 @@ func MakeDatabaseHandler(logger slog.Instance) (dbh DatabaseHandler, err error)
 	return dbh, nil
 }
 
+// MakeRedisClient creates a Redis client with the REDIS_* configuration
+func MakeRedisClient() redis.UniversalClient {
+	var tlsConfig *tls.Config
+	if config.RedisTLSEnabled {
+		tlsConfig = &tls.Config{
+			InsecureSkipVerify: true,
+		}
+	}
+
+	if config.RedisClusterMode {
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:     []string{config.RedisHost},
+			Username:  config.RedisUser,
+			Password:  config.RedisPass,
+			TLSConfig: tlsConfig,
+		})
+	}
+
+	return redis.NewClient(&redis.Options{
+		Addr:      config.RedisHost,
+		Username:  config.RedisUser,
+		Password:  config.RedisPass,
+		TLSConfig: tlsConfig,
+	})
+}
+
 // MakeTokenManager creates an instance of token manager. If Rethink is enabled returns an DatabaseTokenManager, if not a MemoryTokenManager
+// If JWT authentication is enabled, the token manager is wrapped by a JWTTokenManager
 func MakeTokenManager(logger slog.Instance, dbHandler DatabaseHandler) interfaces.TokenManager {
+	var tm interfaces.TokenManager
+
 	if dbHandler != nil {
-		return MakeDatabaseTokenManager(logger, dbHandler)
+		tm = MakeDatabaseTokenManager(logger, dbHandler)
+	} else {
+		tm = MakeMemoryTokenManager(logger)
+	}
+
+	if config.AgentJWTAuth {
+		var revocations TokenRevocationRepository
+		if dbHandler != nil {
+			revocations = dbHandler
+		}
+		tm = MakeJWTTokenManager(logger, tm, revocations)
 	}
 
-	return MakeMemoryTokenManager(logger)
+	return tm
 }
 
 // MakeAuthManager creates an instance of auth manager. If Rethink is enabled returns an DatabaseAuthManager, if not a JSONAuthManager
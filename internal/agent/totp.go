@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/quan-to/chevron/internal/config"
+	"github.com/quan-to/chevron/internal/totp"
+)
+
+// newTOTPSecret generates a new TOTP secret and returns it both in plain text and encrypted with AGENT_TOTP_ENCRYPTION_KEY
+func newTOTPSecret() (secret, encrypted string, err error) {
+	if config.AgentTOTPEncryptionKey == "" {
+		return "", "", fmt.Errorf("TOTP is not available: AGENT_TOTP_ENCRYPTION_KEY is not set")
+	}
+
+	secret, err = totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	encrypted, err = totp.EncryptSecret(config.AgentTOTPEncryptionKey, secret)
+	if err != nil {
+		return "", "", fmt.Errorf("error encrypting TOTP secret: %v", err)
+	}
+
+	return secret, encrypted, nil
+}
+
+// checkTOTPCode validates the code against the encrypted TOTP secret
+// Codes of time steps up to lastStep were already used and are rejected. The step of the accepted code is returned
+func checkTOTPCode(encrypted, code string, lastStep int64) (int64, error) {
+	if encrypted == "" {
+		return 0, fmt.Errorf("user is not enrolled in TOTP")
+	}
+
+	secret, err := totp.DecryptSecret(config.AgentTOTPEncryptionKey, encrypted)
+	if err != nil {
+		return 0, fmt.Errorf("error decrypting TOTP secret: %v", err)
+	}
+
+	if lastStep < 0 {
+		lastStep = 0
+	}
+
+	step, ok := totp.ValidateAfter(secret, code, time.Now(), uint64(lastStep))
+	if !ok {
+		return 0, fmt.Errorf("invalid otp")
+	}
+
+	return int64(step), nil
+}
@@ -0,0 +1,259 @@
+package backendmigrate
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/quan-to/chevron/internal/keybackend"
+	"github.com/quan-to/chevron/internal/tools"
+	"github.com/quan-to/chevron/internal/vaultManager"
+	"github.com/quan-to/chevron/pkg/interfaces"
+	"github.com/quan-to/chevron/pkg/openpgp"
+	"github.com/quan-to/chevron/pkg/openpgp/armor"
+	"github.com/quan-to/chevron/pkg/openpgp/packet"
+	"github.com/quan-to/slog"
+)
+
+// BackendOptions describes a key backend used as source or destination of a migration
+type BackendOptions struct {
+	Type          string
+	Folder        string
+	Prefix        string
+	Base64Encoded bool
+}
+
+// Options are the options of a backend migration
+type Options struct {
+	From        BackendOptions
+	To          BackendOptions
+	DryRun      bool
+	Resume      bool
+	Password    string
+	NewPassword string
+}
+
+// Result counts the keys of a backend migration by outcome
+type Result struct {
+	Migrated int
+	Skipped  int
+	Failed   int
+}
+
+// MakeBackend creates the key backend described by opts
+func MakeBackend(log slog.Instance, opts BackendOptions) (interfaces.StorageBackend, error) {
+	switch opts.Type {
+	case "disk":
+		return keybackend.MakeSaveToDiskBackend(log, opts.Folder, opts.Prefix), nil
+	case "vault":
+		vm := vaultManager.MakeVaultManager(log, opts.Prefix)
+		if vm == nil {
+			return nil, fmt.Errorf("cannot initialize vault backend with prefix %q", opts.Prefix)
+		}
+		return vm, nil
+	}
+
+	return nil, fmt.Errorf("unknown backend type %q", opts.Type)
+}
+
+func decodeKeyData(data string, base64Encoded bool) (string, error) {
+	if !base64Encoded {
+		return data, nil
+	}
+
+	b, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+func encodeKeyData(data string, base64Encoded bool) string {
+	if !base64Encoded {
+		return data
+	}
+
+	return base64.StdEncoding.EncodeToString([]byte(data))
+}
+
+// reencryptKey decrypts the primary key and all subkeys with the current password and encrypts them with the new one
+func reencryptKey(armoredKey, currentPassword, newPassword string) (string, error) {
+	keys, err := tools.ReadKey(armoredKey)
+	if err != nil {
+		return "", err
+	}
+
+	serializedEntities := bytes.NewBuffer(nil)
+
+	for _, e := range keys {
+		if e.PrivateKey == nil {
+			return "", fmt.Errorf("key does not contain a private key")
+		}
+
+		if e.PrivateKey.Encrypted {
+			if err := e.PrivateKey.Decrypt([]byte(currentPassword)); err != nil {
+				return "", err
+			}
+		}
+
+		if err := e.PrivateKey.Encrypt([]byte(newPassword)); err != nil {
+			return "", err
+		}
+
+		for _, sub := range e.Subkeys {
+			if sub.PrivateKey == nil {
+				continue
+			}
+			if sub.PrivateKey.Encrypted {
+				if err := sub.PrivateKey.Decrypt([]byte(currentPassword)); err != nil {
+					return "", err
+				}
+			}
+			if err := sub.PrivateKey.Encrypt([]byte(newPassword)); err != nil {
+				return "", err
+			}
+		}
+
+		err = e.SerializePrivate(serializedEntities, &packet.Config{
+			DefaultHash: crypto.SHA512,
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	buf := bytes.NewBuffer(nil)
+	headers := map[string]string{
+		"Version": "GnuPG v2",
+		"Comment": "Generated by Chevron",
+	}
+
+	w, err := armor.Encode(buf, openpgp.PrivateKeyType, headers)
+	if err != nil {
+		return "", err
+	}
+	_, err = w.Write(serializedEntities.Bytes())
+	if err != nil {
+		return "", err
+	}
+	err = w.Close()
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// verifyMigratedKey re-reads the key from the backend and checks if it has the expected fingerprint
+func verifyMigratedKey(kb interfaces.StorageBackend, name, fingerPrint string, base64Encoded bool) error {
+	data, _, err := kb.Read(name)
+	if err != nil {
+		return fmt.Errorf("cannot read key back: %s", err)
+	}
+
+	keyData, err := decodeKeyData(data, base64Encoded)
+	if err != nil {
+		return fmt.Errorf("cannot decode key: %s", err)
+	}
+
+	fp, err := tools.GetFingerPrintFromKey(keyData)
+	if err != nil {
+		return fmt.Errorf("cannot read key fingerprint: %s", err)
+	}
+
+	if fp != fingerPrint {
+		return fmt.Errorf("fingerprint mismatch: expected %s got %s", fingerPrint, fp)
+	}
+
+	return nil
+}
+
+// migrateKey copies a single key to dst and returns true if it was skipped because it is already migrated
+func migrateKey(src, dst interfaces.StorageBackend, name string, opts Options) (skipped bool, err error) {
+	data, metadata, err := src.Read(name)
+	if err != nil {
+		return false, fmt.Errorf("cannot read key: %s", err)
+	}
+
+	keyData, err := decodeKeyData(data, opts.From.Base64Encoded)
+	if err != nil {
+		return false, fmt.Errorf("cannot decode key: %s", err)
+	}
+
+	fingerPrint, err := tools.GetFingerPrintFromKey(keyData)
+	if err != nil {
+		return false, fmt.Errorf("cannot read key fingerprint: %s", err)
+	}
+
+	if opts.Resume && verifyMigratedKey(dst, name, fingerPrint, opts.To.Base64Encoded) == nil {
+		return true, nil
+	}
+
+	if opts.NewPassword != "" {
+		var meta map[string]string
+		password := opts.Password
+
+		if metadata != "" {
+			err = json.Unmarshal([]byte(metadata), &meta)
+			if err != nil {
+				return false, fmt.Errorf("cannot decode metadata: %s", err)
+			}
+			if meta["password"] != "" {
+				password = meta["password"]
+			}
+		}
+
+		keyData, err = reencryptKey(keyData, password, opts.NewPassword)
+		if err != nil {
+			return false, fmt.Errorf("cannot re-encrypt key: %s", err)
+		}
+
+		if meta["password"] != "" {
+			meta["password"] = opts.NewPassword
+			mj, _ := json.Marshal(meta)
+			metadata = string(mj)
+		}
+	}
+
+	if opts.DryRun {
+		return false, nil
+	}
+
+	err = dst.SaveWithMetadata(name, encodeKeyData(keyData, opts.To.Base64Encoded), metadata)
+	if err != nil {
+		return false, fmt.Errorf("cannot save key: %s", err)
+	}
+
+	return false, verifyMigratedKey(dst, name, fingerPrint, opts.To.Base64Encoded)
+}
+
+// Migrate copies all keys and their metadata from src to dst. The report function is called with the outcome of each key.
+func Migrate(src, dst interfaces.StorageBackend, opts Options, report func(name string, skipped bool, err error)) (Result, error) {
+	var result Result
+
+	keys, err := src.List()
+	if err != nil {
+		return result, fmt.Errorf("cannot list keys from %s: %s", src.Path(), err)
+	}
+
+	for _, name := range keys {
+		skipped, err := migrateKey(src, dst, name, opts)
+		switch {
+		case err != nil:
+			result.Failed++
+		case skipped:
+			result.Skipped++
+		default:
+			result.Migrated++
+		}
+
+		if report != nil {
+			report(name, skipped, err)
+		}
+	}
+
+	return result, nil
+}
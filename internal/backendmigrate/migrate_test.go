@@ -0,0 +1,341 @@
+package backendmigrate
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/quan-to/chevron/internal/tools"
+	"github.com/quan-to/chevron/pkg/interfaces"
+	"github.com/quan-to/chevron/pkg/openpgp"
+	"github.com/quan-to/chevron/test"
+	"github.com/quan-to/slog"
+)
+
+const testKeyName = "privateTestKey.gpg"
+const testNewPassword = "a brand new password"
+
+var testFolder string
+var testKey string
+
+func TestMain(m *testing.M) {
+	slog.SetTestMode()
+
+	data, err := ioutil.ReadFile(path.Join("../../test/data", test.TestKeyFile))
+	if err != nil {
+		slog.Fatal("Error reading the test key: %s", err)
+	}
+	testKey = string(data)
+
+	testFolder, err = ioutil.TempDir("", "chevron-migrate-test")
+	if err != nil {
+		slog.Fatal("Error creating the test folder: %s", err)
+	}
+
+	code := m.Run()
+	slog.UnsetTestMode()
+	_ = os.RemoveAll(testFolder)
+	os.Exit(code)
+}
+
+// makeTestBackend creates a disk backend in a new folder inside the test folder
+func makeTestBackend(t *testing.T, name string) (interfaces.StorageBackend, BackendOptions) {
+	folder := path.Join(testFolder, name)
+	if err := os.Mkdir(folder, 0700); err != nil {
+		t.Fatalf("Error creating %s: %s", folder, err)
+	}
+
+	opts := BackendOptions{
+		Type:   "disk",
+		Folder: folder,
+		Prefix: "testkey_",
+	}
+
+	kb, err := MakeBackend(nil, opts)
+	if err != nil {
+		t.Fatalf("Error creating the backend: %s", err)
+	}
+
+	return kb, opts
+}
+
+// makeTestSource creates a disk backend with the test key and its password in the metadata
+func makeTestSource(t *testing.T, name string) (interfaces.StorageBackend, BackendOptions) {
+	src, opts := makeTestBackend(t, name)
+
+	err := src.SaveWithMetadata(testKeyName, testKey, `{"password":"`+test.TestKeyPassword+`"}`)
+	if err != nil {
+		t.Fatalf("Error saving the test key: %s", err)
+	}
+
+	return src, opts
+}
+
+// assertPassword checks if the primary key and all subkeys of armoredKey can only be decrypted with password
+func assertPassword(t *testing.T, armoredKey, password string) {
+	for _, pass := range []string{password, "wrong password"} {
+		keys, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armoredKey))
+		if err != nil {
+			t.Fatalf("Error reading the key: %s", err)
+		}
+
+		for _, e := range keys {
+			if !e.PrivateKey.Encrypted {
+				t.Fatalf("Expected the private key to be encrypted")
+			}
+
+			err = e.PrivateKey.Decrypt([]byte(pass))
+			if pass == password && err != nil {
+				t.Errorf("Expected the private key to be decrypted with %q: %s", pass, err)
+			}
+			if pass != password && err == nil {
+				t.Errorf("Expected the private key to not be decrypted with %q", pass)
+			}
+
+			for _, sub := range e.Subkeys {
+				if sub.PrivateKey == nil {
+					continue
+				}
+
+				err = sub.PrivateKey.Decrypt([]byte(pass))
+				if pass == password && err != nil {
+					t.Errorf("Expected the subkey %X to be decrypted with %q: %s", sub.PublicKey.KeyId, pass, err)
+				}
+				if pass != password && err == nil {
+					t.Errorf("Expected the subkey %X to not be decrypted with %q", sub.PublicKey.KeyId, pass)
+				}
+			}
+		}
+	}
+}
+
+func TestReencryptKey(t *testing.T) {
+	key, err := reencryptKey(testKey, test.TestKeyPassword, testNewPassword)
+	if err != nil {
+		t.Fatalf("Error re-encrypting the key: %s", err)
+	}
+
+	fp, err := tools.GetFingerPrintFromKey(key)
+	if err != nil {
+		t.Fatalf("Error reading the fingerprint: %s", err)
+	}
+
+	if fp != test.TestKeyFingerprint {
+		t.Errorf("Expected fingerprint %s got %s", test.TestKeyFingerprint, fp)
+	}
+
+	assertPassword(t, key, testNewPassword)
+
+	// Round trip back to the original password
+	key, err = reencryptKey(key, testNewPassword, test.TestKeyPassword)
+	if err != nil {
+		t.Fatalf("Error re-encrypting the key back: %s", err)
+	}
+
+	assertPassword(t, key, test.TestKeyPassword)
+
+	_, err = reencryptKey(testKey, "wrong password", testNewPassword)
+	if err == nil {
+		t.Errorf("Expected an error re-encrypting with a wrong password")
+	}
+
+	_, err = reencryptKey(test.TestPublicKey, "", testNewPassword)
+	if err == nil {
+		t.Errorf("Expected an error re-encrypting a public key")
+	}
+}
+
+func TestVerifyMigratedKey(t *testing.T) {
+	kb, _ := makeTestBackend(t, "verify")
+
+	err := kb.Save("plain", testKey)
+	if err != nil {
+		t.Fatalf("Error saving the key: %s", err)
+	}
+
+	err = kb.Save("encoded", base64.StdEncoding.EncodeToString([]byte(testKey)))
+	if err != nil {
+		t.Fatalf("Error saving the key: %s", err)
+	}
+
+	err = kb.Save("other", test.TestPublicKey2)
+	if err != nil {
+		t.Fatalf("Error saving the key: %s", err)
+	}
+
+	cases := []struct {
+		name          string
+		key           string
+		base64Encoded bool
+		valid         bool
+	}{
+		{"plain key", "plain", false, true},
+		{"base64 encoded key", "encoded", true, true},
+		{"base64 encoded key read as plain", "encoded", false, false},
+		{"plain key read as base64 encoded", "plain", true, false},
+		{"other key", "other", false, false},
+		{"missing key", "missing", false, false},
+	}
+
+	for _, c := range cases {
+		err := verifyMigratedKey(kb, c.key, test.TestKeyFingerprint, c.base64Encoded)
+		if c.valid && err != nil {
+			t.Errorf("%s: expected a valid key got %s", c.name, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("%s: expected an error", c.name)
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	src, srcOpts := makeTestSource(t, "migrate-src")
+	dst, dstOpts := makeTestBackend(t, "migrate-dst")
+	dstOpts.Base64Encoded = true
+
+	reported := map[string]error{}
+	result, err := Migrate(src, dst, Options{
+		From:        srcOpts,
+		To:          dstOpts,
+		NewPassword: testNewPassword,
+	}, func(name string, skipped bool, err error) {
+		reported[name] = err
+	})
+	if err != nil {
+		t.Fatalf("Error migrating: %s", err)
+	}
+
+	if result != (Result{Migrated: 1}) {
+		t.Errorf("Expected one migrated key got %+v", result)
+	}
+
+	if err, ok := reported[testKeyName]; !ok || err != nil {
+		t.Errorf("Expected %s to be reported as migrated got %v", testKeyName, err)
+	}
+
+	data, metadata, err := dst.Read(testKeyName)
+	if err != nil {
+		t.Fatalf("Error reading the migrated key: %s", err)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		t.Fatalf("Expected the migrated key to be base64 encoded: %s", err)
+	}
+
+	assertPassword(t, string(key), testNewPassword)
+
+	var meta map[string]string
+	if err := json.Unmarshal([]byte(metadata), &meta); err != nil {
+		t.Fatalf("Error decoding the migrated metadata: %s", err)
+	}
+
+	if meta["password"] != testNewPassword {
+		t.Errorf("Expected the metadata password to be updated to %q got %q", testNewPassword, meta["password"])
+	}
+
+	// The source is not changed
+	data, _, err = src.Read(testKeyName)
+	if err != nil || data != testKey {
+		t.Errorf("Expected the source key to be kept")
+	}
+}
+
+func TestMigrateDryRun(t *testing.T) {
+	src, srcOpts := makeTestSource(t, "dryrun-src")
+	dst, dstOpts := makeTestBackend(t, "dryrun-dst")
+
+	result, err := Migrate(src, dst, Options{
+		From:        srcOpts,
+		To:          dstOpts,
+		DryRun:      true,
+		NewPassword: testNewPassword,
+	}, nil)
+	if err != nil {
+		t.Fatalf("Error migrating: %s", err)
+	}
+
+	if result != (Result{Migrated: 1}) {
+		t.Errorf("Expected one migrated key got %+v", result)
+	}
+
+	files, err := ioutil.ReadDir(dstOpts.Folder)
+	if err != nil {
+		t.Fatalf("Error listing the destination: %s", err)
+	}
+
+	if len(files) != 0 {
+		t.Errorf("Expected the destination to be untouched got %d files", len(files))
+	}
+}
+
+func TestMigrateResume(t *testing.T) {
+	src, srcOpts := makeTestSource(t, "resume-src")
+	dst, dstOpts := makeTestBackend(t, "resume-dst")
+
+	err := src.Save("other.gpg", testKey)
+	if err != nil {
+		t.Fatalf("Error saving the key: %s", err)
+	}
+
+	// Already migrated
+	err = dst.SaveWithMetadata(testKeyName, testKey, "migrated")
+	if err != nil {
+		t.Fatalf("Error saving the key: %s", err)
+	}
+
+	// Broken in the destination
+	err = dst.Save("other.gpg", test.TestPublicKey2)
+	if err != nil {
+		t.Fatalf("Error saving the key: %s", err)
+	}
+
+	opts := Options{
+		From:   srcOpts,
+		To:     dstOpts,
+		Resume: true,
+	}
+
+	skipped := map[string]bool{}
+	result, err := Migrate(src, dst, opts, func(name string, wasSkipped bool, err error) {
+		if err != nil {
+			t.Errorf("Error migrating %s: %s", name, err)
+		}
+		skipped[name] = wasSkipped
+	})
+	if err != nil {
+		t.Fatalf("Error migrating: %s", err)
+	}
+
+	if result != (Result{Migrated: 1, Skipped: 1}) {
+		t.Errorf("Expected one migrated and one skipped key got %+v", result)
+	}
+
+	if !skipped[testKeyName] || skipped["other.gpg"] {
+		t.Errorf("Expected only %s to be skipped got %v", testKeyName, skipped)
+	}
+
+	_, metadata, err := dst.Read(testKeyName)
+	if err != nil || metadata != "migrated" {
+		t.Errorf("Expected the skipped key to be untouched")
+	}
+
+	if err := verifyMigratedKey(dst, "other.gpg", test.TestKeyFingerprint, false); err != nil {
+		t.Errorf("Expected the broken key to be migrated again: %s", err)
+	}
+
+	// Without resume the keys are migrated again
+	opts.Resume = false
+	result, err = Migrate(src, dst, opts, nil)
+	if err != nil {
+		t.Fatalf("Error migrating: %s", err)
+	}
+
+	if result != (Result{Migrated: 2}) {
+		t.Errorf("Expected two migrated keys got %+v", result)
+	}
+}
@@ -0,0 +1,122 @@
+package cluster
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+// publicKey is a X25519 public key
+type publicKey []byte
+
+// Bytes returns the encoded public key
+func (k publicKey) Bytes() []byte {
+	return k
+}
+
+// privateKey is an ephemeral X25519 key used for a single exchange
+type privateKey struct {
+	scalar []byte
+	public publicKey
+}
+
+// PublicKey returns the public key of the private key
+func (k *privateKey) PublicKey() publicKey {
+	return k.public
+}
+
+// ECDH returns the secret shared with the peer
+// It fails for low order peer keys
+func (k *privateKey) ECDH(peer publicKey) ([]byte, error) {
+	return curve25519.X25519(k.scalar, peer)
+}
+
+// generateKey creates an ephemeral X25519 key used for a single exchange
+func generateKey() (*privateKey, error) {
+	scalar := make([]byte, curve25519.ScalarSize)
+	if _, err := rand.Read(scalar); err != nil {
+		return nil, err
+	}
+
+	public, err := curve25519.X25519(scalar, curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return &privateKey{scalar: scalar, public: public}, nil
+}
+
+func encodePublicKey(key *privateKey) string {
+	return base64.StdEncoding.EncodeToString(key.PublicKey().Bytes())
+}
+
+func decodePublicKey(key string) (publicKey, error) {
+	data, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %v", err)
+	}
+
+	if len(data) != curve25519.PointSize {
+		return nil, fmt.Errorf("invalid public key size")
+	}
+
+	return data, nil
+}
+
+// sessionCipher derives the AES-GCM cipher shared by the two peers of an exchange
+// The key is bound to the challenge and both public keys
+func sessionCipher(private *privateKey, peer publicKey, challenge string, requesterKey, responderKey []byte) (cipher.AEAD, error) {
+	shared, err := private.ECDH(peer)
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha256.New()
+	h.Write(shared)
+	h.Write([]byte(challenge))
+	h.Write(requesterKey)
+	h.Write(responderKey)
+
+	block, err := aes.NewCipher(h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts the data and returns the base64 encoded nonce and payload
+func seal(aead cipher.AEAD, data []byte) (string, string, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", "", err
+	}
+
+	payload := aead.Seal(nil, nonce, data, nil)
+
+	return base64.StdEncoding.EncodeToString(nonce), base64.StdEncoding.EncodeToString(payload), nil
+}
+
+// open decrypts a payload created by seal
+func open(aead cipher.AEAD, nonce, payload string) ([]byte, error) {
+	n, err := base64.StdEncoding.DecodeString(nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nonce: %v", err)
+	}
+
+	if len(n) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce size")
+	}
+
+	p, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload: %v", err)
+	}
+
+	return aead.Open(nil, n, p, nil)
+}
@@ -0,0 +1,194 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/quan-to/chevron/internal/config"
+	"github.com/quan-to/chevron/internal/kubernetes"
+	"github.com/quan-to/chevron/pkg/interfaces"
+	"github.com/quan-to/slog"
+)
+
+// internalPath is the path of the internal endpoint in the remote signer server
+const internalPath = "/remoteSigner/__internal"
+
+// peerURL converts a peer in the format host[:port] or a full URL to the base URL of its internal endpoint
+func peerURL(peer string, defaultPort int) string {
+	peer = strings.TrimSpace(peer)
+	if strings.Contains(peer, "://") {
+		return strings.TrimSuffix(peer, "/")
+	}
+
+	if _, _, err := net.SplitHostPort(peer); err != nil {
+		peer = net.JoinHostPort(strings.Trim(peer, "[]"), strconv.Itoa(defaultPort))
+	}
+
+	return "http://" + peer + internalPath
+}
+
+func parsePeerList(peers []string) []string {
+	res := make([]string, 0)
+	for _, peer := range peers {
+		peer = strings.TrimSpace(peer)
+		if peer == "" || strings.HasPrefix(peer, "#") {
+			continue
+		}
+		res = append(res, peerURL(peer, config.HttpPort))
+	}
+	return res
+}
+
+// StaticDiscovery returns a fixed list of peers
+type StaticDiscovery struct {
+	peers []string
+}
+
+// MakeStaticDiscovery creates a peer discovery with a fixed list of peers in the format host[:port] or full URLs
+func MakeStaticDiscovery(peers []string) *StaticDiscovery {
+	return &StaticDiscovery{
+		peers: parsePeerList(peers),
+	}
+}
+
+// Name returns the name of the discovery method
+func (d *StaticDiscovery) Name() string {
+	return "static"
+}
+
+// Peers returns the configured peers
+func (d *StaticDiscovery) Peers(ctx context.Context) ([]string, error) {
+	return append([]string{}, d.peers...), nil
+}
+
+// FileDiscovery reads the peers from a file with one peer per line. The file is read on each call
+type FileDiscovery struct {
+	filename string
+}
+
+// MakeFileDiscovery creates a peer discovery that reads the peers from the specified file
+func MakeFileDiscovery(filename string) *FileDiscovery {
+	return &FileDiscovery{
+		filename: filename,
+	}
+}
+
+// Name returns the name of the discovery method
+func (d *FileDiscovery) Name() string {
+	return "file"
+}
+
+// Peers returns the peers listed in the file
+func (d *FileDiscovery) Peers(ctx context.Context) ([]string, error) {
+	data, err := ioutil.ReadFile(d.filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePeerList(strings.Split(string(data), "\n")), nil
+}
+
+// DNSDiscovery resolves the peers from DNS records
+// Names starting with _ are resolved as SRV records, others as A/AAAA records
+type DNSDiscovery struct {
+	name       string
+	port       int
+	lookupSRV  func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+	lookupHost func(ctx context.Context, host string) ([]string, error)
+}
+
+// MakeDNSDiscovery creates a peer discovery that resolves the specified DNS name
+func MakeDNSDiscovery(name string) *DNSDiscovery {
+	return &DNSDiscovery{
+		name:       name,
+		port:       config.HttpPort,
+		lookupSRV:  net.DefaultResolver.LookupSRV,
+		lookupHost: net.DefaultResolver.LookupHost,
+	}
+}
+
+// Name returns the name of the discovery method
+func (d *DNSDiscovery) Name() string {
+	return "dns"
+}
+
+// Peers returns one peer for each DNS record
+func (d *DNSDiscovery) Peers(ctx context.Context) ([]string, error) {
+	peers := make([]string, 0)
+
+	if strings.HasPrefix(d.name, "_") {
+		_, records, err := d.lookupSRV(ctx, "", "", d.name)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, srv := range records {
+			host := strings.TrimSuffix(srv.Target, ".")
+			peers = append(peers, peerURL(net.JoinHostPort(host, strconv.Itoa(int(srv.Port))), d.port))
+		}
+
+		return peers, nil
+	}
+
+	addrs, err := d.lookupHost(ctx, d.name)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, addr := range addrs {
+		peers = append(peers, peerURL(net.JoinHostPort(addr, strconv.Itoa(d.port)), d.port))
+	}
+
+	return peers, nil
+}
+
+// MakePeerDiscovery creates the peer discovery selected in the configuration
+// Returns nil if cluster discovery is disabled
+func MakePeerDiscovery(log slog.Instance) (interfaces.PeerDiscovery, error) {
+	if log == nil {
+		log = slog.Scope("Cluster")
+	} else {
+		log = log.SubScope("Cluster")
+	}
+
+	discovery := config.ClusterDiscovery
+	if discovery == "" {
+		discovery = "none"
+		if kubernetes.InKubernetes() {
+			discovery = "kubernetes"
+		}
+	}
+
+	log.Info("Cluster peer discovery: %s", discovery)
+
+	switch discovery {
+	case "none":
+		return nil, nil
+	case "kubernetes":
+		if !kubernetes.InKubernetes() {
+			return nil, fmt.Errorf("kubernetes discovery selected but not running in kubernetes")
+		}
+		return kubernetes.MakeDiscovery(), nil
+	case "static":
+		if config.ClusterPeers == "" {
+			return nil, fmt.Errorf("CLUSTER_PEERS is required for static discovery")
+		}
+		return MakeStaticDiscovery(strings.Split(config.ClusterPeers, ",")), nil
+	case "file":
+		if config.ClusterPeersFile == "" {
+			return nil, fmt.Errorf("CLUSTER_PEERS_FILE is required for file discovery")
+		}
+		return MakeFileDiscovery(config.ClusterPeersFile), nil
+	case "dns":
+		if config.ClusterDNSName == "" {
+			return nil, fmt.Errorf("CLUSTER_DNS_NAME is required for dns discovery")
+		}
+		return MakeDNSDiscovery(config.ClusterDNSName), nil
+	}
+
+	return nil, fmt.Errorf("invalid cluster discovery %q", discovery)
+}
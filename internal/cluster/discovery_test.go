@@ -0,0 +1,174 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/quan-to/chevron/internal/config"
+)
+
+func TestPeerURL(t *testing.T) {
+	cases := map[string]string{
+		"10.0.0.2":                               "http://10.0.0.2:5100/remoteSigner/__internal",
+		"10.0.0.2:8080":                          "http://10.0.0.2:8080/remoteSigner/__internal",
+		"node-a":                                 "http://node-a:5100/remoteSigner/__internal",
+		"::1":                                    "http://[::1]:5100/remoteSigner/__internal",
+		"[::1]:8080":                             "http://[::1]:8080/remoteSigner/__internal",
+		"https://node-a.example.com/__internal/": "https://node-a.example.com/__internal",
+	}
+
+	for peer, expected := range cases {
+		if u := peerURL(peer, 5100); u != expected {
+			t.Errorf("%s: expected %s got %s", peer, expected, u)
+		}
+	}
+}
+
+func TestStaticAndFileDiscovery(t *testing.T) {
+	defer config.Apply(config.Current())
+	config.HttpPort = 5100
+
+	expected := []string{
+		"http://node-a:5100/remoteSigner/__internal",
+		"http://node-b:6000/remoteSigner/__internal",
+	}
+
+	peers, err := MakeStaticDiscovery([]string{"node-a", " node-b:6000 ", ""}).Peers(context.Background())
+	if err != nil || !reflect.DeepEqual(peers, expected) {
+		t.Errorf("expected static peers %v got %v: %v", expected, peers, err)
+	}
+
+	dir, err := ioutil.TempDir("", "cluster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := path.Join(dir, "peers")
+	err = ioutil.WriteFile(filename, []byte("# cluster nodes\nnode-a\n\nnode-b:6000\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := MakeFileDiscovery(filename)
+	peers, err = d.Peers(context.Background())
+	if err != nil || !reflect.DeepEqual(peers, expected) {
+		t.Errorf("expected file peers %v got %v: %v", expected, peers, err)
+	}
+
+	_ = ioutil.WriteFile(filename, []byte("node-c\n"), 0600)
+	peers, _ = d.Peers(context.Background())
+	if len(peers) != 1 || peers[0] != "http://node-c:5100/remoteSigner/__internal" {
+		t.Errorf("expected file to be read again got %v", peers)
+	}
+
+	if _, err := MakeFileDiscovery(path.Join(dir, "missing")).Peers(context.Background()); err == nil {
+		t.Errorf("expected missing file to fail")
+	}
+}
+
+func TestDNSDiscovery(t *testing.T) {
+	d := &DNSDiscovery{
+		name: "_chevron._tcp.example.com",
+		port: 5100,
+		lookupSRV: func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+			if name != "_chevron._tcp.example.com" {
+				return "", nil, fmt.Errorf("unexpected name %s", name)
+			}
+			return name, []*net.SRV{
+				{Target: "node-a.example.com.", Port: 6000},
+				{Target: "node-b.example.com.", Port: 6001},
+			}, nil
+		},
+		lookupHost: func(ctx context.Context, host string) ([]string, error) {
+			if host != "chevron.example.com" {
+				return nil, fmt.Errorf("unexpected host %s", host)
+			}
+			return []string{"10.0.0.2", "10.0.0.3"}, nil
+		},
+	}
+
+	peers, err := d.Peers(context.Background())
+	expected := []string{
+		"http://node-a.example.com:6000/remoteSigner/__internal",
+		"http://node-b.example.com:6001/remoteSigner/__internal",
+	}
+	if err != nil || !reflect.DeepEqual(peers, expected) {
+		t.Errorf("expected SRV peers %v got %v: %v", expected, peers, err)
+	}
+
+	d.name = "chevron.example.com"
+	peers, err = d.Peers(context.Background())
+	expected = []string{
+		"http://10.0.0.2:5100/remoteSigner/__internal",
+		"http://10.0.0.3:5100/remoteSigner/__internal",
+	}
+	if err != nil || !reflect.DeepEqual(peers, expected) {
+		t.Errorf("expected A peers %v got %v: %v", expected, peers, err)
+	}
+}
+
+func TestMakePeerDiscovery(t *testing.T) {
+	defer config.Apply(config.Current())
+
+	config.ClusterDiscovery = ""
+	if d, err := MakePeerDiscovery(nil); d != nil || err != nil {
+		t.Errorf("expected discovery to be disabled outside kubernetes got %v: %v", d, err)
+	}
+
+	config.ClusterDiscovery = "static"
+	config.ClusterPeers = ""
+	if _, err := MakePeerDiscovery(nil); err == nil {
+		t.Errorf("expected static discovery without peers to fail")
+	}
+
+	config.ClusterPeers = "node-a,node-b"
+	if d, err := MakePeerDiscovery(nil); err != nil || d.Name() != "static" {
+		t.Errorf("expected static discovery got %v: %v", d, err)
+	}
+
+	config.ClusterDiscovery = "dns"
+	config.ClusterDNSName = "chevron.example.com"
+	if d, err := MakePeerDiscovery(nil); err != nil || d.Name() != "dns" {
+		t.Errorf("expected dns discovery got %v: %v", d, err)
+	}
+
+	config.ClusterDiscovery = "file"
+	config.ClusterPeersFile = "/etc/chevron/peers"
+	if d, err := MakePeerDiscovery(nil); err != nil || d.Name() != "file" {
+		t.Errorf("expected file discovery got %v: %v", d, err)
+	}
+
+	config.ClusterDiscovery = "kubernetes"
+	if _, err := MakePeerDiscovery(nil); err == nil {
+		t.Errorf("expected kubernetes discovery to fail outside kubernetes")
+	}
+
+	config.ClusterDiscovery = "multicast"
+	if _, err := MakePeerDiscovery(nil); err == nil {
+		t.Errorf("expected invalid discovery to fail")
+	}
+}
+
+func TestIsLocalPeer(t *testing.T) {
+	defer config.Apply(config.Current())
+	config.HttpPort = 5100
+
+	if !isLocalPeer("http://127.0.0.1:5100/remoteSigner/__internal") || !isLocalPeer("http://localhost:5100/remoteSigner/__internal") {
+		t.Errorf("expected loopback peers on the same port to be local")
+	}
+
+	if isLocalPeer("http://127.0.0.1:5101/remoteSigner/__internal") {
+		t.Errorf("expected other ports to not be local")
+	}
+
+	if isLocalPeer("http://192.0.2.10:5100/remoteSigner/__internal") {
+		t.Errorf("expected other hosts to not be local")
+	}
+}
@@ -0,0 +1,389 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quan-to/chevron/internal/tools"
+	"github.com/quan-to/chevron/pkg/interfaces"
+	"github.com/quan-to/slog"
+)
+
+const (
+	// ChallengePath is the path of the challenge endpoint relative to the internal endpoint
+	ChallengePath = "/__cluster/challenge"
+	// PasswordsPath is the path of the password exchange endpoint relative to the internal endpoint
+	PasswordsPath = "/__cluster/passwords"
+	// SignatureHeader is the header with the master key signature of the request and response bodies
+	SignatureHeader = "signature"
+
+	challengeTTL       = time.Minute
+	challengeNonceSize = 16
+	maxPayloadSize     = 10 * 1024 * 1024
+)
+
+// ErrUnauthorized is returned when the peer cannot prove it holds the master key
+var ErrUnauthorized = errors.New("peer is not authorized")
+
+// ChallengeResponse is returned by the challenge endpoint
+type ChallengeResponse struct {
+	Challenge string `json:"challenge"`
+}
+
+// PasswordRequest is signed with the master key by the peer requesting the passwords
+type PasswordRequest struct {
+	Challenge string `json:"challenge"`
+	PublicKey string `json:"publicKey"`
+}
+
+// PasswordResponse is signed with the master key by the peer sending the passwords
+// The payload is only readable by the peer that made the request
+type PasswordResponse struct {
+	Challenge string `json:"challenge"`
+	PublicKey string `json:"publicKey"`
+	Nonce     string `json:"nonce"`
+	Payload   string `json:"payload"`
+}
+
+// PasswordExchange shares the master key encrypted passwords between cluster peers
+// Both peers prove they hold the master key by signing the exchange with it
+type PasswordExchange struct {
+	sync.Mutex
+	sm     interfaces.SecretsManager
+	client *http.Client
+	// challengeKey authenticates the issued challenges, so they are not stored until used
+	challengeKey []byte
+	// usedChallenges maps the challenges already answered to their expiration
+	usedChallenges map[string]time.Time
+	log            slog.Instance
+}
+
+// MakePasswordExchange creates a password exchange using the master key of the secrets manager
+func MakePasswordExchange(log slog.Instance, sm interfaces.SecretsManager) *PasswordExchange {
+	if log == nil {
+		log = slog.Scope("Cluster")
+	} else {
+		log = log.SubScope("Cluster")
+	}
+
+	challengeKey := make([]byte, 32)
+	if _, err := rand.Read(challengeKey); err != nil {
+		log.Fatal("Error generating challenge key: %s", err)
+	}
+
+	return &PasswordExchange{
+		sm:             sm,
+		client:         &http.Client{Timeout: 30 * time.Second},
+		challengeKey:   challengeKey,
+		usedChallenges: map[string]time.Time{},
+		log:            log,
+	}
+}
+
+// NewChallenge creates a single use challenge that must be signed by the peer requesting the passwords
+// The challenge carries its expiration and is authenticated with the challengeKey, so nothing is stored until it is used
+func (pe *PasswordExchange) NewChallenge() (string, error) {
+	return pe.newChallenge(time.Now().Add(challengeTTL))
+}
+
+func (pe *PasswordExchange) newChallenge(expiration time.Time) (string, error) {
+	data := make([]byte, 8+challengeNonceSize)
+	binary.BigEndian.PutUint64(data, uint64(expiration.Unix()))
+	if _, err := rand.Read(data[8:]); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(append(data, pe.challengeMAC(data)...)), nil
+}
+
+func (pe *PasswordExchange) challengeMAC(data []byte) []byte {
+	mac := hmac.New(sha256.New, pe.challengeKey)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// consumeChallenge returns true if the challenge was issued by this node and is not expired. A challenge can only be used once
+// It must only be called for signed requests, so only the peers holding the master key can fill usedChallenges
+func (pe *PasswordExchange) consumeChallenge(challenge string) bool {
+	raw, err := base64.RawURLEncoding.DecodeString(challenge)
+	if err != nil || len(raw) != 8+challengeNonceSize+sha256.Size {
+		return false
+	}
+
+	data, mac := raw[:8+challengeNonceSize], raw[8+challengeNonceSize:]
+	if !hmac.Equal(mac, pe.challengeMAC(data)) {
+		return false
+	}
+
+	now := time.Now()
+	expiration := time.Unix(int64(binary.BigEndian.Uint64(data)), 0)
+	if !now.Before(expiration) {
+		return false
+	}
+
+	pe.Lock()
+	defer pe.Unlock()
+
+	for c, exp := range pe.usedChallenges {
+		if now.After(exp) {
+			delete(pe.usedChallenges, c)
+		}
+	}
+
+	if _, used := pe.usedChallenges[challenge]; used {
+		return false
+	}
+
+	pe.usedChallenges[challenge] = expiration
+
+	return true
+}
+
+func (pe *PasswordExchange) sign(ctx context.Context, data []byte) (string, error) {
+	signature, err := pe.sm.SignWithMasterKey(ctx, data)
+	if err != nil {
+		return "", err
+	}
+
+	return tools.GPG2Quanto(signature, pe.sm.GetMasterKeyFingerPrint(ctx), "SHA512"), nil
+}
+
+func (pe *PasswordExchange) verify(ctx context.Context, data []byte, signature string) error {
+	if signature == "" {
+		return fmt.Errorf("%w: no signature", ErrUnauthorized)
+	}
+
+	gpgSig := tools.Quanto2GPG(signature)
+	if gpgSig == "" {
+		return fmt.Errorf("%w: invalid signature format", ErrUnauthorized)
+	}
+
+	if err := pe.sm.VerifyMasterKeySignature(ctx, data, gpgSig); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnauthorized, err)
+	}
+
+	return nil
+}
+
+// VerifySignature returns ErrUnauthorized if signature is not a valid master key signature of data
+func (pe *PasswordExchange) VerifySignature(ctx context.Context, data []byte, signature string) error {
+	return pe.verify(ctx, data, signature)
+}
+
+// AnswerPasswordRequest validates a signed password request and returns the response body with its signature
+// The passwords are encrypted with a key only known by the requesting peer
+func (pe *PasswordExchange) AnswerPasswordRequest(ctx context.Context, body []byte, signature string) ([]byte, string, error) {
+	var req PasswordRequest
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, "", fmt.Errorf("invalid password request: %v", err)
+	}
+
+	if err := pe.verify(ctx, body, signature); err != nil {
+		return nil, "", err
+	}
+
+	if !pe.consumeChallenge(req.Challenge) {
+		return nil, "", fmt.Errorf("%w: invalid or expired challenge", ErrUnauthorized)
+	}
+
+	peerKey, err := decodePublicKey(req.PublicKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	key, err := generateKey()
+	if err != nil {
+		return nil, "", err
+	}
+
+	aead, err := sessionCipher(key, peerKey, req.Challenge, peerKey.Bytes(), key.PublicKey().Bytes())
+	if err != nil {
+		return nil, "", err
+	}
+
+	passwords, _ := json.Marshal(pe.sm.GetPasswords(ctx))
+
+	nonce, payload, err := seal(aead, passwords)
+	if err != nil {
+		return nil, "", err
+	}
+
+	res, _ := json.Marshal(PasswordResponse{
+		Challenge: req.Challenge,
+		PublicKey: encodePublicKey(key),
+		Nonce:     nonce,
+		Payload:   payload,
+	})
+
+	resSignature, err := pe.sign(ctx, res)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return res, resSignature, nil
+}
+
+func (pe *PasswordExchange) do(ctx context.Context, method, url string, body []byte, header http.Header) ([]byte, http.Header, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if header != nil {
+		req.Header = header
+	}
+
+	res, err := pe.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(res.Body, maxPayloadSize))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("%s %s returned %d: %s", method, url, res.StatusCode, tools.TruncateFieldForDisplay(string(data)))
+	}
+
+	return data, res.Header, nil
+}
+
+// FetchPasswords requests the master key encrypted passwords from the peer
+// peerURL is the base URL of the peer internal endpoint, for example http://10.0.0.1:5100/remoteSigner/__internal
+func (pe *PasswordExchange) FetchPasswords(ctx context.Context, peerURL string) (map[string]string, error) {
+	peerURL = strings.TrimSuffix(peerURL, "/")
+
+	data, _, err := pe.do(ctx, http.MethodGet, peerURL+ChallengePath, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error getting challenge: %v", err)
+	}
+
+	var challenge ChallengeResponse
+	if err := json.Unmarshal(data, &challenge); err != nil || challenge.Challenge == "" {
+		return nil, fmt.Errorf("invalid challenge response")
+	}
+
+	key, err := generateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	reqBody, _ := json.Marshal(PasswordRequest{
+		Challenge: challenge.Challenge,
+		PublicKey: encodePublicKey(key),
+	})
+
+	signature, err := pe.sign(ctx, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("error signing password request: %v", err)
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set(SignatureHeader, signature)
+
+	data, resHeader, err := pe.do(ctx, http.MethodPost, peerURL+PasswordsPath, reqBody, header)
+	if err != nil {
+		return nil, fmt.Errorf("error requesting passwords: %v", err)
+	}
+
+	if err := pe.verify(ctx, data, resHeader.Get(SignatureHeader)); err != nil {
+		return nil, err
+	}
+
+	var res PasswordResponse
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, fmt.Errorf("invalid password response: %v", err)
+	}
+
+	if res.Challenge != challenge.Challenge {
+		return nil, fmt.Errorf("%w: response is not for the current challenge", ErrUnauthorized)
+	}
+
+	peerKey, err := decodePublicKey(res.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := sessionCipher(key, peerKey, challenge.Challenge, key.PublicKey().Bytes(), peerKey.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := open(aead, res.Nonce, res.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting passwords: %v", err)
+	}
+
+	var passwords map[string]string
+	if err := json.Unmarshal(plain, &passwords); err != nil {
+		return nil, fmt.Errorf("invalid passwords payload: %v", err)
+	}
+
+	return passwords, nil
+}
+
+// fetchPasswords calls FetchPasswords recovering from panics, so a misbehaving peer cannot stop the server
+func (pe *PasswordExchange) fetchPasswords(ctx context.Context, peer string) (passwords map[string]string, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("panic fetching passwords: %v", rec)
+		}
+	}()
+
+	return pe.FetchPasswords(ctx, peer)
+}
+
+// Sync fetches the passwords from all peers, stores them in the secrets manager and unlocks the local keys
+// Returns the number of passwords received. Peers that fail are logged and skipped
+func (pe *PasswordExchange) Sync(ctx context.Context, peers []string, gpg interfaces.PGPManager) int {
+	passwordCount := 0
+
+	for _, peer := range peers {
+		passwords, err := pe.fetchPasswords(ctx, peer)
+		if err != nil {
+			pe.log.Error("Error fetching unlock passwords from %s: %s", peer, err)
+			continue
+		}
+
+		pe.log.Info("Received %d passwords from %s", len(passwords), peer)
+		passwordCount += len(passwords)
+
+		for fp, pass := range passwords {
+			pe.sm.PutEncryptedPassword(ctx, fp, pass)
+		}
+	}
+
+	if passwordCount == 0 {
+		pe.log.Info("No passwords received")
+		return 0
+	}
+
+	pe.log.Info("Received %d passwords from %d peers. Triggering Local Unlock", passwordCount, len(peers))
+	pe.sm.UnlockLocalKeys(ctx, gpg)
+
+	return passwordCount
+}
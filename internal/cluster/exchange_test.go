@@ -0,0 +1,138 @@
+package cluster
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSessionCipher(t *testing.T) {
+	requester, err := generateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responder, err := generateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requesterPub, err := decodePublicKey(encodePublicKey(requester))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responderPub, err := decodePublicKey(encodePublicKey(responder))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealer, err := sessionCipher(responder, requesterPub, "challenge", requesterPub.Bytes(), responderPub.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nonce, payload, err := seal(sealer, []byte("passwords"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opener, err := sessionCipher(requester, responderPub, "challenge", requesterPub.Bytes(), responderPub.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := open(opener, nonce, payload)
+	if err != nil || string(data) != "passwords" {
+		t.Fatalf("expected payload to be decrypted got %q: %v", data, err)
+	}
+
+	other, _ := generateKey()
+	otherCipher, _ := sessionCipher(other, responderPub, "challenge", requesterPub.Bytes(), responderPub.Bytes())
+	if _, err := open(otherCipher, nonce, payload); err == nil {
+		t.Errorf("expected other peers to not decrypt the payload")
+	}
+
+	wrongChallenge, _ := sessionCipher(requester, responderPub, "other", requesterPub.Bytes(), responderPub.Bytes())
+	if _, err := open(wrongChallenge, nonce, payload); err == nil {
+		t.Errorf("expected payload to be bound to the challenge")
+	}
+
+	if _, err := decodePublicKey("invalid"); err == nil {
+		t.Errorf("expected invalid public key to fail")
+	}
+
+	if _, err := decodePublicKey(base64.StdEncoding.EncodeToString(make([]byte, 31))); err == nil {
+		t.Errorf("expected public key with invalid size to fail")
+	}
+
+	lowOrder, err := decodePublicKey(base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	if err != nil {
+		t.Fatalf("error decoding low order public key: %v", err)
+	}
+
+	if _, err := sessionCipher(requester, lowOrder, "challenge", lowOrder.Bytes(), responderPub.Bytes()); err == nil {
+		t.Errorf("expected low order public key to fail")
+	}
+}
+
+func TestChallenges(t *testing.T) {
+	pe := MakePasswordExchange(nil, nil)
+
+	c, err := pe.NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !pe.consumeChallenge(c) {
+		t.Errorf("expected challenge to be valid")
+	}
+
+	if pe.consumeChallenge(c) {
+		t.Errorf("expected challenge to be single use")
+	}
+
+	if pe.consumeChallenge("unknown") {
+		t.Errorf("expected unknown challenge to be invalid")
+	}
+
+	c, _ = pe.newChallenge(time.Now().Add(-time.Second))
+	if pe.consumeChallenge(c) {
+		t.Errorf("expected expired challenge to be invalid")
+	}
+
+	c, _ = pe.NewChallenge()
+	raw, _ := base64.RawURLEncoding.DecodeString(c)
+	raw[0] ^= 0xFF
+	if pe.consumeChallenge(base64.RawURLEncoding.EncodeToString(raw)) {
+		t.Errorf("expected tampered challenge to be invalid")
+	}
+
+	other := MakePasswordExchange(nil, nil)
+	c, _ = other.NewChallenge()
+	if pe.consumeChallenge(c) {
+		t.Errorf("expected challenge of other node to be invalid")
+	}
+
+	for i := 0; i < 2048; i++ {
+		if _, err := pe.NewChallenge(); err != nil {
+			t.Fatalf("unexpected error creating challenge %d: %v", i, err)
+		}
+	}
+
+	if len(pe.usedChallenges) != 1 {
+		t.Errorf("expected only the used challenges to be stored got %d", len(pe.usedChallenges))
+	}
+}
+
+func TestVerifyMalformedSignature(t *testing.T) {
+	pe := MakePasswordExchange(nil, nil)
+
+	for _, signature := range []string{"a_b_c", "a_b_", "a$b$cd", "a_b_abcde", "broken"} {
+		if err := pe.verify(context.Background(), []byte("data"), signature); !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("expected %q to be unauthorized got %v", signature, err)
+		}
+	}
+}
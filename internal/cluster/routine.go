@@ -0,0 +1,100 @@
+package cluster
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/quan-to/chevron/internal/config"
+	"github.com/quan-to/chevron/pkg/interfaces"
+)
+
+// isLocalPeer returns true if the peer URL points to this node
+func isLocalPeer(peer string) bool {
+	u, err := url.Parse(peer)
+	if err != nil {
+		return false
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = "80"
+	}
+
+	if port != strconv.Itoa(config.HttpPort) {
+		return false
+	}
+
+	ip := net.ParseIP(u.Hostname())
+	if ip == nil {
+		return u.Hostname() == "localhost"
+	}
+
+	if ip.IsLoopback() {
+		return true
+	}
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+
+	for _, addr := range addrs {
+		if n, ok := addr.(*net.IPNet); ok && n.IP.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SyncWith fetches the passwords from the peers found by the discovery and unlocks the local keys
+// Returns the number of passwords received
+func (pe *PasswordExchange) SyncWith(ctx context.Context, discovery interfaces.PeerDiscovery, gpg interfaces.PGPManager) int {
+	peers, err := discovery.Peers(ctx)
+	if err != nil {
+		pe.log.Error("Error discovering peers with %s: %s", discovery.Name(), err)
+		return 0
+	}
+
+	others := make([]string, 0, len(peers))
+	for _, peer := range peers {
+		if !isLocalPeer(peer) {
+			others = append(others, peer)
+		}
+	}
+
+	pe.log.Info("There are %d other nodes. Fetching encrypted passwords...", len(others))
+
+	return pe.Sync(ctx, others, gpg)
+}
+
+// Routine periodically fetches the key passwords from the cluster peers and unlocks the local keys until stopSig receives a value
+func Routine(stopSig chan bool, exchange *PasswordExchange, discovery interfaces.PeerDiscovery, gpg interfaces.PGPManager) {
+	log := exchange.log
+	log.Info("Starting Cluster Routine with %s discovery", discovery.Name())
+
+	randomWaitTime := time.Duration(rand.Int31n(5)*1000+1000) * time.Millisecond
+	log.Info("To avoid concurrency on cluster starting we're waiting 1 second plus some random time")
+	log.Info("The exact time is %s", randomWaitTime)
+
+	wait := randomWaitTime
+
+	for {
+		select {
+		case <-stopSig:
+			log.Info("Cluster Routine Stopped")
+			return
+		case <-time.After(wait):
+		}
+
+		log.Info("Checking for other remote-signer nodes...")
+		exchange.SyncWith(context.Background(), discovery, gpg)
+
+		wait = config.ClusterSyncInterval
+		log.Info("Sleeping for %s", wait)
+	}
+}
@@ -0,0 +1,289 @@
+package config
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/quan-to/slog"
+)
+
+// Config is the server configuration.
+//
+// Each field is loaded from the variable in the env tag. Other names in the tag are deprecated aliases.
+// The configuration file uses the same names in lower case, for example http_port.
+// Fields tagged as secret are hidden by Dump when redacting
+type Config struct {
+	SyslogServer                string        `env:"SYSLOG_IP" default:"127.0.0.1"`
+	SyslogFacility              string        `env:"SYSLOG_FACILITY" default:"LOG_USER"`
+	PrivateKeyFolder            string        `env:"PRIVATE_KEY_FOLDER" default:"./keys"`
+	KeyPrefix                   string        `env:"KEY_PREFIX"`
+	SKSServer                   string        `env:"SKS_SERVER"`
+	HttpPort                    int           `env:"HTTP_PORT" default:"5100"`
+	HttpBindAddress             string        `env:"HTTP_BIND_ADDRESS" default:"0.0.0.0"`
+	HttpReadTimeout             time.Duration `env:"HTTP_READ_TIMEOUT" default:"30s"`
+	HttpReadHeaderTimeout       time.Duration `env:"HTTP_READ_HEADER_TIMEOUT" default:"10s"`
+	HttpWriteTimeout            time.Duration `env:"HTTP_WRITE_TIMEOUT" default:"2m"`
+	HttpIdleTimeout             time.Duration `env:"HTTP_IDLE_TIMEOUT" default:"2m"`
+	HttpShutdownTimeout         time.Duration `env:"HTTP_SHUTDOWN_TIMEOUT" default:"30s"`
+	HttpMaxBodySize             int64         `env:"HTTP_MAX_BODY_SIZE" default:"33554432"`
+	HttpTrustedProxies          string        `env:"HTTP_TRUSTED_PROXIES"`
+	TLSCertFile                 string        `env:"TLS_CERT_FILE"`
+	TLSKeyFile                  string        `env:"TLS_KEY_FILE"`
+	TLSClientCAFile             string        `env:"TLS_CLIENT_CA_FILE"`
+	TLSClientAuth               string        `env:"TLS_CLIENT_AUTH" default:"require"`
+	MaxKeyRingCache             int           `env:"MAX_KEYRING_CACHE_SIZE" default:"1000"`
+	ShowLines                   bool          `env:"SHOW_LINES"`
+	LogFormat                   string        `env:"LOG_FORMAT"`
+	EnableSwagger               bool          `env:"ENABLE_SWAGGER" default:"true"`
+	Environment                 string        `env:"Environment" default:"development"`
+	Mode                        string        `env:"MODE"`
+	SingleKeyPath               string        `env:"SINGLE_KEY_PATH"`
+	SingleKeyPassword           string        `env:"SINGLE_KEY_PASSWORD" secret:"true"`
+	OnDemandKeyLoad             bool          `env:"ON_DEMAND_KEY_LOAD"`
+	RequestIDHeader             string        `env:"REQUESTID_HEADER"`
+	KeysBase64Encoded           bool          `env:"KEYS_BASE64_ENCODED"`
+	IgnoreKubernetesCA          bool          `env:"IGNORE_KUBERNETES_CA"`
+	MasterGPGKeyPath            string        `env:"MASTER_GPG_KEY_PATH"`
+	MasterGPGKeyPasswordPath    string        `env:"MASTER_GPG_KEY_PASSWORD_PATH"`
+	MasterGPGKeyBase64Encoded   bool          `env:"MASTER_GPG_KEY_BASE64_ENCODED"`
+	DatabaseDialect             string        `env:"DATABASE_DIALECT"`
+	ConnectionString            string        `env:"CONNECTION_STRING" secret:"true"`
+	EnableDatabase              bool          `env:"ENABLE_DATABASE"`
+	EnableRethinkDBSKS          bool          `env:"ENABLE_RETHINKDB_SKS"`
+	RethinkDBHost               string        `env:"RETHINKDB_HOST" default:"127.0.0.1"`
+	RethinkDBPort               int           `env:"RETHINKDB_PORT" default:"28015"`
+	RethinkDBUsername           string        `env:"RETHINKDB_USERNAME" default:"admin"`
+	RethinkDBPassword           string        `env:"RETHINKDB_PASSWORD" secret:"true"`
+	RethinkDBPoolSize           int           `env:"RETHINKDB_POOL_SIZE" default:"10"`
+	DatabaseName                string        `env:"DATABASE_NAME" default:"remote_signer"`
+	DatabaseTokenManager        bool          `env:"DATABASE_TOKEN_MANAGER,RETHINK_TOKEN_MANAGER"`
+	DatabaseAuthManager         bool          `env:"DATABASE_AUTH_MANAGER,AUTH_MANAGER,RETHINK_AUTH_MANAGER"`
+	DatabaseTokenReaperInterval time.Duration `env:"DATABASE_TOKEN_REAPER_INTERVAL" default:"10m"`
+	ClusterDiscovery            string        `env:"CLUSTER_DISCOVERY"`
+	ClusterPeers                string        `env:"CLUSTER_PEERS"`
+	ClusterPeersFile            string        `env:"CLUSTER_PEERS_FILE"`
+	ClusterDNSName              string        `env:"CLUSTER_DNS_NAME"`
+	ClusterSyncInterval         time.Duration `env:"CLUSTER_SYNC_INTERVAL" default:"1m"`
+	VaultStorage                bool          `env:"VAULT_STORAGE"`
+	VaultAddress                string        `env:"VAULT_ADDRESS" default:"http://localhost:8200"`
+	VaultRootToken              string        `env:"VAULT_ROOT_TOKEN" secret:"true"`
+	ReadonlyKeyPath             bool          `env:"READONLY_KEYPATH"`
+	VaultSkipVerify             bool          `env:"VAULT_SKIP_VERIFY"`
+	VaultUseUserpass            bool          `env:"VAULT_USE_USERPASS"`
+	VaultUsername               string        `env:"VAULT_USERNAME"`
+	VaultPassword               string        `env:"VAULT_PASSWORD" secret:"true"`
+	VaultNamespace              string        `env:"VAULT_NAMESPACE" default:"remote-signer"`
+	VaultBackend                string        `env:"VAULT_BACKEND" default:"secret"`
+	VaultSkipDataType           bool          `env:"VAULT_SKIP_DATA_TYPE"`
+	VaultTokenTTL               string        `env:"VAULT_TOKEN_TTL" default:"768h"`
+	AgentTargetURL              string        `env:"AGENT_TARGET_URL" default:"https://api.sandbox.contaquanto.com/all"`
+	AgentTokenExpiration        int           `env:"AGENT_TOKEN_EXPIRATION" default:"3600"`
+	AgentKeyFingerPrint         string        `env:"AGENT_KEY_FINGERPRINT"`
+	AgentBypassLogin            bool          `env:"AGENT_BYPASS_LOGIN"`
+	AgentAdminUsername          string        `env:"AGENT_ADMIN_USERNAME"`
+	AgentAdminPassword          string        `env:"AGENT_ADMIN_PASSWORD" secret:"true"`
+	AgentTOTPEncryptionKey      string        `env:"AGENT_TOTP_ENCRYPTION_KEY" secret:"true"`
+	AgentExternalURL            string        `env:"AGENT_EXTERNAL_URL" default:"/agent"`
+	AgentAdminExternalURL       string        `env:"AGENTADMIN_EXTERNAL_URL" default:"/agentAdmin"`
+	AgentJWTAuth                bool          `env:"AGENT_JWT_AUTH"`
+	AgentJWTJWKS                string        `env:"AGENT_JWT_JWKS"`
+	AgentJWTJWKSRefresh         time.Duration `env:"AGENT_JWT_JWKS_REFRESH" default:"1h"`
+	AgentJWTIssuer              string        `env:"AGENT_JWT_ISSUER"`
+	AgentJWTAudience            string        `env:"AGENT_JWT_AUDIENCE"`
+	AgentJWTUsernameClaim       string        `env:"AGENT_JWT_USERNAME_CLAIM" default:"preferred_username"`
+	AgentJWTFingerprintClaim    string        `env:"AGENT_JWT_FINGERPRINT_CLAIM" default:"chevron_fingerprint"`
+	AgentJWTRolesClaim          string        `env:"AGENT_JWT_ROLES_CLAIM" default:"chevron_roles"`
+	AgentAllowedTargets         string        `env:"AGENT_ALLOWED_TARGETS"`
+	AgentHeaderAllowList        string        `env:"AGENT_HEADER_ALLOWLIST"`
+	AgentHeaderDenyList         string        `env:"AGENT_HEADER_DENYLIST"`
+	AgentMaxBodySize            int64         `env:"AGENT_MAX_BODY_SIZE" default:"10485760"`
+	AgentRequestTimeout         time.Duration `env:"AGENT_REQUEST_TIMEOUT" default:"30s"`
+	AgentTLSCAFile              string        `env:"AGENT_TLS_CA_FILE"`
+	AgentTLSClientCert          string        `env:"AGENT_TLS_CLIENT_CERT"`
+	AgentTLSClientKey           string        `env:"AGENT_TLS_CLIENT_KEY"`
+	AgentRetries                int           `env:"AGENT_RETRIES"`
+	AgentRetryBackoff           time.Duration `env:"AGENT_RETRY_BACKOFF" default:"200ms"`
+	AgentSignMode               string        `env:"AGENT_SIGN_MODE" default:"inject"`
+	AgentSignedHeaders          string        `env:"AGENT_SIGNED_HEADERS" default:"Content-Type"`
+	AgentResponseFingerprint    string        `env:"AGENT_RESPONSE_FINGERPRINT"`
+	AgentResponseFingerprints   string        `env:"AGENT_RESPONSE_FINGERPRINTS"`
+	AgentResponseStrict         bool          `env:"AGENT_RESPONSE_STRICT"`
+	EnableRedis                 bool          `env:"REDIS_ENABLE"`
+	RedisClusterMode            bool          `env:"REDIS_CLUSTER_MODE"`
+	RedisHost                   string        `env:"REDIS_HOST" default:"localhost:6379"`
+	RedisUser                   string        `env:"REDIS_USER"`
+	RedisPass                   string        `env:"REDIS_PASS" secret:"true"`
+	RedisTLSEnabled             bool          `env:"REDIS_TLS_ENABLED"`
+	RedisMaxLocalObjects        int           `env:"REDIS_MAX_LOCAL_OBJECTS" default:"100"`
+	RedisLocalObjectTTL         time.Duration `env:"REDIS_MAX_LOCAL_TTL" default:"5m"`
+	RedisInvalidationChannel    string        `env:"REDIS_INVALIDATION_CHANNEL" default:"chevron-cache-invalidation"`
+	RateLimitEnable             bool          `env:"RATE_LIMIT_ENABLE"`
+	RateLimitBackend            string        `env:"RATE_LIMIT_BACKEND" default:"memory"`
+	RateLimitRedisPrefix        string        `env:"RATE_LIMIT_REDIS_PREFIX" default:"chevron-ratelimit:"`
+	RateLimitWindow             time.Duration `env:"RATE_LIMIT_WINDOW" default:"1m"`
+	RateLimitPrivateKeyRequests int           `env:"RATE_LIMIT_PRIVATE_KEY_REQUESTS" default:"120"`
+	RateLimitPrivateKeyInFlight int           `env:"RATE_LIMIT_PRIVATE_KEY_CONCURRENCY" default:"4"`
+	RateLimitLookupRequests     int           `env:"RATE_LIMIT_LOOKUP_REQUESTS" default:"1200"`
+	RateLimitLookupInFlight     int           `env:"RATE_LIMIT_LOOKUP_CONCURRENCY" default:"32"`
+	ReadyRequiredFingerPrints   string        `env:"READY_REQUIRED_FINGERPRINTS"`
+	ReadyCheckTimeout           time.Duration `env:"READY_CHECK_TIMEOUT" default:"5s"`
+
+	// deprecated has the deprecated names used and their replacements
+	deprecated map[string]string
+}
+
+// deprecatedNames maps the deprecated variables to the ones that replace them
+var deprecatedNames = map[string]string{
+	"RETHINK_TOKEN_MANAGER": "DATABASE_TOKEN_MANAGER",
+	"RETHINK_AUTH_MANAGER":  "DATABASE_AUTH_MANAGER",
+	"AUTH_MANAGER":          "DATABASE_AUTH_MANAGER",
+	"ENABLE_RETHINKDB_SKS":  "DATABASE_DIALECT=rethinkdb",
+}
+
+// bindings pairs the fields of c with the package variables read by the rest of the server
+func (c *Config) bindings() [][2]interface{} {
+	return [][2]interface{}{
+		{&c.SyslogServer, &SyslogServer},
+		{&c.SyslogFacility, &SyslogFacility},
+		{&c.PrivateKeyFolder, &PrivateKeyFolder},
+		{&c.KeyPrefix, &KeyPrefix},
+		{&c.SKSServer, &SKSServer},
+		{&c.HttpPort, &HttpPort},
+		{&c.HttpBindAddress, &HttpBindAddress},
+		{&c.HttpReadTimeout, &HttpReadTimeout},
+		{&c.HttpReadHeaderTimeout, &HttpReadHeaderTimeout},
+		{&c.HttpWriteTimeout, &HttpWriteTimeout},
+		{&c.HttpIdleTimeout, &HttpIdleTimeout},
+		{&c.HttpShutdownTimeout, &HttpShutdownTimeout},
+		{&c.HttpMaxBodySize, &HttpMaxBodySize},
+		{&c.HttpTrustedProxies, &HttpTrustedProxies},
+		{&c.TLSCertFile, &TLSCertFile},
+		{&c.TLSKeyFile, &TLSKeyFile},
+		{&c.TLSClientCAFile, &TLSClientCAFile},
+		{&c.TLSClientAuth, &TLSClientAuth},
+		{&c.MaxKeyRingCache, &MaxKeyRingCache},
+		{&c.ShowLines, &ShowLines},
+		{&c.EnableSwagger, &EnableSwagger},
+		{&c.Environment, &Environment},
+		{&c.SingleKeyPath, &SingleKeyPath},
+		{&c.SingleKeyPassword, &SingleKeyPassword},
+		{&c.OnDemandKeyLoad, &OnDemandKeyLoad},
+		{&c.RequestIDHeader, &RequestIDHeader},
+		{&c.KeysBase64Encoded, &KeysBase64Encoded},
+		{&c.IgnoreKubernetesCA, &IgnoreKubernetesCA},
+		{&c.MasterGPGKeyPath, &MasterGPGKeyPath},
+		{&c.MasterGPGKeyPasswordPath, &MasterGPGKeyPasswordPath},
+		{&c.MasterGPGKeyBase64Encoded, &MasterGPGKeyBase64Encoded},
+		{&c.DatabaseDialect, &DatabaseDialect},
+		{&c.ConnectionString, &ConnectionString},
+		{&c.EnableDatabase, &EnableDatabase},
+		{&c.RethinkDBHost, &RethinkDBHost},
+		{&c.RethinkDBPort, &RethinkDBPort},
+		{&c.RethinkDBUsername, &RethinkDBUsername},
+		{&c.RethinkDBPassword, &RethinkDBPassword},
+		{&c.RethinkDBPoolSize, &RethinkDBPoolSize},
+		{&c.DatabaseName, &DatabaseName},
+		{&c.DatabaseTokenManager, &DatabaseTokenManager},
+		{&c.DatabaseAuthManager, &DatabaseAuthManager},
+		{&c.DatabaseTokenReaperInterval, &DatabaseTokenReaperInterval},
+		{&c.ClusterDiscovery, &ClusterDiscovery},
+		{&c.ClusterPeers, &ClusterPeers},
+		{&c.ClusterPeersFile, &ClusterPeersFile},
+		{&c.ClusterDNSName, &ClusterDNSName},
+		{&c.ClusterSyncInterval, &ClusterSyncInterval},
+		{&c.VaultStorage, &VaultStorage},
+		{&c.VaultAddress, &VaultAddress},
+		{&c.VaultRootToken, &VaultRootToken},
+		{&c.ReadonlyKeyPath, &ReadonlyKeyPath},
+		{&c.VaultSkipVerify, &VaultSkipVerify},
+		{&c.VaultUseUserpass, &VaultUseUserpass},
+		{&c.VaultUsername, &VaultUsername},
+		{&c.VaultPassword, &VaultPassword},
+		{&c.VaultNamespace, &VaultNamespace},
+		{&c.VaultBackend, &VaultBackend},
+		{&c.VaultSkipDataType, &VaultSkipDataType},
+		{&c.VaultTokenTTL, &VaultTokenTTL},
+		{&c.AgentTargetURL, &AgentTargetURL},
+		{&c.AgentTokenExpiration, &AgentTokenExpiration},
+		{&c.AgentKeyFingerPrint, &AgentKeyFingerPrint},
+		{&c.AgentBypassLogin, &AgentBypassLogin},
+		{&c.AgentAdminUsername, &AgentAdminUsername},
+		{&c.AgentAdminPassword, &AgentAdminPassword},
+		{&c.AgentTOTPEncryptionKey, &AgentTOTPEncryptionKey},
+		{&c.AgentExternalURL, &AgentExternalURL},
+		{&c.AgentAdminExternalURL, &AgentAdminExternalURL},
+		{&c.AgentJWTAuth, &AgentJWTAuth},
+		{&c.AgentJWTJWKS, &AgentJWTJWKS},
+		{&c.AgentJWTJWKSRefresh, &AgentJWTJWKSRefresh},
+		{&c.AgentJWTIssuer, &AgentJWTIssuer},
+		{&c.AgentJWTAudience, &AgentJWTAudience},
+		{&c.AgentJWTUsernameClaim, &AgentJWTUsernameClaim},
+		{&c.AgentJWTFingerprintClaim, &AgentJWTFingerprintClaim},
+		{&c.AgentJWTRolesClaim, &AgentJWTRolesClaim},
+		{&c.AgentAllowedTargets, &AgentAllowedTargets},
+		{&c.AgentHeaderAllowList, &AgentHeaderAllowList},
+		{&c.AgentHeaderDenyList, &AgentHeaderDenyList},
+		{&c.AgentMaxBodySize, &AgentMaxBodySize},
+		{&c.AgentRequestTimeout, &AgentRequestTimeout},
+		{&c.AgentTLSCAFile, &AgentTLSCAFile},
+		{&c.AgentTLSClientCert, &AgentTLSClientCert},
+		{&c.AgentTLSClientKey, &AgentTLSClientKey},
+		{&c.AgentRetries, &AgentRetries},
+		{&c.AgentRetryBackoff, &AgentRetryBackoff},
+		{&c.AgentSignMode, &AgentSignMode},
+		{&c.AgentSignedHeaders, &AgentSignedHeaders},
+		{&c.AgentResponseFingerprint, &AgentResponseFingerprint},
+		{&c.AgentResponseFingerprints, &AgentResponseFingerprints},
+		{&c.AgentResponseStrict, &AgentResponseStrict},
+		{&c.EnableRedis, &EnableRedis},
+		{&c.RedisClusterMode, &RedisClusterMode},
+		{&c.RedisHost, &RedisHost},
+		{&c.RedisUser, &RedisUser},
+		{&c.RedisPass, &RedisPass},
+		{&c.RedisTLSEnabled, &RedisTLSEnabled},
+		{&c.RedisMaxLocalObjects, &RedisMaxLocalObjects},
+		{&c.RedisLocalObjectTTL, &RedisLocalObjectTTL},
+		{&c.RedisInvalidationChannel, &RedisInvalidationChannel},
+		{&c.RateLimitEnable, &RateLimitEnable},
+		{&c.RateLimitBackend, &RateLimitBackend},
+		{&c.RateLimitRedisPrefix, &RateLimitRedisPrefix},
+		{&c.RateLimitWindow, &RateLimitWindow},
+		{&c.RateLimitPrivateKeyRequests, &RateLimitPrivateKeyRequests},
+		{&c.RateLimitPrivateKeyInFlight, &RateLimitPrivateKeyInFlight},
+		{&c.RateLimitLookupRequests, &RateLimitLookupRequests},
+		{&c.RateLimitLookupInFlight, &RateLimitLookupInFlight},
+		{&c.ReadyRequiredFingerPrints, &ReadyRequiredFingerPrints},
+		{&c.ReadyCheckTimeout, &ReadyCheckTimeout},
+	}
+}
+
+// Apply sets the package variables from c
+func Apply(c *Config) {
+	for _, b := range c.bindings() {
+		reflect.ValueOf(b[1]).Elem().Set(reflect.ValueOf(b[0]).Elem())
+	}
+
+	LogFormat = slog.ToFormat(c.LogFormat)
+	SingleKeyMode = c.Mode == modeSingleKey
+}
+
+// Current returns the configuration in the package variables
+func Current() *Config {
+	c := &Config{}
+
+	for _, b := range c.bindings() {
+		reflect.ValueOf(b[0]).Elem().Set(reflect.ValueOf(b[1]).Elem())
+	}
+
+	c.LogFormat = string(LogFormat)
+	if SingleKeyMode {
+		c.Mode = modeSingleKey
+	}
+
+	return c
+}
+
+// Deprecations returns the deprecated variables used in the configuration and the ones that replace them
+func (c *Config) Deprecations() map[string]string {
+	return c.deprecated
+}
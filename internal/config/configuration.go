@@ -2,8 +2,6 @@ package config
 
 import (
 	"os"
-	"strconv"
-	"strings"
 	"time"
 
 	"github.com/quan-to/chevron/pkg/QuantoError"
@@ -18,6 +16,18 @@ var PrivateKeyFolder string
 var KeyPrefix string
 var SKSServer string
 var HttpPort int
+var HttpBindAddress string
+var HttpReadTimeout time.Duration
+var HttpReadHeaderTimeout time.Duration
+var HttpWriteTimeout time.Duration
+var HttpIdleTimeout time.Duration
+var HttpShutdownTimeout time.Duration
+var HttpMaxBodySize int64
+var HttpTrustedProxies string
+var TLSCertFile string
+var TLSKeyFile string
+var TLSClientCAFile string
+var TLSClientAuth string
 var MaxKeyRingCache int
 var EnableDatabase bool
 var RethinkDBHost string
@@ -29,6 +39,11 @@ var DatabaseName string
 var MasterGPGKeyPath string
 var MasterGPGKeyPasswordPath string
 var MasterGPGKeyBase64Encoded bool
+var ClusterDiscovery string
+var ClusterPeers string
+var ClusterPeersFile string
+var ClusterDNSName string
+var ClusterSyncInterval time.Duration
 var KeysBase64Encoded bool
 var IgnoreKubernetesCA bool
 var VaultStorage bool
@@ -47,6 +62,32 @@ var AgentTargetURL string
 var AgentTokenExpiration int
 var AgentKeyFingerPrint string
 var AgentBypassLogin bool
+var AgentAdminUsername string
+var AgentAdminPassword string
+var AgentTOTPEncryptionKey string
+var AgentJWTAuth bool
+var AgentJWTJWKS string
+var AgentJWTJWKSRefresh time.Duration
+var AgentJWTIssuer string
+var AgentJWTAudience string
+var AgentJWTUsernameClaim string
+var AgentJWTFingerprintClaim string
+var AgentJWTRolesClaim string
+var AgentAllowedTargets string
+var AgentHeaderAllowList string
+var AgentHeaderDenyList string
+var AgentMaxBodySize int64
+var AgentRequestTimeout time.Duration
+var AgentTLSCAFile string
+var AgentTLSClientCert string
+var AgentTLSClientKey string
+var AgentRetries int
+var AgentRetryBackoff time.Duration
+var AgentSignMode string
+var AgentSignedHeaders string
+var AgentResponseFingerprint string
+var AgentResponseFingerprints string
+var AgentResponseStrict bool
 var OnDemandKeyLoad bool
 var RequestIDHeader string
 var EnableSwagger bool
@@ -63,6 +104,7 @@ var SingleKeyPath string
 var SingleKeyPassword string
 var DatabaseDialect string
 var ConnectionString string
+var DatabaseTokenReaperInterval time.Duration
 
 var EnableRedis bool
 var RedisClusterMode bool
@@ -72,6 +114,19 @@ var RedisPass string
 var RedisTLSEnabled bool
 var RedisMaxLocalObjects int
 var RedisLocalObjectTTL time.Duration
+var RedisInvalidationChannel string
+
+var RateLimitEnable bool
+var RateLimitBackend string
+var RateLimitRedisPrefix string
+var RateLimitWindow time.Duration
+var RateLimitPrivateKeyRequests int
+var RateLimitPrivateKeyInFlight int
+var RateLimitLookupRequests int
+var RateLimitLookupInFlight int
+
+var ReadyRequiredFingerPrints string
+var ReadyCheckTimeout time.Duration
 
 // LogFormat allows to configure the output log format
 var LogFormat slog.Format
@@ -84,247 +139,44 @@ func configDeprecationMessage(userConfig, newConfig string) {
 	}
 }
 
+// Setup loads the configuration from the file in CHEVRON_CONFIG and the environment.
+// Exits showing all the problems found if the configuration is not valid
 func Setup() {
-	var err error
-	// Pre init
-	MaxKeyRingCache = -1
-	HttpPort = -1
-	RethinkDBPort = -1
-	RethinkDBPoolSize = -1
-	AgentTokenExpiration = -1
-	ShowLines = false
-	DatabaseDialect = ""
-	ConnectionString = ""
-
-	// Load envvars
-	DatabaseDialect = strings.ToLower(os.Getenv("DATABASE_DIALECT"))
-	ConnectionString = os.Getenv("CONNECTION_STRING")
-	SyslogServer = os.Getenv("SYSLOG_IP")
-	SyslogFacility = os.Getenv("SYSLOG_FACILITY")
-	PrivateKeyFolder = os.Getenv("PRIVATE_KEY_FOLDER")
-	SKSServer = os.Getenv("SKS_SERVER")
-	KeyPrefix = os.Getenv("KEY_PREFIX")
-	ShowLines = os.Getenv("SHOW_LINES") == "true"
-	EnableSwagger = os.Getenv("ENABLE_SWAGGER") == "" || os.Getenv("ENABLE_SWAGGER") == "true"
-	LogFormat = slog.ToFormat(os.Getenv("LOG_FORMAT"))
-	slog.SetShowLines(ShowLines)
-
-	var maxKeyRingCache = os.Getenv("MAX_KEYRING_CACHE_SIZE")
-	if maxKeyRingCache != "" {
-		i, err := strconv.ParseInt(maxKeyRingCache, 10, 32)
-		if err != nil {
-			slog.Error("Error parsing MAX_KEYRING_CACHE_SIZE: %s", err)
-			panic(err)
-		}
-		MaxKeyRingCache = int(i)
+	if err := SetupFrom(Options{
+		File:        os.Getenv(FileEnvironmentVariable),
+		Environment: os.Environ(),
+	}); err != nil {
+		slog.Error(err)
+		os.Exit(1)
 	}
+}
 
-	var hp = os.Getenv("HTTP_PORT")
-	if hp != "" {
-		i, err := strconv.ParseInt(hp, 10, 32)
-		if err != nil {
-			slog.Error("Error parsing HTTP_PORT: %s", err)
-			panic(err)
-		}
-		HttpPort = int(i)
+// SetupFrom loads the configuration from the sources and applies it
+func SetupFrom(opts Options) error {
+	c, err := Load(opts)
+	if err != nil {
+		return err
 	}
 
-	EnableDatabase = strings.ToLower(os.Getenv("ENABLE_DATABASE")) == "true" || DatabaseDialect == "rethinkdb"
+	setup(c)
 
-	if strings.ToLower(os.Getenv("ENABLE_RETHINKDB_SKS")) == "true" || DatabaseDialect == "rethinkdb" {
+	// Deprecation
+	if DatabaseDialect == "rethinkdb" {
 		slog.Warn("RethinkDB services are currently deprecated. Please check the project README for more information: https://github.com/quan-to/chevron")
-		// Backwards compatibility
-		DatabaseDialect = "rethinkdb"
-		EnableDatabase = true
-		RethinkDBHost = os.Getenv("RETHINKDB_HOST")
-		RethinkDBUsername = os.Getenv("RETHINKDB_USERNAME")
-		RethinkDBPassword = os.Getenv("RETHINKDB_PASSWORD")
-
-		var rdbport = os.Getenv("RETHINKDB_PORT")
-		if rdbport != "" {
-			i, err := strconv.ParseInt(rdbport, 10, 32)
-			if err != nil {
-				slog.Error("Error parsing RETHINKDB_PORT: %s", err)
-				panic(err)
-			}
-			RethinkDBPort = int(i)
-		}
-
-		var poolSize = os.Getenv("RETHINKDB_POOL_SIZE")
-		if poolSize != "" {
-			i, err := strconv.ParseInt(poolSize, 10, 32)
-			if err != nil {
-				slog.Error("Error parsing RETHINKDB_POOL_SIZE: %s", err)
-				panic(err)
-			}
-			RethinkDBPoolSize = int(i)
-		}
-		DatabaseName = os.Getenv("DATABASE_NAME")
-	}
-
-	MasterGPGKeyPath = os.Getenv("MASTER_GPG_KEY_PATH")
-	MasterGPGKeyPasswordPath = os.Getenv("MASTER_GPG_KEY_PASSWORD_PATH")
-	MasterGPGKeyBase64Encoded = strings.ToLower(os.Getenv("MASTER_GPG_KEY_BASE64_ENCODED")) == "true"
-
-	KeysBase64Encoded = strings.ToLower(os.Getenv("KEYS_BASE64_ENCODED")) == "true"
-	IgnoreKubernetesCA = strings.ToLower(os.Getenv("IGNORE_KUBERNETES_CA")) == "true"
-
-	VaultStorage = strings.ToLower(os.Getenv("VAULT_STORAGE")) == "true"
-	VaultAddress = os.Getenv("VAULT_ADDRESS")
-	VaultRootToken = os.Getenv("VAULT_ROOT_TOKEN")
-	ReadonlyKeyPath = os.Getenv("READONLY_KEYPATH") == "true"
-	VaultSkipVerify = os.Getenv("VAULT_SKIP_VERIFY") == "true"
-	VaultUseUserpass = os.Getenv("VAULT_USE_USERPASS") == "true"
-	VaultUsername = os.Getenv("VAULT_USERNAME")
-	VaultPassword = os.Getenv("VAULT_PASSWORD")
-	VaultNamespace = os.Getenv("VAULT_NAMESPACE")
-	VaultBackend = os.Getenv("VAULT_BACKEND")
-	VaultSkipDataType = os.Getenv("VAULT_SKIP_DATA_TYPE") == "true"
-	VaultTokenTTL = os.Getenv("VAULT_TOKEN_TTL")
-	AgentTargetURL = os.Getenv("AGENT_TARGET_URL")
-	AgentKeyFingerPrint = os.Getenv("AGENT_KEY_FINGERPRINT")
-	AgentBypassLogin = os.Getenv("AGENT_BYPASS_LOGIN") == "true"
-	DatabaseTokenManager = os.Getenv("RETHINK_TOKEN_MANAGER") == "true" || os.Getenv("DATABASE_TOKEN_MANAGER") == "true"
-	DatabaseAuthManager = os.Getenv("RETHINK_AUTH_MANAGER") == "true" || os.Getenv("AUTH_MANAGER") == "true"
-
-	if (DatabaseAuthManager || DatabaseTokenManager) && !EnableDatabase {
-		slog.Fatal("Database Auth / Token Manager requires a database configuration")
-	}
-
-	RequestIDHeader = os.Getenv("REQUESTID_HEADER")
-	AgentExternalURL = os.Getenv("AGENT_EXTERNAL_URL")
-	AgentAdminExternalURL = os.Getenv("AGENTADMIN_EXTERNAL_URL")
-
-	Environment = os.Getenv("Environment")
-
-	atke := os.Getenv("AGENT_TOKEN_EXPIRATION")
-
-	if atke != "" {
-		i, err := strconv.ParseInt(atke, 10, 32)
-		if err != nil {
-			slog.Fatal("Error parsing AGENT_TOKEN_EXPIRATION: %s", err)
-		}
-		AgentTokenExpiration = int(i)
-	}
-
-	OnDemandKeyLoad = os.Getenv("ON_DEMAND_KEY_LOAD") == "true"
-
-	SingleKeyMode = os.Getenv("MODE") == "single_key"
-	SingleKeyPath = os.Getenv("SINGLE_KEY_PATH")
-	SingleKeyPassword = os.Getenv("SINGLE_KEY_PASSWORD")
-
-	EnableRedis = os.Getenv("REDIS_ENABLE") == "true"
-	RedisTLSEnabled = os.Getenv("REDIS_TLS_ENABLED") == "true"
-	RedisHost = os.Getenv("REDIS_HOST")
-	RedisUser = os.Getenv("REDIS_USER")
-	RedisPass = os.Getenv("REDIS_PASS")
-	RedisClusterMode = os.Getenv("REDIS_CLUSTER_MODE") == "true"
-
-	redisLocalObjectTTL := os.Getenv("REDIS_MAX_LOCAL_TTL")
-	if redisLocalObjectTTL != "" {
-		if RedisLocalObjectTTL, err = time.ParseDuration(redisLocalObjectTTL); err != nil {
-			slog.Error("Invalid field REDIS_MAX_LOCAL_TTL = %q - Invalid Duration")
-		}
-	}
-
-	redisMaxLocalObjects := os.Getenv("REDIS_MAX_LOCAL_OBJECTS")
-	if redisMaxLocalObjects != "" {
-		v, err := strconv.ParseInt(redisMaxLocalObjects, 10, 32)
-		if err != nil {
-			slog.Error("Invalid field REDIS_MAX_LOCAL_OBJECTS = %q - Invalid number", redisMaxLocalObjects)
-			v = 0
-		}
-		RedisMaxLocalObjects = int(v)
-	}
-
-	// Set defaults if not defined
-	if SyslogServer == "" {
-		SyslogServer = "127.0.0.1"
-	}
-
-	if SyslogFacility == "" {
-		SyslogFacility = "LOG_USER"
-	}
-
-	if PrivateKeyFolder == "" {
-		PrivateKeyFolder = "./keys"
-	}
-
-	if MaxKeyRingCache == -1 {
-		MaxKeyRingCache = 1000
-	}
-
-	if HttpPort == -1 {
-		HttpPort = 5100
-	}
-
-	if RethinkDBHost == "" {
-		RethinkDBHost = "127.0.0.1"
 	}
 
-	if VaultTokenTTL == "" {
-		VaultTokenTTL = "768h"
+	for _, name := range sortedKeys(c.Deprecations()) {
+		configDeprecationMessage(name, c.Deprecations()[name])
 	}
 
-	if RethinkDBUsername == "" {
-		RethinkDBUsername = "admin"
-	}
-
-	if RethinkDBPort == -1 {
-		RethinkDBPort = 28015
-	}
-
-	if RethinkDBPoolSize == -1 {
-		RethinkDBPoolSize = 10
-	}
-
-	if DatabaseName == "" {
-		DatabaseName = "remote_signer"
-	}
-
-	if VaultAddress == "" {
-		VaultAddress = "http://localhost:8200"
-	}
-
-	if VaultNamespace == "" {
-		VaultNamespace = "remote-signer"
-	}
-
-	if VaultBackend == "" {
-		VaultBackend = "secret"
-	}
-
-	if AgentTargetURL == "" {
-		AgentTargetURL = "https://api.sandbox.contaquanto.com/all"
-	}
-
-	if AgentTokenExpiration == -1 {
-		AgentTokenExpiration = 3600
-	}
-
-	if Environment == "" {
-		Environment = "development"
-	}
-
-	if AgentExternalURL == "" {
-		AgentExternalURL = "/agent"
-	}
-
-	if AgentAdminExternalURL == "" {
-		AgentAdminExternalURL = "/agentAdmin"
-	}
-
-	if RedisMaxLocalObjects == 0 {
-		RedisMaxLocalObjects = 100
-	}
+	return nil
+}
 
-	if RedisLocalObjectTTL == 0 {
-		RedisLocalObjectTTL = time.Minute * 5
-	}
+// setup applies c and configures the logger
+func setup(c *Config) {
+	Apply(c)
 
-	if RedisHost == "" {
-		RedisHost = "localhost:6379"
-	}
+	slog.SetShowLines(ShowLines)
 
 	// Other stuff
 	_ = os.Mkdir(PrivateKeyFolder, 0750)
@@ -336,19 +188,25 @@ func Setup() {
 		slog.SetDebug(false)
 		QuantoError.DisableStackTrace()
 	}
+}
 
-	// Deprecation
-	if os.Getenv("RETHINK_TOKEN_MANAGER") != "" {
-		configDeprecationMessage("RETHINK_TOKEN_MANAGER", "DATABASE_TOKEN_MANAGER")
-	}
-	if os.Getenv("RETHINK_AUTH_MANAGER") != "" {
-		configDeprecationMessage("RETHINK_AUTH_MANAGER", "DATABASE_AUTH_MANAGER")
-	}
-	if os.Getenv("ENABLE_RETHINKDB_SKS") != "" {
-		configDeprecationMessage("ENABLE_RETHINKDB_SKS", "DATABASE_DIALECT=rethinkdb")
-	}
+// loadError has the problems found loading the configuration on startup
+var loadError error
+
+// LoadError returns the problems found loading the configuration from CHEVRON_CONFIG and the environment on startup.
+// The invalid values are replaced by the defaults, so the binaries that do not call Setup or SetupFrom must check it
+func LoadError() error {
+	return loadError
 }
 
 func init() {
-	Setup()
+	// The server loads the configuration again with the command line flags and reports the problems found
+	c, err := Load(Options{
+		File:        os.Getenv(FileEnvironmentVariable),
+		Environment: os.Environ(),
+	})
+
+	loadError = err
+
+	setup(c)
 }
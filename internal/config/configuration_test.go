@@ -2,12 +2,14 @@ package config
 
 import (
 	"fmt"
+	"math"
 	"math/rand"
 	"os"
 	"reflect"
 	"strconv"
 	"syscall"
 	"testing"
+	"time"
 
 	"github.com/bouk/monkey"
 	"github.com/quan-to/slog"
@@ -28,13 +30,25 @@ func assertPanic(t *testing.T, f func(), message string) {
 	f()
 }
 
-func TestPushPopVars(t *testing.T) {
-	PopVariables()
-	PushVariables()
-	PopVariables()
+func TestApplyCurrent(t *testing.T) {
+	defer Apply(Current())
+
+	c := Current()
+	c.HttpPort = 1234
+	c.AgentJWTJWKSRefresh = time.Minute
+	c.Mode = modeSingleKey
+	Apply(c)
+
+	if HttpPort != 1234 || AgentJWTJWKSRefresh != time.Minute || !SingleKeyMode {
+		t.Errorf("Apply did not set the package variables")
+	}
+
+	if got := Current(); !reflect.DeepEqual(got, c) {
+		t.Errorf("Current does not match the applied configuration. Expected %+v got %+v", c, got)
+	}
 }
 
-func testIntVar(v *int, envName string, localName string, t *testing.T) {
+func testIntVar(v *int, envName string, localName string, max int32, t *testing.T) {
 	slog.SetTestMode()
 	err := os.Setenv(envName, "huebr")
 	if err != nil {
@@ -43,7 +57,7 @@ func testIntVar(v *int, envName string, localName string, t *testing.T) {
 	}
 	assertPanic(t, Setup, fmt.Sprintf("%s should panic with a invalid value", envName))
 
-	val := int(rand.Int31())
+	val := int(rand.Int31n(max)) + 1
 
 	err = os.Setenv(envName, strconv.FormatInt(int64(val), 10))
 	if err != nil {
@@ -134,11 +148,11 @@ func assertEqual(a interface{}, b interface{}, message string, t *testing.T) {
 
 func TestConfiguration(t *testing.T) {
 	slog.SetTestMode()
-	PushVariables()
+	saved := Current()
 
-	testIntVar(&MaxKeyRingCache, "MAX_KEYRING_CACHE_SIZE", "MaxKeyRingCache", t)
-	testIntVar(&HttpPort, "HTTP_PORT", "HttpPort", t)
-	testIntVar(&AgentTokenExpiration, "AGENT_TOKEN_EXPIRATION", "AgentTokenExpiration", t)
+	testIntVar(&MaxKeyRingCache, "MAX_KEYRING_CACHE_SIZE", "MaxKeyRingCache", math.MaxInt32-1, t)
+	testIntVar(&HttpPort, "HTTP_PORT", "HttpPort", 65535, t)
+	testIntVar(&AgentTokenExpiration, "AGENT_TOKEN_EXPIRATION", "AgentTokenExpiration", math.MaxInt32-1, t)
 
 	testStringVar(&SyslogServer, "SYSLOG_IP", "SyslogServer", "127.0.0.1", t)
 	testStringVar(&SyslogFacility, "SYSLOG_FACILITY", "SyslogFacility", "LOG_USER", t)
@@ -151,12 +165,7 @@ func TestConfiguration(t *testing.T) {
 	testStringVar(&AgentExternalURL, "AGENT_EXTERNAL_URL", "AgentExternalURL", "/agent", t)
 	testStringVar(&AgentAdminExternalURL, "AGENTADMIN_EXTERNAL_URL", "AgentAdminExternalURL", "/agentAdmin", t)
 
-	PopVariables()
-
-	PushVariables()
-	slog.SetTestMode()
-
-	PopVariables()
+	Apply(saved)
 	slog.UnsetTestMode()
 
 	_ = os.Setenv("ENABLE_RETHINKDB_SKS", "true")
@@ -169,18 +178,16 @@ func TestConfiguration(t *testing.T) {
 	Setup()
 	assertEqual(slog.DebugEnabled(), false, "Debug should be disabled in production", t)
 
-	PopVariables()
+	Apply(saved)
 	slog.UnsetTestMode()
 
-	PushVariables()
 	_ = syscall.Setenv("SHOW_LINES", "true")
 	Setup()
 	assertEqual(slog.ShowLinesEnabled(), true, "SHOW_LINES=true env should set slog.SetShowLines to true", t)
-	PopVariables()
+	Apply(saved)
 
-	PushVariables()
 	_ = syscall.Setenv("SHOW_LINES", "false")
 	Setup()
 	assertEqual(slog.ShowLinesEnabled(), false, "SHOW_LINES=false env should set slog.SetShowLines to false", t)
-	PopVariables()
+	Apply(saved)
 }
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// redacted replaces the secret values in Dump
+const redacted = "<redacted>"
+
+// values returns the configuration by file key in declaration order
+func (c *Config) values(redact bool) yaml.MapSlice {
+	values := make(yaml.MapSlice, 0)
+
+	for _, f := range c.fields() {
+		var v interface{}
+
+		switch t := f.value.Interface().(type) {
+		case time.Duration:
+			v = t.String()
+		default:
+			v = t
+		}
+
+		if redact && f.secret && f.value.String() != "" {
+			v = redacted
+		}
+
+		values = append(values, yaml.MapItem{Key: f.key(), Value: v})
+	}
+
+	return values
+}
+
+// Dump returns the configuration in YAML or JSON format. The output can be used as configuration file.
+// Secrets are replaced by <redacted> if redact is true
+func (c *Config) Dump(format string, redact bool) ([]byte, error) {
+	values := c.values(redact)
+
+	switch format {
+	case "yaml", "":
+		return yaml.Marshal(values)
+	case "json":
+		m := make(map[string]interface{}, len(values))
+		for _, item := range values {
+			m[item.Key.(string)] = item.Value
+		}
+		return json.MarshalIndent(m, "", "  ")
+	}
+
+	return nil, fmt.Errorf("unknown format %q", format)
+}
@@ -0,0 +1,292 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// FileEnvironmentVariable is the variable with the path of the configuration file
+const FileEnvironmentVariable = "CHEVRON_CONFIG"
+
+// Options are the sources of the configuration. Later sources override the previous ones: file < environment < flags
+type Options struct {
+	// File is a YAML, TOML or JSON configuration file. The format is selected by the extension
+	File string
+	// Environment in the os.Environ format
+	Environment []string
+	// Flags are the values set in the command line, by variable name
+	Flags map[string]string
+}
+
+// ValidationError has all the problems found in the configuration
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid configuration:\n  %s", strings.Join(e.Problems, "\n  "))
+}
+
+func (e *ValidationError) add(format string, v ...interface{}) {
+	e.Problems = append(e.Problems, fmt.Sprintf(format, v...))
+}
+
+// setting is a value from one of the sources
+type setting struct {
+	value  string
+	source string
+	name   string
+	// rank is the precedence of the source
+	rank int
+}
+
+// field is a configuration field and its names
+type field struct {
+	value reflect.Value
+	names []string
+	def   string
+	// hasDefault is true if the field has a default tag, even if empty
+	hasDefault bool
+	secret     bool
+}
+
+// fields returns the configurable fields of c in declaration order
+func (c *Config) fields() []field {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	fields := make([]field, 0, t.NumField())
+
+	for i := 0; i < t.NumField(); i++ {
+		env, ok := t.Field(i).Tag.Lookup("env")
+		if !ok {
+			continue
+		}
+
+		def, hasDefault := t.Field(i).Tag.Lookup("default")
+
+		fields = append(fields, field{
+			value:      v.Field(i),
+			names:      strings.Split(env, ","),
+			def:        def,
+			hasDefault: hasDefault,
+			secret:     t.Field(i).Tag.Get("secret") == "true",
+		})
+	}
+
+	return fields
+}
+
+// key returns the name of the field in the configuration file
+func (f field) key() string {
+	return strings.ToLower(f.names[0])
+}
+
+// Load reads the configuration from the sources and validates it. Returns a *ValidationError with all the problems found
+// The configuration is returned even when it is not valid, with the defaults in place of the values that could not be read
+func Load(opts Options) (*Config, error) {
+	c := &Config{
+		deprecated: map[string]string{},
+	}
+	verr := &ValidationError{}
+
+	// Settings by upper case variable name
+	settings := map[string]setting{}
+
+	// File and flag names are case insensitive, environment variables are not
+	known := map[string]bool{}
+	knownEnvironment := map[string]bool{}
+	for _, f := range c.fields() {
+		for _, name := range f.names {
+			known[strings.ToUpper(name)] = true
+			knownEnvironment[name] = true
+		}
+	}
+
+	if opts.File != "" {
+		values, err := readFile(opts.File)
+		if err != nil {
+			verr.add("%s", err)
+		}
+
+		for _, k := range sortedKeys(values) {
+			v := values[k]
+			if !known[strings.ToUpper(k)] {
+				verr.add("%s: unknown option %q", opts.File, k)
+				continue
+			}
+			settings[strings.ToUpper(k)] = setting{value: v, source: opts.File, name: k, rank: 1}
+		}
+	}
+
+	for _, kv := range opts.Environment {
+		idx := strings.Index(kv, "=")
+		if idx == -1 {
+			continue
+		}
+
+		k, v := kv[:idx], kv[idx+1:]
+		if knownEnvironment[k] && v != "" {
+			settings[strings.ToUpper(k)] = setting{value: v, source: "environment", name: k, rank: 2}
+		}
+	}
+
+	for _, k := range sortedKeys(opts.Flags) {
+		v := opts.Flags[k]
+		if !known[strings.ToUpper(k)] {
+			verr.add("flag: unknown option %q", k)
+			continue
+		}
+		settings[strings.ToUpper(k)] = setting{value: v, source: "flag", name: k, rank: 3}
+	}
+
+	for _, f := range c.fields() {
+		var s *setting
+
+		// Sources with higher precedence win. In the same source the first name wins over the deprecated ones
+		for _, name := range f.names {
+			v, ok := settings[strings.ToUpper(name)]
+			if ok && v.value != "" && (s == nil || v.rank > s.rank) {
+				s = &v
+			}
+		}
+
+		if s != nil {
+			err := setValue(f.value, s.value)
+			if err == nil {
+				continue
+			}
+			// Fallback to the default so the invalid value is reported only once
+			verr.add("%s (from %s): %s", s.name, s.source, err)
+		}
+
+		if f.hasDefault {
+			if err := setValue(f.value, f.def); err != nil {
+				panic(fmt.Sprintf("invalid default for %s: %s", f.names[0], err))
+			}
+		}
+	}
+
+	for name, replacement := range deprecatedNames {
+		if _, ok := settings[name]; ok {
+			c.deprecated[name] = replacement
+		}
+	}
+
+	c.normalize()
+	c.validate(verr)
+
+	if len(verr.Problems) > 0 {
+		return c, verr
+	}
+
+	return c, nil
+}
+
+// normalize fills the values that depend on other fields
+func (c *Config) normalize() {
+	c.DatabaseDialect = strings.ToLower(c.DatabaseDialect)
+	c.ClusterDiscovery = strings.ToLower(c.ClusterDiscovery)
+
+	// Backwards compatibility
+	if c.EnableRethinkDBSKS || c.DatabaseDialect == "rethinkdb" {
+		c.DatabaseDialect = "rethinkdb"
+		c.EnableDatabase = true
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func setValue(v reflect.Value, value string) error {
+	value = strings.TrimSpace(value)
+
+	switch v.Interface().(type) {
+	case string:
+		v.SetString(value)
+	case bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		v.SetBool(b)
+	case time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		v.SetInt(int64(d))
+	case int, int64:
+		i, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		v.SetInt(i)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+// readFile reads a flat configuration file. Values are converted to strings to be parsed like the environment
+func readFile(filename string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading configuration file: %v", err)
+	}
+
+	raw := map[string]interface{}{}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		_, err = toml.Decode(string(data), &raw)
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&raw)
+	default:
+		return nil, fmt.Errorf("unknown configuration file format %q. Use .yaml, .yml, .toml or .json", filepath.Ext(filename))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error parsing configuration file %s: %v", filename, err)
+	}
+
+	values := map[string]string{}
+	for k, v := range raw {
+		switch t := v.(type) {
+		case string:
+			values[k] = t
+		case json.Number:
+			values[k] = t.String()
+		case bool, int, int64, uint64, float64:
+			values[k] = fmt.Sprint(t)
+		case nil:
+			values[k] = ""
+		default:
+			return nil, fmt.Errorf("%s: option %q must be a string, number or boolean", filename, k)
+		}
+	}
+
+	return values, nil
+}
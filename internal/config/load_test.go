@@ -0,0 +1,234 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testFolder string
+
+func TestMain(m *testing.M) {
+	var err error
+
+	testFolder, err = ioutil.TempDir("", "chevron-config")
+	if err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	_ = os.RemoveAll(testFolder)
+	os.Exit(code)
+}
+
+// writeConfigFile writes a configuration file in the test folder and returns its path
+func writeConfigFile(t *testing.T, name, content string) string {
+	filename := path.Join(testFolder, name)
+	if err := ioutil.WriteFile(filename, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return filename
+}
+
+func TestLoadDefaults(t *testing.T) {
+	c, err := Load(Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if c.HttpPort != 5100 {
+		t.Errorf("Expected default HTTP_PORT 5100 got %d", c.HttpPort)
+	}
+
+	if c.ClusterSyncInterval != time.Minute {
+		t.Errorf("Expected default CLUSTER_SYNC_INTERVAL 1m got %s", c.ClusterSyncInterval)
+	}
+
+	if c.PrivateKeyFolder != "./keys" {
+		t.Errorf("Expected default PRIVATE_KEY_FOLDER ./keys got %s", c.PrivateKeyFolder)
+	}
+}
+
+func TestLoadFileFormats(t *testing.T) {
+	files := map[string]string{
+		"chevron.yaml": "http_port: 5200\nagent_retries: 3\nshow_lines: true\nagent_request_timeout: 10s\nkey_prefix: file_\n",
+		"chevron.toml": "http_port = 5200\nagent_retries = 3\nshow_lines = true\nagent_request_timeout = \"10s\"\nkey_prefix = \"file_\"\n",
+		"chevron.json": `{"http_port": 5200, "agent_retries": 3, "show_lines": true, "agent_request_timeout": "10s", "key_prefix": "file_"}`,
+	}
+
+	for name, content := range files {
+		c, err := Load(Options{File: writeConfigFile(t, name, content)})
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", name, err)
+			continue
+		}
+
+		if c.HttpPort != 5200 || c.AgentRetries != 3 || !c.ShowLines || c.AgentRequestTimeout != 10*time.Second || c.KeyPrefix != "file_" {
+			t.Errorf("%s: values not loaded from file: %+v", name, c)
+		}
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := writeConfigFile(t, "chevron.yaml", "http_port: 5200\nkey_prefix: file_\nsks_server: http://file\n")
+
+	c, err := Load(Options{
+		File:        file,
+		Environment: []string{"KEY_PREFIX=env_", "SKS_SERVER=http://env", "UNRELATED=value"},
+		Flags:       map[string]string{"sks_server": "http://flag"},
+	})
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if c.HttpPort != 5200 {
+		t.Errorf("Expected HTTP_PORT from file got %d", c.HttpPort)
+	}
+
+	if c.KeyPrefix != "env_" {
+		t.Errorf("Expected KEY_PREFIX from environment got %s", c.KeyPrefix)
+	}
+
+	if c.SKSServer != "http://flag" {
+		t.Errorf("Expected SKS_SERVER from flag got %s", c.SKSServer)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	file := writeConfigFile(t, "chevron.yaml", "http_prot: 5200\nhttp_port: 99999\n")
+
+	c, err := Load(Options{
+		File:        file,
+		Environment: []string{"AGENT_TOKEN_EXPIRATION=huebr", "CLUSTER_DISCOVERY=static", "AGENT_JWT_AUTH=true", "AGENT_JWT_JWKS=jwks.json"},
+		Flags:       map[string]string{"show_lines": "maybe"},
+	})
+
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Expected *ValidationError got %v", err)
+	}
+
+	expected := []string{
+		`unknown option "http_prot"`,
+		"HTTP_PORT: invalid port 99999",
+		`AGENT_TOKEN_EXPIRATION (from environment): invalid number "huebr"`,
+		`show_lines (from flag): invalid boolean "maybe"`,
+		"CLUSTER_DISCOVERY=static requires CLUSTER_PEERS",
+		"AGENT_JWT_AUTH requires AGENT_JWT_ISSUER",
+		"AGENT_JWT_AUTH requires AGENT_JWT_AUDIENCE",
+	}
+
+	for _, e := range expected {
+		if !strings.Contains(verr.Error(), e) {
+			t.Errorf("Expected error to contain %q. Got:\n%s", e, verr.Error())
+		}
+	}
+
+	if len(verr.Problems) != len(expected) {
+		t.Errorf("Expected %d problems got %d: %v", len(expected), len(verr.Problems), verr.Problems)
+	}
+
+	// The valid values are kept and the invalid ones fall back to the defaults
+	if c == nil {
+		t.Fatalf("Expected the configuration to be returned with the errors")
+	}
+
+	if c.ClusterDiscovery != "static" || c.HttpPort != 99999 || c.AgentTokenExpiration != 3600 {
+		t.Errorf("Expected the loaded values to be kept. Got %+v", c)
+	}
+}
+
+func TestLoadInvalidFile(t *testing.T) {
+	if _, err := Load(Options{File: writeConfigFile(t, "chevron.ini", "http_port=1")}); err == nil {
+		t.Errorf("Expected error for unknown file format")
+	}
+
+	if _, err := Load(Options{File: writeConfigFile(t, "chevron.yaml", "http_port:\n  - 1\n")}); err == nil {
+		t.Errorf("Expected error for non scalar value")
+	}
+
+	c, err := Load(Options{File: "/nonexistent/chevron.yaml", Environment: []string{"HTTP_PORT=5300"}})
+	if err == nil {
+		t.Errorf("Expected error for missing file")
+	}
+
+	if c == nil || c.HttpPort != 5300 {
+		t.Errorf("Expected the environment to be loaded without the file")
+	}
+}
+
+func TestLoadDeprecated(t *testing.T) {
+	c, err := Load(Options{Environment: []string{"AUTH_MANAGER=true", "ENABLE_RETHINKDB_SKS=true"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if !c.DatabaseAuthManager {
+		t.Errorf("Expected AUTH_MANAGER to set DatabaseAuthManager")
+	}
+
+	if c.DatabaseDialect != "rethinkdb" || !c.EnableDatabase {
+		t.Errorf("Expected ENABLE_RETHINKDB_SKS to enable the rethinkdb database")
+	}
+
+	deprecations := c.Deprecations()
+	if deprecations["AUTH_MANAGER"] != "DATABASE_AUTH_MANAGER" {
+		t.Errorf("Expected AUTH_MANAGER deprecation got %v", deprecations)
+	}
+
+	// The current name wins over the deprecated one
+	c, err = Load(Options{Environment: []string{"DATABASE_DIALECT=memory", "ENABLE_DATABASE=true", "DATABASE_AUTH_MANAGER=false", "AUTH_MANAGER=true"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if c.DatabaseAuthManager {
+		t.Errorf("Expected DATABASE_AUTH_MANAGER to win over AUTH_MANAGER")
+	}
+}
+
+func TestDump(t *testing.T) {
+	c, err := Load(Options{Environment: []string{"VAULT_ROOT_TOKEN=supersecret", "HTTP_PORT=5300"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	data, err := c.Dump("yaml", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if strings.Contains(string(data), "supersecret") {
+		t.Errorf("Expected VAULT_ROOT_TOKEN to be redacted")
+	}
+
+	if !strings.Contains(string(data), "vault_root_token: <redacted>") || !strings.Contains(string(data), "http_port: 5300") {
+		t.Errorf("Unexpected dump:\n%s", data)
+	}
+
+	// A dump without redaction can be loaded back
+	for _, format := range []string{"yaml", "json"} {
+		data, err = c.Dump(format, false)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		loaded, err := Load(Options{File: writeConfigFile(t, "chevron."+format, string(data))})
+		if err != nil {
+			t.Fatalf("%s: unexpected error loading dump: %s", format, err)
+		}
+
+		if loaded.VaultRootToken != "supersecret" || loaded.HttpPort != 5300 || loaded.ClusterSyncInterval != c.ClusterSyncInterval {
+			t.Errorf("%s: dump does not match the configuration", format)
+		}
+	}
+
+	if _, err := c.Dump("xml", false); err == nil {
+		t.Errorf("Expected error for unknown format")
+	}
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"net"
+	"strings"
+	"time"
+)
+
+const modeSingleKey = "single_key"
+
+// validate adds to verr the values that are out of range and the options that conflict
+func (c *Config) validate(verr *ValidationError) {
+	oneOf := func(name, value string, allowed ...string) {
+		for _, v := range allowed {
+			if value == v {
+				return
+			}
+		}
+		expected := make([]string, 0, len(allowed))
+		for _, v := range allowed {
+			if v != "" {
+				expected = append(expected, v)
+			}
+		}
+		verr.add("%s: invalid value %q. Expected one of: %s", name, value, strings.Join(expected, ", "))
+	}
+
+	port := func(name string, value int) {
+		if value < 1 || value > 65535 {
+			verr.add("%s: invalid port %d", name, value)
+		}
+	}
+
+	positive := func(name string, value int64) {
+		if value <= 0 {
+			verr.add("%s: must be greater than zero", name)
+		}
+	}
+
+	positiveDuration := func(name string, value time.Duration) {
+		if value <= 0 {
+			verr.add("%s: must be greater than zero", name)
+		}
+	}
+
+	port("HTTP_PORT", c.HttpPort)
+	port("RETHINKDB_PORT", c.RethinkDBPort)
+
+	positive("RETHINKDB_POOL_SIZE", int64(c.RethinkDBPoolSize))
+	positive("AGENT_TOKEN_EXPIRATION", int64(c.AgentTokenExpiration))
+	positive("AGENT_MAX_BODY_SIZE", c.AgentMaxBodySize)
+	positive("REDIS_MAX_LOCAL_OBJECTS", int64(c.RedisMaxLocalObjects))
+	positive("HTTP_MAX_BODY_SIZE", c.HttpMaxBodySize)
+
+	if c.MaxKeyRingCache < 0 {
+		verr.add("MAX_KEYRING_CACHE_SIZE: must not be negative")
+	}
+
+	if c.AgentRetries < 0 {
+		verr.add("AGENT_RETRIES: must not be negative")
+	}
+
+	positiveDuration("DATABASE_TOKEN_REAPER_INTERVAL", c.DatabaseTokenReaperInterval)
+	positiveDuration("CLUSTER_SYNC_INTERVAL", c.ClusterSyncInterval)
+	positiveDuration("AGENT_JWT_JWKS_REFRESH", c.AgentJWTJWKSRefresh)
+	positiveDuration("AGENT_REQUEST_TIMEOUT", c.AgentRequestTimeout)
+	positiveDuration("AGENT_RETRY_BACKOFF", c.AgentRetryBackoff)
+	positiveDuration("REDIS_MAX_LOCAL_TTL", c.RedisLocalObjectTTL)
+	positiveDuration("HTTP_READ_TIMEOUT", c.HttpReadTimeout)
+	positiveDuration("HTTP_READ_HEADER_TIMEOUT", c.HttpReadHeaderTimeout)
+	positiveDuration("HTTP_WRITE_TIMEOUT", c.HttpWriteTimeout)
+	positiveDuration("HTTP_IDLE_TIMEOUT", c.HttpIdleTimeout)
+	positiveDuration("HTTP_SHUTDOWN_TIMEOUT", c.HttpShutdownTimeout)
+	positiveDuration("READY_CHECK_TIMEOUT", c.ReadyCheckTimeout)
+
+	if _, err := time.ParseDuration(c.VaultTokenTTL); err != nil {
+		verr.add("VAULT_TOKEN_TTL: invalid duration %q", c.VaultTokenTTL)
+	}
+
+	for _, proxy := range strings.Split(c.HttpTrustedProxies, ",") {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" || net.ParseIP(proxy) != nil {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(proxy); err != nil {
+			verr.add("HTTP_TRUSTED_PROXIES: invalid address or network %q", proxy)
+		}
+	}
+
+	oneOf("LOG_FORMAT", strings.ToLower(c.LogFormat), "", "pipe", "json")
+	oneOf("MODE", c.Mode, "", "default", modeSingleKey)
+	oneOf("CLUSTER_DISCOVERY", c.ClusterDiscovery, "", "none", "kubernetes", "static", "file", "dns")
+	oneOf("TLS_CLIENT_AUTH", strings.ToLower(c.TLSClientAuth), "", "require", "optional")
+	oneOf("AGENT_SIGN_MODE", strings.ToLower(c.AgentSignMode), "", "inject", "detached", "auto")
+
+	if c.EnableDatabase {
+		oneOf("DATABASE_DIALECT", c.DatabaseDialect, "rethinkdb", "postgres", "memory")
+	}
+
+	if c.Mode == modeSingleKey && c.SingleKeyPath == "" {
+		verr.add("MODE=single_key requires SINGLE_KEY_PATH")
+	}
+
+	switch c.ClusterDiscovery {
+	case "static":
+		if c.ClusterPeers == "" {
+			verr.add("CLUSTER_DISCOVERY=static requires CLUSTER_PEERS")
+		}
+	case "file":
+		if c.ClusterPeersFile == "" {
+			verr.add("CLUSTER_DISCOVERY=file requires CLUSTER_PEERS_FILE")
+		}
+	case "dns":
+		if c.ClusterDNSName == "" {
+			verr.add("CLUSTER_DISCOVERY=dns requires CLUSTER_DNS_NAME")
+		}
+	}
+
+	if c.AgentJWTAuth {
+		if c.AgentJWTJWKS == "" {
+			verr.add("AGENT_JWT_AUTH requires AGENT_JWT_JWKS")
+		}
+		if c.AgentJWTIssuer == "" {
+			verr.add("AGENT_JWT_AUTH requires AGENT_JWT_ISSUER")
+		}
+		if c.AgentJWTAudience == "" {
+			verr.add("AGENT_JWT_AUTH requires AGENT_JWT_AUDIENCE")
+		}
+	}
+
+	if (c.AgentTLSClientCert == "") != (c.AgentTLSClientKey == "") {
+		verr.add("AGENT_TLS_CLIENT_CERT and AGENT_TLS_CLIENT_KEY must be set together")
+	}
+
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		verr.add("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+
+	if c.TLSClientCAFile != "" && c.TLSCertFile == "" {
+		verr.add("TLS_CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE")
+	}
+
+	if c.RateLimitEnable {
+		oneOf("RATE_LIMIT_BACKEND", strings.ToLower(c.RateLimitBackend), "memory", "redis")
+		positiveDuration("RATE_LIMIT_WINDOW", c.RateLimitWindow)
+		positive("RATE_LIMIT_PRIVATE_KEY_REQUESTS", int64(c.RateLimitPrivateKeyRequests))
+		positive("RATE_LIMIT_PRIVATE_KEY_CONCURRENCY", int64(c.RateLimitPrivateKeyInFlight))
+		positive("RATE_LIMIT_LOOKUP_REQUESTS", int64(c.RateLimitLookupRequests))
+		positive("RATE_LIMIT_LOOKUP_CONCURRENCY", int64(c.RateLimitLookupInFlight))
+	}
+
+	if (c.DatabaseAuthManager || c.DatabaseTokenManager) && !c.EnableDatabase {
+		verr.add("DATABASE_AUTH_MANAGER and DATABASE_TOKEN_MANAGER require a database configuration")
+	}
+
+	if c.VaultUseUserpass && (c.VaultUsername == "" || c.VaultPassword == "") {
+		verr.add("VAULT_USE_USERPASS requires VAULT_USERNAME and VAULT_PASSWORD")
+	}
+}
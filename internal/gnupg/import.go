@@ -0,0 +1,382 @@
+// Package gnupg reads keys from a GnuPG home directory
+//
+// Public keys are read from pubring.kbx (keybox) and the legacy pubring.gpg.
+// Private keys are read from the gpg-agent private-keys-v1.d folder and matched with its public keys.
+package gnupg
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/quan-to/chevron/internal/tools"
+	"github.com/quan-to/chevron/pkg/interfaces"
+	"github.com/quan-to/chevron/pkg/openpgp"
+	"github.com/quan-to/chevron/pkg/openpgp/armor"
+	"github.com/quan-to/chevron/pkg/openpgp/packet"
+	"github.com/quan-to/slog"
+)
+
+const (
+	// StatusImported is the status of keys that were saved
+	StatusImported = "imported"
+	// StatusSkipped is the status of keys that were not saved by choice, like already existing keys
+	StatusSkipped = "skipped"
+	// StatusFailed is the status of keys that could not be read or saved
+	StatusFailed = "failed"
+)
+
+// PassphraseFunc returns the passphrase of a private key. It is used to decrypt the GnuPG key and to encrypt the key saved by Chevron.
+// Returning an empty passphrase skips the private key
+type PassphraseFunc func(fingerPrint, identifier string) (string, error)
+
+// ImportResult is the result of the import of a single key
+type ImportResult struct {
+	FingerPrint string
+	Identifier  string
+	Private     bool
+	Status      string
+	Reason      string
+}
+
+// ImportReport contains the result of each key found in the GnuPG home
+type ImportReport struct {
+	Results []ImportResult
+}
+
+// Count returns the number of results with the specified status
+func (r *ImportReport) Count(status string) int {
+	n := 0
+	for _, v := range r.Results {
+		if v.Status == status {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (r *ImportReport) add(result ImportResult) {
+	r.Results = append(r.Results, result)
+}
+
+// Importer imports the keys of a GnuPG home directory into a PGP Manager
+type Importer struct {
+	log        slog.Instance
+	pgpMan     interfaces.PGPManager
+	passphrase PassphraseFunc
+}
+
+// MakeImporter creates an importer that saves the keys with pgpMan.
+// Keys are saved in the PGP Manager key backend. In remote mode the server also adds them to the PKS
+func MakeImporter(log slog.Instance, pgpMan interfaces.PGPManager, passphrase PassphraseFunc) *Importer {
+	if log == nil {
+		log = slog.Scope("GnuPG")
+	} else {
+		log = log.SubScope("GnuPG")
+	}
+
+	return &Importer{
+		log:        log,
+		pgpMan:     pgpMan,
+		passphrase: passphrase,
+	}
+}
+
+// Import reads the GnuPG home directory and saves its keys.
+// An error is returned only if the home directory cannot be read. Errors in single keys are in the report
+func (i *Importer) Import(ctx context.Context, home string) (*ImportReport, error) {
+	report := &ImportReport{}
+
+	entities, err := readPublicKeys(home, report)
+	if err != nil {
+		return nil, err
+	}
+
+	keyFiles, err := readPrivateKeyFiles(home, report)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := map[string]bool{}
+	for _, k := range i.pgpMan.GetLoadedKeys() {
+		existing[k.FingerPrint] = existing[k.FingerPrint] || k.ContainsPrivateKey
+	}
+
+	used := map[*privateKeyFile]bool{}
+	findKeyFile := func(pub *packet.PublicKey) *privateKeyFile {
+		for _, f := range keyFiles {
+			if f.matches(pub) {
+				used[f] = true
+				return f
+			}
+		}
+		return nil
+	}
+
+	for _, e := range entities {
+		fp := tools.ByteFingerPrint2FP16(e.PrimaryKey.Fingerprint[:])
+		result := ImportResult{
+			FingerPrint: fp,
+			Identifier:  tools.SimpleIdentitiesToString(tools.IdentityMapToArray(e.Identities)),
+		}
+
+		primary := findKeyFile(e.PrimaryKey)
+		subkeys := make([]*privateKeyFile, len(e.Subkeys))
+		for n, sub := range e.Subkeys {
+			subkeys[n] = findKeyFile(sub.PublicKey)
+		}
+
+		if primary != nil && !primary.shadowed() {
+			result.Private = true
+			isPrivate, ok := existing[fp]
+			if ok && isPrivate {
+				result.Status = StatusSkipped
+				result.Reason = "private key already exists"
+			} else {
+				i.importPrivate(e, primary, subkeys, &result)
+			}
+		} else {
+			_, ok := existing[fp]
+			if ok {
+				result.Status = StatusSkipped
+				result.Reason = "key already exists"
+			} else {
+				i.importPublic(e, &result)
+			}
+			if primary != nil {
+				result.Reason = joinReasons(result.Reason, "the private key is stored in a smartcard")
+			}
+		}
+
+		i.log.Info("Key %s (%s): %s %s", result.FingerPrint, result.Identifier, result.Status, result.Reason)
+		report.add(result)
+	}
+
+	for _, f := range keyFiles {
+		if !used[f] {
+			report.add(ImportResult{
+				Private: true,
+				Status:  StatusSkipped,
+				Reason:  fmt.Sprintf("no public key found for private key %s", f.keyGrip),
+			})
+		}
+	}
+
+	return report, nil
+}
+
+// importPublic saves the public key
+func (i *Importer) importPublic(e *openpgp.Entity, result *ImportResult) {
+	armored, err := armorEntity(e, false)
+	if err == nil {
+		err = i.pgpMan.SaveKey(result.FingerPrint, armored, nil)
+	}
+
+	if err != nil {
+		result.Status = StatusFailed
+		result.Reason = err.Error()
+		return
+	}
+
+	result.Status = StatusImported
+}
+
+// importPrivate decrypts the private keys, encrypts them with the same passphrase and saves them
+func (i *Importer) importPrivate(e *openpgp.Entity, primary *privateKeyFile, subkeys []*privateKeyFile, result *ImportResult) {
+	fail := func(err error) {
+		result.Status = StatusFailed
+		result.Reason = err.Error()
+	}
+
+	passphrase, err := i.passphrase(result.FingerPrint, result.Identifier)
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	if passphrase == "" {
+		result.Status = StatusSkipped
+		result.Reason = "no passphrase supplied"
+		return
+	}
+
+	key := *e
+	key.Subkeys = make([]openpgp.Subkey, 0, len(e.Subkeys))
+
+	key.PrivateKey, err = primary.privateKey(e.PrimaryKey, passphrase)
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	missing := 0
+	for n, sub := range e.Subkeys {
+		if subkeys[n] == nil || subkeys[n].shadowed() {
+			missing++
+			continue
+		}
+
+		sub.PrivateKey, err = subkeys[n].privateKey(sub.PublicKey, passphrase)
+		if err != nil {
+			fail(fmt.Errorf("subkey %s: %v", tools.ByteFingerPrint2FP16(sub.PublicKey.Fingerprint[:]), err))
+			return
+		}
+		key.Subkeys = append(key.Subkeys, sub)
+	}
+
+	// Encrypt keeps the decrypted key to sign the identities and subkeys in the serialization
+	err = key.PrivateKey.Encrypt([]byte(passphrase))
+	for _, sub := range key.Subkeys {
+		if err == nil {
+			err = sub.PrivateKey.Encrypt([]byte(passphrase))
+		}
+	}
+
+	armored := ""
+	if err == nil {
+		armored, err = armorEntity(&key, true)
+	}
+
+	if err == nil {
+		err = i.pgpMan.SaveKey(result.FingerPrint, armored, passphrase)
+	}
+
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	result.Status = StatusImported
+	if missing > 0 {
+		result.Reason = fmt.Sprintf("%d subkeys without private key were left out", missing)
+	}
+}
+
+// readPublicKeys reads the public keys from pubring.kbx and pubring.gpg. Keys that cannot be read are added to the report
+func readPublicKeys(home string, report *ImportReport) (openpgp.EntityList, error) {
+	keyblocks := make([][]byte, 0)
+	found := false
+
+	kbx, err := os.Open(path.Join(home, "pubring.kbx"))
+	if err == nil {
+		found = true
+		blocks, err := readKeybox(kbx)
+		_ = kbx.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading pubring.kbx: %v", err)
+		}
+		keyblocks = append(keyblocks, blocks...)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	legacy, err := ioutil.ReadFile(path.Join(home, "pubring.gpg"))
+	if err == nil {
+		found = true
+		keyblocks = append(keyblocks, legacy)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if !found {
+		return nil, fmt.Errorf("no pubring.kbx or pubring.gpg found in %s", home)
+	}
+
+	entities := make(openpgp.EntityList, 0)
+	seen := map[string]bool{}
+
+	for n, block := range keyblocks {
+		el, err := openpgp.ReadKeyRing(bytes.NewReader(block))
+		if err != nil {
+			report.add(ImportResult{
+				Status: StatusFailed,
+				Reason: fmt.Sprintf("error reading keyblock %d: %v", n, err),
+			})
+			continue
+		}
+
+		for _, e := range el {
+			fp := tools.ByteFingerPrint2FP16(e.PrimaryKey.Fingerprint[:])
+			if !seen[fp] {
+				seen[fp] = true
+				entities = append(entities, e)
+			}
+		}
+	}
+
+	return entities, nil
+}
+
+// readPrivateKeyFiles reads the keys in private-keys-v1.d. Files that cannot be read are added to the report
+func readPrivateKeyFiles(home string, report *ImportReport) ([]*privateKeyFile, error) {
+	files, err := filepath.Glob(path.Join(home, "private-keys-v1.d", "*.key"))
+	if err != nil {
+		return nil, err
+	}
+
+	keyFiles := make([]*privateKeyFile, 0)
+	for _, filename := range files {
+		f, err := readPrivateKeyFile(filename)
+		if err != nil {
+			report.add(ImportResult{
+				Private: true,
+				Status:  StatusFailed,
+				Reason:  fmt.Sprintf("error reading %s: %v", path.Base(filename), err),
+			})
+			continue
+		}
+		keyFiles = append(keyFiles, f)
+	}
+
+	return keyFiles, nil
+}
+
+// armorEntity serializes the public or private key in ASCII Armored format
+func armorEntity(e *openpgp.Entity, private bool) (string, error) {
+	serialized := bytes.NewBuffer(nil)
+	blockType := openpgp.PublicKeyType
+
+	var err error
+	if private {
+		blockType = openpgp.PrivateKeyType
+		err = e.SerializePrivate(serialized, &packet.Config{
+			DefaultHash: crypto.SHA512,
+		})
+	} else {
+		err = e.Serialize(serialized)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	buf := bytes.NewBuffer(nil)
+	w, err := armor.Encode(buf, blockType, nil)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := w.Write(serialized.Bytes()); err != nil {
+		return "", err
+	}
+
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func joinReasons(a, b string) string {
+	if a == "" {
+		return b
+	}
+
+	return a + ". " + b
+}
@@ -0,0 +1,225 @@
+package gnupg
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/quan-to/chevron/internal/keybackend"
+	"github.com/quan-to/chevron/internal/keymagic"
+	"github.com/quan-to/chevron/pkg/database/memory"
+	"github.com/quan-to/chevron/pkg/interfaces"
+	"github.com/quan-to/chevron/test"
+	"github.com/quan-to/slog"
+)
+
+const publicOnlyFingerprint = "10A4538B57767816"
+
+func makeTestPGPManager(t *testing.T) (interfaces.PGPManager, func()) {
+	folder, err := ioutil.TempDir("", "gnupg-import")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log := slog.Scope("TestGnuPG")
+	kb := keybackend.MakeSaveToDiskBackend(log, folder, "k_")
+	pgpMan := keymagic.MakePGPManager(log, kb, keymagic.MakeKeyRingManager(log, memory.MakeMemoryDBDriver(log)))
+
+	return pgpMan, func() {
+		_ = os.RemoveAll(folder)
+	}
+}
+
+func passphrase(value string) PassphraseFunc {
+	return func(fingerPrint, identifier string) (string, error) {
+		return value, nil
+	}
+}
+
+func resultOf(report *ImportReport, fingerPrint string) *ImportResult {
+	for _, v := range report.Results {
+		if v.FingerPrint == fingerPrint {
+			return &v
+		}
+	}
+
+	return nil
+}
+
+func TestImportPrivateKeys(t *testing.T) {
+	for _, home := range []string{"agent", "native"} {
+		t.Run(home, func(t *testing.T) {
+			ctx := context.Background()
+			pgpMan, cleanup := makeTestPGPManager(t)
+			defer cleanup()
+
+			importer := MakeImporter(nil, pgpMan, passphrase(test.TestKeyPassword))
+			report, err := importer.Import(ctx, "../../test/data/gnupg/"+home)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if report.Count(StatusImported) != 2 || report.Count(StatusFailed) != 0 {
+				t.Fatalf("expected 2 keys imported got %+v", report.Results)
+			}
+
+			r := resultOf(report, test.TestKeyFingerprint)
+			if r == nil || !r.Private || r.Identifier != "Jon HUEBR <jon@huebr.com>" {
+				t.Fatalf("expected private key %s in the report got %+v", test.TestKeyFingerprint, r)
+			}
+
+			r = resultOf(report, publicOnlyFingerprint)
+			if r == nil || r.Private {
+				t.Fatalf("expected public key %s in the report got %+v", publicOnlyFingerprint, r)
+			}
+
+			// Keys must be loaded from the key backend and usable with the GnuPG passphrase
+			pgpMan.LoadKeys(ctx)
+
+			if err := pgpMan.UnlockKey(ctx, test.TestKeyFingerprint, test.TestKeyPassword); err != nil {
+				t.Fatalf("error unlocking imported key: %s", err)
+			}
+
+			signature, err := pgpMan.SignData(ctx, test.TestKeyFingerprint, []byte("huebr"), crypto.SHA512)
+			if err != nil {
+				t.Fatalf("error signing with imported key: %s", err)
+			}
+
+			valid, err := pgpMan.VerifySignature(ctx, []byte("huebr"), signature)
+			if !valid || err != nil {
+				t.Fatalf("expected valid signature: %v", err)
+			}
+
+			encrypted, err := pgpMan.Encrypt(ctx, "", test.TestKeyFingerprint, []byte("huebr"), false)
+			if err != nil {
+				t.Fatalf("error encrypting: %s", err)
+			}
+
+			decrypted, err := pgpMan.Decrypt(ctx, encrypted, false)
+			if err != nil || decrypted.Base64Data != "aHVlYnI=" {
+				t.Fatalf("error decrypting with imported subkey: %v", err)
+			}
+
+			if pgpMan.GetPublicKeyEntity(ctx, publicOnlyFingerprint) == nil {
+				t.Fatalf("expected public key %s to be imported", publicOnlyFingerprint)
+			}
+
+			// Importing again skips the existing keys
+			report, err = importer.Import(ctx, "../../test/data/gnupg/"+home)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if report.Count(StatusSkipped) != 2 {
+				t.Fatalf("expected 2 keys skipped got %+v", report.Results)
+			}
+		})
+	}
+}
+
+func TestImportWrongPassphrase(t *testing.T) {
+	for _, home := range []string{"agent", "native"} {
+		t.Run(home, func(t *testing.T) {
+			pgpMan, cleanup := makeTestPGPManager(t)
+			defer cleanup()
+
+			report, err := MakeImporter(nil, pgpMan, passphrase("wrong passphrase")).Import(context.Background(), "../../test/data/gnupg/"+home)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := resultOf(report, test.TestKeyFingerprint)
+			if r == nil || r.Status != StatusFailed {
+				t.Fatalf("expected key %s to fail got %+v", test.TestKeyFingerprint, r)
+			}
+
+			if resultOf(report, publicOnlyFingerprint).Status != StatusImported {
+				t.Fatalf("expected public key to be imported got %+v", report.Results)
+			}
+		})
+	}
+}
+
+func TestImportWithoutPassphrase(t *testing.T) {
+	pgpMan, cleanup := makeTestPGPManager(t)
+	defer cleanup()
+
+	report, err := MakeImporter(nil, pgpMan, passphrase("")).Import(context.Background(), "../../test/data/gnupg/agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := resultOf(report, test.TestKeyFingerprint)
+	if r == nil || r.Status != StatusSkipped || !r.Private {
+		t.Fatalf("expected private key %s to be skipped got %+v", test.TestKeyFingerprint, r)
+	}
+
+	failing := func(fingerPrint, identifier string) (string, error) {
+		return "", fmt.Errorf("no terminal")
+	}
+
+	report, err = MakeImporter(nil, pgpMan, failing).Import(context.Background(), "../../test/data/gnupg/agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r = resultOf(report, test.TestKeyFingerprint)
+	if r == nil || r.Status != StatusFailed || r.Reason != "no terminal" {
+		t.Fatalf("expected private key %s to fail got %+v", test.TestKeyFingerprint, r)
+	}
+}
+
+func TestImportLegacyPubring(t *testing.T) {
+	pgpMan, cleanup := makeTestPGPManager(t)
+	defer cleanup()
+
+	// pubring.gpg contains trust packets that must be ignored
+	report, err := MakeImporter(nil, pgpMan, passphrase("")).Import(context.Background(), "../../test/data/gnupg/legacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Results) != 1 || report.Results[0].FingerPrint != publicOnlyFingerprint || report.Results[0].Status != StatusImported {
+		t.Fatalf("expected public key %s to be imported got %+v", publicOnlyFingerprint, report.Results)
+	}
+}
+
+func TestImportInvalidHome(t *testing.T) {
+	pgpMan, cleanup := makeTestPGPManager(t)
+	defer cleanup()
+
+	importer := MakeImporter(nil, pgpMan, passphrase(""))
+
+	if _, err := importer.Import(context.Background(), "../../test/data/gnupg"); err == nil {
+		t.Fatal("expected error for a folder without pubring")
+	}
+
+	folder, _ := ioutil.TempDir("", "gnupg-home")
+	defer os.RemoveAll(folder)
+
+	_ = ioutil.WriteFile(folder+"/pubring.kbx", []byte("not a keybox file"), 0600)
+	if _, err := importer.Import(context.Background(), folder); err == nil {
+		t.Fatal("expected error for an invalid keybox")
+	}
+
+	// Broken private key files and private keys without public keys are in the report
+	_ = os.Remove(folder + "/pubring.kbx")
+	_ = ioutil.WriteFile(folder+"/pubring.gpg", nil, 0600)
+	_ = os.Mkdir(folder+"/private-keys-v1.d", 0700)
+	_ = ioutil.WriteFile(folder+"/private-keys-v1.d/broken.key", []byte("Key: (private-key"), 0600)
+
+	data, _ := ioutil.ReadFile("../../test/data/gnupg/agent/private-keys-v1.d/834BD6719673FC7175AC45C750F214CBCB90D55F.key")
+	_ = ioutil.WriteFile(folder+"/private-keys-v1.d/834BD6719673FC7175AC45C750F214CBCB90D55F.key", data, 0600)
+
+	report, err := importer.Import(context.Background(), folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Count(StatusFailed) != 1 || report.Count(StatusSkipped) != 1 {
+		t.Fatalf("expected one failed and one skipped private key got %+v", report.Results)
+	}
+}
@@ -0,0 +1,65 @@
+package gnupg
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	keyboxBlobHeader  = 1
+	keyboxBlobOpenPGP = 2
+
+	keyboxMaxBlobSize = 16 * 1024 * 1024
+)
+
+// readKeybox reads a GnuPG keybox file (pubring.kbx) and returns the OpenPGP keyblocks it contains.
+// X.509 certificates are ignored.
+//
+// Each blob starts with its length (4 bytes), type (1 byte), version (1 byte) and flags (2 bytes).
+// OpenPGP blobs follow with the offset (4 bytes) and length (4 bytes) of the keyblock inside the blob
+func readKeybox(r io.Reader) ([][]byte, error) {
+	keyblocks := make([][]byte, 0)
+
+	for i := 0; ; i++ {
+		var size uint32
+		err := binary.Read(r, binary.BigEndian, &size)
+		if err == io.EOF {
+			return keyblocks, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading blob %d: %v", i, err)
+		}
+
+		if size < 8 || size > keyboxMaxBlobSize {
+			return nil, fmt.Errorf("invalid size %d for blob %d", size, i)
+		}
+
+		blob := make([]byte, size)
+		binary.BigEndian.PutUint32(blob, size)
+		if _, err := io.ReadFull(r, blob[4:]); err != nil {
+			return nil, fmt.Errorf("error reading blob %d: %v", i, err)
+		}
+
+		if i == 0 && blob[4] != keyboxBlobHeader {
+			return nil, fmt.Errorf("not a keybox file")
+		}
+
+		if blob[4] != keyboxBlobOpenPGP {
+			continue
+		}
+
+		if size < 16 {
+			return nil, fmt.Errorf("invalid OpenPGP blob %d", i)
+		}
+
+		offset := binary.BigEndian.Uint32(blob[8:])
+		length := binary.BigEndian.Uint32(blob[12:])
+
+		if uint64(offset)+uint64(length) > uint64(size) {
+			return nil, fmt.Errorf("invalid keyblock position in blob %d", i)
+		}
+
+		keyblocks = append(keyblocks, blob[offset:offset+length])
+	}
+}
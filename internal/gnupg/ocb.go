@@ -0,0 +1,162 @@
+package gnupg
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"fmt"
+)
+
+const ocbTagSize = 16
+
+// ocbOpen decrypts and authenticates data sealed with OCB (RFC 7253) using a 128 bit tag.
+// The tag is expected at the end of data. This is the mode used by gpg-agent to protect private keys
+func ocbOpen(block cipher.Block, nonce, data, aad []byte) ([]byte, error) {
+	if block.BlockSize() != 16 {
+		return nil, fmt.Errorf("OCB requires a 128 bit block cipher")
+	}
+
+	if len(nonce) == 0 || len(nonce) > 15 {
+		return nil, fmt.Errorf("invalid OCB nonce size %d", len(nonce))
+	}
+
+	if len(data) < ocbTagSize {
+		return nil, fmt.Errorf("OCB data is too short")
+	}
+
+	ciphertext, tag := data[:len(data)-ocbTagSize], data[len(data)-ocbTagSize:]
+
+	lStar := make([]byte, 16)
+	block.Encrypt(lStar, lStar)
+	lDollar := ocbDouble(lStar)
+	l := [][]byte{ocbDouble(lDollar)}
+	lAt := func(i int) []byte {
+		for len(l) <= i {
+			l = append(l, ocbDouble(l[len(l)-1]))
+		}
+		return l[i]
+	}
+
+	// Nonce-dependent initial offset
+	n := make([]byte, 16)
+	copy(n[16-len(nonce):], nonce)
+	n[15-len(nonce)] |= 1
+	bottom := uint(n[15] & 0x3f)
+	n[15] &= 0xc0
+
+	kTop := make([]byte, 16)
+	block.Encrypt(kTop, n)
+	stretch := make([]byte, 24)
+	copy(stretch, kTop)
+	for i := 0; i < 8; i++ {
+		stretch[16+i] = kTop[i] ^ kTop[i+1]
+	}
+
+	offset := make([]byte, 16)
+	byteShift, bitShift := bottom/8, bottom%8
+	for i := uint(0); i < 16; i++ {
+		offset[i] = stretch[i+byteShift] << bitShift
+		if bitShift > 0 {
+			offset[i] |= stretch[i+byteShift+1] >> (8 - bitShift)
+		}
+	}
+
+	plaintext := make([]byte, len(ciphertext))
+	checksum := make([]byte, 16)
+	tmp := make([]byte, 16)
+	blocks := len(ciphertext) / 16
+
+	for i := 0; i < blocks; i++ {
+		ocbXor(offset, lAt(ntz(i+1)))
+		c := ciphertext[i*16 : (i+1)*16]
+		p := plaintext[i*16 : (i+1)*16]
+		copy(tmp, c)
+		ocbXor(tmp, offset)
+		block.Decrypt(p, tmp)
+		ocbXor(p, offset)
+		ocbXor(checksum, p)
+	}
+
+	if rest := len(ciphertext) % 16; rest > 0 {
+		ocbXor(offset, lStar)
+		pad := make([]byte, 16)
+		block.Encrypt(pad, offset)
+		p := plaintext[blocks*16:]
+		for i := 0; i < rest; i++ {
+			p[i] = ciphertext[blocks*16+i] ^ pad[i]
+			checksum[i] ^= p[i]
+		}
+		checksum[rest] ^= 0x80
+	}
+
+	expected := make([]byte, 16)
+	copy(expected, checksum)
+	ocbXor(expected, offset)
+	ocbXor(expected, lDollar)
+	block.Encrypt(expected, expected)
+	ocbXor(expected, ocbHash(block, lStar, lAt, aad))
+
+	if subtle.ConstantTimeCompare(expected, tag) != 1 {
+		return nil, fmt.Errorf("OCB authentication failed")
+	}
+
+	return plaintext, nil
+}
+
+// ocbHash is the HASH function of RFC 7253 over the associated data
+func ocbHash(block cipher.Block, lStar []byte, lAt func(int) []byte, aad []byte) []byte {
+	sum := make([]byte, 16)
+	offset := make([]byte, 16)
+	tmp := make([]byte, 16)
+	blocks := len(aad) / 16
+
+	for i := 0; i < blocks; i++ {
+		ocbXor(offset, lAt(ntz(i+1)))
+		copy(tmp, aad[i*16:(i+1)*16])
+		ocbXor(tmp, offset)
+		block.Encrypt(tmp, tmp)
+		ocbXor(sum, tmp)
+	}
+
+	if rest := len(aad) % 16; rest > 0 {
+		ocbXor(offset, lStar)
+		for i := range tmp {
+			tmp[i] = 0
+		}
+		copy(tmp, aad[blocks*16:])
+		tmp[rest] = 0x80
+		ocbXor(tmp, offset)
+		block.Encrypt(tmp, tmp)
+		ocbXor(sum, tmp)
+	}
+
+	return sum
+}
+
+// ocbDouble multiplies the block by two in GF(2^128)
+func ocbDouble(in []byte) []byte {
+	out := make([]byte, 16)
+	carry := in[0] >> 7
+	for i := 0; i < 15; i++ {
+		out[i] = in[i]<<1 | in[i+1]>>7
+	}
+	out[15] = in[15]<<1 ^ carry*0x87
+
+	return out
+}
+
+func ocbXor(dst, src []byte) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
+
+// ntz returns the number of trailing zero bits of n
+func ntz(n int) int {
+	z := 0
+	for n&1 == 0 {
+		n >>= 1
+		z++
+	}
+
+	return z
+}
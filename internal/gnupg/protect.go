@@ -0,0 +1,531 @@
+package gnupg
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/dsa"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha1"
+	_ "crypto/sha256" // Registers SHA224 and SHA256 for the key protection
+	_ "crypto/sha512" // Registers SHA384 and SHA512 for the key protection
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/quan-to/chevron/pkg/openpgp/elgamal"
+	"github.com/quan-to/chevron/pkg/openpgp/packet"
+	"github.com/quan-to/chevron/pkg/openpgp/s2k"
+	"golang.org/x/crypto/cast5"
+)
+
+const (
+	protectionNative = "openpgp-native"
+	protectionOCB    = "openpgp-s2k3-ocb-aes"
+	protectionCBC    = "openpgp-s2k3-sha1-aes-cbc"
+)
+
+// secretParamNames are the names of the secret parameters of each libgcrypt algorithm in OpenPGP order
+var secretParamNames = map[string][]string{
+	"rsa": {"d", "p", "q", "u"},
+	"dsa": {"x"},
+	"elg": {"x"},
+	"ecc": {"d"},
+}
+
+// privateKeyFile is a key stored by gpg-agent in private-keys-v1.d
+type privateKeyFile struct {
+	keyGrip string
+	kind    string
+	key     *sexp
+}
+
+// readPrivateKeyFile reads a file from private-keys-v1.d
+func readPrivateKeyFile(filename string) (*privateKeyFile, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := parseKeyFile(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(s.list) < 2 || !s.list[1].isList {
+		return nil, fmt.Errorf("invalid key expression")
+	}
+
+	return &privateKeyFile{
+		keyGrip: strings.TrimSuffix(path.Base(filename), ".key"),
+		kind:    s.name(),
+		key:     s.list[1],
+	}, nil
+}
+
+// parseKeyFile parses a key in the extended key format (name-value pairs) or in the legacy canonical S-Expression format
+func parseKeyFile(data []byte) (*sexp, error) {
+	if len(data) > 0 && data[0] == '(' {
+		return parseSexp(data)
+	}
+
+	key := bytes.NewBuffer(nil)
+	inKey := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') {
+			if inKey {
+				key.WriteString("\n")
+				key.WriteString(line)
+			}
+			continue
+		}
+
+		inKey = false
+		if strings.HasPrefix(strings.ToLower(line), "key:") {
+			inKey = true
+			key.WriteString(line[4:])
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if key.Len() == 0 {
+		return nil, fmt.Errorf("no key found in the key file")
+	}
+
+	return parseSexp(key.Bytes())
+}
+
+// algorithm returns the libgcrypt algorithm name
+func (k *privateKeyFile) algorithm() string {
+	algo := k.key.name()
+	if algo == "ecdsa" || algo == "ecdh" || algo == "eddsa" {
+		return "ecc"
+	}
+
+	return algo
+}
+
+// param returns a parameter of the key
+func (k *privateKeyFile) param(name string) *big.Int {
+	v := k.key.find(name).nth(1)
+	if v == nil || v.isList {
+		return nil
+	}
+
+	return new(big.Int).SetBytes(v.value)
+}
+
+// shadowed returns true for keys stored in smartcards
+func (k *privateKeyFile) shadowed() bool {
+	return k.kind == "shadowed-private-key"
+}
+
+// protected returns true if a passphrase is required to read the key
+func (k *privateKeyFile) protected() bool {
+	if k.kind != "protected-private-key" {
+		return false
+	}
+
+	p := k.key.find("protected")
+	if p.nth(1).String() != protectionNative {
+		return true
+	}
+
+	return k.nativeProtection() != "none"
+}
+
+func (k *privateKeyFile) nativeProtection() string {
+	return k.key.find("protected").nth(2).find("protection").nth(1).String()
+}
+
+// matches checks if the key file contains the secret parts of the public key
+func (k *privateKeyFile) matches(pub *packet.PublicKey) bool {
+	switch key := pub.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if k.algorithm() != "rsa" {
+			return false
+		}
+		return key.N.Cmp(bigOrZero(k.param("n"))) == 0
+	case *dsa.PublicKey:
+		if k.algorithm() != "dsa" {
+			return false
+		}
+		return key.Y.Cmp(bigOrZero(k.param("y"))) == 0
+	case *elgamal.PublicKey:
+		if k.algorithm() != "elg" {
+			return false
+		}
+		return key.Y.Cmp(bigOrZero(k.param("y"))) == 0
+	case *ecdsa.PublicKey:
+		if k.algorithm() != "ecc" {
+			return false
+		}
+		q := new(big.Int).SetBytes(elliptic.Marshal(key.Curve, key.X, key.Y))
+		return q.Cmp(bigOrZero(k.param("q"))) == 0
+	}
+
+	return false
+}
+
+func bigOrZero(v *big.Int) *big.Int {
+	if v == nil {
+		return new(big.Int)
+	}
+
+	return v
+}
+
+// privateKey decrypts the key file and returns the private key packet for the public key
+func (k *privateKeyFile) privateKey(pub *packet.PublicKey, passphrase string) (*packet.PrivateKey, error) {
+	if k.shadowed() {
+		return nil, fmt.Errorf("the key is stored in a smartcard")
+	}
+
+	params, err := k.secretParams(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	var priv interface{}
+
+	switch key := pub.PublicKey.(type) {
+	case *rsa.PublicKey:
+		r := &rsa.PrivateKey{
+			PublicKey: *key,
+			D:         params["d"],
+			Primes:    []*big.Int{params["p"], params["q"]},
+		}
+		if err := r.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid RSA key: %v", err)
+		}
+		r.Precompute()
+		priv = r
+	case *dsa.PublicKey:
+		priv = &dsa.PrivateKey{PublicKey: *key, X: params["x"]}
+	case *elgamal.PublicKey:
+		priv = &elgamal.PrivateKey{PublicKey: *key, X: params["x"]}
+	case *ecdsa.PublicKey:
+		priv = &ecdsa.PrivateKey{PublicKey: *key, D: params["d"]}
+	default:
+		return nil, fmt.Errorf("unsupported key algorithm %d", pub.PubKeyAlgo)
+	}
+
+	return &packet.PrivateKey{
+		PublicKey:  *pub,
+		PrivateKey: priv,
+	}, nil
+}
+
+// secretParams returns the secret parameters of the key decrypting it when needed
+func (k *privateKeyFile) secretParams(passphrase string) (map[string]*big.Int, error) {
+	names, ok := secretParamNames[k.algorithm()]
+	if !ok {
+		return nil, fmt.Errorf("unsupported key algorithm %q", k.key.name())
+	}
+
+	var values *sexp
+
+	switch k.kind {
+	case "private-key":
+		values = k.key
+	case "protected-private-key":
+		p := k.key.find("protected")
+		mode := p.nth(1).String()
+
+		switch mode {
+		case protectionNative:
+			return k.nativeSecretParams(names, passphrase)
+		case protectionOCB, protectionCBC:
+			v, err := k.decryptAgentKey(mode, passphrase)
+			if err != nil {
+				return nil, err
+			}
+			values = v
+		default:
+			return nil, fmt.Errorf("unsupported protection %q", mode)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.kind)
+	}
+
+	params := map[string]*big.Int{}
+	for _, name := range names {
+		v := values.find(name).nth(1)
+		if v == nil || v.isList {
+			return nil, fmt.Errorf("missing parameter %q", name)
+		}
+		params[name] = new(big.Int).SetBytes(v.value)
+	}
+
+	return params, nil
+}
+
+// decryptAgentKey decrypts a key protected by gpg-agent and returns the list with the secret parameters
+//
+//	(protected openpgp-s2k3-ocb-aes ((sha1 <salt> <count>) <nonce>) <encrypted data>)
+func (k *privateKeyFile) decryptAgentKey(mode, passphrase string) (*sexp, error) {
+	p := k.key.find("protected")
+	params := p.nth(2)
+	kdf := params.nth(0)
+
+	if kdf.name() != "sha1" || kdf.nth(1) == nil || params.nth(1) == nil || p.nth(3) == nil {
+		return nil, fmt.Errorf("invalid protection parameters")
+	}
+
+	count, err := strconv.Atoi(kdf.nth(2).String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid s2k count: %v", err)
+	}
+
+	key := make([]byte, 16)
+	s2k.Iterated(key, sha1.New(), []byte(passphrase), kdf.nth(1).value, count)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	iv := params.nth(1).value
+	data := p.nth(3).value
+	var plain []byte
+
+	if mode == protectionOCB {
+		plain, err = ocbOpen(block, iv, data, k.associatedData())
+		if err != nil {
+			return nil, fmt.Errorf("bad passphrase")
+		}
+	} else {
+		if len(data) == 0 || len(data)%aes.BlockSize != 0 || len(iv) != aes.BlockSize {
+			return nil, fmt.Errorf("invalid encrypted data")
+		}
+		plain = make([]byte, len(data))
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, data)
+	}
+
+	// The plaintext is ((<secret parameters>)(hash sha1 <hash>)) followed by padding. OCB does not include the hash
+	s, err := parseSexp(plain)
+	if err != nil || s.nth(0) == nil || !s.nth(0).isList {
+		return nil, fmt.Errorf("bad passphrase")
+	}
+
+	return s.nth(0), nil
+}
+
+// associatedData returns the data authenticated by OCB: the key expression without the protected list
+func (k *privateKeyFile) associatedData() []byte {
+	aad := &sexp{isList: true}
+	for _, v := range k.key.list {
+		if v.isList && v.name() == "protected" {
+			continue
+		}
+		aad.list = append(aad.list, v)
+	}
+
+	return aad.canonical()
+}
+
+// nativeSecretParams reads a key imported by gpg-agent that is still protected by the OpenPGP secret key packet encryption
+//
+//	(protected openpgp-native (openpgp-private-key (version V)(algo A)(skey _ P1 _ P2 e ENCRYPTED)(csum C)
+//	                          (protection sha1|sum|none CIPHER IV S2KMODE S2KHASH SALT COUNT)))
+func (k *privateKeyFile) nativeSecretParams(names []string, passphrase string) (map[string]*big.Int, error) {
+	native := k.key.find("protected").nth(2)
+	skey := native.find("skey")
+	protection := native.find("protection")
+
+	if skey == nil || protection == nil {
+		return nil, fmt.Errorf("invalid openpgp-native key")
+	}
+
+	// Values are in pairs: "_" for plain values and "e" for the encrypted blob
+	plainValues := make([][]byte, 0)
+	var encrypted []byte
+	for i := 1; i+1 < len(skey.list); i += 2 {
+		switch skey.list[i].String() {
+		case "_":
+			plainValues = append(plainValues, skey.list[i+1].value)
+		case "e":
+			encrypted = skey.list[i+1].value
+		}
+	}
+
+	params := map[string]*big.Int{}
+
+	if k.nativeProtection() == "none" {
+		if len(plainValues) < len(names) {
+			return nil, fmt.Errorf("missing secret parameters")
+		}
+		secret := plainValues[len(plainValues)-len(names):]
+		for i, name := range names {
+			params[name] = new(big.Int).SetBytes(secret[i])
+		}
+		return params, nil
+	}
+
+	if encrypted == nil {
+		return nil, fmt.Errorf("missing encrypted secret parameters")
+	}
+
+	data, err := decryptNative(protection, encrypted, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	r := bytes.NewReader(data)
+	for _, name := range names {
+		v, err := readMPI(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret parameters: %v", err)
+		}
+		params[name] = v
+	}
+
+	return params, nil
+}
+
+// decryptNative decrypts the secret parameters in OpenPGP format and checks its checksum
+func decryptNative(protection *sexp, encrypted []byte, passphrase string) ([]byte, error) {
+	checksum := protection.nth(1).String()
+	iv := protection.nth(3)
+	salt := protection.nth(6)
+
+	if iv == nil || salt == nil && protection.nth(4).String() != "0" {
+		return nil, fmt.Errorf("invalid protection parameters")
+	}
+
+	keySize, newCipher, err := nativeCipher(protection.nth(2).String())
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := nativeHash(protection.nth(5).String())
+	if err != nil {
+		return nil, err
+	}
+
+	key := make([]byte, keySize)
+	switch protection.nth(4).String() {
+	case "0":
+		s2k.Simple(key, h.New(), []byte(passphrase))
+	case "1":
+		s2k.Salted(key, h.New(), []byte(passphrase), salt.value)
+	case "3":
+		count, err := strconv.Atoi(protection.nth(7).String())
+		if err != nil || count < 0 || count > 255 {
+			return nil, fmt.Errorf("invalid s2k count")
+		}
+		s2k.Iterated(key, h.New(), []byte(passphrase), salt.value, decodeCount(uint8(count)))
+	default:
+		return nil, fmt.Errorf("unsupported s2k mode %s", protection.nth(4).String())
+	}
+
+	block, err := newCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(iv.value) != block.BlockSize() {
+		return nil, fmt.Errorf("invalid IV size")
+	}
+
+	data := make([]byte, len(encrypted))
+	cipher.NewCFBDecrypter(block, iv.value).XORKeyStream(data, encrypted)
+
+	if checksum == "sha1" {
+		if len(data) < sha1.Size {
+			return nil, fmt.Errorf("bad passphrase")
+		}
+		sum := sha1.Sum(data[:len(data)-sha1.Size])
+		if !bytes.Equal(sum[:], data[len(data)-sha1.Size:]) {
+			return nil, fmt.Errorf("bad passphrase")
+		}
+		return data[:len(data)-sha1.Size], nil
+	}
+
+	if len(data) < 2 {
+		return nil, fmt.Errorf("bad passphrase")
+	}
+
+	var sum uint16
+	for _, b := range data[:len(data)-2] {
+		sum += uint16(b)
+	}
+	if data[len(data)-2] != uint8(sum>>8) || data[len(data)-1] != uint8(sum) {
+		return nil, fmt.Errorf("bad passphrase")
+	}
+
+	return data[:len(data)-2], nil
+}
+
+// decodeCount decodes the OpenPGP s2k iteration count
+func decodeCount(c uint8) int {
+	return (16 + int(c&15)) << (uint32(c>>4) + 6)
+}
+
+func nativeCipher(name string) (int, func([]byte) (cipher.Block, error), error) {
+	switch strings.ToUpper(name) {
+	case "AES", "AES128":
+		return 16, aes.NewCipher, nil
+	case "AES192":
+		return 24, aes.NewCipher, nil
+	case "AES256":
+		return 32, aes.NewCipher, nil
+	case "3DES":
+		return 24, des.NewTripleDESCipher, nil
+	case "CAST5":
+		return 16, func(key []byte) (cipher.Block, error) {
+			return cast5.NewCipher(key)
+		}, nil
+	}
+
+	return 0, nil, fmt.Errorf("unsupported cipher %q", name)
+}
+
+func nativeHash(name string) (crypto.Hash, error) {
+	switch strings.ToUpper(name) {
+	case "SHA1":
+		return crypto.SHA1, nil
+	case "SHA224":
+		return crypto.SHA224, nil
+	case "SHA256":
+		return crypto.SHA256, nil
+	case "SHA384":
+		return crypto.SHA384, nil
+	case "SHA512":
+		return crypto.SHA512, nil
+	}
+
+	return 0, fmt.Errorf("unsupported hash %q", name)
+}
+
+// readMPI reads an OpenPGP multiprecision integer
+func readMPI(r io.Reader) (*big.Int, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	bits := int(header[0])<<8 | int(header[1])
+	v := make([]byte, (bits+7)/8)
+	if _, err := io.ReadFull(r, v); err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(v), nil
+}
@@ -0,0 +1,266 @@
+package gnupg
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+)
+
+// sexp is a node of a S-Expression as used by libgcrypt. It is either an atom (value) or a list
+type sexp struct {
+	value  []byte
+	list   []*sexp
+	isList bool
+}
+
+// String returns the atom value as string
+func (s *sexp) String() string {
+	if s == nil || s.isList {
+		return ""
+	}
+
+	return string(s.value)
+}
+
+// nth returns the nth element of the list or nil
+func (s *sexp) nth(n int) *sexp {
+	if s == nil || !s.isList || n >= len(s.list) {
+		return nil
+	}
+
+	return s.list[n]
+}
+
+// name returns the value of the first element of a list
+func (s *sexp) name() string {
+	return s.nth(0).String()
+}
+
+// find returns the first sub-list of s named name
+func (s *sexp) find(name string) *sexp {
+	if s == nil || !s.isList {
+		return nil
+	}
+
+	for _, v := range s.list {
+		if v.isList && v.name() == name {
+			return v
+		}
+	}
+
+	return nil
+}
+
+// canonical returns the canonical encoding of the expression
+func (s *sexp) canonical() []byte {
+	buf := bytes.NewBuffer(nil)
+	s.writeCanonical(buf)
+
+	return buf.Bytes()
+}
+
+func (s *sexp) writeCanonical(buf *bytes.Buffer) {
+	if !s.isList {
+		buf.WriteString(strconv.Itoa(len(s.value)))
+		buf.WriteByte(':')
+		buf.Write(s.value)
+		return
+	}
+
+	buf.WriteByte('(')
+	for _, v := range s.list {
+		v.writeCanonical(buf)
+	}
+	buf.WriteByte(')')
+}
+
+// sexpParser parses S-Expressions in canonical or advanced format
+type sexpParser struct {
+	data []byte
+	pos  int
+}
+
+// parseSexp parses the first expression in data. Anything after it (like padding) is ignored
+func parseSexp(data []byte) (*sexp, error) {
+	p := &sexpParser{data: data}
+	p.skipSpaces()
+
+	if p.pos >= len(p.data) || p.data[p.pos] != '(' {
+		return nil, fmt.Errorf("expected a list at position %d", p.pos)
+	}
+
+	return p.parse()
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isTokenChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || isDigit(c) || bytes.IndexByte([]byte("-./_:*+="), c) != -1
+}
+
+func (p *sexpParser) skipSpaces() {
+	for p.pos < len(p.data) && isSpace(p.data[p.pos]) {
+		p.pos++
+	}
+}
+
+func (p *sexpParser) parse() (*sexp, error) {
+	p.skipSpaces()
+	if p.pos >= len(p.data) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	c := p.data[p.pos]
+
+	switch {
+	case c == '(':
+		p.pos++
+		node := &sexp{isList: true}
+		for {
+			p.skipSpaces()
+			if p.pos >= len(p.data) {
+				return nil, fmt.Errorf("unexpected end of expression")
+			}
+			if p.data[p.pos] == ')' {
+				p.pos++
+				return node, nil
+			}
+			v, err := p.parse()
+			if err != nil {
+				return nil, err
+			}
+			node.list = append(node.list, v)
+		}
+	case isDigit(c):
+		return p.parseVerbatim()
+	case c == '#':
+		return p.parseDelimited('#', func(v []byte) ([]byte, error) {
+			return hex.DecodeString(string(removeSpaces(v)))
+		})
+	case c == '|':
+		return p.parseDelimited('|', func(v []byte) ([]byte, error) {
+			return base64.StdEncoding.DecodeString(string(removeSpaces(v)))
+		})
+	case c == '"':
+		return p.parseQuoted()
+	case isTokenChar(c):
+		start := p.pos
+		for p.pos < len(p.data) && isTokenChar(p.data[p.pos]) {
+			p.pos++
+		}
+		return &sexp{value: p.data[start:p.pos]}, nil
+	}
+
+	return nil, fmt.Errorf("unexpected character %q at position %d", c, p.pos)
+}
+
+// parseVerbatim parses a length prefixed value like 3:abc
+func (p *sexpParser) parseVerbatim() (*sexp, error) {
+	start := p.pos
+	for p.pos < len(p.data) && isDigit(p.data[p.pos]) {
+		p.pos++
+	}
+
+	if p.pos >= len(p.data) || p.data[p.pos] != ':' {
+		return nil, fmt.Errorf("invalid length prefix at position %d", start)
+	}
+
+	n, err := strconv.Atoi(string(p.data[start:p.pos]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid length prefix at position %d: %v", start, err)
+	}
+
+	p.pos++
+	if n > len(p.data)-p.pos {
+		return nil, fmt.Errorf("value at position %d is longer than the expression", start)
+	}
+
+	v := &sexp{value: p.data[p.pos : p.pos+n]}
+	p.pos += n
+
+	return v, nil
+}
+
+func (p *sexpParser) parseDelimited(delimiter byte, decode func([]byte) ([]byte, error)) (*sexp, error) {
+	start := p.pos
+	end := bytes.IndexByte(p.data[p.pos+1:], delimiter)
+	if end == -1 {
+		return nil, fmt.Errorf("unterminated value at position %d", start)
+	}
+
+	v, err := decode(p.data[p.pos+1 : p.pos+1+end])
+	if err != nil {
+		return nil, fmt.Errorf("invalid value at position %d: %v", start, err)
+	}
+
+	p.pos += end + 2
+
+	return &sexp{value: v}, nil
+}
+
+func (p *sexpParser) parseQuoted() (*sexp, error) {
+	start := p.pos
+	p.pos++
+	v := make([]byte, 0)
+
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+
+		switch c {
+		case '"':
+			return &sexp{value: v}, nil
+		case '\\':
+			if p.pos >= len(p.data) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			e := p.data[p.pos]
+			p.pos++
+			switch e {
+			case 'n':
+				v = append(v, '\n')
+			case 'r':
+				v = append(v, '\r')
+			case 't':
+				v = append(v, '\t')
+			case 'x':
+				if p.pos+2 > len(p.data) {
+					return nil, fmt.Errorf("invalid escape at position %d", p.pos)
+				}
+				b, err := hex.DecodeString(string(p.data[p.pos : p.pos+2]))
+				if err != nil {
+					return nil, fmt.Errorf("invalid escape at position %d", p.pos)
+				}
+				v = append(v, b[0])
+				p.pos += 2
+			case '\n':
+				// Line continuation
+			default:
+				v = append(v, e)
+			}
+		default:
+			v = append(v, c)
+		}
+	}
+
+	return nil, fmt.Errorf("unterminated string at position %d", start)
+}
+
+func removeSpaces(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	for _, c := range data {
+		if !isSpace(c) {
+			out = append(out, c)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,154 @@
+package gnupg
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"encoding/hex"
+	"strconv"
+	"testing"
+
+	"github.com/quan-to/chevron/pkg/openpgp/s2k"
+)
+
+func TestParseSexp(t *testing.T) {
+	advanced := []byte(`(private-key (rsa (n #00C1
+  02#)(e "\x01")(comment |aHVl YnI=|) (protected-at 3:abc)))`)
+
+	s, err := parseSexp(advanced)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaKey := s.nth(1)
+	if s.name() != "private-key" || rsaKey.name() != "rsa" {
+		t.Fatalf("unexpected expression %s", s.canonical())
+	}
+
+	if !bytes.Equal(rsaKey.find("n").nth(1).value, []byte{0, 0xc1, 0x02}) {
+		t.Fatalf("unexpected hex value %x", rsaKey.find("n").nth(1).value)
+	}
+
+	if rsaKey.find("comment").nth(1).String() != "huebr" || rsaKey.find("protected-at").nth(1).String() != "abc" {
+		t.Fatalf("unexpected values %s", s.canonical())
+	}
+
+	canonical := s.canonical()
+	expected := "(11:private-key(3:rsa(1:n3:\x00\xc1\x02)(1:e1:\x01)(7:comment5:huebr)(12:protected-at3:abc)))"
+	if string(canonical) != expected {
+		t.Fatalf("expected %q got %q", expected, canonical)
+	}
+
+	// Canonical expressions are parsed back and trailing padding is ignored
+	again, err := parseSexp(append(canonical, 0, 0, 0))
+	if err != nil || !bytes.Equal(again.canonical(), canonical) {
+		t.Fatalf("error parsing canonical expression: %v", err)
+	}
+
+	for _, invalid := range []string{"", "abc", "(rsa", "(rsa #0#)", "(10:abc)", "(rsa \"abc)", "(rsa {})"} {
+		if _, err := parseSexp([]byte(invalid)); err == nil {
+			t.Fatalf("expected error parsing %q", invalid)
+		}
+	}
+}
+
+func TestCBCProtectedKey(t *testing.T) {
+	// Keys protected by older gpg-agent versions use AES-CBC and include a SHA1 hash in the plaintext
+	salt := []byte("saltsalt")
+	iv := bytes.Repeat([]byte{1}, aes.BlockSize)
+	key := make([]byte, 16)
+	s2k.Iterated(key, sha1.New(), []byte("huebr"), salt, 65536)
+
+	plain := []byte("(((1:d1:\x07)(1:p1:\x03)(1:q1:\x05)(1:u1:\x02))(4:hash4:sha120:01234567890123456789))")
+	plain = append(plain, bytes.Repeat([]byte{0}, aes.BlockSize-len(plain)%aes.BlockSize)...)
+	block, _ := aes.NewCipher(key)
+	encrypted := make([]byte, len(plain))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, plain)
+
+	file := []byte("(21:protected-private-key(3:rsa(1:n2:\x00\x0f)(1:e1:\x03)(9:protected25:openpgp-s2k3-sha1-aes-cbc((4:sha18:saltsalt5:65536)16:" +
+		string(iv) + ")" + strconv.Itoa(len(encrypted)) + ":" + string(encrypted) + ")))")
+
+	s, err := parseKeyFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k := &privateKeyFile{kind: s.name(), key: s.nth(1)}
+	if !k.protected() {
+		t.Fatal("expected key to be protected")
+	}
+
+	params, err := k.secretParams("huebr")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if params["d"].Int64() != 7 || params["p"].Int64() != 3 || params["q"].Int64() != 5 || params["u"].Int64() != 2 {
+		t.Fatalf("unexpected parameters %v", params)
+	}
+
+	if _, err := k.secretParams("wrong"); err == nil {
+		t.Fatal("expected error with wrong passphrase")
+	}
+}
+
+func TestOCB(t *testing.T) {
+	// Test vectors from RFC 7253 Appendix A
+	key, _ := hex.DecodeString("000102030405060708090A0B0C0D0E0F")
+	block, _ := aes.NewCipher(key)
+
+	vectors := []struct {
+		nonce, aad, plaintext, ciphertext string
+	}{
+		{"BBAA99887766554433221100", "", "", "785407BFFFC8AD9EDCC5520AC9111EE6"},
+		{"BBAA99887766554433221101", "0001020304050607", "0001020304050607", "6820B3657B6F615A5725BDA0D3B4EB3A257C9AF1F8F03009"},
+		{"BBAA99887766554433221102", "0001020304050607", "", "81017F8203F081277152FADE694A0A00"},
+		{"BBAA99887766554433221103", "", "0001020304050607", "45DD69F8F5AAE72414054CD1F35D82760B2CD00D2F99BFA9"},
+		{"BBAA99887766554433221104", "000102030405060708090A0B0C0D0E0F", "000102030405060708090A0B0C0D0E0F", "571D535B60B277188BE5147170A9A22C3AD7A4FF3835B8C5701C1CCEC8FC3358"},
+		{"BBAA99887766554433221107", "000102030405060708090A0B0C0D0E0F1011121314151617", "000102030405060708090A0B0C0D0E0F1011121314151617", "1CA2207308C87C010756104D8840CE1952F09673A448A122C92C62241051F57356D7F3C90BB0E07F"},
+	}
+
+	for _, v := range vectors {
+		nonce, _ := hex.DecodeString(v.nonce)
+		aad, _ := hex.DecodeString(v.aad)
+		expected, _ := hex.DecodeString(v.plaintext)
+		ciphertext, _ := hex.DecodeString(v.ciphertext)
+
+		plaintext, err := ocbOpen(block, nonce, ciphertext, aad)
+		if err != nil || !bytes.Equal(plaintext, expected) {
+			t.Fatalf("vector %s: expected %x got %x (%v)", v.nonce, expected, plaintext, err)
+		}
+
+		ciphertext[0] ^= 1
+		if _, err := ocbOpen(block, nonce, ciphertext, aad); err == nil {
+			t.Fatalf("vector %s: expected authentication error", v.nonce)
+		}
+	}
+}
+
+func TestReadKeybox(t *testing.T) {
+	// Header blob, OpenPGP blob with the keyblock "abc" and a X.509 blob
+	kbx := []byte{0, 0, 0, 8, 1, 1, 0, 0}
+	kbx = append(kbx, 0, 0, 0, 19, 2, 1, 0, 0, 0, 0, 0, 16, 0, 0, 0, 3, 'a', 'b', 'c')
+	kbx = append(kbx, 0, 0, 0, 9, 3, 1, 0, 0, 'x')
+
+	keyblocks, err := readKeybox(bytes.NewReader(kbx))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(keyblocks) != 1 || string(keyblocks[0]) != "abc" {
+		t.Fatalf("unexpected keyblocks %q", keyblocks)
+	}
+
+	// Keyblock out of the blob
+	kbx[15+8] = 17
+	if _, err := readKeybox(bytes.NewReader(kbx)); err == nil {
+		t.Fatal("expected error for invalid keyblock position")
+	}
+
+	if _, err := readKeybox(bytes.NewReader(kbx[:10])); err == nil {
+		t.Fatal("expected error for truncated keybox")
+	}
+}
@@ -0,0 +1,46 @@
+package jwt
+
+import (
+	"strings"
+	"time"
+)
+
+// Claims are the decoded claims of a JWT
+type Claims map[string]interface{}
+
+// String returns the claim as a string or empty if it is not a string
+func (c Claims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// Strings returns the claim as a list of strings
+// A string claim is split by spaces and commas, as usually done for the scope claim
+func (c Claims) Strings(name string) []string {
+	switch v := c[name].(type) {
+	case string:
+		return strings.FieldsFunc(v, func(r rune) bool {
+			return r == ' ' || r == ','
+		})
+	case []interface{}:
+		res := make([]string, 0, len(v))
+		for _, i := range v {
+			if s, ok := i.(string); ok {
+				res = append(res, s)
+			}
+		}
+		return res
+	}
+
+	return nil
+}
+
+// Time returns a NumericDate claim as time
+func (c Claims) Time(name string) (time.Time, bool) {
+	v, ok := c[name].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return time.Unix(int64(v), 0), true
+}
@@ -0,0 +1,271 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quan-to/slog"
+)
+
+// minForcedRefresh is the minimum interval between refreshes triggered by unknown key ids
+const minForcedRefresh = 10 * time.Second
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// PublicKey is a public key from a JWKS
+type PublicKey struct {
+	ID        string
+	Algorithm string
+	Key       crypto.PublicKey
+}
+
+// ParseKeySet parses a JSON Web Key Set returning the signature keys indexed by key id
+// Keys with unsupported types are ignored
+func ParseKeySet(data []byte) (map[string]PublicKey, error) {
+	var set jsonWebKeySet
+
+	err := json.Unmarshal(data, &set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %v", err)
+	}
+
+	keys := map[string]PublicKey{}
+
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		var key crypto.PublicKey
+
+		switch jwk.Kty {
+		case "RSA":
+			key, err = parseRSAKey(jwk)
+		case "EC":
+			key, err = parseECKey(jwk)
+		default:
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %v", jwk.Kid, err)
+		}
+
+		keys[jwk.Kid] = PublicKey{
+			ID:        jwk.Kid,
+			Algorithm: jwk.Alg,
+			Key:       key,
+		}
+	}
+
+	return keys, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty value")
+	}
+
+	return new(big.Int).SetBytes(data), nil
+}
+
+func parseRSAKey(jwk jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := decodeBigInt(jwk.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %v", err)
+	}
+
+	e, err := decodeBigInt(jwk.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %v", err)
+	}
+
+	if !e.IsInt64() || e.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("exponent too large")
+	}
+
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func parseECKey(jwk jsonWebKey) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+
+	switch jwk.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+	}
+
+	x, err := decodeBigInt(jwk.X)
+	if err != nil {
+		return nil, fmt.Errorf("invalid x: %v", err)
+	}
+
+	y, err := decodeBigInt(jwk.Y)
+	if err != nil {
+		return nil, fmt.Errorf("invalid y: %v", err)
+	}
+
+	if !curve.IsOnCurve(x, y) {
+		return nil, fmt.Errorf("point is not on curve")
+	}
+
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// KeySource loads the keys of a JWKS from a file or an URL
+// The keys are reloaded after the refresh interval or when an unknown key id is requested
+type KeySource struct {
+	sync.RWMutex
+	log             slog.Instance
+	location        string
+	refreshInterval time.Duration
+	client          *http.Client
+	keys            map[string]PublicKey
+	lastRefresh     time.Time
+}
+
+// MakeKeySource creates a KeySource for the JWKS at location.
+// If location starts with http:// or https:// it is fetched from the network, otherwise it is read as a file
+func MakeKeySource(log slog.Instance, location string, refreshInterval time.Duration) *KeySource {
+	if log == nil {
+		log = slog.Scope("JWKS")
+	} else {
+		log = log.SubScope("JWKS")
+	}
+
+	return &KeySource{
+		log:             log,
+		location:        location,
+		refreshInterval: refreshInterval,
+		client:          &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (ks *KeySource) isURL() bool {
+	return strings.HasPrefix(ks.location, "http://") || strings.HasPrefix(ks.location, "https://")
+}
+
+func (ks *KeySource) fetch() ([]byte, error) {
+	if !ks.isURL() {
+		return ioutil.ReadFile(ks.location)
+	}
+
+	res, err := ks.client.Get(ks.location)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+
+	return ioutil.ReadAll(res.Body)
+}
+
+// Refresh reloads the keys from the JWKS location
+func (ks *KeySource) Refresh() error {
+	data, err := ks.fetch()
+	if err != nil {
+		return fmt.Errorf("error loading JWKS from %s: %v", ks.location, err)
+	}
+
+	keys, err := ParseKeySet(data)
+	if err != nil {
+		return err
+	}
+
+	ks.Lock()
+	ks.keys = keys
+	ks.lastRefresh = time.Now()
+	ks.Unlock()
+
+	ks.log.Debug("Loaded %d keys from %s", len(keys), ks.location)
+
+	return nil
+}
+
+func (ks *KeySource) lookup(kid string) (PublicKey, bool) {
+	if kid == "" && len(ks.keys) == 1 {
+		for _, k := range ks.keys {
+			return k, true
+		}
+	}
+
+	k, ok := ks.keys[kid]
+	return k, ok
+}
+
+// Key returns the key with the specified id. If kid is empty and the set has a single key, it is returned
+func (ks *KeySource) Key(kid string) (PublicKey, error) {
+	ks.RLock()
+	key, ok := ks.lookup(kid)
+	age := time.Since(ks.lastRefresh)
+	ks.RUnlock()
+
+	fresh := ks.refreshInterval <= 0 || age < ks.refreshInterval
+
+	if ok && fresh {
+		return key, nil
+	}
+
+	if !ok && age < minForcedRefresh {
+		return PublicKey{}, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	err := ks.Refresh()
+	if err != nil {
+		ks.log.Error("%s", err)
+		if ok {
+			// Keep using the previous keys if the JWKS is temporarily unavailable
+			return key, nil
+		}
+		return PublicKey{}, err
+	}
+
+	ks.RLock()
+	defer ks.RUnlock()
+
+	key, ok = ks.lookup(kid)
+	if !ok {
+		return PublicKey{}, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	return key, nil
+}
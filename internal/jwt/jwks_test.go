@@ -0,0 +1,133 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseKeySet(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+
+	jwks := makeJWKS(t,
+		rsaJWK("rsa", &rsaKey.PublicKey),
+		ecJWK("ec", &ecKey.PublicKey),
+		map[string]interface{}{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"},
+		map[string]interface{}{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AQAB", "e": "AQAB"},
+	)
+
+	keys, err := ParseKeySet(jwks)
+	if err != nil {
+		t.Fatalf("error parsing jwks: %v", err)
+	}
+
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 keys got %d", len(keys))
+	}
+
+	if k, ok := keys["rsa"].Key.(*rsa.PublicKey); !ok || k.N.Cmp(rsaKey.N) != 0 {
+		t.Errorf("expected rsa key to match")
+	}
+
+	if k, ok := keys["ec"].Key.(*ecdsa.PublicKey); !ok || k.X.Cmp(ecKey.X) != 0 {
+		t.Errorf("expected ec key to match")
+	}
+
+	if _, err := ParseKeySet([]byte(`{"keys":[{"kty":"EC","kid":"bad","crv":"P-256","x":"AQAB","y":"AQAB"}]}`)); err == nil {
+		t.Errorf("expected invalid ec point to fail")
+	}
+
+	if _, err := ParseKeySet([]byte(`not json`)); err == nil {
+		t.Errorf("expected invalid json to fail")
+	}
+}
+
+func TestKeySourceFile(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	dir, err := ioutil.TempDir("", "jwks")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := path.Join(dir, "jwks.json")
+	err = ioutil.WriteFile(file, makeJWKS(t, rsaJWK("", &rsaKey.PublicKey)), 0600)
+	if err != nil {
+		t.Fatalf("error writing jwks: %v", err)
+	}
+
+	ks := MakeKeySource(nil, file, time.Hour)
+	v := MakeVerifier(ks, "", "")
+
+	// Tokens without kid are accepted when the set has a single key
+	if _, err := v.Verify(signToken(t, "RS256", "", rsaKey, validClaims())); err != nil {
+		t.Fatalf("expected token to be valid: %v", err)
+	}
+}
+
+func TestKeySourceURL(t *testing.T) {
+	key1, _ := rsa.GenerateKey(rand.Reader, 2048)
+	key2, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	lock := sync.Mutex{}
+	requests := 0
+	jwks := makeJWKS(t, rsaJWK("key1", &key1.PublicKey))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(jwks)
+	}))
+	defer server.Close()
+
+	ks := MakeKeySource(nil, server.URL, time.Hour)
+	v := MakeVerifier(ks, "", "")
+
+	if _, err := v.Verify(signToken(t, "RS256", "key1", key1, validClaims())); err != nil {
+		t.Fatalf("expected token to be valid: %v", err)
+	}
+
+	if _, err := v.Verify(signToken(t, "RS256", "key1", key1, validClaims())); err != nil {
+		t.Fatalf("expected token to be valid: %v", err)
+	}
+
+	if requests != 1 {
+		t.Errorf("expected keys to be cached, got %d requests", requests)
+	}
+
+	// Rotate keys. Unknown kids refresh the set at most every minForcedRefresh
+	lock.Lock()
+	jwks = makeJWKS(t, rsaJWK("key1", &key1.PublicKey), ecJWK("key2", &key2.PublicKey))
+	lock.Unlock()
+
+	token := signToken(t, "ES256", "key2", key2, validClaims())
+
+	if _, err := v.Verify(token); err == nil {
+		t.Fatalf("expected unknown kid to fail before the forced refresh interval")
+	}
+
+	ks.Lock()
+	ks.lastRefresh = time.Now().Add(-minForcedRefresh)
+	ks.Unlock()
+
+	if _, err := v.Verify(token); err != nil {
+		t.Fatalf("expected rotated key to be loaded: %v", err)
+	}
+
+	if requests != 2 {
+		t.Errorf("expected 2 requests got %d", requests)
+	}
+}
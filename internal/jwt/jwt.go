@@ -0,0 +1,209 @@
+// Package jwt implements verification of JSON Web Tokens signed by asymmetric keys published in a JWKS
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	// Register hash functions used by the signature algorithms
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+// DefaultLeeway is the clock skew tolerance when validating time claims
+const DefaultLeeway = 30 * time.Second
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ"`
+}
+
+var algorithmHashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+	"PS256": crypto.SHA256,
+	"PS384": crypto.SHA384,
+	"PS512": crypto.SHA512,
+	"ES256": crypto.SHA256,
+	"ES384": crypto.SHA384,
+	"ES512": crypto.SHA512,
+}
+
+// KeyProvider returns the public key for a key id
+type KeyProvider interface {
+	Key(kid string) (PublicKey, error)
+}
+
+// Verifier validates the signature and the standard claims of JWTs
+type Verifier struct {
+	keys     KeyProvider
+	issuer   string
+	audience string
+	leeway   time.Duration
+	now      func() time.Time
+}
+
+// MakeVerifier creates a Verifier using the specified keys.
+// If issuer or audience are not empty, the iss and aud claims are required to match them
+func MakeVerifier(keys KeyProvider, issuer, audience string) *Verifier {
+	return &Verifier{
+		keys:     keys,
+		issuer:   issuer,
+		audience: audience,
+		leeway:   DefaultLeeway,
+		now:      time.Now,
+	}
+}
+
+// IsJWT returns true if the token has the format of a JWT
+func IsJWT(token string) bool {
+	return strings.Count(token, ".") == 2 && !strings.ContainsAny(token, " \t\n")
+}
+
+// Verify checks the token signature, expiration, issuer and audience and returns its claims
+func (v *Verifier) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+
+	var h header
+	err := decodeSegment(parts[0], &h)
+	if err != nil {
+		return nil, fmt.Errorf("invalid header: %v", err)
+	}
+
+	hash, ok := algorithmHashes[h.Alg]
+	if !ok {
+		return nil, fmt.Errorf("unsupported algorithm %q", h.Alg)
+	}
+
+	key, err := v.keys.Key(h.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.Algorithm != "" && key.Algorithm != h.Alg {
+		return nil, fmt.Errorf("algorithm %s does not match key algorithm %s", h.Alg, key.Algorithm)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature encoding: %v", err)
+	}
+
+	err = verifySignature(h.Alg, hash, key.Key, []byte(parts[0]+"."+parts[1]), signature)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	err = decodeSegment(parts[1], &claims)
+	if err != nil {
+		return nil, fmt.Errorf("invalid claims: %v", err)
+	}
+
+	err = v.validateClaims(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (v *Verifier) validateClaims(claims Claims) error {
+	now := v.now()
+
+	exp, ok := claims.Time("exp")
+	if !ok {
+		return fmt.Errorf("token has no expiration")
+	}
+
+	if now.After(exp.Add(v.leeway)) {
+		return fmt.Errorf("token expired")
+	}
+
+	if nbf, ok := claims.Time("nbf"); ok && now.Add(v.leeway).Before(nbf) {
+		return fmt.Errorf("token not valid yet")
+	}
+
+	if v.issuer != "" && claims.String("iss") != v.issuer {
+		return fmt.Errorf("invalid issuer")
+	}
+
+	if v.audience != "" {
+		found := false
+		for _, aud := range claims.Strings("aud") {
+			if aud == v.audience {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("invalid audience")
+		}
+	}
+
+	return nil
+}
+
+func verifySignature(alg string, hash crypto.Hash, key crypto.PublicKey, signed, signature []byte) error {
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch alg[:2] {
+	case "RS":
+		k, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("algorithm %s requires a RSA key", alg)
+		}
+		if rsa.VerifyPKCS1v15(k, hash, digest, signature) != nil {
+			return fmt.Errorf("invalid signature")
+		}
+	case "PS":
+		k, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("algorithm %s requires a RSA key", alg)
+		}
+		if rsa.VerifyPSS(k, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) != nil {
+			return fmt.Errorf("invalid signature")
+		}
+	case "ES":
+		k, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("algorithm %s requires an EC key", alg)
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return fmt.Errorf("invalid signature")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return fmt.Errorf("invalid signature")
+		}
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+
+	return nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
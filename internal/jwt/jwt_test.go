@@ -0,0 +1,230 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// keySetProvider adapts a parsed key set to KeyProvider
+type keySetProvider map[string]PublicKey
+
+func (p keySetProvider) Key(kid string) (PublicKey, error) {
+	ks := &KeySource{keys: p, lastRefresh: time.Now()}
+	return ks.Key(kid)
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) map[string]interface{} {
+	return map[string]interface{}{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"n":   b64(key.N.Bytes()),
+		"e":   b64(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) map[string]interface{} {
+	size := (key.Curve.Params().BitSize + 7) / 8
+	x := make([]byte, size)
+	y := make([]byte, size)
+	key.X.FillBytes(x)
+	key.Y.FillBytes(y)
+
+	return map[string]interface{}{
+		"kty": "EC",
+		"kid": kid,
+		"crv": key.Curve.Params().Name,
+		"x":   b64(x),
+		"y":   b64(y),
+	}
+}
+
+func makeJWKS(t *testing.T, keys ...map[string]interface{}) []byte {
+	data, err := json.Marshal(map[string]interface{}{"keys": keys})
+	if err != nil {
+		t.Fatalf("error encoding jwks: %v", err)
+	}
+	return data
+}
+
+func signToken(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]interface{}) string {
+	h, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	c, _ := json.Marshal(claims)
+	signed := b64(h) + "." + b64(c)
+
+	hash := algorithmHashes[alg]
+	hh := hash.New()
+	hh.Write([]byte(signed))
+	digest := hh.Sum(nil)
+
+	var signature []byte
+	var err error
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if strings.HasPrefix(alg, "PS") {
+			signature, err = rsa.SignPSS(rand.Reader, k, hash, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		} else {
+			signature, err = rsa.SignPKCS1v15(rand.Reader, k, hash, digest)
+		}
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, k, digest)
+		size := (k.Curve.Params().BitSize + 7) / 8
+		signature = make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		s.FillBytes(signature[size:])
+	}
+
+	if err != nil {
+		t.Fatalf("error signing token: %v", err)
+	}
+
+	return signed + "." + b64(signature)
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss":                "https://idp.example.com",
+		"aud":                []string{"chevron"},
+		"sub":                "1234",
+		"preferred_username": "johnhuebr",
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"iat":                time.Now().Unix(),
+	}
+}
+
+func TestVerify(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	keys, err := ParseKeySet(makeJWKS(t, rsaJWK("rsa", &rsaKey.PublicKey), ecJWK("ec", &ecKey.PublicKey)))
+	if err != nil {
+		t.Fatalf("error parsing jwks: %v", err)
+	}
+
+	v := MakeVerifier(keySetProvider(keys), "https://idp.example.com", "chevron")
+
+	for _, alg := range []string{"RS256", "RS512", "PS256"} {
+		claims, err := v.Verify(signToken(t, alg, "rsa", rsaKey, validClaims()))
+		if err != nil {
+			t.Fatalf("expected %s token to be valid: %v", alg, err)
+		}
+		if claims.String("preferred_username") != "johnhuebr" {
+			t.Errorf("expected username johnhuebr got %q", claims.String("preferred_username"))
+		}
+	}
+
+	if _, err := v.Verify(signToken(t, "ES256", "ec", ecKey, validClaims())); err != nil {
+		t.Fatalf("expected ES256 token to be valid: %v", err)
+	}
+
+	// Expired
+	claims := validClaims()
+	claims["exp"] = time.Now().Add(-time.Hour).Unix()
+	if _, err := v.Verify(signToken(t, "RS256", "rsa", rsaKey, claims)); err == nil {
+		t.Errorf("expected expired token to be invalid")
+	}
+
+	// No expiration
+	claims = validClaims()
+	delete(claims, "exp")
+	if _, err := v.Verify(signToken(t, "RS256", "rsa", rsaKey, claims)); err == nil {
+		t.Errorf("expected token without expiration to be invalid")
+	}
+
+	// Not valid yet
+	claims = validClaims()
+	claims["nbf"] = time.Now().Add(time.Hour).Unix()
+	if _, err := v.Verify(signToken(t, "RS256", "rsa", rsaKey, claims)); err == nil {
+		t.Errorf("expected token not valid yet to be invalid")
+	}
+
+	// Wrong issuer
+	claims = validClaims()
+	claims["iss"] = "https://evil.example.com"
+	if _, err := v.Verify(signToken(t, "RS256", "rsa", rsaKey, claims)); err == nil {
+		t.Errorf("expected token with wrong issuer to be invalid")
+	}
+
+	// Wrong audience
+	claims = validClaims()
+	claims["aud"] = "other"
+	if _, err := v.Verify(signToken(t, "RS256", "rsa", rsaKey, claims)); err == nil {
+		t.Errorf("expected token with wrong audience to be invalid")
+	}
+
+	// Key type mismatch
+	if _, err := v.Verify(signToken(t, "RS256", "ec", rsaKey, validClaims())); err == nil {
+		t.Errorf("expected token signed with the wrong key to be invalid")
+	}
+
+	// Unknown key
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	if _, err := v.Verify(signToken(t, "RS256", "other", otherKey, validClaims())); err == nil {
+		t.Errorf("expected token with unknown kid to be invalid")
+	}
+
+	// Tampered payload
+	token := signToken(t, "RS256", "rsa", rsaKey, validClaims())
+	parts := strings.Split(token, ".")
+	tampered := validClaims()
+	tampered["preferred_username"] = "admin"
+	c, _ := json.Marshal(tampered)
+	if _, err := v.Verify(parts[0] + "." + b64(c) + "." + parts[2]); err == nil {
+		t.Errorf("expected tampered token to be invalid")
+	}
+
+	// alg none
+	h, _ := json.Marshal(map[string]string{"alg": "none", "kid": "rsa"})
+	if _, err := v.Verify(b64(h) + "." + parts[1] + "."); err == nil {
+		t.Errorf("expected alg none to be rejected")
+	}
+}
+
+func TestIsJWT(t *testing.T) {
+	if !IsJWT("aaa.bbb.ccc") {
+		t.Errorf("expected aaa.bbb.ccc to be a jwt")
+	}
+
+	if IsJWT("f0d6e6a0-3b41-4b8f-8ac5-0a1fd1a4e8c2") {
+		t.Errorf("expected uuid to not be a jwt")
+	}
+}
+
+func TestClaims(t *testing.T) {
+	c := Claims{
+		"scope": "openid profile,email",
+		"roles": []interface{}{"admin", 1, "signer"},
+		"exp":   float64(1600000000),
+	}
+
+	if s := c.Strings("scope"); len(s) != 3 || s[2] != "email" {
+		t.Errorf("unexpected scope %v", s)
+	}
+
+	if r := c.Strings("roles"); len(r) != 2 || r[1] != "signer" {
+		t.Errorf("unexpected roles %v", r)
+	}
+
+	if exp, ok := c.Time("exp"); !ok || exp.Unix() != 1600000000 {
+		t.Errorf("unexpected exp %v", exp)
+	}
+
+	if c.String("missing") != "" {
+		t.Errorf("expected missing claim to be empty")
+	}
+}
@@ -19,8 +19,7 @@ import (
 
 func TestAddKey(t *testing.T) {
 	ctx := context.Background()
-	remote_signer.PushVariables()
-	defer remote_signer.PopVariables()
+	defer remote_signer.Apply(remote_signer.Current())
 	remote_signer.MaxKeyRingCache = 10
 	mem := memory.MakeMemoryDBDriver(nil)
 	krm := MakeKeyRingManager(nil, mem)
@@ -150,8 +149,7 @@ func TestAddKey(t *testing.T) {
 
 func TestGetKeyExternal(t *testing.T) {
 	ctx := context.Background()
-	remote_signer.PushVariables()
-	defer remote_signer.PopVariables()
+	defer remote_signer.Apply(remote_signer.Current())
 	// Test External SKS Fetch
 	remote_signer.EnableDatabase = false
 	remote_signer.SKSServer = "https://keyserver.ubuntu.com/"
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path"
 	"testing"
 
 	"github.com/bouk/monkey"
@@ -33,18 +34,22 @@ func TestMain(m *testing.M) {
 	slog.SetTestMode()
 	u, _ := uuid.NewRandom()
 	config.DatabaseName = "qrs_test_" + u.String()
-	config.PrivateKeyFolder = "../../test/data/"
+	keyFolder, err := test.MakeKeyFolder("../../test/data")
+	if err != nil {
+		slog.Fatal("Error creating the test key folder: %s", err)
+	}
+
+	config.PrivateKeyFolder = keyFolder
 	config.KeyPrefix = "testkey_"
 	config.KeysBase64Encoded = false
 
 	config.MasterGPGKeyBase64Encoded = false
-	config.MasterGPGKeyPath = "../../test/data/testkey_privateTestKey.gpg"
-	config.MasterGPGKeyPasswordPath = "../../test/data/testprivatekeyPassword.txt"
+	config.MasterGPGKeyPath = path.Join(keyFolder, test.TestKeyFile)
+	config.MasterGPGKeyPasswordPath = path.Join(keyFolder, test.TestKeyPasswordFile)
 
 	config.HttpPort = 40000
 	config.SKSServer = fmt.Sprintf("http://localhost:%d/sks/", config.HttpPort)
 	config.EnableDatabase = true
-	config.PushVariables()
 
 	var kb interfaces.StorageBackend
 
@@ -72,6 +77,7 @@ func TestMain(m *testing.M) {
 
 	code := m.Run()
 	slog.UnsetTestMode()
+	_ = os.RemoveAll(keyFolder)
 	os.Exit(code)
 }
 
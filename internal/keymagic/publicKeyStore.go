@@ -18,6 +18,9 @@ type DatabaseHandler interface {
 	FindGPGKeyByValue(value string, pageStart, pageEnd int) ([]models.GPGKey, error)
 	FindGPGKeyByName(name string, pageStart, pageEnd int) ([]models.GPGKey, error)
 	FetchGPGKeyByFingerprint(fingerprint string) (*models.GPGKey, error)
+	RestoreGPGKey(key models.GPGKey) error
+	FetchGPGKeyHistory(fingerprint string) ([]models.GPGKeyHistory, error)
+	SearchGPGKeys(query models.GPGKeySearchQuery) (*models.GPGKeySearchResult, error)
 }
 
 var pksLog = slog.Scope("PKS")
@@ -90,6 +93,49 @@ func PKSSearch(ctx context.Context, value string, pageStart, pageEnd int) ([]mod
 	return nil, fmt.Errorf("the server does not have database enabled so it cannot serve search")
 }
 
+// PKSSearchKeys searches the public key store with prefix, fuzzy and domain matching, filters and cursor pagination
+func PKSSearchKeys(ctx context.Context, query models.GPGKeySearchQuery) (*models.GPGKeySearchResult, error) {
+	requestID := tools.GetRequestIDFromContext(ctx)
+	log := pksLog.Tag(requestID)
+	log.DebugNote("PKSSearchKeys(%q)", query.Query)
+	dbh := dbHandlerFromContext(ctx)
+	if dbh != nil {
+		return dbh.SearchGPGKeys(query)
+	}
+
+	return nil, fmt.Errorf("the server does not have database enabled so it cannot search keys")
+}
+
+// PKSGetKeyHistory returns the change history of the key with the specified fingerprint
+func PKSGetKeyHistory(ctx context.Context, fingerPrint string) ([]models.GPGKeyHistory, error) {
+	requestID := tools.GetRequestIDFromContext(ctx)
+	log := pksLog.Tag(requestID)
+	log.DebugNote("PKSGetKeyHistory(%q)", fingerPrint)
+	dbh := dbHandlerFromContext(ctx)
+	if dbh != nil {
+		return dbh.FetchGPGKeyHistory(fingerPrint)
+	}
+
+	return nil, fmt.Errorf("the server does not have database enabled so it cannot serve key history")
+}
+
+// PKSRestoreKey restores a previously deleted key with the specified fingerprint
+func PKSRestoreKey(ctx context.Context, fingerPrint string) error {
+	requestID := tools.GetRequestIDFromContext(ctx)
+	log := pksLog.Tag(requestID)
+	log.DebugNote("PKSRestoreKey(%q)", fingerPrint)
+	dbh := dbHandlerFromContext(ctx)
+	if dbh == nil {
+		return fmt.Errorf("the server does not have database enabled so it cannot restore keys")
+	}
+
+	log.Info("Restoring public key %s on PKS", tools.FPto16(fingerPrint))
+	return dbh.RestoreGPGKey(models.GPGKey{
+		FullFingerprint: fingerPrint,
+		Actor:           tools.GetActorFromContext(ctx),
+	})
+}
+
 func PKSAdd(ctx context.Context, pubKey string) string {
 	requestID := tools.GetRequestIDFromContext(ctx)
 	log := pksLog.Tag(requestID)
@@ -101,6 +147,7 @@ func PKSAdd(ctx context.Context, pubKey string) string {
 			log.Debug("PKSAdd Error: %s", err)
 			return "NOK"
 		}
+		key.Actor = tools.GetActorFromContext(ctx)
 
 		existingKey, err := dbh.FetchGPGKeyByFingerprint(key.FullFingerprint)
 
@@ -109,12 +156,16 @@ func PKSAdd(ctx context.Context, pubKey string) string {
 			return "NOK"
 		}
 
-		if existingKey != nil {
+		if existingKey != nil && existingKey.AsciiArmoredPublicKey == key.AsciiArmoredPublicKey {
 			log.Info("Tried to add key %s to PKS but already exists.", key.GetShortFingerPrint())
 			return "OK"
 		}
 
-		log.Info("Adding public key %s to PKS", key.GetShortFingerPrint())
+		if existingKey != nil {
+			log.Info("Updating public key %s on PKS", key.GetShortFingerPrint())
+		} else {
+			log.Info("Adding public key %s to PKS", key.GetShortFingerPrint())
+		}
 		_, _, err = dbh.AddGPGKey(key)
 
 		if err != nil {
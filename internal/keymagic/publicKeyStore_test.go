@@ -15,8 +15,7 @@ import (
 )
 
 func TestPKSGetKey(t *testing.T) {
-	config.PushVariables()
-	defer config.PopVariables()
+	defer config.Apply(config.Current())
 	config.DatabaseDialect = "memory"
 
 	// Test Internal
@@ -78,8 +77,7 @@ func TestPKSGetKey(t *testing.T) {
 }
 
 func TestPKSSearchByName(t *testing.T) {
-	config.PushVariables()
-	defer config.PopVariables()
+	defer config.Apply(config.Current())
 
 	// Test Panics
 	config.EnableDatabase = false
@@ -90,8 +88,7 @@ func TestPKSSearchByName(t *testing.T) {
 }
 
 func TestPKSSearchByFingerPrint(t *testing.T) {
-	config.PushVariables()
-	defer config.PopVariables()
+	defer config.Apply(config.Current())
 
 	// Test Panics
 	_, err := PKSSearchByFingerPrint(context.Background(), "", 0, 1)
@@ -101,8 +98,7 @@ func TestPKSSearchByFingerPrint(t *testing.T) {
 }
 
 func TestPKSSearchByEmail(t *testing.T) {
-	config.PushVariables()
-	defer config.PopVariables()
+	defer config.Apply(config.Current())
 
 	// Test Panics
 	_, err := PKSSearchByEmail(context.Background(), "", 0, 1)
@@ -120,8 +116,7 @@ func TestPKSSearch(t *testing.T) {
 }
 
 func TestPKSAdd(t *testing.T) {
-	config.PushVariables()
-	defer config.PopVariables()
+	defer config.Apply(config.Current())
 	dbh, err := agent.MakeDatabaseHandler(slog.Scope("TEST"))
 	if err != nil {
 		t.Error(err)
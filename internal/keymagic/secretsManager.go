@@ -4,6 +4,7 @@ package keymagic
 
 import (
 	"context"
+	"crypto"
 	"encoding/base64"
 	"fmt"
 	"io/ioutil"
@@ -20,10 +21,42 @@ import (
 	"github.com/quan-to/slog"
 )
 
+const (
+	// passwordKeyPrefix is the prefix of the master key encrypted passwords in the master key backend
+	passwordKeyPrefix = "password-"
+	// forgottenKeyPrefix is the prefix of the forgotten passwords in the master key backend
+	// A forgotten password is not stored again when received from the cluster peers
+	forgottenKeyPrefix = "forgotten-"
+)
+
+// masterKeysBackend hides the stored passwords from the master key PGP manager
+type masterKeysBackend struct {
+	interfaces.StorageBackend
+}
+
+// List lists the stored keys without the stored passwords
+func (b masterKeysBackend) List() ([]string, error) {
+	keys, err := b.StorageBackend.List()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if !strings.HasPrefix(key, passwordKeyPrefix) && !strings.HasPrefix(key, forgottenKeyPrefix) {
+			res = append(res, key)
+		}
+	}
+
+	return res, nil
+}
+
 type secretsManager struct {
 	sync.Mutex
 	encryptedPasswords   map[string]string
+	forgottenPasswords   map[string]bool
 	gpg                  interfaces.PGPManager
+	kb                   interfaces.StorageBackend
 	masterKeyFingerPrint string
 	amIUseless           bool
 	log                  slog.Instance
@@ -51,6 +84,8 @@ func MakeSecretsManager(log slog.Instance, dbHandler DatabaseHandler) interfaces
 	var sm = &secretsManager{
 		amIUseless:         false,
 		encryptedPasswords: map[string]string{},
+		forgottenPasswords: map[string]bool{},
+		kb:                 kb,
 		log:                log,
 		dbh:                dbHandler,
 	}
@@ -86,7 +121,7 @@ func MakeSecretsManager(log slog.Instance, dbHandler DatabaseHandler) interfaces
 
 	sm.masterKeyFingerPrint = masterKeyFp
 
-	sm.gpg = MakePGPManager(log, kb, MakeKeyRingManager(log, dbHandler))
+	sm.gpg = MakePGPManager(log, masterKeysBackend{kb}, MakeKeyRingManager(log, dbHandler))
 	sm.gpg.SetKeysBase64Encoded(config.MasterGPGKeyBase64Encoded)
 
 	n, err := sm.gpg.LoadKey(ctx, string(masterKeyBytes))
@@ -126,9 +161,111 @@ func MakeSecretsManager(log slog.Instance, dbHandler DatabaseHandler) interfaces
 		sm.log.Fatal("Error saving master key to default backend: %s", err)
 	}
 
+	sm.loadStoredPasswords()
+
 	return sm
 }
 
+// loadStoredPasswords loads the master key encrypted passwords stored in the master key backend
+func (sm *secretsManager) loadStoredPasswords() {
+	keys, err := sm.kb.List()
+	if err != nil {
+		sm.log.Error("Error listing stored passwords: %s", err)
+		return
+	}
+
+	sm.Lock()
+	defer sm.Unlock()
+
+	for _, key := range keys {
+		if fingerprint := strings.TrimPrefix(key, forgottenKeyPrefix); fingerprint != key {
+			if tools.IsFingerPrint(fingerprint) {
+				sm.forgottenPasswords[fingerprint] = true
+			}
+			continue
+		}
+
+		fingerprint := strings.TrimPrefix(key, passwordKeyPrefix)
+		if fingerprint == key || !tools.IsFingerPrint(fingerprint) {
+			continue
+		}
+
+		encPass, _, err := sm.kb.Read(key)
+		if err != nil {
+			sm.log.Error("Error reading stored password %s: %s", key, err)
+			continue
+		}
+
+		sm.encryptedPasswords[fingerprint] = encPass
+	}
+
+	sm.log.Info("Loaded %d stored key passwords", len(sm.encryptedPasswords))
+}
+
+// isForgotten returns true if the password of the specified fingerprint was forgotten. Must be called with the lock held
+func (sm *secretsManager) isForgotten(fingerprint string) bool {
+	for fp := range sm.forgottenPasswords {
+		if tools.CompareFingerPrint(fp, fingerprint) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// markForgotten marks the password of the specified fingerprint as forgotten. Must be called with the lock held
+func (sm *secretsManager) markForgotten(fingerprint string) error {
+	sm.forgottenPasswords[fingerprint] = true
+
+	if sm.kb == nil {
+		return nil
+	}
+
+	return sm.kb.Save(forgottenKeyPrefix+fingerprint, fingerprint)
+}
+
+// clearForgotten removes the forgotten mark of the specified fingerprint. Must be called with the lock held
+func (sm *secretsManager) clearForgotten(fingerprint string) error {
+	for fp := range sm.forgottenPasswords {
+		if !tools.CompareFingerPrint(fp, fingerprint) {
+			continue
+		}
+
+		delete(sm.forgottenPasswords, fp)
+
+		if sm.kb != nil {
+			if err := sm.kb.Delete(forgottenKeyPrefix + fp); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// storePassword saves the master key encrypted password in the master key backend. Must be called with the lock held
+func (sm *secretsManager) storePassword(fingerprint, encryptedPassword string) {
+	if !tools.IsFingerPrint(fingerprint) {
+		sm.log.Error("Not storing password for invalid fingerprint %q", fingerprint)
+		return
+	}
+
+	if sm.encryptedPasswords[fingerprint] == encryptedPassword {
+		return
+	}
+
+	sm.encryptedPasswords[fingerprint] = encryptedPassword
+
+	if sm.kb == nil {
+		return
+	}
+
+	err := sm.kb.Save(passwordKeyPrefix+fingerprint, encryptedPassword)
+	if err != nil {
+		sm.log.Error("Error storing password for key %s: %s", fingerprint, err)
+	}
+}
+
 // PutKeyPassword stores the password for the specified key fingerprint in the key backend encrypted with the master key
 func (sm *secretsManager) PutKeyPassword(ctx context.Context, fingerprint, password string) {
 	requestID := tools.GetRequestIDFromContext(ctx)
@@ -142,8 +279,18 @@ func (sm *secretsManager) PutKeyPassword(ctx context.Context, fingerprint, passw
 	sm.Lock()
 	defer sm.Unlock()
 
+	if !tools.IsFingerPrint(fingerprint) {
+		sm.log.Error("Not saving password for invalid fingerprint %q", fingerprint)
+		return
+	}
+
 	sm.log.Info("Saving password for key %s", fingerprint)
 
+	// The key was unlocked explicitly, so the password can be shared again
+	if err := sm.clearForgotten(fingerprint); err != nil {
+		sm.log.Error("Error removing forgotten password mark of key %s: %s", fingerprint, err)
+	}
+
 	filename := fmt.Sprintf("key-password-utf8-%s.txt", fingerprint)
 
 	encPass, err := sm.gpg.Encrypt(ctx, filename, sm.masterKeyFingerPrint, []byte(password), config.SMEncryptedDataOnly)
@@ -153,10 +300,11 @@ func (sm *secretsManager) PutKeyPassword(ctx context.Context, fingerprint, passw
 		return
 	}
 
-	sm.encryptedPasswords[fingerprint] = encPass
+	sm.storePassword(fingerprint, encPass)
 }
 
 // PutEncryptedPassword stores in memory a master key encrypted password for the specified fingerprint
+// Passwords forgotten with DeleteKeyPassword are not stored again
 func (sm *secretsManager) PutEncryptedPassword(ctx context.Context, fingerprint, encryptedPassword string) {
 	requestID := tools.GetRequestIDFromContext(ctx)
 	log := pksLog.Tag(requestID)
@@ -168,7 +316,51 @@ func (sm *secretsManager) PutEncryptedPassword(ctx context.Context, fingerprint,
 	sm.Lock()
 	defer sm.Unlock()
 
-	sm.encryptedPasswords[fingerprint] = encryptedPassword
+	if sm.isForgotten(fingerprint) {
+		log.Warn("Not saving password for key %s. The password was forgotten", fingerprint)
+		return
+	}
+
+	sm.storePassword(fingerprint, encryptedPassword)
+}
+
+// DeleteKeyPassword removes the stored password of the specified key fingerprint from memory and from the key backend
+// The password is marked as forgotten, so it is not stored again when received from the cluster peers
+func (sm *secretsManager) DeleteKeyPassword(ctx context.Context, fingerprint string) error {
+	requestID := tools.GetRequestIDFromContext(ctx)
+	log := pksLog.Tag(requestID)
+	log.DebugNote("DeleteKeyPassword(%s)", fingerprint)
+
+	sm.Lock()
+	defer sm.Unlock()
+
+	found := ""
+	for fp := range sm.encryptedPasswords {
+		if tools.CompareFingerPrint(fp, fingerprint) {
+			found = fp
+			break
+		}
+	}
+
+	if found == "" {
+		return fmt.Errorf("there is no stored password for key %s", fingerprint)
+	}
+
+	delete(sm.encryptedPasswords, found)
+
+	if err := sm.markForgotten(found); err != nil {
+		return fmt.Errorf("error forgetting password for key %s: %v", found, err)
+	}
+
+	if sm.kb != nil {
+		if err := sm.kb.Delete(passwordKeyPrefix + found); err != nil {
+			return fmt.Errorf("error deleting stored password for key %s: %v", found, err)
+		}
+	}
+
+	log.Info("Forgot password for key %s", found)
+
+	return nil
 }
 
 // GetPasswords returns a list of master key encrypted passwords stored in memory
@@ -232,3 +424,45 @@ func (sm *secretsManager) GetMasterKeyFingerPrint(ctx context.Context) string {
 	log.DebugNote("GetMasterKeyFingerPrint()")
 	return sm.masterKeyFingerPrint
 }
+
+// SignWithMasterKey signs the data with the master key and returns the armored signature
+func (sm *secretsManager) SignWithMasterKey(ctx context.Context, data []byte) (string, error) {
+	requestID := tools.GetRequestIDFromContext(ctx)
+	log := pksLog.Tag(requestID)
+	log.DebugNote("SignWithMasterKey(---)")
+	if sm.amIUseless {
+		return "", fmt.Errorf("master key not loaded")
+	}
+
+	return sm.gpg.SignData(ctx, sm.masterKeyFingerPrint, data, crypto.SHA512)
+}
+
+// VerifyMasterKeySignature returns an error if the armored signature of the data was not made by the master key
+func (sm *secretsManager) VerifyMasterKeySignature(ctx context.Context, data []byte, signature string) error {
+	requestID := tools.GetRequestIDFromContext(ctx)
+	log := pksLog.Tag(requestID)
+	log.DebugNote("VerifyMasterKeySignature(---, ---)")
+	if sm.amIUseless {
+		return fmt.Errorf("master key not loaded")
+	}
+
+	issuer, err := tools.GetFingerPrintFromSignature(signature)
+	if err != nil {
+		return err
+	}
+
+	if !tools.CompareFingerPrint(issuer, sm.masterKeyFingerPrint) {
+		return fmt.Errorf("signature made by %s is not from the master key", issuer)
+	}
+
+	valid, err := sm.gpg.VerifySignature(ctx, data, signature)
+	if err != nil {
+		return err
+	}
+
+	if !valid {
+		return fmt.Errorf("invalid master key signature")
+	}
+
+	return nil
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/quan-to/chevron/internal/config"
+	"github.com/quan-to/chevron/internal/tools"
+	"github.com/quan-to/chevron/pkg/database/memory"
 	"github.com/quan-to/chevron/test"
 )
 
@@ -90,3 +93,108 @@ func TestUnlockLocalKeys(t *testing.T) {
 
 	sm.UnlockLocalKeys(ctx, pgpMan)
 }
+
+func TestMasterKeySignature(t *testing.T) {
+	ctx := context.Background()
+	data := []byte("cluster challenge")
+
+	signature, err := sm.SignWithMasterKey(ctx, data)
+	if err != nil {
+		t.Fatalf("error signing with master key: %s", err)
+	}
+
+	if err := sm.VerifyMasterKeySignature(ctx, data, signature); err != nil {
+		t.Errorf("expected master key signature to be valid: %s", err)
+	}
+
+	if err := sm.VerifyMasterKeySignature(ctx, []byte("other data"), signature); err == nil {
+		t.Errorf("expected signature of other data to be invalid")
+	}
+
+	if err := sm.VerifyMasterKeySignature(ctx, data, "invalid"); err == nil {
+		t.Errorf("expected invalid signature to fail")
+	}
+}
+
+func TestStoredPasswords(t *testing.T) {
+	ctx := context.Background()
+	mem := memory.MakeMemoryDBDriver(nil)
+
+	sm.PutKeyPassword(ctx, test.TestKeyFingerprint, test.TestKeyPassword)
+
+	// region Passwords are reloaded by new instances
+	sm2 := MakeSecretsManager(nil, mem).(*secretsManager)
+	if sm2.GetPasswords(ctx)[test.TestKeyFingerprint] != sm.GetPasswords(ctx)[test.TestKeyFingerprint] {
+		t.Fatalf("expected stored password to be loaded by a new secrets manager")
+	}
+
+	keys, err := sm2.gpg.(*pgpManager).kbkend.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range keys {
+		if strings.HasPrefix(key, passwordKeyPrefix) {
+			t.Errorf("expected stored passwords to be hidden from the master key manager")
+		}
+	}
+
+	locked := MakePGPManager(nil, pgpMan.kbkend, MakeKeyRingManager(nil, mem))
+	locked.LoadKeys(ctx)
+	if !locked.IsKeyLocked(test.TestKeyFingerprint) {
+		t.Fatalf("expected key to be locked")
+	}
+
+	sm2.UnlockLocalKeys(ctx, locked)
+	if locked.IsKeyLocked(test.TestKeyFingerprint) {
+		t.Errorf("expected key to be unlocked with the stored password")
+	}
+	// endregion
+	// region Invalid fingerprints are not stored
+	sm2.PutEncryptedPassword(ctx, "../../../tmp/evil", sm2.GetPasswords(ctx)[test.TestKeyFingerprint])
+	if _, ok := sm2.GetPasswords(ctx)["../../../tmp/evil"]; ok {
+		t.Errorf("expected password with invalid fingerprint to not be stored")
+	}
+	// endregion
+	// region Forget password
+	encPass := sm2.GetPasswords(ctx)[test.TestKeyFingerprint]
+	if err := sm2.DeleteKeyPassword(ctx, tools.FPto16(test.TestKeyFingerprint)); err != nil {
+		t.Fatalf("error deleting password: %s", err)
+	}
+
+	if _, ok := sm2.GetPasswords(ctx)[test.TestKeyFingerprint]; ok {
+		t.Errorf("expected password to be removed from memory")
+	}
+
+	if err := sm2.DeleteKeyPassword(ctx, test.TestKeyFingerprint); err == nil {
+		t.Errorf("expected error deleting a password that is not stored")
+	}
+
+	sm2.PutEncryptedPassword(ctx, test.TestKeyFingerprint, encPass)
+	if _, ok := sm2.GetPasswords(ctx)[test.TestKeyFingerprint]; ok {
+		t.Errorf("expected forgotten password to not be stored again from the cluster peers")
+	}
+
+	sm3 := MakeSecretsManager(nil, mem).(*secretsManager)
+	if _, ok := sm3.GetPasswords(ctx)[test.TestKeyFingerprint]; ok {
+		t.Errorf("expected deleted password to not be loaded by new instances")
+	}
+
+	sm3.PutEncryptedPassword(ctx, test.TestKeyFingerprint, encPass)
+	if _, ok := sm3.GetPasswords(ctx)[test.TestKeyFingerprint]; ok {
+		t.Errorf("expected forgotten password to not be stored again by new instances")
+	}
+	// endregion
+	// region Unlocking the key again stores the password
+	sm3.PutKeyPassword(ctx, test.TestKeyFingerprint, test.TestKeyPassword)
+	sm3.PutEncryptedPassword(ctx, test.TestKeyFingerprint, encPass)
+	if sm3.GetPasswords(ctx)[test.TestKeyFingerprint] != encPass {
+		t.Errorf("expected password to be stored again after unlocking the key")
+	}
+
+	sm4 := MakeSecretsManager(nil, mem).(*secretsManager)
+	if sm4.GetPasswords(ctx)[test.TestKeyFingerprint] != encPass {
+		t.Errorf("expected password stored after unlocking the key to be loaded by new instances")
+	}
+	// endregion
+}
@@ -2,6 +2,7 @@ package keymagic
 
 import (
 	"context"
+	"crypto"
 	"encoding/base64"
 	"fmt"
 	"io/ioutil"
@@ -142,6 +143,11 @@ func (sm *secretsManager) PutEncryptedPassword(ctx context.Context, fingerPrint,
 		smLog.Warn("Not saving password. Master Key not loaded")
 	}
 
+	if !tools.IsFingerPrint(fingerPrint) {
+		smLog.Error("Not saving password for invalid fingerprint %q", fingerPrint)
+		return
+	}
+
 	sm.Lock()
 	defer sm.Unlock()
 
@@ -196,7 +202,58 @@ func (sm *secretsManager) UnlockLocalKeys(ctx context.Context, gpg interfaces.PG
 	}
 }
 
+// DeleteKeyPassword removes the stored password of the specified key fingerprint from memory
+func (sm *secretsManager) DeleteKeyPassword(ctx context.Context, fingerPrint string) error {
+	sm.Lock()
+	defer sm.Unlock()
+
+	for fp := range sm.encryptedPasswords {
+		if tools.CompareFingerPrint(fp, fingerPrint) {
+			delete(sm.encryptedPasswords, fp)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("there is no stored password for key %s", fingerPrint)
+}
+
 // GetMasterKeyFingerPrint returns the fingerprint of the master key
 func (sm *secretsManager) GetMasterKeyFingerPrint(ctx context.Context) string {
 	return sm.masterKeyFingerPrint
 }
+
+// SignWithMasterKey signs the data with the master key and returns the armored signature
+func (sm *secretsManager) SignWithMasterKey(ctx context.Context, data []byte) (string, error) {
+	if sm.amIUseless {
+		return "", fmt.Errorf("master key not loaded")
+	}
+
+	return sm.gpg.SignData(ctx, sm.masterKeyFingerPrint, data, crypto.SHA512)
+}
+
+// VerifyMasterKeySignature returns an error if the armored signature of the data was not made by the master key
+func (sm *secretsManager) VerifyMasterKeySignature(ctx context.Context, data []byte, signature string) error {
+	if sm.amIUseless {
+		return fmt.Errorf("master key not loaded")
+	}
+
+	issuer, err := tools.GetFingerPrintFromSignature(signature)
+	if err != nil {
+		return err
+	}
+
+	if !tools.CompareFingerPrint(issuer, sm.masterKeyFingerPrint) {
+		return fmt.Errorf("signature made by %s is not from the master key", issuer)
+	}
+
+	valid, err := sm.gpg.VerifySignature(ctx, data, signature)
+	if err != nil {
+		return err
+	}
+
+	if !valid {
+		return fmt.Errorf("invalid master key signature")
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/quan-to/chevron/internal/config"
+)
+
+// Discovery finds the other running pods of the current namespace
+type Discovery struct{}
+
+// MakeDiscovery creates a peer discovery using the kubernetes pod list API
+func MakeDiscovery() *Discovery {
+	return &Discovery{}
+}
+
+// Name returns the name of the discovery method
+func (d *Discovery) Name() string {
+	return "kubernetes"
+}
+
+// Peers returns the internal endpoint URL of the other running pods
+func (d *Discovery) Peers(ctx context.Context) ([]string, error) {
+	if !inKubernetes {
+		return nil, fmt.Errorf("not running in kubernetes")
+	}
+
+	myId := Me().Metadata.UID
+	peers := make([]string, 0)
+
+	for _, pod := range Pods() {
+		if pod.Metadata.UID == myId {
+			continue
+		}
+		if pod.Status.Phase != Running {
+			continue
+		}
+
+		peers = append(peers, fmt.Sprintf("http://%s:%d/remoteSigner/__internal", pod.Status.PodIP, config.HttpPort))
+	}
+
+	return peers, nil
+}
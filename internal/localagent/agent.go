@@ -0,0 +1,310 @@
+package localagent
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quan-to/chevron/internal/tools"
+	"github.com/quan-to/chevron/pkg/client"
+	"github.com/quan-to/chevron/pkg/models"
+	"github.com/quan-to/slog"
+)
+
+// requestTimeout is the maximum time to read a request, call the server and answer
+const requestTimeout = 2 * time.Minute
+
+// Agent forwards the requests received in a Unix socket to a Chevron server
+type Agent struct {
+	log        slog.Instance
+	api        *client.Client
+	defaultKey string
+
+	listener   net.Listener
+	socketPath string
+	done       chan struct{}
+	closeOnce  sync.Once
+	wg         sync.WaitGroup
+
+	fingerPrintsLock sync.Mutex
+	fingerPrints     map[string]string
+}
+
+// MakeAgent creates an agent that calls the server with api. The defaultKey is used when the request does not specify one
+func MakeAgent(log slog.Instance, api *client.Client, defaultKey string) *Agent {
+	if log == nil {
+		log = slog.Scope("Agent")
+	} else {
+		log = log.SubScope("Agent")
+	}
+
+	return &Agent{
+		log:          log,
+		api:          api,
+		defaultKey:   defaultKey,
+		done:         make(chan struct{}),
+		fingerPrints: map[string]string{},
+	}
+}
+
+// Listen creates the Unix socket. The socket is only accessible by the current user.
+// A socket left by an agent that is not running anymore is replaced
+func (a *Agent) Listen(socketPath string) error {
+	if err := os.MkdirAll(path.Dir(socketPath), 0700); err != nil {
+		return fmt.Errorf("error creating socket folder: %v", err)
+	}
+
+	if _, err := os.Stat(socketPath); err == nil {
+		conn, err := net.Dial("unix", socketPath)
+		if err == nil {
+			_ = conn.Close()
+			return fmt.Errorf("there is an agent already listening at %s", socketPath)
+		}
+
+		if err := os.Remove(socketPath); err != nil {
+			return fmt.Errorf("error removing stale socket %s: %v", socketPath, err)
+		}
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Chmod(socketPath, 0600); err != nil {
+		_ = listener.Close()
+		return fmt.Errorf("error setting socket permissions: %v", err)
+	}
+
+	a.listener = listener
+	a.socketPath = socketPath
+	a.log.Info("Listening at %s", socketPath)
+
+	return nil
+}
+
+// Serve accepts connections until Close is called
+func (a *Agent) Serve() error {
+	if a.listener == nil {
+		return fmt.Errorf("agent is not listening")
+	}
+
+	for {
+		conn, err := a.listener.Accept()
+		if err != nil {
+			select {
+			case <-a.done:
+				return nil
+			default:
+			}
+
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				a.log.Warn("Error accepting connection: %s", err)
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+
+			return err
+		}
+
+		a.wg.Add(1)
+		go func() {
+			defer a.wg.Done()
+			a.handle(conn)
+		}()
+	}
+}
+
+// Close stops listening, waits for the running requests and removes the socket
+func (a *Agent) Close() error {
+	var err error
+
+	a.closeOnce.Do(func() {
+		close(a.done)
+		if a.listener != nil {
+			err = a.listener.Close()
+			a.wg.Wait()
+			_ = os.Remove(a.socketPath)
+		}
+	})
+
+	return err
+}
+
+func (a *Agent) handle(conn net.Conn) {
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	_ = conn.SetDeadline(time.Now().Add(requestTimeout))
+
+	var req Request
+	var res Response
+
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		res = Response{Error: fmt.Sprintf("invalid request: %v", err)}
+	} else {
+		res = a.process(ctx, req)
+	}
+
+	if res.Error != "" {
+		a.log.Error("Error processing %s request: %s", req.Operation, res.Error)
+	}
+
+	if err := json.NewEncoder(conn).Encode(res); err != nil {
+		a.log.Error("Error sending response: %s", err)
+	}
+}
+
+func (a *Agent) process(ctx context.Context, req Request) Response {
+	switch req.Operation {
+	case OperationSign:
+		return a.sign(ctx, req.Key, req.Data)
+	case OperationDecrypt:
+		return a.decrypt(ctx, req.Data)
+	}
+
+	return Response{Error: fmt.Sprintf("unknown operation %q", req.Operation)}
+}
+
+func (a *Agent) sign(ctx context.Context, key string, data []byte) Response {
+	fingerPrint, err := a.resolveKey(ctx, key)
+	if err != nil {
+		return Response{Error: err.Error()}
+	}
+
+	a.log.Info("Signing %d bytes with %s", len(data), fingerPrint)
+
+	signature, err := a.api.Sign(ctx, models.GPGSignData{
+		FingerPrint: fingerPrint,
+		Base64Data:  base64.StdEncoding.EncodeToString(data),
+	})
+
+	if err != nil {
+		return Response{Error: err.Error()}
+	}
+
+	return Response{
+		Signature:   signature,
+		FingerPrint: a.fullFingerPrint(ctx, fingerPrint),
+	}
+}
+
+func (a *Agent) decrypt(ctx context.Context, data []byte) Response {
+	req := models.GPGDecryptData{
+		AsciiArmoredData: string(data),
+	}
+
+	if !isArmored(data) {
+		req.AsciiArmoredData = base64.StdEncoding.EncodeToString(data)
+		req.DataOnly = true
+	}
+
+	a.log.Info("Decrypting %d bytes", len(data))
+
+	decrypted, err := a.api.Decrypt(ctx, req)
+	if err != nil {
+		return Response{Error: err.Error()}
+	}
+
+	plain, err := base64.StdEncoding.DecodeString(decrypted.Base64Data)
+	if err != nil {
+		return Response{Error: fmt.Sprintf("invalid data from server: %v", err)}
+	}
+
+	return Response{
+		FingerPrint: decrypted.FingerPrint,
+		Data:        plain,
+	}
+}
+
+// resolveKey returns the fingerprint for the key. Keys that are not fingerprints are matched
+// against the identifiers of the private keys loaded in the server
+func (a *Agent) resolveKey(ctx context.Context, key string) (string, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		key = a.defaultKey
+	}
+
+	if key == "" {
+		return "", fmt.Errorf("no key specified and the agent does not have a default key")
+	}
+
+	hexKey := strings.ToUpper(strings.TrimPrefix(strings.TrimPrefix(key, "0x"), "0X"))
+	if _, err := hex.DecodeString(hexKey); err == nil && (len(hexKey) == 16 || len(hexKey) == 40) {
+		return tools.FPto16(hexKey), nil
+	}
+
+	keys, err := a.api.PrivateKeys(ctx)
+	if err != nil {
+		return "", fmt.Errorf("error listing server keys: %v", err)
+	}
+
+	search := strings.ToLower(key)
+	if start, end := strings.LastIndex(search, "<"), strings.LastIndex(search, ">"); start != -1 && end > start {
+		search = search[start+1 : end]
+	}
+
+	for _, k := range keys {
+		// Short key ids
+		if len(hexKey) == 8 && strings.HasSuffix(k.FingerPrint, hexKey) {
+			return k.FingerPrint, nil
+		}
+
+		if strings.Contains(strings.ToLower(k.Identifier), search) {
+			return k.FingerPrint, nil
+		}
+	}
+
+	return "", fmt.Errorf("no private key in the server matches %q", key)
+}
+
+// fullFingerPrint returns the full fingerprint of the key or fingerPrint if the public key is not available
+func (a *Agent) fullFingerPrint(ctx context.Context, fingerPrint string) string {
+	a.fingerPrintsLock.Lock()
+	defer a.fingerPrintsLock.Unlock()
+
+	if full, ok := a.fingerPrints[fingerPrint]; ok {
+		return full
+	}
+
+	publicKey, err := a.api.KeyRingGetKey(ctx, fingerPrint)
+	if err != nil {
+		return fingerPrint
+	}
+
+	entity, err := tools.ReadKeyToEntity(publicKey)
+	if err != nil {
+		return fingerPrint
+	}
+
+	full := fingerPrint
+	if tools.ByteFingerPrint2FP16(entity.PrimaryKey.Fingerprint[:]) == fingerPrint {
+		full = fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint[:])
+	}
+
+	for _, sub := range entity.Subkeys {
+		if tools.ByteFingerPrint2FP16(sub.PublicKey.Fingerprint[:]) == fingerPrint {
+			full = fmt.Sprintf("%X", sub.PublicKey.Fingerprint[:])
+		}
+	}
+
+	a.fingerPrints[fingerPrint] = full
+
+	return full
+}
+
+// isArmored returns if data is an ASCII armored block
+func isArmored(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN PGP"))
+}
@@ -0,0 +1,231 @@
+package localagent
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quan-to/chevron/internal/agent"
+	"github.com/quan-to/chevron/internal/config"
+	"github.com/quan-to/chevron/internal/etc/magicbuilder"
+	"github.com/quan-to/chevron/internal/server"
+	"github.com/quan-to/chevron/internal/tools"
+	"github.com/quan-to/chevron/pkg/client"
+	"github.com/quan-to/chevron/pkg/models"
+	"github.com/quan-to/chevron/test"
+	"github.com/quan-to/slog"
+)
+
+var log = slog.Scope("TestLocalAgent")
+
+var api *client.Client
+var socketPath string
+
+func TestMain(m *testing.M) {
+	slog.UnsetTestMode()
+
+	u, _ := uuid.NewRandom()
+
+	config.DatabaseName = "qrs_test_" + u.String()
+	keyFolder, err := test.MakeKeyFolder("../../test/data")
+	if err != nil {
+		slog.Fatal("Error creating the test key folder: %s", err)
+	}
+
+	config.PrivateKeyFolder = keyFolder
+	config.KeyPrefix = "testkey_"
+	config.KeysBase64Encoded = false
+	config.EnableDatabase = false
+	config.AgentTOTPEncryptionKey = "test-totp-key"
+
+	config.MasterGPGKeyBase64Encoded = false
+	config.MasterGPGKeyPath = path.Join(keyFolder, test.TestKeyFile)
+	config.MasterGPGKeyPasswordPath = path.Join(keyFolder, test.TestKeyPasswordFile)
+
+	dbh, err := agent.MakeDatabaseHandler(log)
+	if err != nil {
+		slog.Fatal("Error initializing selected database: %s", err)
+	}
+
+	ctx := context.WithValue(context.Background(), tools.CtxDatabaseHandler, dbh)
+
+	sm := magicbuilder.MakeSM(nil, dbh)
+	gpg := magicbuilder.MakePGP(nil, dbh)
+	gpg.LoadKeys(ctx)
+
+	if err := gpg.UnlockKey(ctx, test.TestKeyFingerprint, test.TestKeyPassword); err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	ts := httptest.NewServer(server.GenRemoteSignerServerMux(log, sm, gpg, dbh))
+	api = client.MakeClient(log, ts.URL)
+
+	folder, err := ioutil.TempDir("", "localagent")
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	socketPath = path.Join(folder, "agent.sock")
+
+	a := MakeAgent(log, api, test.TestKeyFingerprint)
+	if err := a.Listen(socketPath); err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	go func() {
+		_ = a.Serve()
+	}()
+
+	slog.SetTestMode()
+	code := m.Run()
+	slog.UnsetTestMode()
+
+	_ = a.Close()
+	ts.Close()
+	_ = os.RemoveAll(folder)
+	_ = os.RemoveAll(keyFolder)
+	os.Exit(code)
+}
+
+func verify(t *testing.T, data []byte, signature string) {
+	err := api.VerifySignature(context.Background(), models.GPGVerifySignatureData{
+		Base64Data: base64.StdEncoding.EncodeToString(data),
+		Signature:  signature,
+	})
+
+	if err != nil {
+		t.Fatalf("expected valid signature: %s", err)
+	}
+}
+
+func TestSign(t *testing.T) {
+	ctx := context.Background()
+	data := []byte("huebr")
+
+	keys, err := api.PrivateKeys(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	identifier := ""
+	for _, k := range keys {
+		if k.FingerPrint == test.TestKeyFingerprint {
+			identifier = k.Identifier
+		}
+	}
+
+	email := identifier[strings.LastIndex(identifier, "<")+1 : strings.LastIndex(identifier, ">")]
+
+	for _, key := range []string{"", test.TestKeyFingerprint, "0x" + strings.ToLower(test.TestKeyFingerprint), test.TestKeyFingerprint[8:], identifier, strings.ToUpper(email)} {
+		res, err := Sign(ctx, socketPath, key, data)
+		if err != nil {
+			t.Fatalf("error signing with key %q: %s", key, err)
+		}
+
+		if !strings.HasSuffix(res.FingerPrint, test.TestKeyFingerprint) || len(res.FingerPrint) != 40 {
+			t.Fatalf("expected full fingerprint of %s got %q", test.TestKeyFingerprint, res.FingerPrint)
+		}
+
+		verify(t, data, res.Signature)
+	}
+
+	if _, err := Sign(ctx, socketPath, "nobody@example.com", data); err == nil || !strings.Contains(err.Error(), "no private key") {
+		t.Fatalf("expected error for unknown key got %v", err)
+	}
+
+	if _, err := Sign(ctx, socketPath, "AAAAAAAAAAAAAAAA", data); err == nil {
+		t.Fatal("expected error for key not in the server")
+	}
+}
+
+func TestDecrypt(t *testing.T) {
+	ctx := context.Background()
+
+	for _, dataOnly := range []bool{false, true} {
+		encrypted, err := api.Encrypt(ctx, models.GPGEncryptData{
+			FingerPrint: test.TestKeyFingerprint,
+			Base64Data:  base64.StdEncoding.EncodeToString([]byte("huebr")),
+			DataOnly:    dataOnly,
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data := []byte(encrypted)
+		if dataOnly {
+			data, _ = base64.StdEncoding.DecodeString(encrypted)
+		}
+
+		res, err := Decrypt(ctx, socketPath, data)
+		if err != nil {
+			t.Fatalf("error decrypting (dataOnly: %v): %s", dataOnly, err)
+		}
+
+		if string(res.Data) != "huebr" {
+			t.Fatalf("expected huebr got %q", res.Data)
+		}
+	}
+
+	if _, err := Decrypt(ctx, socketPath, []byte("not encrypted")); err == nil {
+		t.Fatal("expected error decrypting invalid data")
+	}
+}
+
+func TestInvalidOperation(t *testing.T) {
+	_, err := call(context.Background(), socketPath, Request{Operation: "export"})
+	if err == nil || !strings.Contains(err.Error(), "unknown operation") {
+		t.Fatalf("expected unknown operation error got %v", err)
+	}
+}
+
+func TestListen(t *testing.T) {
+	// A running agent is not replaced
+	if err := MakeAgent(nil, api, "").Listen(socketPath); err == nil {
+		t.Fatal("expected error listening in the socket of a running agent")
+	}
+
+	folder, _ := ioutil.TempDir("", "localagent")
+	defer os.RemoveAll(folder)
+
+	stale := path.Join(folder, "sub", "agent.sock")
+	_ = os.Mkdir(path.Join(folder, "sub"), 0700)
+	_ = ioutil.WriteFile(stale, nil, 0600)
+
+	a := MakeAgent(nil, api, "")
+	if err := a.Listen(stale); err != nil {
+		t.Fatalf("expected stale socket to be replaced: %s", err)
+	}
+
+	info, err := os.Stat(stale)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("expected socket with permissions 0600 got %v (%v)", info.Mode(), err)
+	}
+
+	go func() {
+		_ = a.Serve()
+	}()
+
+	if _, err := Sign(context.Background(), stale, "", []byte("huebr")); err == nil || !strings.Contains(err.Error(), "default key") {
+		t.Fatalf("expected error without default key got %v", err)
+	}
+
+	_ = a.Close()
+
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Fatal("expected socket to be removed on close")
+	}
+
+	if _, err := Sign(context.Background(), stale, "", []byte("huebr")); err == nil {
+		t.Fatal("expected error calling a closed agent")
+	}
+}
@@ -0,0 +1,315 @@
+package localagent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/quan-to/chevron/pkg/openpgp/armor"
+	"github.com/quan-to/chevron/pkg/openpgp/packet"
+	"github.com/quan-to/chevron/pkg/openpgp/s2k"
+)
+
+// gpgOptions are the gpg command line options supported by RunGPG
+type gpgOptions struct {
+	sign      bool
+	detach    bool
+	armor     bool
+	decrypt   bool
+	localUser string
+	output    string
+	statusFd  int
+	files     []string
+}
+
+// gpgIgnoredOptions are accepted and ignored. The value is true for options that take an argument
+var gpgIgnoredOptions = map[string]bool{
+	"batch":         false,
+	"yes":           false,
+	"no-tty":        false,
+	"quiet":         false,
+	"verbose":       false,
+	"no-verbose":    false,
+	"use-agent":     false,
+	"no-armor":      false,
+	"keyid-format":  true,
+	"pinentry-mode": true,
+	"digest-algo":   true,
+	"homedir":       true,
+}
+
+// gpgShortOptions maps the supported short options to their long names
+var gpgShortOptions = map[byte]string{
+	'b': "detach-sign",
+	's': "sign",
+	'a': "armor",
+	'd': "decrypt",
+	'u': "local-user",
+	'o': "output",
+	'q': "quiet",
+	'v': "verbose",
+}
+
+// gpgValueOptions are the supported options that take an argument
+var gpgValueOptions = map[string]bool{
+	"local-user":  true,
+	"default-key": true,
+	"output":      true,
+	"status-fd":   true,
+}
+
+func parseGPGArgs(args []string) (*gpgOptions, error) {
+	opts := &gpgOptions{statusFd: -1}
+
+	set := func(name, value string) error {
+		switch name {
+		case "detach-sign":
+			opts.sign = true
+			opts.detach = true
+		case "sign":
+			opts.sign = true
+		case "armor":
+			opts.armor = true
+		case "no-armor":
+			opts.armor = false
+		case "decrypt":
+			opts.decrypt = true
+		case "local-user", "default-key":
+			opts.localUser = value
+		case "output":
+			opts.output = value
+		case "status-fd":
+			fd, err := strconv.Atoi(value)
+			if err != nil || fd < 0 {
+				return fmt.Errorf("invalid status-fd %q", value)
+			}
+			opts.statusFd = fd
+		case "digest-algo":
+			if !strings.EqualFold(value, "SHA512") {
+				return fmt.Errorf("only SHA512 signatures are supported")
+			}
+		default:
+			if _, ok := gpgIgnoredOptions[name]; !ok {
+				return fmt.Errorf("unsupported option --%s", name)
+			}
+		}
+
+		return nil
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		switch {
+		case arg == "--":
+			opts.files = append(opts.files, args[i+1:]...)
+			return opts, nil
+		case strings.HasPrefix(arg, "--"):
+			name := arg[2:]
+			value := ""
+			hasValue := false
+
+			if idx := strings.Index(name, "="); idx != -1 {
+				name, value, hasValue = name[:idx], name[idx+1:], true
+			}
+
+			if (gpgValueOptions[name] || gpgIgnoredOptions[name]) && !hasValue {
+				if i+1 >= len(args) {
+					return nil, fmt.Errorf("missing argument for --%s", name)
+				}
+				i++
+				value = args[i]
+			}
+
+			if err := set(name, value); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(arg, "-") && arg != "-":
+			// Short options can be grouped like -bsau KEY
+			for j := 1; j < len(arg); j++ {
+				name, ok := gpgShortOptions[arg[j]]
+				if !ok {
+					return nil, fmt.Errorf("unsupported option -%c", arg[j])
+				}
+
+				value := ""
+				if gpgValueOptions[name] {
+					value = arg[j+1:]
+					if value == "" {
+						if i+1 >= len(args) {
+							return nil, fmt.Errorf("missing argument for -%c", arg[j])
+						}
+						i++
+						value = args[i]
+					}
+					j = len(arg)
+				}
+
+				if err := set(name, value); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			opts.files = append(opts.files, arg)
+		}
+	}
+
+	return opts, nil
+}
+
+// RunGPG runs a gpg compatible command line that signs and decrypts through the agent at socketPath.
+// It supports detached signatures (--detach-sign, --armor, --local-user) and --decrypt, reporting
+// the result in --status-fd like gpg does. Returns the exit code
+func RunGPG(socketPath string, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	opts, err := parseGPGArgs(args)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "gpg: %s\n", err)
+		return 2
+	}
+
+	status := ioutil.Discard
+	switch opts.statusFd {
+	case -1:
+	case 1:
+		status = stdout
+	case 2:
+		status = stderr
+	default:
+		f := os.NewFile(uintptr(opts.statusFd), "status")
+		if f == nil {
+			_, _ = fmt.Fprintf(stderr, "gpg: invalid status-fd %d\n", opts.statusFd)
+			return 2
+		}
+		defer f.Close()
+		status = f
+	}
+
+	if len(opts.files) > 1 {
+		_, _ = fmt.Fprintf(stderr, "gpg: only one input file is supported\n")
+		return 2
+	}
+
+	input := "-"
+	if len(opts.files) == 1 {
+		input = opts.files[0]
+	}
+
+	var data []byte
+	if input == "-" {
+		data, err = ioutil.ReadAll(stdin)
+	} else {
+		data, err = ioutil.ReadFile(input)
+	}
+
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "gpg: can't open '%s': %s\n", input, err)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	switch {
+	case opts.sign && opts.detach && !opts.decrypt:
+		return gpgSign(ctx, socketPath, opts, input, data, stdout, stderr, status)
+	case opts.decrypt && !opts.sign:
+		return gpgDecrypt(ctx, socketPath, opts, input, data, stdout, stderr, status)
+	}
+
+	_, _ = fmt.Fprintf(stderr, "gpg: only --detach-sign and --decrypt are supported\n")
+
+	return 2
+}
+
+func gpgSign(ctx context.Context, socketPath string, opts *gpgOptions, input string, data []byte, stdout, stderr, status io.Writer) int {
+	res, err := Sign(ctx, socketPath, opts.localUser, data)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "gpg: signing failed: %s\n", err)
+		_, _ = fmt.Fprintf(status, "[GNUPG:] FAILURE sign 1\n")
+		return 2
+	}
+
+	block, err := armor.Decode(strings.NewReader(res.Signature))
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "gpg: invalid signature from agent: %s\n", err)
+		return 2
+	}
+
+	binarySignature, err := ioutil.ReadAll(block.Body)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "gpg: invalid signature from agent: %s\n", err)
+		return 2
+	}
+
+	p, err := packet.Read(bytes.NewReader(binarySignature))
+	sig, ok := p.(*packet.Signature)
+	if err != nil || !ok {
+		_, _ = fmt.Fprintf(stderr, "gpg: invalid signature from agent\n")
+		return 2
+	}
+
+	hashID, _ := s2k.HashToHashId(sig.Hash)
+
+	output := opts.output
+	if output == "" && input != "-" {
+		output = input + ".sig"
+		if opts.armor {
+			output = input + ".asc"
+		}
+	}
+
+	signature := binarySignature
+	if opts.armor {
+		signature = []byte(res.Signature)
+		if !bytes.HasSuffix(signature, []byte("\n")) {
+			signature = append(signature, '\n')
+		}
+	}
+
+	if err := writeGPGOutput(output, signature, stdout); err != nil {
+		_, _ = fmt.Fprintf(stderr, "gpg: error writing signature: %s\n", err)
+		return 2
+	}
+
+	_, _ = fmt.Fprintf(status, "[GNUPG:] KEY_CONSIDERED %s 0\n", res.FingerPrint)
+	_, _ = fmt.Fprintf(status, "[GNUPG:] BEGIN_SIGNING H%d\n", hashID)
+	_, _ = fmt.Fprintf(status, "[GNUPG:] SIG_CREATED D %d %d %02x %d %s\n", sig.PubKeyAlgo, hashID, sig.SigType, sig.CreationTime.Unix(), res.FingerPrint)
+
+	return 0
+}
+
+func gpgDecrypt(ctx context.Context, socketPath string, opts *gpgOptions, input string, data []byte, stdout, stderr, status io.Writer) int {
+	_, _ = fmt.Fprintf(status, "[GNUPG:] BEGIN_DECRYPTION\n")
+
+	res, err := Decrypt(ctx, socketPath, data)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "gpg: decryption failed: %s\n", err)
+		_, _ = fmt.Fprintf(status, "[GNUPG:] DECRYPTION_FAILED\n[GNUPG:] END_DECRYPTION\n")
+		return 2
+	}
+
+	_, _ = fmt.Fprintf(stderr, "gpg: encrypted with key %s\n", res.FingerPrint)
+
+	if err := writeGPGOutput(opts.output, res.Data, stdout); err != nil {
+		_, _ = fmt.Fprintf(stderr, "gpg: error writing output: %s\n", err)
+		return 2
+	}
+
+	_, _ = fmt.Fprintf(status, "[GNUPG:] DECRYPTION_OKAY\n[GNUPG:] END_DECRYPTION\n")
+
+	return 0
+}
+
+func writeGPGOutput(output string, data []byte, stdout io.Writer) error {
+	if output == "" || output == "-" {
+		_, err := stdout.Write(data)
+		return err
+	}
+
+	return ioutil.WriteFile(output, data, 0644)
+}
@@ -0,0 +1,143 @@
+package localagent
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/quan-to/chevron/pkg/models"
+	"github.com/quan-to/chevron/pkg/openpgp/armor"
+	"github.com/quan-to/chevron/test"
+)
+
+func runGPG(args []string, stdin string) (int, string, string) {
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+
+	code := RunGPG(socketPath, args, strings.NewReader(stdin), stdout, stderr)
+
+	return code, stdout.String(), stderr.String()
+}
+
+func TestGPGGitSign(t *testing.T) {
+	commit := "tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\nauthor Jon HUEBR <jon@huebr.com> 1600000000 +0000\n\nhuebr\n"
+
+	// Arguments used by git to sign commits and tags
+	for _, args := range [][]string{
+		{"--status-fd=2", "-bsau", test.TestKeyFingerprint},
+		{"--status-fd", "2", "-b", "-s", "-a", "-u", test.TestKeyFingerprint},
+		{"-bsau" + test.TestKeyFingerprint, "--status-fd=2", "--detach-sign", "--armor"},
+	} {
+		code, stdout, stderr := runGPG(args, commit)
+		if code != 0 {
+			t.Fatalf("%v: expected exit code 0 got %d: %s", args, code, stderr)
+		}
+
+		if !strings.HasPrefix(stdout, "-----BEGIN PGP SIGNATURE-----") {
+			t.Fatalf("%v: expected armored signature got %q", args, stdout)
+		}
+
+		// git checks for SIG_CREATED in the status output
+		if !strings.Contains(stderr, "\n[GNUPG:] SIG_CREATED D 1 10 00 ") || !strings.Contains(stderr, test.TestKeyFingerprint+"\n") {
+			t.Fatalf("%v: expected SIG_CREATED status got %q", args, stderr)
+		}
+
+		verify(t, []byte(commit), stdout)
+	}
+}
+
+func TestGPGSignFile(t *testing.T) {
+	folder, _ := ioutil.TempDir("", "localagent")
+	defer os.RemoveAll(folder)
+
+	artifact := path.Join(folder, "artifact.tar.gz")
+	_ = ioutil.WriteFile(artifact, []byte("huebr"), 0600)
+
+	// Without --output the signature is written next to the file
+	if code, _, stderr := runGPG([]string{"--detach-sign", "--armor", "--local-user", test.TestKeyFingerprint, artifact}, ""); code != 0 {
+		t.Fatalf("expected exit code 0 got %d: %s", code, stderr)
+	}
+
+	signature, err := ioutil.ReadFile(artifact + ".asc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verify(t, []byte("huebr"), string(signature))
+
+	// Binary signatures
+	output := path.Join(folder, "signature.bin")
+	if code, _, stderr := runGPG([]string{"-b", "-o", output, artifact}, ""); code != 0 {
+		t.Fatalf("expected exit code 0 got %d: %s", code, stderr)
+	}
+
+	signature, _ = ioutil.ReadFile(output)
+	if bytes.HasPrefix(signature, []byte("-----")) {
+		t.Fatal("expected binary signature")
+	}
+
+	armored := bytes.NewBuffer(nil)
+	w, _ := armor.Encode(armored, "PGP SIGNATURE", nil)
+	_, _ = w.Write(signature)
+	_ = w.Close()
+
+	verify(t, []byte("huebr"), armored.String())
+}
+
+func TestGPGDecrypt(t *testing.T) {
+	encrypted, err := api.Encrypt(context.Background(), models.GPGEncryptData{
+		FingerPrint: test.TestKeyFingerprint,
+		Base64Data:  base64.StdEncoding.EncodeToString([]byte("huebr")),
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := runGPG([]string{"--status-fd=2", "--decrypt"}, encrypted)
+	if code != 0 || stdout != "huebr" {
+		t.Fatalf("expected huebr got %q (%d): %s", stdout, code, stderr)
+	}
+
+	if !strings.Contains(stderr, "[GNUPG:] DECRYPTION_OKAY") {
+		t.Fatalf("expected DECRYPTION_OKAY status got %q", stderr)
+	}
+
+	code, _, stderr = runGPG([]string{"--status-fd=2", "-d"}, "not encrypted")
+	if code != 2 || !strings.Contains(stderr, "[GNUPG:] DECRYPTION_FAILED") {
+		t.Fatalf("expected decryption failure got %d: %s", code, stderr)
+	}
+}
+
+func TestGPGErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"--verify", "file.sig", "-"},
+		{"--clearsign"},
+		{"-s"},
+		{"-bsau"},
+		{"--status-fd=x", "-bs"},
+		{"-bs", "--digest-algo", "SHA1"},
+		{"-bs", "a", "b"},
+		{"-bs", "/nonexistent/file"},
+	} {
+		if code, _, stderr := runGPG(args, "huebr"); code != 2 || !strings.HasPrefix(stderr, "gpg: ") {
+			t.Fatalf("%v: expected exit code 2 and error message got %d: %q", args, code, stderr)
+		}
+	}
+
+	// Ignored options
+	if code, _, stderr := runGPG([]string{"--batch", "--yes", "--keyid-format", "long", "--pinentry-mode=loopback", "-bsa"}, "huebr"); code != 0 {
+		t.Fatalf("expected exit code 0 got %d: %s", code, stderr)
+	}
+
+	// Agent not running
+	stderr := bytes.NewBuffer(nil)
+	if code := RunGPG("/nonexistent/agent.sock", []string{"--status-fd=2", "-bsa"}, strings.NewReader("huebr"), ioutil.Discard, stderr); code != 2 || !strings.Contains(stderr.String(), "[GNUPG:] FAILURE sign") {
+		t.Fatalf("expected failure without agent got %d: %s", code, stderr)
+	}
+}
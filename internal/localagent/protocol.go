@@ -0,0 +1,104 @@
+// Package localagent implements a signing agent for developer machines
+//
+// The agent listens on a Unix socket and forwards sign and decrypt requests to a Chevron server
+// using the developer's token, so the private keys never leave the server.
+// RunGPG is a gpg compatible command line that talks to the agent, to be used as git gpg.program.
+package localagent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path"
+)
+
+const (
+	// OperationSign creates an armored detached signature of the data
+	OperationSign = "sign"
+	// OperationDecrypt decrypts the data with any of the private keys unlocked in the server
+	OperationDecrypt = "decrypt"
+
+	// SocketEnvironmentVariable overrides the default socket path
+	SocketEnvironmentVariable = "CHEVRON_AGENT_SOCKET"
+)
+
+// Request is sent to the agent. Each connection carries a single request
+type Request struct {
+	Operation string
+	// Key is a fingerprint, an email or an identity like "Name <email>". The agent default key is used if empty
+	Key  string `json:",omitempty"`
+	Data []byte
+}
+
+// Response is the agent answer for a Request
+type Response struct {
+	Error string `json:",omitempty"`
+	// Signature is the armored detached signature
+	Signature string `json:",omitempty"`
+	// FingerPrint is the key used to sign or decrypt
+	FingerPrint string `json:",omitempty"`
+	// Data is the decrypted data
+	Data []byte `json:",omitempty"`
+}
+
+// DefaultSocketPath returns the socket path from CHEVRON_AGENT_SOCKET or ~/.chevron/agent.sock
+func DefaultSocketPath() string {
+	if socketPath := os.Getenv(SocketEnvironmentVariable); socketPath != "" {
+		return socketPath
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "chevron-agent.sock"
+	}
+
+	return path.Join(home, ".chevron", "agent.sock")
+}
+
+// Sign asks the agent listening at socketPath to sign the data with the key
+func Sign(ctx context.Context, socketPath, key string, data []byte) (*Response, error) {
+	return call(ctx, socketPath, Request{
+		Operation: OperationSign,
+		Key:       key,
+		Data:      data,
+	})
+}
+
+// Decrypt asks the agent listening at socketPath to decrypt the data. Data can be armored or binary
+func Decrypt(ctx context.Context, socketPath string, data []byte) (*Response, error) {
+	return call(ctx, socketPath, Request{
+		Operation: OperationDecrypt,
+		Data:      data,
+	})
+}
+
+func call(ctx context.Context, socketPath string, req Request) (*Response, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to the agent at %s: %v", socketPath, err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("error sending request to the agent: %v", err)
+	}
+
+	var res Response
+	if err := json.NewDecoder(conn).Decode(&res); err != nil {
+		return nil, fmt.Errorf("error reading agent response: %v", err)
+	}
+
+	if res.Error != "" {
+		return nil, errors.New(res.Error)
+	}
+
+	return &res, nil
+}
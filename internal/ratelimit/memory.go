@@ -0,0 +1,92 @@
+// Package ratelimit implements the counters used to limit the requests of each client
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is the minimum interval between the removal of expired windows
+const sweepInterval = time.Minute
+
+type window struct {
+	count int64
+	reset time.Time
+}
+
+// MemoryCounter is a RateCounter for a single replica
+type MemoryCounter struct {
+	sync.Mutex
+	windows   map[string]*window
+	inFlight  map[string]int64
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// MakeMemoryCounter creates a RateCounter that keeps the counters in memory
+func MakeMemoryCounter() *MemoryCounter {
+	return &MemoryCounter{
+		windows:  map[string]*window{},
+		inFlight: map[string]int64{},
+		now:      time.Now,
+	}
+}
+
+// Hit counts a request for key in a fixed window of the specified duration
+func (m *MemoryCounter) Hit(_ context.Context, key string, duration time.Duration) (int64, time.Duration, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	w, ok := m.windows[key]
+	if !ok || !now.Before(w.reset) {
+		w = &window{reset: now.Add(duration)}
+		m.windows[key] = w
+	}
+
+	w.count++
+
+	return w.count, w.reset.Sub(now), nil
+}
+
+// sweep removes the expired windows so idle clients do not accumulate
+func (m *MemoryCounter) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+
+	for key, w := range m.windows {
+		if !now.Before(w.reset) {
+			delete(m.windows, key)
+		}
+	}
+
+	m.lastSweep = now
+}
+
+// Acquire increments the in-flight requests of key and returns the new value
+// The ttl is not used since the counters are lost with the process
+func (m *MemoryCounter) Acquire(_ context.Context, key string, _ time.Duration) (int64, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.inFlight[key]++
+
+	return m.inFlight[key], nil
+}
+
+// Release decrements the in-flight requests of key
+func (m *MemoryCounter) Release(_ context.Context, key string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.inFlight[key]--
+	if m.inFlight[key] <= 0 {
+		delete(m.inFlight, key)
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryCounterHit(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1000, 0)
+
+	m := MakeMemoryCounter()
+	m.now = func() time.Time { return now }
+
+	for i := int64(1); i <= 3; i++ {
+		count, reset, err := m.Hit(ctx, "client", time.Minute)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if count != i {
+			t.Errorf("Expected count %d got %d", i, count)
+		}
+		if reset != time.Minute {
+			t.Errorf("Expected reset in 1m got %s", reset)
+		}
+	}
+
+	// Other keys have their own window
+	if count, _, _ := m.Hit(ctx, "other", time.Minute); count != 1 {
+		t.Errorf("Expected count 1 for other key got %d", count)
+	}
+
+	now = now.Add(40 * time.Second)
+	if count, reset, _ := m.Hit(ctx, "client", time.Minute); count != 4 || reset != 20*time.Second {
+		t.Errorf("Expected count 4 resetting in 20s got %d in %s", count, reset)
+	}
+
+	now = now.Add(20 * time.Second)
+	if count, reset, _ := m.Hit(ctx, "client", time.Minute); count != 1 || reset != time.Minute {
+		t.Errorf("Expected a new window got count %d resetting in %s", count, reset)
+	}
+}
+
+func TestMemoryCounterSweep(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	m := MakeMemoryCounter()
+	m.now = func() time.Time { return now }
+
+	_, _, _ = m.Hit(ctx, "a", time.Second)
+	_, _, _ = m.Hit(ctx, "b", time.Hour)
+
+	now = now.Add(sweepInterval)
+	_, _, _ = m.Hit(ctx, "c", time.Second)
+
+	if _, ok := m.windows["a"]; ok {
+		t.Errorf("Expected expired window to be removed")
+	}
+
+	if len(m.windows) != 2 {
+		t.Errorf("Expected 2 windows got %d", len(m.windows))
+	}
+}
+
+func TestMemoryCounterInFlight(t *testing.T) {
+	ctx := context.Background()
+	m := MakeMemoryCounter()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = m.Acquire(ctx, "client", time.Minute)
+		}()
+	}
+	wg.Wait()
+
+	if count, _ := m.Acquire(ctx, "client", time.Minute); count != 51 {
+		t.Errorf("Expected 51 in-flight got %d", count)
+	}
+
+	for i := 0; i < 51; i++ {
+		_ = m.Release(ctx, "client")
+	}
+
+	if _, ok := m.inFlight["client"]; ok {
+		t.Errorf("Expected released counter to be removed")
+	}
+}
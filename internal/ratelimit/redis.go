@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// releaseSource decrements an existing in-flight counter, deleting it when it reaches zero.
+// A plain DECR of an expired counter would create a negative counter without expiration
+const releaseSource = `
+local count = tonumber(redis.call("GET", KEYS[1]))
+if count == nil then
+	return 0
+end
+if count <= 1 then
+	redis.call("DEL", KEYS[1])
+	return 0
+end
+return redis.call("DECR", KEYS[1])
+`
+
+var releaseScript = redis.NewScript(releaseSource)
+
+// RedisCounter is a RateCounter shared by all the replicas connected to the same Redis
+type RedisCounter struct {
+	client redis.Cmdable
+	prefix string
+}
+
+// MakeRedisCounter creates a RateCounter that keeps the counters in Redis. All keys start with prefix
+func MakeRedisCounter(client redis.Cmdable, prefix string) *RedisCounter {
+	return &RedisCounter{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// Hit counts a request for key in a fixed window of the specified duration
+func (r *RedisCounter) Hit(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	key = r.prefix + "rate:" + key
+
+	count, err := r.client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if count == 1 {
+		if err := r.client.PExpire(ctx, key, window).Err(); err != nil {
+			return 0, 0, err
+		}
+		return count, window, nil
+	}
+
+	ttl, err := r.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if ttl < 0 {
+		// The replica that created the window failed before setting the expiration
+		if err := r.client.PExpire(ctx, key, window).Err(); err != nil {
+			return 0, 0, err
+		}
+		ttl = window
+	}
+
+	return count, ttl, nil
+}
+
+// Acquire increments the in-flight requests of key and returns the new value
+func (r *RedisCounter) Acquire(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	key = r.prefix + "inflight:" + key
+
+	count, err := r.client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	// Counters of replicas that stopped without releasing expire after ttl without new requests
+	if err := r.client.PExpire(ctx, key, ttl).Err(); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Release decrements the in-flight requests of key. Counters that already expired are not recreated
+func (r *RedisCounter) Release(ctx context.Context, key string) error {
+	return releaseScript.Run(ctx, r.client, []string{r.prefix + "inflight:" + key}).Err()
+}
@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v8"
+)
+
+func TestRedisCounterHit(t *testing.T) {
+	ctx := context.Background()
+	db, mock := redismock.NewClientMock()
+	r := MakeRedisCounter(db, "chevron:")
+
+	// First request of the window
+	mock.ExpectIncr("chevron:rate:client").SetVal(1)
+	mock.ExpectPExpire("chevron:rate:client", time.Minute).SetVal(true)
+
+	count, reset, err := r.Hit(ctx, "client", time.Minute)
+	if err != nil || count != 1 || reset != time.Minute {
+		t.Errorf("Expected 1 resetting in 1m got %d in %s (%v)", count, reset, err)
+	}
+
+	// Next requests read the window expiration
+	mock.ExpectIncr("chevron:rate:client").SetVal(2)
+	mock.ExpectPTTL("chevron:rate:client").SetVal(30 * time.Second)
+
+	count, reset, err = r.Hit(ctx, "client", time.Minute)
+	if err != nil || count != 2 || reset != 30*time.Second {
+		t.Errorf("Expected 2 resetting in 30s got %d in %s (%v)", count, reset, err)
+	}
+
+	// Windows without expiration are fixed
+	mock.ExpectIncr("chevron:rate:client").SetVal(3)
+	mock.ExpectPTTL("chevron:rate:client").SetVal(-1)
+	mock.ExpectPExpire("chevron:rate:client", time.Minute).SetVal(true)
+
+	count, reset, err = r.Hit(ctx, "client", time.Minute)
+	if err != nil || count != 3 || reset != time.Minute {
+		t.Errorf("Expected 3 resetting in 1m got %d in %s (%v)", count, reset, err)
+	}
+
+	mock.ExpectIncr("chevron:rate:client").SetErr(errors.New("connection refused"))
+	if _, _, err = r.Hit(ctx, "client", time.Minute); err == nil {
+		t.Errorf("Expected redis error")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("expectations were not met: %s", err)
+	}
+}
+
+func TestRedisCounterInFlight(t *testing.T) {
+	ctx := context.Background()
+	db, mock := redismock.NewClientMock()
+	r := MakeRedisCounter(db, "chevron:")
+
+	mock.ExpectIncr("chevron:inflight:client").SetVal(2)
+	mock.ExpectPExpire("chevron:inflight:client", time.Minute).SetVal(true)
+	mock.ExpectEvalSha(releaseScript.Hash(), []string{"chevron:inflight:client"}).SetVal(int64(1))
+
+	count, err := r.Acquire(ctx, "client", time.Minute)
+	if err != nil || count != 2 {
+		t.Errorf("Expected 2 in-flight got %d (%v)", count, err)
+	}
+
+	if err := r.Release(ctx, "client"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	// The script is loaded when Redis does not have it cached
+	mock.ExpectEvalSha(releaseScript.Hash(), []string{"chevron:inflight:client"}).SetErr(errors.New("NOSCRIPT No matching script"))
+	mock.ExpectEval(releaseSource, []string{"chevron:inflight:client"}).SetVal(int64(0))
+
+	if err := r.Release(ctx, "client"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("expectations were not met: %s", err)
+	}
+}
@@ -28,6 +28,40 @@ var RootManagementQuery = graphql.NewObject(graphql.ObjectConfig{
 			Type:    graphql.String,
 			Resolve: resolveWhoAmI,
 		},
+		"Users": &graphql.Field{
+			Type: graphql.NewList(mgql.GraphQLUser),
+			Args: graphql.FieldConfigArgument{
+				"pageStart": &graphql.ArgumentConfig{
+					Type:        graphql.Int,
+					Description: "Index of the first user to return. Defaults to 0",
+				},
+				"pageEnd": &graphql.ArgumentConfig{
+					Type:        graphql.Int,
+					Description: "Index after the last user to return. Defaults to 100",
+				},
+			},
+			Resolve: resolveUsers,
+		},
+		"User": &graphql.Field{
+			Type: mgql.GraphQLUser,
+			Args: graphql.FieldConfigArgument{
+				"username": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "Login of the user",
+				},
+			},
+			Resolve: resolveUser,
+		},
+		"ActiveTokens": &graphql.Field{
+			Type: graphql.NewList(mgql.GraphQLTokenInfo),
+			Args: graphql.FieldConfigArgument{
+				"username": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "Login of the user. Only administrators and operators can list tokens of other users",
+				},
+			},
+			Resolve: resolveActiveTokens,
+		},
 	},
 })
 
@@ -45,6 +79,10 @@ var RootManagementMutation = graphql.NewObject(graphql.ObjectConfig{
 					Type:        graphql.NewNonNull(graphql.String),
 					Description: "Password to Login",
 				},
+				"otp": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "TOTP code. Required if the user is enrolled in TOTP",
+				},
 				"expiresAfter": &graphql.ArgumentConfig{
 					Type:        graphql.Int,
 					Description: "Number of seconds since creation when the generated token will expire. If 0, defaults to server default.",
@@ -67,6 +105,10 @@ var RootManagementMutation = graphql.NewObject(graphql.ObjectConfig{
 					Type:        graphql.String,
 					Description: "The fingerPrint that this user will use. Defaults to server Default",
 				},
+				"roles": &graphql.ArgumentConfig{
+					Type:        graphql.NewList(mgql.GraphQLRole),
+					Description: "The roles of the new user. Defaults to signer",
+				},
 			},
 			Resolve: resolveAddUser,
 		},
@@ -85,7 +127,7 @@ var RootManagementMutation = graphql.NewObject(graphql.ObjectConfig{
 			Args: graphql.FieldConfigArgument{
 				"username": &graphql.ArgumentConfig{
 					Type:        graphql.String,
-					Description: "Optional username to inform the user. It doesn't create anything and it isn't validated. Its just for info purpose.",
+					Description: "Optional username to inform the user. It doesn't create anything. The token gets the roles of the user with the same username, so only administrators can use the username of an existing user.",
 				},
 				"fullname": &graphql.ArgumentConfig{
 					Type:        graphql.String,
@@ -93,7 +135,7 @@ var RootManagementMutation = graphql.NewObject(graphql.ObjectConfig{
 				},
 				"fingerPrint": &graphql.ArgumentConfig{
 					Type:        graphql.String,
-					Description: "Fingerprint of the key to give access to. Defaults to Agent Default",
+					Description: "Fingerprint of the key to give access to. Defaults to Agent Default for administrators and to the own key for operators",
 				},
 				"expiresAfter": &graphql.ArgumentConfig{
 					Type:        graphql.Int,
@@ -112,18 +154,117 @@ var RootManagementMutation = graphql.NewObject(graphql.ObjectConfig{
 			},
 			Resolve: resolveInvalidateToken,
 		},
+		"DisableUser": &graphql.Field{
+			Type: graphql.String,
+			Args: graphql.FieldConfigArgument{
+				"username": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "Login of the user to disable. All user tokens are invalidated",
+				},
+			},
+			Resolve: resolveDisableUser,
+		},
+		"EnableUser": &graphql.Field{
+			Type: graphql.String,
+			Args: graphql.FieldConfigArgument{
+				"username": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "Login of the user to enable",
+				},
+			},
+			Resolve: resolveEnableUser,
+		},
+		"DeleteUser": &graphql.Field{
+			Type: graphql.String,
+			Args: graphql.FieldConfigArgument{
+				"username": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "Login of the user to delete. All user tokens are invalidated",
+				},
+			},
+			Resolve: resolveDeleteUser,
+		},
+		"UpdateUserFingerprint": &graphql.Field{
+			Type: mgql.GraphQLUser,
+			Args: graphql.FieldConfigArgument{
+				"username": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "Login of the user",
+				},
+				"fingerPrint": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "The fingerPrint of the key this user will use. Existing tokens of the user are revoked",
+				},
+			},
+			Resolve: resolveUpdateUserFingerprint,
+		},
+		"GrantRole": &graphql.Field{
+			Type: mgql.GraphQLUser,
+			Args: graphql.FieldConfigArgument{
+				"username": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "Login of the user",
+				},
+				"role": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(mgql.GraphQLRole),
+					Description: "The role to grant",
+				},
+			},
+			Resolve: resolveGrantRole,
+		},
+		"RevokeRole": &graphql.Field{
+			Type: mgql.GraphQLUser,
+			Args: graphql.FieldConfigArgument{
+				"username": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "Login of the user",
+				},
+				"role": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(mgql.GraphQLRole),
+					Description: "The role to revoke",
+				},
+			},
+			Resolve: resolveRevokeRole,
+		},
+		"EnrollTOTP": &graphql.Field{
+			Type:        mgql.GraphQLTOTPEnrollment,
+			Description: "Generates a new TOTP secret for the logged user. It needs to be confirmed with ConfirmTOTP",
+			Resolve:     resolveEnrollTOTP,
+		},
+		"ConfirmTOTP": &graphql.Field{
+			Type: graphql.String,
+			Args: graphql.FieldConfigArgument{
+				"otp": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "A TOTP code generated with the enrolled secret",
+				},
+			},
+			Resolve: resolveConfirmTOTP,
+		},
+		"DisableTOTP": &graphql.Field{
+			Type: graphql.String,
+			Args: graphql.FieldConfigArgument{
+				"username": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "Login of the user. Defaults to the logged user. Only administrators can disable TOTP of other users",
+				},
+				"otp": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "A valid TOTP code. Required to disable your own confirmed enrollment",
+				},
+			},
+			Resolve: resolveDisableTOTP,
+		},
 	},
 })
 
 func resolveWhoAmI(p graphql.ResolveParams) (i interface{}, e error) {
-	lu := p.Context.Value(LoggedUserKey).(interfaces.UserData)
-
-	if lu == nil {
-		e := QuantoError.New(QuantoError.PermissionDenied, "proxyToken", "You need to be logged in to use this query", nil)
-		return nil, e.ToFormattedError()
+	lu, err := requireRole(p, models.Roles...)
+	if err != nil {
+		return nil, err
 	}
 
-	return lu.GetFullName(), nil
+	return lu.FullName, nil
 }
 
 func resolveLogin(p graphql.ResolveParams) (i interface{}, e error) {
@@ -140,6 +281,10 @@ func resolveLogin(p graphql.ResolveParams) (i interface{}, e error) {
 		return nil, e.ToFormattedError()
 	}
 
+	if err := checkLoginOTP(am, username, p.Args["otp"]); err != nil {
+		return nil, err
+	}
+
 	createdAt := time.Now()
 
 	expTime := config.AgentTokenExpiration
@@ -168,15 +313,8 @@ func resolveLogin(p graphql.ResolveParams) (i interface{}, e error) {
 func resolveAddUser(p graphql.ResolveParams) (i interface{}, e error) {
 	var username, fullname, fingerPrint, password string
 
-	lu := p.Context.Value(LoggedUserKey).(interfaces.UserData)
-	if lu == nil {
-		e := QuantoError.New(QuantoError.PermissionDenied, "proxyToken", "You need to be logged in to use this query", nil)
-		return nil, e.ToFormattedError()
-	}
-
-	if lu.GetUsername() != "admin" {
-		e := QuantoError.New(QuantoError.PermissionDenied, "username", "Only the administrator can add users", nil)
-		return nil, e.ToFormattedError()
+	if _, err := adminUser(p); err != nil {
+		return nil, err
 	}
 
 	am := p.Context.Value(AuthManagerKey).(interfaces.AuthManager)
@@ -198,6 +336,24 @@ func resolveAddUser(p graphql.ResolveParams) (i interface{}, e error) {
 		return nil, e.ToFormattedError()
 	}
 
+	// Tokens generated for the username before the user existed must not get its roles
+	revokeUserTokens(p, username)
+
+	if p.Args["roles"] != nil {
+		var roles []string
+		for _, role := range p.Args["roles"].([]interface{}) {
+			roles = append(roles, role.(string))
+		}
+
+		if len(roles) > 0 {
+			err = setInitialRoles(am, username, roles)
+			if err != nil {
+				e := QuantoError.New(QuantoError.InternalServerError, "server", "There was an error setting the user roles. Please try again.", err.Error())
+				return nil, e.ToFormattedError()
+			}
+		}
+	}
+
 	amGqlLog.Info("Added new user %s (%s)", fullname, username)
 
 	return mgql.AddUserResult{
@@ -211,39 +367,32 @@ func resolveAddUser(p graphql.ResolveParams) (i interface{}, e error) {
 }
 
 func resolveChangePassword(p graphql.ResolveParams) (i interface{}, e error) {
-	lu := p.Context.Value(LoggedUserKey).(interfaces.UserData)
-	if lu == nil {
-		e := QuantoError.New(QuantoError.PermissionDenied, "proxyToken", "You need to be logged in to use this query", nil)
-		return nil, e.ToFormattedError()
+	lu, err := requireRole(p, models.Roles...)
+	if err != nil {
+		return nil, err
 	}
 
 	am := p.Context.Value(AuthManagerKey).(interfaces.AuthManager)
 	password := p.Args["password"].(string)
 
-	err := am.ChangePassword(lu.GetUsername(), password)
+	err = am.ChangePassword(lu.Username, password)
 
 	if err != nil {
-		amGqlLog.Error("Error changing user %s password: %s", lu.GetUsername(), err)
+		amGqlLog.Error("Error changing user %s password: %s", lu.Username, err)
 		e := QuantoError.New(QuantoError.InternalServerError, "server", "There was an error changing your password. Please try again.", err.Error())
 		return "NOK", e.ToFormattedError()
 	}
 
-	amGqlLog.Info("Changed Password for (%s)", lu.GetFullName(), lu.GetUsername())
+	amGqlLog.Info("Changed Password for %s (%s)", lu.FullName, lu.Username)
 
 	return "OK", nil
 }
 
 func resolveGenerateToken(p graphql.ResolveParams) (i interface{}, e error) {
 	var username, fullname, fingerPrint string
-	lu := p.Context.Value(LoggedUserKey).(interfaces.UserData)
-	if lu == nil {
-		e := QuantoError.New(QuantoError.PermissionDenied, "proxyToken", "You need to be logged in to use this query", nil)
-		return nil, e.ToFormattedError()
-	}
-
-	if lu.GetUsername() != "admin" {
-		e := QuantoError.New(QuantoError.PermissionDenied, "username", "Only the administrator can add users", nil)
-		return nil, e.ToFormattedError()
+	lu, err := requireRole(p, models.RoleAdmin, models.RoleOperator)
+	if err != nil {
+		return nil, err
 	}
 
 	tm := p.Context.Value(TokenManagerKey).(interfaces.TokenManager)
@@ -255,6 +404,15 @@ func resolveGenerateToken(p graphql.ResolveParams) (i interface{}, e error) {
 		username = u.String()
 	}
 
+	isAdmin := lu.HasRole(models.RoleAdmin)
+	am := p.Context.Value(AuthManagerKey).(interfaces.AuthManager)
+
+	// The token gets the roles of the user with the same username, so only administrators can choose one
+	if !isAdmin && am.UserExists(username) {
+		e := QuantoError.New(QuantoError.PermissionDenied, "username", "Operators cannot generate tokens for existing users", nil)
+		return nil, e.ToFormattedError()
+	}
+
 	if p.Args["fullname"] != nil {
 		fullname = p.Args["fullname"].(string)
 	} else {
@@ -263,8 +421,15 @@ func resolveGenerateToken(p graphql.ResolveParams) (i interface{}, e error) {
 
 	if p.Args["fingerPrint"] != nil {
 		fingerPrint = p.Args["fingerPrint"].(string)
-	} else {
+	} else if isAdmin {
 		fingerPrint = config.AgentKeyFingerPrint
+	} else {
+		fingerPrint = lu.Fingerprint
+	}
+
+	if !isAdmin && fingerPrint != lu.Fingerprint {
+		e := QuantoError.New(QuantoError.PermissionDenied, "fingerPrint", "Operators can only generate tokens for their own key", nil)
+		return nil, e.ToFormattedError()
 	}
 
 	expiration := 0
@@ -302,10 +467,22 @@ func resolveGenerateToken(p graphql.ResolveParams) (i interface{}, e error) {
 }
 
 func resolveInvalidateToken(p graphql.ResolveParams) (i interface{}, e error) {
+	lu, err := requireRole(p, models.Roles...)
+	if err != nil {
+		return "NOK", err
+	}
+
 	tm := p.Context.Value(TokenManagerKey).(interfaces.TokenManager)
 	token := p.Args["token"].(string)
 
-	err := tm.InvalidateToken(token)
+	if !lu.HasRole(models.RoleAdmin, models.RoleOperator) {
+		if tm.Verify(token) != nil || tm.GetUserData(token).GetUsername() != lu.Username {
+			e := QuantoError.New(QuantoError.PermissionDenied, "token", "You can only invalidate your own tokens", nil)
+			return "NOK", e.ToFormattedError()
+		}
+	}
+
+	err = tm.InvalidateToken(token)
 	if err != nil {
 		return "NOK", err
 	}
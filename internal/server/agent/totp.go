@@ -0,0 +1,130 @@
+package agent
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/quan-to/chevron/internal/totp"
+	"github.com/quan-to/chevron/pkg/QuantoError"
+	"github.com/quan-to/chevron/pkg/interfaces"
+	"github.com/quan-to/chevron/pkg/models"
+	mgql "github.com/quan-to/chevron/pkg/models/graphql"
+)
+
+const totpIssuer = "Chevron"
+
+// checkLoginOTP returns an error if the user is enrolled in TOTP and the otp is missing, invalid or already used
+// The login is denied if the TOTP enrollment of the user cannot be checked
+func checkLoginOTP(am interfaces.AuthManager, username string, otp interface{}) error {
+	um, err := am.GetUser(username)
+	if err != nil || um == nil {
+		errMsg := "user not found"
+		if err != nil {
+			errMsg = err.Error()
+		}
+		e := QuantoError.New(QuantoError.InternalServerError, "server", "There was an error checking the user TOTP enrollment.", errMsg)
+		return e.ToFormattedError()
+	}
+
+	if !um.TOTPEnabled {
+		return nil
+	}
+
+	code, _ := otp.(string)
+	if code == "" {
+		e := QuantoError.New(QuantoError.InvalidFieldData, "otp", "This user requires an OTP to login", nil)
+		return e.ToFormattedError()
+	}
+
+	if am.VerifyTOTP(username, code) != nil {
+		e := QuantoError.New(QuantoError.InvalidFieldData, "username/password/otp", "Invalid username, password or otp", nil)
+		return e.ToFormattedError()
+	}
+
+	return nil
+}
+
+func resolveEnrollTOTP(p graphql.ResolveParams) (i interface{}, e error) {
+	lu, err := requireRole(p, models.Roles...)
+	if err != nil {
+		return nil, err
+	}
+
+	am := p.Context.Value(AuthManagerKey).(interfaces.AuthManager)
+
+	if lu.TOTPEnabled {
+		e := QuantoError.New(QuantoError.InvalidFieldData, "username", "You are already enrolled in TOTP. Disable it before enrolling again", nil)
+		return nil, e.ToFormattedError()
+	}
+
+	secret, err := am.EnrollTOTP(lu.Username)
+	if err != nil {
+		e := QuantoError.New(QuantoError.InternalServerError, "server", "There was an error enrolling in TOTP.", err.Error())
+		return nil, e.ToFormattedError()
+	}
+
+	amGqlLog.Info("User %s started TOTP enrollment", lu.Username)
+
+	return mgql.TOTPEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(totpIssuer, lu.Username, secret),
+	}, nil
+}
+
+func resolveConfirmTOTP(p graphql.ResolveParams) (i interface{}, e error) {
+	lu, err := requireRole(p, models.Roles...)
+	if err != nil {
+		return "NOK", err
+	}
+
+	am := p.Context.Value(AuthManagerKey).(interfaces.AuthManager)
+
+	err = am.ConfirmTOTP(lu.Username, p.Args["otp"].(string))
+	if err != nil {
+		e := QuantoError.New(QuantoError.InvalidFieldData, "otp", "Invalid otp or no pending TOTP enrollment", err.Error())
+		return "NOK", e.ToFormattedError()
+	}
+
+	amGqlLog.Info("User %s confirmed TOTP enrollment", lu.Username)
+
+	return "OK", nil
+}
+
+func resolveDisableTOTP(p graphql.ResolveParams) (i interface{}, e error) {
+	lu, err := requireRole(p, models.Roles...)
+	if err != nil {
+		return "NOK", err
+	}
+
+	am := p.Context.Value(AuthManagerKey).(interfaces.AuthManager)
+
+	username := lu.Username
+	if p.Args["username"] != nil {
+		username = p.Args["username"].(string)
+	}
+
+	if username != lu.Username {
+		if !lu.HasRole(models.RoleAdmin) {
+			e := QuantoError.New(QuantoError.PermissionDenied, "username", "Only administrators can disable TOTP of other users", nil)
+			return "NOK", e.ToFormattedError()
+		}
+
+		if !am.UserExists(username) {
+			return "NOK", userNotFound(username)
+		}
+	} else if lu.TOTPEnabled {
+		otp, _ := p.Args["otp"].(string)
+		if am.VerifyTOTP(username, otp) != nil {
+			e := QuantoError.New(QuantoError.InvalidFieldData, "otp", "A valid otp is required to disable TOTP", nil)
+			return "NOK", e.ToFormattedError()
+		}
+	}
+
+	err = am.DisableTOTP(username)
+	if err != nil {
+		e := QuantoError.New(QuantoError.InternalServerError, "server", "There was an error disabling TOTP.", err.Error())
+		return "NOK", e.ToFormattedError()
+	}
+
+	amGqlLog.Info("Disabled TOTP of user %s", username)
+
+	return "OK", nil
+}
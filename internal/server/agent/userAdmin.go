@@ -0,0 +1,342 @@
+package agent
+
+import (
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/quan-to/chevron/pkg/QuantoError"
+	"github.com/quan-to/chevron/pkg/interfaces"
+	"github.com/quan-to/chevron/pkg/models"
+	mgql "github.com/quan-to/chevron/pkg/models/graphql"
+)
+
+// loggedUser returns the user that is logged in the request or an error if there is none
+func loggedUser(p graphql.ResolveParams) (interfaces.UserData, error) {
+	lu, _ := p.Context.Value(LoggedUserKey).(interfaces.UserData)
+	if lu == nil {
+		e := QuantoError.New(QuantoError.PermissionDenied, "proxyToken", "You need to be logged in to use this query", nil)
+		return nil, e.ToFormattedError()
+	}
+
+	return lu, nil
+}
+
+// requireRole returns the user that is logged in the request or an error if it does not have any of the specified roles
+// The roles are always loaded from the AuthManager so changes are applied to already issued tokens,
+// unless the user was authenticated by an external identity provider that informs the roles
+func requireRole(p graphql.ResolveParams, roles ...string) (*models.User, error) {
+	lu, err := loggedUser(p)
+	if err != nil {
+		return nil, err
+	}
+
+	var um *models.User
+
+	if ru, ok := lu.(interfaces.RoleUserData); ok {
+		um = &models.User{
+			Username:    ru.GetUsername(),
+			FullName:    ru.GetFullName(),
+			Fingerprint: ru.GetFingerPrint(),
+			Roles:       ru.GetRoles(),
+		}
+	} else {
+		am := p.Context.Value(AuthManagerKey).(interfaces.AuthManager)
+		um, err = am.GetUser(lu.GetUsername())
+	}
+
+	if err != nil || um.Disabled || !um.HasRole(roles...) {
+		e := QuantoError.New(QuantoError.PermissionDenied, "username", "You don't have permission to do this. Required roles: "+strings.Join(roles, ", "), nil)
+		return nil, e.ToFormattedError()
+	}
+
+	return um, nil
+}
+
+// adminUser returns the user that is logged in the request or an error if it is not an administrator
+func adminUser(p graphql.ResolveParams) (*models.User, error) {
+	return requireRole(p, models.RoleAdmin)
+}
+
+func userNotFound(username string) error {
+	e := QuantoError.New(QuantoError.NotFound, "username", "User "+username+" does not exists", nil)
+	return e.ToFormattedError()
+}
+
+func resolveUsers(p graphql.ResolveParams) (i interface{}, e error) {
+	if _, err := requireRole(p, models.RoleAdmin, models.RoleOperator); err != nil {
+		return nil, err
+	}
+
+	am := p.Context.Value(AuthManagerKey).(interfaces.AuthManager)
+
+	pageStart := models.DefaultPageStart
+	pageEnd := models.DefaultPageEnd
+
+	if p.Args["pageStart"] != nil {
+		pageStart = p.Args["pageStart"].(int)
+	}
+
+	if p.Args["pageEnd"] != nil {
+		pageEnd = p.Args["pageEnd"].(int)
+	}
+
+	users, err := am.ListUsers(pageStart, pageEnd)
+	if err != nil {
+		e := QuantoError.New(QuantoError.InternalServerError, "server", "There was an error listing the users. Please try again.", err.Error())
+		return nil, e.ToFormattedError()
+	}
+
+	res := make([]mgql.User, len(users))
+	for idx, u := range users {
+		res[idx] = mgql.UserFromModel(u)
+	}
+
+	return res, nil
+}
+
+func resolveUser(p graphql.ResolveParams) (i interface{}, e error) {
+	if _, err := requireRole(p, models.RoleAdmin, models.RoleOperator); err != nil {
+		return nil, err
+	}
+
+	am := p.Context.Value(AuthManagerKey).(interfaces.AuthManager)
+	username := p.Args["username"].(string)
+
+	um, err := am.GetUser(username)
+	if err != nil {
+		return nil, userNotFound(username)
+	}
+
+	return mgql.UserFromModel(*um), nil
+}
+
+func resolveActiveTokens(p graphql.ResolveParams) (i interface{}, e error) {
+	lu, err := requireRole(p, models.Roles...)
+	if err != nil {
+		return nil, err
+	}
+
+	username := p.Args["username"].(string)
+
+	if lu.Username != username && !lu.HasRole(models.RoleAdmin, models.RoleOperator) {
+		e := QuantoError.New(QuantoError.PermissionDenied, "username", "Only administrators and operators can list tokens of other users", nil)
+		return nil, e.ToFormattedError()
+	}
+
+	tm := p.Context.Value(TokenManagerKey).(interfaces.TokenManager)
+
+	tokens, err := tm.ActiveTokens(username)
+	if err != nil {
+		e := QuantoError.New(QuantoError.InternalServerError, "server", "There was an error listing the tokens. Please try again.", err.Error())
+		return nil, e.ToFormattedError()
+	}
+
+	res := make([]mgql.TokenInfo, len(tokens))
+	for idx, ut := range tokens {
+		res[idx] = mgql.TokenInfoFromModel(ut)
+	}
+
+	return res, nil
+}
+
+func resolveDisableUser(p graphql.ResolveParams) (i interface{}, e error) {
+	return setUserDisabled(p, true)
+}
+
+func resolveEnableUser(p graphql.ResolveParams) (i interface{}, e error) {
+	return setUserDisabled(p, false)
+}
+
+func setUserDisabled(p graphql.ResolveParams, disabled bool) (interface{}, error) {
+	lu, err := adminUser(p)
+	if err != nil {
+		return "NOK", err
+	}
+
+	username := p.Args["username"].(string)
+
+	if disabled && username == lu.Username {
+		e := QuantoError.New(QuantoError.InvalidFieldData, "username", "You cannot disable yourself", nil)
+		return "NOK", e.ToFormattedError()
+	}
+
+	am := p.Context.Value(AuthManagerKey).(interfaces.AuthManager)
+	if !am.UserExists(username) {
+		return "NOK", userNotFound(username)
+	}
+
+	err = am.SetUserDisabled(username, disabled)
+	if err != nil {
+		e := QuantoError.New(QuantoError.InternalServerError, "server", "There was an error updating the user. Please try again.", err.Error())
+		return "NOK", e.ToFormattedError()
+	}
+
+	if disabled {
+		revokeUserTokens(p, username)
+		amGqlLog.Info("Disabled user %s", username)
+	} else {
+		amGqlLog.Info("Enabled user %s", username)
+	}
+
+	return "OK", nil
+}
+
+func resolveDeleteUser(p graphql.ResolveParams) (i interface{}, e error) {
+	lu, err := adminUser(p)
+	if err != nil {
+		return "NOK", err
+	}
+
+	username := p.Args["username"].(string)
+
+	if username == lu.Username {
+		e := QuantoError.New(QuantoError.InvalidFieldData, "username", "You cannot delete yourself", nil)
+		return "NOK", e.ToFormattedError()
+	}
+
+	am := p.Context.Value(AuthManagerKey).(interfaces.AuthManager)
+	if !am.UserExists(username) {
+		return "NOK", userNotFound(username)
+	}
+
+	err = am.DeleteUser(username)
+	if err != nil {
+		e := QuantoError.New(QuantoError.InternalServerError, "server", "There was an error deleting the user. Please try again.", err.Error())
+		return "NOK", e.ToFormattedError()
+	}
+
+	revokeUserTokens(p, username)
+	amGqlLog.Info("Deleted user %s", username)
+
+	return "OK", nil
+}
+
+func resolveUpdateUserFingerprint(p graphql.ResolveParams) (i interface{}, e error) {
+	if _, err := adminUser(p); err != nil {
+		return nil, err
+	}
+
+	am := p.Context.Value(AuthManagerKey).(interfaces.AuthManager)
+	username := p.Args["username"].(string)
+	fingerPrint := p.Args["fingerPrint"].(string)
+
+	if !am.UserExists(username) {
+		return nil, userNotFound(username)
+	}
+
+	err := am.UpdateFingerprint(username, fingerPrint)
+	if err != nil {
+		e := QuantoError.New(QuantoError.InternalServerError, "server", "There was an error updating the user. Please try again.", err.Error())
+		return nil, e.ToFormattedError()
+	}
+
+	// Tokens keep the fingerPrint they were created with
+	revokeUserTokens(p, username)
+	amGqlLog.Info("Changed user %s fingerPrint to %s", username, fingerPrint)
+
+	um, err := am.GetUser(username)
+	if err != nil {
+		return nil, userNotFound(username)
+	}
+
+	return mgql.UserFromModel(*um), nil
+}
+
+// revokeUserTokens invalidates all tokens of a disabled or deleted user or of a user that changed the fingerPrint
+// Errors are only logged since the user operation was already done
+func revokeUserTokens(p graphql.ResolveParams, username string) {
+	tm := p.Context.Value(TokenManagerKey).(interfaces.TokenManager)
+
+	n, err := tm.RevokeUserTokens(username)
+	if err != nil {
+		amGqlLog.Error("Error revoking tokens of user %s: %s", username, err)
+		return
+	}
+
+	amGqlLog.Info("Revoked %d tokens of user %s", n, username)
+}
+
+func resolveGrantRole(p graphql.ResolveParams) (i interface{}, e error) {
+	if _, err := adminUser(p); err != nil {
+		return nil, err
+	}
+
+	return setUserRole(p, true)
+}
+
+func resolveRevokeRole(p graphql.ResolveParams) (i interface{}, e error) {
+	lu, err := adminUser(p)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Args["username"].(string) == lu.Username && p.Args["role"].(string) == models.RoleAdmin {
+		e := QuantoError.New(QuantoError.InvalidFieldData, "role", "You cannot revoke your own admin role", nil)
+		return nil, e.ToFormattedError()
+	}
+
+	return setUserRole(p, false)
+}
+
+func setUserRole(p graphql.ResolveParams, grant bool) (interface{}, error) {
+	am := p.Context.Value(AuthManagerKey).(interfaces.AuthManager)
+	username := p.Args["username"].(string)
+	role := p.Args["role"].(string)
+
+	if !am.UserExists(username) {
+		return nil, userNotFound(username)
+	}
+
+	var err error
+	if grant {
+		err = am.GrantRole(username, role)
+	} else {
+		err = am.RevokeRole(username, role)
+	}
+
+	if err != nil {
+		e := QuantoError.New(QuantoError.InternalServerError, "server", "There was an error updating the user roles. Please try again.", err.Error())
+		return nil, e.ToFormattedError()
+	}
+
+	if grant {
+		amGqlLog.Info("Granted role %s to user %s", role, username)
+	} else {
+		amGqlLog.Info("Revoked role %s from user %s", role, username)
+	}
+
+	um, err := am.GetUser(username)
+	if err != nil {
+		return nil, userNotFound(username)
+	}
+
+	return mgql.UserFromModel(*um), nil
+}
+
+// setInitialRoles replaces the default roles of a newly added user with the specified ones
+func setInitialRoles(am interfaces.AuthManager, username string, roles []string) error {
+	for _, role := range roles {
+		if err := am.GrantRole(username, role); err != nil {
+			return err
+		}
+	}
+
+	for _, role := range models.Roles {
+		if !containsRole(roles, role) {
+			if err := am.RevokeRole(username, role); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func containsRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
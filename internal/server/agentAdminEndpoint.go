@@ -100,6 +100,7 @@ func (gi *graphIntercept) WriteHeader(statusCode int) {
 // @Accept json
 // @Produce json
 // @param proxyToken header string true "Proxy Token of the admin user. It is required for all calls besides the login"
+// @param Authorization header string false "Bearer JWT issued by the identity provider. Alternative to proxyToken when JWT authentication is enabled"
 // @param message body string true "The JSON content of the graphql query"
 // @Success 200 {string} result "result of the query"
 // @Failure default {object} QuantoError.ErrorObject
@@ -117,7 +118,7 @@ func (admin *AgentAdmin) handleGraphQL(w http.ResponseWriter, r *http.Request) {
 	gi := graphIntercept{originalHandler: w, StatusCode: http.StatusOK}
 	ctx := context.WithValue(admin.ctx, agent.HTTPRequestKey, r)
 
-	token := r.Header.Get("proxyToken")
+	token := popProxyToken(r.Header)
 
 	if token != "" {
 		err := admin.tm.Verify(token)
@@ -9,7 +9,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/quan-to/chevron/internal/agent"
+	"github.com/quan-to/chevron/internal/totp"
 	"github.com/quan-to/chevron/pkg/QuantoError"
+	"github.com/quan-to/chevron/pkg/database/memory"
+	"github.com/quan-to/chevron/pkg/models"
 )
 
 func TestAdminLogin(t *testing.T) {
@@ -147,3 +151,423 @@ func TestInvalidToken(t *testing.T) {
 		errorDie(fmt.Errorf("expected %s in errorCode, got %s", QuantoError.InvalidFieldData, errObj.ErrorCode), t)
 	}
 }
+
+// agentAdminQuery runs a GraphQL query against the agentAdmin endpoint and returns the decoded response
+func agentAdminQuery(t *testing.T, token, query string, variables map[string]interface{}) map[string]interface{} {
+	payload := map[string]interface{}{
+		"query":     query,
+		"variables": variables,
+	}
+
+	d, _ := json.Marshal(payload)
+
+	req, err := http.NewRequest("POST", "/agentAdmin", bytes.NewReader(d))
+	errorDie(err, t)
+
+	if token != "" {
+		req.Header.Add("proxyToken", token)
+	}
+
+	res := executeRequest(req)
+
+	d, err = ioutil.ReadAll(res.Body)
+	errorDie(err, t)
+
+	if res.Code != 200 {
+		errorDie(fmt.Errorf("expected 200 got %d: %s", res.Code, string(d)), t)
+	}
+
+	var data map[string]interface{}
+	err = json.Unmarshal(d, &data)
+	errorDie(err, t)
+
+	return data
+}
+
+func agentAdminLogin(t *testing.T, username, password string) string {
+	data := agentAdminQuery(t, "", "mutation Login($username: String!, $password: String!) { Login(username: $username, password: $password) { Value }}", map[string]interface{}{
+		"username": username,
+		"password": password,
+	})
+
+	login, _ := data["data"].(map[string]interface{})["Login"].(map[string]interface{})
+	if login == nil {
+		errorDie(fmt.Errorf("login of %s failed: %v", username, data["errors"]), t)
+	}
+
+	return login["Value"].(string)
+}
+
+func agentAdminHasError(data map[string]interface{}) bool {
+	errs, _ := data["errors"].([]interface{})
+	return len(errs) > 0
+}
+
+func TestAdminUserManagement(t *testing.T) {
+	adminToken := agentAdminLogin(t, "admin", "admin")
+
+	// region Add User
+	data := agentAdminQuery(t, adminToken, "mutation { AddUser(username: \"managed\", fullname: \"Managed User\", fingerPrint: \"DEADBEEF\") { Password }}", nil)
+	if agentAdminHasError(data) {
+		errorDie(fmt.Errorf("error adding user: %v", data["errors"]), t)
+	}
+	password := data["data"].(map[string]interface{})["AddUser"].(map[string]interface{})["Password"].(string)
+	// endregion
+	// region List and Get
+	data = agentAdminQuery(t, adminToken, "query { Users(pageStart: 0, pageEnd: 100) { Username FullName FingerPrint Disabled }}", nil)
+	users := data["data"].(map[string]interface{})["Users"].([]interface{})
+	found := false
+	for _, u := range users {
+		um := u.(map[string]interface{})
+		if um["Username"] == "managed" {
+			found = true
+			if um["FingerPrint"] != "DEADBEEF" || um["Disabled"] != false {
+				errorDie(fmt.Errorf("unexpected user data: %v", um), t)
+			}
+		}
+	}
+	if !found {
+		errorDie(fmt.Errorf("expected user managed to be listed in %v", users), t)
+	}
+
+	data = agentAdminQuery(t, adminToken, "query { User(username: \"managed\") { FullName }}", nil)
+	if fullName := data["data"].(map[string]interface{})["User"].(map[string]interface{})["FullName"]; fullName != "Managed User" {
+		errorDie(fmt.Errorf("expected full name Managed User got %v", fullName), t)
+	}
+
+	data = agentAdminQuery(t, adminToken, "query { User(username: \"does-not-exist\") { FullName }}", nil)
+	if !agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected error fetching an unknown user"), t)
+	}
+	// endregion
+	// region Non admin access
+	userToken := agentAdminLogin(t, "managed", password)
+
+	data = agentAdminQuery(t, userToken, "query { Users { Username }}", nil)
+	if !agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected non admin user to be denied listing users"), t)
+	}
+
+	data = agentAdminQuery(t, userToken, "query { ActiveTokens(username: \"managed\") { UserName FingerPrint }}", nil)
+	tokens := data["data"].(map[string]interface{})["ActiveTokens"].([]interface{})
+	if len(tokens) != 1 {
+		errorDie(fmt.Errorf("expected 1 active token got %d", len(tokens)), t)
+	}
+
+	data = agentAdminQuery(t, userToken, "query { ActiveTokens(username: \"admin\") { UserName }}", nil)
+	if !agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected non admin user to be denied listing other user tokens"), t)
+	}
+	// endregion
+	// region Update Fingerprint
+	_ = agentAdminLogin(t, "managed", password)
+
+	data = agentAdminQuery(t, adminToken, "mutation { UpdateUserFingerprint(username: \"managed\", fingerPrint: \"BEEFDEAD\") { FingerPrint }}", nil)
+	if fp := data["data"].(map[string]interface{})["UpdateUserFingerprint"].(map[string]interface{})["FingerPrint"]; fp != "BEEFDEAD" {
+		errorDie(fmt.Errorf("expected fingerPrint BEEFDEAD got %v", fp), t)
+	}
+
+	data = agentAdminQuery(t, adminToken, "query { ActiveTokens(username: \"managed\") { UserName }}", nil)
+	if tokens := data["data"].(map[string]interface{})["ActiveTokens"].([]interface{}); len(tokens) != 0 {
+		errorDie(fmt.Errorf("expected tokens with the old fingerPrint to be revoked, got %d", len(tokens)), t)
+	}
+	// endregion
+	// region Disable / Enable
+	data = agentAdminQuery(t, adminToken, "mutation { DisableUser(username: \"admin\") }", nil)
+	if !agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected admin to be unable to disable itself"), t)
+	}
+
+	data = agentAdminQuery(t, adminToken, "mutation { DisableUser(username: \"managed\") }", nil)
+	if agentAdminHasError(data) {
+		errorDie(fmt.Errorf("error disabling user: %v", data["errors"]), t)
+	}
+
+	data = agentAdminQuery(t, adminToken, "query { ActiveTokens(username: \"managed\") { UserName }}", nil)
+	if tokens := data["data"].(map[string]interface{})["ActiveTokens"].([]interface{}); len(tokens) != 0 {
+		errorDie(fmt.Errorf("expected disabled user tokens to be revoked, got %d", len(tokens)), t)
+	}
+
+	data = agentAdminQuery(t, "", "mutation { Login(username: \"managed\", password: \""+password+"\") { Value }}", nil)
+	if !agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected disabled user to be unable to login"), t)
+	}
+
+	data = agentAdminQuery(t, adminToken, "mutation { EnableUser(username: \"managed\") }", nil)
+	if agentAdminHasError(data) {
+		errorDie(fmt.Errorf("error enabling user: %v", data["errors"]), t)
+	}
+
+	_ = agentAdminLogin(t, "managed", password)
+	// endregion
+	// region Delete
+	data = agentAdminQuery(t, adminToken, "mutation { DeleteUser(username: \"managed\") }", nil)
+	if agentAdminHasError(data) {
+		errorDie(fmt.Errorf("error deleting user: %v", data["errors"]), t)
+	}
+
+	data = agentAdminQuery(t, adminToken, "query { User(username: \"managed\") { FullName }}", nil)
+	if !agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected deleted user to not exist"), t)
+	}
+	// endregion
+}
+
+func TestAdminRoles(t *testing.T) {
+	adminToken := agentAdminLogin(t, "admin", "admin")
+
+	data := agentAdminQuery(t, adminToken, "query { User(username: \"admin\") { Roles }}", nil)
+	roles := data["data"].(map[string]interface{})["User"].(map[string]interface{})["Roles"].([]interface{})
+	if len(roles) == 0 || roles[len(roles)-1] != models.RoleAdmin {
+		errorDie(fmt.Errorf("expected bootstrapped admin to have the admin role got %v", roles), t)
+	}
+
+	// region Add users with roles
+	data = agentAdminQuery(t, adminToken, "mutation { AddUser(username: \"operator\", fullname: \"Operator\", fingerPrint: \"DEADBEEF\", roles: [operator]) { Password }}", nil)
+	if agentAdminHasError(data) {
+		errorDie(fmt.Errorf("error adding operator: %v", data["errors"]), t)
+	}
+	operatorPassword := data["data"].(map[string]interface{})["AddUser"].(map[string]interface{})["Password"].(string)
+
+	data = agentAdminQuery(t, adminToken, "mutation { AddUser(username: \"signer\", fullname: \"Signer\") { Password }}", nil)
+	if agentAdminHasError(data) {
+		errorDie(fmt.Errorf("error adding signer: %v", data["errors"]), t)
+	}
+	signerPassword := data["data"].(map[string]interface{})["AddUser"].(map[string]interface{})["Password"].(string)
+
+	defer func() {
+		agentAdminQuery(t, adminToken, "mutation { DeleteUser(username: \"operator\") }", nil)
+		agentAdminQuery(t, adminToken, "mutation { DeleteUser(username: \"signer\") }", nil)
+	}()
+
+	data = agentAdminQuery(t, adminToken, "query { User(username: \"signer\") { Roles }}", nil)
+	if roles := data["data"].(map[string]interface{})["User"].(map[string]interface{})["Roles"].([]interface{}); len(roles) != 1 || roles[0] != models.RoleSigner {
+		errorDie(fmt.Errorf("expected new user to have the signer role got %v", roles), t)
+	}
+	// endregion
+	// region Operator
+	operatorToken := agentAdminLogin(t, "operator", operatorPassword)
+
+	data = agentAdminQuery(t, operatorToken, "query { Users { Username }}", nil)
+	if agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected operator to list users: %v", data["errors"]), t)
+	}
+
+	data = agentAdminQuery(t, operatorToken, "mutation { GenerateToken(fingerPrint: \"BEEFDEAD\") { Value }}", nil)
+	if !agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected operator to be denied generating tokens for other keys"), t)
+	}
+
+	data = agentAdminQuery(t, operatorToken, "mutation { GenerateToken { Value }}", nil)
+	if agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected operator to generate tokens for its own key: %v", data["errors"]), t)
+	}
+
+	for _, username := range []string{"admin", "operator", "signer"} {
+		data = agentAdminQuery(t, operatorToken, "mutation { GenerateToken(username: \""+username+"\") { Value }}", nil)
+		if !agentAdminHasError(data) {
+			errorDie(fmt.Errorf("expected operator to be denied generating tokens for the existing user %s", username), t)
+		}
+	}
+
+	data = agentAdminQuery(t, operatorToken, "mutation { GenerateToken(username: \"future\") { Value }}", nil)
+	if agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected operator to generate tokens for new usernames: %v", data["errors"]), t)
+	}
+	futureToken := data["data"].(map[string]interface{})["GenerateToken"].(map[string]interface{})["Value"].(string)
+
+	data = agentAdminQuery(t, adminToken, "mutation { AddUser(username: \"future\", fullname: \"Future\", roles: [admin]) { Password }}", nil)
+	if agentAdminHasError(data) {
+		errorDie(fmt.Errorf("error adding future: %v", data["errors"]), t)
+	}
+	defer agentAdminQuery(t, adminToken, "mutation { DeleteUser(username: \"future\") }", nil)
+
+	req, err := http.NewRequest("POST", "/agentAdmin", bytes.NewReader([]byte(`{"query": "query { Users { Username }}"}`)))
+	errorDie(err, t)
+	req.Header.Add("proxyToken", futureToken)
+
+	if res := executeRequest(req); res.Code == 200 {
+		errorDie(fmt.Errorf("expected tokens generated before the user was added to be revoked"), t)
+	}
+
+	data = agentAdminQuery(t, operatorToken, "mutation { AddUser(username: \"other\", fullname: \"Other\") { Password }}", nil)
+	if !agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected operator to be denied adding users"), t)
+	}
+
+	data = agentAdminQuery(t, operatorToken, "mutation { GrantRole(username: \"operator\", role: admin) { Roles }}", nil)
+	if !agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected operator to be denied granting roles"), t)
+	}
+	// endregion
+	// region Signer
+	signerToken := agentAdminLogin(t, "signer", signerPassword)
+
+	data = agentAdminQuery(t, signerToken, "query { WhoAmI }", nil)
+	if agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected signer to use WhoAmI: %v", data["errors"]), t)
+	}
+
+	data = agentAdminQuery(t, signerToken, "mutation { GenerateToken { Value }}", nil)
+	if !agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected signer to be denied generating tokens"), t)
+	}
+
+	data = agentAdminQuery(t, signerToken, "mutation { InvalidateToken(token: \""+operatorToken+"\") }", nil)
+	if !agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected signer to be denied invalidating other user tokens"), t)
+	}
+	// endregion
+	// region Grant / Revoke
+	data = agentAdminQuery(t, adminToken, "mutation { GrantRole(username: \"signer\", role: operator) { Roles }}", nil)
+	if roles := data["data"].(map[string]interface{})["GrantRole"].(map[string]interface{})["Roles"].([]interface{}); len(roles) != 2 {
+		errorDie(fmt.Errorf("expected signer to have 2 roles got %v", roles), t)
+	}
+
+	data = agentAdminQuery(t, signerToken, "query { Users { Username }}", nil)
+	if agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected granted role to apply to existing tokens: %v", data["errors"]), t)
+	}
+
+	data = agentAdminQuery(t, adminToken, "mutation { RevokeRole(username: \"signer\", role: operator) { Roles }}", nil)
+	if roles := data["data"].(map[string]interface{})["RevokeRole"].(map[string]interface{})["Roles"].([]interface{}); len(roles) != 1 {
+		errorDie(fmt.Errorf("expected signer to have 1 role got %v", roles), t)
+	}
+
+	data = agentAdminQuery(t, signerToken, "query { Users { Username }}", nil)
+	if !agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected revoked role to apply to existing tokens"), t)
+	}
+
+	data = agentAdminQuery(t, adminToken, "mutation { RevokeRole(username: \"admin\", role: admin) { Roles }}", nil)
+	if !agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected admin to be unable to revoke its own admin role"), t)
+	}
+
+	data = agentAdminQuery(t, signerToken, "mutation { InvalidateToken(token: \""+signerToken+"\") }", nil)
+	if agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected signer to invalidate its own token: %v", data["errors"]), t)
+	}
+	// endregion
+}
+
+func TestAdminTOTP(t *testing.T) {
+	adminToken := agentAdminLogin(t, "admin", "admin")
+
+	data := agentAdminQuery(t, adminToken, "mutation { AddUser(username: \"totpuser\", fullname: \"TOTP User\") { Password }}", nil)
+	if agentAdminHasError(data) {
+		errorDie(fmt.Errorf("error adding user: %v", data["errors"]), t)
+	}
+	password := data["data"].(map[string]interface{})["AddUser"].(map[string]interface{})["Password"].(string)
+
+	defer agentAdminQuery(t, adminToken, "mutation { DeleteUser(username: \"totpuser\") }", nil)
+
+	userToken := agentAdminLogin(t, "totpuser", password)
+	loginQuery := "mutation Login($password: String!, $otp: String) { Login(username: \"totpuser\", password: $password, otp: $otp) { Value }}"
+
+	// region Enroll
+	data = agentAdminQuery(t, userToken, "mutation { EnrollTOTP { Secret ProvisioningURI }}", nil)
+	if agentAdminHasError(data) {
+		errorDie(fmt.Errorf("error enrolling: %v", data["errors"]), t)
+	}
+	secret := data["data"].(map[string]interface{})["EnrollTOTP"].(map[string]interface{})["Secret"].(string)
+
+	// Not confirmed yet, so login without otp still works
+	_ = agentAdminLogin(t, "totpuser", password)
+
+	data = agentAdminQuery(t, userToken, "mutation { ConfirmTOTP(otp: \"000000\") }", nil)
+	if !agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected invalid otp to not confirm enrollment"), t)
+	}
+
+	// Code of the previous period, so the code of the current one is still unused for the login
+	code, _ := totp.GenerateCode(secret, time.Now().Add(-totp.Period))
+	data = agentAdminQuery(t, userToken, "mutation { ConfirmTOTP(otp: \""+code+"\") }", nil)
+	if agentAdminHasError(data) {
+		errorDie(fmt.Errorf("error confirming enrollment: %v", data["errors"]), t)
+	}
+
+	data = agentAdminQuery(t, adminToken, "query { User(username: \"totpuser\") { TOTPEnabled }}", nil)
+	if enabled := data["data"].(map[string]interface{})["User"].(map[string]interface{})["TOTPEnabled"]; enabled != true {
+		errorDie(fmt.Errorf("expected TOTPEnabled to be true"), t)
+	}
+	// endregion
+	// region Login
+	data = agentAdminQuery(t, "", loginQuery, map[string]interface{}{"password": password})
+	if !agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected login without otp to fail"), t)
+	}
+
+	data = agentAdminQuery(t, "", loginQuery, map[string]interface{}{"password": password, "otp": "000000"})
+	if !agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected login with invalid otp to fail"), t)
+	}
+
+	code, _ = totp.GenerateCode(secret, time.Now())
+	data = agentAdminQuery(t, "", loginQuery, map[string]interface{}{"password": password, "otp": code})
+	if agentAdminHasError(data) {
+		errorDie(fmt.Errorf("error logging in with otp: %v", data["errors"]), t)
+	}
+
+	data = agentAdminQuery(t, "", loginQuery, map[string]interface{}{"password": password, "otp": code})
+	if !agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected login with an already used otp to fail"), t)
+	}
+	// endregion
+	// region Disable
+	data = agentAdminQuery(t, userToken, "mutation { DisableTOTP }", nil)
+	if !agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected disabling own TOTP without otp to fail"), t)
+	}
+
+	data = agentAdminQuery(t, userToken, "mutation { DisableTOTP(username: \"admin\") }", nil)
+	if !agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected non admin to be denied disabling TOTP of other users"), t)
+	}
+
+	data = agentAdminQuery(t, userToken, "mutation { DisableTOTP(otp: \""+code+"\") }", nil)
+	if !agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected disabling TOTP with an already used otp to fail"), t)
+	}
+
+	code, _ = totp.GenerateCode(secret, time.Now().Add(totp.Period))
+	data = agentAdminQuery(t, userToken, "mutation { DisableTOTP(otp: \""+code+"\") }", nil)
+	if agentAdminHasError(data) {
+		errorDie(fmt.Errorf("error disabling TOTP: %v", data["errors"]), t)
+	}
+
+	_ = agentAdminLogin(t, "totpuser", password)
+	// endregion
+}
+
+func TestAdminLegacyRoles(t *testing.T) {
+	db := memory.MakeMemoryDBDriver(log)
+
+	for _, username := range []string{"admin", "legacy"} {
+		_, err := db.AddUser(models.User{Username: username, FullName: username})
+		errorDie(err, t)
+	}
+
+	am := agent.NewDatabaseAuthManager(log, db)
+
+	um, err := db.GetUser("admin")
+	errorDie(err, t)
+	if !um.HasRole(models.RoleAdmin) {
+		errorDie(fmt.Errorf("expected legacy admin to receive the admin role got %v", um.Roles), t)
+	}
+
+	um, err = db.GetUser("legacy")
+	errorDie(err, t)
+	if len(um.Roles) != 0 || !um.HasRole(models.RoleSigner) || um.HasRole(models.RoleAdmin) {
+		errorDie(fmt.Errorf("expected legacy user to be a signer got %v", um.Roles), t)
+	}
+
+	errorDie(am.GrantRole("legacy", models.RoleOperator), t)
+	errorDie(am.RevokeRole("legacy", models.RoleOperator), t)
+
+	um, err = db.GetUser("legacy")
+	errorDie(err, t)
+	if len(um.Roles) != 1 || um.Roles[0] != models.RoleSigner {
+		errorDie(fmt.Errorf("expected user to keep the signer role after revoking the last one got %v", um.Roles), t)
+	}
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/quan-to/chevron/internal/agent"
+	"github.com/quan-to/chevron/internal/config"
+	"github.com/quan-to/chevron/test"
+)
+
+func jwtB64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func makeTestJWT(t *testing.T, key *rsa.PrivateKey, claims map[string]interface{}) string {
+	h, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	c, _ := json.Marshal(claims)
+	signed := jwtB64(h) + "." + jwtB64(c)
+
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	errorDie(err, t)
+
+	return signed + "." + jwtB64(signature)
+}
+
+func jwtClaims(username string, roles ...string) map[string]interface{} {
+	return map[string]interface{}{
+		"iss":                 "https://idp.example.com",
+		"aud":                 "chevron",
+		"sub":                 "id-" + username,
+		"name":                "JWT " + username,
+		"preferred_username":  username,
+		"chevron_fingerprint": test.TestKeyFingerprint,
+		"chevron_roles":       roles,
+		"iat":                 time.Now().Add(-time.Second).Unix(),
+		"exp":                 time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func jwtAdminQuery(t *testing.T, r *mux.Router, token, query string) map[string]interface{} {
+	d, _ := json.Marshal(map[string]interface{}{"query": query})
+	req, err := http.NewRequest("POST", "/agentAdmin", bytes.NewReader(d))
+	errorDie(err, t)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		return map[string]interface{}{"errors": []interface{}{rr.Body.String()}}
+	}
+
+	var data map[string]interface{}
+	err = json.Unmarshal(rr.Body.Bytes(), &data)
+	errorDie(err, t)
+
+	return data
+}
+
+func TestAgentJWTAuthentication(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	errorDie(err, t)
+
+	jwks, _ := json.Marshal(map[string]interface{}{
+		"keys": []map[string]interface{}{
+			{
+				"kty": "RSA",
+				"kid": "test",
+				"use": "sig",
+				"alg": "RS256",
+				"n":   jwtB64(key.N.Bytes()),
+				"e":   jwtB64(big.NewInt(int64(key.E)).Bytes()),
+			},
+		},
+	})
+
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(jwks)
+	}))
+	defer jwksServer.Close()
+
+	var receivedHeaders http.Header
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedHeaders = r.Header.Clone()
+		_, _ = w.Write([]byte("{}"))
+	}))
+	defer targetServer.Close()
+
+	defer config.Apply(config.Current())
+
+	config.AgentJWTAuth = true
+	config.AgentJWTJWKS = jwksServer.URL
+	config.AgentJWTIssuer = "https://idp.example.com"
+	config.AgentJWTAudience = "chevron"
+	config.AgentTargetURL = targetServer.URL
+
+	tm := agent.MakeJWTTokenManager(nil, agent.MakeMemoryTokenManager(nil), dbh)
+	am := agent.MakeAuthManager(nil, dbh)
+
+	r := mux.NewRouter()
+	MakeAgentAdmin(nil, tm, am).AddHandlers(r.PathPrefix("/agentAdmin").Subrouter())
+	MakeAgentProxy(nil, gpg, tm).AddHandlers(r.PathPrefix("/agent").Subrouter())
+
+	signerToken := makeTestJWT(t, key, jwtClaims("jwtsigner"))
+	adminToken := makeTestJWT(t, key, jwtClaims("jwtadmin", "admin"))
+
+	// region agentAdmin
+	data := jwtAdminQuery(t, r, signerToken, "query { WhoAmI }")
+	if agentAdminHasError(data) {
+		errorDie(fmt.Errorf("error running WhoAmI with JWT: %v", data["errors"]), t)
+	}
+
+	if name := data["data"].(map[string]interface{})["WhoAmI"]; name != "JWT jwtsigner" {
+		errorDie(fmt.Errorf("expected WhoAmI to be \"JWT jwtsigner\" got %v", name), t)
+	}
+
+	data = jwtAdminQuery(t, r, signerToken, "query { Users { Username }}")
+	if !agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected JWT without admin role to be denied listing users"), t)
+	}
+
+	data = jwtAdminQuery(t, r, adminToken, "query { Users { Username }}")
+	if agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected JWT with admin role to list users: %v", data["errors"]), t)
+	}
+
+	expiredClaims := jwtClaims("jwtadmin", "admin")
+	expiredClaims["exp"] = time.Now().Add(-time.Hour).Unix()
+	data = jwtAdminQuery(t, r, makeTestJWT(t, key, expiredClaims), "query { WhoAmI }")
+	if !agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected expired JWT to be rejected"), t)
+	}
+
+	otherAudience := jwtClaims("jwtadmin", "admin")
+	otherAudience["aud"] = "other"
+	data = jwtAdminQuery(t, r, makeTestJWT(t, key, otherAudience), "query { WhoAmI }")
+	if !agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected JWT with wrong audience to be rejected"), t)
+	}
+	// endregion
+	// region agent proxy
+	req, err := http.NewRequest("POST", "/agent", bytes.NewReader([]byte(`{"hello":"world"}`)))
+	errorDie(err, t)
+	req.Header.Set("Authorization", "Bearer "+signerToken)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		errorDie(fmt.Errorf("expected 200 from proxy got %d: %s", rr.Code, rr.Body.String()), t)
+	}
+
+	if receivedHeaders.Get("signature") == "" {
+		errorDie(fmt.Errorf("expected target to receive the signature"), t)
+	}
+
+	if receivedHeaders.Get("Authorization") != "" {
+		errorDie(fmt.Errorf("expected the JWT to not be forwarded to the target"), t)
+	}
+
+	noKeyClaims := jwtClaims("jwtnokey")
+	delete(noKeyClaims, "chevron_fingerprint")
+	req, _ = http.NewRequest("POST", "/agent", bytes.NewReader([]byte(`{"hello":"world"}`)))
+	req.Header.Set("Authorization", "Bearer "+makeTestJWT(t, key, noKeyClaims))
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code == http.StatusOK {
+		errorDie(fmt.Errorf("expected JWT without fingerprint to be denied"), t)
+	}
+	// endregion
+	// region Invalidation
+	data = jwtAdminQuery(t, r, signerToken, "mutation { InvalidateToken(token: \""+signerToken+"\") }")
+	if agentAdminHasError(data) {
+		errorDie(fmt.Errorf("error invalidating JWT: %v", data["errors"]), t)
+	}
+
+	data = jwtAdminQuery(t, r, signerToken, "query { WhoAmI }")
+	if !agentAdminHasError(data) {
+		errorDie(fmt.Errorf("expected invalidated JWT to be rejected"), t)
+	}
+
+	// Revocations are stored in the database, so they are seen by other instances
+	otherTm := agent.MakeJWTTokenManager(nil, agent.MakeMemoryTokenManager(nil), dbh)
+	if otherTm.Verify(signerToken) == nil {
+		errorDie(fmt.Errorf("expected invalidated JWT to be rejected by other instances"), t)
+	}
+
+	if otherTm.Verify(adminToken) != nil {
+		errorDie(fmt.Errorf("expected other JWTs to still be valid"), t)
+	}
+
+	_, err = otherTm.RevokeUserTokens("jwtadmin")
+	errorDie(err, t)
+
+	if tm.Verify(adminToken) == nil {
+		errorDie(fmt.Errorf("expected JWTs of revoked user to be rejected by other instances"), t)
+	}
+
+	noIssuedAt := jwtClaims("jwtadmin", "admin")
+	delete(noIssuedAt, "iat")
+	if tm.Verify(makeTestJWT(t, key, noIssuedAt)) == nil {
+		errorDie(fmt.Errorf("expected JWTs without iat of revoked user to be rejected"), t)
+	}
+	// endregion
+}
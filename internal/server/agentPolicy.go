@@ -0,0 +1,346 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/quan-to/chevron/internal/config"
+)
+
+// alwaysDeniedHeaders are never forwarded to the target. They are either hop-by-hop or used by the agent itself
+var alwaysDeniedHeaders = []string{
+	"proxyToken",
+	"serverUrl",
+	"signMode",
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+	"Host",
+	"Content-Length",
+}
+
+// idempotentMethods are the methods that can be retried
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+// targetRule is an allowed target in the format [scheme://]host[:port][/path]
+type targetRule struct {
+	scheme     string
+	host       string
+	port       string
+	wildcard   bool
+	pathPrefix string
+}
+
+func parseTargetRule(rule string) (targetRule, error) {
+	rule = strings.TrimSpace(rule)
+	if !strings.Contains(rule, "://") {
+		rule = "any://" + rule
+	}
+
+	u, err := url.Parse(rule)
+	if err != nil {
+		return targetRule{}, fmt.Errorf("invalid target %q: %v", rule, err)
+	}
+
+	if u.Hostname() == "" {
+		return targetRule{}, fmt.Errorf("invalid target %q: no host", rule)
+	}
+
+	tr := targetRule{
+		host:       strings.ToLower(u.Hostname()),
+		port:       u.Port(),
+		pathPrefix: strings.TrimSuffix(path.Clean("/"+u.Path), "/"),
+	}
+
+	if u.Scheme != "any" {
+		tr.scheme = strings.ToLower(u.Scheme)
+	}
+
+	if strings.HasPrefix(tr.host, "*.") {
+		tr.wildcard = true
+		tr.host = tr.host[1:]
+	}
+
+	return tr, nil
+}
+
+func (tr targetRule) matches(u *url.URL) bool {
+	if tr.scheme != "" && tr.scheme != strings.ToLower(u.Scheme) {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if tr.wildcard {
+		if !strings.HasSuffix(host, tr.host) {
+			return false
+		}
+	} else if host != tr.host {
+		return false
+	}
+
+	if tr.port != "" && tr.port != targetPort(u) {
+		return false
+	}
+
+	p, ok := targetPath(u)
+	if !ok {
+		return false
+	}
+
+	if tr.pathPrefix == "" {
+		return true
+	}
+
+	return p == tr.pathPrefix || strings.HasPrefix(p, tr.pathPrefix+"/")
+}
+
+// targetPath returns the unescaped and cleaned path of the url
+// Paths with dot segments are rejected, since the target could resolve them outside of the allowed path
+func targetPath(u *url.URL) (string, bool) {
+	p, err := url.PathUnescape(u.EscapedPath())
+	if err != nil {
+		return "", false
+	}
+
+	for _, segment := range strings.Split(p, "/") {
+		if segment == "." || segment == ".." {
+			return "", false
+		}
+	}
+
+	return path.Clean("/" + p), true
+}
+
+func targetPort(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Port()
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "http":
+		return "80"
+	case "https":
+		return "443"
+	}
+
+	return ""
+}
+
+func splitList(list string) []string {
+	res := make([]string, 0)
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			res = append(res, item)
+		}
+	}
+	return res
+}
+
+// responseKeyRule is the key expected to sign the responses of the matching targets
+type responseKeyRule struct {
+	target      targetRule
+	fingerPrint string
+}
+
+// agentProxyPolicy restricts where and what the agent proxy sends
+type agentProxyPolicy struct {
+	targets         []targetRule
+	headerAllow     map[string]bool
+	headerDeny      map[string]bool
+	maxBodySize     int64
+	timeout         time.Duration
+	retries         int
+	retryBackoff    time.Duration
+	responseKeys    []responseKeyRule
+	responseKey     string
+	strictResponses bool
+}
+
+// makeAgentProxyPolicy creates the agent proxy policy from the current configuration
+// If no allowed targets are configured, only the host of AGENT_TARGET_URL is allowed
+func makeAgentProxyPolicy() (*agentProxyPolicy, error) {
+	p := &agentProxyPolicy{
+		headerAllow:     map[string]bool{},
+		headerDeny:      map[string]bool{},
+		maxBodySize:     config.AgentMaxBodySize,
+		timeout:         config.AgentRequestTimeout,
+		retries:         config.AgentRetries,
+		retryBackoff:    config.AgentRetryBackoff,
+		responseKey:     strings.TrimSpace(config.AgentResponseFingerprint),
+		strictResponses: config.AgentResponseStrict,
+	}
+
+	targets := splitList(config.AgentAllowedTargets)
+	if len(targets) == 0 {
+		u, err := url.Parse(config.AgentTargetURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid AGENT_TARGET_URL: %v", err)
+		}
+		targets = []string{u.Scheme + "://" + u.Host}
+	}
+
+	for _, target := range targets {
+		tr, err := parseTargetRule(target)
+		if err != nil {
+			return nil, err
+		}
+		p.targets = append(p.targets, tr)
+	}
+
+	for _, item := range splitList(config.AgentResponseFingerprints) {
+		i := strings.LastIndex(item, "=")
+		if i <= 0 || strings.TrimSpace(item[i+1:]) == "" {
+			return nil, fmt.Errorf("invalid response fingerprint %q. Expected target=fingerprint", item)
+		}
+
+		tr, err := parseTargetRule(item[:i])
+		if err != nil {
+			return nil, err
+		}
+
+		p.responseKeys = append(p.responseKeys, responseKeyRule{
+			target:      tr,
+			fingerPrint: strings.TrimSpace(item[i+1:]),
+		})
+	}
+
+	for _, h := range splitList(config.AgentHeaderAllowList) {
+		p.headerAllow[http.CanonicalHeaderKey(h)] = true
+	}
+
+	for _, h := range append(splitList(config.AgentHeaderDenyList), alwaysDeniedHeaders...) {
+		p.headerDeny[http.CanonicalHeaderKey(h)] = true
+	}
+
+	return p, nil
+}
+
+// responseFingerPrint returns the key expected to sign the responses of the target or empty if they're not verified
+func (p *agentProxyPolicy) responseFingerPrint(u *url.URL) string {
+	for _, rule := range p.responseKeys {
+		if rule.target.matches(u) {
+			return rule.fingerPrint
+		}
+	}
+
+	return p.responseKey
+}
+
+// checkTarget returns an error if the target is not allowed
+func (p *agentProxyPolicy) checkTarget(target string) (*url.URL, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target url: %v", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid target scheme %q", u.Scheme)
+	}
+
+	if u.User != nil {
+		return nil, fmt.Errorf("target url cannot contain credentials")
+	}
+
+	for _, tr := range p.targets {
+		if tr.matches(u) {
+			return u, nil
+		}
+	}
+
+	return nil, fmt.Errorf("target %s is not allowed", u.Host)
+}
+
+// forwardHeader returns true if the header can be forwarded to the target
+func (p *agentProxyPolicy) forwardHeader(name string) bool {
+	name = http.CanonicalHeaderKey(name)
+
+	if p.headerDeny[name] {
+		return false
+	}
+
+	return len(p.headerAllow) == 0 || p.headerAllow[name]
+}
+
+// canRetry returns true if a request with the method can be retried after the response status or error
+func (p *agentProxyPolicy) canRetry(method string, attempt int, res *http.Response, err error) bool {
+	if attempt >= p.retries || !idempotentMethods[method] {
+		return false
+	}
+
+	if err != nil {
+		return true
+	}
+
+	switch res.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// backoff returns the delay before the specified retry attempt
+func (p *agentProxyPolicy) backoff(attempt int) time.Duration {
+	return p.retryBackoff * time.Duration(1<<uint(attempt))
+}
+
+// makeAgentTransport creates the transport used to send requests to the targets with the configured TLS settings
+func makeAgentTransport() (*http.Transport, error) {
+	tlsConfig := &tls.Config{}
+
+	if config.AgentTLSCAFile != "" {
+		data, err := ioutil.ReadFile(config.AgentTLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading AGENT_TLS_CA_FILE: %v", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in AGENT_TLS_CA_FILE")
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if config.AgentTLSClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(config.AgentTLSClientCert, config.AgentTLSClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("error loading agent client certificate: %v", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSClientConfig:     tlsConfig,
+		TLSHandshakeTimeout: 10 * time.Second,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     30 * time.Second,
+	}, nil
+}
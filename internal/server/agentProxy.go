@@ -2,26 +2,29 @@ package server
 
 import (
 	"bytes"
-	"crypto"
-	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/quan-to/chevron/internal/config"
-	"github.com/quan-to/chevron/internal/tools"
+	"github.com/quan-to/chevron/pkg/QuantoError"
 	"github.com/quan-to/chevron/pkg/interfaces"
 	"github.com/quan-to/chevron/pkg/uuid"
 	"github.com/quan-to/slog"
 )
 
 type AgentProxy struct {
-	gpg       interfaces.PGPManager
-	transport *http.Transport
-	tm        interfaces.TokenManager
-	log       slog.Instance
+	gpg           interfaces.PGPManager
+	transport     *http.Transport
+	policy        *agentProxyPolicy
+	signMode      string
+	signedHeaders []string
+	tm            interfaces.TokenManager
+	log           slog.Instance
 }
 
 // MakeAgentProxy creates an instance of agent proxy endpoint
@@ -32,15 +35,50 @@ func MakeAgentProxy(log slog.Instance, gpg interfaces.PGPManager, tm interfaces.
 		log = log.SubScope("Agent")
 	}
 
+	policy, err := makeAgentProxyPolicy()
+	if err != nil {
+		log.Error("Error creating agent proxy policy: %s", err)
+		return nil
+	}
+
+	signMode, err := parseSignMode(config.AgentSignMode)
+	if err != nil {
+		log.Error("Error creating agent proxy: %s", err)
+		return nil
+	}
+
+	transport, err := makeAgentTransport()
+	if err != nil {
+		log.Error("Error creating agent proxy transport: %s", err)
+		return nil
+	}
+
 	return &AgentProxy{
-		gpg: gpg,
-		transport: &http.Transport{
-			MaxIdleConns:    10,
-			IdleConnTimeout: 30 * time.Second,
-		},
-		tm:  tm,
-		log: log,
+		gpg:           gpg,
+		transport:     transport,
+		policy:        policy,
+		signMode:      signMode,
+		signedHeaders: splitList(config.AgentSignedHeaders),
+		tm:            tm,
+		log:           log,
+	}
+}
+
+// popProxyToken returns the token from the proxyToken header or from a bearer Authorization header, removing it from the headers
+func popProxyToken(h http.Header) string {
+	token := h.Get("proxyToken")
+	if token != "" {
+		h.Del("proxyToken")
+		return token
+	}
+
+	auth := h.Get("Authorization")
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		h.Del("Authorization")
+		return strings.TrimSpace(auth[7:])
 	}
+
+	return ""
 }
 
 func injectUniquenessFields(log slog.Instance, json map[string]interface{}) error {
@@ -60,14 +98,16 @@ func injectUniquenessFields(log slog.Instance, json map[string]interface{}) erro
 // @Accept json
 // @Produce json
 // @param proxyToken header string false "Proxy Token generated with agentAdmin. It is required if running with authentication enabled"
+// @param Authorization header string false "Bearer JWT issued by the identity provider. Alternative to proxyToken when JWT authentication is enabled"
 // @param serverUrl header string false "Target server URL. Defaults to environment variable AGENT_TARGET_URL"
-// @param message body string true "POST Content to send signed to the target server. The message body will be sent to the target server with it's signature in a header field named 'signature'."
+// @param signMode header string false "Signing mode (inject, detached or auto). Defaults to environment variable AGENT_SIGN_MODE"
+// @param message body string true "Content to send signed to the target server. The message body will be sent to the target server with it's signature in a header field named 'signature'."
 // @Success 200 {string} result "result of the query"
+// @Header 200 {string} X-Agent-Signature-Status "Result of the target response signature verification, if enabled (verified, missing, invalid or untrusted)"
 // @Failure default {object} QuantoError.ErrorObject
 // @Router /agent [post]
 func (proxy *AgentProxy) defaultHandler(w http.ResponseWriter, r *http.Request) {
 	var res *http.Response
-	var req *http.Request
 	var err error
 
 	ctx := wrapContextWithRequestID(r)
@@ -92,31 +132,41 @@ func (proxy *AgentProxy) defaultHandler(w http.ResponseWriter, r *http.Request)
 		"targetURL": targetURL,
 	})
 
+	target, err := proxy.policy.checkTarget(targetURL)
+	if err != nil {
+		log.Warn("Rejected target: %s", err)
+		PermissionDenied("serverUrl", err.Error(), w, r, log)
+		return
+	}
+
 	client := &http.Client{
 		Transport: proxy.transport,
+		Timeout:   proxy.policy.timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			_, err := proxy.policy.checkTarget(req.URL.String())
+			return err
+		},
 	}
 
-	if r.Method == http.MethodOptions {
-		req, err = http.NewRequest(r.Method, targetURL, nil)
-
-		if err != nil {
-			InternalServerError("There was an error processing your request", err.Error(), w, r, log)
-			return
-		}
+	var bodyData []byte
+	outHeader := http.Header{}
 
-		req.Header.Add("X-Powered-By", "RemoteSigner Agent")
+	if r.Method == http.MethodOptions {
+		proxy.forwardHeaders(r.Header, outHeader)
+		outHeader.Set("X-Powered-By", "RemoteSigner Agent")
 	} else {
 		token := ""
 
 		if !config.AgentBypassLogin {
-			if h.Get("proxyToken") == "" {
+			token = popProxyToken(h)
+			if token == "" {
 				PermissionDenied("proxyToken", "Please check if your proxyToken is valid", w, r, log)
 				return
 			}
 
-			token = h.Get("proxyToken")
-			h.Del("proxyToken")
-
 			log.Await("Verifying user token")
 			err = proxy.tm.Verify(token)
 			log.Done("Token verified")
@@ -132,10 +182,15 @@ func (proxy *AgentProxy) defaultHandler(w http.ResponseWriter, r *http.Request)
 		if !config.AgentBypassLogin {
 			user := proxy.tm.GetUserData(token)
 			fingerPrint = user.GetFingerPrint()
+
+			if fingerPrint == "" {
+				PermissionDenied("proxyToken", "There is no key associated with your token", w, r, log)
+				return
+			}
 		}
 
 		log.DebugAwait("Reading body")
-		bodyData, err := ioutil.ReadAll(r.Body)
+		bodyData, err = ioutil.ReadAll(io.LimitReader(r.Body, proxy.policy.maxBodySize+1))
 		log.DebugDone("Body read")
 
 		if err != nil {
@@ -143,64 +198,95 @@ func (proxy *AgentProxy) defaultHandler(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
-		var jsondata map[string]interface{}
-
-		err = json.Unmarshal(bodyData, &jsondata)
-
-		if err != nil {
-			InternalServerError("There was an error processing your request", err.Error(), w, r, log)
+		if int64(len(bodyData)) > proxy.policy.maxBodySize {
+			e := QuantoError.New(QuantoError.OperationLimitExceeded, "body", fmt.Sprintf("The request body exceeds the maximum size of %d bytes", proxy.policy.maxBodySize), nil)
+			WriteJSON(e, http.StatusRequestEntityTooLarge, w, r, log)
 			return
 		}
 
-		err = injectUniquenessFields(log, jsondata)
-
-		if err != nil {
-			InternalServerError("There was an error ensuring request uniqueness", err.Error(), w, r, log)
-			return
+		signMode := proxy.signMode
+		if h.Get("signMode") != "" {
+			signMode, err = parseSignMode(h.Get("signMode"))
+			if err != nil {
+				InvalidFieldData("signMode", err.Error(), w, r, log)
+				return
+			}
 		}
 
-		bodyData, _ = json.Marshal(jsondata)
-
-		req, err = http.NewRequest(r.Method, targetURL, bytes.NewBuffer(bodyData))
+		if signMode == signModeAuto {
+			signMode = signModeDetached
+			if isJSONObject(bodyData) {
+				signMode = signModeInject
+			}
+		}
 
-		if err != nil {
-			InternalServerError("There was an error processing your request", err.Error(), w, r, log)
+		if signMode == signModeInject && !isJSONObject(bodyData) {
+			InvalidFieldData("body", "The body must be a JSON object to inject the uniqueness fields. Use the detached sign mode for other payloads", w, r, log)
 			return
 		}
 
-		log.Await("Signing data with %s", fingerPrint)
-		signature, err := proxy.gpg.SignData(ctx, fingerPrint, bodyData, crypto.SHA512)
-		log.Done("Data signed")
+		proxy.forwardHeaders(r.Header, outHeader)
+
+		if signMode == signModeDetached {
+			err = proxy.signDetached(ctx, log, fingerPrint, r.Method, target, bodyData, outHeader)
+		} else {
+			bodyData, err = proxy.signInjected(ctx, log, fingerPrint, bodyData, outHeader)
+		}
 
 		if err != nil {
 			InternalServerError("There was an error signing your request", err.Error(), w, r, log)
 			return
 		}
 
-		quantoSig := tools.GPG2Quanto(signature, fingerPrint, "SHA512")
-
-		req.Header.Add("signature", quantoSig)
-		req.Header.Add("X-Powered-By", "RemoteSigner Agent")
-	}
-
-	for k, v := range r.Header {
-		if len(v) > 1 {
-			for _, t := range v {
-				req.Header.Add(k, t)
-			}
-		} else {
-			req.Header.Set(k, v[0])
-		}
+		outHeader.Set("X-Powered-By", "RemoteSigner Agent")
 	}
 
 	log.Await("Sending request to %s", targetURL)
-	res, err = client.Do(req)
+	res, err = proxy.send(log, client, r.Method, targetURL, bodyData, outHeader)
 	log.Done("Received response")
 
 	if err != nil {
-		InternalServerError("There was an error processing your request", err.Error(), w, r, log)
+		e := QuantoError.New(QuantoError.TargetConnectionError, "serverUrl", "There was an error sending your request to the target", err.Error())
+		WriteJSON(e, http.StatusBadGateway, w, r, log)
 		return
 	}
+	defer res.Body.Close()
+
+	var resBody io.Reader = res.Body
+	status := ""
+
+	if expected := proxy.policy.responseFingerPrint(res.Request.URL); expected != "" {
+		var resData []byte
+		resData, err = ioutil.ReadAll(io.LimitReader(res.Body, proxy.policy.maxBodySize+1))
+		if err != nil {
+			e := QuantoError.New(QuantoError.TargetConnectionError, "serverUrl", "There was an error reading the target response", err.Error())
+			WriteJSON(e, http.StatusBadGateway, w, r, log)
+			return
+		}
+
+		if int64(len(resData)) > proxy.policy.maxBodySize {
+			status, err = responseInvalid, fmt.Errorf("the response exceeds the maximum size of %d bytes", proxy.policy.maxBodySize)
+			resBody = io.MultiReader(bytes.NewReader(resData), res.Body)
+		} else {
+			status, err = proxy.verifyResponse(ctx, log, expected, res.Header.Get("signature"), resData)
+			resBody = bytes.NewReader(resData)
+		}
+
+		if err != nil {
+			log.Warn("Response signature %s: %s", status, err)
+
+			if proxy.policy.strictResponses {
+				e := QuantoError.New(QuantoError.InvalidFieldData, "signature", "The target response signature could not be verified", map[string]interface{}{
+					"status":       status,
+					"targetStatus": res.StatusCode,
+					"reason":       err.Error(),
+				})
+				w.Header().Set(responseStatusHeader, status)
+				WriteJSON(e, http.StatusBadGateway, w, r, log)
+				return
+			}
+		}
+	}
 
 	for k, v := range res.Header {
 		if len(v) > 1 {
@@ -212,11 +298,66 @@ func (proxy *AgentProxy) defaultHandler(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
+	// The status header can only be set by the agent
+	w.Header().Del(responseStatusHeader)
+	if status != "" {
+		w.Header().Set(responseStatusHeader, status)
+	}
+
 	log.Info("Sending response")
-	n, _ := io.Copy(w, res.Body)
+	w.WriteHeader(res.StatusCode)
+	n, _ := io.Copy(w, resBody)
 	LogExit(log, r, res.StatusCode, int(n))
 }
 
+// forwardHeaders copies the headers allowed by the policy, except the ones set by the agent
+func (proxy *AgentProxy) forwardHeaders(in, out http.Header) {
+	for k, v := range in {
+		if !proxy.policy.forwardHeader(k) {
+			continue
+		}
+
+		for _, t := range v {
+			out.Add(k, t)
+		}
+	}
+
+	for _, k := range agentSignatureHeaders {
+		out.Del(k)
+	}
+}
+
+// send sends the request to the target retrying idempotent requests as configured in the policy
+func (proxy *AgentProxy) send(log slog.Instance, client *http.Client, method, targetURL string, body []byte, header http.Header) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		var bodyReader io.Reader
+		if body != nil {
+			bodyReader = bytes.NewReader(body)
+		}
+
+		req, err := http.NewRequest(method, targetURL, bodyReader)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header = header.Clone()
+
+		res, err := client.Do(req)
+		if !proxy.policy.canRetry(method, attempt, res, err) {
+			return res, err
+		}
+
+		if res != nil {
+			_, _ = io.Copy(ioutil.Discard, res.Body)
+			_ = res.Body.Close()
+		}
+
+		wait := proxy.policy.backoff(attempt)
+		log.Warn("Request to %s failed on attempt %d. Retrying in %s", targetURL, attempt+1, wait)
+		time.Sleep(wait)
+	}
+}
+
 func (proxy *AgentProxy) AddHandlers(r *mux.Router) {
 	r.HandleFunc("/", proxy.defaultHandler)
 	r.HandleFunc("", proxy.defaultHandler)
@@ -2,13 +2,28 @@ package server
 
 import (
 	"bytes"
+	"context"
+	"crypto"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/gorilla/mux"
+	"github.com/quan-to/chevron/internal/agent"
+	"github.com/quan-to/chevron/internal/config"
+	"github.com/quan-to/chevron/internal/tools"
 	"github.com/quan-to/chevron/pkg/QuantoError"
+	"github.com/quan-to/chevron/pkg/models"
+	"github.com/quan-to/chevron/pkg/openpgp"
+	"github.com/quan-to/chevron/pkg/openpgp/armor"
+	"github.com/quan-to/chevron/test"
 )
 
 func TestProxy(t *testing.T) {
@@ -62,3 +77,410 @@ func TestProxy(t *testing.T) {
 	//remote_signer.PopVariables()
 	// endregion
 }
+
+func TestProxyPolicy(t *testing.T) {
+	var attempts int32
+	var receivedHeaders http.Header
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/flaky" && atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "https://evil.example.com/", http.StatusFound)
+			return
+		}
+
+		receivedHeaders = r.Header.Clone()
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("{}"))
+	}))
+	defer target.Close()
+
+	defer config.Apply(config.Current())
+
+	config.AgentBypassLogin = false
+	config.AgentAllowedTargets = target.URL
+	config.AgentHeaderDenyList = "X-Internal"
+	config.AgentMaxBodySize = 64
+	config.AgentRetries = 2
+	config.AgentRetryBackoff = time.Millisecond
+
+	tm := agent.MakeMemoryTokenManager(nil)
+	token := tm.AddUser(&models.BasicUser{
+		Username:    "proxytest",
+		FullName:    "Proxy Test",
+		FingerPrint: test.TestKeyFingerprint,
+		CreatedAt:   time.Now(),
+	})
+
+	proxy := MakeAgentProxy(nil, gpg, tm)
+	if proxy == nil {
+		errorDie(fmt.Errorf("expected agent proxy to be created"), t)
+	}
+
+	r := mux.NewRouter()
+	proxy.AddHandlers(r.PathPrefix("/agent").Subrouter())
+
+	send := func(method, serverURL, body string, headers map[string]string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, "/agent", strings.NewReader(body))
+		errorDie(err, t)
+		req.Header.Set("proxyToken", token)
+		req.Header.Set("serverUrl", serverURL)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// region Allowed target
+	rr := send(http.MethodPost, target.URL+"/ok", `{"a":1}`, map[string]string{
+		"X-Internal":   "secret",
+		"X-Request-Id": "abc",
+		"Signature":    "forged",
+	})
+
+	if rr.Code != http.StatusCreated {
+		errorDie(fmt.Errorf("expected target status 201 got %d: %s", rr.Code, rr.Body.String()), t)
+	}
+
+	if receivedHeaders.Get("X-Internal") != "" || receivedHeaders.Get("serverUrl") != "" || receivedHeaders.Get("proxyToken") != "" {
+		errorDie(fmt.Errorf("expected denied headers to not be forwarded: %v", receivedHeaders), t)
+	}
+
+	if receivedHeaders.Get("X-Request-Id") != "abc" {
+		errorDie(fmt.Errorf("expected X-Request-Id to be forwarded"), t)
+	}
+
+	if sig := receivedHeaders.Get("Signature"); sig == "" || sig == "forged" {
+		errorDie(fmt.Errorf("expected agent signature to not be overridden by the caller"), t)
+	}
+	// endregion
+	// region Denied target
+	rr = send(http.MethodPost, "https://evil.example.com/", `{"a":1}`, nil)
+	if rr.Code == http.StatusOK || !strings.Contains(rr.Body.String(), QuantoError.PermissionDenied) {
+		errorDie(fmt.Errorf("expected denied target to fail with permission denied got %d: %s", rr.Code, rr.Body.String()), t)
+	}
+
+	rr = send(http.MethodPost, target.URL+"/redirect", `{"a":1}`, nil)
+	if rr.Code != http.StatusBadGateway {
+		errorDie(fmt.Errorf("expected redirect to denied target to fail got %d", rr.Code), t)
+	}
+	// endregion
+	// region Body size
+	rr = send(http.MethodPost, target.URL+"/ok", `{"a":"`+strings.Repeat("x", 100)+`"}`, nil)
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		errorDie(fmt.Errorf("expected 413 for large body got %d", rr.Code), t)
+	}
+	// endregion
+	// region Retries
+	rr = send(http.MethodPut, target.URL+"/flaky", `{"a":1}`, nil)
+	if rr.Code != http.StatusCreated || atomic.LoadInt32(&attempts) != 3 {
+		errorDie(fmt.Errorf("expected PUT to succeed after 3 attempts got %d after %d", rr.Code, attempts), t)
+	}
+
+	atomic.StoreInt32(&attempts, 0)
+	rr = send(http.MethodPost, target.URL+"/flaky", `{"a":1}`, nil)
+	if rr.Code != http.StatusServiceUnavailable || atomic.LoadInt32(&attempts) != 1 {
+		errorDie(fmt.Errorf("expected POST to not be retried got %d after %d", rr.Code, attempts), t)
+	}
+	// endregion
+}
+
+func TestProxySigningModes(t *testing.T) {
+	type received struct {
+		method string
+		header http.Header
+		body   []byte
+		valid  bool
+	}
+
+	var last received
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		last = received{method: r.Method, header: r.Header.Clone(), body: body}
+
+		signed := body
+		if r.Header.Get("X-Signature-Mode") == "detached" {
+			timestamp, _ := strconv.ParseInt(r.Header.Get("X-Signature-Timestamp"), 10, 64)
+			headers := strings.Split(r.Header.Get("X-Signature-Headers"), ";")
+			signed = canonicalRequest(r.Method, r.URL, timestamp, r.Header.Get("X-Signature-Nonce"), headers, r.Header, body)
+		}
+
+		last.valid, _ = gpg.VerifySignature(context.Background(), signed, tools.Quanto2GPG(r.Header.Get("signature")))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	defer config.Apply(config.Current())
+
+	config.AgentBypassLogin = true
+	config.AgentKeyFingerPrint = test.TestKeyFingerprint
+	config.AgentAllowedTargets = target.URL
+	config.AgentSignMode = "auto"
+	config.AgentSignedHeaders = "Content-Type, X-Request-Id"
+
+	proxy := MakeAgentProxy(nil, gpg, nil)
+	if proxy == nil {
+		errorDie(fmt.Errorf("expected agent proxy to be created"), t)
+	}
+
+	r := mux.NewRouter()
+	proxy.AddHandlers(r.PathPrefix("/agent").Subrouter())
+
+	send := func(method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, "/agent", strings.NewReader(body))
+		errorDie(err, t)
+		req.Header.Set("serverUrl", target.URL+path)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// region Auto mode with JSON object injects the fields
+	rr := send(http.MethodPost, "/payments", `{"amount":10}`, map[string]string{"Content-Type": "application/json"})
+	if rr.Code != http.StatusOK || !last.valid {
+		errorDie(fmt.Errorf("expected injected signature to be valid got %d", rr.Code), t)
+	}
+
+	if !bytes.Contains(last.body, []byte("_timeUniqueId")) || last.header.Get("X-Signature-Mode") != "" {
+		errorDie(fmt.Errorf("expected uniqueness fields to be injected in body"), t)
+	}
+	// endregion
+	// region Auto mode with other payloads uses detached signatures
+	payloads := []struct {
+		method      string
+		path        string
+		body        string
+		contentType string
+	}{
+		{http.MethodPost, "/batch", `[{"amount":10},{"amount":20}]`, "application/json"},
+		{http.MethodPost, "/form", "amount=10&currency=BRL", "application/x-www-form-urlencoded"},
+		{http.MethodPut, "/xml", "<amount>10</amount>", "application/xml"},
+		{http.MethodGet, "/payments?id=1&status=paid", "", ""},
+		{http.MethodDelete, "/payments/1", "", ""},
+	}
+
+	for _, p := range payloads {
+		rr = send(p.method, p.path, p.body, map[string]string{"Content-Type": p.contentType, "X-Request-Id": "abc"})
+		if rr.Code != http.StatusOK {
+			errorDie(fmt.Errorf("%s %s: expected 200 got %d: %s", p.method, p.path, rr.Code, rr.Body.String()), t)
+		}
+
+		if last.method != p.method || string(last.body) != p.body {
+			errorDie(fmt.Errorf("%s %s: expected body to be sent untouched got %q", p.method, p.path, last.body), t)
+		}
+
+		if last.header.Get("X-Signature-Mode") != "detached" || last.header.Get("X-Signature-Nonce") == "" || !strings.HasPrefix(last.header.Get("Digest"), "SHA-512=") {
+			errorDie(fmt.Errorf("%s %s: expected detached signature headers got %v", p.method, p.path, last.header), t)
+		}
+
+		if last.header.Get("X-Signature-Headers") != "content-type;x-request-id" {
+			errorDie(fmt.Errorf("%s %s: unexpected signed headers %q", p.method, p.path, last.header.Get("X-Signature-Headers")), t)
+		}
+
+		if !last.valid {
+			errorDie(fmt.Errorf("%s %s: expected detached signature to be valid", p.method, p.path), t)
+		}
+	}
+	// endregion
+	// region Sign mode override
+	rr = send(http.MethodPost, "/payments", `{"amount":10}`, map[string]string{"signMode": "detached"})
+	if rr.Code != http.StatusOK || last.header.Get("X-Signature-Mode") != "detached" || string(last.body) != `{"amount":10}` || !last.valid {
+		errorDie(fmt.Errorf("expected signMode header to force detached mode"), t)
+	}
+
+	rr = send(http.MethodPost, "/batch", `[1,2]`, map[string]string{"signMode": "inject"})
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), QuantoError.InvalidFieldData) {
+		errorDie(fmt.Errorf("expected inject mode to reject non object bodies got %d", rr.Code), t)
+	}
+
+	rr = send(http.MethodPost, "/batch", `[1,2]`, map[string]string{"signMode": "invalid"})
+	if rr.Code != http.StatusBadRequest {
+		errorDie(fmt.Errorf("expected invalid sign mode to be rejected got %d", rr.Code), t)
+	}
+	// endregion
+}
+
+func TestProxyResponseVerification(t *testing.T) {
+	ctx := context.Background()
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := []byte(`{"status":"ok"}`)
+		w.Header().Set(responseStatusHeader, "verified")
+
+		switch r.URL.Path {
+		case "/signed", "/other/signed":
+			sig, _ := gpg.SignData(ctx, test.TestKeyFingerprint, body, crypto.SHA512)
+			w.Header().Set("signature", tools.GPG2Quanto(sig, test.TestKeyFingerprint, "SHA512"))
+		case "/tampered":
+			sig, _ := gpg.SignData(ctx, test.TestKeyFingerprint, []byte(`{"status":"fail"}`), crypto.SHA512)
+			w.Header().Set("signature", tools.GPG2Quanto(sig, test.TestKeyFingerprint, "SHA512"))
+		case "/broken":
+			w.Header().Set("signature", "broken")
+		case "/short":
+			w.Header().Set("signature", "a_b_c")
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write(body)
+	}))
+	defer target.Close()
+
+	defer config.Apply(config.Current())
+
+	config.AgentBypassLogin = true
+	config.AgentKeyFingerPrint = test.TestKeyFingerprint
+	config.AgentAllowedTargets = target.URL
+	config.AgentResponseFingerprint = test.TestKeyFingerprint
+	config.AgentResponseFingerprints = target.URL + "/other=0000000000000000"
+
+	send := func(proxy *AgentProxy, path string) *httptest.ResponseRecorder {
+		r := mux.NewRouter()
+		proxy.AddHandlers(r.PathPrefix("/agent").Subrouter())
+
+		req, err := http.NewRequest(http.MethodPost, "/agent", strings.NewReader(`{"a":1}`))
+		errorDie(err, t)
+		req.Header.Set("serverUrl", target.URL+path)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	expected := map[string]string{
+		"/signed":       responseVerified,
+		"/unsigned":     responseMissing,
+		"/tampered":     responseInvalid,
+		"/broken":       responseInvalid,
+		"/short":        responseInvalid,
+		"/other/signed": responseUntrusted,
+	}
+
+	// region Non strict
+	proxy := MakeAgentProxy(nil, gpg, nil)
+	for path, status := range expected {
+		rr := send(proxy, path)
+		if rr.Code != http.StatusAccepted || rr.Body.String() != `{"status":"ok"}` {
+			errorDie(fmt.Errorf("%s: expected target response to be returned got %d: %s", path, rr.Code, rr.Body.String()), t)
+		}
+
+		if rr.Header().Get(responseStatusHeader) != status {
+			errorDie(fmt.Errorf("%s: expected status %s got %s", path, status, rr.Header().Get(responseStatusHeader)), t)
+		}
+	}
+	// endregion
+	// region Strict
+	config.AgentResponseStrict = true
+	proxy = MakeAgentProxy(nil, gpg, nil)
+
+	for path, status := range expected {
+		rr := send(proxy, path)
+		if rr.Header().Get(responseStatusHeader) != status {
+			errorDie(fmt.Errorf("%s: expected status %s got %s", path, status, rr.Header().Get(responseStatusHeader)), t)
+		}
+
+		if status == responseVerified {
+			if rr.Code != http.StatusAccepted {
+				errorDie(fmt.Errorf("%s: expected verified response to be returned got %d", path, rr.Code), t)
+			}
+			continue
+		}
+
+		if rr.Code != http.StatusBadGateway || !strings.Contains(rr.Body.String(), QuantoError.InvalidFieldData) || strings.Contains(rr.Body.String(), `"status":"ok"`) {
+			errorDie(fmt.Errorf("%s: expected unverified response to be replaced by an error got %d: %s", path, rr.Code, rr.Body.String()), t)
+		}
+	}
+	// endregion
+	// region Verification disabled
+	config.AgentResponseFingerprint = ""
+	config.AgentResponseFingerprints = ""
+	proxy = MakeAgentProxy(nil, gpg, nil)
+
+	rr := send(proxy, "/unsigned")
+	if rr.Code != http.StatusAccepted || rr.Header().Get(responseStatusHeader) != "" {
+		errorDie(fmt.Errorf("expected no verification when no fingerprint is configured"), t)
+	}
+	// endregion
+}
+
+// subkeySigningEntity returns the key that signs with its subkey and its armored public key
+func subkeySigningEntity(t *testing.T) (*openpgp.Entity, string) {
+	keys, err := openpgp.ReadArmoredKeyRing(strings.NewReader(test.TestSubkeySigningPrivateKey))
+	errorDie(err, t)
+
+	var b bytes.Buffer
+	w, err := armor.Encode(&b, openpgp.PublicKeyType, nil)
+	errorDie(err, t)
+	errorDie(keys[0].Serialize(w), t)
+	errorDie(w.Close(), t)
+
+	return keys[0], b.String()
+}
+
+func TestProxyResponseVerificationSubkey(t *testing.T) {
+	ctx := context.Background()
+	entity, publicKey := subkeySigningEntity(t)
+	fingerPrint := test.TestSubkeySigningFingerPrint
+
+	// Signs with the private key of the subkey instead of the primary key
+	signer := *entity
+	signer.PrivateKey = entity.Subkeys[0].PrivateKey
+
+	_, err := gpg.LoadKey(ctx, publicKey)
+	errorDie(err, t)
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := []byte(`{"status":"ok"}`)
+
+		var sig bytes.Buffer
+		if err := openpgp.ArmoredDetachSign(&sig, &signer, bytes.NewReader(body), nil); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("signature", tools.GPG2Quanto(sig.String(), fingerPrint, "SHA512"))
+		_, _ = w.Write(body)
+	}))
+	defer target.Close()
+
+	defer config.Apply(config.Current())
+
+	config.AgentBypassLogin = true
+	config.AgentKeyFingerPrint = test.TestKeyFingerprint
+	config.AgentAllowedTargets = target.URL
+	config.AgentResponseStrict = true
+	config.AgentResponseFingerprints = ""
+
+	send := func() *httptest.ResponseRecorder {
+		r := mux.NewRouter()
+		MakeAgentProxy(nil, gpg, nil).AddHandlers(r.PathPrefix("/agent").Subrouter())
+
+		req, err := http.NewRequest(http.MethodPost, "/agent", strings.NewReader(`{"a":1}`))
+		errorDie(err, t)
+		req.Header.Set("serverUrl", target.URL)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// The response is signed by the subkey of the expected key
+	config.AgentResponseFingerprint = fingerPrint
+	rr := send()
+	if rr.Code != http.StatusOK || rr.Header().Get(responseStatusHeader) != responseVerified {
+		errorDie(fmt.Errorf("expected response signed by subkey %s of %s to be verified got %d %s: %s", test.TestSubkeySigningSubkeyFingerPrint, fingerPrint, rr.Code, rr.Header().Get(responseStatusHeader), rr.Body.String()), t)
+	}
+
+	// Subkeys of other keys are not trusted
+	config.AgentResponseFingerprint = test.TestKeyFingerprint
+	rr = send()
+	if rr.Header().Get(responseStatusHeader) != responseUntrusted {
+		errorDie(fmt.Errorf("expected response signed by a subkey of other key to be untrusted got %s", rr.Header().Get(responseStatusHeader)), t)
+	}
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quan-to/chevron/internal/tools"
+	"github.com/quan-to/chevron/pkg/uuid"
+	"github.com/quan-to/slog"
+)
+
+const (
+	signModeInject   = "inject"
+	signModeDetached = "detached"
+	signModeAuto     = "auto"
+)
+
+// agentSignatureHeaders are set by the agent when signing and cannot be sent by the caller
+var agentSignatureHeaders = []string{
+	"Signature",
+	"X-Powered-By",
+	"X-Signature-Mode",
+	"X-Signature-Timestamp",
+	"X-Signature-Nonce",
+	"X-Signature-Headers",
+	"Digest",
+}
+
+func parseSignMode(mode string) (string, error) {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	switch mode {
+	case signModeInject, signModeDetached, signModeAuto:
+		return mode, nil
+	}
+
+	return "", fmt.Errorf("invalid sign mode %q. Expected %s, %s or %s", mode, signModeInject, signModeDetached, signModeAuto)
+}
+
+// isJSONObject returns true if the data is a JSON object that can receive the uniqueness fields
+func isJSONObject(data []byte) bool {
+	var v map[string]interface{}
+	return json.Unmarshal(data, &v) == nil && v != nil
+}
+
+// canonicalRequest builds the string signed in detached mode
+func canonicalRequest(method string, target *url.URL, timestamp int64, nonce string, signedHeaders []string, header http.Header, body []byte) []byte {
+	path := target.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	if target.RawQuery != "" {
+		path += "?" + target.RawQuery
+	}
+
+	digest := sha512.Sum512(body)
+
+	lines := []string{
+		strings.ToUpper(method),
+		path,
+		strconv.FormatInt(timestamp, 10),
+		nonce,
+	}
+
+	for _, h := range signedHeaders {
+		values := append([]string(nil), header[http.CanonicalHeaderKey(h)]...)
+		for i, v := range values {
+			values[i] = strings.TrimSpace(v)
+		}
+		lines = append(lines, strings.ToLower(h)+":"+strings.Join(values, ","))
+	}
+
+	lines = append(lines, hex.EncodeToString(digest[:]))
+
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// signInjected adds the uniqueness fields to the JSON body and signs it
+func (proxy *AgentProxy) signInjected(ctx context.Context, log slog.Instance, fingerPrint string, body []byte, header http.Header) ([]byte, error) {
+	var jsondata map[string]interface{}
+
+	err := json.Unmarshal(body, &jsondata)
+	if err != nil {
+		return nil, fmt.Errorf("the body is not a JSON object. Use the %s sign mode for other payloads: %v", signModeDetached, err)
+	}
+
+	err = injectUniquenessFields(log, jsondata)
+	if err != nil {
+		return nil, err
+	}
+
+	body, _ = json.Marshal(jsondata)
+
+	log.Await("Signing data with %s", fingerPrint)
+	signature, err := proxy.gpg.SignData(ctx, fingerPrint, body, crypto.SHA512)
+	log.Done("Data signed")
+
+	if err != nil {
+		return nil, err
+	}
+
+	header.Set("signature", tools.GPG2Quanto(signature, fingerPrint, "SHA512"))
+
+	return body, nil
+}
+
+// signDetached signs the canonical request string and adds the signature metadata to the headers. The body is not changed
+func (proxy *AgentProxy) signDetached(ctx context.Context, log slog.Instance, fingerPrint, method string, target *url.URL, body []byte, header http.Header) error {
+	timestamp := time.Now().UnixNano() / 1e6
+	nonce := uuid.EnsureUUID(log)
+	digest := sha512.Sum512(body)
+
+	signedHeaders := make([]string, len(proxy.signedHeaders))
+	for i, h := range proxy.signedHeaders {
+		signedHeaders[i] = strings.ToLower(h)
+	}
+
+	data := canonicalRequest(method, target, timestamp, nonce, signedHeaders, header, body)
+	log.DebugNote("Request Nonce: %q - RequestTimestamp: %d", nonce, timestamp)
+
+	log.Await("Signing request with %s", fingerPrint)
+	signature, err := proxy.gpg.SignData(ctx, fingerPrint, data, crypto.SHA512)
+	log.Done("Request signed")
+
+	if err != nil {
+		return err
+	}
+
+	header.Set("X-Signature-Mode", signModeDetached)
+	header.Set("X-Signature-Timestamp", strconv.FormatInt(timestamp, 10))
+	header.Set("X-Signature-Nonce", nonce)
+	header.Set("X-Signature-Headers", strings.Join(signedHeaders, ";"))
+	header.Set("Digest", "SHA-512="+base64.StdEncoding.EncodeToString(digest[:]))
+	header.Set("signature", tools.GPG2Quanto(signature, fingerPrint, "SHA512"))
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParseSignMode(t *testing.T) {
+	for _, mode := range []string{"inject", "Detached", " auto "} {
+		if _, err := parseSignMode(mode); err != nil {
+			t.Errorf("expected %q to be valid: %v", mode, err)
+		}
+	}
+
+	if _, err := parseSignMode("body"); err == nil {
+		t.Errorf("expected invalid sign mode to fail")
+	}
+}
+
+func TestCanonicalRequest(t *testing.T) {
+	u, _ := url.Parse("https://api.example.com/pay%20ments?id=1&b=2")
+	h := http.Header{}
+	h.Add("Content-Type", " text/plain ")
+	h.Add("X-Multi", "a")
+	h.Add("X-Multi", "b")
+
+	data := canonicalRequest("post", u, 1234, "nonce", []string{"content-type", "x-multi", "x-missing"}, h, []byte("hello"))
+
+	expected := strings.Join([]string{
+		"POST",
+		"/pay%20ments?id=1&b=2",
+		"1234",
+		"nonce",
+		"content-type:text/plain",
+		"x-multi:a,b",
+		"x-missing:",
+		"9b71d224bd62f3785d96d46ad3ea3d73319bfbc2890caadae2dff72519673ca72323c3d99ba5c11d7c7acc6e14b8c5da0c4663475c2e5c3adef46f73bcdec043",
+	}, "\n")
+
+	if string(data) != expected {
+		t.Errorf("unexpected canonical request:\n%s\nexpected:\n%s", data, expected)
+	}
+
+	if h.Get("Content-Type") != " text/plain " {
+		t.Errorf("expected header values to not be changed")
+	}
+
+	u, _ = url.Parse("https://api.example.com")
+	if !strings.HasPrefix(string(canonicalRequest("GET", u, 0, "", nil, h, nil)), "GET\n/\n") {
+		t.Errorf("expected empty path to be canonicalized as /")
+	}
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/quan-to/chevron/internal/tools"
+	"github.com/quan-to/chevron/pkg/QuantoError"
+	"github.com/quan-to/slog"
+)
+
+// responseStatusHeader is the header returned to the caller with the result of the response verification
+const responseStatusHeader = "X-Agent-Signature-Status"
+
+const (
+	responseVerified  = "verified"
+	responseMissing   = "missing"
+	responseInvalid   = "invalid"
+	responseUntrusted = "untrusted"
+)
+
+// verifyResponse checks if the response body is signed by the expected key. The signature is in Quanto format
+func (proxy *AgentProxy) verifyResponse(ctx context.Context, log slog.Instance, fingerPrint, signature string, body []byte) (string, error) {
+	if signature == "" {
+		return responseMissing, errors.New("the response has no signature")
+	}
+
+	gpgSig := tools.Quanto2GPG(signature)
+	if gpgSig == "" {
+		return responseInvalid, QuantoError.New(QuantoError.InvalidFieldData, "signature", "The response signature is not in the Quanto format", nil)
+	}
+
+	issuer, err := tools.GetFingerPrintFromSignature(gpgSig)
+	if err != nil {
+		return responseInvalid, err
+	}
+
+	if !proxy.isKeyOf(ctx, fingerPrint, issuer) {
+		return responseUntrusted, fmt.Errorf("the response was signed by %s but %s was expected", issuer, fingerPrint)
+	}
+
+	log.Await("Verifying response signature from %s", fingerPrint)
+	valid, err := proxy.gpg.VerifySignature(ctx, body, gpgSig)
+	log.Done("Response signature verified")
+
+	if err != nil {
+		return responseInvalid, err
+	}
+
+	if !valid {
+		return responseInvalid, errors.New("the response signature is not valid")
+	}
+
+	return responseVerified, nil
+}
+
+// isKeyOf returns true if the issuer is the key with the specified fingerprint or one of its subkeys
+func (proxy *AgentProxy) isKeyOf(ctx context.Context, fingerPrint, issuer string) bool {
+	if issuer == tools.FPto16(fingerPrint) {
+		return true
+	}
+
+	entity := proxy.gpg.GetPublicKeyEntity(ctx, fingerPrint)
+	if entity == nil {
+		return false
+	}
+
+	for _, subkey := range entity.Subkeys {
+		if tools.IssuerKeyIdToFP16(subkey.PublicKey.KeyId) == issuer {
+			return true
+		}
+	}
+
+	return false
+}
@@ -40,6 +40,7 @@ func MakeGPGEndpoint(log slog.Instance, sm interfaces.SecretsManager, gpg interf
 func (ge *GPGEndpoint) AttachHandlers(r *mux.Router) {
 	r.HandleFunc("/generateKey", ge.generateKey).Methods("POST")
 	r.HandleFunc("/unlockKey", ge.unlockKey).Methods("POST")
+	r.HandleFunc("/forgetKeyPassword", ge.forgetKeyPassword).Methods("POST")
 	r.HandleFunc("/sign", ge.sign).Methods("POST")
 	r.HandleFunc("/signQuanto", ge.signQuanto).Methods("POST")
 	r.HandleFunc("/verifySignature", ge.verifySignature).Methods("POST")
@@ -382,6 +383,46 @@ func (ge *GPGEndpoint) unlockKey(w http.ResponseWriter, r *http.Request) {
 	LogExit(log, r, 200, n)
 }
 
+// ForgetKeyPassword godoc
+// @id gpg-key-forget-password
+// @tags GPG Operations
+// @Summary Forgets the stored password of a GPG Private Key
+// @Description Removes the master key encrypted password stored by unlockKey. The key stays unlocked until the next restart
+// @Accept json
+// @Produce plain
+// @Param message body models.GPGForgetKeyPasswordData true "Key Data"
+// @Success 200 {string} Result Returns OK on success
+// @Failure default {object} QuantoError.ErrorObject
+// @Router /gpg/forgetKeyPassword [post]
+func (ge *GPGEndpoint) forgetKeyPassword(w http.ResponseWriter, r *http.Request) {
+	ctx := wrapContextWithRequestID(r)
+	log := wrapLogWithRequestID(ge.log, r)
+	InitHTTPTimer(log, r)
+	var data models.GPGForgetKeyPasswordData
+
+	if !UnmarshalBodyOrDie(&data, w, r, log) {
+		return
+	}
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			CatchAllError(rec, w, r, log)
+		}
+	}()
+
+	err := ge.sm.DeleteKeyPassword(ctx, data.FingerPrint)
+
+	if err != nil {
+		NotFound("FingerPrint", err.Error(), w, r, log)
+		return
+	}
+
+	w.Header().Set("Content-Type", models.MimeText)
+	w.WriteHeader(200)
+	n, _ := w.Write([]byte("OK"))
+	LogExit(log, r, 200, n)
+}
+
 // GenerateKey godoc
 // @id gpg-key-generate
 // @tags GPG Operations
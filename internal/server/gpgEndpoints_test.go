@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/quan-to/chevron/internal/tools"
@@ -656,4 +657,49 @@ func TestUnlockKey(t *testing.T) {
 	// endregion
 }
 
+func TestForgetKeyPassword(t *testing.T) {
+	InvalidPayloadTest("/gpg/forgetKeyPassword", t)
+
+	forget := func() *httptest.ResponseRecorder {
+		body, _ := json.Marshal(models.GPGForgetKeyPasswordData{FingerPrint: test.TestKeyFingerprint})
+		req, err := http.NewRequest("POST", "/gpg/forgetKeyPassword", bytes.NewReader(body))
+		errorDie(err, t)
+		return executeRequest(req)
+	}
+
+	sm.PutKeyPassword(context.Background(), test.TestKeyFingerprint, test.TestKeyPassword)
+	encPass := sm.GetPasswords(context.Background())[test.TestKeyFingerprint]
+
+	// Unlocking the key again clears the forgotten mark for the other tests
+	defer sm.PutKeyPassword(context.Background(), test.TestKeyFingerprint, test.TestKeyPassword)
+
+	// region Forget stored password
+	res := forget()
+	d, err := ioutil.ReadAll(res.Body)
+	errorDie(err, t)
+
+	if res.Code != 200 || string(d) != "OK" {
+		errorDie(fmt.Errorf("expected OK got %d: %s", res.Code, string(d)), t)
+	}
+
+	if _, ok := sm.GetPasswords(context.Background())[test.TestKeyFingerprint]; ok {
+		errorDie(fmt.Errorf("expected password to be forgotten"), t)
+	}
+
+	sm.PutEncryptedPassword(context.Background(), test.TestKeyFingerprint, encPass)
+	if _, ok := sm.GetPasswords(context.Background())[test.TestKeyFingerprint]; ok {
+		errorDie(fmt.Errorf("expected forgotten password to not be stored again from the cluster peers"), t)
+	}
+	// endregion
+	// region Forget unknown password
+	res = forget()
+	errObj, err := ReadErrorObject(res.Body)
+	errorDie(err, t)
+
+	if errObj.ErrorCode != QuantoError.NotFound {
+		errorDie(fmt.Errorf("expected ErrorCode to be %s got %s", QuantoError.NotFound, errObj.ErrorCode), t)
+	}
+	// endregion
+}
+
 // endregion
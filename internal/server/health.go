@@ -0,0 +1,296 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/quan-to/chevron/internal/config"
+	"github.com/quan-to/chevron/internal/tools"
+	"github.com/quan-to/chevron/internal/vaultManager"
+	"github.com/quan-to/chevron/pkg/interfaces"
+	"github.com/quan-to/chevron/pkg/models"
+
+	"github.com/gorilla/mux"
+	"github.com/quan-to/slog"
+)
+
+const (
+	healthOK   = "ok"
+	healthFail = "fail"
+)
+
+// cachedHealthChecker is implemented by the database handlers cached in Redis, so both can be checked separately
+type cachedHealthChecker interface {
+	CacheHealthCheck() error
+	ProxiedHealthCheck() error
+}
+
+// componentCheck checks a single component and returns the details to be shown
+type componentCheck func(ctx context.Context) (interface{}, error)
+
+// HealthEndpoint reports if the server is alive and ready to serve
+type HealthEndpoint struct {
+	log slog.Instance
+	sm  interfaces.SecretsManager
+	gpg interfaces.PGPManager
+	vm  *vaultManager.VaultManager
+	kb  interfaces.StorageBackend
+	db  HealthCheckHandler
+}
+
+// MakeHealthEndpoint creates an instance of the liveness and readiness endpoints
+func MakeHealthEndpoint(log slog.Instance, sm interfaces.SecretsManager, gpg interfaces.PGPManager, vm *vaultManager.VaultManager, kb interfaces.StorageBackend, dbHandler HealthCheckHandler) *HealthEndpoint {
+	if log == nil {
+		log = slog.Scope("Health")
+	} else {
+		log = log.SubScope("Health")
+	}
+
+	return &HealthEndpoint{
+		log: log,
+		sm:  sm,
+		gpg: gpg,
+		vm:  vm,
+		kb:  kb,
+		db:  dbHandler,
+	}
+}
+
+// AttachHandlers adds the probes to the root and the /remoteSigner paths
+func (he *HealthEndpoint) AttachHandlers(r *mux.Router) {
+	for _, prefix := range []string{"", "/remoteSigner"} {
+		r.HandleFunc(prefix+"/healthz", he.healthz)
+		r.HandleFunc(prefix+"/readyz", he.readyz)
+	}
+}
+
+// checks returns the checks of the components enabled in the server
+func (he *HealthEndpoint) checks() map[string]componentCheck {
+	checks := map[string]componentCheck{}
+
+	if cached, ok := he.db.(cachedHealthChecker); ok {
+		checks["database"] = func(context.Context) (interface{}, error) {
+			return nil, cached.ProxiedHealthCheck()
+		}
+		checks["redis"] = func(context.Context) (interface{}, error) {
+			return nil, cached.CacheHealthCheck()
+		}
+	} else if he.db != nil {
+		checks["database"] = func(context.Context) (interface{}, error) {
+			return nil, he.db.HealthCheck()
+		}
+	}
+
+	if he.vm != nil {
+		checks["vault"] = he.checkVault
+	}
+
+	if he.kb != nil {
+		checks["keyBackend"] = he.checkKeyBackend
+	}
+
+	if he.sm != nil {
+		checks["masterKey"] = he.checkMasterKey
+	}
+
+	if he.gpg != nil {
+		checks["privateKeys"] = he.checkPrivateKeys
+	}
+
+	return checks
+}
+
+func (he *HealthEndpoint) checkVault(context.Context) (interface{}, error) {
+	health, err := he.vm.HealthStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	if !health.Initialized || health.Sealed {
+		return nil, fmt.Errorf("vault initialized: %t, sealed: %t", health.Initialized, health.Sealed)
+	}
+
+	return nil, nil
+}
+
+func (he *HealthEndpoint) checkKeyBackend(context.Context) (interface{}, error) {
+	keys, err := he.kb.List()
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"Name": he.kb.Name(),
+		"Keys": len(keys),
+	}, nil
+}
+
+func (he *HealthEndpoint) checkMasterKey(ctx context.Context) (interface{}, error) {
+	fp := he.sm.GetMasterKeyFingerPrint(ctx)
+	if fp == "" {
+		return nil, fmt.Errorf("master key not loaded")
+	}
+
+	return map[string]interface{}{
+		"FingerPrint": fp,
+	}, nil
+}
+
+// checkPrivateKeys counts the loaded private keys and fails if any of READY_REQUIRED_FINGERPRINTS is not loaded and unlocked
+func (he *HealthEndpoint) checkPrivateKeys(ctx context.Context) (interface{}, error) {
+	result := models.PrivateKeysHealth{}
+	unlocked := map[string]bool{}
+
+	for _, key := range he.gpg.GetLoadedPrivateKeys(ctx) {
+		unlocked[key.FingerPrint] = key.PrivateKeyIsDecrypted
+		if key.PrivateKeyIsDecrypted {
+			result.Unlocked++
+		} else {
+			result.Locked++
+		}
+	}
+
+	for _, fp := range splitList(config.ReadyRequiredFingerPrints) {
+		isUnlocked, loaded := unlocked[he.gpg.FixFingerPrint(fp)]
+		if !loaded {
+			result.RequiredMissing = append(result.RequiredMissing, fp)
+		} else if !isUnlocked {
+			result.RequiredLocked = append(result.RequiredLocked, fp)
+		}
+	}
+
+	var problems []string
+	if len(result.RequiredMissing) > 0 {
+		problems = append(problems, "required keys not loaded: "+strings.Join(result.RequiredMissing, ", "))
+	}
+	if len(result.RequiredLocked) > 0 {
+		problems = append(problems, "required keys locked: "+strings.Join(result.RequiredLocked, ", "))
+	}
+
+	if len(problems) > 0 {
+		return result, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+
+	return result, nil
+}
+
+// readiness runs the checks in parallel. Checks that do not finish in READY_CHECK_TIMEOUT fail
+func (he *HealthEndpoint) readiness(ctx context.Context) models.HealthStatus {
+	type checkResult struct {
+		name   string
+		health models.ComponentHealth
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, config.ReadyCheckTimeout)
+	defer cancel()
+
+	checks := he.checks()
+	results := make(chan checkResult, len(checks))
+
+	for name, check := range checks {
+		go func(name string, check componentCheck) {
+			start := time.Now()
+			details, err := check(ctx)
+			health := models.ComponentHealth{
+				Status:    healthOK,
+				LatencyMs: latencyMs(time.Since(start)),
+				Details:   details,
+			}
+			if err != nil {
+				health.Status = healthFail
+				health.Error = err.Error()
+			}
+			results <- checkResult{name: name, health: health}
+		}(name, check)
+	}
+
+	status := models.HealthStatus{
+		Status:     healthOK,
+		Components: make(map[string]models.ComponentHealth, len(checks)),
+	}
+
+	for len(status.Components) < len(checks) {
+		select {
+		case res := <-results:
+			status.Components[res.name] = res.health
+		case <-ctx.Done():
+			for name := range checks {
+				if _, ok := status.Components[name]; !ok {
+					status.Components[name] = models.ComponentHealth{
+						Status:    healthFail,
+						LatencyMs: latencyMs(config.ReadyCheckTimeout),
+						Error:     fmt.Sprintf("timeout after %s", config.ReadyCheckTimeout),
+					}
+				}
+			}
+		}
+	}
+
+	for _, health := range status.Components {
+		if health.Status != healthOK {
+			status.Status = healthFail
+		}
+	}
+
+	return status
+}
+
+func latencyMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// writeHealth writes the status without logging. The probes are called often and would flood the log
+func writeHealth(w http.ResponseWriter, status models.HealthStatus) {
+	statusCode := http.StatusOK
+	if status.Status != healthOK {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	b, _ := json.Marshal(status)
+
+	w.Header().Set("Content-Type", models.MimeJSON)
+	w.WriteHeader(statusCode)
+	_, _ = w.Write(b)
+}
+
+// Liveness godoc
+// @id health-healthz
+// @tags Health
+// @Summary Checks if the Chevron process is alive
+// @Produce json
+// @Success 200 {object} models.HealthStatus
+// @Router /healthz [get]
+func (he *HealthEndpoint) healthz(w http.ResponseWriter, _ *http.Request) {
+	writeHealth(w, models.HealthStatus{Status: healthOK})
+}
+
+// Readiness godoc
+// @id health-readyz
+// @tags Health
+// @Summary Checks if Chevron is ready to serve, showing the status and latency of each component
+// @Produce json
+// @Success 200 {object} models.HealthStatus
+// @Failure 503 {object} models.HealthStatus
+// @Router /readyz [get]
+func (he *HealthEndpoint) readyz(w http.ResponseWriter, r *http.Request) {
+	ctx := wrapContextWithRequestID(r)
+	status := he.readiness(ctx)
+
+	if status.Status != healthOK {
+		var problems []string
+		for name, health := range status.Components {
+			if health.Status != healthOK {
+				problems = append(problems, name+": "+health.Error)
+			}
+		}
+		sort.Strings(problems)
+		he.log.Tag(tools.GetRequestIDFromContext(ctx)).Warn("Not ready. %s", strings.Join(problems, "; "))
+	}
+
+	writeHealth(w, status)
+}
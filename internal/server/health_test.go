@@ -0,0 +1,185 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/quan-to/chevron/internal/config"
+	"github.com/quan-to/chevron/pkg/interfaces"
+	"github.com/quan-to/chevron/pkg/models"
+	"github.com/quan-to/chevron/test"
+)
+
+type healthCheckFunc func() error
+
+func (f healthCheckFunc) HealthCheck() error {
+	return f()
+}
+
+// loadedKeysPGP is a PGPManager that only reports the loaded private keys
+type loadedKeysPGP struct {
+	interfaces.PGPManager
+	keys []models.KeyInfo
+}
+
+func (p loadedKeysPGP) GetLoadedPrivateKeys(context.Context) []models.KeyInfo {
+	return p.keys
+}
+
+func (p loadedKeysPGP) FixFingerPrint(fp string) string {
+	if len(fp) > 16 {
+		return fp[len(fp)-16:]
+	}
+	return fp
+}
+
+func getHealth(t *testing.T, h http.Handler, path string) (int, models.HealthStatus) {
+	req := httptest.NewRequest("GET", path, nil)
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+
+	var status models.HealthStatus
+	if err := json.Unmarshal(res.Body.Bytes(), &status); err != nil {
+		t.Fatalf("%s: invalid body %q: %s", path, res.Body.String(), err)
+	}
+
+	return res.Code, status
+}
+
+func TestHealthz(t *testing.T) {
+	for _, path := range []string{"/healthz", "/remoteSigner/healthz"} {
+		code, status := getHealth(t, router, path)
+		if code != http.StatusOK || status.Status != healthOK {
+			t.Errorf("%s: expected %d %q got %d %q", path, http.StatusOK, healthOK, code, status.Status)
+		}
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	defer config.Apply(config.Current())
+	config.ReadyRequiredFingerPrints = test.TestKeyFingerprint
+
+	for _, path := range []string{"/readyz", "/remoteSigner/readyz"} {
+		code, status := getHealth(t, router, path)
+		if code != http.StatusOK || status.Status != healthOK {
+			t.Fatalf("%s: expected %d %q got %d %+v", path, http.StatusOK, healthOK, code, status)
+		}
+
+		for _, name := range []string{"database", "keyBackend", "masterKey", "privateKeys"} {
+			c, ok := status.Components[name]
+			if !ok {
+				t.Errorf("%s: expected component %s", path, name)
+				continue
+			}
+			if c.Status != healthOK || c.LatencyMs < 0 {
+				t.Errorf("%s: expected %s to be ok got %+v", path, name, c)
+			}
+		}
+
+		keys, _ := status.Components["privateKeys"].Details.(map[string]interface{})
+		if unlocked, _ := keys["Unlocked"].(float64); unlocked < 1 {
+			t.Errorf("%s: expected at least one unlocked key got %v", path, keys)
+		}
+	}
+
+	config.ReadyRequiredFingerPrints = test.TestKeyFingerprint + ",0000000000000000"
+
+	code, status := getHealth(t, router, "/readyz")
+	if code != http.StatusServiceUnavailable || status.Status != healthFail {
+		t.Fatalf("expected %d %q got %d %q", http.StatusServiceUnavailable, healthFail, code, status.Status)
+	}
+
+	if c := status.Components["privateKeys"]; c.Status != healthFail || c.Error == "" {
+		t.Errorf("expected privateKeys to fail got %+v", c)
+	}
+
+	if c := status.Components["database"]; c.Status != healthOK {
+		t.Errorf("expected database to be ok got %+v", c)
+	}
+}
+
+func TestReadyzRequiredFingerPrints(t *testing.T) {
+	defer config.Apply(config.Current())
+	config.ReadyRequiredFingerPrints = "AAAABBBBCCCCDDDD1111111111111111, 2222222222222222, 3333333333333333"
+
+	he := MakeHealthEndpoint(nil, nil, loadedKeysPGP{keys: []models.KeyInfo{
+		{FingerPrint: "1111111111111111", PrivateKeyIsDecrypted: true},
+		{FingerPrint: "2222222222222222"},
+		{FingerPrint: "4444444444444444"},
+	}}, nil, nil, nil)
+
+	details, err := he.checkPrivateKeys(context.Background())
+	if err == nil {
+		t.Fatalf("expected error for locked and missing keys")
+	}
+
+	keys := details.(models.PrivateKeysHealth)
+	if keys.Unlocked != 1 || keys.Locked != 2 {
+		t.Errorf("expected 1 unlocked and 2 locked keys got %+v", keys)
+	}
+
+	if len(keys.RequiredLocked) != 1 || keys.RequiredLocked[0] != "2222222222222222" {
+		t.Errorf("expected 2222222222222222 to be locked got %v", keys.RequiredLocked)
+	}
+
+	if len(keys.RequiredMissing) != 1 || keys.RequiredMissing[0] != "3333333333333333" {
+		t.Errorf("expected 3333333333333333 to be missing got %v", keys.RequiredMissing)
+	}
+
+	config.ReadyRequiredFingerPrints = "AAAABBBBCCCCDDDD1111111111111111"
+	if _, err := he.checkPrivateKeys(context.Background()); err != nil {
+		t.Errorf("expected required keys to be unlocked got %s", err)
+	}
+}
+
+func TestReadyzFailures(t *testing.T) {
+	defer config.Apply(config.Current())
+	config.ReadyCheckTimeout = 100 * time.Millisecond
+
+	release := make(chan bool)
+	defer close(release)
+
+	he := MakeHealthEndpoint(nil, nil, nil, nil, nil, healthCheckFunc(func() error {
+		return fmt.Errorf("connection refused")
+	}))
+
+	code, status := getHealth(t, http.HandlerFunc(he.readyz), "/readyz")
+	if code != http.StatusServiceUnavailable || status.Status != healthFail {
+		t.Fatalf("expected %d %q got %d %q", http.StatusServiceUnavailable, healthFail, code, status.Status)
+	}
+
+	if c := status.Components["database"]; c.Status != healthFail || c.Error != "connection refused" {
+		t.Errorf("expected database to fail got %+v", c)
+	}
+
+	// Checks that hang fail after the timeout
+	he.db = healthCheckFunc(func() error {
+		<-release
+		return nil
+	})
+
+	start := time.Now()
+	code, status = getHealth(t, http.HandlerFunc(he.readyz), "/readyz")
+	if code != http.StatusServiceUnavailable {
+		t.Fatalf("expected %d got %d", http.StatusServiceUnavailable, code)
+	}
+
+	if time.Since(start) > time.Second {
+		t.Errorf("expected readiness to return after the timeout")
+	}
+
+	if c := status.Components["database"]; c.Status != healthFail || c.LatencyMs != 100 {
+		t.Errorf("expected database to time out got %+v", c)
+	}
+
+	// Liveness does not depend on the components
+	code, status = getHealth(t, http.HandlerFunc(he.healthz), "/healthz")
+	if code != http.StatusOK || status.Status != healthOK || len(status.Components) != 0 {
+		t.Errorf("expected liveness to be ok got %d %+v", code, status)
+	}
+}
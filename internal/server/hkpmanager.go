@@ -144,6 +144,7 @@ func hkpLookup(log slog.Instance, w http.ResponseWriter, r *http.Request) {
 // @Router /pks/add [post]
 func hkpAdd(log slog.Instance, w http.ResponseWriter, r *http.Request) {
 	ctx := wrapContextWithRequestID(r)
+	ctx = wrapContextWithActor(ctx, r)
 	log = wrapLogWithRequestID(log.SubScope("HKP"), r)
 
 	InitHTTPTimer(log, r)
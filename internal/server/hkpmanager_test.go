@@ -19,8 +19,7 @@ import (
 
 func TestHKPAdd(t *testing.T) {
 	ctx := wrapContextWithDatabaseHandler(dbh, context.Background())
-	config.PushVariables()
-	defer config.PopVariables()
+	defer config.Apply(config.Current())
 
 	req, err := http.NewRequest("POST", "/pks/add", nil)
 
@@ -0,0 +1,253 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/quan-to/chevron/internal/config"
+	"github.com/quan-to/chevron/pkg/QuantoError"
+	"github.com/quan-to/slog"
+)
+
+// tlsReloader holds the server TLS configuration and reloads the certificates from disk
+type tlsReloader struct {
+	sync.RWMutex
+	certFile   string
+	keyFile    string
+	caFile     string
+	clientAuth tls.ClientAuthType
+	current    *tls.Config
+}
+
+func makeTLSReloader(c *config.Config) (*tlsReloader, error) {
+	t := &tlsReloader{
+		certFile:   c.TLSCertFile,
+		keyFile:    c.TLSKeyFile,
+		caFile:     c.TLSClientCAFile,
+		clientAuth: tls.NoClientCert,
+	}
+
+	if t.caFile != "" {
+		t.clientAuth = tls.RequireAndVerifyClientCert
+		if strings.ToLower(c.TLSClientAuth) == "optional" {
+			t.clientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+
+	return t, t.reload()
+}
+
+// reload reads the certificate, key and client CA again. The previous configuration is kept on errors
+func (t *tlsReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(t.certFile, t.keyFile)
+	if err != nil {
+		return fmt.Errorf("error loading TLS certificate: %s", err)
+	}
+
+	c := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   t.clientAuth,
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+
+	if t.caFile != "" {
+		caData, err := ioutil.ReadFile(t.caFile)
+		if err != nil {
+			return fmt.Errorf("error reading TLS client CA: %s", err)
+		}
+
+		c.ClientCAs = x509.NewCertPool()
+		if !c.ClientCAs.AppendCertsFromPEM(caData) {
+			return fmt.Errorf("no certificates found in TLS client CA %s", t.caFile)
+		}
+	}
+
+	t.Lock()
+	t.current = c
+	t.Unlock()
+
+	return nil
+}
+
+func (t *tlsReloader) get() *tls.Config {
+	t.RLock()
+	defer t.RUnlock()
+
+	return t.current
+}
+
+// config returns the TLS configuration for the server. Each connection uses the last loaded certificates
+func (t *tlsReloader) config() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return &t.get().Certificates[0], nil
+		},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return t.get(), nil
+		},
+	}
+}
+
+// maxBodySize rejects requests with bodies bigger than limit
+func maxBodySize(log slog.Instance, limit int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > limit {
+			InitHTTPTimer(log, r)
+			e := QuantoError.New(QuantoError.OperationLimitExceeded, "body", fmt.Sprintf("The request body exceeds the maximum size of %d bytes", limit), nil)
+			WriteJSON(e, http.StatusRequestEntityTooLarge, w, r, log)
+			return
+		}
+
+		// Requests without Content-Length fail when reading past the limit
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// httpServer is the HTTP server used by both remote signer modes
+type httpServer struct {
+	log             slog.Instance
+	server          *http.Server
+	tls             *tlsReloader
+	shutdownTimeout time.Duration
+}
+
+// makeHTTPServer creates the HTTP server with the HTTP and TLS settings of c
+func makeHTTPServer(log slog.Instance, c *config.Config, handler http.Handler) (*httpServer, error) {
+	if log == nil {
+		log = slog.Scope("HTTP")
+	} else {
+		log = log.SubScope("HTTP")
+	}
+
+	s := &httpServer{
+		log: log,
+		server: &http.Server{
+			Addr:              net.JoinHostPort(c.HttpBindAddress, fmt.Sprint(c.HttpPort)),
+			Handler:           maxBodySize(log, c.HttpMaxBodySize, handler),
+			ReadTimeout:       c.HttpReadTimeout,
+			ReadHeaderTimeout: c.HttpReadHeaderTimeout,
+			WriteTimeout:      c.HttpWriteTimeout,
+			IdleTimeout:       c.HttpIdleTimeout,
+		},
+		shutdownTimeout: c.HttpShutdownTimeout,
+	}
+
+	if c.TLSCertFile != "" {
+		t, err := makeTLSReloader(c)
+		if err != nil {
+			return nil, err
+		}
+		s.tls = t
+		s.server.TLSConfig = t.config()
+	}
+
+	return s, nil
+}
+
+// listen opens the server address
+func (s *httpServer) listen() (net.Listener, error) {
+	l, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("error listening at %s: %s", s.server.Addr, err)
+	}
+
+	return l, nil
+}
+
+// start serves in l asynchronously and returns a stop channel.
+// On stop the server waits the in-flight requests for HTTP_SHUTDOWN_TIMEOUT before closing the connections
+func (s *httpServer) start(l net.Listener) chan bool {
+	stopChannel := make(chan bool)
+	hup := make(chan os.Signal, 1)
+	hupDone := make(chan bool)
+
+	if s.tls != nil {
+		signal.Notify(hup, syscall.SIGHUP)
+		go func() {
+			for {
+				select {
+				case <-hup:
+					s.log.Info("Received SIGHUP. Reloading TLS certificates")
+					if err := s.tls.reload(); err != nil {
+						s.log.Error("Error reloading TLS certificates, keeping the previous ones: %s", err)
+						continue
+					}
+					s.log.Info("TLS certificates reloaded")
+				case <-hupDone:
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		<-stopChannel
+		s.log.Info("Received STOP. Waiting up to %s for in-flight requests", s.shutdownTimeout)
+
+		if s.tls != nil {
+			signal.Stop(hup)
+			close(hupDone)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+		defer cancel()
+
+		if err := s.server.Shutdown(ctx); err != nil {
+			s.log.Warn("Graceful shutdown failed (%s). Closing server", err)
+			_ = s.server.Close()
+		}
+
+		stopChannel <- true
+	}()
+
+	go func() {
+		var err error
+		if s.tls != nil {
+			err = s.server.ServeTLS(l, "", "")
+		} else {
+			err = s.server.Serve(l)
+		}
+
+		if err != nil && err != http.ErrServerClosed {
+			s.log.Error(err)
+		}
+		s.log.Info("HTTP Server Closed")
+	}()
+
+	if s.tls != nil {
+		s.log.Info("Remote Signer is now listening at https://%s", l.Addr())
+	} else {
+		s.log.Info("Remote Signer is now listening at http://%s", l.Addr())
+	}
+
+	return stopChannel
+}
+
+// runHTTPServer starts the remote signer HTTP server with handler using the current configuration
+func runHTTPServer(log slog.Instance, handler http.Handler) (chan bool, error) {
+	s, err := makeHTTPServer(log, config.Current(), handler)
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := s.listen()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.start(l), nil
+}
@@ -0,0 +1,346 @@
+package server
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/quan-to/chevron/internal/config"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+	tls  tls.Certificate
+}
+
+// makeTestCert creates a certificate signed by parent. A nil parent creates a self signed CA
+func makeTestCert(t *testing.T, name string, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, _ := x509.ParseCertificate(der)
+	keyDer, _ := x509.MarshalECPrivateKey(key)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCert{cert: cert, key: key, pem: append(certPEM, keyPEM...), tls: pair}
+}
+
+func writeTestFile(t *testing.T, filename string, data []byte) {
+	if err := ioutil.WriteFile(filename, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func startTestHTTPServer(t *testing.T, c *config.Config, handler http.Handler) (string, chan bool) {
+	c.HttpBindAddress = "127.0.0.1"
+	c.HttpPort = 0
+
+	s, err := makeHTTPServer(nil, c, handler)
+	if err != nil {
+		t.Fatalf("Error creating server: %s", err)
+	}
+
+	l, err := s.listen()
+	if err != nil {
+		t.Fatalf("Error listening: %s", err)
+	}
+
+	return l.Addr().String(), s.start(l)
+}
+
+func TestHTTPServerGracefulShutdown(t *testing.T) {
+	c := config.Current()
+	c.HttpShutdownTimeout = 5 * time.Second
+
+	started := make(chan bool)
+	release := make(chan bool)
+
+	addr, stop := startTestHTTPServer(t, c, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- true
+		<-release
+		_, _ = w.Write([]byte("signed"))
+	}))
+
+	result := make(chan string)
+	go func() {
+		res, err := http.Get("http://" + addr + "/")
+		if err != nil {
+			result <- err.Error()
+			return
+		}
+		data, _ := ioutil.ReadAll(res.Body)
+		_ = res.Body.Close()
+		result <- string(data)
+	}()
+
+	<-started
+	stop <- true
+
+	select {
+	case <-stop:
+		t.Fatalf("Server stopped with a request in-flight")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	release <- true
+
+	if r := <-result; r != "signed" {
+		t.Errorf("Expected in-flight request to finish. Got %q", r)
+	}
+
+	select {
+	case <-stop:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Server did not stop after the request finished")
+	}
+
+	if _, err := http.Get("http://" + addr + "/"); err == nil {
+		t.Errorf("Expected server to be closed")
+	}
+}
+
+func TestHTTPServerShutdownTimeout(t *testing.T) {
+	c := config.Current()
+	c.HttpShutdownTimeout = 100 * time.Millisecond
+
+	started := make(chan bool)
+	release := make(chan bool)
+	defer close(release)
+
+	addr, stop := startTestHTTPServer(t, c, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- true
+		<-release
+	}))
+
+	go func() {
+		_, _ = http.Get("http://" + addr + "/")
+	}()
+
+	<-started
+	stop <- true
+
+	select {
+	case <-stop:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Server did not stop after HTTP_SHUTDOWN_TIMEOUT")
+	}
+}
+
+func TestHTTPServerMaxBodySize(t *testing.T) {
+	c := config.Current()
+	c.HttpMaxBodySize = 16
+
+	addr, stop := startTestHTTPServer(t, c, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := ioutil.ReadAll(r.Body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer func() {
+		stop <- true
+		<-stop
+	}()
+
+	res, err := http.Post("http://"+addr+"/", "text/plain", bytes.NewReader(make([]byte, 16)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("Expected %d for a body within the limit got %d", http.StatusOK, res.StatusCode)
+	}
+
+	res, err = http.Post("http://"+addr+"/", "text/plain", bytes.NewReader(make([]byte, 17)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected %d for a body over the limit got %d", http.StatusRequestEntityTooLarge, res.StatusCode)
+	}
+
+	// Without Content-Length the limit is applied while reading
+	res, err = http.Post("http://"+addr+"/", "text/plain", io.MultiReader(bytes.NewReader(make([]byte, 10)), bytes.NewReader(make([]byte, 10))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected the handler to fail reading a chunked body over the limit. Got %d", res.StatusCode)
+	}
+}
+
+func TestHTTPServerTLS(t *testing.T) {
+	c := config.Current()
+
+	dir, err := ioutil.TempDir("", "chevron-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	ca := makeTestCert(t, "Test CA", nil)
+	serverCert := makeTestCert(t, "server-1", ca)
+	clientCert := makeTestCert(t, "client", ca)
+
+	c.TLSCertFile = path.Join(dir, "server.pem")
+	c.TLSKeyFile = path.Join(dir, "server.pem")
+	c.TLSClientCAFile = path.Join(dir, "ca.pem")
+	c.TLSClientAuth = "require"
+
+	writeTestFile(t, c.TLSCertFile, serverCert.pem)
+	writeTestFile(t, c.TLSClientCAFile, ca.pem)
+
+	addr, stop := startTestHTTPServer(t, c, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	defer func() {
+		stop <- true
+		<-stop
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	request := func(certs []tls.Certificate) (string, string, error) {
+		client := &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs, ServerName: "localhost"},
+			},
+		}
+		res, err := client.Get("https://" + addr + "/")
+		if err != nil {
+			return "", "", err
+		}
+		defer func() { _ = res.Body.Close() }()
+		data, _ := ioutil.ReadAll(res.Body)
+		return string(data), res.TLS.PeerCertificates[0].Subject.CommonName, nil
+	}
+
+	if _, _, err := request(nil); err == nil {
+		t.Errorf("Expected request without client certificate to fail")
+	}
+
+	peer, server, err := request([]tls.Certificate{clientCert.tls})
+	if err != nil {
+		t.Fatalf("Unexpected error with client certificate: %s", err)
+	}
+	if peer != "client" || server != "server-1" {
+		t.Errorf("Unexpected certificates. Client %q server %q", peer, server)
+	}
+
+	// SIGHUP reloads the certificate
+	writeTestFile(t, c.TLSCertFile, makeTestCert(t, "server-2", ca).pem)
+	_ = syscall.Kill(os.Getpid(), syscall.SIGHUP)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, server, err = request([]tls.Certificate{clientCert.tls})
+		if err == nil && server == "server-2" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Certificate was not reloaded after SIGHUP. Got %q (%v)", server, err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// An invalid certificate keeps the previous one
+	writeTestFile(t, c.TLSCertFile, []byte("invalid"))
+	_ = syscall.Kill(os.Getpid(), syscall.SIGHUP)
+	time.Sleep(100 * time.Millisecond)
+
+	if _, server, err = request([]tls.Certificate{clientCert.tls}); err != nil || server != "server-2" {
+		t.Errorf("Expected previous certificate after a failed reload. Got %q (%v)", server, err)
+	}
+}
+
+func TestHTTPServerTLSOptionalClientCert(t *testing.T) {
+	c := config.Current()
+
+	dir, err := ioutil.TempDir("", "chevron-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	ca := makeTestCert(t, "Test CA", nil)
+
+	c.TLSCertFile = path.Join(dir, "server.pem")
+	c.TLSKeyFile = path.Join(dir, "server.pem")
+	c.TLSClientCAFile = path.Join(dir, "ca.pem")
+	c.TLSClientAuth = "optional"
+
+	writeTestFile(t, c.TLSCertFile, makeTestCert(t, "server", ca).pem)
+	writeTestFile(t, c.TLSClientCAFile, ca.pem)
+
+	addr, stop := startTestHTTPServer(t, c, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer func() {
+		stop <- true
+		<-stop
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, ServerName: "localhost"}},
+	}
+
+	res, err := client.Get("https://" + addr + "/")
+	if err != nil {
+		t.Fatalf("Expected request without client certificate to work. Got %s", err)
+	}
+	_ = res.Body.Close()
+
+	c.TLSCertFile = path.Join(dir, "missing.pem")
+	if _, err := makeHTTPServer(nil, c, nil); err == nil {
+		t.Errorf("Expected error with a missing certificate")
+	}
+}
@@ -14,8 +14,10 @@ import (
 	"github.com/quan-to/chevron/internal/config"
 	"github.com/quan-to/chevron/internal/tools"
 	"github.com/quan-to/chevron/pkg/QuantoError"
+	"github.com/quan-to/chevron/pkg/interfaces"
 	"github.com/quan-to/chevron/pkg/models"
 
+	"github.com/gorilla/mux"
 	"github.com/quan-to/slog"
 )
 
@@ -84,6 +86,11 @@ func InvalidFieldData(field string, message string, w http.ResponseWriter, r *ht
 	WriteJSON(QuantoError.New(QuantoError.InvalidFieldData, field, message, nil), 400, w, r, logI)
 }
 
+// AlreadyExists helper method to return an already exists error to http client
+func AlreadyExists(field string, message string, w http.ResponseWriter, r *http.Request, logI slog.Instance) {
+	WriteJSON(QuantoError.New(QuantoError.AlreadyExists, field, message, nil), 400, w, r, logI)
+}
+
 // PermissionDenied helper method to return an permission denied error to http client
 func PermissionDenied(field string, message string, w http.ResponseWriter, r *http.Request, logI slog.Instance) {
 	WriteJSON(QuantoError.New(QuantoError.PermissionDenied, field, message, nil), 400, w, r, logI)
@@ -176,6 +183,101 @@ func wrapContextWithRequestID(r *http.Request) context.Context {
 	return context.WithValue(r.Context(), tools.CtxRequestID, requestID)
 }
 
+// trustedProxies returns the networks in HTTP_TRUSTED_PROXIES. Single addresses are returned as host networks
+func trustedProxies() []*net.IPNet {
+	var networks []*net.IPNet
+
+	for _, proxy := range splitList(config.HttpTrustedProxies) {
+		if ip := net.ParseIP(proxy); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		if _, network, err := net.ParseCIDR(proxy); err == nil {
+			networks = append(networks, network)
+		}
+	}
+
+	return networks
+}
+
+func isTrustedProxy(addr string, proxies []*net.IPNet) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range proxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clientIdentity returns the address of the http client
+// X-Forwarded-For is only used when the request comes from one of HTTP_TRUSTED_PROXIES.
+// In that case the client is the last forwarded address that is not a trusted proxy
+func clientIdentity(r *http.Request) string {
+	client, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		client = r.RemoteAddr
+	}
+
+	proxies := trustedProxies()
+	if !isTrustedProxy(client, proxies) {
+		return client
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header["X-Forwarded-For"], ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		addr := strings.TrimSpace(forwarded[i])
+		if addr == "" {
+			continue
+		}
+		client = addr
+		if !isTrustedProxy(addr, proxies) {
+			break
+		}
+	}
+
+	return client
+}
+
+// wrapContextWithActor adds who is doing the request to the context.
+// The user identified by actorMiddleware is used when there is one, otherwise the client address
+func wrapContextWithActor(ctx context.Context, r *http.Request) context.Context {
+	if actor, ok := r.Context().Value(tools.CtxActor).(string); ok && actor != "" {
+		return context.WithValue(ctx, tools.CtxActor, actor)
+	}
+
+	return context.WithValue(ctx, tools.CtxActor, clientIdentity(r))
+}
+
+// actorMiddleware identifies the requests with a valid token as done by the token user.
+// The token is not removed from the request
+func actorMiddleware(tm interfaces.TokenManager) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := popProxyToken(r.Header.Clone())
+
+			if token != "" && tm != nil && tm.Verify(token) == nil {
+				if user := tm.GetUserData(token); user != nil {
+					r = r.WithContext(context.WithValue(r.Context(), tools.CtxActor, "user:"+user.GetUsername()))
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func wrapLogWithRequestID(log slog.Instance, r *http.Request) slog.Instance {
 	id, ok := r.Header[config.RequestIDHeader]
 	if ok && len(id) >= 1 {
@@ -1,12 +1,18 @@
 package server
 
 import (
+	"context"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/quan-to/chevron/internal/agent"
+	"github.com/quan-to/chevron/internal/config"
+	"github.com/quan-to/chevron/internal/tools"
 	"github.com/quan-to/chevron/pkg/QuantoError"
+	"github.com/quan-to/chevron/pkg/models"
 	"github.com/quan-to/slog"
 )
 
@@ -110,3 +116,74 @@ func TestWriteJSON(test *testing.T) {
 	}
 
 }
+
+func TestClientIdentity(t *testing.T) {
+	defer config.Apply(config.Current())
+	config.HttpTrustedProxies = "10.0.0.1, 192.168.0.0/16"
+
+	testCases := []struct {
+		remoteAddr string
+		forwarded  []string
+		expected   string
+	}{
+		{"172.16.0.1:1000", nil, "172.16.0.1"},
+		// Untrusted clients cannot choose the address
+		{"172.16.0.1:1000", []string{"1.1.1.1"}, "172.16.0.1"},
+		{"10.0.0.1:1000", []string{"1.1.1.1"}, "1.1.1.1"},
+		// Addresses added before the trusted proxies are ignored
+		{"10.0.0.1:1000", []string{"6.6.6.6, 1.1.1.1, 192.168.1.1"}, "1.1.1.1"},
+		{"10.0.0.1:1000", []string{"6.6.6.6", "1.1.1.1"}, "1.1.1.1"},
+		{"10.0.0.1:1000", []string{"192.168.1.2, 192.168.1.1"}, "192.168.1.2"},
+		{"10.0.0.1:1000", nil, "10.0.0.1"},
+	}
+
+	for _, tc := range testCases {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tc.remoteAddr
+		for _, fwd := range tc.forwarded {
+			r.Header.Add("X-Forwarded-For", fwd)
+		}
+
+		if identity := clientIdentity(r); identity != tc.expected {
+			t.Errorf("%s %v: expected %s got %s", tc.remoteAddr, tc.forwarded, tc.expected, identity)
+		}
+	}
+}
+
+func TestWrapContextWithActor(t *testing.T) {
+	tm := agent.MakeMemoryTokenManager(nil)
+	token := tm.AddUser(&models.BasicUser{Username: "uploader", CreatedAt: time.Now()})
+
+	testCases := []struct {
+		header   string
+		token    string
+		expected string
+	}{
+		{"", "", "172.16.0.1"},
+		{"proxyToken", token, "user:uploader"},
+		{"Authorization", "Bearer " + token, "user:uploader"},
+		// Invalid tokens fallback to the address
+		{"proxyToken", "invalid", "172.16.0.1"},
+	}
+
+	for _, tc := range testCases {
+		r := httptest.NewRequest("POST", "/sks/addKey", nil)
+		r.RemoteAddr = "172.16.0.1:1000"
+		if tc.header != "" {
+			r.Header.Set(tc.header, tc.token)
+		}
+
+		actor := ""
+		handler := actorMiddleware(tm)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			actor = tools.GetActorFromContext(wrapContextWithActor(context.Background(), r))
+			if tc.token != "" && r.Header.Get(tc.header) != tc.token {
+				t.Errorf("%s: expected the token to be kept in the request", tc.header)
+			}
+		}))
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+
+		if actor != tc.expected {
+			t.Errorf("%s %s: expected actor %s got %s", tc.header, tc.token, tc.expected, actor)
+		}
+	}
+}
@@ -2,8 +2,14 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 
+	"github.com/quan-to/chevron/internal/cluster"
+	"github.com/quan-to/chevron/internal/tools"
 	"github.com/quan-to/chevron/pkg/interfaces"
 	"github.com/quan-to/chevron/pkg/models"
 
@@ -12,9 +18,10 @@ import (
 )
 
 type InternalEndpoint struct {
-	sm  interfaces.SecretsManager
-	gpg interfaces.PGPManager
-	log slog.Instance
+	sm       interfaces.SecretsManager
+	gpg      interfaces.PGPManager
+	exchange *cluster.PasswordExchange
+	log      slog.Instance
 }
 
 // MakeInternalEndpoint creates an instance to handle internal control endpoints such as key password data
@@ -26,15 +33,17 @@ func MakeInternalEndpoint(log slog.Instance, sm interfaces.SecretsManager, gpg i
 	}
 
 	return &InternalEndpoint{
-		sm:  sm,
-		gpg: gpg,
-		log: log,
+		sm:       sm,
+		gpg:      gpg,
+		exchange: cluster.MakePasswordExchange(log, sm),
+		log:      log,
 	}
 }
 
 func (ie *InternalEndpoint) AttachHandlers(r *mux.Router) {
 	r.HandleFunc("/__triggerKeyUnlock", ie.triggerKeyUnlock)
-	r.HandleFunc("/__getUnlockPasswords", ie.getUnlockPasswords).Methods("GET")
+	r.HandleFunc(cluster.ChallengePath, ie.clusterChallenge).Methods("GET")
+	r.HandleFunc(cluster.PasswordsPath, ie.clusterPasswords).Methods("POST")
 	r.HandleFunc("/__postEncryptedPasswords", ie.postUnlockPasswords).Methods("POST")
 }
 
@@ -57,7 +66,28 @@ func (ie *InternalEndpoint) triggerKeyUnlock(w http.ResponseWriter, r *http.Requ
 	LogExit(log, r, 200, n)
 }
 
-func (ie *InternalEndpoint) getUnlockPasswords(w http.ResponseWriter, r *http.Request) {
+// clusterChallenge returns a single use challenge that a peer must sign with the master key to request the passwords
+func (ie *InternalEndpoint) clusterChallenge(w http.ResponseWriter, r *http.Request) {
+	log := wrapLogWithRequestID(ie.log, r)
+	InitHTTPTimer(log, r)
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			CatchAllError(rec, w, r, log)
+		}
+	}()
+
+	challenge, err := ie.exchange.NewChallenge()
+	if err != nil {
+		InternalServerError("There was an error creating the challenge", err.Error(), w, r, log)
+		return
+	}
+
+	WriteJSON(cluster.ChallengeResponse{Challenge: challenge}, 200, w, r, log)
+}
+
+// clusterPasswords returns the master key encrypted passwords to a peer that proved it holds the master key
+func (ie *InternalEndpoint) clusterPasswords(w http.ResponseWriter, r *http.Request) {
 	ctx := wrapContextWithRequestID(r)
 	log := wrapLogWithRequestID(ie.log, r)
 	InitHTTPTimer(log, r)
@@ -68,32 +98,67 @@ func (ie *InternalEndpoint) getUnlockPasswords(w http.ResponseWriter, r *http.Re
 		}
 	}()
 
-	passwords := ie.sm.GetPasswords(ctx)
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 64*1024))
+	if err != nil {
+		InternalServerError("There was an error reading your request", err.Error(), w, r, log)
+		return
+	}
 
-	bodyData, _ := json.Marshal(passwords)
+	res, signature, err := ie.exchange.AnswerPasswordRequest(ctx, body, r.Header.Get(cluster.SignatureHeader))
+	if err != nil {
+		log.Warn("Rejected cluster password request from %s: %s", r.RemoteAddr, err)
+		if errors.Is(err, cluster.ErrUnauthorized) {
+			PermissionDenied(cluster.SignatureHeader, err.Error(), w, r, log)
+		} else {
+			InvalidFieldData("body", err.Error(), w, r, log)
+		}
+		return
+	}
 
+	w.Header().Set(cluster.SignatureHeader, signature)
 	w.Header().Set("Content-Type", models.MimeJSON)
 	w.WriteHeader(200)
-	n, _ := w.Write(bodyData)
+	n, _ := w.Write(res)
 	LogExit(log, r, 200, n)
 }
 
+// postUnlockPasswords stores the master key encrypted passwords posted by a peer that signed the body with the master key
 func (ie *InternalEndpoint) postUnlockPasswords(w http.ResponseWriter, r *http.Request) {
 	ctx := wrapContextWithRequestID(r)
 	log := wrapLogWithRequestID(ie.log, r)
 	InitHTTPTimer(log, r)
 
+	defer func() {
+		if rec := recover(); rec != nil {
+			CatchAllError(rec, w, r, log)
+		}
+	}()
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 10*1024*1024))
+	if err != nil {
+		InternalServerError("There was an error reading your request", err.Error(), w, r, log)
+		return
+	}
+
+	if err := ie.exchange.VerifySignature(ctx, body, r.Header.Get(cluster.SignatureHeader)); err != nil {
+		log.Warn("Rejected encrypted passwords from %s: %s", r.RemoteAddr, err)
+		PermissionDenied(cluster.SignatureHeader, err.Error(), w, r, log)
+		return
+	}
+
 	var passwords map[string]string
 
-	if !UnmarshalBodyOrDie(&passwords, w, r, log) {
+	if err := json.Unmarshal(body, &passwords); err != nil {
+		InvalidFieldData("body", "The body is not a map of fingerprints to encrypted passwords", w, r, log)
 		return
 	}
 
-	defer func() {
-		if rec := recover(); rec != nil {
-			CatchAllError(rec, w, r, log)
+	for k := range passwords {
+		if !tools.IsFingerPrint(k) {
+			InvalidFieldData("FingerPrint", fmt.Sprintf("%q is not a valid key fingerprint", k), w, r, log)
+			return
 		}
-	}()
+	}
 
 	for k, v := range passwords {
 		ie.sm.PutEncryptedPassword(ctx, k, v)
@@ -3,15 +3,25 @@ package server
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"os"
 	"testing"
 
+	"github.com/gorilla/mux"
+	"github.com/quan-to/chevron/internal/cluster"
 	remote_signer "github.com/quan-to/chevron/internal/config"
+	"github.com/quan-to/chevron/internal/etc/magicbuilder"
+	"github.com/quan-to/chevron/internal/tools"
 	"github.com/quan-to/chevron/pkg/QuantoError"
+	"github.com/quan-to/chevron/pkg/interfaces"
 	"github.com/quan-to/chevron/test"
+	"golang.org/x/crypto/curve25519"
 )
 
 /*
@@ -45,83 +55,60 @@ func TestUnlockLocalKeys(t *testing.T) {
 }
 */
 
-func TestGetUnlockPasswords(t *testing.T) {
+func TestPostUnlockPassword(t *testing.T) {
 	ctx := context.Background()
 	filename := fmt.Sprintf("key-password-utf8-%s.txt", test.TestKeyFingerprint)
 
-	encPass, err := gpg.Encrypt(ctx, filename, sm.GetMasterKeyFingerPrint(ctx), []byte(test.TestKeyPassword), remote_signer.SMEncryptedDataOnly)
+	encPass, err := gpg.Encrypt(ctx, filename, sm.GetMasterKeyFingerPrint(ctx), []byte(test.TestKeyFingerprint), remote_signer.SMEncryptedDataOnly)
 
 	if err != nil {
 		t.Errorf("Error saving password: %s", err)
 		t.FailNow()
 	}
 
-	sm.PutEncryptedPassword(ctx, test.TestKeyFingerprint, encPass)
+	payload := map[string]string{
+		test.TestKeyFingerprint: encPass,
+	}
 
-	req, err := http.NewRequest("GET", "/__internal/__getUnlockPasswords", nil)
+	d, _ := json.Marshal(payload)
 
+	signature, err := sm.SignWithMasterKey(ctx, d)
 	errorDie(err, t)
 
-	res := executeRequest(req)
-
-	d, err := ioutil.ReadAll(res.Body)
+	// region Unsigned requests are rejected
+	req, err := http.NewRequest("POST", "/__internal/__postEncryptedPasswords", bytes.NewReader(d))
+	errorDie(err, t)
 
-	if res.Code != 200 {
-		var errObj QuantoError.ErrorObject
-		err := json.Unmarshal(d, &errObj)
-		errorDie(err, t)
-		errorDie(fmt.Errorf(errObj.Message), t)
+	res := executeRequest(req)
+	var errObj QuantoError.ErrorObject
+	errorDie(json.Unmarshal(res.Body.Bytes(), &errObj), t)
+	if errObj.ErrorCode != QuantoError.PermissionDenied {
+		t.Fatalf("expected unsigned passwords to be rejected with %s, got %s", QuantoError.PermissionDenied, errObj.ErrorCode)
 	}
-
+	// endregion
+	// region Invalid fingerprints are rejected
+	invalid, _ := json.Marshal(map[string]string{"../../../tmp/evil": encPass})
+	invalidSignature, err := sm.SignWithMasterKey(ctx, invalid)
 	errorDie(err, t)
 
-	var data map[string]string
-
-	err = json.Unmarshal(d, &data)
-
+	req, err = http.NewRequest("POST", "/__internal/__postEncryptedPasswords", bytes.NewReader(invalid))
 	errorDie(err, t)
+	req.Header.Set(cluster.SignatureHeader, tools.GPG2Quanto(invalidSignature, sm.GetMasterKeyFingerPrint(ctx), "SHA512"))
 
-	found := false
-
-	for k, v := range data {
-		if k == test.TestKeyFingerprint {
-			found = true
-			if v != encPass {
-				t.Errorf("The encrypted password is not the expected one.")
-			}
-			break
-		}
-	}
-
-	if !found {
-		t.Errorf("The added password was not found at the password list")
-	}
-}
-
-func TestPostUnlockPassword(t *testing.T) {
-	ctx := context.Background()
-	filename := fmt.Sprintf("key-password-utf8-%s.txt", test.TestKeyFingerprint)
-
-	encPass, err := gpg.Encrypt(ctx, filename, sm.GetMasterKeyFingerPrint(ctx), []byte(test.TestKeyFingerprint), remote_signer.SMEncryptedDataOnly)
-
-	if err != nil {
-		t.Errorf("Error saving password: %s", err)
-		t.FailNow()
-	}
-
-	payload := map[string]string{
-		test.TestKeyFingerprint: encPass,
+	res = executeRequest(req)
+	errorDie(json.Unmarshal(res.Body.Bytes(), &errObj), t)
+	if errObj.ErrorCode != QuantoError.InvalidFieldData {
+		t.Fatalf("expected invalid fingerprint to be rejected with %s, got %s", QuantoError.InvalidFieldData, errObj.ErrorCode)
 	}
+	// endregion
 
-	d, _ := json.Marshal(payload)
-
-	r := bytes.NewReader(d)
-
-	req, err := http.NewRequest("POST", "/__internal/__postEncryptedPasswords", r)
+	req, err = http.NewRequest("POST", "/__internal/__postEncryptedPasswords", bytes.NewReader(d))
 
 	errorDie(err, t)
 
-	res := executeRequest(req)
+	req.Header.Set(cluster.SignatureHeader, tools.GPG2Quanto(signature, sm.GetMasterKeyFingerPrint(ctx), "SHA512"))
+
+	res = executeRequest(req)
 
 	d, err = ioutil.ReadAll(res.Body)
 
@@ -168,3 +155,103 @@ func TestTriggerKeyUnlock(t *testing.T) {
 
 	// TODO: Check if the key was really unlocked
 }
+
+func TestClusterPasswordExchange(t *testing.T) {
+	ctx := context.Background()
+
+	// Node A already has the password of the test key
+	sm.PutKeyPassword(ctx, test.TestKeyFingerprint, test.TestKeyPassword)
+	nodeA := httptest.NewServer(router)
+	defer nodeA.Close()
+
+	// Nodes B and C share the master key but have their keys locked
+	makeNode := func() (interfaces.SecretsManager, interfaces.PGPManager, *httptest.Server) {
+		nodeSM := magicbuilder.MakeSM(nil, dbh)
+		nodeGPG := magicbuilder.MakePGP(nil, dbh)
+		nodeGPG.LoadKeys(ctx)
+		r := mux.NewRouter()
+		MakeInternalEndpoint(nil, nodeSM, nodeGPG).AttachHandlers(r.PathPrefix("/__internal").Subrouter())
+		return nodeSM, nodeGPG, httptest.NewServer(r)
+	}
+
+	smB, gpgB, nodeB := makeNode()
+	defer nodeB.Close()
+	smC, gpgC, nodeC := makeNode()
+	defer nodeC.Close()
+
+	if !gpgB.IsKeyLocked(test.TestKeyFingerprint) || !gpgC.IsKeyLocked(test.TestKeyFingerprint) {
+		errorDie(fmt.Errorf("expected test key to be locked in new nodes"), t)
+	}
+
+	// region B syncs from A and an unreachable peer
+	n := cluster.MakePasswordExchange(nil, smB).Sync(ctx, []string{nodeA.URL + "/__internal", "http://127.0.0.1:1/__internal"}, gpgB)
+	if n == 0 || gpgB.IsKeyLocked(test.TestKeyFingerprint) {
+		errorDie(fmt.Errorf("expected node B to receive the passwords and unlock the key"), t)
+	}
+	// endregion
+	// region C discovers B from a peers file and syncs from it
+	peersFile, err := ioutil.TempFile("", "peers")
+	errorDie(err, t)
+	defer os.Remove(peersFile.Name())
+	_, _ = peersFile.WriteString(nodeC.URL + "/__internal\n" + nodeB.URL + "/__internal\n")
+	_ = peersFile.Close()
+
+	n = cluster.MakePasswordExchange(nil, smC).SyncWith(ctx, cluster.MakeFileDiscovery(peersFile.Name()), gpgC)
+	if n == 0 || gpgC.IsKeyLocked(test.TestKeyFingerprint) {
+		errorDie(fmt.Errorf("expected node C to receive the passwords from node B and unlock the key"), t)
+	}
+	// endregion
+	// region Requests not signed by the master key
+	getChallenge := func() string {
+		res, err := http.Get(nodeA.URL + "/__internal" + cluster.ChallengePath)
+		errorDie(err, t)
+		defer res.Body.Close()
+		var c cluster.ChallengeResponse
+		errorDie(json.NewDecoder(res.Body).Decode(&c), t)
+		return c.Challenge
+	}
+
+	postPasswords := func(body []byte, signature string) int {
+		req, err := http.NewRequest("POST", nodeA.URL+"/__internal"+cluster.PasswordsPath, bytes.NewReader(body))
+		errorDie(err, t)
+		req.Header.Set(cluster.SignatureHeader, signature)
+		res, err := http.DefaultClient.Do(req)
+		errorDie(err, t)
+		_ = res.Body.Close()
+		return res.StatusCode
+	}
+
+	body, _ := json.Marshal(cluster.PasswordRequest{Challenge: getChallenge(), PublicKey: "AAAA"})
+	if code := postPasswords(body, ""); code == 200 {
+		errorDie(fmt.Errorf("expected unsigned request to be rejected"), t)
+	}
+
+	otherBody, _ := json.Marshal(cluster.PasswordRequest{Challenge: "other", PublicKey: "AAAA"})
+	otherSig, err := sm.SignWithMasterKey(ctx, otherBody)
+	errorDie(err, t)
+	if code := postPasswords(body, tools.GPG2Quanto(otherSig, sm.GetMasterKeyFingerPrint(ctx), "SHA512")); code == 200 {
+		errorDie(fmt.Errorf("expected request with signature of other data to be rejected"), t)
+	}
+
+	if code := postPasswords(otherBody, tools.GPG2Quanto(otherSig, sm.GetMasterKeyFingerPrint(ctx), "SHA512")); code == 200 {
+		errorDie(fmt.Errorf("expected request with unknown challenge to be rejected"), t)
+	}
+	// endregion
+	// region Challenges are single use
+	scalar := make([]byte, curve25519.ScalarSize)
+	_, _ = rand.Read(scalar)
+	key, _ := curve25519.X25519(scalar, curve25519.Basepoint)
+	body, _ = json.Marshal(cluster.PasswordRequest{Challenge: getChallenge(), PublicKey: base64.StdEncoding.EncodeToString(key)})
+	sig, err := sm.SignWithMasterKey(ctx, body)
+	errorDie(err, t)
+	quantoSig := tools.GPG2Quanto(sig, sm.GetMasterKeyFingerPrint(ctx), "SHA512")
+
+	if code := postPasswords(body, quantoSig); code != 200 {
+		errorDie(fmt.Errorf("expected signed request to be accepted got %d", code), t)
+	}
+
+	if code := postPasswords(body, quantoSig); code == 200 {
+		errorDie(fmt.Errorf("expected replayed request to be rejected"), t)
+	}
+	// endregion
+}
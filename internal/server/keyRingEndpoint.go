@@ -215,6 +215,7 @@ func (kre *KeyRingEndpoint) addPrivateKey(w http.ResponseWriter, r *http.Request
 	var data models.KeyRingAddPrivateKeyData
 	ctx := wrapContextWithRequestID(r)
 	ctx = wrapContextWithDatabaseHandler(kre.dbh, ctx)
+	ctx = wrapContextWithActor(ctx, r)
 	log := wrapLogWithRequestID(kre.log, r)
 	InitHTTPTimer(log, r)
 
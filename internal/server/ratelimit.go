@@ -0,0 +1,185 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/quan-to/chevron/internal/agent"
+	"github.com/quan-to/chevron/internal/config"
+	"github.com/quan-to/chevron/internal/ratelimit"
+	"github.com/quan-to/chevron/pkg/QuantoError"
+	"github.com/quan-to/chevron/pkg/interfaces"
+	"github.com/quan-to/slog"
+)
+
+const (
+	operationPrivateKey = "privateKey"
+	operationLookup     = "lookup"
+)
+
+// privateKeyOperations are the endpoints that use a private key, relative to the /remoteSigner prefix
+var privateKeyOperations = map[string]bool{
+	"/gpg/sign":              true,
+	"/gpg/signQuanto":        true,
+	"/gpg/decrypt":           true,
+	"/gpg/generateKey":       true,
+	"/gpg/unlockKey":         true,
+	"/fieldCipher/decipher":  true,
+	"/keyRing/addPrivateKey": true,
+}
+
+// unlimitedPrefixes are the endpoints that are not rate limited: health checks, cluster and static pages
+var unlimitedPrefixes = []string{
+	"/tests/",
+	"/healthz",
+	"/readyz",
+	"/__internal/",
+	"/swagger",
+	"/assets/",
+	"/graphiql",
+}
+
+// operationClass returns the budget used by the request path or an empty string if it is not limited
+func operationClass(path string) string {
+	path = strings.TrimPrefix(path, "/remoteSigner")
+
+	for _, prefix := range unlimitedPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return ""
+		}
+	}
+
+	if privateKeyOperations[strings.TrimSuffix(path, "/")] || path == "/agent" || strings.HasPrefix(path, "/agent/") {
+		return operationPrivateKey
+	}
+
+	return operationLookup
+}
+
+type rateBudget struct {
+	requests int64
+	inFlight int64
+}
+
+// rateLimiter limits the requests and the concurrent operations of each client
+type rateLimiter struct {
+	log     slog.Instance
+	counter interfaces.RateCounter
+	tm      interfaces.TokenManager
+	window  time.Duration
+	budgets map[string]rateBudget
+}
+
+// makeRateLimiter creates the rate limiter from the configuration. Returns nil if rate limiting is disabled
+func makeRateLimiter(log slog.Instance, tm interfaces.TokenManager) *rateLimiter {
+	if !config.RateLimitEnable {
+		return nil
+	}
+
+	if log == nil {
+		log = slog.Scope("RateLimit")
+	} else {
+		log = log.SubScope("RateLimit")
+	}
+
+	var counter interfaces.RateCounter = ratelimit.MakeMemoryCounter()
+	if strings.ToLower(config.RateLimitBackend) == "redis" {
+		counter = ratelimit.MakeRedisCounter(agent.MakeRedisClient(), config.RateLimitRedisPrefix)
+	}
+
+	log.Info("Rate limiting enabled using %s counters", strings.ToLower(config.RateLimitBackend))
+
+	return &rateLimiter{
+		log:     log,
+		counter: counter,
+		tm:      tm,
+		window:  config.RateLimitWindow,
+		budgets: map[string]rateBudget{
+			operationPrivateKey: {
+				requests: int64(config.RateLimitPrivateKeyRequests),
+				inFlight: int64(config.RateLimitPrivateKeyInFlight),
+			},
+			operationLookup: {
+				requests: int64(config.RateLimitLookupRequests),
+				inFlight: int64(config.RateLimitLookupInFlight),
+			},
+		},
+	}
+}
+
+// identity returns the key of the client. Clients with a valid token are identified by the username, others by the address
+func (rl *rateLimiter) identity(r *http.Request) string {
+	token := popProxyToken(r.Header.Clone())
+
+	if token != "" && rl.tm != nil && rl.tm.Verify(token) == nil {
+		if user := rl.tm.GetUserData(token); user != nil {
+			return "user:" + user.GetUsername()
+		}
+	}
+
+	return "ip:" + clientIdentity(r)
+}
+
+// busy writes a ServerIsBusy error asking the client to retry after the specified time
+func (rl *rateLimiter) busy(w http.ResponseWriter, r *http.Request, retryAfter time.Duration, message string) {
+	InitHTTPTimer(rl.log, r)
+	w.Header().Set("Retry-After", fmt.Sprint(int64(math.Max(1, math.Ceil(retryAfter.Seconds())))))
+	WriteJSON(QuantoError.New(QuantoError.ServerIsBusy, "rateLimit", message, nil), http.StatusTooManyRequests, w, r, rl.log)
+}
+
+// middleware applies the budget of the request operation. Counter errors do not block the requests
+func (rl *rateLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		class := operationClass(r.URL.Path)
+		if class == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		budget := rl.budgets[class]
+		key := class + ":" + rl.identity(r)
+		ctx := context.Background()
+
+		count, reset, err := rl.counter.Hit(ctx, key, rl.window)
+		if err != nil {
+			rl.log.Warn("Error counting request of %s: %s", key, err)
+		} else if count > budget.requests {
+			rl.log.Warn("Rate limit exceeded by %s", key)
+			rl.busy(w, r, reset, fmt.Sprintf("Too many requests. The limit is %d per %s", budget.requests, rl.window))
+			return
+		}
+
+		inFlight, err := rl.counter.Acquire(ctx, key, config.HttpWriteTimeout)
+		if err != nil {
+			rl.log.Warn("Error counting in-flight requests of %s: %s", key, err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		defer func() {
+			if err := rl.counter.Release(ctx, key); err != nil {
+				rl.log.Warn("Error releasing in-flight request of %s: %s", key, err)
+			}
+		}()
+
+		if inFlight > budget.inFlight {
+			rl.log.Warn("Concurrency limit exceeded by %s", key)
+			rl.busy(w, r, time.Second, fmt.Sprintf("Too many concurrent requests. The limit is %d", budget.inFlight))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// attach adds the rate limiter to the router
+func (rl *rateLimiter) attach(r *mux.Router) {
+	if rl != nil {
+		r.Use(rl.middleware)
+	}
+}
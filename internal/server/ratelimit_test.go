@@ -0,0 +1,255 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/quan-to/chevron/internal/agent"
+	"github.com/quan-to/chevron/internal/config"
+	"github.com/quan-to/chevron/internal/ratelimit"
+	"github.com/quan-to/chevron/pkg/QuantoError"
+	"github.com/quan-to/chevron/pkg/models"
+)
+
+func TestOperationClass(t *testing.T) {
+	classes := map[string]string{
+		"/gpg/sign":                          operationPrivateKey,
+		"/remoteSigner/gpg/decrypt":          operationPrivateKey,
+		"/remoteSigner/fieldCipher/decipher": operationPrivateKey,
+		"/agent":                             operationPrivateKey,
+		"/agent/":                            operationPrivateKey,
+		"/gpg/verifySignature":               operationLookup,
+		"/remoteSigner/sks/getKey":           operationLookup,
+		"/pks/lookup":                        operationLookup,
+		"/agentAdmin":                        operationLookup,
+		"/tests/ping":                        "",
+		"/healthz":                           "",
+		"/remoteSigner/readyz":               "",
+		"/remoteSigner/__internal/challenge": "",
+		"/swagger/index.html":                "",
+	}
+
+	for path, expected := range classes {
+		if c := operationClass(path); c != expected {
+			t.Errorf("%s: expected class %q got %q", path, expected, c)
+		}
+	}
+}
+
+func makeTestRateLimiter(requests, inFlight int64) *rateLimiter {
+	budget := rateBudget{requests: requests, inFlight: inFlight}
+
+	return &rateLimiter{
+		log:     log,
+		counter: ratelimit.MakeMemoryCounter(),
+		tm:      agent.MakeMemoryTokenManager(nil),
+		window:  time.Minute,
+		budgets: map[string]rateBudget{operationPrivateKey: budget, operationLookup: budget},
+	}
+}
+
+func TestRateLimiterRequests(t *testing.T) {
+	rl := makeTestRateLimiter(2, 10)
+	h := rl.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	token := rl.tm.AddUser(&models.BasicUser{Username: "signer", CreatedAt: time.Now()})
+
+	do := func(path, remoteAddr, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", path, nil)
+		r.RemoteAddr = remoteAddr
+		if token != "" {
+			r.Header.Set("proxyToken", token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := do("/gpg/sign", "10.0.0.1:1000", ""); w.Code != http.StatusOK {
+			t.Fatalf("Expected request %d to be allowed got %d", i, w.Code)
+		}
+	}
+
+	w := do("/gpg/sign", "10.0.0.1:1000", "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected %d got %d", http.StatusTooManyRequests, w.Code)
+	}
+
+	if w.Header().Get("Retry-After") != "60" {
+		t.Errorf("Expected Retry-After 60 got %q", w.Header().Get("Retry-After"))
+	}
+
+	var qe QuantoError.ErrorObject
+	if err := json.Unmarshal(w.Body.Bytes(), &qe); err != nil || qe.ErrorCode != QuantoError.ServerIsBusy {
+		t.Errorf("Expected %s error got %s (%v)", QuantoError.ServerIsBusy, w.Body.String(), err)
+	}
+
+	// Lookups have a separate budget
+	if w := do("/sks/getKey", "10.0.0.1:1000", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected lookup to be allowed got %d", w.Code)
+	}
+
+	// Unlimited endpoints
+	for i := 0; i < 5; i++ {
+		if w := do("/tests/ping", "10.0.0.1:1000", ""); w.Code != http.StatusOK {
+			t.Errorf("Expected ping to be allowed got %d", w.Code)
+		}
+	}
+
+	// Other clients have their own budget
+	if w := do("/gpg/sign", "10.0.0.2:1000", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected request from other address to be allowed got %d", w.Code)
+	}
+
+	// Valid tokens are limited by user, from any address
+	for i, addr := range []string{"10.0.0.1:1000", "10.0.0.3:1000"} {
+		if w := do("/gpg/sign", addr, token); w.Code != http.StatusOK {
+			t.Errorf("Expected request %d with token to be allowed got %d", i, w.Code)
+		}
+	}
+
+	if w := do("/gpg/sign", "10.0.0.4:1000", token); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected user budget to be exceeded got %d", w.Code)
+	}
+
+	// Invalid tokens fallback to the address
+	if w := do("/gpg/sign", "10.0.0.2:1000", "invalid"); w.Code != http.StatusOK {
+		t.Errorf("Expected request with invalid token to use the address budget got %d", w.Code)
+	}
+
+	if w := do("/gpg/sign", "10.0.0.2:1000", "other-invalid"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected invalid tokens to share the address budget got %d", w.Code)
+	}
+}
+
+func TestRateLimiterForwardedFor(t *testing.T) {
+	defer config.Apply(config.Current())
+
+	rl := makeTestRateLimiter(1, 10)
+	h := rl.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	do := func(remoteAddr, forwardedFor string) int {
+		r := httptest.NewRequest("POST", "/gpg/sign", nil)
+		r.RemoteAddr = remoteAddr
+		r.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// Clients cannot get a new budget by changing X-Forwarded-For
+	config.HttpTrustedProxies = ""
+	if code := do("10.0.0.1:1000", "192.168.0.1"); code != http.StatusOK {
+		t.Fatalf("Expected first request to be allowed got %d", code)
+	}
+
+	if code := do("10.0.0.1:1000", "192.168.0.2"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected spoofed X-Forwarded-For to share the address budget got %d", code)
+	}
+
+	// Trusted proxies forward the client address
+	config.HttpTrustedProxies = "10.0.0.100"
+	if code := do("10.0.0.100:1000", "192.168.0.3"); code != http.StatusOK {
+		t.Errorf("Expected forwarded client to have its own budget got %d", code)
+	}
+
+	if code := do("10.0.0.100:1000", "192.168.0.3"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected forwarded client budget to be exceeded got %d", code)
+	}
+}
+
+func TestRateLimiterConcurrency(t *testing.T) {
+	rl := makeTestRateLimiter(100, 1)
+
+	started := make(chan bool)
+	release := make(chan bool)
+
+	h := rl.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- true
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	do := func() int {
+		r := httptest.NewRequest("POST", "/gpg/decrypt", bytes.NewReader(nil))
+		r.RemoteAddr = "10.0.0.1:1000"
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	result := make(chan int)
+	go func() { result <- do() }()
+	<-started
+
+	// The handler is not called for the second request
+	if code := do(); code != http.StatusTooManyRequests {
+		t.Errorf("Expected concurrent request to be rejected got %d", code)
+	}
+
+	release <- true
+	if code := <-result; code != http.StatusOK {
+		t.Errorf("Expected first request to succeed got %d", code)
+	}
+
+	// The slot is released after the request
+	go func() { result <- do() }()
+	<-started
+	release <- true
+	if code := <-result; code != http.StatusOK {
+		t.Errorf("Expected request after release to succeed got %d", code)
+	}
+}
+
+func TestRateLimiterMux(t *testing.T) {
+	defer config.Apply(config.Current())
+	config.RateLimitEnable = true
+	config.RateLimitBackend = "memory"
+	config.RateLimitWindow = time.Minute
+	config.RateLimitPrivateKeyRequests = 1
+	config.RateLimitPrivateKeyInFlight = 1
+	config.RateLimitLookupRequests = 100
+	config.RateLimitLookupInFlight = 10
+
+	ts := httptest.NewServer(GenRemoteSignerServerMux(log, sm, gpg, dbh))
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/remoteSigner/gpg/sign", "application/json", bytes.NewReader([]byte("{}")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+
+	if res.StatusCode == http.StatusTooManyRequests {
+		t.Fatalf("Expected first request to be allowed")
+	}
+
+	res, err = http.Post(ts.URL+"/gpg/sign", "application/json", bytes.NewReader([]byte("{}")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+
+	if res.StatusCode != http.StatusTooManyRequests || res.Header.Get("Retry-After") == "" {
+		t.Errorf("Expected %d with Retry-After got %d", http.StatusTooManyRequests, res.StatusCode)
+	}
+
+	res, err = http.Get(ts.URL + "/tests/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+
+	if res.StatusCode == http.StatusTooManyRequests {
+		t.Errorf("Expected ping not to be limited")
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/quan-to/chevron/internal/agent"
 	"github.com/quan-to/chevron/internal/config"
+	"github.com/quan-to/chevron/internal/etc/kbBuilder"
 	_ "github.com/quan-to/chevron/internal/server/docs"
 	"github.com/quan-to/chevron/internal/server/pages"
 	"github.com/quan-to/chevron/internal/tools"
@@ -56,15 +57,20 @@ import (
 // GenRemoteSignerServerMux generates a remote signer HTTP Router
 func GenRemoteSignerServerMux(slog slog.Instance, sm interfaces.SecretsManager, gpg interfaces.PGPManager, dbh DatabaseHandler) *mux.Router {
 	var vm *vaultManager.VaultManager
+	var kb interfaces.StorageBackend
 	log := slog.Scope("MUX")
 
 	if config.VaultStorage {
 		vm = vaultManager.MakeVaultManager(log, config.KeyPrefix)
+		kb = vm
+	} else {
+		kb = kbBuilder.BuildKeyBackend(log)
 	}
 
 	ge := MakeGPGEndpoint(log, sm, gpg)
 	ie := MakeInternalEndpoint(log, sm, gpg)
 	te := MakeTestsEndpoint(log, vm, dbh)
+	he := MakeHealthEndpoint(log, sm, gpg, vm, kb, dbh)
 	kre := MakeKeyRingEndpoint(log, sm, gpg, dbh)
 	sks := MakeSKSEndpoint(log, sm, gpg, dbh)
 	tm := agent.MakeTokenManager(log, dbh)
@@ -89,10 +95,16 @@ func GenRemoteSignerServerMux(slog slog.Instance, sm interfaces.SecretsManager,
 	}
 
 	r := mux.NewRouter()
+	r.Use(actorMiddleware(tm))
+	makeRateLimiter(log, tm).attach(r)
+
 	if config.EnableSwagger {
 		r.PathPrefix("/swagger").HandlerFunc(httpSwagger.Handler())
 	}
 
+	// Health probes for / and /remoteSigner
+	he.AttachHandlers(r)
+
 	// Add for /
 	AddHKPEndpoints(log, dbh, r.PathPrefix("/pks").Subrouter())
 	ge.AttachHandlers(r.PathPrefix("/gpg").Subrouter())
@@ -132,36 +144,10 @@ func GenRemoteSignerServerMux(slog slog.Instance, sm interfaces.SecretsManager,
 }
 
 // RunRemoteSignerServer runs a remote signer server asynchronously and returns a stop channel
-func RunRemoteSignerServer(slog slog.Instance, sm interfaces.SecretsManager, gpg interfaces.PGPManager, dbh DatabaseHandler) chan bool {
-
+func RunRemoteSignerServer(slog slog.Instance, sm interfaces.SecretsManager, gpg interfaces.PGPManager, dbh DatabaseHandler) (chan bool, error) {
 	r := GenRemoteSignerServerMux(slog, sm, gpg, dbh)
 
-	listenAddr := fmt.Sprintf("0.0.0.0:%d", config.HttpPort)
-
-	srv := &http.Server{
-		Addr:    listenAddr,
-		Handler: r, // Pass our instance of gorilla/mux in.
-	}
-
-	stopChannel := make(chan bool)
-
-	go func() {
-		<-stopChannel
-		slog.Info("Received STOP. Closing server")
-		_ = srv.Close()
-		stopChannel <- true
-	}()
-
-	go func() {
-		if err := srv.ListenAndServe(); err != nil {
-			slog.Error(err)
-		}
-		slog.Info("HTTP Server Closed")
-	}()
-
-	slog.Info("Remote Signer is now listening at %s", listenAddr)
-
-	return stopChannel
+	return runHTTPServer(slog, r)
 }
 
 // RunRemoteSignerServerSingleKey runs a single key instance of remote signer server asynchronously and returns a stop channel
@@ -204,30 +190,5 @@ func RunRemoteSignerServerSingleKey(slog slog.Instance, sm interfaces.SecretsMan
 
 	r := GenRemoteSignerServerMux(slog, sm, gpg, dbh)
 
-	listenAddr := fmt.Sprintf("0.0.0.0:%d", config.HttpPort)
-
-	srv := &http.Server{
-		Addr:    listenAddr,
-		Handler: r, // Pass our instance of gorilla/mux in.
-	}
-
-	stopChannel := make(chan bool)
-
-	go func() {
-		<-stopChannel
-		slog.Info("Received STOP. Closing server")
-		_ = srv.Close()
-		stopChannel <- true
-	}()
-
-	go func() {
-		if err := srv.ListenAndServe(); err != nil {
-			slog.Error(err)
-		}
-		slog.Info("HTTP Server Closed")
-	}()
-
-	slog.Info("Remote Signer is now listening at %s", listenAddr)
-
-	return stopChannel, nil
+	return runHTTPServer(slog, r)
 }
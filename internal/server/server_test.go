@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path"
 	"runtime/debug"
 	"testing"
 
@@ -60,15 +61,23 @@ func TestMain(m *testing.M) {
 	u, _ := uuid.NewRandom()
 
 	config.DatabaseName = "qrs_test_" + u.String()
-	config.PrivateKeyFolder = "../../test/data"
+	keyFolder, err := test.MakeKeyFolder("../../test/data")
+	if err != nil {
+		slog.Fatal("Error creating the test key folder: %s", err)
+	}
+
+	config.PrivateKeyFolder = keyFolder
 	config.KeyPrefix = "testkey_"
 	config.KeysBase64Encoded = false
 	config.RethinkDBPoolSize = 1
 	config.EnableDatabase = false
+	config.AgentAdminUsername = "admin"
+	config.AgentAdminPassword = "admin"
+	config.AgentTOTPEncryptionKey = "test-totp-key"
 
 	config.MasterGPGKeyBase64Encoded = false
-	config.MasterGPGKeyPath = "../../test/data/testkey_privateTestKey.gpg"
-	config.MasterGPGKeyPasswordPath = "../../test/data/testprivatekeyPassword.txt"
+	config.MasterGPGKeyPath = path.Join(keyFolder, test.TestKeyFile)
+	config.MasterGPGKeyPasswordPath = path.Join(keyFolder, test.TestKeyPasswordFile)
 
 	ctx := context.Background()
 	dbh, err = agent.MakeDatabaseHandler(log)
@@ -99,6 +108,7 @@ func TestMain(m *testing.M) {
 	slog.SetTestMode()
 	code := m.Run()
 	slog.UnsetTestMode()
+	_ = os.RemoveAll(keyFolder)
 	os.Exit(code)
 }
 
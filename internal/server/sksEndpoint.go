@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/quan-to/chevron/internal/agent"
 
@@ -46,7 +48,10 @@ func (sks *SKSEndpoint) AttachHandlers(r *mux.Router) {
 	r.HandleFunc("/searchByFingerPrint", sks.searchByFingerPrint).Methods("GET")
 	r.HandleFunc("/searchByEmail", sks.searchByEmail).Methods("GET")
 	r.HandleFunc("/search", sks.search).Methods("GET")
+	r.HandleFunc("/searchKeys", sks.searchKeys).Methods("GET")
 	r.HandleFunc("/addKey", sks.addKey).Methods("POST")
+	r.HandleFunc("/keyHistory", sks.keyHistory).Methods("GET")
+	r.HandleFunc("/restoreKey", sks.restoreKey).Methods("POST")
 }
 
 // Get GPG Key godoc
@@ -338,6 +343,119 @@ func (sks *SKSEndpoint) search(w http.ResponseWriter, r *http.Request) {
 	LogExit(log, r, 200, n)
 }
 
+// parseSearchKeysQuery reads a models.GPGKeySearchQuery from the url query parameters
+// Returns the name of the invalid field in case of error
+func parseSearchKeysQuery(r *http.Request) (query models.GPGKeySearchQuery, field string, err error) {
+	q := r.URL.Query()
+
+	query = models.GPGKeySearchQuery{
+		Query:     q.Get("q"),
+		Algorithm: q.Get("algorithm"),
+		SortBy:    q.Get("sortBy"),
+		SortOrder: q.Get("sortOrder"),
+		Cursor:    q.Get("cursor"),
+	}
+
+	ints := map[string]*int{
+		"minKeyBits": &query.MinKeyBits,
+		"maxKeyBits": &query.MaxKeyBits,
+		"limit":      &query.Limit,
+	}
+
+	for name, dst := range ints {
+		if v := q.Get(name); v != "" {
+			*dst, err = strconv.Atoi(v)
+			if err != nil {
+				return query, name, fmt.Errorf("%s should be a number", name)
+			}
+		}
+	}
+
+	bools := map[string]*bool{
+		"fuzzy":          &query.Fuzzy,
+		"excludeExpired": &query.ExcludeExpired,
+	}
+
+	for name, dst := range bools {
+		if v := q.Get(name); v != "" {
+			*dst, err = strconv.ParseBool(v)
+			if err != nil {
+				return query, name, fmt.Errorf("%s should be true or false", name)
+			}
+		}
+	}
+
+	times := map[string]**time.Time{
+		"expiresAfter":  &query.ExpiresAfter,
+		"expiresBefore": &query.ExpiresBefore,
+	}
+
+	for name, dst := range times {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return query, name, fmt.Errorf("%s should be a RFC3339 date", name)
+			}
+			*dst = &t
+		}
+	}
+
+	query = query.Normalized()
+	err = query.Validate()
+	if err != nil {
+		return query, "query", err
+	}
+
+	return query, "", nil
+}
+
+// Search GPG Keys godoc
+// @id pks-search-keys
+// @tags Public Key Server, Key Store
+// @Summary Searches for GPG Keys with prefix, fuzzy and domain matching, filters and cursor pagination
+// @Produce json
+// @param q query string false "Name or email prefix, @domain or fingerprint (empty matches all keys)"
+// @param fuzzy query bool false "Also match similar names and emails (default: false)"
+// @param minKeyBits query int false "Minimum key size"
+// @param maxKeyBits query int false "Maximum key size"
+// @param algorithm query string false "Public key algorithm (RSA, DSA, ECDSA, ECDH, ELGAMAL)"
+// @param expiresAfter query string false "Only keys that are valid after this RFC3339 date"
+// @param expiresBefore query string false "Only keys that expire before this RFC3339 date"
+// @param excludeExpired query bool false "Do not return expired keys (default: false)"
+// @param sortBy query string false "relevance, fingerPrint, name, email, keyBits or expiresAt (default: relevance for fuzzy searches, fingerPrint otherwise)"
+// @param sortOrder query string false "asc or desc (default: desc for relevance, asc otherwise)"
+// @param cursor query string false "NextCursor returned by the previous page"
+// @param limit query int false "Maximum number of keys returned (default: 100, max: 1000)"
+// @Success 200 {object} models.GPGKeySearchResult
+// @Failure default {object} QuantoError.ErrorObject
+// @Router /sks/searchKeys [get]
+func (sks *SKSEndpoint) searchKeys(w http.ResponseWriter, r *http.Request) {
+	log := wrapLogWithRequestID(sks.log, r)
+	InitHTTPTimer(log, r)
+	ctx := wrapContextWithRequestID(r)
+	ctx = wrapContextWithDatabaseHandler(sks.dbh, ctx)
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			CatchAllError(rec, w, r, log)
+		}
+	}()
+
+	query, field, err := parseSearchKeysQuery(r)
+	if err != nil {
+		InvalidFieldData(field, err.Error(), w, r, log)
+		return
+	}
+
+	result, err := keymagic.PKSSearchKeys(ctx, query)
+	if err != nil {
+		InternalServerError(err.Error(), nil, w, r, log)
+		return
+	}
+
+	WriteJSON(result, 200, w, r, log)
+}
+
 // Add Public Key godoc
 // @id pks-add-public-key
 // @tags Public Key Server, Key Store
@@ -353,6 +471,7 @@ func (sks *SKSEndpoint) addKey(w http.ResponseWriter, r *http.Request) {
 	log := wrapLogWithRequestID(sks.log, r)
 	InitHTTPTimer(log, r)
 	ctx = wrapContextWithDatabaseHandler(sks.dbh, ctx)
+	ctx = wrapContextWithActor(ctx, r)
 
 	var data models.SKSAddKey
 
@@ -378,3 +497,99 @@ func (sks *SKSEndpoint) addKey(w http.ResponseWriter, r *http.Request) {
 	n, _ := w.Write([]byte("OK"))
 	LogExit(log, r, 200, n)
 }
+
+// Get GPG Key History godoc
+// @id pks-key-history
+// @tags Public Key Server, Key Store
+// @Summary Lists all changes made to a GPG Public Key, oldest first
+// @Produce json
+// @param fingerPrint query string true "Fingerprint of the key you want the history"
+// @Success 200 {object} []models.GPGKeyHistory
+// @Failure default {object} QuantoError.ErrorObject
+// @Router /sks/keyHistory [get]
+func (sks *SKSEndpoint) keyHistory(w http.ResponseWriter, r *http.Request) {
+	log := wrapLogWithRequestID(sks.log, r)
+	InitHTTPTimer(log, r)
+	ctx := wrapContextWithRequestID(r)
+	ctx = wrapContextWithDatabaseHandler(sks.dbh, ctx)
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			CatchAllError(rec, w, r, log)
+		}
+	}()
+
+	fingerPrint := r.URL.Query().Get("fingerPrint")
+
+	if fingerPrint == "" {
+		InvalidFieldData("fingerPrint", "you should provide a fingerPrint", w, r, log)
+		return
+	}
+
+	history, err := keymagic.PKSGetKeyHistory(ctx, fingerPrint)
+
+	if err != nil {
+		if strings.EqualFold(err.Error(), "not found") {
+			NotFound("fingerPrint", fmt.Sprintf("No history for key with fingerPrint %s was found", fingerPrint), w, r, log)
+		} else {
+			InternalServerError(err.Error(), nil, w, r, log)
+		}
+		return
+	}
+
+	WriteJSON(history, 200, w, r, log)
+}
+
+// Restore GPG Key godoc
+// @id pks-restore-key
+// @tags Public Key Server, Key Store
+// @Summary Restores a deleted GPG Public Key
+// @Accept json
+// @Produce plain
+// @Param message body models.SKSRestoreKey true "Fingerprint of the key to be restored"
+// @Success 200 {string} result "OK"
+// @Failure default {object} QuantoError.ErrorObject
+// @Router /sks/restoreKey [post]
+func (sks *SKSEndpoint) restoreKey(w http.ResponseWriter, r *http.Request) {
+	ctx := wrapContextWithRequestID(r)
+	log := wrapLogWithRequestID(sks.log, r)
+	InitHTTPTimer(log, r)
+	ctx = wrapContextWithDatabaseHandler(sks.dbh, ctx)
+	ctx = wrapContextWithActor(ctx, r)
+
+	var data models.SKSRestoreKey
+
+	if !UnmarshalBodyOrDie(&data, w, r, log) {
+		return
+	}
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			CatchAllError(rec, w, r, log)
+		}
+	}()
+
+	if data.FingerPrint == "" {
+		InvalidFieldData("FingerPrint", "you should provide a FingerPrint", w, r, log)
+		return
+	}
+
+	err := keymagic.PKSRestoreKey(ctx, data.FingerPrint)
+
+	if err != nil {
+		switch err.Error() {
+		case "not found":
+			NotFound("FingerPrint", fmt.Sprintf("No deleted key with fingerPrint %s was found", data.FingerPrint), w, r, log)
+		case "already exists":
+			AlreadyExists("FingerPrint", fmt.Sprintf("Key with fingerPrint %s is not deleted", data.FingerPrint), w, r, log)
+		default:
+			InvalidFieldData("FingerPrint", err.Error(), w, r, log)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", models.MimeText)
+	w.WriteHeader(200)
+	n, _ := w.Write([]byte("OK"))
+	LogExit(log, r, 200, n)
+}
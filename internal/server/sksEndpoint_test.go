@@ -7,9 +7,12 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/quan-to/chevron/internal/config"
+	"github.com/quan-to/chevron/internal/keymagic"
+	"github.com/quan-to/chevron/internal/tools"
 	"github.com/quan-to/chevron/pkg/QuantoError"
 	"github.com/quan-to/chevron/pkg/models"
 	"github.com/quan-to/chevron/test"
@@ -59,8 +62,7 @@ func TestSKSGetKey(t *testing.T) {
 }
 
 func BaseTestSearch(name, value, endpoint string, t *testing.T) {
-	config.PushVariables()
-	defer config.PopVariables()
+	defer config.Apply(config.Current())
 
 	config.EnableDatabase = true
 
@@ -171,8 +173,7 @@ func TestSKSSearch(t *testing.T) {
 
 func TestAddKey(t *testing.T) {
 	ctx := context.Background()
-	config.PushVariables()
-	defer config.PopVariables()
+	defer config.Apply(config.Current())
 
 	config.EnableDatabase = true
 	// region Test Add Key
@@ -220,3 +221,246 @@ func TestAddKey(t *testing.T) {
 	}
 	// endregion
 }
+
+func restoreKeyRequest(fingerPrint string) *http.Request {
+	body, _ := json.Marshal(models.SKSRestoreKey{
+		FingerPrint: fingerPrint,
+	})
+
+	req, _ := http.NewRequest("POST", "/sks/restoreKey", bytes.NewReader(body))
+	req.RemoteAddr = "10.0.0.3:1000"
+	req.Header.Set("X-Forwarded-For", "10.0.0.1, 10.0.0.2")
+	return req
+}
+
+func TestSKSKeyHistoryAndRestore(t *testing.T) {
+	defer config.Apply(config.Current())
+	config.HttpTrustedProxies = "10.0.0.2,10.0.0.3"
+
+	// region Test Restore non deleted key
+	res := executeRequest(restoreKeyRequest(test.TestKeyFingerprint))
+
+	errObj, err := ReadErrorObject(res.Body)
+	errorDie(err, t)
+
+	if errObj.ErrorCode != QuantoError.AlreadyExists {
+		errorDie(fmt.Errorf("expected error code %s got %s", QuantoError.AlreadyExists, errObj.ErrorCode), t)
+	}
+	// endregion
+	// region Test Restore deleted key
+	err = dbh.DeleteGPGKey(models.GPGKey{FullFingerprint: test.TestKeyFingerprint})
+	errorDie(err, t)
+
+	res = executeRequest(restoreKeyRequest(test.TestKeyFingerprint))
+
+	d, err := ioutil.ReadAll(res.Body)
+	errorDie(err, t)
+
+	if string(d) != "OK" {
+		errorDie(fmt.Errorf("expected OK got %s", string(d)), t)
+	}
+	// endregion
+	// region Test Key History
+	req, err := http.NewRequest("GET", "/sks/keyHistory", nil)
+	errorDie(err, t)
+	q := req.URL.Query()
+	q.Add("fingerPrint", test.TestKeyFingerprint)
+	req.URL.RawQuery = q.Encode()
+
+	res = executeRequest(req)
+
+	d, err = ioutil.ReadAll(res.Body)
+	errorDie(err, t)
+
+	if res.Code != 200 {
+		errorDie(fmt.Errorf("expected 200 got %d: %s", res.Code, string(d)), t)
+	}
+
+	var history []models.GPGKeyHistory
+	err = json.Unmarshal(d, &history)
+	errorDie(err, t)
+
+	if len(history) < 3 {
+		errorDie(fmt.Errorf("expected at least 3 history entries got %d", len(history)), t)
+	}
+
+	last := history[len(history)-1]
+	if last.Action != models.KeyHistoryRestored {
+		errorDie(fmt.Errorf("expected last action to be %s got %s", models.KeyHistoryRestored, last.Action), t)
+	}
+
+	if last.Actor != "10.0.0.1" {
+		errorDie(fmt.Errorf("expected restore actor to be 10.0.0.1 got %q", last.Actor), t)
+	}
+
+	if history[len(history)-2].Action != models.KeyHistoryDeleted {
+		errorDie(fmt.Errorf("expected action to be %s got %s", models.KeyHistoryDeleted, history[len(history)-2].Action), t)
+	}
+	// endregion
+	// region Test Unknown Key
+	res = executeRequest(restoreKeyRequest("ABCDABCDABCDABCD"))
+
+	errObj, err = ReadErrorObject(res.Body)
+	errorDie(err, t)
+
+	if errObj.ErrorCode != QuantoError.NotFound {
+		errorDie(fmt.Errorf("expected error code %s got %s", QuantoError.NotFound, errObj.ErrorCode), t)
+	}
+	// endregion
+}
+
+type failingHistoryDatabase struct {
+	DatabaseHandler
+}
+
+func (failingHistoryDatabase) FetchGPGKeyHistory(string) ([]models.GPGKeyHistory, error) {
+	return nil, fmt.Errorf("connection refused")
+}
+
+func TestSKSKeyHistoryDatabaseError(t *testing.T) {
+	sks := MakeSKSEndpoint(log, sm, gpg, failingHistoryDatabase{dbh})
+
+	req := httptest.NewRequest("GET", "/sks/keyHistory?fingerPrint="+test.TestKeyFingerprint, nil)
+	res := httptest.NewRecorder()
+	sks.keyHistory(res, req)
+
+	errObj, err := ReadErrorObject(res.Body)
+	errorDie(err, t)
+
+	if res.Code != 500 || errObj.ErrorCode != QuantoError.InternalServerError {
+		errorDie(fmt.Errorf("expected 500 %s got %d %s", QuantoError.InternalServerError, res.Code, errObj.ErrorCode), t)
+	}
+}
+
+func searchKeys(params map[string]string, t *testing.T) (*httptest.ResponseRecorder, models.GPGKeySearchResult) {
+	req, err := http.NewRequest("GET", "/sks/searchKeys", nil)
+	errorDie(err, t)
+
+	q := req.URL.Query()
+	for k, v := range params {
+		q.Add(k, v)
+	}
+	req.URL.RawQuery = q.Encode()
+
+	res := executeRequest(req)
+
+	var result models.GPGKeySearchResult
+	if res.Code == 200 {
+		errorDie(json.Unmarshal(res.Body.Bytes(), &result), t)
+	}
+
+	return res, result
+}
+
+func searchKeysContains(result models.GPGKeySearchResult, fingerPrint string) bool {
+	for _, k := range result.Keys {
+		if tools.CompareFingerPrint(k.FullFingerprint, fingerPrint) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSKSSearchKeys(t *testing.T) {
+	ctx := context.WithValue(context.Background(), tools.CtxDatabaseHandler, dbh)
+	if status := keymagic.PKSAdd(ctx, test.TestPublicKey2); status != "OK" {
+		errorDie(fmt.Errorf("expected OK got %s", status), t)
+	}
+
+	// region Test matching
+	matching := []map[string]string{
+		{"q": "hue"},
+		{"q": "JON@HUE"},
+		{"q": "@huebr.com"},
+		{"q": test.TestKeyFingerprint},
+		{"q": "HUEBER", "fuzzy": "true"},
+		{"q": "jon@huebr.com", "minKeyBits": "2048", "algorithm": "rsa", "excludeExpired": "true"},
+	}
+
+	for _, params := range matching {
+		res, result := searchKeys(params, t)
+		if res.Code != 200 {
+			errorDie(fmt.Errorf("expected 200 for %v got %d: %s", params, res.Code, res.Body.String()), t)
+		}
+		if !searchKeysContains(result, test.TestKeyFingerprint) {
+			errorDie(fmt.Errorf("expected search %v to find %s", params, test.TestKeyFingerprint), t)
+		}
+	}
+	// endregion
+	// region Test not matching
+	notMatching := []map[string]string{
+		{"q": "HUEBER"},
+		{"q": "@huebr"},
+		{"q": "huebr.com"},
+		{"q": "hue", "minKeyBits": "100000"},
+		{"q": "hue", "algorithm": "DSA"},
+		{"q": "hue", "expiresBefore": "2000-01-01T00:00:00Z"},
+	}
+
+	for _, params := range notMatching {
+		res, result := searchKeys(params, t)
+		if res.Code != 200 {
+			errorDie(fmt.Errorf("expected 200 for %v got %d: %s", params, res.Code, res.Body.String()), t)
+		}
+		if searchKeysContains(result, test.TestKeyFingerprint) {
+			errorDie(fmt.Errorf("expected search %v to not find %s", params, test.TestKeyFingerprint), t)
+		}
+	}
+	// endregion
+	// region Test cursor pagination
+	params := map[string]string{"limit": "1", "sortBy": "name"}
+	seen := map[string]bool{}
+	total := -1
+
+	for {
+		res, result := searchKeys(params, t)
+		if res.Code != 200 {
+			errorDie(fmt.Errorf("expected 200 got %d: %s", res.Code, res.Body.String()), t)
+		}
+
+		if total == -1 {
+			total = result.Total
+		}
+
+		if result.Total != total {
+			errorDie(fmt.Errorf("expected total to be %d in all pages got %d", total, result.Total), t)
+		}
+
+		for _, k := range result.Keys {
+			if seen[k.FullFingerprint] {
+				errorDie(fmt.Errorf("key %s returned twice", k.FullFingerprint), t)
+			}
+			seen[k.FullFingerprint] = true
+		}
+
+		if result.NextCursor == "" {
+			break
+		}
+		params["cursor"] = result.NextCursor
+	}
+
+	if total < 2 || len(seen) != total {
+		errorDie(fmt.Errorf("expected to walk through %d keys got %d", total, len(seen)), t)
+	}
+	// endregion
+	// region Test invalid parameters
+	invalid := []map[string]string{
+		{"sortBy": "potato"},
+		{"limit": "abc"},
+		{"fuzzy": "maybe"},
+		{"expiresAfter": "yesterday"},
+		{"cursor": "invalid"},
+		{"sortBy": "keyBits", "cursor": models.GPGKeySearchCursor{SortBy: "name", Value: "a", FingerPrint: "AB"}.Encode()},
+	}
+
+	for _, params := range invalid {
+		res, _ := searchKeys(params, t)
+		errObj, err := ReadErrorObject(res.Body)
+		errorDie(err, t)
+
+		if errObj.ErrorCode != QuantoError.InvalidFieldData {
+			errorDie(fmt.Errorf("expected error code %s for %v got %s", QuantoError.InvalidFieldData, params, errObj.ErrorCode), t)
+		}
+	}
+	// endregion
+}
@@ -14,6 +14,7 @@ import (
 	"path"
 	"regexp"
 	"strings"
+	"unicode"
 
 	"github.com/google/uuid"
 	"github.com/mewkiz/pkg/osutil"
@@ -32,6 +33,7 @@ type ContextField string
 const (
 	CtxRequestID       ContextField = "requestID"
 	CtxDatabaseHandler ContextField = "dbHandler"
+	CtxActor           ContextField = "actor"
 )
 
 const (
@@ -81,7 +83,8 @@ func Quanto2GPG(signature string) string {
 		s = strings.Split(signature, "_")
 	}
 
-	if len(s) != 3 {
+	// The signature must have more than the 5 bytes of the checksum
+	if len(s) != 3 || len(s[2]) <= 5 {
 		return ""
 	}
 
@@ -276,6 +279,31 @@ func GetFingerPrintFromKey(armored string) (string, error) {
 	return "", fmt.Errorf("cannot read key")
 }
 
+// GetFingerPrintFromSignature returns the 16 chars fingerprint of the key that made the armored signature
+func GetFingerPrintFromSignature(armored string) (string, error) {
+	block, err := armor.Decode(strings.NewReader(SignatureFix(armored)))
+	if err != nil {
+		return "", err
+	}
+
+	pkt, err := packet.NewReader(block.Body).Next()
+	if err != nil {
+		return "", err
+	}
+
+	switch sig := pkt.(type) {
+	case *packet.Signature:
+		if sig.IssuerKeyId == nil {
+			return "", fmt.Errorf("signature doesn't have an issuer")
+		}
+		return IssuerKeyIdToFP16(*sig.IssuerKeyId), nil
+	case *packet.SignatureV3:
+		return IssuerKeyIdToFP16(sig.IssuerKeyId), nil
+	}
+
+	return "", fmt.Errorf("openpgp packet is not signature")
+}
+
 func GetFingerPrintsFromKey(armored string) ([]string, error) {
 	keys, err := ReadKey(armored)
 
@@ -485,6 +513,17 @@ func ReadKeyToEntity(asciiArmored string) (*openpgp.Entity, error) {
 	return nil, errors.New("no keys found")
 }
 
+// IsFingerPrint returns true if fp is a 16 or 40 characters hex encoded key fingerprint
+func IsFingerPrint(fp string) bool {
+	if len(fp) != 16 && len(fp) != 40 {
+		return false
+	}
+
+	_, err := hex.DecodeString(fp)
+
+	return err == nil
+}
+
 func CompareFingerPrint(fpA, fpB string) bool {
 	if fpA == "" || fpB == "" {
 		return false
@@ -658,3 +697,45 @@ func GetRequestIDFromContext(ctx context.Context) string {
 
 	return requestID
 }
+
+// GetActorFromContext returns the identity of who triggered the current call or empty string if unknown
+func GetActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(CtxActor).(string)
+	return actor
+}
+
+func trigrams(s string) map[string]struct{} {
+	res := make(map[string]struct{})
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for _, w := range words {
+		padded := []rune("  " + w + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			res[string(padded[i:i+3])] = struct{}{}
+		}
+	}
+
+	return res
+}
+
+// TrigramSimilarity returns how similar two strings are, from 0 (nothing in common) to 1 (same words)
+// It uses the same algorithm as PostgreSQL pg_trgm similarity()
+func TrigramSimilarity(a, b string) float64 {
+	ta := trigrams(a)
+	tb := trigrams(b)
+
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+
+	common := 0
+	for t := range ta {
+		if _, ok := tb[t]; ok {
+			common++
+		}
+	}
+
+	return float64(common) / float64(len(ta)+len(tb)-common)
+}
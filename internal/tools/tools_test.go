@@ -79,6 +79,12 @@ func TestQuanto2GPG(t *testing.T) {
 	if z != "" {
 		t.Errorf("Expected empty got %s", z)
 	}
+
+	for _, short := range []string{"a_b_c", "a_b_abcde", "a$b$", "a_b_"} {
+		if z = Quanto2GPG(short); z != "" {
+			t.Errorf("Expected empty for %q got %s", short, z)
+		}
+	}
 }
 
 func TestGPG2Quanto(t *testing.T) {
@@ -268,6 +274,22 @@ func TestCompareFingerPrint(t *testing.T) {
 	}
 }
 
+func TestIsFingerPrint(t *testing.T) {
+	valid := []string{"0551F452ABE463A4", "0551f452abe463a4", "1234567890ABCDEF1234567890ABCDEF0551F452"}
+	for _, fp := range valid {
+		if !IsFingerPrint(fp) {
+			t.Errorf("Expected %q to be a valid fingerprint", fp)
+		}
+	}
+
+	invalid := []string{"", "0551F452", "0551F452ABE463A", "../../../etc/cron", "0551F452ABE463G4", "0551F452ABE463A4/"}
+	for _, fp := range invalid {
+		if IsFingerPrint(fp) {
+			t.Errorf("Expected %q to be an invalid fingerprint", fp)
+		}
+	}
+}
+
 func TestCrc24(t *testing.T) {
 	z := []byte{1, 2, 3, 3, 41, 23, 12, 31, 23, 12, 31, 23, 12, 41, 24, 15, 12, 43, 12, 31, 23, 12, 31, 23, 123, 12, 4, 12, 31, 23, 12, 31, 23, 120}
 	o := CRC24(z)
@@ -657,3 +679,43 @@ func TestGetRequestIDFromEmptyContext(t *testing.T) {
 		t.Errorf("Expected returns default tag")
 	}
 }
+
+func TestTrigramSimilarity(t *testing.T) {
+	if s := TrigramSimilarity("Remote Signer", "remote signer"); s != 1 {
+		t.Errorf("expected similarity 1 for same words got %f", s)
+	}
+
+	if s := TrigramSimilarity("abc", "xyz"); s != 0 {
+		t.Errorf("expected similarity 0 got %f", s)
+	}
+
+	if s := TrigramSimilarity("", "xyz"); s != 0 {
+		t.Errorf("expected similarity 0 for empty string got %f", s)
+	}
+
+	// "word" => "  w", " wo", "wor", "ord", "rd "
+	// "words" => "  w", " wo", "wor", "ord", "rds", "ds "
+	// 4 common trigrams out of 7 => 0.571429 (same result as PostgreSQL)
+	if s := TrigramSimilarity("word", "words"); s < 0.571 || s > 0.572 {
+		t.Errorf("expected similarity 0.571 got %f", s)
+	}
+
+	if TrigramSimilarity("Jonh Smith", "john smith") < 0.3 {
+		t.Errorf("expected typo to have similarity >= 0.3")
+	}
+}
+
+func TestGetFingerPrintFromSignature(t *testing.T) {
+	fp, err := GetFingerPrintFromSignature(test.BrokenMacOSXSignatureFixed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fp != "0016A9CA870AFA59" {
+		t.Errorf("expected fingerprint 0016A9CA870AFA59 got %q", fp)
+	}
+
+	if _, err := GetFingerPrintFromSignature(test.TestPublicKeyManySubkeys); err == nil {
+		t.Errorf("expected error for non signature data")
+	}
+}
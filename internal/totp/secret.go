@@ -0,0 +1,68 @@
+package totp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+// EncryptSecret encrypts the secret with AES-256-GCM using a key derived from encryptionKey
+// The result is base64 encoded and contains the nonce
+func EncryptSecret(encryptionKey, secret string) (string, error) {
+	gcm, err := makeGCM(encryptionKey)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", fmt.Errorf("error generating nonce: %v", err)
+	}
+
+	data := gcm.Seal(nonce, nonce, []byte(secret), nil)
+
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// DecryptSecret decrypts a secret encrypted by EncryptSecret
+func DecryptSecret(encryptionKey, encryptedSecret string) (string, error) {
+	gcm, err := makeGCM(encryptionKey)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encryptedSecret)
+	if err != nil {
+		return "", fmt.Errorf("error decoding secret: %v", err)
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return "", fmt.Errorf("invalid encrypted secret")
+	}
+
+	secret, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("error decrypting secret: %v", err)
+	}
+
+	return string(secret), nil
+}
+
+func makeGCM(encryptionKey string) (cipher.AEAD, error) {
+	if encryptionKey == "" {
+		return nil, fmt.Errorf("no encryption key configured")
+	}
+
+	key := sha256.Sum256([]byte(encryptionKey))
+
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,137 @@
+// Package totp implements RFC 6238 Time-Based One-Time Passwords
+// compatible with the common authenticator apps (HMAC-SHA1, 6 digits, 30 seconds period)
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Digits is the number of digits of the generated codes
+	Digits = 6
+	// Period is the time step of the codes
+	Period = 30 * time.Second
+	// Skew is the number of periods before and after the current one that are also accepted
+	Skew = 1
+
+	secretSize = 20
+)
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret generates a new random base32 encoded secret
+func GenerateSecret() (string, error) {
+	secret := make([]byte, secretSize)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", fmt.Errorf("error generating secret: %v", err)
+	}
+
+	return b32.EncodeToString(secret), nil
+}
+
+// GenerateCode returns the code for the specified base32 secret at the specified time
+func GenerateCode(secret string, t time.Time) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+
+	return hotp(key, counter(t), Digits), nil
+}
+
+// Validate checks if the code is valid for the specified base32 secret at the specified time
+// Codes from Skew periods before and after are also accepted to compensate clock drift
+func Validate(secret, code string, t time.Time) bool {
+	_, ok := ValidateAfter(secret, code, t, 0)
+	return ok
+}
+
+// ValidateAfter checks the code like Validate, but only accepts codes of time steps after lastStep.
+// It returns the time step of the accepted code, to be stored as the lastStep of the next validation
+// so the same code cannot be used twice
+func ValidateAfter(secret, code string, t time.Time, lastStep uint64) (uint64, bool) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return 0, false
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) != Digits {
+		return 0, false
+	}
+
+	c := counter(t)
+	for i := -Skew; i <= Skew; i++ {
+		step := uint64(int64(c) + int64(i))
+		if step <= lastStep {
+			continue
+		}
+		if hmac.Equal([]byte(hotp(key, step, Digits)), []byte(code)) {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// ProvisioningURI returns the otpauth:// URI used by authenticator apps to enroll the secret
+func ProvisioningURI(issuer, account, secret string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprintf("%d", Digits))
+	v.Set("period", fmt.Sprintf("%d", int(Period.Seconds())))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: v.Encode(),
+	}
+
+	return u.String()
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.TrimRight(strings.ReplaceAll(secret, " ", ""), "="))
+	key, err := b32.DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret: %v", err)
+	}
+
+	return key, nil
+}
+
+func counter(t time.Time) uint64 {
+	return uint64(t.Unix() / int64(Period.Seconds()))
+}
+
+// hotp implements RFC 4226 HMAC-Based One-Time Password
+func hotp(key []byte, counter uint64, digits int) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0xf
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
@@ -0,0 +1,145 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 Appendix B test vectors for HMAC-SHA1
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "94287082"},
+	{1111111109, "07081804"},
+	{1111111111, "14050471"},
+	{1234567890, "89005924"},
+	{2000000000, "69279037"},
+	{20000000000, "65353130"},
+}
+
+func TestHOTPVectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+
+	for _, v := range rfcVectors {
+		code := hotp(key, counter(time.Unix(v.unix, 0)), 8)
+		if code != v.code {
+			t.Errorf("expected code %s at %d got %s", v.code, v.unix, code)
+		}
+	}
+}
+
+func TestGenerateAndValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("error generating secret: %v", err)
+	}
+
+	now := time.Now()
+
+	code, err := GenerateCode(secret, now)
+	if err != nil {
+		t.Fatalf("error generating code: %v", err)
+	}
+
+	if len(code) != Digits {
+		t.Fatalf("expected %d digits got %q", Digits, code)
+	}
+
+	if !Validate(secret, code, now) {
+		t.Errorf("expected code to be valid")
+	}
+
+	if !Validate(secret, code, now.Add(Period)) {
+		t.Errorf("expected code of previous period to be valid")
+	}
+
+	if Validate(secret, code, now.Add(3*Period)) {
+		t.Errorf("expected code to be invalid after skew")
+	}
+
+	if Validate(secret, "abc", now) {
+		t.Errorf("expected malformed code to be invalid")
+	}
+
+	if Validate("not base32!", code, now) {
+		t.Errorf("expected invalid secret to fail")
+	}
+}
+
+func TestValidateAfter(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("error generating secret: %v", err)
+	}
+
+	now := time.Now()
+
+	code, err := GenerateCode(secret, now)
+	if err != nil {
+		t.Fatalf("error generating code: %v", err)
+	}
+
+	step, ok := ValidateAfter(secret, code, now, 0)
+	if !ok || step != counter(now) {
+		t.Fatalf("expected code to be accepted at step %d got %d (%t)", counter(now), step, ok)
+	}
+
+	if _, ok := ValidateAfter(secret, code, now, step); ok {
+		t.Errorf("expected code to be rejected when reused")
+	}
+
+	if _, ok := ValidateAfter(secret, code, now.Add(Period), step); ok {
+		t.Errorf("expected code to be rejected when reused in the next period")
+	}
+
+	next, err := GenerateCode(secret, now.Add(Period))
+	if err != nil {
+		t.Fatalf("error generating code: %v", err)
+	}
+
+	if nextStep, ok := ValidateAfter(secret, next, now.Add(Period), step); !ok || nextStep != step+1 {
+		t.Errorf("expected code of the next period to be accepted")
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("Chevron", "johnhuebr", "JBSWY3DPEHPK3PXP")
+
+	if !strings.HasPrefix(uri, "otpauth://totp/Chevron:johnhuebr?") {
+		t.Errorf("unexpected uri %s", uri)
+	}
+
+	if !strings.Contains(uri, "secret=JBSWY3DPEHPK3PXP") {
+		t.Errorf("expected secret in uri %s", uri)
+	}
+}
+
+func TestEncryptSecret(t *testing.T) {
+	enc, err := EncryptSecret("my key", "JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatalf("error encrypting: %v", err)
+	}
+
+	if strings.Contains(enc, "JBSWY3DPEHPK3PXP") {
+		t.Fatalf("expected secret to be encrypted")
+	}
+
+	dec, err := DecryptSecret("my key", enc)
+	if err != nil {
+		t.Fatalf("error decrypting: %v", err)
+	}
+
+	if dec != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("expected JBSWY3DPEHPK3PXP got %s", dec)
+	}
+
+	if _, err := DecryptSecret("other key", enc); err == nil {
+		t.Errorf("expected error decrypting with wrong key")
+	}
+
+	if _, err := EncryptSecret("", "JBSWY3DPEHPK3PXP"); err == nil {
+		t.Errorf("expected error encrypting without key")
+	}
+}
@@ -18,7 +18,7 @@ func TestMain(m *testing.M) {
 }
 
 func TestVaultManager_Make(t *testing.T) {
-	config.PushVariables()
+	defer config.Apply(config.Current())
 	// Test Vault SkipVerify
 	config.VaultSkipVerify = true
 	tmpVM := MakeVaultManager(nil, "test_")
@@ -39,8 +39,6 @@ func TestVaultManager_Make(t *testing.T) {
 	if err != nil {
 		t.Errorf("Got error listing: %s", err)
 	}
-
-	config.PopVariables()
 }
 
 func TestVaultGetToken(t *testing.T) {
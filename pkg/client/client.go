@@ -0,0 +1,335 @@
+// Package client is a typed client for the Chevron REST API
+//
+// It covers the /gpg, /keyRing, /sks, /fieldCipher, /pks and /tests endpoints.
+// The agent proxy, the agent admin GraphQL and the cluster internal endpoints are not covered.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quan-to/chevron/internal/tools"
+	"github.com/quan-to/chevron/pkg/QuantoError"
+	"github.com/quan-to/chevron/pkg/models"
+	"github.com/quan-to/slog"
+)
+
+const (
+	// DefaultRequestIDHeader is the header used to send the request ID. It should match REQUESTID_HEADER in the server
+	DefaultRequestIDHeader = "X-Request-ID"
+	// DefaultRetries is the number of retries for failed requests
+	DefaultRetries = 2
+	// DefaultRetryWait is the wait before the first retry. It doubles on each retry
+	DefaultRetryWait = 500 * time.Millisecond
+	// DefaultTimeout is the timeout of each request
+	DefaultTimeout = time.Minute
+
+	maxRetryWait = 30 * time.Second
+)
+
+// idempotentMethods are the methods that are retried on connection and server errors
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+type retrySafeKey struct{}
+
+// Client calls the Chevron REST API
+type Client struct {
+	baseURL         string
+	httpClient      *http.Client
+	token           string
+	requestIDHeader string
+	retries         int
+	retryWait       time.Duration
+	log             slog.Instance
+}
+
+// Option customizes the client
+type Option func(c *Client)
+
+// WithHTTPClient sets the http client used for the requests
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithToken sets the token sent as a bearer Authorization header
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithRequestIDHeader sets the header used to send the request ID
+func WithRequestIDHeader(header string) Option {
+	return func(c *Client) {
+		c.requestIDHeader = header
+	}
+}
+
+// WithRetries sets the number of retries and the wait before the first retry
+// Requests are retried when the server is busy. Only idempotent requests, or the ones with a context
+// created by WithRetrySafe, are retried on connection and other server errors
+func WithRetries(retries int, wait time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.retryWait = wait
+	}
+}
+
+// MakeClient creates a client for the Chevron server at baseURL, for example https://chevron.example.com/remoteSigner
+func MakeClient(log slog.Instance, baseURL string, opts ...Option) *Client {
+	if log == nil {
+		log = slog.Scope("Client")
+	} else {
+		log = log.SubScope("Client")
+	}
+
+	c := &Client{
+		baseURL:         strings.TrimSuffix(baseURL, "/"),
+		httpClient:      &http.Client{Timeout: DefaultTimeout},
+		requestIDHeader: DefaultRequestIDHeader,
+		retries:         DefaultRetries,
+		retryWait:       DefaultRetryWait,
+		log:             log,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// WithRequestID returns a context that makes the client send the request ID to the server
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, tools.CtxRequestID, requestID)
+}
+
+// WithRetrySafe returns a context that marks the requests as safe to retry on connection and server errors
+// Use it for requests without side effects, such as sign, verify, encrypt and decrypt
+func WithRetrySafe(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retrySafeKey{}, true)
+}
+
+// isRetrySafe checks if a request with the method and context can be sent again after it may have reached the server
+func isRetrySafe(ctx context.Context, method string) bool {
+	safe, _ := ctx.Value(retrySafeKey{}).(bool)
+	return safe || idempotentMethods[method]
+}
+
+// ErrorCode returns the QuantoError code of an error returned by the client or an empty string if it is not a server error
+func ErrorCode(err error) string {
+	if e, ok := err.(*QuantoError.ErrorObject); ok {
+		return e.ErrorCode
+	}
+
+	return ""
+}
+
+// errorFromStatus creates an error for responses that does not contain a QuantoError.ErrorObject
+func errorFromStatus(statusCode int, body []byte) *QuantoError.ErrorObject {
+	code := QuantoError.InternalServerError
+
+	switch statusCode {
+	case http.StatusNotFound:
+		code = QuantoError.NotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		code = QuantoError.PermissionDenied
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		code = QuantoError.ServerIsBusy
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		code = QuantoError.TargetConnectionError
+	case http.StatusRequestEntityTooLarge:
+		code = QuantoError.OperationLimitExceeded
+	}
+
+	return &QuantoError.ErrorObject{
+		ErrorCode:  code,
+		ErrorField: "server",
+		Message:    fmt.Sprintf("server returned %d: %s", statusCode, tools.TruncateFieldForDisplay(string(body))),
+	}
+}
+
+// retryAfter returns the wait requested by the server in the Retry-After header
+func retryAfter(res *http.Response) time.Duration {
+	if res == nil {
+		return 0
+	}
+
+	seconds, err := strconv.Atoi(res.Header.Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// shouldRetry checks if a failed attempt can be retried
+// retrySafe tells if the request can be sent again after it may have been processed by the server
+func (c *Client) shouldRetry(retrySafe bool, attempt int, res *http.Response, err error) bool {
+	if attempt >= c.retries {
+		return false
+	}
+
+	if err != nil {
+		return retrySafe
+	}
+
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout, http.StatusInternalServerError:
+		return retrySafe
+	}
+
+	return false
+}
+
+func (c *Client) backoff(attempt int, res *http.Response) time.Duration {
+	if wait := retryAfter(res); wait > 0 {
+		return wait
+	}
+
+	wait := c.retryWait << uint(attempt)
+	if wait > maxRetryWait || wait <= 0 {
+		wait = maxRetryWait
+	}
+
+	return wait
+}
+
+// do sends the request retrying as configured and returns the body of a 200 response
+// Errors sent by the server are returned as *QuantoError.ErrorObject
+func (c *Client) do(ctx context.Context, method, endpoint string, query url.Values, contentType string, body []byte) ([]byte, error) {
+	u := c.baseURL + endpoint
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	requestID := tools.GetRequestIDFromContext(ctx)
+	log := c.log.Tag(requestID)
+	retrySafe := isRetrySafe(ctx, method)
+
+	for attempt := 0; ; attempt++ {
+		var bodyReader io.Reader
+		if body != nil {
+			bodyReader = bytes.NewReader(body)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, u, bodyReader)
+		if err != nil {
+			return nil, err
+		}
+
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+
+		if c.requestIDHeader != "" && requestID != tools.DefaultTag {
+			req.Header.Set(c.requestIDHeader, requestID)
+		}
+
+		res, err := c.httpClient.Do(req)
+		if err == nil && res.StatusCode == http.StatusOK {
+			defer res.Body.Close()
+			return ioutil.ReadAll(res.Body)
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		var data []byte
+		if res != nil {
+			data, _ = ioutil.ReadAll(res.Body)
+			_ = res.Body.Close()
+		}
+
+		if !c.shouldRetry(retrySafe, attempt, res, err) {
+			if err != nil {
+				return nil, err
+			}
+
+			var e QuantoError.ErrorObject
+			if json.Unmarshal(data, &e) == nil && e.ErrorCode != "" {
+				return nil, &e
+			}
+
+			return nil, errorFromStatus(res.StatusCode, data)
+		}
+
+		wait := c.backoff(attempt, res)
+		if err != nil {
+			log.Warn("%s %s failed on attempt %d: %s. Retrying in %s", method, endpoint, attempt+1, err, wait)
+		} else {
+			log.Warn("%s %s returned %d on attempt %d. Retrying in %s", method, endpoint, res.StatusCode, attempt+1, wait)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// get sends a GET request
+func (c *Client) get(ctx context.Context, endpoint string, query url.Values) ([]byte, error) {
+	return c.do(ctx, http.MethodGet, endpoint, query, "", nil)
+}
+
+// post sends a POST request with the payload encoded as JSON
+func (c *Client) post(ctx context.Context, endpoint string, payload interface{}) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.do(ctx, http.MethodPost, endpoint, nil, models.MimeJSON, data)
+}
+
+// getJSON sends a GET request and decodes the JSON response into out
+func (c *Client) getJSON(ctx context.Context, endpoint string, query url.Values, out interface{}) error {
+	data, err := c.get(ctx, endpoint, query)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, out)
+}
+
+// postJSON sends a POST request and decodes the JSON response into out
+func (c *Client) postJSON(ctx context.Context, endpoint string, payload, out interface{}) error {
+	data, err := c.post(ctx, endpoint, payload)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, out)
+}
+
+// Ping checks if the server and its external dependencies are healthy
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.get(ctx, "/tests/ping", nil)
+	return err
+}
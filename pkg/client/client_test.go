@@ -0,0 +1,425 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quan-to/chevron/internal/agent"
+	"github.com/quan-to/chevron/internal/config"
+	"github.com/quan-to/chevron/internal/etc/magicbuilder"
+	"github.com/quan-to/chevron/internal/server"
+	"github.com/quan-to/chevron/internal/tools"
+	"github.com/quan-to/chevron/pkg/QuantoError"
+	"github.com/quan-to/chevron/pkg/models"
+	"github.com/quan-to/chevron/test"
+	"github.com/quan-to/slog"
+)
+
+var log = slog.Scope("TestClient")
+
+var ts *httptest.Server
+
+var headersLock sync.Mutex
+var lastHeaders http.Header
+
+func lastRequestHeaders() http.Header {
+	headersLock.Lock()
+	defer headersLock.Unlock()
+
+	return lastHeaders
+}
+
+func TestMain(m *testing.M) {
+	slog.UnsetTestMode()
+
+	u, _ := uuid.NewRandom()
+
+	config.DatabaseName = "qrs_test_" + u.String()
+	keyFolder, err := test.MakeKeyFolder("../../test/data")
+	if err != nil {
+		slog.Fatal("Error creating the test key folder: %s", err)
+	}
+
+	config.PrivateKeyFolder = keyFolder
+	config.KeyPrefix = "testkey_"
+	config.KeysBase64Encoded = false
+	config.EnableDatabase = false
+	config.AgentTOTPEncryptionKey = "test-totp-key"
+	config.RequestIDHeader = DefaultRequestIDHeader
+
+	config.MasterGPGKeyBase64Encoded = false
+	config.MasterGPGKeyPath = path.Join(keyFolder, test.TestKeyFile)
+	config.MasterGPGKeyPasswordPath = path.Join(keyFolder, test.TestKeyPasswordFile)
+
+	dbh, err := agent.MakeDatabaseHandler(log)
+	if err != nil {
+		slog.Fatal("Error initializing selected database: %s", err)
+	}
+
+	ctx := context.WithValue(context.Background(), tools.CtxDatabaseHandler, dbh)
+
+	sm := magicbuilder.MakeSM(nil, dbh)
+	gpg := magicbuilder.MakePGP(nil, dbh)
+	gpg.LoadKeys(ctx)
+
+	if err := gpg.UnlockKey(ctx, test.TestKeyFingerprint, test.TestKeyPassword); err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	router := server.GenRemoteSignerServerMux(log, sm, gpg, dbh)
+
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headersLock.Lock()
+		lastHeaders = r.Header.Clone()
+		headersLock.Unlock()
+		router.ServeHTTP(w, r)
+	}))
+
+	slog.SetTestMode()
+	code := m.Run()
+	slog.UnsetTestMode()
+
+	ts.Close()
+	_ = os.RemoveAll(keyFolder)
+	os.Exit(code)
+}
+
+func TestSignAndVerify(t *testing.T) {
+	ctx := context.Background()
+	c := MakeClient(log, ts.URL)
+	data := base64.StdEncoding.EncodeToString([]byte("huebr"))
+
+	signature, err := c.Sign(ctx, models.GPGSignData{FingerPrint: test.TestKeyFingerprint, Base64Data: data})
+	if err != nil {
+		t.Fatalf("error signing: %s", err)
+	}
+
+	err = c.VerifySignature(ctx, models.GPGVerifySignatureData{Base64Data: data, Signature: signature})
+	if err != nil {
+		t.Fatalf("expected valid signature: %s", err)
+	}
+
+	quanto, err := c.SignQuanto(ctx, models.GPGSignData{FingerPrint: test.TestKeyFingerprint, Base64Data: data})
+	if err != nil {
+		t.Fatalf("error signing: %s", err)
+	}
+
+	err = c.VerifySignatureQuanto(ctx, models.GPGVerifySignatureData{Base64Data: data, Signature: quanto})
+	if err != nil {
+		t.Fatalf("expected valid signature: %s", err)
+	}
+
+	other := base64.StdEncoding.EncodeToString([]byte("other data"))
+	err = c.VerifySignature(ctx, models.GPGVerifySignatureData{Base64Data: other, Signature: signature})
+	if ErrorCode(err) != QuantoError.InvalidFieldData {
+		t.Fatalf("expected %s for invalid signature, got %v", QuantoError.InvalidFieldData, err)
+	}
+
+	var e *QuantoError.ErrorObject
+	if !errors.As(err, &e) || e.ErrorField != "Signature" {
+		t.Fatalf("expected error field Signature, got %v", err)
+	}
+}
+
+func TestEncryptAndDecrypt(t *testing.T) {
+	ctx := context.Background()
+	c := MakeClient(log, ts.URL)
+
+	encrypted, err := c.Encrypt(ctx, models.GPGEncryptData{
+		FingerPrint: test.TestKeyFingerprint,
+		Base64Data:  base64.StdEncoding.EncodeToString([]byte("huebr")),
+		Filename:    "test.txt",
+	})
+	if err != nil {
+		t.Fatalf("error encrypting: %s", err)
+	}
+
+	decrypted, err := c.Decrypt(ctx, models.GPGDecryptData{AsciiArmoredData: encrypted})
+	if err != nil {
+		t.Fatalf("error decrypting: %s", err)
+	}
+
+	data, _ := base64.StdEncoding.DecodeString(decrypted.Base64Data)
+	if string(data) != "huebr" || decrypted.Filename != "test.txt" {
+		t.Fatalf("unexpected decrypted data %q (%s)", data, decrypted.Filename)
+	}
+}
+
+func TestUnlockKey(t *testing.T) {
+	ctx := context.Background()
+	c := MakeClient(log, ts.URL)
+
+	err := c.UnlockKey(ctx, models.GPGUnlockKeyData{FingerPrint: test.TestKeyFingerprint, Password: "wrong password"})
+	if ErrorCode(err) != QuantoError.InvalidFieldData {
+		t.Fatalf("expected %s for wrong password, got %v", QuantoError.InvalidFieldData, err)
+	}
+
+	err = c.UnlockKey(ctx, models.GPGUnlockKeyData{FingerPrint: test.TestKeyFingerprint, Password: test.TestKeyPassword})
+	if err != nil {
+		t.Fatalf("error unlocking key: %s", err)
+	}
+}
+
+func TestKeyRing(t *testing.T) {
+	ctx := context.Background()
+	c := MakeClient(log, ts.URL)
+
+	keys, err := c.PrivateKeys(ctx)
+	if err != nil {
+		t.Fatalf("error listing private keys: %s", err)
+	}
+
+	found := false
+	for _, k := range keys {
+		if k.FingerPrint == test.TestKeyFingerprint {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Fatalf("expected %s in the private keys", test.TestKeyFingerprint)
+	}
+
+	key, err := c.KeyRingGetKey(ctx, test.TestKeyFingerprint)
+	if err != nil {
+		t.Fatalf("error getting key: %s", err)
+	}
+
+	fp, _ := tools.GetFingerPrintFromKey(key)
+	if fp != test.TestKeyFingerprint {
+		t.Fatalf("expected key %s got %s", test.TestKeyFingerprint, fp)
+	}
+
+	if _, err := c.CachedKeys(ctx); err != nil {
+		t.Fatalf("error listing cached keys: %s", err)
+	}
+
+	_, err = c.KeyRingGetKey(ctx, "0000000000000000")
+	if ErrorCode(err) != QuantoError.NotFound {
+		t.Fatalf("expected %s for unknown key, got %v", QuantoError.NotFound, err)
+	}
+}
+
+func TestSKS(t *testing.T) {
+	ctx := context.Background()
+	c := MakeClient(log, ts.URL)
+
+	key, err := c.KeyRingGetKey(ctx, test.TestKeyFingerprint)
+	if err != nil {
+		t.Fatalf("error getting key: %s", err)
+	}
+
+	if err := c.AddKey(ctx, models.SKSAddKey{PublicKey: key}); err != nil {
+		t.Fatalf("error adding key: %s", err)
+	}
+
+	if _, err := c.SKSGetKey(ctx, test.TestKeyFingerprint); err != nil {
+		t.Fatalf("error getting key: %s", err)
+	}
+
+	keys, err := c.SearchByFingerPrint(ctx, test.TestKeyFingerprint, 0, 10)
+	if err != nil || len(keys) == 0 {
+		t.Fatalf("expected key in search by fingerprint: %v", err)
+	}
+
+	result, err := c.SearchKeys(ctx, models.GPGKeySearchQuery{Query: test.TestKeyFingerprint, Limit: 10})
+	if err != nil || result.Total == 0 {
+		t.Fatalf("expected key in search: %v", err)
+	}
+
+	_, err = c.SearchKeys(ctx, models.GPGKeySearchQuery{SortBy: "invalid"})
+	if ErrorCode(err) != QuantoError.InvalidFieldData {
+		t.Fatalf("expected %s for invalid search, got %v", QuantoError.InvalidFieldData, err)
+	}
+
+	if _, err := c.KeyHistory(ctx, test.TestKeyFingerprint); err != nil {
+		t.Fatalf("error getting key history: %s", err)
+	}
+
+	lookup, err := c.HKPLookup(ctx, "get", "0x"+test.TestKeyFingerprint, "")
+	if err != nil {
+		t.Fatalf("error in HKP lookup: %s", err)
+	}
+
+	if fp, _ := tools.GetFingerPrintFromKey(lookup); fp != test.TestKeyFingerprint {
+		t.Fatalf("expected key %s got %s", test.TestKeyFingerprint, fp)
+	}
+
+	if err := c.HKPAdd(ctx, key); err != nil {
+		t.Fatalf("error in HKP add: %s", err)
+	}
+}
+
+func TestFieldCipher(t *testing.T) {
+	ctx := context.Background()
+	c := MakeClient(log, ts.URL)
+
+	packet, err := c.CipherFields(ctx, models.FieldCipherInput{
+		JSON: map[string]interface{}{"name": "huebr"},
+		Keys: []string{test.TestKeyFingerprint},
+	})
+	if err != nil {
+		t.Fatalf("error ciphering: %s", err)
+	}
+
+	decrypted, err := c.DecipherFields(ctx, models.FieldDecipherInput{
+		KeyFingerprint: test.TestKeyFingerprint,
+		EncryptedKey:   packet.EncryptedKey,
+		EncryptedJSON:  packet.EncryptedJSON,
+	})
+	if err != nil {
+		t.Fatalf("error deciphering: %s", err)
+	}
+
+	if decrypted.DecryptedData["name"] != "huebr" {
+		t.Fatalf("unexpected deciphered data %v", decrypted.DecryptedData)
+	}
+}
+
+func TestRequestIDAndToken(t *testing.T) {
+	c := MakeClient(log, ts.URL, WithToken("my-token"))
+	ctx := WithRequestID(context.Background(), "my-request-id")
+
+	if err := c.Ping(ctx); err != nil {
+		t.Fatalf("error in ping: %s", err)
+	}
+
+	h := lastRequestHeaders()
+
+	if h.Get(DefaultRequestIDHeader) != "my-request-id" {
+		t.Fatalf("expected request ID header, got %q", h.Get(DefaultRequestIDHeader))
+	}
+
+	if h.Get("Authorization") != "Bearer my-token" {
+		t.Fatalf("expected bearer token, got %q", h.Get("Authorization"))
+	}
+
+	if err := MakeClient(log, ts.URL).Ping(context.Background()); err != nil {
+		t.Fatalf("error in ping: %s", err)
+	}
+
+	if h := lastRequestHeaders(); h.Get(DefaultRequestIDHeader) != "" || h.Get("Authorization") != "" {
+		t.Fatalf("expected no request ID or token, got %v", h)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	var calls int32
+	failures := int32(2)
+	status := http.StatusServiceUnavailable
+
+	busy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		_, _ = w.Write([]byte("OK"))
+	}))
+	defer busy.Close()
+
+	ctx := context.Background()
+	c := MakeClient(log, busy.URL, WithRetries(2, time.Millisecond))
+
+	if err := c.UnlockKey(ctx, models.GPGUnlockKeyData{}); err != nil {
+		t.Fatalf("expected success after retries: %s", err)
+	}
+
+	if calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", calls)
+	}
+
+	// Not enough retries
+	atomic.StoreInt32(&calls, 0)
+	err := MakeClient(log, busy.URL, WithRetries(1, time.Millisecond)).Ping(ctx)
+	if ErrorCode(err) != QuantoError.ServerIsBusy {
+		t.Fatalf("expected %s, got %v", QuantoError.ServerIsBusy, err)
+	}
+
+	// Server errors are only retried for GET
+	atomic.StoreInt32(&calls, 0)
+	status = http.StatusBadGateway
+	failures = 1
+
+	err = c.UnlockKey(ctx, models.GPGUnlockKeyData{})
+	if ErrorCode(err) != QuantoError.TargetConnectionError || calls != 1 {
+		t.Fatalf("expected a single call with %s, got %d calls and %v", QuantoError.TargetConnectionError, calls, err)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	if err := c.Ping(ctx); err != nil || calls != 2 {
+		t.Fatalf("expected success after one retry, got %d calls and %v", calls, err)
+	}
+
+	// Unless the request is marked as safe to retry
+	atomic.StoreInt32(&calls, 0)
+	if err := c.UnlockKey(WithRetrySafe(ctx), models.GPGUnlockKeyData{}); err != nil || calls != 2 {
+		t.Fatalf("expected success after one retry, got %d calls and %v", calls, err)
+	}
+}
+
+func TestRetryConnectionErrors(t *testing.T) {
+	var calls int32
+
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				_ = conn.Close()
+			}
+			return
+		}
+		_, _ = w.Write([]byte("OK"))
+	}))
+	defer broken.Close()
+
+	ctx := context.Background()
+	c := MakeClient(log, broken.URL, WithRetries(2, time.Millisecond))
+
+	// The request may have been processed, so it is not sent again
+	if err := c.UnlockKey(ctx, models.GPGUnlockKeyData{}); err == nil || calls != 1 {
+		t.Fatalf("expected a single call with a connection error, got %d calls and %v", calls, err)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	if err := c.UnlockKey(WithRetrySafe(ctx), models.GPGUnlockKeyData{}); err != nil || calls != 2 {
+		t.Fatalf("expected success after one retry, got %d calls and %v", calls, err)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	if err := c.Ping(ctx); err != nil || calls != 2 {
+		t.Fatalf("expected success after one retry, got %d calls and %v", calls, err)
+	}
+}
+
+func TestContextCancel(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer slow.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := MakeClient(log, slow.URL).Ping(ctx)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("the client did not stop waiting on context cancellation")
+	}
+}
@@ -0,0 +1,32 @@
+package client
+
+import (
+	"context"
+
+	"github.com/quan-to/chevron/pkg/fieldcipher"
+	"github.com/quan-to/chevron/pkg/models"
+)
+
+// CipherFields encrypts the fields of a JSON for the specified public keys
+func (c *Client) CipherFields(ctx context.Context, data models.FieldCipherInput) (*fieldcipher.CipherPacket, error) {
+	var packet fieldcipher.CipherPacket
+
+	err := c.postJSON(ctx, "/fieldCipher/cipher", data, &packet)
+	if err != nil {
+		return nil, err
+	}
+
+	return &packet, nil
+}
+
+// DecipherFields decrypts a JSON encrypted by CipherFields with a private key unlocked in the server
+func (c *Client) DecipherFields(ctx context.Context, data models.FieldDecipherInput) (*fieldcipher.DecipherPacket, error) {
+	var packet fieldcipher.DecipherPacket
+
+	err := c.postJSON(ctx, "/fieldCipher/decipher", data, &packet)
+	if err != nil {
+		return nil, err
+	}
+
+	return &packet, nil
+}
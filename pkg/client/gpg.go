@@ -0,0 +1,67 @@
+package client
+
+import (
+	"context"
+
+	"github.com/quan-to/chevron/pkg/models"
+)
+
+// GenerateKey generates a new key in the server. The key is returned encrypted with the password and it's not stored by the server
+func (c *Client) GenerateKey(ctx context.Context, data models.GPGGenerateKeyData) (string, error) {
+	key, err := c.post(ctx, "/gpg/generateKey", data)
+	return string(key), err
+}
+
+// UnlockKey unlocks a private key loaded in the server
+func (c *Client) UnlockKey(ctx context.Context, data models.GPGUnlockKeyData) error {
+	_, err := c.post(ctx, "/gpg/unlockKey", data)
+	return err
+}
+
+// ForgetKeyPassword removes the password stored by UnlockKey
+func (c *Client) ForgetKeyPassword(ctx context.Context, data models.GPGForgetKeyPasswordData) error {
+	_, err := c.post(ctx, "/gpg/forgetKeyPassword", data)
+	return err
+}
+
+// Sign creates an armored detached signature of the base64 data
+func (c *Client) Sign(ctx context.Context, data models.GPGSignData) (string, error) {
+	signature, err := c.post(ctx, "/gpg/sign", data)
+	return string(signature), err
+}
+
+// SignQuanto creates a detached signature of the base64 data in Quanto format
+func (c *Client) SignQuanto(ctx context.Context, data models.GPGSignData) (string, error) {
+	signature, err := c.post(ctx, "/gpg/signQuanto", data)
+	return string(signature), err
+}
+
+// VerifySignature verifies an armored signature. Returns nil if the signature is valid
+func (c *Client) VerifySignature(ctx context.Context, data models.GPGVerifySignatureData) error {
+	_, err := c.post(ctx, "/gpg/verifySignature", data)
+	return err
+}
+
+// VerifySignatureQuanto verifies a Quanto signature. Returns nil if the signature is valid
+func (c *Client) VerifySignatureQuanto(ctx context.Context, data models.GPGVerifySignatureData) error {
+	_, err := c.post(ctx, "/gpg/verifySignatureQuanto", data)
+	return err
+}
+
+// Encrypt encrypts the base64 data for a public key
+func (c *Client) Encrypt(ctx context.Context, data models.GPGEncryptData) (string, error) {
+	encrypted, err := c.post(ctx, "/gpg/encrypt", data)
+	return string(encrypted), err
+}
+
+// Decrypt decrypts the data with any of the private keys unlocked in the server
+func (c *Client) Decrypt(ctx context.Context, data models.GPGDecryptData) (*models.GPGDecryptedData, error) {
+	var decrypted models.GPGDecryptedData
+
+	err := c.postJSON(ctx, "/gpg/decrypt", data, &decrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decrypted, nil
+}
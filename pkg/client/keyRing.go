@@ -0,0 +1,52 @@
+package client
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/quan-to/chevron/pkg/models"
+)
+
+// KeyRingGetKey returns the armored public key from the server key ring
+func (c *Client) KeyRingGetKey(ctx context.Context, fingerPrint string) (string, error) {
+	key, err := c.get(ctx, "/keyRing/getKey", url.Values{"fingerPrint": {fingerPrint}})
+	return string(key), err
+}
+
+// CachedKeys returns the public keys cached in the server
+func (c *Client) CachedKeys(ctx context.Context) ([]models.KeyInfo, error) {
+	keys := make([]models.KeyInfo, 0)
+	err := c.getJSON(ctx, "/keyRing/cachedKeys", nil, &keys)
+	return keys, err
+}
+
+// PrivateKeys returns the private keys loaded in the server
+func (c *Client) PrivateKeys(ctx context.Context) ([]models.KeyInfo, error) {
+	keys := make([]models.KeyInfo, 0)
+	err := c.getJSON(ctx, "/keyRing/privateKeys", nil, &keys)
+	return keys, err
+}
+
+// AddPrivateKey loads a private key in the server, optionally unlocking and storing it
+func (c *Client) AddPrivateKey(ctx context.Context, data models.KeyRingAddPrivateKeyData) (*models.GPGAddPrivateKeyReturn, error) {
+	var ret models.GPGAddPrivateKeyReturn
+
+	err := c.postJSON(ctx, "/keyRing/addPrivateKey", data, &ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ret, nil
+}
+
+// DeletePrivateKey removes a private key from the server
+func (c *Client) DeletePrivateKey(ctx context.Context, data models.KeyRingDeletePrivateKeyData) (*models.GPGDeletePrivateKeyReturn, error) {
+	var ret models.GPGDeletePrivateKeyReturn
+
+	err := c.postJSON(ctx, "/keyRing/deletePrivateKey", data, &ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ret, nil
+}
@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/quan-to/chevron/pkg/QuantoError"
+)
+
+// HKPLookup runs a HKP lookup operation (get, index or vindex) in the server
+func (c *Client) HKPLookup(ctx context.Context, op, search, options string) (string, error) {
+	q := url.Values{
+		"op":     {op},
+		"search": {search},
+	}
+
+	if options != "" {
+		q.Set("options", options)
+	}
+
+	result, err := c.get(ctx, "/pks/lookup", q)
+	return string(result), err
+}
+
+// HKPAdd adds a public key using the HKP add operation
+func (c *Client) HKPAdd(ctx context.Context, publicKey string) error {
+	form := url.Values{"keytext": {publicKey}}
+
+	result, err := c.do(ctx, http.MethodPost, "/pks/add", nil, "application/x-www-form-urlencoded", []byte(form.Encode()))
+	if err != nil {
+		return err
+	}
+
+	if status := strings.TrimSpace(string(result)); status != "OK" {
+		return QuantoError.New(QuantoError.InvalidFieldData, "keytext", status, nil)
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/quan-to/chevron/pkg/models"
+)
+
+// SKSGetKey returns the armored public key from the server public key store
+func (c *Client) SKSGetKey(ctx context.Context, fingerPrint string) (string, error) {
+	key, err := c.get(ctx, "/sks/getKey", url.Values{"fingerPrint": {fingerPrint}})
+	return string(key), err
+}
+
+func (c *Client) searchPage(ctx context.Context, endpoint, field, value string, pageStart, pageEnd int) ([]models.GPGKey, error) {
+	keys := make([]models.GPGKey, 0)
+
+	err := c.getJSON(ctx, endpoint, url.Values{
+		field:       {value},
+		"pageStart": {strconv.Itoa(pageStart)},
+		"pageEnd":   {strconv.Itoa(pageEnd)},
+	}, &keys)
+
+	return keys, err
+}
+
+// SearchByName searches the public keys by its identifier name
+func (c *Client) SearchByName(ctx context.Context, name string, pageStart, pageEnd int) ([]models.GPGKey, error) {
+	return c.searchPage(ctx, "/sks/searchByName", "name", name, pageStart, pageEnd)
+}
+
+// SearchByFingerPrint searches the public keys by fingerprint
+func (c *Client) SearchByFingerPrint(ctx context.Context, fingerPrint string, pageStart, pageEnd int) ([]models.GPGKey, error) {
+	return c.searchPage(ctx, "/sks/searchByFingerPrint", "fingerPrint", fingerPrint, pageStart, pageEnd)
+}
+
+// SearchByEmail searches the public keys by email
+func (c *Client) SearchByEmail(ctx context.Context, email string, pageStart, pageEnd int) ([]models.GPGKey, error) {
+	return c.searchPage(ctx, "/sks/searchByEmail", "email", email, pageStart, pageEnd)
+}
+
+// Search searches the public keys by any field
+func (c *Client) Search(ctx context.Context, value string, pageStart, pageEnd int) ([]models.GPGKey, error) {
+	return c.searchPage(ctx, "/sks/search", "valueData", value, pageStart, pageEnd)
+}
+
+// searchKeysValues converts the query to the url query parameters of /sks/searchKeys
+func searchKeysValues(query models.GPGKeySearchQuery) url.Values {
+	v := url.Values{}
+
+	set := func(name, value string) {
+		if value != "" {
+			v.Set(name, value)
+		}
+	}
+
+	set("q", query.Query)
+	set("algorithm", query.Algorithm)
+	set("sortBy", query.SortBy)
+	set("sortOrder", query.SortOrder)
+	set("cursor", query.Cursor)
+
+	if query.Fuzzy {
+		v.Set("fuzzy", "true")
+	}
+
+	if query.ExcludeExpired {
+		v.Set("excludeExpired", "true")
+	}
+
+	if query.MinKeyBits > 0 {
+		v.Set("minKeyBits", strconv.Itoa(query.MinKeyBits))
+	}
+
+	if query.MaxKeyBits > 0 {
+		v.Set("maxKeyBits", strconv.Itoa(query.MaxKeyBits))
+	}
+
+	if query.Limit > 0 {
+		v.Set("limit", strconv.Itoa(query.Limit))
+	}
+
+	if query.ExpiresAfter != nil {
+		v.Set("expiresAfter", query.ExpiresAfter.Format(time.RFC3339))
+	}
+
+	if query.ExpiresBefore != nil {
+		v.Set("expiresBefore", query.ExpiresBefore.Format(time.RFC3339))
+	}
+
+	return v
+}
+
+// SearchKeys searches the public keys with filters and cursor pagination
+func (c *Client) SearchKeys(ctx context.Context, query models.GPGKeySearchQuery) (*models.GPGKeySearchResult, error) {
+	var result models.GPGKeySearchResult
+
+	err := c.getJSON(ctx, "/sks/searchKeys", searchKeysValues(query), &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// AddKey adds a public key to the server public key store
+func (c *Client) AddKey(ctx context.Context, data models.SKSAddKey) error {
+	_, err := c.post(ctx, "/sks/addKey", data)
+	return err
+}
+
+// KeyHistory returns the changes made to a public key, oldest first
+func (c *Client) KeyHistory(ctx context.Context, fingerPrint string) ([]models.GPGKeyHistory, error) {
+	history := make([]models.GPGKeyHistory, 0)
+	err := c.getJSON(ctx, "/sks/keyHistory", url.Values{"fingerPrint": {fingerPrint}}, &history)
+	return history, err
+}
+
+// RestoreKey restores a deleted public key
+func (c *Client) RestoreKey(ctx context.Context, data models.SKSRestoreKey) error {
+	_, err := c.post(ctx, "/sks/restoreKey", data)
+	return err
+}
@@ -42,16 +42,29 @@ func (h *Driver) getKeyListCache(value, criteria string, pageStart, pageEnd int,
 	}
 
 	// Get fallback
-	keys, err = fallback(value, pageStart, pageEnd)
+	// Concurrent misses of the same list share a single fallback call
+	v, err, _ := h.flight.Do(keyString, func() (interface{}, error) {
+		keys, err := fallback(value, pageStart, pageEnd)
+		if err != nil {
+			return nil, err
+		}
+
+		if cacheErr := h.cacheKeyList(keys, keyString); cacheErr != nil {
+			// Cache errors are just logged
+			h.log.Error("error caching key entry list for %s: %s", criteria, cacheErr)
+		}
+		return keys, nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if cacheErr := h.cacheKeyList(keys, keyString); cacheErr != nil {
-		// Cache errors are just logged
-		h.log.Error("error caching key entry list for %s: %s", criteria, cacheErr)
-	}
-	return keys, err
+	// Each caller gets its own copy of the list
+	shared := v.([]models.GPGKey)
+	keys = make([]models.GPGKey, len(shared))
+	copy(keys, shared)
+
+	return keys, nil
 }
 
 func (h *Driver) cacheKey(key models.GPGKey) error {
@@ -108,12 +121,13 @@ func (h *Driver) getCachedKeyByFingerprint(fingerprint string) (key *models.GPGK
 	return key, err
 }
 
+// gpgKeyCacheKeys returns the cache keys used to store the specified GPG key
+func gpgKeyCacheKeys(key models.GPGKey) []string {
+	return []string{gpgKeyByIDPrefix + key.ID, gpgKeyByFingerprintPrefix + tools.FPto16(key.FullFingerprint)}
+}
+
 func (h *Driver) invalidateCachedKey(key models.GPGKey) error {
-	err := h.cache.Delete(context.TODO(), gpgKeyByIDPrefix+key.ID)
-	if err != nil {
-		return err
-	}
-	return h.cache.Delete(context.TODO(), gpgKeyByFingerprintPrefix+key.FullFingerprint)
+	return h.invalidateKeys(gpgKeyCacheKeys(key)...)
 }
 
 // UpdateGPGKey updates the specified GPG key by using it's ID
@@ -124,6 +138,8 @@ func (h *Driver) UpdateGPGKey(key models.GPGKey) (err error) {
 		// The cacheKey will log the error
 		// and we don't want to break the flow
 		_ = h.cacheKey(key)
+		// Other replicas might have the old version in their local cache
+		h.publishInvalidation(gpgKeyCacheKeys(key)...)
 	}
 	return err
 }
@@ -151,6 +167,27 @@ func (h *Driver) DeleteGPGKey(key models.GPGKey) error {
 	return h.proxy.DeleteGPGKey(key)
 }
 
+// RestoreGPGKey restores a previously deleted GPG key by its fingerprint
+// The restored key is cached again on the next fetch
+func (h *Driver) RestoreGPGKey(key models.GPGKey) error {
+	h.log.Debug("RestoreGPGKey(%s)", key.FullFingerprint)
+	return h.proxy.RestoreGPGKey(key)
+}
+
+// FetchGPGKeyHistory returns all changes made to the key with the specified fingerprint
+// History is not cached
+func (h *Driver) FetchGPGKeyHistory(fingerprint string) ([]models.GPGKeyHistory, error) {
+	h.log.Debug("FetchGPGKeyHistory(%s)", fingerprint)
+	return h.proxy.FetchGPGKeyHistory(fingerprint)
+}
+
+// SearchGPGKeys searches the key store using the specified query
+// Search results are not cached
+func (h *Driver) SearchGPGKeys(query models.GPGKeySearchQuery) (*models.GPGKeySearchResult, error) {
+	h.log.Debug("SearchGPGKeys(%s)", query.Query)
+	return h.proxy.SearchGPGKeys(query)
+}
+
 // AddGPGKey adds a GPG Key to the database or update an existing one by fingerprint
 // Returns generated id / hasBeenAdded / error
 func (h *Driver) AddGPGKey(key models.GPGKey) (string, bool, error) {
@@ -161,6 +198,10 @@ func (h *Driver) AddGPGKey(key models.GPGKey) (string, bool, error) {
 	// The cacheKey will log the error
 	// and we don't want to break the flow
 	_ = h.cacheKey(key)
+	if err == nil && !added {
+		// An existing key was updated, other replicas might have the old version in their local cache
+		h.publishInvalidation(gpgKeyCacheKeys(key)...)
+	}
 
 	return id, added, err
 }
@@ -177,16 +218,30 @@ func (h *Driver) FetchGPGKeysWithoutSubKeys() (res []models.GPGKey, err error) {
 func (h *Driver) FetchGPGKeyByFingerprint(fingerprint string) (*models.GPGKey, error) {
 	h.log.Debug("FetchGPGKeyByFingerprint(%s)", fingerprint)
 	key, err := h.getCachedKeyByFingerprint(fingerprint)
-	if err != nil { // Cache miss
-		h.log.Debug("load cache %s error: %s", fingerprint, err)
-		if key, err = h.proxy.FetchGPGKeyByFingerprint(fingerprint); err == nil {
-			// The cacheKey will log the error
-			// and we don't want to break the flow
-			_ = h.cacheKey(*key)
+	if err == nil {
+		return key, nil
+	}
+
+	// Cache miss
+	// Concurrent misses of the same key share a single database call
+	h.log.Debug("load cache %s error: %s", fingerprint, err)
+	v, err, _ := h.flight.Do(gpgKeyByFingerprintPrefix+tools.FPto16(fingerprint), func() (interface{}, error) {
+		key, err := h.proxy.FetchGPGKeyByFingerprint(fingerprint)
+		if err != nil {
+			return nil, err
 		}
+		// The cacheKey will log the error
+		// and we don't want to break the flow
+		_ = h.cacheKey(*key)
+		return *key, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return key, err
+	// Each caller gets its own copy of the key
+	k := v.(models.GPGKey)
+	return &k, nil
 }
 
 // FindGPGKeyByEmail find all keys that has a underlying UID that contains that email
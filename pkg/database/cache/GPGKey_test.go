@@ -87,7 +87,7 @@ func TestDriver_DeleteGPGKey(t *testing.T) {
 	_, _, _ = mem.AddGPGKey(testmodels.GpgKey)
 
 	mock.ExpectDel(gpgKeyByIDPrefix + testKeyToRemove.ID).SetVal(0)
-	mock.ExpectDel(gpgKeyByFingerprintPrefix + testKeyToRemove.FullFingerprint).SetVal(0)
+	mock.ExpectDel(gpgKeyByFingerprintPrefix + testKeyToRemove.GetShortFingerPrint()).SetVal(0)
 
 	err := h.DeleteGPGKey(testKeyToRemove)
 	if err != nil {
@@ -313,3 +313,60 @@ func TestDriver_FindGPGKeyByValue(t *testing.T) {
 		testFindFunction(v, gpgKeysByValueCriteria, h, h.FindGPGKeyByValue, t)
 	}
 }
+
+func TestDriver_RestoreGPGKey(t *testing.T) {
+	mem := memory.MakeMemoryDBDriver(nil)
+	db, mock := redismock.NewClientMock()
+	h := MakeRedisDriver(mem, nil)
+	h.cache = cache.New(&cache.Options{
+		Redis: db,
+	})
+
+	testKey := testmodels.GpgKey
+	testKey.Actor = "tester"
+
+	// Assume mem works, and add / delete the gpg key
+	_, _, _ = mem.AddGPGKey(testKey)
+	_ = mem.DeleteGPGKey(testKey)
+
+	err := h.RestoreGPGKey(testKey)
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	key, err := mem.FetchGPGKeyByFingerprint(testKey.FullFingerprint)
+	if key == nil || err != nil {
+		t.Fatalf("expected key to be restored")
+	}
+
+	err = h.RestoreGPGKey(testKey)
+	if err == nil {
+		t.Fatalf("expected error when restoring a non deleted key")
+	}
+
+	history, err := h.FetchGPGKeyHistory(testKey.FullFingerprint)
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	expectedActions := []string{models.KeyHistoryAdded, models.KeyHistoryDeleted, models.KeyHistoryRestored}
+	if len(history) != len(expectedActions) {
+		t.Fatalf("expected %d history entries got %d", len(expectedActions), len(history))
+	}
+
+	for i, v := range history {
+		if v.Action != expectedActions[i] {
+			t.Errorf("expected history entry %d to be %s got %s", i, expectedActions[i], v.Action)
+		}
+		if v.Actor != testKey.Actor {
+			t.Errorf("expected history entry %d actor to be %q got %q", i, testKey.Actor, v.Actor)
+		}
+		if v.Key.AsciiArmoredPrivateKey != "" {
+			t.Errorf("expected history entry %d to not contain the private key", i)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+}
@@ -7,7 +7,9 @@ import (
 
 	"github.com/go-redis/cache/v8"
 	"github.com/go-redis/redis/v8"
+	"github.com/quan-to/chevron/pkg/uuid"
 	"github.com/quan-to/slog"
+	"golang.org/x/sync/singleflight"
 )
 
 type rediser interface {
@@ -17,6 +19,19 @@ type rediser interface {
 
 	Get(ctx context.Context, key string) *redis.StringCmd
 	Del(ctx context.Context, keys ...string) *redis.IntCmd
+
+	SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	SRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	SMembers(ctx context.Context, key string) *redis.StringSliceCmd
+	TTL(ctx context.Context, key string) *redis.DurationCmd
+	Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd
+
+	Publish(ctx context.Context, channel string, message interface{}) *redis.IntCmd
+}
+
+// subscriber is implemented by the redis clients that support pub/sub
+type subscriber interface {
+	Subscribe(ctx context.Context, channels ...string) *redis.PubSub
 }
 
 // Driver is a database handler proxy for caching
@@ -25,6 +40,12 @@ type Driver struct {
 	log   slog.Instance
 	redis rediser
 	cache *cache.Cache
+
+	localCache          cache.LocalCache
+	instanceID          string
+	invalidationChannel string
+	pubSub              *redis.PubSub
+	flight              singleflight.Group
 }
 
 // MakeRedisDriver creates a Redis Caching layer for the specified handler
@@ -35,24 +56,32 @@ func MakeRedisDriver(dbh ProxiedHandler, log slog.Instance) *Driver {
 	} else {
 		log = log.SubScope("REDIS")
 	}
-	return &Driver{proxy: dbh, log: log}
+	return &Driver{proxy: dbh, log: log, instanceID: uuid.EnsureUUID(log)}
 }
 
 // HealthCheck returns nil if everything is OK with the handler
 func (h *Driver) HealthCheck() error {
+	if err := h.CacheHealthCheck(); err != nil {
+		return err
+	}
+	return h.ProxiedHealthCheck()
+}
+
+// CacheHealthCheck returns nil if the Redis connection is OK
+func (h *Driver) CacheHealthCheck() error {
 	// This might deviate the statistics,
 	// but its the only way I found out to test the connection
-	err := h.cache.Set(&cache.Item{
+	return h.cache.Set(&cache.Item{
 		Ctx:   context.TODO(),
 		Key:   userTokenPrefix + "__HC__",
 		Value: &struct{}{},
 		TTL:   time.Second * 4,
 	})
-	if err == nil {
-		// Test the proxy
-		return h.proxy.HealthCheck()
-	}
-	return err
+}
+
+// ProxiedHealthCheck returns nil if everything is OK with the proxied handler
+func (h *Driver) ProxiedHealthCheck() error {
+	return h.proxy.HealthCheck()
 }
 
 // Setup configures the RedisDriver connection and cache ring
@@ -67,9 +96,10 @@ func (h *Driver) Setup(client rediser, maxLocalObjects int, localObjectTTL time.
 		return fmt.Errorf("you should specify a redis client")
 	}
 	h.redis = client
+	h.localCache = cache.NewTinyLFU(maxLocalObjects, localObjectTTL)
 	h.cache = cache.New(&cache.Options{
 		Redis:      h.redis,
-		LocalCache: cache.NewTinyLFU(maxLocalObjects, localObjectTTL),
+		LocalCache: h.localCache,
 	})
 
 	return nil
@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-redis/cache/v8"
+	"github.com/go-redis/redismock/v8"
+	"github.com/quan-to/chevron/pkg/database/memory"
+)
+
+func TestDriver_HealthCheck(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	h := MakeRedisDriver(memory.MakeMemoryDBDriver(nil), nil)
+	h.redis = db
+	h.cache = cache.New(&cache.Options{
+		Redis: db,
+	})
+
+	data, err := h.cache.Marshal(&struct{}{})
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	mock.ExpectSet(userTokenPrefix+"__HC__", data, time.Second*4).SetVal("OK")
+
+	if err := h.HealthCheck(); err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	mock.ExpectSet(userTokenPrefix+"__HC__", data, time.Second*4).SetErr(fmt.Errorf("connection refused"))
+
+	if err := h.CacheHealthCheck(); err == nil {
+		t.Fatalf("expected error when redis is down")
+	}
+
+	if err := h.ProxiedHealthCheck(); err != nil {
+		t.Fatalf("expected proxied handler to be healthy got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf(expectationsWereNotMet, err)
+	}
+}
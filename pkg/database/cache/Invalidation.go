@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// invalidationMessage is published to the invalidation channel every time a cached entry changes
+// so all other replicas evict it from their local cache
+type invalidationMessage struct {
+	Origin string   `json:"origin"`
+	Keys   []string `json:"keys"`
+}
+
+// StartInvalidationListener subscribes to the specified redis channel to evict local cache entries
+// changed by other replicas. Without it, a replica can serve a stale entry until its local TTL expires.
+// Messages lost while the subscription is reconnecting are not recovered, so the local TTL still
+// bounds the staleness in that case.
+func (h *Driver) StartInvalidationListener(channel string) error {
+	if channel == "" {
+		return fmt.Errorf("invalidation channel can't be empty")
+	}
+
+	sub, ok := h.redis.(subscriber)
+	if !ok {
+		return fmt.Errorf("redis client does not support pub/sub")
+	}
+
+	h.invalidationChannel = channel
+	h.pubSub = sub.Subscribe(context.Background(), channel)
+
+	go h.listenInvalidations(h.pubSub.Channel())
+
+	h.log.Info("Listening cache invalidations on channel %s", channel)
+	return nil
+}
+
+// StopInvalidationListener closes the invalidation channel subscription
+func (h *Driver) StopInvalidationListener() error {
+	if h.pubSub == nil {
+		return nil
+	}
+
+	err := h.pubSub.Close()
+	h.pubSub = nil
+	return err
+}
+
+func (h *Driver) listenInvalidations(messages <-chan *redis.Message) {
+	for msg := range messages {
+		h.handleInvalidationMessage(msg.Payload)
+	}
+	h.log.Debug("invalidation listener stopped")
+}
+
+// handleInvalidationMessage evicts from the local cache the keys of a message published by another replica
+func (h *Driver) handleInvalidationMessage(payload string) {
+	var msg invalidationMessage
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		h.log.Warn("invalid cache invalidation message: %s", err)
+		return
+	}
+
+	if msg.Origin == h.instanceID || h.localCache == nil {
+		return
+	}
+
+	for _, key := range msg.Keys {
+		h.log.Debug("evicting %s from local cache", key)
+		h.localCache.Del(key)
+	}
+}
+
+// publishInvalidation notifies the other replicas that the keys have changed
+// Errors are only logged since the entries will expire by their local TTL anyway
+func (h *Driver) publishInvalidation(keys ...string) {
+	if h.redis == nil || h.invalidationChannel == "" || len(keys) == 0 {
+		return
+	}
+
+	data, _ := json.Marshal(invalidationMessage{
+		Origin: h.instanceID,
+		Keys:   keys,
+	})
+
+	if err := h.redis.Publish(context.TODO(), h.invalidationChannel, string(data)).Err(); err != nil {
+		h.log.Error("error publishing cache invalidation for %v: %s", keys, err)
+	}
+}
+
+// invalidateKeys deletes the keys from redis and the local cache and notifies the other replicas
+func (h *Driver) invalidateKeys(keys ...string) error {
+	for _, key := range keys {
+		if err := h.cache.Delete(context.TODO(), key); err != nil {
+			return err
+		}
+	}
+
+	h.publishInvalidation(keys...)
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-redis/cache/v8"
+	"github.com/go-redis/redismock/v8"
+	"github.com/quan-to/chevron/pkg/database/memory"
+	"github.com/quan-to/chevron/pkg/models"
+	"github.com/quan-to/chevron/pkg/models/testmodels"
+)
+
+const testInvalidationChannel = "test-invalidation"
+
+// slowFetchDriver counts the calls to FetchGPGKeyByFingerprint and FindGPGKeyByEmail delaying them,
+// so concurrent calls overlap
+type slowFetchDriver struct {
+	*memory.DbDriver
+	calls int32
+}
+
+func (d *slowFetchDriver) FetchGPGKeyByFingerprint(fingerprint string) (*models.GPGKey, error) {
+	atomic.AddInt32(&d.calls, 1)
+	time.Sleep(50 * time.Millisecond)
+	return d.DbDriver.FetchGPGKeyByFingerprint(fingerprint)
+}
+
+func (d *slowFetchDriver) FindGPGKeyByEmail(email string, pageStart, pageEnd int) ([]models.GPGKey, error) {
+	atomic.AddInt32(&d.calls, 1)
+	time.Sleep(50 * time.Millisecond)
+	return d.DbDriver.FindGPGKeyByEmail(email, pageStart, pageEnd)
+}
+
+func makeLocalOnlyDriver(t *testing.T) (*Driver, *slowFetchDriver) {
+	mem := &slowFetchDriver{DbDriver: memory.MakeMemoryDBDriver(nil)}
+	_, _, err := mem.DbDriver.AddGPGKey(testmodels.GpgKey)
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	h := MakeRedisDriver(mem, nil)
+	h.localCache = cache.NewTinyLFU(10, time.Minute)
+	h.cache = cache.New(&cache.Options{
+		LocalCache: h.localCache,
+	})
+
+	return h, mem
+}
+
+func TestDriver_RemoveUserTokenPublishesInvalidation(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	h := MakeRedisDriver(nil, nil)
+	h.redis = db
+	h.invalidationChannel = testInvalidationChannel
+	h.cache = cache.New(&cache.Options{
+		Redis: db,
+	})
+
+	msg, _ := json.Marshal(invalidationMessage{
+		Origin: h.instanceID,
+		Keys:   []string{userTokenPrefix + testmodels.Token.Token},
+	})
+
+	mock.ExpectDel(userTokenPrefix + testmodels.Token.Token).SetVal(1)
+	mock.ExpectPublish(testInvalidationChannel, string(msg)).SetVal(1)
+
+	err := h.RemoveUserToken(testmodels.Token.Token)
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf(expectationsWereNotMet, err)
+	}
+}
+
+func TestDriver_HandleInvalidationMessage(t *testing.T) {
+	h, _ := makeLocalOnlyDriver(t)
+	key := gpgKeyByFingerprintPrefix + testmodels.GpgKey.GetShortFingerPrint()
+
+	h.localCache.Set(key, []byte("data"))
+
+	// Own messages are ignored
+	own, _ := json.Marshal(invalidationMessage{Origin: h.instanceID, Keys: []string{key}})
+	h.handleInvalidationMessage(string(own))
+	if _, ok := h.localCache.Get(key); !ok {
+		t.Fatal("expected own invalidation message to be ignored")
+	}
+
+	// Invalid messages are ignored
+	h.handleInvalidationMessage("{invalid")
+	if _, ok := h.localCache.Get(key); !ok {
+		t.Fatal("expected invalid message to be ignored")
+	}
+
+	other, _ := json.Marshal(invalidationMessage{Origin: "another-replica", Keys: []string{key}})
+	h.handleInvalidationMessage(string(other))
+	if _, ok := h.localCache.Get(key); ok {
+		t.Fatal("expected key to be evicted from the local cache")
+	}
+}
+
+func TestDriver_FetchGPGKeyByFingerprintSingleFlight(t *testing.T) {
+	h, mem := makeLocalOnlyDriver(t)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key, err := h.FetchGPGKeyByFingerprint(testmodels.GpgKey.FullFingerprint)
+			if err != nil {
+				t.Errorf(unexpectedError, err)
+				return
+			}
+			if key.FullFingerprint != testmodels.GpgKey.FullFingerprint {
+				t.Errorf("expected fingerprint %s got %s", testmodels.GpgKey.FullFingerprint, key.FullFingerprint)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls := atomic.LoadInt32(&mem.calls); calls != 1 {
+		t.Fatalf("expected 1 database call got %d", calls)
+	}
+}
+
+func TestDriver_KeyListCacheSingleFlight(t *testing.T) {
+	h, mem := makeLocalOnlyDriver(t)
+	email := testmodels.GpgKey.KeyUids[0].Email
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			keys, err := h.FindGPGKeyByEmail(email, 0, 10)
+			if err != nil {
+				t.Errorf(unexpectedError, err)
+				return
+			}
+			if len(keys) != 1 {
+				t.Errorf("expected 1 key got %d", len(keys))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls := atomic.LoadInt32(&mem.calls); calls != 1 {
+		t.Fatalf("expected 1 database call got %d", calls)
+	}
+}
@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/cache/v8"
+	"github.com/quan-to/chevron/pkg/models"
+	"github.com/quan-to/chevron/pkg/uuid"
+)
+
+const (
+	tokenRevocationPrefix = "tokenRevocation-"
+	// userTokenRevocationPrefix keeps only the latest revocation of all tokens of a user, without expiration
+	userTokenRevocationPrefix = "userTokenRevocation-"
+)
+
+func userTokenRevocationKey(username string) string {
+	return userTokenRevocationPrefix + strings.ToLower(username)
+}
+
+// AddTokenRevocation stores a token revocation and returns its ID
+// Token revocations expire with the token. Revocations of all tokens of a user replace the previous one
+func (h *Driver) AddTokenRevocation(tr models.TokenRevocation) (string, error) {
+	h.log.Debug("AddTokenRevocation(%s)", tr.Username)
+	if tr.TokenHash == "" && tr.Username == "" {
+		return "", fmt.Errorf("empty revocation")
+	}
+
+	tr.ID = uuid.EnsureUUID(h.log)
+
+	if tr.TokenHash != "" {
+		return tr.ID, h.cache.Set(&cache.Item{
+			Ctx:   context.TODO(),
+			Key:   tokenRevocationPrefix + tr.TokenHash,
+			Value: &tr,
+			TTL:   tr.Expiration.Sub(time.Now()),
+		})
+	}
+
+	key := userTokenRevocationKey(tr.Username)
+	if err := h.cache.Set(&cache.Item{
+		Ctx:   context.TODO(),
+		Key:   key,
+		Value: &tr,
+	}); err != nil {
+		return "", err
+	}
+
+	// Other replicas might have the previous revocation in their local cache
+	h.publishInvalidation(key)
+
+	return tr.ID, nil
+}
+
+// GetTokenRevocation fetches the revocation of the token with the specified hash
+func (h *Driver) GetTokenRevocation(tokenHash string) (tr *models.TokenRevocation, err error) {
+	h.log.Debug("GetTokenRevocation()")
+	return h.getTokenRevocation(tokenRevocationPrefix + tokenHash)
+}
+
+// GetUserTokenRevocation fetches the latest revocation of all tokens of the specified user
+func (h *Driver) GetUserTokenRevocation(username string) (tr *models.TokenRevocation, err error) {
+	h.log.Debug("GetUserTokenRevocation(%s)", username)
+	return h.getTokenRevocation(userTokenRevocationKey(username))
+}
+
+func (h *Driver) getTokenRevocation(key string) (tr *models.TokenRevocation, err error) {
+	err = h.cache.Get(context.TODO(), key, &tr)
+	if err == cache.ErrCacheMiss {
+		return nil, fmt.Errorf("not found")
+	}
+
+	return tr, err
+}
+
+// InvalidateTokenRevocations removes the revocations of tokens that had been already expired
+// Does nothing on REDIS due automatic expiration using TTL
+func (h *Driver) InvalidateTokenRevocations() (int, error) {
+	// Not needed for redis, automatic expiration due TTL
+	return 0, nil
+}
@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"bou.ke/monkey"
+	"github.com/go-redis/cache/v8"
+	"github.com/go-redis/redis/v8"
+	"github.com/go-redis/redismock/v8"
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/quan-to/chevron/pkg/models"
+	"github.com/quan-to/chevron/pkg/models/testmodels"
+	"github.com/quan-to/chevron/pkg/uuid"
+	"github.com/quan-to/slog"
+)
+
+func TestDriver_AddTokenRevocation(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	h := MakeRedisDriver(nil, nil)
+	h.redis = db
+	h.cache = cache.New(&cache.Options{
+		Redis: db,
+	})
+
+	monkey.Patch(uuid.EnsureUUID, func(log slog.Instance) string {
+		return "0000"
+	})
+	monkey.Patch(time.Now, func() time.Time {
+		return testmodels.Time
+	})
+
+	tokenRevocation := models.TokenRevocation{
+		ID:         "0000",
+		TokenHash:  "abcd",
+		Username:   testmodels.Token.Username,
+		CreatedAt:  testmodels.Time,
+		Expiration: testmodels.Time.Add(time.Hour),
+	}
+
+	userRevocation := models.TokenRevocation{
+		ID:        "0000",
+		Username:  testmodels.Token.Username,
+		CreatedAt: testmodels.Time,
+	}
+
+	tokenData, _ := h.cache.Marshal(&tokenRevocation)
+	userData, _ := h.cache.Marshal(&userRevocation)
+
+	mock.ExpectSet(tokenRevocationPrefix+tokenRevocation.TokenHash, tokenData, time.Hour).SetVal("")
+	mock.ExpectSet(userTokenRevocationKey(userRevocation.Username), userData, 0).SetVal("")
+
+	id, err := h.AddTokenRevocation(tokenRevocation)
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	if id != "0000" {
+		t.Fatalf("expected id to be %s got %s", "0000", id)
+	}
+
+	_, err = h.AddTokenRevocation(userRevocation)
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	_, err = h.AddTokenRevocation(models.TokenRevocation{})
+	if err == nil {
+		t.Fatalf("expected error adding an empty revocation")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf(expectationsWereNotMet, err)
+	}
+}
+
+func TestDriver_GetTokenRevocation(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	h := MakeRedisDriver(nil, nil)
+	h.cache = cache.New(&cache.Options{
+		Redis: db,
+	})
+
+	testData := models.TokenRevocation{
+		ID:        "0000",
+		Username:  testmodels.Token.Username,
+		CreatedAt: testmodels.Time,
+	}
+
+	data, _ := h.cache.Marshal(&testData)
+
+	mock.ExpectGet(userTokenRevocationKey(testData.Username)).SetVal(string(data))
+	mock.ExpectGet(tokenRevocationPrefix + "abcd").SetErr(redis.Nil)
+
+	tr, err := h.GetUserTokenRevocation(strings.ToUpper(testData.Username))
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	if diff := pretty.Compare(testData, tr); diff != "" {
+		t.Errorf("Expected revocation to be the same. (-got +want)\\n%s", diff)
+	}
+
+	_, err = h.GetTokenRevocation("abcd")
+	if err == nil || !strings.EqualFold(err.Error(), "not found") {
+		t.Fatalf("expected error to be %q got %v", "not found", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf(expectationsWereNotMet, err)
+	}
+}
@@ -21,3 +21,15 @@ func (h *Driver) UpdateUser(um models.User) error {
 	h.log.Debug("UpdateUser(%s)", um.Username)
 	return h.proxy.UpdateUser(um)
 }
+
+// ListUsers returns the users sorted by username in the specified page
+func (h *Driver) ListUsers(pageStart, pageEnd int) ([]models.User, error) {
+	h.log.Debug("ListUsers(%d, %d)", pageStart, pageEnd)
+	return h.proxy.ListUsers(pageStart, pageEnd)
+}
+
+// DeleteUser deletes the specified user
+func (h *Driver) DeleteUser(um models.User) error {
+	h.log.Debug("DeleteUser(%s)", um.Username)
+	return h.proxy.DeleteUser(um)
+}
@@ -117,3 +117,41 @@ func TestDriver_UpdateUser(t *testing.T) {
 		t.Fatalf(expectationsWereNotMet, err)
 	}
 }
+
+func TestDriver_ListAndDeleteUser(t *testing.T) {
+	mem := memory.MakeMemoryDBDriver(nil)
+	db, mock := redismock.NewClientMock()
+	h := MakeRedisDriver(mem, nil)
+	h.cache = cache.New(&cache.Options{
+		Redis: db,
+	})
+
+	_, err := mem.AddUser(testmodels.User)
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	// Passthrough calls, test if consistent
+	users, err := h.ListUsers(0, 10)
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	if len(users) != 1 || users[0].Username != testmodels.User.Username {
+		t.Fatalf("expected user %s to be listed, got %v", testmodels.User.Username, users)
+	}
+
+	err = h.DeleteUser(users[0])
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	_, err = mem.GetUser(testmodels.User.Username)
+	if err == nil {
+		t.Fatalf("expected user to be deleted")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf(expectationsWereNotMet, err)
+	}
+}
@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/go-redis/cache/v8"
@@ -9,7 +10,16 @@ import (
 	"github.com/quan-to/chevron/pkg/uuid"
 )
 
-const userTokenPrefix = "userToken-"
+const (
+	userTokenPrefix = "userToken-"
+	// userTokensByUserPrefix is a redis set with all tokens of a user
+	// Members are not removed when a token expires, so they're cleaned when listed
+	userTokensByUserPrefix = "userTokensByUser-"
+)
+
+func userTokensKey(username string) string {
+	return userTokensByUserPrefix + strings.ToLower(username)
+}
 
 // AddUserToken adds a new user token to be valid and returns its token ID
 func (h *Driver) AddUserToken(ut models.UserToken) (string, error) {
@@ -26,13 +36,38 @@ func (h *Driver) AddUserToken(ut models.UserToken) (string, error) {
 		return "", err
 	}
 
+	if err := h.indexUserToken(ut.Username, ut.Token, exp); err != nil {
+		// The token is still valid, it just won't be listed in the user tokens
+		h.log.Error("error indexing token of user %s: %s", ut.Username, err)
+	}
+
 	return ut.ID, nil
 }
 
+// indexUserToken adds the token to the user token set and extends the set expiration to the token expiration
+func (h *Driver) indexUserToken(username, token string, exp time.Duration) error {
+	key := userTokensKey(username)
+
+	if err := h.redis.SAdd(context.TODO(), key, token).Err(); err != nil {
+		return err
+	}
+
+	ttl, err := h.redis.TTL(context.TODO(), key).Result()
+	if err != nil {
+		return err
+	}
+
+	if ttl < exp {
+		return h.redis.Expire(context.TODO(), key, exp).Err()
+	}
+
+	return nil
+}
+
 // RemoveUserToken removes a user token from the database
 func (h *Driver) RemoveUserToken(token string) (err error) {
 	h.log.Debug("RemoveUserToken(%s)", token)
-	return h.cache.Delete(context.TODO(), userTokenPrefix+token)
+	return h.invalidateKeys(userTokenPrefix + token)
 }
 
 // GetUserToken fetch a UserToken object by the specified token
@@ -48,3 +83,62 @@ func (h *Driver) InvalidateUserTokens() (int, error) {
 	// Not needed for redis, automatic expiration due TTL
 	return 0, nil
 }
+
+// FetchUserTokens returns all tokens of the specified user
+func (h *Driver) FetchUserTokens(username string) ([]models.UserToken, error) {
+	h.log.Debug("FetchUserTokens(%s)", username)
+	key := userTokensKey(username)
+
+	members, err := h.redis.SMembers(context.TODO(), key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make([]models.UserToken, 0, len(members))
+	var expired []interface{}
+
+	for _, token := range members {
+		ut, err := h.GetUserToken(token)
+		if err == cache.ErrCacheMiss {
+			expired = append(expired, token)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *ut)
+	}
+
+	if len(expired) > 0 {
+		if err := h.redis.SRem(context.TODO(), key, expired...).Err(); err != nil {
+			h.log.Error("error cleaning expired tokens of user %s: %s", username, err)
+		}
+	}
+
+	return tokens, nil
+}
+
+// RemoveUserTokens removes all tokens of the specified user
+func (h *Driver) RemoveUserTokens(username string) (int, error) {
+	h.log.Debug("RemoveUserTokens(%s)", username)
+
+	tokens, err := h.FetchUserTokens(username)
+	if err != nil {
+		return 0, err
+	}
+
+	keys := make([]string, len(tokens))
+	for i, ut := range tokens {
+		keys[i] = userTokenPrefix + ut.Token
+	}
+
+	if err := h.invalidateKeys(keys...); err != nil {
+		return 0, err
+	}
+
+	if err := h.redis.Del(context.TODO(), userTokensKey(username)).Err(); err != nil {
+		return 0, err
+	}
+
+	return len(tokens), nil
+}
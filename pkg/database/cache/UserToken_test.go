@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/quan-to/chevron/pkg/models"
 	"github.com/quan-to/chevron/pkg/models/testmodels"
 
 	"bou.ke/monkey"
@@ -23,6 +24,7 @@ const userTokenExpirationTime = time.Hour
 func TestDriver_AddUserToken(t *testing.T) {
 	db, mock := redismock.NewClientMock()
 	h := MakeRedisDriver(nil, nil)
+	h.redis = db
 	h.cache = cache.New(&cache.Options{
 		Redis: db,
 	})
@@ -45,6 +47,9 @@ func TestDriver_AddUserToken(t *testing.T) {
 
 	mock.ExpectSet(userTokenPrefix+testmodels.Token.Token, data, userTokenExpirationTime).
 		SetVal("")
+	mock.ExpectSAdd(userTokensKey(testmodels.Token.Username), testmodels.Token.Token).SetVal(1)
+	mock.ExpectTTL(userTokensKey(testmodels.Token.Username)).SetVal(-2)
+	mock.ExpectExpire(userTokensKey(testmodels.Token.Username), userTokenExpirationTime).SetVal(true)
 
 	entryId, err := h.AddUserToken(testmodels.Token)
 	if err != nil {
@@ -111,3 +116,76 @@ func TestDriver_InvalidateUserTokens(t *testing.T) {
 		t.Fatalf("expected no invalidations, got %d", n)
 	}
 }
+
+func TestDriver_FetchUserTokens(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	h := MakeRedisDriver(nil, nil)
+	h.redis = db
+	h.cache = cache.New(&cache.Options{
+		Redis: db,
+	})
+
+	testData := testmodels.Token
+	testData.ID = "0000"
+
+	data, err := h.cache.Marshal(&testData)
+
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	key := userTokensKey(testmodels.Token.Username)
+
+	mock.ExpectSMembers(key).SetVal([]string{testmodels.Token.Token, "expired"})
+	mock.ExpectGet(userTokenPrefix + testmodels.Token.Token).SetVal(string(data))
+	mock.ExpectGet(userTokenPrefix + "expired").RedisNil()
+	mock.ExpectSRem(key, "expired").SetVal(1)
+
+	tokens, err := h.FetchUserTokens(testmodels.Token.Username)
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	if diff := pretty.Compare([]models.UserToken{testData}, tokens); diff != "" {
+		t.Errorf("Expected tokens to be the same. (-got +want)\\n%s", diff)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf(expectationsWereNotMet, err)
+	}
+}
+
+func TestDriver_RemoveUserTokens(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	h := MakeRedisDriver(nil, nil)
+	h.redis = db
+	h.cache = cache.New(&cache.Options{
+		Redis: db,
+	})
+
+	data, err := h.cache.Marshal(&testmodels.Token)
+
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	key := userTokensKey(testmodels.Token.Username)
+
+	mock.ExpectSMembers(key).SetVal([]string{testmodels.Token.Token})
+	mock.ExpectGet(userTokenPrefix + testmodels.Token.Token).SetVal(string(data))
+	mock.ExpectDel(userTokenPrefix + testmodels.Token.Token).SetVal(1)
+	mock.ExpectDel(key).SetVal(1)
+
+	n, err := h.RemoveUserTokens(testmodels.Token.Username)
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	if n != 1 {
+		t.Fatalf("expected 1 removed token, got %d", n)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf(expectationsWereNotMet, err)
+	}
+}
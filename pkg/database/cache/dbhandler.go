@@ -24,6 +24,10 @@ type ProxiedUserRepository interface {
 	InvalidateUserTokens() (int, error)
 	AddUser(um models.User) (string, error)
 	UpdateUser(um models.User) error
+	// ListUsers returns the users sorted by username in the specified page
+	ListUsers(pageStart, pageEnd int) ([]models.User, error)
+	// DeleteUser deletes the specified user
+	DeleteUser(um models.User) error
 }
 
 // ProxiedUserRepository a proxy to a GPG Repository
@@ -49,6 +53,12 @@ type ProxiedGPGRepository interface {
 	FetchGPGKeysWithoutSubKeys() (res []models.GPGKey, err error)
 	// DeleteGPGKey deletes the specified GPG key by using it's ID
 	DeleteGPGKey(key models.GPGKey) error
+	// RestoreGPGKey restores a previously deleted GPG key by its fingerprint
+	RestoreGPGKey(key models.GPGKey) error
+	// FetchGPGKeyHistory returns all changes made to the key with the specified fingerprint
+	FetchGPGKeyHistory(fingerprint string) ([]models.GPGKeyHistory, error)
+	// SearchGPGKeys searches the key store using the specified query
+	SearchGPGKeys(query models.GPGKeySearchQuery) (*models.GPGKeySearchResult, error)
 	// UpdateGPGKey updates the specified GPG key by using it's ID
 	UpdateGPGKey(key models.GPGKey) (err error)
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/quan-to/chevron/internal/tools"
 	"github.com/quan-to/chevron/pkg/models"
 	"github.com/quan-to/chevron/pkg/uuid"
 )
@@ -19,6 +20,9 @@ func (h *DbDriver) UpdateGPGKey(key models.GPGKey) error {
 
 	for i, v := range h.keys {
 		if strings.EqualFold(v.FullFingerprint, key.FullFingerprint) {
+			if changes := models.DiffGPGKeys(v, key); len(changes) > 0 {
+				h.addHistory(key, models.KeyHistoryUpdated, changes)
+			}
 			h.keys[i] = key
 			return nil
 		}
@@ -27,14 +31,49 @@ func (h *DbDriver) UpdateGPGKey(key models.GPGKey) error {
 	return fmt.Errorf("not found")
 }
 
+// DeleteGPGKey soft deletes the specified GPG key by using it's ID or fingerprint
+// The key can be restored later using RestoreGPGKey
 func (h *DbDriver) DeleteGPGKey(key models.GPGKey) error {
 	h.log.Debug("DeleteGPGKey(%q, %q)", key.ID, key.FullFingerprint)
 	h.lock.Lock()
 	defer h.lock.Unlock()
 
 	for i, v := range h.keys {
-		if v.ID == key.ID || v.FullFingerprint == key.FullFingerprint {
+		if v.ID == key.ID || (key.FullFingerprint != "" && tools.CompareFingerPrint(v.FullFingerprint, key.FullFingerprint)) {
 			h.keys = append(h.keys[:i], h.keys[i+1:]...)
+			h.deletedKeys = append(h.deletedKeys, v)
+			v.Actor = key.Actor
+			h.addHistory(v, models.KeyHistoryDeleted, nil)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("not found")
+}
+
+// RestoreGPGKey restores a previously deleted GPG key by its fingerprint
+func (h *DbDriver) RestoreGPGKey(key models.GPGKey) error {
+	h.log.Debug("RestoreGPGKey(%q)", key.FullFingerprint)
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if key.FullFingerprint == "" {
+		return fmt.Errorf("not found")
+	}
+
+	for _, v := range h.keys {
+		if tools.CompareFingerPrint(v.FullFingerprint, key.FullFingerprint) {
+			return fmt.Errorf("already exists")
+		}
+	}
+
+	for i := len(h.deletedKeys) - 1; i >= 0; i-- {
+		v := h.deletedKeys[i]
+		if tools.CompareFingerPrint(v.FullFingerprint, key.FullFingerprint) {
+			h.deletedKeys = append(h.deletedKeys[:i], h.deletedKeys[i+1:]...)
+			h.keys = append(h.keys, v)
+			v.Actor = key.Actor
+			h.addHistory(v, models.KeyHistoryRestored, nil)
 			return nil
 		}
 	}
@@ -42,6 +81,36 @@ func (h *DbDriver) DeleteGPGKey(key models.GPGKey) error {
 	return fmt.Errorf("not found")
 }
 
+// FetchGPGKeyHistory returns all changes made to the key with the specified fingerprint, oldest first
+func (h *DbDriver) FetchGPGKeyHistory(fingerprint string) ([]models.GPGKeyHistory, error) {
+	h.log.Debug("FetchGPGKeyHistory(%q)", fingerprint)
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	if fingerprint == "" {
+		return nil, fmt.Errorf("not found")
+	}
+
+	var items []models.GPGKeyHistory
+	for _, v := range h.keyHistory {
+		if tools.CompareFingerPrint(v.FullFingerprint, fingerprint) {
+			items = append(items, v)
+		}
+	}
+
+	if len(items) == 0 {
+		return nil, fmt.Errorf("not found")
+	}
+
+	return items, nil
+}
+
+func (h *DbDriver) addHistory(key models.GPGKey, action string, changes []string) {
+	entry := models.MakeGPGKeyHistory(key, action, changes)
+	entry.ID = uuid.EnsureUUID(h.log)
+	h.keyHistory = append(h.keyHistory, entry)
+}
+
 func (h *DbDriver) addGpgKey(key models.GPGKey) (string, bool, error) {
 	if key.FullFingerprint == "" {
 		return "", false, fmt.Errorf("invalid key fingerprint")
@@ -50,6 +119,9 @@ func (h *DbDriver) addGpgKey(key models.GPGKey) (string, bool, error) {
 	for i, v := range h.keys {
 		if strings.EqualFold(v.FullFingerprint, key.FullFingerprint) {
 			key.ID = v.ID
+			if changes := models.DiffGPGKeys(v, key); len(changes) > 0 {
+				h.addHistory(key, models.KeyHistoryUpdated, changes)
+			}
 			h.keys[i] = key
 			return key.ID, false, nil
 		}
@@ -58,6 +130,7 @@ func (h *DbDriver) addGpgKey(key models.GPGKey) (string, bool, error) {
 	key.ID = uuid.EnsureUUID(h.log)
 
 	h.keys = append(h.keys, key)
+	h.addHistory(key, models.KeyHistoryAdded, nil)
 	return key.ID, true, nil
 }
 
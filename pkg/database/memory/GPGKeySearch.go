@@ -0,0 +1,251 @@
+package memory
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/quan-to/chevron/internal/tools"
+	"github.com/quan-to/chevron/pkg/models"
+)
+
+type searchMatch struct {
+	key       models.GPGKey
+	sortValue interface{}
+}
+
+// SearchGPGKeys searches the key store using the specified query
+// The matching rules are the same as the PostgreSQL driver
+func (h *DbDriver) SearchGPGKeys(query models.GPGKeySearchQuery) (*models.GPGKeySearchResult, error) {
+	h.log.Debug("SearchGPGKeys(%q)", query.Query)
+	query = query.Normalized()
+	err := query.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor *models.GPGKeySearchCursor
+	var cursorValue interface{}
+	if query.Cursor != "" {
+		cursor, _ = query.DecodeCursor()
+		cursorValue, _ = models.ParseSearchSortValue(cursor.SortBy, cursor.Value)
+	}
+
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	now := time.Now()
+	var matches []searchMatch
+
+	for _, v := range h.keys {
+		if v.ParentKey != nil || !keyMatchesSearch(v, query, now) {
+			continue
+		}
+		matches = append(matches, searchMatch{
+			key:       v,
+			sortValue: searchSortValue(v, query),
+		})
+	}
+
+	desc := query.SortOrder == models.SearchOrderDesc
+	sort.SliceStable(matches, func(i, j int) bool {
+		c := compareSearchMatch(matches[i].sortValue, matches[i].key.FullFingerprint, matches[j].sortValue, matches[j].key.FullFingerprint)
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	res := &models.GPGKeySearchResult{
+		Keys:  make([]models.GPGKey, 0),
+		Total: len(matches),
+	}
+
+	start := 0
+	if cursor != nil {
+		for start < len(matches) {
+			c := compareSearchMatch(matches[start].sortValue, matches[start].key.FullFingerprint, cursorValue, cursor.FingerPrint)
+			if (desc && c < 0) || (!desc && c > 0) {
+				break
+			}
+			start++
+		}
+	}
+
+	end := start + query.Limit
+	if end > len(matches) {
+		end = len(matches)
+	}
+
+	for _, m := range matches[start:end] {
+		res.Keys = append(res.Keys, m.key)
+	}
+
+	if end < len(matches) {
+		last := matches[end-1]
+		res.NextCursor = models.GPGKeySearchCursor{
+			SortBy:      query.SortBy,
+			Value:       models.FormatSearchSortValue(last.sortValue),
+			FingerPrint: last.key.FullFingerprint,
+		}.Encode()
+	}
+
+	return res, nil
+}
+
+func keyMatchesSearch(key models.GPGKey, query models.GPGKeySearchQuery, now time.Time) bool {
+	if query.MinKeyBits > 0 && key.KeyBits < query.MinKeyBits {
+		return false
+	}
+
+	if query.MaxKeyBits > 0 && key.KeyBits > query.MaxKeyBits {
+		return false
+	}
+
+	if query.Algorithm != "" && !strings.EqualFold(key.KeyAlgorithm, query.Algorithm) {
+		return false
+	}
+
+	if query.ExcludeExpired && key.IsExpired(now) {
+		return false
+	}
+
+	if query.ExpiresAfter != nil && key.IsExpired(*query.ExpiresAfter) {
+		return false
+	}
+
+	if query.ExpiresBefore != nil && (key.ExpiresAt == nil || !key.ExpiresAt.Before(*query.ExpiresBefore)) {
+		return false
+	}
+
+	if query.Query == "" {
+		return true
+	}
+
+	if domain := query.Domain(); domain != "" {
+		for _, uid := range key.KeyUids {
+			if strings.HasSuffix(strings.ToLower(uid.Email), domain) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if fp := query.FingerPrint(); fp != "" && strings.HasSuffix(key.FullFingerprint, fp) {
+		return true
+	}
+
+	terms := query.Terms()
+	lowerQuery := strings.ToLower(query.Query)
+
+	for _, uid := range key.KeyUids {
+		if len(terms) > 0 && wordsHavePrefixes(models.SearchWords(uid.Name), terms) {
+			return true
+		}
+
+		if uid.Email != "" && strings.HasPrefix(strings.ToLower(uid.Email), lowerQuery) {
+			return true
+		}
+
+		if query.Fuzzy &&
+			(tools.TrigramSimilarity(uid.Name, lowerQuery) >= models.DefaultFuzzyThreshold ||
+				tools.TrigramSimilarity(uid.Email, lowerQuery) >= models.DefaultFuzzyThreshold) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// wordsHavePrefixes returns true if every term is a prefix of at least one of the words
+func wordsHavePrefixes(words, terms []string) bool {
+	for _, term := range terms {
+		found := false
+		for _, w := range words {
+			if strings.HasPrefix(w, term) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func searchSortValue(key models.GPGKey, query models.GPGKeySearchQuery) interface{} {
+	switch query.SortBy {
+	case models.SearchSortRelevance:
+		score := 0.0
+		if query.Query != "" {
+			lowerQuery := strings.ToLower(query.Query)
+			for _, uid := range key.KeyUids {
+				score = math.Max(score, tools.TrigramSimilarity(uid.Name, lowerQuery))
+				score = math.Max(score, tools.TrigramSimilarity(uid.Email, lowerQuery))
+			}
+		}
+		return score
+	case models.SearchSortName, models.SearchSortEmail:
+		value := ""
+		for i, uid := range key.KeyUids {
+			field := strings.ToLower(uid.Name)
+			if query.SortBy == models.SearchSortEmail {
+				field = strings.ToLower(uid.Email)
+			}
+			if i == 0 || field < value {
+				value = field
+			}
+		}
+		return value
+	case models.SearchSortKeyBits:
+		return key.KeyBits
+	case models.SearchSortExpiresAt:
+		if key.ExpiresAt == nil {
+			return models.NeverExpires
+		}
+		return key.ExpiresAt.UTC()
+	}
+
+	return key.FullFingerprint
+}
+
+// compareSearchMatch compares two keys by its sort value and then fingerprint
+func compareSearchMatch(valueA interface{}, fpA string, valueB interface{}, fpB string) int {
+	c := 0
+
+	switch a := valueA.(type) {
+	case string:
+		c = strings.Compare(a, valueB.(string))
+	case int:
+		b := valueB.(int)
+		c = compareOrdered(a < b, a > b)
+	case float64:
+		b := valueB.(float64)
+		c = compareOrdered(a < b, a > b)
+	case time.Time:
+		b := valueB.(time.Time)
+		c = compareOrdered(a.Before(b), a.After(b))
+	default:
+		panic(fmt.Sprintf("unsupported sort value type %T", valueA))
+	}
+
+	if c != 0 {
+		return c
+	}
+
+	return strings.Compare(fpA, fpB)
+}
+
+func compareOrdered(less, greater bool) int {
+	if less {
+		return -1
+	}
+	if greater {
+		return 1
+	}
+	return 0
+}
@@ -12,8 +12,13 @@ type DbDriver struct {
 	log    slog.Instance
 	users  []models.User
 	tokens []models.UserToken
-	keys   []models.GPGKey
-	lock   sync.RWMutex
+	// revocations are the revoked externally issued tokens
+	revocations []models.TokenRevocation
+	keys        []models.GPGKey
+	lock        sync.RWMutex
+
+	deletedKeys []models.GPGKey
+	keyHistory  []models.GPGKeyHistory
 }
 
 // MakeMemoryDBDriver creates a new database driver for rethinkdb
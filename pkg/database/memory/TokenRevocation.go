@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quan-to/chevron/pkg/models"
+)
+
+// AddTokenRevocation stores a token revocation and returns its ID
+func (h *DbDriver) AddTokenRevocation(tr models.TokenRevocation) (string, error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if tr.TokenHash == "" && tr.Username == "" {
+		return "", fmt.Errorf("empty revocation")
+	}
+
+	tr.ID = uuid.New().String()
+
+	h.revocations = append(h.revocations, tr)
+
+	return tr.ID, nil
+}
+
+// GetTokenRevocation fetches the revocation of the token with the specified hash
+func (h *DbDriver) GetTokenRevocation(tokenHash string) (*models.TokenRevocation, error) {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	for _, v := range h.revocations {
+		if v.TokenHash != "" && v.TokenHash == tokenHash {
+			tr := v
+			return &tr, nil
+		}
+	}
+
+	return nil, fmt.Errorf("not found")
+}
+
+// GetUserTokenRevocation fetches the latest revocation of all tokens of the specified user
+func (h *DbDriver) GetUserTokenRevocation(username string) (*models.TokenRevocation, error) {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	var latest *models.TokenRevocation
+	for _, v := range h.revocations {
+		if v.TokenHash == "" && strings.EqualFold(v.Username, username) && (latest == nil || v.CreatedAt.After(latest.CreatedAt)) {
+			tr := v
+			latest = &tr
+		}
+	}
+
+	if latest == nil {
+		return nil, fmt.Errorf("not found")
+	}
+
+	return latest, nil
+}
+
+// InvalidateTokenRevocations removes the revocations of tokens that had been already expired
+// Revocations of all tokens of a user are kept
+func (h *DbDriver) InvalidateTokenRevocations() (int, error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	revocations := make([]models.TokenRevocation, 0, len(h.revocations))
+	for _, v := range h.revocations {
+		if v.TokenHash == "" || time.Since(v.Expiration) < 0 {
+			revocations = append(revocations, v)
+		}
+	}
+
+	removed := len(h.revocations) - len(revocations)
+	h.revocations = revocations
+
+	return removed, nil
+}
@@ -2,6 +2,7 @@ package memory
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/quan-to/chevron/pkg/models"
@@ -50,3 +51,49 @@ func (h *DbDriver) UpdateUser(um models.User) error {
 
 	return fmt.Errorf("not found")
 }
+
+// ListUsers returns the users sorted by username in the specified page
+func (h *DbDriver) ListUsers(pageStart, pageEnd int) ([]models.User, error) {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	if pageStart < 0 {
+		pageStart = models.DefaultPageStart
+	}
+
+	if pageEnd < 0 {
+		pageEnd = models.DefaultPageEnd
+	}
+
+	users := make([]models.User, len(h.users))
+	copy(users, h.users)
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Username < users[j].Username
+	})
+
+	if pageStart >= len(users) || pageEnd <= pageStart {
+		return []models.User{}, nil
+	}
+
+	if pageEnd > len(users) {
+		pageEnd = len(users)
+	}
+
+	return users[pageStart:pageEnd], nil
+}
+
+// DeleteUser deletes the specified user by using it's ID
+func (h *DbDriver) DeleteUser(um models.User) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for i, v := range h.users {
+		if (um.ID != "" && v.ID == um.ID) || (um.ID == "" && strings.EqualFold(v.Username, um.Username)) {
+			h.users = append(h.users[:i], h.users[i+1:]...)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("not found")
+}
@@ -65,3 +65,36 @@ func (h *DbDriver) InvalidateUserTokens() (int, error) {
 
 	return len(tokensToDelete), nil
 }
+
+// FetchUserTokens returns all tokens of the specified user
+func (h *DbDriver) FetchUserTokens(username string) ([]models.UserToken, error) {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	tokens := make([]models.UserToken, 0)
+	for _, v := range h.tokens {
+		if strings.EqualFold(v.Username, username) {
+			tokens = append(tokens, v)
+		}
+	}
+
+	return tokens, nil
+}
+
+// RemoveUserTokens removes all tokens of the specified user
+func (h *DbDriver) RemoveUserTokens(username string) (int, error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	tokens := make([]models.UserToken, 0, len(h.tokens))
+	for _, v := range h.tokens {
+		if !strings.EqualFold(v.Username, username) {
+			tokens = append(tokens, v)
+		}
+	}
+
+	removed := len(h.tokens) - len(tokens)
+	h.tokens = tokens
+
+	return removed, nil
+}
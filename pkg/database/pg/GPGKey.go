@@ -19,8 +19,8 @@ func (h *PostgreSQLDBDriver) UpdateGPGKey(key models.GPGKey) (err error) {
 	return h.updateGPGKey(tx, key)
 }
 
-// DeleteGPGKey deletes the specified GPG key by using it's ID
-func (h *PostgreSQLDBDriver) DeleteGPGKey(key models.GPGKey) error {
+// DeleteGPGKey soft deletes the specified GPG key by using it's ID (or fingerprint if ID is empty)
+func (h *PostgreSQLDBDriver) DeleteGPGKey(key models.GPGKey) (err error) {
 	h.log.Debug("DeleteGPGKey(%s)", key.FullFingerprint)
 	tx, err := h.conn.Beginx()
 	if err != nil {
@@ -28,7 +28,34 @@ func (h *PostgreSQLDBDriver) DeleteGPGKey(key models.GPGKey) error {
 	}
 	defer func() { h.rollbackIfErrorCommitIfNot(err, tx) }()
 
-	return h.deleteGPGKey(tx, key)
+	err = h.deleteGPGKey(tx, key)
+	return err
+}
+
+// RestoreGPGKey restores the last deleted version of the GPG key with the specified fingerprint
+func (h *PostgreSQLDBDriver) RestoreGPGKey(key models.GPGKey) (err error) {
+	h.log.Debug("RestoreGPGKey(%s)", key.FullFingerprint)
+	tx, err := h.conn.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() { h.rollbackIfErrorCommitIfNot(err, tx) }()
+
+	err = h.restoreGPGKey(tx, key)
+	return err
+}
+
+// FetchGPGKeyHistory returns all changes made to the key with the specified fingerprint, oldest first
+func (h *PostgreSQLDBDriver) FetchGPGKeyHistory(fingerprint string) (res []models.GPGKeyHistory, err error) {
+	h.log.Debug("FetchGPGKeyHistory(%s)", fingerprint)
+	tx, err := h.conn.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { h.rollbackIfErrorCommitIfNot(err, tx) }()
+
+	res, err = h.fetchGPGKeyHistory(tx, fingerprint)
+	return res, err
 }
 
 // AddGPGKey adds a GPG Key to the database or update an existing one by fingerprint
@@ -147,6 +174,25 @@ func (h *PostgreSQLDBDriver) FindGPGKeyByName(name string, pageStart, pageEnd in
 	return convertArray(keys, tx)
 }
 
+// SearchGPGKeys searches the key store using the specified query
+func (h *PostgreSQLDBDriver) SearchGPGKeys(query models.GPGKeySearchQuery) (res *models.GPGKeySearchResult, err error) {
+	h.log.Debug("SearchGPGKeys(%s)", query.Query)
+	query = query.Normalized()
+	err = query.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := h.conn.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { h.rollbackIfErrorCommitIfNot(err, tx) }()
+
+	res, err = h.searchGPGKeys(tx, query)
+	return res, err
+}
+
 func convertArray(keys []pgGPGKey, tx *sqlx.Tx) (res []models.GPGKey, err error) {
 	for _, v := range keys {
 		k, err := v.toGPGKey(tx)
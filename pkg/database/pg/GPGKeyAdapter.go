@@ -15,6 +15,8 @@ type pgGPGKey struct {
 	FullFingerprint        string     `db:"gpg_key_full_fingerprint"`
 	Fingerprint16          string     `db:"gpg_key_fingerprint16"`
 	KeyBits                int        `db:"gpg_key_keybits"`
+	KeyAlgorithm           string     `db:"gpg_key_algorithm"`
+	ExpiresAt              *time.Time `db:"gpg_key_expires_at"`
 	ASCIIArmoredPublicKey  string     `db:"gpg_key_public_key"`
 	ASCIIArmoredPrivateKey string     `db:"gpg_key_private_key"`
 	CreatedAt              time.Time  `db:"gpg_key_created_at"`
@@ -85,6 +87,8 @@ func pgGPGKeyFromGPGKey(key models.GPGKey) *pgGPGKey {
 		FullFingerprint:        key.FullFingerprint,
 		Fingerprint16:          tools.FPto16(key.FullFingerprint),
 		KeyBits:                key.KeyBits,
+		KeyAlgorithm:           key.KeyAlgorithm,
+		ExpiresAt:              key.ExpiresAt,
 		ASCIIArmoredPublicKey:  key.AsciiArmoredPublicKey,
 		ASCIIArmoredPrivateKey: key.AsciiArmoredPrivateKey,
 
@@ -163,6 +167,8 @@ func (k *pgGPGKey) toGPGKey(tx *sqlx.Tx) (*models.GPGKey, error) {
 		Emails:                 emails,
 		KeyUids:                keyUids,
 		KeyBits:                k.KeyBits,
+		KeyAlgorithm:           k.KeyAlgorithm,
+		ExpiresAt:              k.ExpiresAt,
 		Subkeys:                subkeys,
 		AsciiArmoredPublicKey:  k.ASCIIArmoredPublicKey,
 		AsciiArmoredPrivateKey: k.ASCIIArmoredPrivateKey,
@@ -240,8 +246,8 @@ func (k *pgGPGKey) save(tx *sqlx.Tx) error {
 	if k.ID == "" { // Insert
 		k.ID = uuid.EnsureUUID(nil)
 		_, err := tx.NamedExec(`INSERT INTO 
-    		chevron_gpg_key(gpg_key_id, gpg_key_full_fingerprint, gpg_key_fingerprint16, gpg_key_keybits, gpg_key_parent, gpg_key_public_key, gpg_key_private_key) 
-    		VALUES (:gpg_key_id, :gpg_key_full_fingerprint, :gpg_key_fingerprint16, :gpg_key_keybits, :gpg_key_parent, :gpg_key_public_key, :gpg_key_private_key)`, k)
+    		chevron_gpg_key(gpg_key_id, gpg_key_full_fingerprint, gpg_key_fingerprint16, gpg_key_keybits, gpg_key_algorithm, gpg_key_expires_at, gpg_key_parent, gpg_key_public_key, gpg_key_private_key) 
+    		VALUES (:gpg_key_id, :gpg_key_full_fingerprint, :gpg_key_fingerprint16, :gpg_key_keybits, :gpg_key_algorithm, :gpg_key_expires_at, :gpg_key_parent, :gpg_key_public_key, :gpg_key_private_key)`, k)
 		if err != nil {
 			return err
 		}
@@ -259,7 +265,9 @@ func (k *pgGPGKey) save(tx *sqlx.Tx) error {
 	// Update
 	_, err := tx.NamedExec(`UPDATE chevron_gpg_key SET 
                            gpg_key_private_key = :gpg_key_private_key,
-                           gpg_key_public_key = :gpg_key_public_key 
+                           gpg_key_public_key = :gpg_key_public_key,
+                           gpg_key_algorithm = :gpg_key_algorithm,
+                           gpg_key_expires_at = :gpg_key_expires_at
                            WHERE gpg_key_id = :gpg_key_id`, k)
 	return err
 }
@@ -0,0 +1,58 @@
+package pg
+
+import (
+	"time"
+
+	"github.com/quan-to/chevron/pkg/models"
+)
+
+// gpgKeyMetadata is the algorithm and expiration of a stored key
+type gpgKeyMetadata struct {
+	id        string
+	algorithm string
+	expiresAt *time.Time
+}
+
+// backfillGPGKeyMetadata fills the algorithm and expiration of the keys stored before migration 000007 added them.
+// The values are read from the stored public keys. Keys that cannot be parsed are kept empty and retried on the next start
+func (h *PostgreSQLDBDriver) backfillGPGKeyMetadata() error {
+	rows, err := h.conn.Queryx("SELECT gpg_key_id, gpg_key_public_key FROM chevron_gpg_key WHERE gpg_key_algorithm = '' AND gpg_key_public_key <> ''")
+	if err != nil {
+		return err
+	}
+
+	var pending []gpgKeyMetadata
+
+	for rows.Next() {
+		var id, publicKey string
+		if err := rows.Scan(&id, &publicKey); err != nil {
+			_ = rows.Close()
+			return err
+		}
+
+		key, err := models.AsciiArmored2GPGKey(publicKey)
+		if err != nil || key.KeyAlgorithm == "" {
+			h.log.Warn("Cannot read the algorithm and expiration of key %s: %v", id, err)
+			continue
+		}
+
+		pending = append(pending, gpgKeyMetadata{id: id, algorithm: key.KeyAlgorithm, expiresAt: key.ExpiresAt})
+	}
+
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	for _, v := range pending {
+		_, err := h.conn.Exec("UPDATE chevron_gpg_key SET gpg_key_algorithm = $1, gpg_key_expires_at = $2 WHERE gpg_key_id = $3", v.algorithm, v.expiresAt, v.id)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(pending) > 0 {
+		h.log.Info("Filled the algorithm and expiration of %d keys", len(pending))
+	}
+
+	return nil
+}
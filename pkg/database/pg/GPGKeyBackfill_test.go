@@ -0,0 +1,65 @@
+package pg
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/quan-to/chevron/pkg/models"
+)
+
+func TestPostgreSQLDBDriver_backfillGPGKeyMetadata(t *testing.T) {
+	h := MakePostgreSQLDBDriver(nil)
+	converter := sqlmock.ValueConverterOption(customConverter{})
+	mockDB, mock, _ := sqlmock.New(converter)
+	h.conn = sqlx.NewDb(mockDB, "sqlmock")
+
+	publicKey, err := ioutil.ReadFile("../../../test/data/testkey_privateTestKey.gpg")
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	expected, err := models.AsciiArmored2GPGKey(string(publicKey))
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT gpg_key_id, gpg_key_public_key FROM chevron_gpg_key WHERE gpg_key_algorithm = ''`)).
+		WillReturnRows(sqlmock.NewRows([]string{"gpg_key_id", "gpg_key_public_key"}).
+			AddRow("abcd", string(publicKey)).
+			AddRow("invalid", "PUBKEY"))
+
+	// Only the parsed key is updated
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE chevron_gpg_key SET gpg_key_algorithm = $1, gpg_key_expires_at = $2 WHERE gpg_key_id = $3`)).
+		WithArgs(expected.KeyAlgorithm, expected.ExpiresAt, "abcd").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := h.backfillGPGKeyMetadata(); err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	if expected.KeyAlgorithm != "RSA" {
+		t.Errorf("expected algorithm RSA got %q", expected.KeyAlgorithm)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf(expectationsDidNotMet, err)
+	}
+
+	// Update errors are returned
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT gpg_key_id, gpg_key_public_key FROM chevron_gpg_key`)).
+		WillReturnRows(sqlmock.NewRows([]string{"gpg_key_id", "gpg_key_public_key"}).AddRow("abcd", string(publicKey)))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE chevron_gpg_key`)).
+		WillReturnError(fmt.Errorf("connection refused"))
+
+	if err := h.backfillGPGKeyMetadata(); err == nil {
+		t.Fatalf("expected error when the update fails")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf(expectationsDidNotMet, err)
+	}
+}
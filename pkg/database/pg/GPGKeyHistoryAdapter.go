@@ -0,0 +1,74 @@
+package pg
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/quan-to/chevron/internal/tools"
+	"github.com/quan-to/chevron/pkg/models"
+	"github.com/quan-to/chevron/pkg/uuid"
+)
+
+type pgGPGKeyHistory struct {
+	ID              string    `db:"gpg_key_history_id"`
+	KeyID           string    `db:"gpg_key_history_key"`
+	FullFingerprint string    `db:"gpg_key_history_full_fingerprint"`
+	Fingerprint16   string    `db:"gpg_key_history_fingerprint16"`
+	Action          string    `db:"gpg_key_history_action"`
+	Actor           string    `db:"gpg_key_history_actor"`
+	Changes         []byte    `db:"gpg_key_history_changes"`
+	Snapshot        []byte    `db:"gpg_key_history_snapshot"`
+	CreatedAt       time.Time `db:"gpg_key_history_created_at"`
+}
+
+func pgGPGKeyHistoryFromGPGKeyHistory(keyID string, entry models.GPGKeyHistory) (*pgGPGKeyHistory, error) {
+	changes, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot, err := json.Marshal(entry.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pgGPGKeyHistory{
+		ID:              entry.ID,
+		KeyID:           keyID,
+		FullFingerprint: entry.FullFingerprint,
+		Fingerprint16:   tools.FPto16(entry.FullFingerprint),
+		Action:          entry.Action,
+		Actor:           entry.Actor,
+		Changes:         changes,
+		Snapshot:        snapshot,
+	}, nil
+}
+
+func (k *pgGPGKeyHistory) toGPGKeyHistory() (*models.GPGKeyHistory, error) {
+	entry := &models.GPGKeyHistory{
+		ID:              k.ID,
+		FullFingerprint: k.FullFingerprint,
+		Action:          k.Action,
+		Actor:           k.Actor,
+		CreatedAt:       k.CreatedAt,
+	}
+
+	if err := json.Unmarshal(k.Changes, &entry.Changes); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(k.Snapshot, &entry.Key); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (k *pgGPGKeyHistory) save(tx *sqlx.Tx) error {
+	k.ID = uuid.EnsureUUID(nil)
+	_, err := tx.NamedExec(`INSERT INTO 
+    		chevron_gpg_key_history(gpg_key_history_id, gpg_key_history_key, gpg_key_history_full_fingerprint, gpg_key_history_fingerprint16, gpg_key_history_action, gpg_key_history_actor, gpg_key_history_changes, gpg_key_history_snapshot) 
+    		VALUES (:gpg_key_history_id, :gpg_key_history_key, :gpg_key_history_full_fingerprint, :gpg_key_history_fingerprint16, :gpg_key_history_action, :gpg_key_history_actor, :gpg_key_history_changes, :gpg_key_history_snapshot)`, k)
+	return err
+}
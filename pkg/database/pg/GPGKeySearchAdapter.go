@@ -0,0 +1,153 @@
+package pg
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/quan-to/chevron/pkg/models"
+)
+
+type pgGPGKeySearchRow struct {
+	pgGPGKey
+	SortValue interface{} `db:"search_sort"`
+}
+
+// pgSearchQuery builds the SQL of a key search with PostgreSQL positional arguments
+type pgSearchQuery struct {
+	query models.GPGKeySearchQuery
+	args  []interface{}
+}
+
+const uidSubquery = "SELECT %s FROM chevron_gpg_key_uid u WHERE u.gpg_key_uid_parent = k.gpg_key_id"
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (q *pgSearchQuery) arg(v interface{}) string {
+	q.args = append(q.args, v)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+// where returns the filter conditions of the search, excluding the cursor
+func (q *pgSearchQuery) where(now time.Time) string {
+	conds := []string{
+		"k.gpg_key_deleted_at IS NULL",
+		"k.gpg_key_parent IS NULL",
+	}
+
+	if q.query.MinKeyBits > 0 {
+		conds = append(conds, "k.gpg_key_keybits >= "+q.arg(q.query.MinKeyBits))
+	}
+
+	if q.query.MaxKeyBits > 0 {
+		conds = append(conds, "k.gpg_key_keybits <= "+q.arg(q.query.MaxKeyBits))
+	}
+
+	if q.query.Algorithm != "" {
+		conds = append(conds, "k.gpg_key_algorithm = "+q.arg(strings.ToUpper(q.query.Algorithm)))
+	}
+
+	if q.query.ExcludeExpired {
+		conds = append(conds, fmt.Sprintf("(k.gpg_key_expires_at IS NULL OR k.gpg_key_expires_at > %s)", q.arg(now.UTC())))
+	}
+
+	if q.query.ExpiresAfter != nil {
+		conds = append(conds, fmt.Sprintf("(k.gpg_key_expires_at IS NULL OR k.gpg_key_expires_at > %s)", q.arg(q.query.ExpiresAfter.UTC())))
+	}
+
+	if q.query.ExpiresBefore != nil {
+		conds = append(conds, "k.gpg_key_expires_at < "+q.arg(q.query.ExpiresBefore.UTC()))
+	}
+
+	if q.query.Query != "" {
+		conds = append(conds, q.textCondition())
+	}
+
+	return strings.Join(conds, " AND ")
+}
+
+func (q *pgSearchQuery) textCondition() string {
+	if domain := q.query.Domain(); domain != "" {
+		return fmt.Sprintf("EXISTS ("+uidSubquery+" AND lower(u.gpg_key_uid_email) LIKE %s)", "1", q.arg("%"+likeEscaper.Replace(domain)))
+	}
+
+	lowerQuery := strings.ToLower(q.query.Query)
+	var uidConds []string
+
+	if terms := q.query.Terms(); len(terms) > 0 {
+		tsQuery := strings.Join(terms, ":* & ") + ":*"
+		uidConds = append(uidConds, fmt.Sprintf("to_tsvector('simple', coalesce(u.gpg_key_uid_name, '')) @@ to_tsquery('simple', %s)", q.arg(tsQuery)))
+	}
+
+	uidConds = append(uidConds, "lower(u.gpg_key_uid_email) LIKE "+q.arg(likeEscaper.Replace(lowerQuery)+"%"))
+
+	if q.query.Fuzzy {
+		// % operator uses pg_trgm.similarity_threshold which is set on the transaction to models.DefaultFuzzyThreshold
+		fuzzyArg := q.arg(lowerQuery)
+		uidConds = append(uidConds,
+			fmt.Sprintf("lower(u.gpg_key_uid_name) %% %s", fuzzyArg),
+			fmt.Sprintf("lower(u.gpg_key_uid_email) %% %s", fuzzyArg),
+		)
+	}
+
+	conds := []string{
+		fmt.Sprintf("EXISTS ("+uidSubquery+" AND (%s))", "1", strings.Join(uidConds, " OR ")),
+	}
+
+	if fp := q.query.FingerPrint(); fp != "" {
+		conds = append(conds, "k.gpg_key_full_fingerprint LIKE "+q.arg("%"+fp))
+	}
+
+	return "(" + strings.Join(conds, " OR ") + ")"
+}
+
+// sortExpression returns the expression used to sort the keys and the type cast used for cursor values
+func (q *pgSearchQuery) sortExpression() (string, string) {
+	switch q.query.SortBy {
+	case models.SearchSortRelevance:
+		if q.query.Query == "" {
+			return "0::float8", "::float8"
+		}
+		a := q.arg(strings.ToLower(q.query.Query))
+		expr := fmt.Sprintf("greatest(similarity(lower(u.gpg_key_uid_name), %s), similarity(lower(u.gpg_key_uid_email), %s))", a, a)
+		return fmt.Sprintf("COALESCE(("+uidSubquery+"), 0)::float8", "max("+expr+")"), "::float8"
+	case models.SearchSortName:
+		return fmt.Sprintf("COALESCE(("+uidSubquery+"), '') COLLATE \"C\"", "min(lower(u.gpg_key_uid_name))"), ""
+	case models.SearchSortEmail:
+		return fmt.Sprintf("COALESCE(("+uidSubquery+"), '') COLLATE \"C\"", "min(lower(u.gpg_key_uid_email))"), ""
+	case models.SearchSortKeyBits:
+		return "k.gpg_key_keybits", "::int"
+	case models.SearchSortExpiresAt:
+		return "COALESCE(k.gpg_key_expires_at, " + q.arg(models.NeverExpires) + "::timestamp)", "::timestamp"
+	}
+
+	return "k.gpg_key_full_fingerprint COLLATE \"C\"", ""
+}
+
+// countSQL returns the query that counts all keys that matches the search
+func (q *pgSearchQuery) countSQL(now time.Time) string {
+	return "SELECT count(*) FROM chevron_gpg_key k WHERE " + q.where(now)
+}
+
+// pageSQL returns the query that fetches the page after the cursor. Fetches one extra row to detect if there is a next page
+func (q *pgSearchQuery) pageSQL(now time.Time, cursor *models.GPGKeySearchCursor) string {
+	sortExpr, cast := q.sortExpression()
+	where := q.where(now)
+
+	sql := fmt.Sprintf("SELECT * FROM (SELECT k.*, %s AS search_sort FROM chevron_gpg_key k WHERE %s) AS search", sortExpr, where)
+
+	op := ">"
+	order := "ASC"
+	if q.query.SortOrder == models.SearchOrderDesc {
+		op = "<"
+		order = "DESC"
+	}
+
+	if cursor != nil {
+		sql += fmt.Sprintf(" WHERE (search_sort, gpg_key_full_fingerprint) %s (%s%s, %s)", op, q.arg(cursor.Value), cast, q.arg(cursor.FingerPrint))
+	}
+
+	sql += fmt.Sprintf(" ORDER BY search_sort %s, gpg_key_full_fingerprint %s LIMIT %s", order, order, q.arg(q.query.Limit+1))
+
+	return sql
+}
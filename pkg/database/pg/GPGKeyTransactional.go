@@ -3,8 +3,10 @@ package pg
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/quan-to/chevron/internal/tools"
 	"github.com/quan-to/chevron/pkg/models"
 )
 
@@ -20,6 +22,11 @@ func (h *PostgreSQLDBDriver) updateGPGKey(tx *sqlx.Tx, key models.GPGKey) error
 		if err != nil {
 			return err
 		}
+		// Snapshot the current version to compute the changes
+		oldKey, err := gpgKey.toGPGKey(tx)
+		if err != nil {
+			return err
+		}
 		// Update UIDs
 		err = gpgKey.updateUIDs(tx, key.KeyUids)
 		if err != nil {
@@ -29,6 +36,8 @@ func (h *PostgreSQLDBDriver) updateGPGKey(tx *sqlx.Tx, key models.GPGKey) error
 		// Update Fields if needed
 		if gpgKey.fieldsChanged(key) {
 			gpgKey.KeyBits = key.KeyBits
+			gpgKey.KeyAlgorithm = key.KeyAlgorithm
+			gpgKey.ExpiresAt = key.ExpiresAt
 			gpgKey.ASCIIArmoredPublicKey = key.AsciiArmoredPublicKey
 			gpgKey.ASCIIArmoredPrivateKey = key.AsciiArmoredPrivateKey
 			err := gpgKey.save(tx)
@@ -36,19 +45,171 @@ func (h *PostgreSQLDBDriver) updateGPGKey(tx *sqlx.Tx, key models.GPGKey) error
 				return err
 			}
 		}
+
+		changes := models.DiffGPGKeys(*oldKey, key)
+		if len(changes) > 0 {
+			return h.addGPGKeyHistory(tx, gpgKey.ID, key, models.KeyHistoryUpdated, changes)
+		}
 		return nil
 	}
 
 	gpgKey = pgGPGKeyFromGPGKey(key)
-	return gpgKey.save(tx)
+	err = gpgKey.save(tx)
+	if err != nil {
+		return err
+	}
+
+	return h.addGPGKeyHistory(tx, gpgKey.ID, key, models.KeyHistoryAdded, nil)
 }
 
 func (h *PostgreSQLDBDriver) deleteGPGKey(tx *sqlx.Tx, key models.GPGKey) error {
-	pgKey := pgGPGKeyFromGPGKey(key)
+	var pgKey *pgGPGKey
+	var err error
+
+	if key.ID != "" {
+		pgKey, err = h.fetchGPGKeyByID(tx, key.ID)
+	} else {
+		pgKey, err = h.fetchGPGKeyByFingerprint(tx, key.FullFingerprint)
+	}
 
-	_, err := tx.NamedExec(`DELETE FROM chevron_gpg_key WHERE gpg_key_id = :gpg_key_id`, &pgKey)
+	if err != nil {
+		return err
+	}
 
-	return err
+	snapshot, err := pgKey.toGPGKey(tx)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.NamedExec(`UPDATE chevron_gpg_key SET gpg_key_deleted_at = now() WHERE gpg_key_id = :gpg_key_id`, pgKey)
+	if err != nil {
+		return err
+	}
+
+	snapshot.Actor = key.Actor
+	return h.addGPGKeyHistory(tx, pgKey.ID, *snapshot, models.KeyHistoryDeleted, nil)
+}
+
+func (h *PostgreSQLDBDriver) restoreGPGKey(tx *sqlx.Tx, key models.GPGKey) error {
+	existing, err := h.fetchGPGKeyByFingerprint(tx, key.FullFingerprint)
+	if errorIsNotNilAndNotNotFound(err) {
+		return err
+	}
+
+	if existing != nil {
+		return fmt.Errorf("already exists")
+	}
+
+	pgKey, err := h.fetchDeletedGPGKeyByFingerprint(tx, key.FullFingerprint)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.NamedExec(`UPDATE chevron_gpg_key SET gpg_key_deleted_at = NULL, gpg_key_updated_at = now() WHERE gpg_key_id = :gpg_key_id`, pgKey)
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := pgKey.toGPGKey(tx)
+	if err != nil {
+		return err
+	}
+
+	snapshot.Actor = key.Actor
+	return h.addGPGKeyHistory(tx, pgKey.ID, *snapshot, models.KeyHistoryRestored, nil)
+}
+
+func (h *PostgreSQLDBDriver) addGPGKeyHistory(tx *sqlx.Tx, keyID string, key models.GPGKey, action string, changes []string) error {
+	entry, err := pgGPGKeyHistoryFromGPGKeyHistory(keyID, models.MakeGPGKeyHistory(key, action, changes))
+	if err != nil {
+		return err
+	}
+
+	return entry.save(tx)
+}
+
+func (h *PostgreSQLDBDriver) fetchGPGKeyHistory(tx *sqlx.Tx, fingerprint string) ([]models.GPGKeyHistory, error) {
+	if len(fingerprint) < 16 {
+		return nil, fmt.Errorf("expected fingerprint length >= 16")
+	}
+
+	var rows []pgGPGKeyHistory
+	err := tx.Select(&rows, "SELECT * FROM chevron_gpg_key_history WHERE gpg_key_history_fingerprint16 = $1 ORDER BY gpg_key_history_created_at", tools.FPto16(fingerprint))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("not found")
+	}
+
+	res := make([]models.GPGKeyHistory, len(rows))
+	for i, v := range rows {
+		entry, err := v.toGPGKeyHistory()
+		if err != nil {
+			return nil, err
+		}
+		res[i] = *entry
+	}
+
+	return res, nil
+}
+
+func (h *PostgreSQLDBDriver) searchGPGKeys(tx *sqlx.Tx, query models.GPGKeySearchQuery) (*models.GPGKeySearchResult, error) {
+	var cursor *models.GPGKeySearchCursor
+	var err error
+
+	if query.Cursor != "" {
+		cursor, err = query.DecodeCursor()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if query.Fuzzy && query.Query != "" {
+		_, err = tx.Exec("SELECT set_config('pg_trgm.similarity_threshold', $1, true)", fmt.Sprintf("%g", models.DefaultFuzzyThreshold))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	res := &models.GPGKeySearchResult{
+		Keys: make([]models.GPGKey, 0),
+	}
+
+	countQuery := &pgSearchQuery{query: query}
+	err = tx.Get(&res.Total, countQuery.countSQL(now), countQuery.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []pgGPGKeySearchRow
+	pageQuery := &pgSearchQuery{query: query}
+	err = tx.Select(&rows, pageQuery.pageSQL(now, cursor), pageQuery.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) > query.Limit {
+		rows = rows[:query.Limit]
+		last := rows[len(rows)-1]
+		res.NextCursor = models.GPGKeySearchCursor{
+			SortBy:      query.SortBy,
+			Value:       models.FormatSearchSortValue(last.SortValue),
+			FingerPrint: last.FullFingerprint,
+		}.Encode()
+	}
+
+	for _, v := range rows {
+		k, err := v.toGPGKey(tx)
+		if err != nil {
+			return nil, err
+		}
+		res.Keys = append(res.Keys, *k)
+	}
+
+	return res, nil
 }
 
 // Transactional Methods
@@ -71,6 +232,11 @@ func (h *PostgreSQLDBDriver) addGPGKey(tx *sqlx.Tx, key models.GPGKey) (string,
 		return "", false, err
 	}
 
+	err = h.addGPGKeyHistory(tx, pgKey.ID, key, models.KeyHistoryAdded, nil)
+	if err != nil {
+		return "", false, err
+	}
+
 	return pgKey.ID, true, nil
 }
 
@@ -89,7 +255,41 @@ func (h *PostgreSQLDBDriver) fetchGPGKeyByFingerprint(tx *sqlx.Tx, fingerprint s
 
 	key := &pgGPGKey{}
 
-	err := tx.Get(key, "SELECT * FROM chevron_gpg_key WHERE gpg_key_fingerprint16 = $1 LIMIT 1", fingerprint)
+	err := tx.Get(key, "SELECT * FROM chevron_gpg_key WHERE gpg_key_fingerprint16 = $1 AND gpg_key_deleted_at IS NULL LIMIT 1", fingerprint)
+	if err != nil && !strings.EqualFold("sql: no rows in result set", err.Error()) {
+		return nil, err
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("not found")
+	}
+
+	return key, nil
+}
+
+func (h *PostgreSQLDBDriver) fetchDeletedGPGKeyByFingerprint(tx *sqlx.Tx, fingerprint string) (*pgGPGKey, error) {
+	if len(fingerprint) < 16 {
+		return nil, fmt.Errorf("expected fingerprint length >= 16")
+	}
+
+	key := &pgGPGKey{}
+
+	err := tx.Get(key, "SELECT * FROM chevron_gpg_key WHERE gpg_key_fingerprint16 = $1 AND gpg_key_deleted_at IS NOT NULL ORDER BY gpg_key_deleted_at DESC LIMIT 1", tools.FPto16(fingerprint))
+	if err != nil && !strings.EqualFold("sql: no rows in result set", err.Error()) {
+		return nil, err
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("not found")
+	}
+
+	return key, nil
+}
+
+func (h *PostgreSQLDBDriver) fetchGPGKeyByID(tx *sqlx.Tx, id string) (*pgGPGKey, error) {
+	key := &pgGPGKey{}
+
+	err := tx.Get(key, "SELECT * FROM chevron_gpg_key WHERE gpg_key_id = $1 AND gpg_key_deleted_at IS NULL LIMIT 1", id)
 	if err != nil && !strings.EqualFold("sql: no rows in result set", err.Error()) {
 		return nil, err
 	}
@@ -122,7 +322,7 @@ func (h *PostgreSQLDBDriver) findGPGKeyByFingerPrint(tx *sqlx.Tx, fingerPrint st
 
 	fingerPrint = strings.ToUpper("%" + fingerPrint)
 
-	err = tx.Select(&res, "SELECT * FROM chevron_gpg_key WHERE gpg_key_fingerprint16 LIKE $1 LIMIT $2 OFFSET $3", fingerPrint, numItems, pageStart)
+	err = tx.Select(&res, "SELECT * FROM chevron_gpg_key WHERE gpg_key_fingerprint16 LIKE $1 AND gpg_key_deleted_at IS NULL LIMIT $2 OFFSET $3", fingerPrint, numItems, pageStart)
 
 	if err != nil {
 		return nil, err
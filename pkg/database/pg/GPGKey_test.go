@@ -3,6 +3,7 @@ package pg
 import (
 	"database/sql"
 	"database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"regexp"
 	"strings"
@@ -15,6 +16,7 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/kylelemons/godebug/pretty"
 	"github.com/quan-to/chevron/internal/tools"
+	"github.com/quan-to/chevron/pkg/models"
 	"github.com/quan-to/chevron/pkg/uuid"
 	"github.com/quan-to/slog"
 )
@@ -44,6 +46,7 @@ func expectToUpdate(mock sqlmock.Sqlmock, withSubkeys bool) {
 		"gpg_key_full_fingerprint",
 		"gpg_key_fingerprint16",
 		"gpg_key_keybits",
+		"gpg_key_algorithm",
 		"gpg_key_public_key",
 		"gpg_key_private_key",
 		"gpg_key_created_at",
@@ -52,13 +55,14 @@ func expectToUpdate(mock sqlmock.Sqlmock, withSubkeys bool) {
 		"gpg_key_parent",
 	})
 
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_gpg_key WHERE gpg_key_fingerprint16 = $1 LIMIT 1`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_gpg_key WHERE gpg_key_fingerprint16 = $1 AND gpg_key_deleted_at IS NULL LIMIT 1`)).
 		WithArgs(tools.FPto16(testmodels.GpgKey.FullFingerprint)).
 		WillReturnRows(testRow.AddRow(
 			testmodels.GpgKey.ID,
 			testmodels.GpgKey.FullFingerprint,
 			tools.FPto16(testmodels.GpgKey.FullFingerprint),
 			testmodels.GpgKey.KeyBits,
+			testmodels.GpgKey.KeyAlgorithm,
 			testmodels.GpgKey.AsciiArmoredPublicKey,
 			testmodels.GpgKey.AsciiArmoredPrivateKey,
 			time.Now(),
@@ -67,13 +71,14 @@ func expectToUpdate(mock sqlmock.Sqlmock, withSubkeys bool) {
 			(*string)(nil),
 		))
 	if withSubkeys {
-		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_gpg_key WHERE gpg_key_fingerprint16 = $1 LIMIT 1`)).
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_gpg_key WHERE gpg_key_fingerprint16 = $1 AND gpg_key_deleted_at IS NULL LIMIT 1`)).
 			WithArgs(tools.FPto16(testmodels.GpgKey.FullFingerprint)).
 			WillReturnRows(testRow.AddRow(
 				testmodels.GpgKey.ID,
 				testmodels.GpgKey.FullFingerprint,
 				tools.FPto16(testmodels.GpgKey.FullFingerprint),
 				testmodels.GpgKey.KeyBits,
+				testmodels.GpgKey.KeyAlgorithm,
 				testmodels.GpgKey.AsciiArmoredPublicKey,
 				testmodels.GpgKey.AsciiArmoredPrivateKey,
 				time.Now(),
@@ -86,6 +91,10 @@ func expectToUpdate(mock sqlmock.Sqlmock, withSubkeys bool) {
 	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_gpg_key_uid WHERE gpg_key_uid_parent = $1`)).
 		WithArgs(testmodels.GpgKey.ID).
 		WillReturnRows(sqlmock.NewRows(nil))
+	// Load Subkeys
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_gpg_key WHERE gpg_key_parent = $1`)).
+		WithArgs(testmodels.GpgKey.ID).
+		WillReturnRows(sqlmock.NewRows(nil))
 
 	// Insert UIDs
 	for _, uid := range testmodels.GpgKey.KeyUids {
@@ -94,12 +103,65 @@ func expectToUpdate(mock sqlmock.Sqlmock, withSubkeys bool) {
 			WillReturnResult(sqlmock.NewResult(0, 1))
 	}
 	// Update Key
-	mock.ExpectExec(regexp.QuoteMeta(`UPDATE chevron_gpg_key SET gpg_key_private_key = ?, gpg_key_public_key = ? WHERE gpg_key_id = ?`)).
-		WithArgs(testmodels.GpgKey.AsciiArmoredPrivateKey, testmodels.GpgKey.AsciiArmoredPublicKey, testmodels.GpgKey.ID).
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE chevron_gpg_key SET gpg_key_private_key = ?, gpg_key_public_key = ?, gpg_key_algorithm = ?, gpg_key_expires_at = ? WHERE gpg_key_id = ?`)).
+		WithArgs(testmodels.GpgKey.AsciiArmoredPrivateKey, testmodels.GpgKey.AsciiArmoredPublicKey, testmodels.GpgKey.KeyAlgorithm, testmodels.GpgKey.ExpiresAt, testmodels.GpgKey.ID).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectHistoryInsert(mock, models.KeyHistoryUpdated)
 	mock.ExpectCommit()
 }
 
+func expectHistoryInsert(mock sqlmock.Sqlmock, action string) {
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO chevron_gpg_key_history(gpg_key_history_id, gpg_key_history_key, gpg_key_history_full_fingerprint, gpg_key_history_fingerprint16, gpg_key_history_action, gpg_key_history_actor, gpg_key_history_changes, gpg_key_history_snapshot) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)).
+		WithArgs(
+			sqlmock.AnyArg(),
+			sqlmock.AnyArg(),
+			testmodels.GpgKey.FullFingerprint,
+			tools.FPto16(testmodels.GpgKey.FullFingerprint),
+			action,
+			sqlmock.AnyArg(),
+			sqlmock.AnyArg(),
+			sqlmock.AnyArg(),
+		).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func gpgKeyRow() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{
+		"gpg_key_id",
+		"gpg_key_full_fingerprint",
+		"gpg_key_fingerprint16",
+		"gpg_key_keybits",
+		"gpg_key_algorithm",
+		"gpg_key_public_key",
+		"gpg_key_private_key",
+		"gpg_key_created_at",
+		"gpg_key_updated_at",
+		"gpg_key_deleted_at",
+		"gpg_key_parent",
+	}).AddRow(
+		testmodels.GpgKey.ID,
+		testmodels.GpgKey.FullFingerprint,
+		tools.FPto16(testmodels.GpgKey.FullFingerprint),
+		testmodels.GpgKey.KeyBits,
+		testmodels.GpgKey.KeyAlgorithm,
+		testmodels.GpgKey.AsciiArmoredPublicKey,
+		testmodels.GpgKey.AsciiArmoredPrivateKey,
+		time.Now(),
+		time.Now(),
+		time.Time{},
+		(*string)(nil),
+	)
+}
+
+func expectRelationsLoad(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_gpg_key_uid WHERE gpg_key_uid_parent = $1`)).
+		WithArgs(testmodels.GpgKey.ID).
+		WillReturnRows(sqlmock.NewRows(nil))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_gpg_key WHERE gpg_key_parent = $1`)).
+		WithArgs(testmodels.GpgKey.ID).
+		WillReturnRows(sqlmock.NewRows(nil))
+}
+
 func newMock() (*sql.DB, sqlmock.Sqlmock) {
 	converter := sqlmock.ValueConverterOption(customConverter{})
 	mockDB, mock, _ := sqlmock.New(converter)
@@ -114,12 +176,12 @@ func prepareAddGPGKey(t *testing.T) (*PostgreSQLDBDriver, sqlmock.Sqlmock) {
 
 	mock.ExpectBegin()
 	// Check existance
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_gpg_key WHERE gpg_key_fingerprint16 = $1 LIMIT 1`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_gpg_key WHERE gpg_key_fingerprint16 = $1 AND gpg_key_deleted_at IS NULL LIMIT 1`)).
 		WithArgs(tools.FPto16(testmodels.GpgKey.FullFingerprint)).
 		WillReturnError(fmt.Errorf("sql: no rows in result set"))
 	// Insert Key
-	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO chevron_gpg_key(gpg_key_id, gpg_key_full_fingerprint, gpg_key_fingerprint16, gpg_key_keybits, gpg_key_parent, gpg_key_public_key, gpg_key_private_key) VALUES (?, ?, ?, ?, ?, ?, ?)`)).
-		WithArgs(sqlmock.AnyArg(), testmodels.GpgKey.FullFingerprint, tools.FPto16(testmodels.GpgKey.FullFingerprint), testmodels.GpgKey.KeyBits, (*string)(nil), testmodels.GpgKey.AsciiArmoredPublicKey, testmodels.GpgKey.AsciiArmoredPrivateKey).
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO chevron_gpg_key(gpg_key_id, gpg_key_full_fingerprint, gpg_key_fingerprint16, gpg_key_keybits, gpg_key_algorithm, gpg_key_expires_at, gpg_key_parent, gpg_key_public_key, gpg_key_private_key) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)).
+		WithArgs(sqlmock.AnyArg(), testmodels.GpgKey.FullFingerprint, tools.FPto16(testmodels.GpgKey.FullFingerprint), testmodels.GpgKey.KeyBits, testmodels.GpgKey.KeyAlgorithm, testmodels.GpgKey.ExpiresAt, (*string)(nil), testmodels.GpgKey.AsciiArmoredPublicKey, testmodels.GpgKey.AsciiArmoredPrivateKey).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	// Insert UIDs
@@ -128,6 +190,7 @@ func prepareAddGPGKey(t *testing.T) (*PostgreSQLDBDriver, sqlmock.Sqlmock) {
 			WithArgs(sqlmock.AnyArg(), uid.Name, uid.Email, uid.Description, sqlmock.AnyArg()).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 	}
+	expectHistoryInsert(mock, models.KeyHistoryAdded)
 	mock.ExpectCommit()
 
 	return h, mock
@@ -182,9 +245,14 @@ func TestPostgreSQLDBDriver_DeleteGPGKey(t *testing.T) {
 	h.conn = sqlx.NewDb(mockDB, "sqlmock")
 
 	mock.ExpectBegin()
-	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM chevron_gpg_key WHERE gpg_key_id = ?`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_gpg_key WHERE gpg_key_id = $1 AND gpg_key_deleted_at IS NULL LIMIT 1`)).
+		WithArgs(testmodels.GpgKey.ID).
+		WillReturnRows(gpgKeyRow())
+	expectRelationsLoad(mock)
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE chevron_gpg_key SET gpg_key_deleted_at = now() WHERE gpg_key_id = ?`)).
 		WithArgs(testmodels.GpgKey.ID).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectHistoryInsert(mock, models.KeyHistoryDeleted)
 	mock.ExpectCommit()
 
 	err := h.DeleteGPGKey(testmodels.GpgKey)
@@ -194,6 +262,144 @@ func TestPostgreSQLDBDriver_DeleteGPGKey(t *testing.T) {
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Fatalf(expectationsDidNotMet, err)
 	}
+
+	// Test not found
+	mockDB, mock, _ = sqlmock.New(converter)
+	h.conn = sqlx.NewDb(mockDB, "sqlmock")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_gpg_key WHERE gpg_key_id = $1 AND gpg_key_deleted_at IS NULL LIMIT 1`)).
+		WithArgs(testmodels.GpgKey.ID).
+		WillReturnRows(sqlmock.NewRows(nil))
+	mock.ExpectRollback()
+
+	err = h.DeleteGPGKey(testmodels.GpgKey)
+	if err == nil || !strings.EqualFold(err.Error(), "not found") {
+		t.Fatalf("expected error %q got %v", "not found", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf(expectationsDidNotMet, err)
+	}
+}
+
+func TestPostgreSQLDBDriver_RestoreGPGKey(t *testing.T) {
+	h := MakePostgreSQLDBDriver(nil)
+	converter := sqlmock.ValueConverterOption(customConverter{})
+
+	mockDB, mock, _ := sqlmock.New(converter)
+	h.conn = sqlx.NewDb(mockDB, "sqlmock")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_gpg_key WHERE gpg_key_fingerprint16 = $1 AND gpg_key_deleted_at IS NULL LIMIT 1`)).
+		WithArgs(tools.FPto16(testmodels.GpgKey.FullFingerprint)).
+		WillReturnRows(sqlmock.NewRows(nil))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_gpg_key WHERE gpg_key_fingerprint16 = $1 AND gpg_key_deleted_at IS NOT NULL ORDER BY gpg_key_deleted_at DESC LIMIT 1`)).
+		WithArgs(tools.FPto16(testmodels.GpgKey.FullFingerprint)).
+		WillReturnRows(gpgKeyRow())
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE chevron_gpg_key SET gpg_key_deleted_at = NULL, gpg_key_updated_at = now() WHERE gpg_key_id = ?`)).
+		WithArgs(testmodels.GpgKey.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectRelationsLoad(mock)
+	expectHistoryInsert(mock, models.KeyHistoryRestored)
+	mock.ExpectCommit()
+
+	err := h.RestoreGPGKey(testmodels.GpgKey)
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf(expectationsDidNotMet, err)
+	}
+
+	// Test key not deleted
+	mockDB, mock, _ = sqlmock.New(converter)
+	h.conn = sqlx.NewDb(mockDB, "sqlmock")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_gpg_key WHERE gpg_key_fingerprint16 = $1 AND gpg_key_deleted_at IS NULL LIMIT 1`)).
+		WithArgs(tools.FPto16(testmodels.GpgKey.FullFingerprint)).
+		WillReturnRows(gpgKeyRow())
+	mock.ExpectRollback()
+
+	err = h.RestoreGPGKey(testmodels.GpgKey)
+	if err == nil || !strings.EqualFold(err.Error(), "already exists") {
+		t.Fatalf("expected error %q got %v", "already exists", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf(expectationsDidNotMet, err)
+	}
+}
+
+func TestPostgreSQLDBDriver_FetchGPGKeyHistory(t *testing.T) {
+	h := MakePostgreSQLDBDriver(nil)
+	converter := sqlmock.ValueConverterOption(customConverter{})
+
+	mockDB, mock, _ := sqlmock.New(converter)
+	h.conn = sqlx.NewDb(mockDB, "sqlmock")
+
+	snapshot := models.MakeGPGKeyHistory(testmodels.GpgKey, models.KeyHistoryAdded, nil).Key
+	snapshotJSON, _ := json.Marshal(snapshot)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_gpg_key_history WHERE gpg_key_history_fingerprint16 = $1 ORDER BY gpg_key_history_created_at`)).
+		WithArgs(tools.FPto16(testmodels.GpgKey.FullFingerprint)).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"gpg_key_history_id",
+			"gpg_key_history_key",
+			"gpg_key_history_full_fingerprint",
+			"gpg_key_history_fingerprint16",
+			"gpg_key_history_action",
+			"gpg_key_history_actor",
+			"gpg_key_history_changes",
+			"gpg_key_history_snapshot",
+			"gpg_key_history_created_at",
+		}).AddRow(
+			"h1",
+			testmodels.GpgKey.ID,
+			testmodels.GpgKey.FullFingerprint,
+			tools.FPto16(testmodels.GpgKey.FullFingerprint),
+			models.KeyHistoryAdded,
+			"127.0.0.1",
+			[]byte("[]"),
+			snapshotJSON,
+			testmodels.Time,
+		).AddRow(
+			"h2",
+			testmodels.GpgKey.ID,
+			testmodels.GpgKey.FullFingerprint,
+			tools.FPto16(testmodels.GpgKey.FullFingerprint),
+			models.KeyHistoryUpdated,
+			"127.0.0.1",
+			[]byte(`["public key changed"]`),
+			snapshotJSON,
+			testmodels.Time,
+		))
+	mock.ExpectCommit()
+
+	history, err := h.FetchGPGKeyHistory(testmodels.GpgKey.FullFingerprint)
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	if len(history) != 2 {
+		t.Fatalf("expected 2 history entries got %d", len(history))
+	}
+
+	if history[1].Action != models.KeyHistoryUpdated || len(history[1].Changes) != 1 {
+		t.Fatalf("unexpected history entry: %+v", history[1])
+	}
+
+	if diff := pretty.Compare(snapshot, history[0].Key); diff != "" {
+		t.Errorf("Expected snapshot to be the same. (-got +want)\\n%s", diff)
+	}
+
+	if history[0].Key.AsciiArmoredPrivateKey != "" {
+		t.Fatalf("expected private key to not be in history")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf(expectationsDidNotMet, err)
+	}
 }
 
 func TestPostgreSQLDBDriver_FetchGPGKeyByFingerprint(t *testing.T) {
@@ -210,6 +416,7 @@ func TestPostgreSQLDBDriver_FetchGPGKeyByFingerprint(t *testing.T) {
 		"gpg_key_full_fingerprint",
 		"gpg_key_fingerprint16",
 		"gpg_key_keybits",
+		"gpg_key_algorithm",
 		"gpg_key_public_key",
 		"gpg_key_private_key",
 		"gpg_key_created_at",
@@ -221,6 +428,7 @@ func TestPostgreSQLDBDriver_FetchGPGKeyByFingerprint(t *testing.T) {
 		testmodels.GpgKey.FullFingerprint,
 		tools.FPto16(testmodels.GpgKey.FullFingerprint),
 		testmodels.GpgKey.KeyBits,
+		testmodels.GpgKey.KeyAlgorithm,
 		testmodels.GpgKey.AsciiArmoredPublicKey,
 		testmodels.GpgKey.AsciiArmoredPrivateKey,
 		time.Now(),
@@ -234,6 +442,7 @@ func TestPostgreSQLDBDriver_FetchGPGKeyByFingerprint(t *testing.T) {
 		"gpg_key_full_fingerprint",
 		"gpg_key_fingerprint16",
 		"gpg_key_keybits",
+		"gpg_key_algorithm",
 		"gpg_key_public_key",
 		"gpg_key_private_key",
 		"gpg_key_created_at",
@@ -248,6 +457,7 @@ func TestPostgreSQLDBDriver_FetchGPGKeyByFingerprint(t *testing.T) {
 			v,
 			v,
 			testmodels.GpgKey.KeyBits,
+			testmodels.GpgKey.KeyAlgorithm,
 			testmodels.GpgKey.AsciiArmoredPublicKey,
 			testmodels.GpgKey.AsciiArmoredPrivateKey,
 			time.Now(),
@@ -281,7 +491,7 @@ func TestPostgreSQLDBDriver_FetchGPGKeyByFingerprint(t *testing.T) {
 		)
 	}
 
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_gpg_key WHERE gpg_key_fingerprint16 = $1 LIMIT 1`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_gpg_key WHERE gpg_key_fingerprint16 = $1 AND gpg_key_deleted_at IS NULL LIMIT 1`)).
 		WithArgs(tools.FPto16(testmodels.GpgKey.FullFingerprint)).
 		WillReturnRows(expectedGPGKeyRow)
 
@@ -375,6 +585,7 @@ func TestPostgreSQLDBDriver_FindGPGKeyByFingerPrint(t *testing.T) {
 		"gpg_key_full_fingerprint",
 		"gpg_key_fingerprint16",
 		"gpg_key_keybits",
+		"gpg_key_algorithm",
 		"gpg_key_public_key",
 		"gpg_key_private_key",
 		"gpg_key_created_at",
@@ -386,6 +597,7 @@ func TestPostgreSQLDBDriver_FindGPGKeyByFingerPrint(t *testing.T) {
 		testmodels.GpgKey.FullFingerprint,
 		tools.FPto16(testmodels.GpgKey.FullFingerprint),
 		testmodels.GpgKey.KeyBits,
+		testmodels.GpgKey.KeyAlgorithm,
 		testmodels.GpgKey.AsciiArmoredPublicKey,
 		testmodels.GpgKey.AsciiArmoredPrivateKey,
 		time.Now(),
@@ -397,6 +609,7 @@ func TestPostgreSQLDBDriver_FindGPGKeyByFingerPrint(t *testing.T) {
 		testmodels.GpgKey.FullFingerprint,
 		tools.FPto16(testmodels.GpgKey.FullFingerprint),
 		testmodels.GpgKey.KeyBits,
+		testmodels.GpgKey.KeyAlgorithm,
 		testmodels.GpgKey.AsciiArmoredPublicKey,
 		testmodels.GpgKey.AsciiArmoredPrivateKey,
 		time.Now(),
@@ -405,7 +618,7 @@ func TestPostgreSQLDBDriver_FindGPGKeyByFingerPrint(t *testing.T) {
 		(*string)(nil),
 	)
 
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_gpg_key WHERE gpg_key_fingerprint16 LIKE $1 LIMIT $2 OFFSET $3`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_gpg_key WHERE gpg_key_fingerprint16 LIKE $1 AND gpg_key_deleted_at IS NULL LIMIT $2 OFFSET $3`)).
 		WithArgs(
 			"%"+tools.FPto16(testmodels.GpgKey.FullFingerprint),
 			10,
@@ -531,3 +744,100 @@ func TestPostgreSQLDBDriver_UpdateGPGKey(t *testing.T) {
 		t.Fatalf(expectationsDidNotMet, err)
 	}
 }
+
+func TestPostgreSQLDBDriver_SearchGPGKeys(t *testing.T) {
+	h := MakePostgreSQLDBDriver(nil)
+	mockDB, mock := newMock()
+	h.conn = sqlx.NewDb(mockDB, "sqlmock")
+
+	const domainCondition = `EXISTS (SELECT 1 FROM chevron_gpg_key_uid u WHERE u.gpg_key_uid_parent = k.gpg_key_id AND lower(u.gpg_key_uid_email) LIKE $1)`
+	secondFingerprint := "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF"
+
+	// region Test domain search with next page
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM chevron_gpg_key k WHERE k.gpg_key_deleted_at IS NULL AND k.gpg_key_parent IS NULL AND ` + domainCondition)).
+		WithArgs(`%@a.com`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM (SELECT k.*, COALESCE((SELECT min(lower(u.gpg_key_uid_name)) FROM chevron_gpg_key_uid u WHERE u.gpg_key_uid_parent = k.gpg_key_id), '') COLLATE "C" AS search_sort FROM chevron_gpg_key k WHERE k.gpg_key_deleted_at IS NULL AND k.gpg_key_parent IS NULL AND `+domainCondition+`) AS search ORDER BY search_sort ASC, gpg_key_full_fingerprint ASC LIMIT $2`)).
+		WithArgs(`%@a.com`, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"gpg_key_id", "gpg_key_full_fingerprint", "gpg_key_keybits", "search_sort"}).
+			AddRow(testmodels.GpgKey.ID, testmodels.GpgKey.FullFingerprint, testmodels.GpgKey.KeyBits, "abce").
+			AddRow("efgh", secondFingerprint, testmodels.GpgKey.KeyBits, "b asd"))
+	expectRelationsLoad(mock)
+	mock.ExpectCommit()
+
+	res, err := h.SearchGPGKeys(models.GPGKeySearchQuery{
+		Query:  "@A.com",
+		SortBy: models.SearchSortName,
+		Limit:  1,
+	})
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	if res.Total != 2 {
+		t.Fatalf("expected total 2 got %d", res.Total)
+	}
+
+	if len(res.Keys) != 1 || res.Keys[0].FullFingerprint != testmodels.GpgKey.FullFingerprint {
+		t.Fatalf("expected only key %s got %+v", testmodels.GpgKey.FullFingerprint, res.Keys)
+	}
+
+	cursor, err := models.GPGKeySearchQuery{SortBy: models.SearchSortName, Cursor: res.NextCursor}.DecodeCursor()
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	if cursor.Value != "abce" || cursor.FingerPrint != testmodels.GpgKey.FullFingerprint {
+		t.Fatalf("unexpected cursor %+v", cursor)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf(expectationsDidNotMet, err)
+	}
+	// endregion
+	// region Test fuzzy search after cursor
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT set_config('pg_trgm.similarity_threshold', $1, true)`)).
+		WithArgs("0.3").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM chevron_gpg_key k WHERE k.gpg_key_deleted_at IS NULL AND k.gpg_key_parent IS NULL AND k.gpg_key_keybits >= $1 AND (EXISTS (SELECT 1 FROM chevron_gpg_key_uid u WHERE u.gpg_key_uid_parent = k.gpg_key_id AND (to_tsvector('simple', coalesce(u.gpg_key_uid_name, '')) @@ to_tsquery('simple', $2) OR lower(u.gpg_key_uid_email) LIKE $3 OR lower(u.gpg_key_uid_name) % $4 OR lower(u.gpg_key_uid_email) % $4)))`)).
+		WithArgs(1024, "abc:*", "abc%", "abc").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta(`AS search WHERE (search_sort, gpg_key_full_fingerprint) < ($6::float8, $7) ORDER BY search_sort DESC, gpg_key_full_fingerprint DESC LIMIT $8`)).
+		WithArgs("abc", 1024, "abc:*", "abc%", "abc", "0.5", secondFingerprint, models.DefaultSearchLimit+1).
+		WillReturnRows(sqlmock.NewRows([]string{"gpg_key_id", "gpg_key_full_fingerprint", "gpg_key_keybits", "search_sort"}))
+	mock.ExpectCommit()
+
+	res, err = h.SearchGPGKeys(models.GPGKeySearchQuery{
+		Query:      "ABC",
+		Fuzzy:      true,
+		MinKeyBits: 1024,
+		Cursor: models.GPGKeySearchCursor{
+			SortBy:      models.SearchSortRelevance,
+			Value:       "0.5",
+			FingerPrint: secondFingerprint,
+		}.Encode(),
+	})
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	if res.Total != 1 || len(res.Keys) != 0 || res.NextCursor != "" {
+		t.Fatalf("expected empty last page with total 1 got %+v", res)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf(expectationsDidNotMet, err)
+	}
+	// endregion
+	// region Test invalid cursor
+	_, err = h.SearchGPGKeys(models.GPGKeySearchQuery{
+		Query:  "abc",
+		Cursor: models.GPGKeySearchCursor{SortBy: models.SearchSortKeyBits, Value: "1", FingerPrint: "AB"}.Encode(),
+	})
+	if err == nil {
+		t.Fatalf("expected error for cursor of another sort got nil")
+	}
+	// endregion
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"runtime/debug"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-migrate/migrate/v4"
@@ -24,6 +25,10 @@ type PostgreSQLDBDriver struct {
 	// Migrate
 	gpgKeysRows *sqlx.Rows
 	usersRows   *sqlx.Rows
+
+	// Token Reaper
+	reaperStop chan struct{}
+	reaperLock sync.Mutex
 }
 
 // MakeRethinkDBDriver creates a new database driver for rethinkdb
@@ -68,6 +73,10 @@ func (h *PostgreSQLDBDriver) Connect(connectionString string) error {
 	}
 	h.conn = db
 	h.log.Info("Connected!")
+
+	if err := h.backfillGPGKeyMetadata(); err != nil {
+		h.log.Error("Error filling the algorithm and expiration of the stored keys: %s", err)
+	}
 	return nil
 }
 
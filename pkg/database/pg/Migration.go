@@ -5,14 +5,14 @@ import (
 )
 
 func (h *PostgreSQLDBDriver) InitCursor() error {
-	gpgRows, err := h.conn.Queryx("SELECT * FROM chevron_gpg_key")
+	gpgRows, err := h.conn.Queryx("SELECT * FROM chevron_gpg_key WHERE gpg_key_deleted_at IS NULL")
 	if err != nil {
 		return err
 	}
 
 	h.gpgKeysRows = gpgRows
 
-	userRows, err := h.conn.Queryx("SELECT * FROM chevron_user")
+	userRows, err := h.conn.Queryx("SELECT * FROM chevron_user WHERE user_deleted_at IS NULL")
 	if err != nil {
 		_ = h.gpgKeysRows.Close()
 		h.gpgKeysRows = nil
@@ -73,6 +73,8 @@ func (h *PostgreSQLDBDriver) NextGPGKey(key *models.GPGKey) bool {
 		key.Emails = newKey.Emails
 		key.KeyUids = newKey.KeyUids
 		key.KeyBits = newKey.KeyBits
+		key.KeyAlgorithm = newKey.KeyAlgorithm
+		key.ExpiresAt = newKey.ExpiresAt
 		key.Subkeys = newKey.Subkeys
 		key.AsciiArmoredPublicKey = newKey.AsciiArmoredPublicKey
 		key.AsciiArmoredPrivateKey = newKey.AsciiArmoredPrivateKey
@@ -101,6 +103,11 @@ func (h *PostgreSQLDBDriver) NextUser(user *models.User) bool {
 		user.FullName = newUser.FullName
 		user.CreatedAt = newUser.CreatedAt
 		user.Password = newUser.Password
+		user.Disabled = newUser.Disabled
+		user.Roles = newUser.Roles
+		user.TOTPSecret = newUser.TOTPSecret
+		user.TOTPEnabled = newUser.TOTPEnabled
+		user.TOTPLastStep = newUser.TOTPLastStep
 
 		return true
 	}
@@ -110,7 +117,7 @@ func (h *PostgreSQLDBDriver) NextUser(user *models.User) bool {
 
 func (h *PostgreSQLDBDriver) NumGPGKeys() (int, error) {
 	count := -1
-	err := h.conn.Get(&count, "SELECT COUNT(*) FROM chevron_gpg_key")
+	err := h.conn.Get(&count, "SELECT COUNT(*) FROM chevron_gpg_key WHERE gpg_key_deleted_at IS NULL")
 	return count, err
 }
 
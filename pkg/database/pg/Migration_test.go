@@ -23,7 +23,7 @@ func TestPostgreSQLDBDriver_InitCursor(t *testing.T) {
 
 	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_gpg_key`)).
 		WillReturnRows(mock.NewRows(nil))
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_user`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_user WHERE user_deleted_at IS NULL`)).
 		WillReturnRows(mock.NewRows(nil))
 
 	err := h.InitCursor()
@@ -88,6 +88,7 @@ func TestPostgreSQLDBDriver_NextGPGKey(t *testing.T) {
 		"gpg_key_full_fingerprint",
 		"gpg_key_fingerprint16",
 		"gpg_key_keybits",
+		"gpg_key_algorithm",
 		"gpg_key_public_key",
 		"gpg_key_private_key",
 		"gpg_key_created_at",
@@ -99,6 +100,7 @@ func TestPostgreSQLDBDriver_NextGPGKey(t *testing.T) {
 		testmodels.GpgKey.FullFingerprint,
 		tools.FPto16(testmodels.GpgKey.FullFingerprint),
 		testmodels.GpgKey.KeyBits,
+		testmodels.GpgKey.KeyAlgorithm,
 		testmodels.GpgKey.AsciiArmoredPublicKey,
 		testmodels.GpgKey.AsciiArmoredPrivateKey,
 		time.Now(),
@@ -142,6 +144,7 @@ func TestPostgreSQLDBDriver_NextGPGKey(t *testing.T) {
 		"gpg_key_full_fingerprint",
 		"gpg_key_fingerprint16",
 		"gpg_key_keybits",
+		"gpg_key_algorithm",
 		"gpg_key_public_key",
 		"gpg_key_private_key",
 		"gpg_key_created_at",
@@ -156,6 +159,7 @@ func TestPostgreSQLDBDriver_NextGPGKey(t *testing.T) {
 			v,
 			v,
 			testmodels.GpgKey.KeyBits,
+			testmodels.GpgKey.KeyAlgorithm,
 			testmodels.GpgKey.AsciiArmoredPublicKey,
 			testmodels.GpgKey.AsciiArmoredPrivateKey,
 			time.Now(),
@@ -205,6 +209,11 @@ func TestPostgreSQLDBDriver_NextUser(t *testing.T) {
 		"user_created_at",
 		"user_updated_at",
 		"user_deleted_at",
+		"user_disabled",
+		"user_roles",
+		"user_totp_secret",
+		"user_totp_enabled",
+		"user_totp_last_step",
 	}).AddRow(
 		testmodels.User.ID,
 		testmodels.User.Fingerprint,
@@ -214,6 +223,11 @@ func TestPostgreSQLDBDriver_NextUser(t *testing.T) {
 		testmodels.User.CreatedAt,
 		time.Time{},
 		(*time.Time)(nil),
+		testmodels.User.Disabled,
+		"signer",
+		testmodels.User.TOTPSecret,
+		testmodels.User.TOTPEnabled,
+		testmodels.User.TOTPLastStep,
 	)
 
 	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_gpg_key`)).
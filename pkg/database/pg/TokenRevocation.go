@@ -0,0 +1,58 @@
+package pg
+
+import (
+	"github.com/quan-to/chevron/pkg/models"
+)
+
+// AddTokenRevocation stores a token revocation and returns its ID
+func (h *PostgreSQLDBDriver) AddTokenRevocation(tr models.TokenRevocation) (id string, err error) {
+	h.log.Debug("AddTokenRevocation(%s)", tr.Username)
+	tx, err := h.conn.Beginx()
+	if err != nil {
+		return "", err
+	}
+	defer func() { h.rollbackIfErrorCommitIfNot(err, tx) }()
+
+	id, err = h.addTokenRevocation(tx, tr)
+	return id, err
+}
+
+// GetTokenRevocation fetches the revocation of the token with the specified hash
+func (h *PostgreSQLDBDriver) GetTokenRevocation(tokenHash string) (tr *models.TokenRevocation, err error) {
+	h.log.Debug("GetTokenRevocation()")
+	tx, err := h.conn.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { h.rollbackIfErrorCommitIfNot(err, tx) }()
+
+	tr, err = h.getTokenRevocation(tx, "SELECT * FROM chevron_token_revocation WHERE token_revocation_token_hash = $1 LIMIT 1", tokenHash)
+	return tr, err
+}
+
+// GetUserTokenRevocation fetches the latest revocation of all tokens of the specified user
+func (h *PostgreSQLDBDriver) GetUserTokenRevocation(username string) (tr *models.TokenRevocation, err error) {
+	h.log.Debug("GetUserTokenRevocation(%s)", username)
+	tx, err := h.conn.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { h.rollbackIfErrorCommitIfNot(err, tx) }()
+
+	tr, err = h.getTokenRevocation(tx, "SELECT * FROM chevron_token_revocation WHERE token_revocation_username = $1 AND token_revocation_token_hash = '' ORDER BY token_revocation_created_at DESC LIMIT 1", username)
+	return tr, err
+}
+
+// InvalidateTokenRevocations removes the revocations of tokens that had been already expired
+// Revocations of all tokens of a user are kept
+func (h *PostgreSQLDBDriver) InvalidateTokenRevocations() (n int, err error) {
+	h.log.Debug("InvalidateTokenRevocations()")
+	tx, err := h.conn.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { h.rollbackIfErrorCommitIfNot(err, tx) }()
+
+	n, err = h.invalidateTokenRevocations(tx)
+	return n, err
+}
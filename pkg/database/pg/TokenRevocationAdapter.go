@@ -0,0 +1,49 @@
+package pg
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/quan-to/chevron/pkg/models"
+	"github.com/quan-to/chevron/pkg/uuid"
+)
+
+type pgTokenRevocation struct {
+	ID         string    `db:"token_revocation_id"`
+	TokenHash  string    `db:"token_revocation_token_hash"`
+	Username   string    `db:"token_revocation_username"`
+	CreatedAt  time.Time `db:"token_revocation_created_at"`
+	Expiration time.Time `db:"token_revocation_expiration"`
+}
+
+func (t *pgTokenRevocation) toTokenRevocation() *models.TokenRevocation {
+	return &models.TokenRevocation{
+		ID:         t.ID,
+		TokenHash:  t.TokenHash,
+		Username:   t.Username,
+		CreatedAt:  t.CreatedAt,
+		Expiration: t.Expiration,
+	}
+}
+
+func pgTokenRevocationFromTokenRevocation(tr models.TokenRevocation) *pgTokenRevocation {
+	return &pgTokenRevocation{
+		ID:         tr.ID,
+		TokenHash:  tr.TokenHash,
+		Username:   tr.Username,
+		CreatedAt:  tr.CreatedAt,
+		Expiration: tr.Expiration,
+	}
+}
+
+func (t *pgTokenRevocation) save(tx *sqlx.Tx) error {
+	if t.ID == "" {
+		t.ID = uuid.EnsureUUID(nil)
+	}
+
+	_, err := tx.NamedExec(`INSERT INTO 
+            chevron_token_revocation(token_revocation_id, token_revocation_token_hash, token_revocation_username, token_revocation_created_at, token_revocation_expiration) 
+            VALUES (:token_revocation_id, :token_revocation_token_hash, :token_revocation_username, :token_revocation_created_at, :token_revocation_expiration)`, t)
+
+	return err
+}
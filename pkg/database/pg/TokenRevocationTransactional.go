@@ -0,0 +1,46 @@
+package pg
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/quan-to/chevron/pkg/models"
+)
+
+func (h *PostgreSQLDBDriver) addTokenRevocation(tx *sqlx.Tx, tr models.TokenRevocation) (string, error) {
+	if tr.TokenHash == "" && tr.Username == "" {
+		return "", fmt.Errorf("empty revocation")
+	}
+
+	revocation := pgTokenRevocationFromTokenRevocation(tr)
+	revocation.ID = ""
+	err := revocation.save(tx)
+
+	return revocation.ID, err
+}
+
+func (h *PostgreSQLDBDriver) getTokenRevocation(tx *sqlx.Tx, query string, args ...interface{}) (*models.TokenRevocation, error) {
+	tr := &pgTokenRevocation{}
+	err := tx.Get(tr, query, args...)
+	if err != nil && !strings.EqualFold("sql: no rows in result set", err.Error()) {
+		return nil, err
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("not found")
+	}
+
+	return tr.toTokenRevocation(), nil
+}
+
+func (h *PostgreSQLDBDriver) invalidateTokenRevocations(tx *sqlx.Tx) (int, error) {
+	res, err := tx.Exec("DELETE FROM chevron_token_revocation WHERE token_revocation_token_hash <> '' AND token_revocation_expiration < now()")
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+
+	return int(n), err
+}
@@ -0,0 +1,183 @@
+package pg
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/quan-to/chevron/pkg/models"
+)
+
+var testRevocation = models.TokenRevocation{
+	TokenHash:  hashToken("revoked-token"),
+	Username:   "johnhuebr",
+	CreatedAt:  time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+	Expiration: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+}
+
+var tokenRevocationColumns = []string{
+	"token_revocation_id",
+	"token_revocation_token_hash",
+	"token_revocation_username",
+	"token_revocation_created_at",
+	"token_revocation_expiration",
+}
+
+func TestPostgreSQLDBDriver_AddTokenRevocation(t *testing.T) {
+	h := MakePostgreSQLDBDriver(nil)
+	converter := sqlmock.ValueConverterOption(customConverter{})
+
+	mockDB, mock, _ := sqlmock.New(converter)
+	h.conn = sqlx.NewDb(mockDB, "sqlmock")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO chevron_token_revocation(token_revocation_id, token_revocation_token_hash, token_revocation_username, token_revocation_created_at, token_revocation_expiration) VALUES (?, ?, ?, ?, ?)`)).
+		WithArgs(
+			sqlmock.AnyArg(),
+			testRevocation.TokenHash,
+			testRevocation.Username,
+			testRevocation.CreatedAt,
+			testRevocation.Expiration,
+		).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	id, err := h.AddTokenRevocation(testRevocation)
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	if id == "" {
+		t.Fatalf("expected generated id")
+	}
+
+	// Test empty revocation
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	_, err = h.AddTokenRevocation(models.TokenRevocation{})
+	if err == nil {
+		t.Fatalf("expected error adding an empty revocation")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf(expectationsDidNotMet, err)
+	}
+}
+
+func TestPostgreSQLDBDriver_GetTokenRevocation(t *testing.T) {
+	h := MakePostgreSQLDBDriver(nil)
+	converter := sqlmock.ValueConverterOption(customConverter{})
+
+	mockDB, mock, _ := sqlmock.New(converter)
+	h.conn = sqlx.NewDb(mockDB, "sqlmock")
+
+	expected := testRevocation
+	expected.ID = "abcd"
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_token_revocation WHERE token_revocation_token_hash = $1 LIMIT 1`)).
+		WithArgs(expected.TokenHash).
+		WillReturnRows(sqlmock.NewRows(tokenRevocationColumns).AddRow(
+			expected.ID,
+			expected.TokenHash,
+			expected.Username,
+			expected.CreatedAt,
+			expected.Expiration,
+		))
+	mock.ExpectCommit()
+
+	tr, err := h.GetTokenRevocation(expected.TokenHash)
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	if diff := pretty.Compare(expected, tr); diff != "" {
+		t.Errorf("Expected revocation to be the same. (-got +want)\\n%s", diff)
+	}
+
+	// Test not found
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_token_revocation WHERE token_revocation_token_hash = $1 LIMIT 1`)).
+		WithArgs("invalid").
+		WillReturnRows(sqlmock.NewRows(nil))
+	mock.ExpectRollback()
+
+	_, err = h.GetTokenRevocation("invalid")
+	if err == nil || !strings.EqualFold("not found", err.Error()) {
+		t.Fatalf("expected error to be %q got %v", "not found", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf(expectationsDidNotMet, err)
+	}
+}
+
+func TestPostgreSQLDBDriver_GetUserTokenRevocation(t *testing.T) {
+	h := MakePostgreSQLDBDriver(nil)
+	converter := sqlmock.ValueConverterOption(customConverter{})
+
+	mockDB, mock, _ := sqlmock.New(converter)
+	h.conn = sqlx.NewDb(mockDB, "sqlmock")
+
+	expected := models.TokenRevocation{
+		ID:        "abcd",
+		Username:  testRevocation.Username,
+		CreatedAt: testRevocation.CreatedAt,
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_token_revocation WHERE token_revocation_username = $1 AND token_revocation_token_hash = '' ORDER BY token_revocation_created_at DESC LIMIT 1`)).
+		WithArgs(expected.Username).
+		WillReturnRows(sqlmock.NewRows(tokenRevocationColumns).AddRow(
+			expected.ID,
+			expected.TokenHash,
+			expected.Username,
+			expected.CreatedAt,
+			expected.Expiration,
+		))
+	mock.ExpectCommit()
+
+	tr, err := h.GetUserTokenRevocation(expected.Username)
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	if diff := pretty.Compare(expected, tr); diff != "" {
+		t.Errorf("Expected revocation to be the same. (-got +want)\\n%s", diff)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf(expectationsDidNotMet, err)
+	}
+}
+
+func TestPostgreSQLDBDriver_InvalidateTokenRevocations(t *testing.T) {
+	h := MakePostgreSQLDBDriver(nil)
+	converter := sqlmock.ValueConverterOption(customConverter{})
+
+	mockDB, mock, _ := sqlmock.New(converter)
+	h.conn = sqlx.NewDb(mockDB, "sqlmock")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM chevron_token_revocation WHERE token_revocation_token_hash <> '' AND token_revocation_expiration < now()`)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	n, err := h.InvalidateTokenRevocations()
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	if n != 2 {
+		t.Fatalf("expected 2 removed revocations, got %d", n)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf(expectationsDidNotMet, err)
+	}
+}
@@ -39,3 +39,30 @@ func (h *PostgreSQLDBDriver) UpdateUser(um models.User) error {
 
 	return h.updateUser(tx, um)
 }
+
+// ListUsers returns the users sorted by username in the specified page
+func (h *PostgreSQLDBDriver) ListUsers(pageStart, pageEnd int) (res []models.User, err error) {
+	h.log.Debug("ListUsers(%d, %d)", pageStart, pageEnd)
+	tx, err := h.conn.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { h.rollbackIfErrorCommitIfNot(err, tx) }()
+
+	res, err = h.listUsers(tx, pageStart, pageEnd)
+	return res, err
+}
+
+// DeleteUser deletes the specified user by it's ID or username
+// The user row is kept with the deletion date
+func (h *PostgreSQLDBDriver) DeleteUser(um models.User) (err error) {
+	h.log.Debug("DeleteUser(%s)", um.Username)
+	tx, err := h.conn.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() { h.rollbackIfErrorCommitIfNot(err, tx) }()
+
+	err = h.deleteUser(tx, um)
+	return err
+}
@@ -1,6 +1,7 @@
 package pg
 
 import (
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -17,27 +18,50 @@ type pgUser struct {
 	CreatedAt   time.Time  `db:"user_created_at"`
 	UpdatedAt   time.Time  `db:"user_updated_at"`
 	DeletedAt   *time.Time `db:"user_deleted_at"`
+	Disabled    bool       `db:"user_disabled"`
+	// Roles are stored comma separated
+	Roles        string `db:"user_roles"`
+	TOTPSecret   string `db:"user_totp_secret"`
+	TOTPEnabled  bool   `db:"user_totp_enabled"`
+	TOTPLastStep int64  `db:"user_totp_last_step"`
+}
+
+func splitRoles(roles string) []string {
+	if roles == "" {
+		return nil
+	}
+	return strings.Split(roles, ",")
 }
 
 func (u *pgUser) toUser() *models.User {
 	return &models.User{
-		ID:          u.ID,
-		Fingerprint: u.Fingerprint,
-		Username:    u.Username,
-		Password:    string(u.Password),
-		FullName:    u.FullName,
-		CreatedAt:   u.CreatedAt,
+		ID:           u.ID,
+		Fingerprint:  u.Fingerprint,
+		Username:     u.Username,
+		Password:     string(u.Password),
+		FullName:     u.FullName,
+		CreatedAt:    u.CreatedAt,
+		Disabled:     u.Disabled,
+		Roles:        splitRoles(u.Roles),
+		TOTPSecret:   u.TOTPSecret,
+		TOTPEnabled:  u.TOTPEnabled,
+		TOTPLastStep: u.TOTPLastStep,
 	}
 }
 
 func pgUserFromUser(um models.User) *pgUser {
 	return &pgUser{
-		ID:          um.ID,
-		Fingerprint: um.Fingerprint,
-		Username:    um.Username,
-		Password:    []byte(um.Password),
-		FullName:    um.FullName,
-		CreatedAt:   um.CreatedAt,
+		ID:           um.ID,
+		Fingerprint:  um.Fingerprint,
+		Username:     um.Username,
+		Password:     []byte(um.Password),
+		FullName:     um.FullName,
+		CreatedAt:    um.CreatedAt,
+		Disabled:     um.Disabled,
+		Roles:        strings.Join(um.Roles, ","),
+		TOTPSecret:   um.TOTPSecret,
+		TOTPEnabled:  um.TOTPEnabled,
+		TOTPLastStep: um.TOTPLastStep,
 	}
 }
 
@@ -45,8 +69,8 @@ func (u *pgUser) save(tx *sqlx.Tx) error {
 	if u.ID == "" { // Insert
 		u.ID = uuid.EnsureUUID(nil)
 		_, err := tx.NamedExec(`INSERT INTO 
-            chevron_user(user_id, user_fingerprint, user_username, user_password, user_full_name, user_created_at) 
-            VALUES (:user_id, :user_fingerprint, :user_username, :user_password, :user_full_name, now())`, u)
+            chevron_user(user_id, user_fingerprint, user_username, user_password, user_full_name, user_disabled, user_roles, user_totp_secret, user_totp_enabled, user_totp_last_step, user_created_at) 
+            VALUES (:user_id, :user_fingerprint, :user_username, :user_password, :user_full_name, :user_disabled, :user_roles, :user_totp_secret, :user_totp_enabled, :user_totp_last_step, now())`, u)
 		if err != nil {
 			return err
 		}
@@ -58,6 +82,11 @@ func (u *pgUser) save(tx *sqlx.Tx) error {
                            user_fingerprint = :user_fingerprint,
                            user_password = :user_password,
                            user_full_name = :user_full_name,
+                           user_disabled = :user_disabled,
+                           user_roles = :user_roles,
+                           user_totp_secret = :user_totp_secret,
+                           user_totp_enabled = :user_totp_enabled,
+                           user_totp_last_step = :user_totp_last_step,
                            user_updated_at = now()
                            WHERE user_id = :user_id`, u)
 	return err
@@ -26,7 +26,7 @@ func (h *PostgreSQLDBDriver) addUser(tx *sqlx.Tx, um models.User) (string, error
 
 func (h *PostgreSQLDBDriver) getUser(tx *sqlx.Tx, username string) (um *models.User, err error) {
 	user := &pgUser{}
-	err = tx.Get(user, "SELECT * FROM chevron_user WHERE user_username = $1 LIMIT 1", username)
+	err = tx.Get(user, "SELECT * FROM chevron_user WHERE user_username = $1 AND user_deleted_at IS NULL LIMIT 1", username)
 	if err != nil && !strings.EqualFold("sql: no rows in result set", err.Error()) {
 		return nil, err
 	}
@@ -54,3 +54,58 @@ func (h *PostgreSQLDBDriver) updateUser(tx *sqlx.Tx, um models.User) error {
 
 	return pguser.save(tx)
 }
+
+func (h *PostgreSQLDBDriver) listUsers(tx *sqlx.Tx, pageStart, pageEnd int) ([]models.User, error) {
+	if pageStart < 0 {
+		pageStart = models.DefaultPageStart
+	}
+
+	if pageEnd < 0 {
+		pageEnd = models.DefaultPageEnd
+	}
+
+	numItems := pageEnd - pageStart
+	if numItems <= 0 {
+		return []models.User{}, nil
+	}
+
+	var res []pgUser
+	err := tx.Select(&res, "SELECT * FROM chevron_user WHERE user_deleted_at IS NULL ORDER BY user_username LIMIT $1 OFFSET $2", numItems, pageStart)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]models.User, len(res))
+	for i, u := range res {
+		users[i] = *u.toUser()
+	}
+
+	return users, nil
+}
+
+func (h *PostgreSQLDBDriver) deleteUser(tx *sqlx.Tx, um models.User) error {
+	if um.ID == "" {
+		// Fetch user
+		u, err := h.getUser(tx, um.Username)
+		if err != nil {
+			return err
+		}
+		um.ID = u.ID
+	}
+
+	res, err := tx.Exec("UPDATE chevron_user SET user_deleted_at = now(), user_updated_at = now() WHERE user_id = $1 AND user_deleted_at IS NULL", um.ID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return fmt.Errorf("not found")
+	}
+
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jmoiron/sqlx"
 	"github.com/kylelemons/godebug/pretty"
+	"github.com/quan-to/chevron/pkg/models"
 	"github.com/quan-to/chevron/pkg/models/testmodels"
 )
 
@@ -23,6 +24,11 @@ func expectUserSelect(mock sqlmock.Sqlmock) {
 		"user_created_at",
 		"user_updated_at",
 		"user_deleted_at",
+		"user_disabled",
+		"user_roles",
+		"user_totp_secret",
+		"user_totp_enabled",
+		"user_totp_last_step",
 	}).AddRow(
 		testmodels.User.ID,
 		testmodels.User.Fingerprint,
@@ -32,8 +38,13 @@ func expectUserSelect(mock sqlmock.Sqlmock) {
 		testmodels.User.CreatedAt,
 		time.Time{},
 		(*time.Time)(nil),
+		testmodels.User.Disabled,
+		"signer",
+		testmodels.User.TOTPSecret,
+		testmodels.User.TOTPEnabled,
+		testmodels.User.TOTPLastStep,
 	)
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_user WHERE user_username = $1 LIMIT 1`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_user WHERE user_username = $1 AND user_deleted_at IS NULL LIMIT 1`)).
 		WithArgs(testmodels.User.Username).
 		WillReturnRows(expectedUserRows)
 }
@@ -49,16 +60,21 @@ func TestPostgreSQLDBDriver_AddUser(t *testing.T) {
 	testAdd.ID = ""
 
 	mock.ExpectBegin()
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_user WHERE user_username = $1 LIMIT 1`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_user WHERE user_username = $1 AND user_deleted_at IS NULL LIMIT 1`)).
 		WithArgs(testmodels.User.Username).
 		WillReturnRows(sqlmock.NewRows(nil))
-	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO chevron_user(user_id, user_fingerprint, user_username, user_password, user_full_name, user_created_at) VALUES (?, ?, ?, ?, ?, now())`)).
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO chevron_user(user_id, user_fingerprint, user_username, user_password, user_full_name, user_disabled, user_roles, user_totp_secret, user_totp_enabled, user_totp_last_step, user_created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, now())`)).
 		WithArgs(
 			sqlmock.AnyArg(),
 			testAdd.Fingerprint,
 			testAdd.Username,
 			[]byte(testAdd.Password),
 			testAdd.FullName,
+			testAdd.Disabled,
+			"signer",
+			testmodels.User.TOTPSecret,
+			testmodels.User.TOTPEnabled,
+			testmodels.User.TOTPLastStep,
 		).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectCommit()
@@ -107,7 +123,7 @@ func TestPostgreSQLDBDriver_GetUser(t *testing.T) {
 	mockDB, mock, _ = sqlmock.New(converter)
 	h.conn = sqlx.NewDb(mockDB, "sqlmock")
 	mock.ExpectBegin()
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_user WHERE user_username = $1 LIMIT 1`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_user WHERE user_username = $1 AND user_deleted_at IS NULL LIMIT 1`)).
 		WithArgs(testmodels.User.Username).
 		WillReturnError(fmt.Errorf("sql: no rows in result set"))
 	mock.ExpectRollback()
@@ -133,11 +149,16 @@ func TestPostgreSQLDBDriver_UpdateUser(t *testing.T) {
 	h.conn = sqlx.NewDb(mockDB, "sqlmock")
 
 	mock.ExpectBegin()
-	mock.ExpectExec(regexp.QuoteMeta(`UPDATE chevron_user SET user_fingerprint = ?, user_password = ?, user_full_name = ?, user_updated_at = now() WHERE user_id = ?`)).
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE chevron_user SET user_fingerprint = ?, user_password = ?, user_full_name = ?, user_disabled = ?, user_roles = ?, user_totp_secret = ?, user_totp_enabled = ?, user_totp_last_step = ?, user_updated_at = now() WHERE user_id = ?`)).
 		WithArgs(
 			testmodels.User.Fingerprint,
 			[]byte(testmodels.User.Password),
 			testmodels.User.FullName,
+			testmodels.User.Disabled,
+			"signer",
+			testmodels.User.TOTPSecret,
+			testmodels.User.TOTPEnabled,
+			testmodels.User.TOTPLastStep,
 			testmodels.User.ID,
 		).WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectCommit()
@@ -157,11 +178,16 @@ func TestPostgreSQLDBDriver_UpdateUser(t *testing.T) {
 
 	mock.ExpectBegin()
 	expectUserSelect(mock)
-	mock.ExpectExec(regexp.QuoteMeta(`UPDATE chevron_user SET user_fingerprint = ?, user_password = ?, user_full_name = ?, user_updated_at = now() WHERE user_id = ?`)).
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE chevron_user SET user_fingerprint = ?, user_password = ?, user_full_name = ?, user_disabled = ?, user_roles = ?, user_totp_secret = ?, user_totp_enabled = ?, user_totp_last_step = ?, user_updated_at = now() WHERE user_id = ?`)).
 		WithArgs(
 			testmodels.User.Fingerprint,
 			[]byte(testmodels.User.Password),
 			testmodels.User.FullName,
+			testmodels.User.Disabled,
+			"signer",
+			testmodels.User.TOTPSecret,
+			testmodels.User.TOTPEnabled,
+			testmodels.User.TOTPLastStep,
 			testmodels.User.ID,
 		).WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectCommit()
@@ -178,3 +204,101 @@ func TestPostgreSQLDBDriver_UpdateUser(t *testing.T) {
 		t.Fatalf(expectationsDidNotMet, err)
 	}
 }
+
+func TestPostgreSQLDBDriver_ListUsers(t *testing.T) {
+	h := MakePostgreSQLDBDriver(nil)
+	converter := sqlmock.ValueConverterOption(customConverter{})
+
+	mockDB, mock, _ := sqlmock.New(converter)
+	h.conn = sqlx.NewDb(mockDB, "sqlmock")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_user WHERE user_deleted_at IS NULL ORDER BY user_username LIMIT $1 OFFSET $2`)).
+		WithArgs(10, 5).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"user_id",
+			"user_fingerprint",
+			"user_username",
+			"user_password",
+			"user_full_name",
+			"user_created_at",
+			"user_updated_at",
+			"user_deleted_at",
+			"user_disabled",
+			"user_roles",
+			"user_totp_secret",
+			"user_totp_enabled",
+			"user_totp_last_step",
+		}).AddRow(
+			testmodels.User.ID,
+			testmodels.User.Fingerprint,
+			testmodels.User.Username,
+			[]byte(testmodels.User.Password),
+			testmodels.User.FullName,
+			testmodels.User.CreatedAt,
+			time.Time{},
+			(*time.Time)(nil),
+			true,
+			"signer",
+			testmodels.User.TOTPSecret,
+			testmodels.User.TOTPEnabled,
+			testmodels.User.TOTPLastStep,
+		))
+	mock.ExpectCommit()
+
+	users, err := h.ListUsers(5, 15)
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	expected := testmodels.User
+	expected.Disabled = true
+
+	if diff := pretty.Compare([]models.User{expected}, users); diff != "" {
+		t.Errorf("Expected users to be the same. (-got +want)\\n%s", diff)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf(expectationsDidNotMet, err)
+	}
+}
+
+func TestPostgreSQLDBDriver_DeleteUser(t *testing.T) {
+	h := MakePostgreSQLDBDriver(nil)
+	converter := sqlmock.ValueConverterOption(customConverter{})
+
+	mockDB, mock, _ := sqlmock.New(converter)
+	h.conn = sqlx.NewDb(mockDB, "sqlmock")
+
+	// Delete by username
+	userToDelete := testmodels.User
+	userToDelete.ID = ""
+
+	mock.ExpectBegin()
+	expectUserSelect(mock)
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE chevron_user SET user_deleted_at = now(), user_updated_at = now() WHERE user_id = $1 AND user_deleted_at IS NULL`)).
+		WithArgs(testmodels.User.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := h.DeleteUser(userToDelete)
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	// Test not found
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE chevron_user SET user_deleted_at = now(), user_updated_at = now() WHERE user_id = $1 AND user_deleted_at IS NULL`)).
+		WithArgs(testmodels.User.ID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err = h.DeleteUser(testmodels.User)
+	if err == nil || !strings.EqualFold("not found", err.Error()) {
+		t.Fatalf("expected error to be %q got %v", "not found", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf(expectationsDidNotMet, err)
+	}
+}
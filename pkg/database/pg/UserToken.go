@@ -1,24 +1,131 @@
 package pg
 
 import (
-	"fmt"
+	"time"
 
 	"github.com/quan-to/chevron/pkg/models"
 )
 
-func (h *PostgreSQLDBDriver) AddUserToken(ut models.UserToken) (string, error) {
-	return "", fmt.Errorf("token is not supported on postgres. please use redis wrapper around it")
+// AddUserToken adds a new user token to be valid and returns its token ID
+// Only a hash of the token is stored in the database
+func (h *PostgreSQLDBDriver) AddUserToken(ut models.UserToken) (id string, err error) {
+	h.log.Debug("AddUserToken(%s, %s)", ut.Username, ut.Fingerprint)
+	tx, err := h.conn.Beginx()
+	if err != nil {
+		return "", err
+	}
+	defer func() { h.rollbackIfErrorCommitIfNot(err, tx) }()
+
+	id, err = h.addUserToken(tx, ut)
+	return id, err
 }
 
 // RemoveUserToken removes a user token from the database
 func (h *PostgreSQLDBDriver) RemoveUserToken(token string) (err error) {
-	return fmt.Errorf("token is not supported on postgres. please use redis wrapper around it")
+	h.log.Debug("RemoveUserToken()")
+	tx, err := h.conn.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() { h.rollbackIfErrorCommitIfNot(err, tx) }()
+
+	err = h.removeUserToken(tx, token)
+	return err
 }
 
+// GetUserToken fetch a UserToken object by the specified token
 func (h *PostgreSQLDBDriver) GetUserToken(token string) (ut *models.UserToken, err error) {
-	return nil, fmt.Errorf("token is not supported on postgres. please use redis wrapper around it")
+	h.log.Debug("GetUserToken()")
+	tx, err := h.conn.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { h.rollbackIfErrorCommitIfNot(err, tx) }()
+
+	ut, err = h.getUserToken(tx, token)
+	return ut, err
+}
+
+// InvalidateUserTokens removes all user tokens that had been already expired
+func (h *PostgreSQLDBDriver) InvalidateUserTokens() (n int, err error) {
+	h.log.Debug("InvalidateUserTokens()")
+	tx, err := h.conn.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { h.rollbackIfErrorCommitIfNot(err, tx) }()
+
+	n, err = h.invalidateUserTokens(tx)
+	return n, err
+}
+
+// FetchUserTokens returns all tokens of the specified user
+// The returned tokens does not have the Token field since only its hash is stored
+func (h *PostgreSQLDBDriver) FetchUserTokens(username string) (res []models.UserToken, err error) {
+	h.log.Debug("FetchUserTokens(%s)", username)
+	tx, err := h.conn.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { h.rollbackIfErrorCommitIfNot(err, tx) }()
+
+	res, err = h.fetchUserTokens(tx, username)
+	return res, err
 }
 
-func (h *PostgreSQLDBDriver) InvalidateUserTokens() (int, error) {
-	return 0, fmt.Errorf("token is not supported on postgres. please use redis wrapper around it")
+// RemoveUserTokens removes all tokens of the specified user
+func (h *PostgreSQLDBDriver) RemoveUserTokens(username string) (n int, err error) {
+	h.log.Debug("RemoveUserTokens(%s)", username)
+	tx, err := h.conn.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { h.rollbackIfErrorCommitIfNot(err, tx) }()
+
+	n, err = h.removeUserTokens(tx, username)
+	return n, err
+}
+
+// StartTokenReaper starts a background routine that removes expired tokens every interval
+// Calling it again replaces the running reaper
+func (h *PostgreSQLDBDriver) StartTokenReaper(interval time.Duration) {
+	h.StopTokenReaper()
+
+	h.reaperLock.Lock()
+	defer h.reaperLock.Unlock()
+
+	stop := make(chan struct{})
+	h.reaperStop = stop
+
+	h.log.Info("Starting token reaper with interval %s", interval)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				n, err := h.InvalidateUserTokens()
+				if err != nil {
+					h.log.Error("Error removing expired tokens: %s", err)
+					continue
+				}
+				if n > 0 {
+					h.log.Info("Removed %d expired tokens", n)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// StopTokenReaper stops the background token reaper if running
+func (h *PostgreSQLDBDriver) StopTokenReaper() {
+	h.reaperLock.Lock()
+	defer h.reaperLock.Unlock()
+
+	if h.reaperStop != nil {
+		close(h.reaperStop)
+		h.reaperStop = nil
+	}
 }
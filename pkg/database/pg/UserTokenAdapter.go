@@ -0,0 +1,66 @@
+package pg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/quan-to/chevron/pkg/models"
+	"github.com/quan-to/chevron/pkg/uuid"
+)
+
+type pgUserToken struct {
+	ID          string    `db:"user_token_id"`
+	Hash        string    `db:"user_token_hash"`
+	Fingerprint string    `db:"user_token_fingerprint"`
+	Username    string    `db:"user_token_username"`
+	FullName    string    `db:"user_token_full_name"`
+	CreatedAt   time.Time `db:"user_token_created_at"`
+	Expiration  time.Time `db:"user_token_expiration"`
+}
+
+// hashToken returns the representation of the token that is stored at the database
+// The plain token is never persisted, so a database leak does not leak valid tokens
+func hashToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
+
+// toUserToken converts to a models.UserToken. Since only the hash is stored
+// the plain token should be provided by the caller
+func (t *pgUserToken) toUserToken(token string) *models.UserToken {
+	return &models.UserToken{
+		ID:          t.ID,
+		Fingerprint: t.Fingerprint,
+		Username:    t.Username,
+		Fullname:    t.FullName,
+		Token:       token,
+		CreatedAt:   t.CreatedAt,
+		Expiration:  t.Expiration,
+	}
+}
+
+func pgUserTokenFromUserToken(ut models.UserToken) *pgUserToken {
+	return &pgUserToken{
+		ID:          ut.ID,
+		Hash:        hashToken(ut.Token),
+		Fingerprint: ut.Fingerprint,
+		Username:    ut.Username,
+		FullName:    ut.Fullname,
+		CreatedAt:   ut.CreatedAt,
+		Expiration:  ut.Expiration,
+	}
+}
+
+func (t *pgUserToken) save(tx *sqlx.Tx) error {
+	if t.ID == "" {
+		t.ID = uuid.EnsureUUID(nil)
+	}
+
+	_, err := tx.NamedExec(`INSERT INTO 
+            chevron_user_token(user_token_id, user_token_hash, user_token_fingerprint, user_token_username, user_token_full_name, user_token_created_at, user_token_expiration) 
+            VALUES (:user_token_id, :user_token_hash, :user_token_fingerprint, :user_token_username, :user_token_full_name, :user_token_created_at, :user_token_expiration)`, t)
+
+	return err
+}
@@ -0,0 +1,91 @@
+package pg
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/quan-to/chevron/pkg/models"
+)
+
+func (h *PostgreSQLDBDriver) addUserToken(tx *sqlx.Tx, ut models.UserToken) (string, error) {
+	if ut.Token == "" {
+		return "", fmt.Errorf("empty token")
+	}
+
+	token := pgUserTokenFromUserToken(ut)
+	token.ID = ""
+	err := token.save(tx)
+
+	return token.ID, err
+}
+
+func (h *PostgreSQLDBDriver) getUserToken(tx *sqlx.Tx, token string) (*models.UserToken, error) {
+	ut := &pgUserToken{}
+	err := tx.Get(ut, "SELECT * FROM chevron_user_token WHERE user_token_hash = $1 LIMIT 1", hashToken(token))
+	if err != nil && !strings.EqualFold("sql: no rows in result set", err.Error()) {
+		return nil, err
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("not found")
+	}
+
+	return ut.toUserToken(token), nil
+}
+
+func (h *PostgreSQLDBDriver) removeUserToken(tx *sqlx.Tx, token string) error {
+	res, err := tx.Exec("DELETE FROM chevron_user_token WHERE user_token_hash = $1", hashToken(token))
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return fmt.Errorf("not found")
+	}
+
+	return nil
+}
+
+func (h *PostgreSQLDBDriver) invalidateUserTokens(tx *sqlx.Tx) (int, error) {
+	res, err := tx.Exec("DELETE FROM chevron_user_token WHERE user_token_expiration < now()")
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+
+	return int(n), err
+}
+
+func (h *PostgreSQLDBDriver) fetchUserTokens(tx *sqlx.Tx, username string) ([]models.UserToken, error) {
+	var res []pgUserToken
+	err := tx.Select(&res, "SELECT * FROM chevron_user_token WHERE user_token_username = $1 ORDER BY user_token_created_at", username)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make([]models.UserToken, len(res))
+	for i, t := range res {
+		// Only the hash is stored, so the plain token is not available
+		tokens[i] = *t.toUserToken("")
+	}
+
+	return tokens, nil
+}
+
+func (h *PostgreSQLDBDriver) removeUserTokens(tx *sqlx.Tx, username string) (int, error) {
+	res, err := tx.Exec("DELETE FROM chevron_user_token WHERE user_token_username = $1", username)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+
+	return int(n), err
+}
@@ -0,0 +1,286 @@
+package pg
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/quan-to/chevron/pkg/models"
+	"github.com/quan-to/chevron/pkg/models/testmodels"
+)
+
+func TestHashToken(t *testing.T) {
+	h := hashToken(testmodels.Token.Token)
+
+	if h == testmodels.Token.Token {
+		t.Fatalf("expected token to be hashed")
+	}
+
+	if len(h) != 64 {
+		t.Fatalf("expected sha256 hex hash with 64 chars, got %d", len(h))
+	}
+
+	if h != hashToken(testmodels.Token.Token) {
+		t.Fatalf("expected hash to be deterministic")
+	}
+}
+
+func TestPostgreSQLDBDriver_AddUserToken(t *testing.T) {
+	h := MakePostgreSQLDBDriver(nil)
+	converter := sqlmock.ValueConverterOption(customConverter{})
+
+	mockDB, mock, _ := sqlmock.New(converter)
+	h.conn = sqlx.NewDb(mockDB, "sqlmock")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO chevron_user_token(user_token_id, user_token_hash, user_token_fingerprint, user_token_username, user_token_full_name, user_token_created_at, user_token_expiration) VALUES (?, ?, ?, ?, ?, ?, ?)`)).
+		WithArgs(
+			sqlmock.AnyArg(),
+			hashToken(testmodels.Token.Token),
+			testmodels.Token.Fingerprint,
+			testmodels.Token.Username,
+			testmodels.Token.Fullname,
+			testmodels.Token.CreatedAt,
+			testmodels.Token.Expiration,
+		).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	id, err := h.AddUserToken(testmodels.Token)
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	if id == "" {
+		t.Fatalf("expected generated id")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf(expectationsDidNotMet, err)
+	}
+}
+
+func TestPostgreSQLDBDriver_GetUserToken(t *testing.T) {
+	h := MakePostgreSQLDBDriver(nil)
+	converter := sqlmock.ValueConverterOption(customConverter{})
+
+	mockDB, mock, _ := sqlmock.New(converter)
+	h.conn = sqlx.NewDb(mockDB, "sqlmock")
+
+	expected := testmodels.Token
+	expected.ID = "abcd"
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_user_token WHERE user_token_hash = $1 LIMIT 1`)).
+		WithArgs(hashToken(testmodels.Token.Token)).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"user_token_id",
+			"user_token_hash",
+			"user_token_fingerprint",
+			"user_token_username",
+			"user_token_full_name",
+			"user_token_created_at",
+			"user_token_expiration",
+		}).AddRow(
+			expected.ID,
+			hashToken(expected.Token),
+			expected.Fingerprint,
+			expected.Username,
+			expected.Fullname,
+			expected.CreatedAt,
+			expected.Expiration,
+		))
+	mock.ExpectCommit()
+
+	ut, err := h.GetUserToken(testmodels.Token.Token)
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	if diff := pretty.Compare(expected, ut); diff != "" {
+		t.Errorf("Expected token to be the same. (-got +want)\\n%s", diff)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf(expectationsDidNotMet, err)
+	}
+
+	// Test not found
+	mockDB, mock, _ = sqlmock.New(converter)
+	h.conn = sqlx.NewDb(mockDB, "sqlmock")
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_user_token WHERE user_token_hash = $1 LIMIT 1`)).
+		WithArgs(hashToken("invalid")).
+		WillReturnRows(sqlmock.NewRows(nil))
+	mock.ExpectRollback()
+
+	_, err = h.GetUserToken("invalid")
+	if err == nil || !strings.EqualFold("not found", err.Error()) {
+		t.Fatalf("expected error to be %q got %v", "not found", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf(expectationsDidNotMet, err)
+	}
+}
+
+func TestPostgreSQLDBDriver_RemoveUserToken(t *testing.T) {
+	h := MakePostgreSQLDBDriver(nil)
+	converter := sqlmock.ValueConverterOption(customConverter{})
+
+	mockDB, mock, _ := sqlmock.New(converter)
+	h.conn = sqlx.NewDb(mockDB, "sqlmock")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM chevron_user_token WHERE user_token_hash = $1`)).
+		WithArgs(hashToken(testmodels.Token.Token)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := h.RemoveUserToken(testmodels.Token.Token)
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	// Test not found
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM chevron_user_token WHERE user_token_hash = $1`)).
+		WithArgs(hashToken(testmodels.Token.Token)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err = h.RemoveUserToken(testmodels.Token.Token)
+	if err == nil || !strings.EqualFold("not found", err.Error()) {
+		t.Fatalf("expected error to be %q got %v", "not found", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf(expectationsDidNotMet, err)
+	}
+}
+
+func TestPostgreSQLDBDriver_InvalidateUserTokens(t *testing.T) {
+	h := MakePostgreSQLDBDriver(nil)
+	converter := sqlmock.ValueConverterOption(customConverter{})
+
+	mockDB, mock, _ := sqlmock.New(converter)
+	h.conn = sqlx.NewDb(mockDB, "sqlmock")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM chevron_user_token WHERE user_token_expiration < now()`)).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	n, err := h.InvalidateUserTokens()
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	if n != 3 {
+		t.Fatalf("expected 3 invalidated tokens, got %d", n)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf(expectationsDidNotMet, err)
+	}
+}
+
+func TestPostgreSQLDBDriver_TokenReaper(t *testing.T) {
+	h := MakePostgreSQLDBDriver(nil)
+	converter := sqlmock.ValueConverterOption(customConverter{})
+
+	mockDB, mock, _ := sqlmock.New(converter)
+	h.conn = sqlx.NewDb(mockDB, "sqlmock")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM chevron_user_token WHERE user_token_expiration < now()`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	h.StartTokenReaper(time.Millisecond * 50)
+	time.Sleep(time.Millisecond * 200)
+	h.StopTokenReaper()
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf(expectationsDidNotMet, err)
+	}
+}
+
+func TestPostgreSQLDBDriver_FetchUserTokens(t *testing.T) {
+	h := MakePostgreSQLDBDriver(nil)
+	converter := sqlmock.ValueConverterOption(customConverter{})
+
+	mockDB, mock, _ := sqlmock.New(converter)
+	h.conn = sqlx.NewDb(mockDB, "sqlmock")
+
+	expected := testmodels.Token
+	expected.ID = "abcd"
+	// Only the hash is stored
+	expected.Token = ""
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM chevron_user_token WHERE user_token_username = $1 ORDER BY user_token_created_at`)).
+		WithArgs(testmodels.Token.Username).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"user_token_id",
+			"user_token_hash",
+			"user_token_fingerprint",
+			"user_token_username",
+			"user_token_full_name",
+			"user_token_created_at",
+			"user_token_expiration",
+		}).AddRow(
+			expected.ID,
+			hashToken(testmodels.Token.Token),
+			expected.Fingerprint,
+			expected.Username,
+			expected.Fullname,
+			expected.CreatedAt,
+			expected.Expiration,
+		))
+	mock.ExpectCommit()
+
+	tokens, err := h.FetchUserTokens(testmodels.Token.Username)
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	if diff := pretty.Compare([]models.UserToken{expected}, tokens); diff != "" {
+		t.Errorf("Expected tokens to be the same. (-got +want)\\n%s", diff)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf(expectationsDidNotMet, err)
+	}
+}
+
+func TestPostgreSQLDBDriver_RemoveUserTokens(t *testing.T) {
+	h := MakePostgreSQLDBDriver(nil)
+	converter := sqlmock.ValueConverterOption(customConverter{})
+
+	mockDB, mock, _ := sqlmock.New(converter)
+	h.conn = sqlx.NewDb(mockDB, "sqlmock")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM chevron_user_token WHERE user_token_username = $1`)).
+		WithArgs(testmodels.Token.Username).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	n, err := h.RemoveUserTokens(testmodels.Token.Username)
+	if err != nil {
+		t.Fatalf(unexpectedError, err)
+	}
+
+	if n != 2 {
+		t.Fatalf("expected 2 removed tokens, got %d", n)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf(expectationsDidNotMet, err)
+	}
+}
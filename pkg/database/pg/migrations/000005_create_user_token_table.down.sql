@@ -0,0 +1,2 @@
+--changeset racerxdl:create_user_token_table
+DROP TABLE chevron_user_token;
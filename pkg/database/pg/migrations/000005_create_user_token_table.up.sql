@@ -0,0 +1,15 @@
+--changeset racerxdl:create_user_token_table
+CREATE TABLE chevron_user_token
+(
+    user_token_id          uuid      NOT NULL PRIMARY KEY,
+    user_token_hash        varchar   NOT NULL,
+    user_token_fingerprint varchar   NOT NULL,
+    user_token_username    varchar   NOT NULL,
+    user_token_full_name   varchar   NOT NULL,
+    user_token_created_at  timestamp NOT NULL DEFAULT now(),
+    user_token_expiration  timestamp NOT NULL
+);
+
+CREATE UNIQUE INDEX chevron_user_token_hash_idx ON chevron_user_token (user_token_hash);
+CREATE INDEX chevron_user_token_expiration_idx ON chevron_user_token (user_token_expiration);
+CREATE INDEX chevron_user_token_username_idx ON chevron_user_token (user_token_username);
@@ -0,0 +1,3 @@
+--changeset racerxdl:create_gpg_key_history_table
+DROP INDEX chevron_gpg_key_deleted_at_idx;
+DROP TABLE chevron_gpg_key_history;
@@ -0,0 +1,16 @@
+--changeset racerxdl:create_gpg_key_history_table
+CREATE TABLE chevron_gpg_key_history
+(
+    gpg_key_history_id               uuid      NOT NULL PRIMARY KEY,
+    gpg_key_history_key              uuid      NOT NULL REFERENCES chevron_gpg_key (gpg_key_id) ON DELETE CASCADE,
+    gpg_key_history_full_fingerprint varchar   NOT NULL,
+    gpg_key_history_fingerprint16    varchar   NOT NULL,
+    gpg_key_history_action           varchar   NOT NULL,
+    gpg_key_history_actor            varchar   NOT NULL DEFAULT '',
+    gpg_key_history_changes          jsonb     NOT NULL DEFAULT '[]',
+    gpg_key_history_snapshot         jsonb     NOT NULL,
+    gpg_key_history_created_at       timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX chevron_gpg_key_history_fingerprint16_idx ON chevron_gpg_key_history (gpg_key_history_fingerprint16);
+CREATE INDEX chevron_gpg_key_deleted_at_idx ON chevron_gpg_key (gpg_key_deleted_at);
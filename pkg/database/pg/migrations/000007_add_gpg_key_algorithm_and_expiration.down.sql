@@ -0,0 +1,8 @@
+--changeset racerxdl:add_gpg_key_algorithm_and_expiration
+DROP INDEX IF EXISTS chevron_gpg_key_expires_at_idx;
+DROP INDEX IF EXISTS chevron_gpg_key_algorithm_idx;
+DROP INDEX IF EXISTS chevron_gpg_key_keybits_idx;
+
+ALTER TABLE chevron_gpg_key
+    DROP COLUMN gpg_key_expires_at,
+    DROP COLUMN gpg_key_algorithm;
@@ -0,0 +1,8 @@
+--changeset racerxdl:add_gpg_key_algorithm_and_expiration
+ALTER TABLE chevron_gpg_key
+    ADD COLUMN gpg_key_algorithm  varchar   NOT NULL DEFAULT '',
+    ADD COLUMN gpg_key_expires_at timestamp NULL;
+
+CREATE INDEX chevron_gpg_key_keybits_idx ON chevron_gpg_key (gpg_key_keybits);
+CREATE INDEX chevron_gpg_key_algorithm_idx ON chevron_gpg_key (gpg_key_algorithm);
+CREATE INDEX chevron_gpg_key_expires_at_idx ON chevron_gpg_key (gpg_key_expires_at);
@@ -0,0 +1,5 @@
+--changeset racerxdl:create_gpg_key_uid_search_indexes
+DROP INDEX IF EXISTS chevron_gpg_key_full_fingerprint_trgm_idx;
+DROP INDEX IF EXISTS chevron_gpg_key_uid_email_trgm_idx;
+DROP INDEX IF EXISTS chevron_gpg_key_uid_name_trgm_idx;
+DROP INDEX IF EXISTS chevron_gpg_key_uid_name_fts_idx;
@@ -0,0 +1,16 @@
+--changeset racerxdl:create_gpg_key_uid_search_indexes
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Prefix search over name words
+CREATE INDEX chevron_gpg_key_uid_name_fts_idx ON chevron_gpg_key_uid
+    USING gin (to_tsvector('simple', coalesce(gpg_key_uid_name, '')));
+
+-- Fuzzy search over names and prefix / domain / fuzzy search over emails
+CREATE INDEX chevron_gpg_key_uid_name_trgm_idx ON chevron_gpg_key_uid
+    USING gin (lower(gpg_key_uid_name) gin_trgm_ops);
+CREATE INDEX chevron_gpg_key_uid_email_trgm_idx ON chevron_gpg_key_uid
+    USING gin (lower(gpg_key_uid_email) gin_trgm_ops);
+
+-- Fingerprint suffix search
+CREATE INDEX chevron_gpg_key_full_fingerprint_trgm_idx ON chevron_gpg_key
+    USING gin (gpg_key_full_fingerprint gin_trgm_ops);
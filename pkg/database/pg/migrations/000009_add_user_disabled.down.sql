@@ -0,0 +1,3 @@
+--changeset racerxdl:add_user_disabled
+ALTER TABLE chevron_user
+    DROP COLUMN user_disabled;
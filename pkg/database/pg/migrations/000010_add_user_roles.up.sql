@@ -0,0 +1,3 @@
+--changeset racerxdl:add_user_roles
+ALTER TABLE chevron_user
+    ADD COLUMN user_roles varchar NOT NULL DEFAULT '';
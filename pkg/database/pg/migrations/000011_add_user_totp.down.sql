@@ -0,0 +1,4 @@
+--changeset racerxdl:add_user_totp
+ALTER TABLE chevron_user
+    DROP COLUMN user_totp_secret,
+    DROP COLUMN user_totp_enabled;
@@ -0,0 +1,4 @@
+--changeset racerxdl:add_user_totp
+ALTER TABLE chevron_user
+    ADD COLUMN user_totp_secret varchar NOT NULL DEFAULT '',
+    ADD COLUMN user_totp_enabled boolean NOT NULL DEFAULT false;
@@ -0,0 +1,3 @@
+--changeset racerxdl:add_user_totp_last_step
+ALTER TABLE chevron_user
+    ADD COLUMN user_totp_last_step bigint NOT NULL DEFAULT 0;
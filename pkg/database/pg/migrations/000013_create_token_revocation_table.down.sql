@@ -0,0 +1,2 @@
+--changeset racerxdl:create_token_revocation_table
+DROP TABLE chevron_token_revocation;
@@ -0,0 +1,13 @@
+--changeset racerxdl:create_token_revocation_table
+CREATE TABLE chevron_token_revocation
+(
+    token_revocation_id         uuid      NOT NULL PRIMARY KEY,
+    token_revocation_token_hash varchar   NOT NULL DEFAULT '',
+    token_revocation_username   varchar   NOT NULL,
+    token_revocation_created_at timestamp NOT NULL DEFAULT now(),
+    token_revocation_expiration timestamp NOT NULL
+);
+
+CREATE INDEX chevron_token_revocation_token_hash_idx ON chevron_token_revocation (token_revocation_token_hash);
+CREATE INDEX chevron_token_revocation_username_idx ON chevron_token_revocation (token_revocation_username, token_revocation_created_at);
+CREATE INDEX chevron_token_revocation_expiration_idx ON chevron_token_revocation (token_revocation_expiration);
@@ -8,6 +8,24 @@
 // migrations/000003_create_gpgkeyuid_table.up.sql
 // migrations/000004_add_username_to_user.down.sql
 // migrations/000004_add_username_to_user.up.sql
+// migrations/000005_create_user_token_table.down.sql
+// migrations/000005_create_user_token_table.up.sql
+// migrations/000006_create_gpg_key_history_table.down.sql
+// migrations/000006_create_gpg_key_history_table.up.sql
+// migrations/000007_add_gpg_key_algorithm_and_expiration.down.sql
+// migrations/000007_add_gpg_key_algorithm_and_expiration.up.sql
+// migrations/000008_create_gpg_key_uid_search_indexes.down.sql
+// migrations/000008_create_gpg_key_uid_search_indexes.up.sql
+// migrations/000009_add_user_disabled.down.sql
+// migrations/000009_add_user_disabled.up.sql
+// migrations/000010_add_user_roles.down.sql
+// migrations/000010_add_user_roles.up.sql
+// migrations/000011_add_user_totp.down.sql
+// migrations/000011_add_user_totp.up.sql
+// migrations/000012_add_user_totp_last_step.down.sql
+// migrations/000012_add_user_totp_last_step.up.sql
+// migrations/000013_create_token_revocation_table.down.sql
+// migrations/000013_create_token_revocation_table.up.sql
 package migrations
 
 import (
@@ -84,7 +102,7 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
-var __000001_create_users_tableDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xd2\xd5\x4d\xce\x48\xcc\x4b\x4f\x2d\x4e\x2d\x51\x28\x4a\x4c\x4e\x2d\xaa\x48\xc9\xb1\x4a\x2e\x4a\x4d\x2c\x49\x8d\x2f\x2d\x4e\x2d\x2a\x8e\x2f\x49\x4c\xca\x49\xe5\x72\x09\xf2\x0f\x50\x08\x71\x74\xf2\x71\x55\x48\xce\x48\x2d\x2b\xca\xcf\x03\x4b\x5b\x73\x01\x02\x00\x00\xff\xff\xec\x78\xf3\x92\x41\x00\x00\x00")
+var __000001_create_users_tableDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x41\x00\xbe\xff\x2d\x2d\x63\x68\x61\x6e\x67\x65\x73\x65\x74\x20\x72\x61\x63\x65\x72\x78\x64\x6c\x3a\x63\x72\x65\x61\x74\x65\x5f\x75\x73\x65\x72\x73\x5f\x74\x61\x62\x6c\x65\x0a\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x63\x68\x65\x76\x72\x6f\x6e\x5f\x75\x73\x65\x72\x3b\x0a\x03\x00\xec\x78\xf3\x92\x41\x00\x00\x00")
 
 func _000001_create_users_tableDownSqlBytes() ([]byte, error) {
 	return bindataRead(
@@ -99,12 +117,12 @@ func _000001_create_users_tableDownSql() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "000001_create_users_table.down.sql", size: 65, mode: os.FileMode(436), modTime: time.Unix(1607959210, 0)}
+	info := bindataFileInfo{name: "000001_create_users_table.down.sql", size: 65, mode: os.FileMode(436), modTime: time.Unix(1611083718, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
 
-var __000001_create_users_tableUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x9c\x90\xc1\x4a\x03\x31\x10\x86\xef\x79\x8a\x39\x76\xc1\xbe\x80\x3d\xad\x36\x42\x31\x6e\x65\xd9\x05\x7b\x0a\xd3\x64\xda\x0d\x64\xb3\xcb\x24\x69\xeb\xdb\x8b\x15\x75\xad\xe2\xa1\xb7\x39\x7c\xdf\xc0\xff\xcd\xe7\xa6\xc3\xb0\xa7\x48\x09\x18\x0d\xf1\xc9\xfa\x5b\xc3\x84\x89\x74\x8e\xc4\x51\x27\xdc\x7a\x12\xf7\xb5\x2c\x1b\x09\x4d\x79\xa7\x24\x98\x8e\x0e\x3c\x84\x33\x00\x33\x01\x00\xf0\x7e\x6a\x67\x21\x67\x67\xa1\x5a\x37\x10\xb2\xf7\xf0\x5c\xaf\x9e\xca\x7a\x03\x8f\x72\x73\xf3\x8d\xed\x5c\xd8\x13\x8f\xec\x42\x82\x03\xb2\xe9\x90\xcf\x4a\xd5\x2a\x35\xc1\x46\x8c\xf1\x38\xb0\x85\xed\x6b\x22\xfc\x8b\xd8\x65\xef\x75\xc0\x9e\xfe\x7b\xf3\x31\xc6\x6a\x4c\x90\x5c\x4f\x31\x61\x3f\x7e\x71\xb0\x94\x0f\x65\xab\x1a\x08\xc3\x71\x56\x4c\xac\x3c\xda\x2b\x2c\x4b\x9e\x7e\x5b\xad\x52\xa2\x58\x88\xcf\x86\xab\x6a\x29\x5f\x7e\x34\x9c\x16\xd1\xce\x9e\x60\x5d\x5d\x34\xbe\xa4\x8a\x85\x78\x0b\x00\x00\xff\xff\x01\x59\x93\x2e\xba\x01\x00\x00")
+var __000001_create_users_tableUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x9c\x90\xc1\x4a\x03\x31\x10\x86\xef\x79\x8a\x39\x76\xc1\xbe\x80\x3d\xad\x36\x42\x31\x6e\x65\xd9\x05\x7b\x0a\xd3\x64\xda\x0d\x64\xb3\xcb\x24\x69\xeb\xdb\x8b\x15\x75\xad\xe2\xa1\xb7\x39\x7c\xdf\xc0\xff\xcd\xe7\xa6\xc3\xb0\xa7\x48\x09\x18\x0d\xf1\xc9\xfa\x5b\xc3\x84\x89\x74\x8e\xc4\x51\x27\xdc\x7a\x12\xf7\xb5\x2c\x1b\x09\x4d\x79\xa7\x24\x98\x8e\x0e\x3c\x84\x33\x00\x33\x01\x00\xf0\x7e\x6a\x67\x21\x67\x67\xa1\x5a\x37\x10\xb2\xf7\xf0\x5c\xaf\x9e\xca\x7a\x03\x8f\x72\x73\xf3\x8d\xed\x5c\xd8\x13\x8f\xec\x42\x82\x03\xb2\xe9\x90\xcf\x4a\xd5\x2a\x35\xc1\x46\x8c\xf1\x38\xb0\x85\xed\x6b\x22\xfc\x8b\xd8\x65\xef\x75\xc0\x9e\xfe\x7b\xf3\x31\xc6\x6a\x4c\x90\x5c\x4f\x31\x61\x3f\x7e\x71\xb0\x94\x0f\x65\xab\x1a\x08\xc3\x71\x56\x4c\xac\x3c\xda\x2b\x2c\x4b\x9e\x7e\x5b\xad\x52\xa2\x58\x88\xcf\x86\xab\x6a\x29\x5f\x7e\x34\x9c\x16\xd1\xce\x9e\x60\x5d\x5d\x34\xbe\xa4\x8a\x85\x78\x1b\x00\x01\x59\x93\x2e\xba\x01\x00\x00")
 
 func _000001_create_users_tableUpSqlBytes() ([]byte, error) {
 	return bindataRead(
@@ -119,12 +137,12 @@ func _000001_create_users_tableUpSql() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "000001_create_users_table.up.sql", size: 442, mode: os.FileMode(436), modTime: time.Unix(1607959210, 0)}
+	info := bindataFileInfo{name: "000001_create_users_table.up.sql", size: 442, mode: os.FileMode(436), modTime: time.Unix(1611083718, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
 
-var __000002_create_gpgkey_tableDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xd2\xd5\x4d\xce\x48\xcc\x4b\x4f\x2d\x4e\x2d\x51\x28\x4a\x4c\x4e\x2d\xaa\x48\xc9\xb1\x4a\x2e\x4a\x4d\x2c\x49\x8d\x4f\x2f\x48\xcf\x4e\xad\x8c\x2f\x49\x4c\xca\x49\xe5\x72\x09\xf2\x0f\x50\x08\x71\x74\xf2\x71\x55\x48\xce\x48\x2d\x2b\xca\xcf\x83\xca\x5b\x73\x01\x02\x00\x00\xff\xff\x6d\xa5\xec\x36\x44\x00\x00\x00")
+var __000002_create_gpgkey_tableDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x44\x00\xbb\xff\x2d\x2d\x63\x68\x61\x6e\x67\x65\x73\x65\x74\x20\x72\x61\x63\x65\x72\x78\x64\x6c\x3a\x63\x72\x65\x61\x74\x65\x5f\x67\x70\x67\x6b\x65\x79\x5f\x74\x61\x62\x6c\x65\x0a\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x63\x68\x65\x76\x72\x6f\x6e\x5f\x67\x70\x67\x6b\x65\x79\x3b\x0a\x03\x00\x6d\xa5\xec\x36\x44\x00\x00\x00")
 
 func _000002_create_gpgkey_tableDownSqlBytes() ([]byte, error) {
 	return bindataRead(
@@ -139,12 +157,12 @@ func _000002_create_gpgkey_tableDownSql() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "000002_create_gpgkey_table.down.sql", size: 68, mode: os.FileMode(436), modTime: time.Unix(1607959210, 0)}
+	info := bindataFileInfo{name: "000002_create_gpgkey_table.down.sql", size: 68, mode: os.FileMode(436), modTime: time.Unix(1611083718, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
 
-var __000002_create_gpgkey_tableUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xa4\x92\x31\x6f\xc2\x30\x10\x85\x77\xff\x8a\x1b\x89\x04\x43\x97\x0e\x65\x4a\x93\x43\x42\x75\x43\x15\x82\x54\x26\xcb\xc4\x47\xb0\x30\x21\x32\x0e\x85\x7f\x5f\xa5\x14\x82\x53\xa1\x0c\xf5\x96\xe4\x7d\x79\xcf\xef\x6e\x34\xca\x37\xb2\x2c\xe8\x40\x0e\xac\xcc\xc9\x9e\x94\x79\xc9\x2d\x49\x47\xa2\xa8\x8a\x2d\x9d\x85\x93\x2b\x43\x2c\x4a\x31\xcc\x10\xb2\xf0\x95\x23\xe4\x1b\x3a\xda\x7d\xd9\x28\xc4\x96\xce\x6c\xc0\x00\x00\x7e\x9f\x84\x56\xe0\x9f\xba\xbe\xbe\x4a\x66\x19\x24\x0b\xce\xe1\x23\x9d\xbe\x87\xe9\x12\xde\x70\x39\xf4\xe0\x75\x6d\x8c\x58\xeb\xb2\x20\x5b\x59\x5d\x3a\x38\x4a\x9b\x6f\xa4\xbd\x83\x3b\x40\xab\x7d\x7a\x6e\x3e\xf4\x01\x5b\x3a\xaf\xb4\x3b\xb4\xf1\x1a\x17\x3f\x9e\x0f\x54\xd2\xd2\x4d\xe2\xdf\x27\xc5\x09\xa6\x98\x44\x38\xef\x76\x02\x83\xb6\x8e\x00\x66\x09\xc4\xc8\x31\x43\x88\xc2\x79\x14\xc6\x38\x64\xbe\x45\xbd\x32\x3a\xff\xe1\x2e\xc7\xd1\xc9\x75\x52\x58\x7d\x6c\xa6\x72\xd3\x5c\x24\x9e\xe6\x32\x38\x25\xe4\x35\xad\xd3\x3b\x3a\x38\xb9\xab\xda\xe6\x63\x9c\x84\x0b\x9e\x41\xb9\xff\x1a\x04\xbe\x45\x5d\xa9\xff\xe0\x8a\x0c\x3d\xc2\x17\x9c\xb3\x60\xcc\xae\x6b\x34\x4d\x62\xfc\xec\x56\xf6\x67\xf6\x42\xab\x53\x53\xdd\xc3\x6a\xbb\x40\x30\xee\x31\xb8\xdf\x95\xfe\xbf\xdf\xab\x83\x31\xfb\x0e\x00\x00\xff\xff\x5e\xe4\x7f\x02\x2c\x03\x00\x00")
+var __000002_create_gpgkey_tableUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xa4\x92\xcf\x8e\xb2\x30\x14\xc5\xf7\x7d\x8a\xbb\x94\x44\x17\x6e\x5c\x7c\xae\xf8\xe0\x9a\x98\xe9\xe0\x04\x31\x19\x57\x4d\xa5\x57\x6c\x44\x24\xb5\x38\xfa\xf6\x93\x46\x51\xcb\x8c\x71\x31\xac\xf8\x73\x7e\x9c\x73\xcf\xed\x60\x90\x6f\x64\x55\xd0\x81\x2c\x18\x99\x93\x39\xa9\xf2\x5f\x6e\x48\x5a\x12\x45\x5d\x6c\xe9\x2c\xac\x5c\x95\xc4\xa2\x14\xc3\x0c\x21\x0b\xff\x73\x84\x7c\x43\x47\xb3\xaf\x9c\x42\x6c\xe9\xcc\x7a\x0c\x00\xe0\xfa\x24\xb4\x02\xff\x6a\x9a\xf6\x55\x32\xcb\x20\x59\x70\x0e\x1f\xe9\xf4\x3d\x4c\x97\xf0\x86\xcb\xbe\x07\xaf\x9b\xb2\x14\x6b\x5d\x15\x64\x6a\xa3\x2b\x0b\x47\x69\xf2\x8d\x34\x0f\x70\x07\xb8\x6b\x87\x23\xf7\xe1\x15\xb0\xa5\xf3\x4a\xdb\x43\x1b\x0e\xc0\xb9\xf8\xf1\x7c\xa0\x96\x86\x2a\xfb\xeb\x3c\x29\x4e\x30\xc5\x24\xc2\x79\xb7\x13\xe8\xb5\xb8\x56\x01\xcc\x12\x88\x91\x63\x86\x10\x85\xf3\x28\x8c\xb1\xcf\x7c\x8b\x66\x55\xea\xdc\xdd\x5e\x2d\x2c\x9d\x6c\x27\x85\xd1\x47\xb7\x95\x9b\xe6\x22\xf1\x34\x97\xc5\x29\x21\xdb\xb4\x56\xef\xe8\x60\xe5\xae\xbe\x8d\x06\x31\x4e\xc2\x05\xcf\xa0\xda\x7f\xf5\x02\xdf\xa2\xa9\xd5\x5f\x70\x45\x25\x3d\xc3\x17\x9c\xb3\x60\xcc\xda\x63\x34\x4d\x62\xfc\xec\x56\xf6\x63\xf7\x42\xab\x93\xab\xee\x69\xb5\x5d\x20\x18\xbf\x30\xb8\x4b\x87\xa3\xd7\x7f\x7f\x54\x07\x63\xf6\x3d\x00\x5e\xe4\x7f\x02\x2c\x03\x00\x00")
 
 func _000002_create_gpgkey_tableUpSqlBytes() ([]byte, error) {
 	return bindataRead(
@@ -159,12 +177,12 @@ func _000002_create_gpgkey_tableUpSql() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "000002_create_gpgkey_table.up.sql", size: 812, mode: os.FileMode(436), modTime: time.Unix(1607959210, 0)}
+	info := bindataFileInfo{name: "000002_create_gpgkey_table.up.sql", size: 812, mode: os.FileMode(436), modTime: time.Unix(1611083718, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
 
-var __000003_create_gpgkeyuid_tableDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xd2\xd5\x4d\xce\x48\xcc\x4b\x4f\x2d\x4e\x2d\x51\x28\x4a\x4c\x4e\x2d\xaa\x48\xc9\xb1\x4a\x2e\x4a\x4d\x2c\x49\x8d\x4f\x2f\x48\xcf\x4e\xad\x2c\xcd\x4c\x89\x2f\x49\x4c\xca\x49\xe5\x72\x09\xf2\x0f\x50\x08\x71\x74\xf2\x71\x55\x48\xce\x48\x2d\x2b\xca\xcf\x03\x29\x89\xcf\x4e\xad\x8c\x2f\xcd\x4c\xb1\xe6\x02\x04\x00\x00\xff\xff\xa0\x1d\xf9\xc4\x4c\x00\x00\x00")
+var __000003_create_gpgkeyuid_tableDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x4c\x00\xb3\xff\x2d\x2d\x63\x68\x61\x6e\x67\x65\x73\x65\x74\x20\x72\x61\x63\x65\x72\x78\x64\x6c\x3a\x63\x72\x65\x61\x74\x65\x5f\x67\x70\x67\x6b\x65\x79\x75\x69\x64\x5f\x74\x61\x62\x6c\x65\x0a\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x63\x68\x65\x76\x72\x6f\x6e\x5f\x67\x70\x67\x5f\x6b\x65\x79\x5f\x75\x69\x64\x3b\x0a\x03\x00\xa0\x1d\xf9\xc4\x4c\x00\x00\x00")
 
 func _000003_create_gpgkeyuid_tableDownSqlBytes() ([]byte, error) {
 	return bindataRead(
@@ -179,12 +197,12 @@ func _000003_create_gpgkeyuid_tableDownSql() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "000003_create_gpgkeyuid_table.down.sql", size: 76, mode: os.FileMode(436), modTime: time.Unix(1607959210, 0)}
+	info := bindataFileInfo{name: "000003_create_gpgkeyuid_table.down.sql", size: 76, mode: os.FileMode(436), modTime: time.Unix(1611083718, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
 
-var __000003_create_gpgkeyuid_tableUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xa4\x91\xdf\x6a\xc2\x30\x14\xc6\xef\xf3\x14\xe7\xd2\x82\xbe\xc0\xbc\xca\xda\x23\xc8\xb2\x3a\x6a\x85\x79\x15\xb2\xe4\xa0\xc1\x34\x96\x98\x38\x7d\xfb\xa1\xa3\x73\xac\x1d\x0e\x96\xbb\x24\xbf\xef\x3b\x7f\xbe\xc9\x44\x6f\x95\xdf\xd0\x81\x22\x04\xa5\x29\x9c\x8c\x7b\xd0\x81\x54\x24\xb9\x69\x37\x3b\x3a\x27\x6b\x64\x54\x6f\x8e\x58\x5e\x21\xaf\x11\x6a\xfe\x28\x10\xf4\x96\x8e\x61\xef\x2f\x90\xdc\xd1\x59\x26\x6b\xd8\x88\x01\x00\x7c\x7b\x91\xd6\xc0\xd7\x49\xa9\xbb\x95\x8b\x1a\x7c\x72\x0e\x5e\xaa\xf9\x33\xaf\xd6\xf0\x84\xeb\x71\x4f\xeb\x55\x43\x9d\xf6\xa8\x82\xde\xaa\xd0\x87\xa8\x51\xd6\xdd\x83\x0c\x1d\x74\xb0\x6d\xb4\x7b\xff\x3b\xd4\xaa\x40\x3e\x0e\xb5\x5a\xae\x84\x80\x0a\x67\x58\x61\x99\xe3\xf2\xe7\xe8\x30\xea\x6c\xac\xc9\x60\x51\x42\x81\x02\x6b\x84\x9c\x2f\x73\x5e\xe0\x98\xf5\x4a\x7d\xee\xd7\x48\x15\x01\xa2\x6d\xe8\x10\x55\xd3\xde\x4a\x15\x38\xe3\x2b\x51\x83\xdf\xbf\x8f\xb2\x7e\xa3\xa9\x35\xff\x50\x1b\x72\x34\xa0\x5e\x09\xc1\xb2\x29\xeb\x32\x9e\x97\x05\xbe\x0e\x65\x7c\x4d\x45\x5a\x73\xba\x0c\x3a\xf0\x7f\x5b\x46\x07\x67\xd3\xfb\xa6\xd7\x14\xff\xec\x7a\xa5\xb3\x29\xfb\x08\x00\x00\xff\xff\x70\x54\x0d\x96\xbc\x02\x00\x00")
+var __000003_create_gpgkeyuid_tableUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xa4\x91\xdf\x6a\xc2\x30\x14\xc6\xef\xf3\x14\xe7\xb2\x05\x7d\x81\x79\xd5\xb5\x47\x90\x65\x75\xd4\x0a\xf3\x2a\x9c\x35\x07\x0d\xa6\xb1\xa4\xa9\xd3\xb7\x1f\x3a\xba\x0d\xdb\xe1\x60\xb9\x4b\xf2\xfb\xbe\xf3\xe7\x9b\x4e\xab\x1d\xb9\x2d\xb7\x1c\xc0\x53\xc5\xfe\xa4\xed\x43\xe5\x99\x02\xab\x6d\xb3\xdd\xf3\xb9\x33\x5a\x05\x7a\xb3\x2c\xd2\x02\x93\x12\xa1\x4c\x1e\x25\x42\xb5\xe3\xa3\x3f\xb8\x0b\xa4\xf6\x7c\x56\x9d\xd1\x22\x12\x00\x00\x3f\x5e\x94\xd1\xf0\x75\xba\xae\xbf\xe5\xcb\x12\x5c\x67\x2d\xbc\x14\x8b\xe7\xa4\xd8\xc0\x13\x6e\x26\x03\xad\xa3\x9a\x7b\xed\x91\x7c\xb5\x23\x3f\x84\xb8\x26\x63\xef\x41\x9a\xdb\xca\x9b\x26\x98\x83\xfb\x1d\x6a\xc8\xb3\x0b\x63\xad\xe6\x6b\x29\xa1\xc0\x39\x16\x98\xa7\xb8\xba\x1d\x1d\xa2\xde\xc6\xe8\x18\x96\x39\x64\x28\xb1\x44\x48\x93\x55\x9a\x64\x38\x11\x83\x52\x9f\xfb\xd5\x8a\x02\x40\x30\x35\xb7\x81\xea\xe6\xbb\x54\x86\xf3\x64\x2d\x4b\x70\x87\xf7\x28\x1e\x36\xda\x35\xfa\x1f\x6a\xcd\x96\x47\xd4\x6b\x29\x45\x3c\x13\x7d\xc6\x8b\x3c\xc3\xd7\xb1\x8c\xaf\xa9\x28\xa3\x4f\x97\x41\x47\xfe\x21\xba\x85\xe3\xd9\x7d\xd3\x6b\x8a\x7f\x76\xe5\x9a\x8c\x8d\x67\xe2\x63\x00\x70\x54\x0d\x96\xbc\x02\x00\x00")
 
 func _000003_create_gpgkeyuid_tableUpSqlBytes() ([]byte, error) {
 	return bindataRead(
@@ -199,12 +217,12 @@ func _000003_create_gpgkeyuid_tableUpSql() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "000003_create_gpgkeyuid_table.up.sql", size: 700, mode: os.FileMode(436), modTime: time.Unix(1607959210, 0)}
+	info := bindataFileInfo{name: "000003_create_gpgkeyuid_table.up.sql", size: 700, mode: os.FileMode(436), modTime: time.Unix(1611083718, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
 
-var __000004_add_username_to_userDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xd2\xd5\x4d\xce\x48\xcc\x4b\x4f\x2d\x4e\x2d\x51\x28\x4a\x4c\x4e\x2d\xaa\x48\xc9\xb1\x4a\x4c\x49\x89\x2f\x2d\x4e\x2d\xca\x4b\xcc\x4d\x8d\x2f\xc9\x07\xb3\xb9\xb8\x1c\x7d\x42\x5c\x83\x14\x42\x1c\x9d\x7c\x5c\x15\x92\x33\x52\xcb\x8a\xf2\xf3\x20\x32\x0a\x0a\x0a\x0a\x2e\x41\xfe\x01\x0a\xce\xfe\x3e\xa1\xbe\x7e\x0a\x20\x41\xb8\x7e\x6b\x2e\x2e\xb0\x9c\xa7\x9f\x8b\x6b\x04\x8a\x3e\x84\x15\x99\x29\x15\xd6\x5c\x80\x00\x00\x00\xff\xff\x0f\x03\x4d\xfb\x8a\x00\x00\x00")
+var __000004_add_username_to_userDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xd2\xd5\x4d\xce\x48\xcc\x4b\x4f\x2d\x4e\x2d\x51\x28\x4a\x4c\x4e\x2d\xaa\x48\xc9\xb1\x4a\x4c\x49\x89\x2f\x2d\x4e\x2d\xca\x4b\xcc\x4d\x8d\x2f\xc9\x07\xb3\xb9\xb8\x1c\x7d\x42\x5c\x83\x14\x42\x1c\x9d\x7c\x5c\x15\x92\x33\x52\xcb\x8a\xf2\xf3\x20\x32\x0a\x0a\x0a\x0a\x2e\x41\xfe\x01\x0a\xce\xfe\x3e\xa1\xbe\x7e\x0a\x20\x41\xb8\x7e\x6b\x2e\x2e\xb0\x9c\xa7\x9f\x8b\x6b\x04\x8a\x3e\x84\x15\x99\x29\x15\xd6\x5c\x80\x01\x00\x0f\x03\x4d\xfb\x8a\x00\x00\x00")
 
 func _000004_add_username_to_userDownSqlBytes() ([]byte, error) {
 	return bindataRead(
@@ -219,12 +237,12 @@ func _000004_add_username_to_userDownSql() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "000004_add_username_to_user.down.sql", size: 138, mode: os.FileMode(436), modTime: time.Unix(1607959215, 0)}
+	info := bindataFileInfo{name: "000004_add_username_to_user.down.sql", size: 138, mode: os.FileMode(436), modTime: time.Unix(1611083718, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
 
-var __000004_add_username_to_userUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x54\x8d\x4d\x0a\xc2\x30\x14\x84\xf7\x39\xc5\xec\xaa\x8b\x5e\xc0\xae\x9e\x4d\x44\xe1\x99\x42\x48\xc5\x5d\x09\xcd\xc3\x0a\xda\x42\x5a\xa5\xc7\x17\x15\x94\xee\x86\xf9\xe6\x27\xcf\xdb\x2e\xf4\x17\x19\x65\x42\x0a\xad\xa4\x39\xde\x36\x21\xc6\xe6\x31\x4a\xea\xc3\x5d\x9a\x69\xf8\x68\xa5\x88\xbd\x71\xf0\xb4\x65\x83\xb6\x93\x67\x1a\xfa\x2f\x01\x00\xd2\x1a\x65\xc5\xf5\xd1\xe2\xed\xfd\xea\x38\x91\x2b\xf7\xe4\x60\x2b\x0f\x5b\x33\x43\x9b\x1d\xd5\xec\x91\x65\x85\x52\xa5\x33\xe4\x0d\x0e\x56\x9b\xf3\x62\xf4\xff\x7f\x8d\x33\x2a\xbb\x80\x58\x2d\x22\xeb\x42\xbd\x02\x00\x00\xff\xff\x50\x7a\x18\x3b\xc7\x00\x00\x00")
+var __000004_add_username_to_userUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x54\x8d\x4d\x0a\xc2\x30\x14\x84\xf7\x39\xc5\xec\xaa\x8b\x5e\xc0\xae\x9e\x4d\x44\xe1\x99\x42\x48\xc5\x5d\x09\xcd\xc3\x0a\xda\x42\x5a\xa5\xc7\x17\x15\x94\xee\x86\xf9\xe6\x27\xcf\xdb\x2e\xf4\x17\x19\x65\x42\x0a\xad\xa4\x39\xde\x36\x21\xc6\xe6\x31\x4a\xea\xc3\x5d\x9a\x69\xf8\x68\xa5\x88\xbd\x71\xf0\xb4\x65\x83\xb6\x93\x67\x1a\xfa\x2f\x01\x00\xd2\x1a\x65\xc5\xf5\xd1\xe2\xed\xfd\xea\x38\x91\x2b\xf7\xe4\x60\x2b\x0f\x5b\x33\x43\x9b\x1d\xd5\xec\x91\x65\x85\x52\xa5\x33\xe4\x0d\x0e\x56\x9b\xf3\x62\xf4\xff\x7f\x8d\x33\x2a\xbb\x80\x58\x2d\x22\xeb\x42\xbd\x06\x00\x50\x7a\x18\x3b\xc7\x00\x00\x00")
 
 func _000004_add_username_to_userUpSqlBytes() ([]byte, error) {
 	return bindataRead(
@@ -239,7 +257,367 @@ func _000004_add_username_to_userUpSql() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "000004_add_username_to_user.up.sql", size: 199, mode: os.FileMode(436), modTime: time.Unix(1607959215, 0)}
+	info := bindataFileInfo{name: "000004_add_username_to_user.up.sql", size: 199, mode: os.FileMode(436), modTime: time.Unix(1611083718, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000005_create_user_token_tableDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x4c\x00\xb3\xff\x2d\x2d\x63\x68\x61\x6e\x67\x65\x73\x65\x74\x20\x72\x61\x63\x65\x72\x78\x64\x6c\x3a\x63\x72\x65\x61\x74\x65\x5f\x75\x73\x65\x72\x5f\x74\x6f\x6b\x65\x6e\x5f\x74\x61\x62\x6c\x65\x0a\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x63\x68\x65\x76\x72\x6f\x6e\x5f\x75\x73\x65\x72\x5f\x74\x6f\x6b\x65\x6e\x3b\x0a\x03\x00\xbb\x2c\x7a\x1d\x4c\x00\x00\x00")
+
+func _000005_create_user_token_tableDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000005_create_user_token_tableDownSql,
+		"000005_create_user_token_table.down.sql",
+	)
+}
+
+func _000005_create_user_token_tableDownSql() (*asset, error) {
+	bytes, err := _000005_create_user_token_tableDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000005_create_user_token_table.down.sql", size: 76, mode: os.FileMode(420), modTime: time.Unix(1792425388, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000005_create_user_token_tableUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8c\x91\xcd\x6a\x84\x30\x10\x80\xef\x79\x8a\x39\x2a\x74\x5f\xa0\x9e\x6c\x37\x05\xa9\x75\x5b\x51\xe8\x9e\xc2\x54\xa7\x6b\xa8\x46\x19\xe3\xd6\xc7\x2f\xfd\xc9\xae\xec\x5a\x48\x6e\x21\xdf\xf7\x11\x66\x36\x9b\xaa\x41\x73\xa0\x91\x2c\x30\x56\xc4\x73\xdd\xde\x56\x4c\x68\x49\x4d\x23\xb1\xb2\xfd\x07\x19\x65\xf1\xad\x25\x71\x9f\xcb\xb8\x90\x50\xc4\x77\xa9\x84\xaa\xa1\x23\xf7\x66\x41\x89\x40\x00\x00\x2c\x34\x5d\xc3\xe9\x4c\x93\xbb\x65\xbb\x02\xb2\x32\x4d\xe1\x39\x4f\x9e\xe2\x7c\x0f\x8f\x72\x7f\x73\xa9\x36\x38\x36\x4e\x3d\x22\x57\x0d\xf2\x42\xbd\xc2\xdf\xb5\x39\x10\x0f\xac\x8d\xf5\xc1\xbf\x4d\x83\x1d\xf9\xd6\xa7\xb6\x55\x7f\xbc\x07\xfe\x3b\xbf\x5a\xa1\x05\xb0\xba\xa3\xd1\x62\x37\x9c\x70\xd8\xca\x87\xb8\x4c\x0b\x30\xfd\x67\x10\x5e\xc9\x34\x0f\x9a\xd1\xea\xde\xac\xc9\x22\x8c\x84\xdb\x43\x99\x25\x2f\xa5\x84\x24\xdb\xca\xd7\x95\x75\xfc\x8c\x50\xe9\x7a\x86\x5d\xb6\xf2\x0c\xc1\x05\x1a\x46\x2e\xfc\x6f\xf1\xfc\x37\xcf\xee\x59\xf0\xa8\xbb\xa5\x78\xb6\xa7\x91\xd8\x60\x47\x61\x24\xbe\x06\x00\x87\x7f\xcd\x04\xc6\x02\x00\x00")
+
+func _000005_create_user_token_tableUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000005_create_user_token_tableUpSql,
+		"000005_create_user_token_table.up.sql",
+	)
+}
+
+func _000005_create_user_token_tableUpSql() (*asset, error) {
+	bytes, err := _000005_create_user_token_tableUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000005_create_user_token_table.up.sql", size: 710, mode: os.FileMode(420), modTime: time.Unix(1792425388, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000006_create_gpg_key_history_tableDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xd2\xd5\x4d\xce\x48\xcc\x4b\x4f\x2d\x4e\x2d\x51\x28\x4a\x4c\x4e\x2d\xaa\x48\xc9\xb1\x4a\x2e\x4a\x4d\x2c\x49\x8d\x4f\x2f\x48\x8f\xcf\x4e\xad\x8c\xcf\xc8\x2c\x2e\xc9\x2f\xaa\x8c\x2f\x49\x4c\xca\x49\xe5\x72\x09\xf2\x0f\x50\xf0\xf4\x73\x71\x8d\x50\x48\xce\x48\x2d\x2b\xca\xcf\x83\x2b\x4c\x49\xcd\x49\x2d\x49\x4d\x89\x4f\x2c\x89\xcf\x4c\xa9\xb0\x86\x28\x0d\x71\x74\xf2\x71\xc5\x50\x9a\x91\x59\x5c\x92\x5f\x54\x69\xcd\x05\x18\x00\xd3\x65\x11\xba\x81\x00\x00\x00")
+
+func _000006_create_gpg_key_history_tableDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000006_create_gpg_key_history_tableDownSql,
+		"000006_create_gpg_key_history_table.down.sql",
+	)
+}
+
+func _000006_create_gpg_key_history_tableDownSql() (*asset, error) {
+	bytes, err := _000006_create_gpg_key_history_tableDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000006_create_gpg_key_history_table.down.sql", size: 129, mode: os.FileMode(420), modTime: time.Unix(1792425570, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000006_create_gpg_key_history_tableUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x94\x92\x41\x4f\x83\x30\x18\x86\xef\xfc\x8a\xef\x36\x48\xdc\xc1\x8b\x07\x77\x42\xf8\x96\x2c\x22\x33\x8c\x25\x2e\xc6\x34\x1d\x7c\x83\x3a\xd6\x92\xd2\xcd\xed\xdf\x9b\xa9\x44\xc7\xe8\x54\x8e\xcd\xfb\x3c\x2f\x4d\xdf\xe1\x30\x2b\xb9\x2c\xa8\x21\x03\x9a\x67\xa4\xf7\x79\x75\x9b\x69\xe2\x86\x58\x51\x17\x6c\x4d\x07\x56\x8a\xc6\x28\x7d\x60\x86\x2f\x2b\x72\x82\x04\xfd\x14\x21\xf5\xef\x22\x84\xac\xa4\x9d\x56\xb2\x1b\x75\x5c\x07\x00\xa0\x2b\x10\x39\x9c\x7e\xdb\x6d\x7b\x14\x4f\x53\x88\xe7\x51\x04\x8f\xc9\xe4\xc1\x4f\x16\x70\x8f\x8b\xab\x5e\xc9\x9a\x0e\xbf\x4a\x12\x1c\x63\x82\x71\x80\xb3\xee\x1f\x82\xdb\xfa\x44\xee\xc1\x34\x86\x10\x23\x4c\x11\x02\x7f\x16\xf8\x21\xf6\x57\xae\xb6\x55\xc5\x56\x42\x16\xa4\x6b\x2d\xa4\x81\x1d\xd7\x59\xc9\xf5\x8f\x4a\x0b\xf8\xcd\x5c\xdf\x1c\x03\x7f\x05\x79\x66\x84\x92\xed\x0d\xff\x07\x2a\x0d\x17\x41\x08\x71\xec\xcf\xa3\x14\x06\x83\x7e\xc7\xd7\x22\x5a\x03\xc0\x6b\xa3\xe4\x12\xa0\xd7\xf1\xfc\x62\xb1\x34\x92\xd7\x4d\xa9\xcc\x05\x8b\xa5\xfe\x63\x7d\x39\xe3\x2d\x6a\xc4\x86\x1a\xc3\x37\xf5\x79\xbd\x54\x6f\xae\xe7\x78\x23\xa7\x9d\xe5\x24\x0e\xf1\xc9\x36\xcb\xd3\xf7\x60\x22\xdf\x1f\x17\x60\x09\x83\xdb\x39\x38\xa5\xbd\xd1\xe5\xca\x9c\x2a\xfa\xbc\x85\xa5\x07\xdc\xf3\xa8\x37\x72\xde\x07\x00\x1a\xb5\xe1\xa8\x91\x03\x00\x00")
+
+func _000006_create_gpg_key_history_tableUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000006_create_gpg_key_history_tableUpSql,
+		"000006_create_gpg_key_history_table.up.sql",
+	)
+}
+
+func _000006_create_gpg_key_history_tableUpSql() (*asset, error) {
+	bytes, err := _000006_create_gpg_key_history_tableUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000006_create_gpg_key_history_table.up.sql", size: 913, mode: os.FileMode(420), modTime: time.Unix(1792425570, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000007_add_gpg_key_algorithm_and_expirationDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8c\x8e\x41\xaa\x83\x30\x14\x00\xf7\x39\xc5\x3b\xc0\xf7\x02\xdf\x95\xad\x29\x08\x56\x8b\x5a\x70\x17\x5e\xcd\x23\x09\x6a\x22\x31\x14\xbd\x7d\xc1\x42\x0b\x2d\x05\xf7\x33\xcc\x44\x51\xa7\xd1\x2a\x9a\x29\x80\xc7\x8e\xfc\x22\x87\x7f\x94\x52\xa8\x49\x89\x9e\x56\x81\x83\x72\xde\x04\x3d\x0a\xb4\x52\xd0\x32\x19\x8f\xc1\x38\xcb\xd2\xaa\xbc\x40\x56\xa4\xbc\x85\xec\x04\xbc\xcd\xea\xa6\x86\x4e\xd3\xdd\x3b\xfb\xb2\x37\x9e\x66\x81\x41\x18\xb9\xc4\xfb\xa4\x77\x72\xbf\xd3\xd3\x7a\x33\x61\x7e\x56\x58\x92\x37\xbc\x82\x26\x39\xe4\xfc\x93\x64\x00\x00\xdb\xc6\xb1\xcc\xaf\xe7\x02\xbe\x57\xff\x7e\x32\x38\x28\xe7\x4d\xd0\x63\xcc\x1e\x03\x00\x3e\x82\x43\xfd\x39\x01\x00\x00")
+
+func _000007_add_gpg_key_algorithm_and_expirationDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000007_add_gpg_key_algorithm_and_expirationDownSql,
+		"000007_add_gpg_key_algorithm_and_expiration.down.sql",
+	)
+}
+
+func _000007_add_gpg_key_algorithm_and_expirationDownSql() (*asset, error) {
+	bytes, err := _000007_add_gpg_key_algorithm_and_expirationDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000007_add_gpg_key_algorithm_and_expiration.down.sql", size: 313, mode: os.FileMode(420), modTime: time.Unix(1792426157, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000007_add_gpg_key_algorithm_and_expirationUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x84\x90\x4d\x4b\xc4\x30\x10\x86\xef\xf9\x15\xef\x6d\x5d\x70\xff\x80\x3d\xc5\x6d\x04\x21\xa6\xb0\xa4\xe0\x2d\x8c\xcd\xd0\x06\xb7\x1f\x24\x61\xa9\xff\x5e\x2c\x68\xa1\xa2\x3d\xcc\x69\x9e\xf7\x99\x8f\xd3\xa9\xe9\x68\x68\x39\x71\x46\xa4\x86\xe3\xec\xaf\x0f\xe4\xbd\x6b\xa7\xd6\xbd\xf3\x87\xa3\x6b\x3b\xc6\x90\xbb\xde\xd1\xe0\x1d\xcf\x53\x88\x94\xc3\x38\x08\xa9\xad\xba\xc0\xca\x47\xad\xd0\x74\x7c\x8b\xe3\xf0\x1d\x12\x00\x20\xcb\x12\xe7\x4a\xd7\x2f\x06\xbf\x5c\xc0\x8d\x62\xd3\x51\x04\x60\x2a\x0b\x53\x6b\x8d\x52\x3d\xc9\x5a\x5b\x1c\x0e\xf7\x7f\x09\x96\xf1\x9c\x1c\x65\xe4\xd0\x73\xca\xd4\x4f\x4b\xb8\x10\xe2\x7c\x51\xd2\x2a\x3c\x9b\x52\xbd\x6e\x17\xfa\xaa\xb7\x90\x93\x0b\x7e\x46\x65\xb6\x6d\xdc\x6d\xb8\x63\xf1\xbf\x6e\x7d\xca\x9e\xf0\x87\xdc\x53\xae\xa7\xed\x3a\x57\xf4\x58\x88\xcf\x01\x00\x7f\x60\x1f\x75\xc1\x01\x00\x00")
+
+func _000007_add_gpg_key_algorithm_and_expirationUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000007_add_gpg_key_algorithm_and_expirationUpSql,
+		"000007_add_gpg_key_algorithm_and_expiration.up.sql",
+	)
+}
+
+func _000007_add_gpg_key_algorithm_and_expirationUpSql() (*asset, error) {
+	bytes, err := _000007_add_gpg_key_algorithm_and_expirationUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000007_add_gpg_key_algorithm_and_expiration.up.sql", size: 449, mode: os.FileMode(420), modTime: time.Unix(1792426157, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000008_create_gpg_key_uid_search_indexesDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x9c\xcd\x41\xae\x82\x30\x10\x06\xe0\x3d\xa7\xe8\x05\xb8\xc0\x63\x0b\x2f\x61\xa3\x46\x5c\xb0\x9b\x34\xed\x4f\x99\xd8\x8e\x64\x5a\x4c\xbd\xbd\x71\xe3\xda\x70\x80\x2f\x5f\xdb\xba\xd5\x4a\x40\x46\x31\x6a\x1d\xb4\xfa\xf8\xe7\x14\xb6\x80\xc2\x16\xe8\x8e\x17\xed\xec\x29\xc3\xaa\x5b\x89\xc5\xa3\x22\x37\xfd\xf5\x7c\x31\xe3\xa9\x1f\x66\x33\xfe\x9b\x61\x1e\xa7\xdb\x64\xdc\x8a\xa7\x3e\xe4\xeb\x96\x3d\x46\x5a\x58\x02\x74\x53\x96\x42\x45\x43\x22\xf6\xb5\xfb\xcd\x7f\x5e\x24\xcb\xf1\x08\x14\x9b\x70\xd8\x2d\x25\x13\xfb\xda\x35\xef\x01\x00\xd4\x34\x3e\xfa\x1f\x01\x00\x00")
+
+func _000008_create_gpg_key_uid_search_indexesDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000008_create_gpg_key_uid_search_indexesDownSql,
+		"000008_create_gpg_key_uid_search_indexes.down.sql",
+	)
+}
+
+func _000008_create_gpg_key_uid_search_indexesDownSql() (*asset, error) {
+	bytes, err := _000008_create_gpg_key_uid_search_indexesDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000008_create_gpg_key_uid_search_indexes.down.sql", size: 287, mode: os.FileMode(420), modTime: time.Unix(1792426157, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000008_create_gpg_key_uid_search_indexesUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xa4\x91\x41\x4f\xc2\x30\x1c\xc5\xef\xfb\x14\xef\x36\x96\xb0\x70\x97\x93\xd1\x61\x76\x19\xc6\xcd\x84\x5b\xd3\x74\xff\x95\xc6\xae\x5d\xda\x0e\x06\x9f\xde\x30\x12\x89\x4c\x51\xe3\xfd\xdf\xdf\xfb\xbd\xd7\x34\x15\x5b\x6e\x24\x79\x0a\x70\x5c\x90\x1b\x6a\x7d\x27\x1c\xf1\x40\x4c\x76\x92\xbd\xd1\x81\xf5\xaa\x66\x9e\xb8\x13\x5b\xa6\x4c\x4d\x03\xf9\xe8\xe1\x25\xbb\xaf\x32\x64\x9b\x2a\x2b\xca\x7c\x5d\x20\x5f\xa1\x58\x57\xc8\x36\x79\x59\x95\xe8\x24\x0b\x4e\xb6\xcb\x28\x4a\x53\x3c\x3b\x6a\xd4\x80\x33\x01\x76\x47\x0e\x86\xb7\x84\xbd\x75\xf5\x07\x29\x2f\x1e\xb3\x0d\xc4\x96\x76\xce\x9a\x4f\xc9\xa7\x5b\xd6\x04\xcf\x54\x3d\x60\x5d\x7c\x75\x13\x01\xc0\x6b\x99\x17\x4f\x90\xca\x60\x16\x2c\x0b\x7e\x47\x22\x58\x37\x8b\xbd\x6a\x3b\x4d\xf1\x1c\xc2\x72\x4d\x5e\xd0\xec\x9a\x3e\x47\x1c\x27\x49\x72\xb6\x5d\xf5\xc7\xe3\x61\x22\xeb\xc1\x4d\x8d\xee\xdc\x64\x81\xda\xb6\x5c\x19\x2c\xd0\x4c\xae\xa9\xe5\x4a\xff\xb6\xd6\x69\xa4\x3f\xf4\xd2\x76\x4f\x6e\xa2\x9f\x9c\x4a\x8f\x7b\x33\xdb\xf9\x64\xf9\x73\xf6\xe8\xf8\xff\xf0\x11\x33\x49\x1f\x47\x54\x46\x92\xeb\x9c\x32\x01\xbe\x6f\x2e\xdf\x7f\xdb\xad\xe9\xb5\x66\xcd\xe5\xed\x2d\xc5\x6b\xbd\xef\x18\xd7\x7a\xef\x03\x00\x83\xc8\xe5\x7e\xf1\x02\x00\x00")
+
+func _000008_create_gpg_key_uid_search_indexesUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000008_create_gpg_key_uid_search_indexesUpSql,
+		"000008_create_gpg_key_uid_search_indexes.up.sql",
+	)
+}
+
+func _000008_create_gpg_key_uid_search_indexesUpSql() (*asset, error) {
+	bytes, err := _000008_create_gpg_key_uid_search_indexesUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000008_create_gpg_key_uid_search_indexes.up.sql", size: 753, mode: os.FileMode(420), modTime: time.Unix(1792426157, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000009_add_user_disabledDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x5f\x00\xa0\xff\x2d\x2d\x63\x68\x61\x6e\x67\x65\x73\x65\x74\x20\x72\x61\x63\x65\x72\x78\x64\x6c\x3a\x61\x64\x64\x5f\x75\x73\x65\x72\x5f\x64\x69\x73\x61\x62\x6c\x65\x64\x0a\x41\x4c\x54\x45\x52\x20\x54\x41\x42\x4c\x45\x20\x63\x68\x65\x76\x72\x6f\x6e\x5f\x75\x73\x65\x72\x0a\x20\x20\x20\x20\x44\x52\x4f\x50\x20\x43\x4f\x4c\x55\x4d\x4e\x20\x75\x73\x65\x72\x5f\x64\x69\x73\x61\x62\x6c\x65\x64\x3b\x0a\x03\x00\xee\x1c\xd6\xcf\x5f\x00\x00\x00")
+
+func _000009_add_user_disabledDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000009_add_user_disabledDownSql,
+		"000009_add_user_disabled.down.sql",
+	)
+}
+
+func _000009_add_user_disabledDownSql() (*asset, error) {
+	bytes, err := _000009_add_user_disabledDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000009_add_user_disabled.down.sql", size: 95, mode: os.FileMode(420), modTime: time.Unix(1792426735, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000009_add_user_disabledUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x7d\x00\x82\xff\x2d\x2d\x63\x68\x61\x6e\x67\x65\x73\x65\x74\x20\x72\x61\x63\x65\x72\x78\x64\x6c\x3a\x61\x64\x64\x5f\x75\x73\x65\x72\x5f\x64\x69\x73\x61\x62\x6c\x65\x64\x0a\x41\x4c\x54\x45\x52\x20\x54\x41\x42\x4c\x45\x20\x63\x68\x65\x76\x72\x6f\x6e\x5f\x75\x73\x65\x72\x0a\x20\x20\x20\x20\x41\x44\x44\x20\x43\x4f\x4c\x55\x4d\x4e\x20\x75\x73\x65\x72\x5f\x64\x69\x73\x61\x62\x6c\x65\x64\x20\x62\x6f\x6f\x6c\x65\x61\x6e\x20\x4e\x4f\x54\x20\x4e\x55\x4c\x4c\x20\x44\x45\x46\x41\x55\x4c\x54\x20\x66\x61\x6c\x73\x65\x3b\x0a\x03\x00\xb6\xe7\x62\x1b\x7d\x00\x00\x00")
+
+func _000009_add_user_disabledUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000009_add_user_disabledUpSql,
+		"000009_add_user_disabled.up.sql",
+	)
+}
+
+func _000009_add_user_disabledUpSql() (*asset, error) {
+	bytes, err := _000009_add_user_disabledUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000009_add_user_disabled.up.sql", size: 125, mode: os.FileMode(420), modTime: time.Unix(1792426735, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000010_add_user_rolesDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x59\x00\xa6\xff\x2d\x2d\x63\x68\x61\x6e\x67\x65\x73\x65\x74\x20\x72\x61\x63\x65\x72\x78\x64\x6c\x3a\x61\x64\x64\x5f\x75\x73\x65\x72\x5f\x72\x6f\x6c\x65\x73\x0a\x41\x4c\x54\x45\x52\x20\x54\x41\x42\x4c\x45\x20\x63\x68\x65\x76\x72\x6f\x6e\x5f\x75\x73\x65\x72\x0a\x20\x20\x20\x20\x44\x52\x4f\x50\x20\x43\x4f\x4c\x55\x4d\x4e\x20\x75\x73\x65\x72\x5f\x72\x6f\x6c\x65\x73\x3b\x0a\x03\x00\xb4\xfd\x83\xeb\x59\x00\x00\x00")
+
+func _000010_add_user_rolesDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000010_add_user_rolesDownSql,
+		"000010_add_user_roles.down.sql",
+	)
+}
+
+func _000010_add_user_rolesDownSql() (*asset, error) {
+	bytes, err := _000010_add_user_rolesDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000010_add_user_roles.down.sql", size: 89, mode: os.FileMode(420), modTime: time.Unix(1792427019, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000010_add_user_rolesUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x74\x00\x8b\xff\x2d\x2d\x63\x68\x61\x6e\x67\x65\x73\x65\x74\x20\x72\x61\x63\x65\x72\x78\x64\x6c\x3a\x61\x64\x64\x5f\x75\x73\x65\x72\x5f\x72\x6f\x6c\x65\x73\x0a\x41\x4c\x54\x45\x52\x20\x54\x41\x42\x4c\x45\x20\x63\x68\x65\x76\x72\x6f\x6e\x5f\x75\x73\x65\x72\x0a\x20\x20\x20\x20\x41\x44\x44\x20\x43\x4f\x4c\x55\x4d\x4e\x20\x75\x73\x65\x72\x5f\x72\x6f\x6c\x65\x73\x20\x76\x61\x72\x63\x68\x61\x72\x20\x4e\x4f\x54\x20\x4e\x55\x4c\x4c\x20\x44\x45\x46\x41\x55\x4c\x54\x20\x27\x27\x3b\x0a\x03\x00\xa9\x0a\xad\x26\x74\x00\x00\x00")
+
+func _000010_add_user_rolesUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000010_add_user_rolesUpSql,
+		"000010_add_user_roles.up.sql",
+	)
+}
+
+func _000010_add_user_rolesUpSql() (*asset, error) {
+	bytes, err := _000010_add_user_rolesUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000010_add_user_roles.up.sql", size: 116, mode: os.FileMode(420), modTime: time.Unix(1792427019, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000011_add_user_totpDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xd2\xd5\x4d\xce\x48\xcc\x4b\x4f\x2d\x4e\x2d\x51\x28\x4a\x4c\x4e\x2d\xaa\x48\xc9\xb1\x4a\x4c\x49\x89\x2f\x2d\x4e\x2d\x8a\x2f\xc9\x2f\x29\xe0\x72\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\x48\xce\x48\x2d\x2b\xca\xcf\x03\xcb\x72\x29\x28\x28\x28\xb8\x04\xf9\x07\x28\x38\xfb\xfb\x84\xfa\xfa\x29\xc0\xb5\xc4\x17\xa7\x26\x17\xa5\x96\xe8\xe0\x51\x91\x9a\x97\x98\x94\x93\x9a\x62\xcd\x05\x18\x00\x6d\x3d\xda\x14\x81\x00\x00\x00")
+
+func _000011_add_user_totpDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000011_add_user_totpDownSql,
+		"000011_add_user_totp.down.sql",
+	)
+}
+
+func _000011_add_user_totpDownSql() (*asset, error) {
+	bytes, err := _000011_add_user_totpDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000011_add_user_totp.down.sql", size: 129, mode: os.FileMode(420), modTime: time.Unix(1792427391, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000011_add_user_totpUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x74\xcb\xb1\x0e\x82\x30\x10\x06\xe0\x9d\xa7\xf8\x37\x16\x79\x01\x9d\xaa\xe0\x74\x42\x62\xca\x4c\x8e\xf6\xb4\x43\xd3\x9a\x6b\x25\x3e\xbe\x89\x83\x8b\x71\xfe\xf2\x75\x9d\x0b\x9c\xee\x52\xa4\x42\xd9\x89\xbe\x7c\xdc\xb3\xf7\xcb\xb3\x88\x2e\x35\xd7\x47\x63\xc8\x0e\x57\x58\x73\xa4\x01\x2e\xc8\xa6\x39\x7d\xb4\x01\x00\xd3\xf7\x38\x4d\x34\x5f\x46\x7c\xc7\x52\xc4\xa9\x54\x6c\xac\x2e\xb0\x62\x9c\x2c\xc6\x99\x08\xfd\x70\x36\x33\x59\xb4\xed\xee\x7f\x96\xc4\x6b\x14\x8f\x35\xe7\x28\x9c\x7e\xf7\x8d\x63\x91\x43\xf3\x1e\x00\x37\xea\x23\x10\xba\x00\x00\x00")
+
+func _000011_add_user_totpUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000011_add_user_totpUpSql,
+		"000011_add_user_totp.up.sql",
+	)
+}
+
+func _000011_add_user_totpUpSql() (*asset, error) {
+	bytes, err := _000011_add_user_totpUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000011_add_user_totp.up.sql", size: 186, mode: os.FileMode(420), modTime: time.Unix(1792427391, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000012_add_user_totp_last_stepDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x6b\x00\x94\xff\x2d\x2d\x63\x68\x61\x6e\x67\x65\x73\x65\x74\x20\x72\x61\x63\x65\x72\x78\x64\x6c\x3a\x61\x64\x64\x5f\x75\x73\x65\x72\x5f\x74\x6f\x74\x70\x5f\x6c\x61\x73\x74\x5f\x73\x74\x65\x70\x0a\x41\x4c\x54\x45\x52\x20\x54\x41\x42\x4c\x45\x20\x63\x68\x65\x76\x72\x6f\x6e\x5f\x75\x73\x65\x72\x0a\x20\x20\x20\x20\x44\x52\x4f\x50\x20\x43\x4f\x4c\x55\x4d\x4e\x20\x75\x73\x65\x72\x5f\x74\x6f\x74\x70\x5f\x6c\x61\x73\x74\x5f\x73\x74\x65\x70\x3b\x0a\x03\x00\x7c\x63\xc8\x36\x6b\x00\x00\x00")
+
+func _000012_add_user_totp_last_stepDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000012_add_user_totp_last_stepDownSql,
+		"000012_add_user_totp_last_step.down.sql",
+	)
+}
+
+func _000012_add_user_totp_last_stepDownSql() (*asset, error) {
+	bytes, err := _000012_add_user_totp_last_stepDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000012_add_user_totp_last_step.down.sql", size: 107, mode: os.FileMode(420), modTime: time.Unix(1792432914, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000012_add_user_totp_last_stepUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x6c\xcb\x31\x0e\xc2\x20\x14\x06\xe0\x9d\x53\xfc\x17\x68\xe2\xac\x13\x0a\x4e\x4f\x9a\x18\x98\x09\xc2\x4b\xdb\xa4\xa1\x0d\x3c\x8d\xc7\x37\x71\xee\xfe\x7d\xc3\x90\xe7\x54\x27\xee\x2c\x68\x29\x73\xfb\x96\xf5\x9c\x4a\x89\xef\xce\x2d\xca\x26\x7b\x5c\x53\x97\xd8\x85\x77\xa5\xc9\xdb\x27\xbc\xbe\x92\x45\x9e\xf9\xd3\xb6\xfa\x77\x0a\x00\xb4\x31\xb8\x8d\x14\x1e\x0e\x07\x17\xaf\x65\x5a\xaa\xc0\x8d\x1e\x2e\x10\xc1\xd8\xbb\x0e\xe4\x71\xba\xa8\xdf\x00\x35\x20\xe4\x3c\x84\x00\x00\x00")
+
+func _000012_add_user_totp_last_stepUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000012_add_user_totp_last_stepUpSql,
+		"000012_add_user_totp_last_step.up.sql",
+	)
+}
+
+func _000012_add_user_totp_last_stepUpSql() (*asset, error) {
+	bytes, err := _000012_add_user_totp_last_stepUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000012_add_user_totp_last_step.up.sql", size: 132, mode: os.FileMode(420), modTime: time.Unix(1792432914, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000013_create_token_revocation_tableDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x58\x00\xa7\xff\x2d\x2d\x63\x68\x61\x6e\x67\x65\x73\x65\x74\x20\x72\x61\x63\x65\x72\x78\x64\x6c\x3a\x63\x72\x65\x61\x74\x65\x5f\x74\x6f\x6b\x65\x6e\x5f\x72\x65\x76\x6f\x63\x61\x74\x69\x6f\x6e\x5f\x74\x61\x62\x6c\x65\x0a\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x63\x68\x65\x76\x72\x6f\x6e\x5f\x74\x6f\x6b\x65\x6e\x5f\x72\x65\x76\x6f\x63\x61\x74\x69\x6f\x6e\x3b\x0a\x03\x00\x62\x30\xd7\x58\x58\x00\x00\x00")
+
+func _000013_create_token_revocation_tableDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000013_create_token_revocation_tableDownSql,
+		"000013_create_token_revocation_table.down.sql",
+	)
+}
+
+func _000013_create_token_revocation_tableDownSql() (*asset, error) {
+	bytes, err := _000013_create_token_revocation_tableDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000013_create_token_revocation_table.down.sql", size: 88, mode: os.FileMode(420), modTime: time.Unix(1792433069, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000013_create_token_revocation_tableUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x9c\x90\xd1\x6a\x83\x30\x14\x86\xef\xf3\x14\xe7\xae\x0a\xed\x0b\xcc\x2b\xb7\x66\x50\xe6\xec\x10\x0b\xeb\x55\x38\x8b\x87\x25\xac\x26\x25\x46\xe7\xe3\x0f\x56\x42\x0b\x9a\x52\xf4\x4e\x38\xdf\xff\xff\xf9\x36\x1b\xa9\xd0\x7c\x53\x47\x1e\x1c\x4a\x72\x63\x73\x7a\x92\x8e\xd0\x93\xf0\xf6\x87\x8c\x70\x34\x58\x89\x5e\x5b\x23\x3c\x7e\x9d\x88\xbd\x54\x3c\xaf\x39\xd4\xf9\x73\xc1\x41\x2a\x1a\x9c\x35\x93\x5b\x96\x30\x00\x80\x49\x84\x6e\x20\x7c\x7d\x1f\x7e\xca\x7d\x0d\xe5\xa1\x28\xe0\xa3\xda\xbd\xe7\xd5\x11\xde\xf8\x71\x3d\xcf\x5f\x02\x15\x76\x0a\x06\x74\x52\xa1\xbb\xe5\xb7\xfc\x35\x3f\x14\x35\xac\x56\x11\xbc\xef\xc8\x19\x6c\x09\x60\x06\x8f\x30\x17\x1b\x8d\x40\x0f\x5e\xb7\xd4\x79\x6c\xcf\xd3\x4a\x63\x7f\x93\x34\x92\x40\xe3\x59\xbb\xff\xfd\x33\x09\x2c\xcd\x58\x70\xba\x2b\xb7\xfc\x33\xea\xf4\xe6\xf1\x42\x37\x23\xec\xcb\xe8\x29\x24\x77\xe0\x34\x7b\xb0\x2f\xd8\x5a\xd0\x16\xd0\xf5\x3d\x9f\x0f\x0f\xb9\x0a\x5c\x30\xe5\x0a\xa7\x19\xfb\x1b\x00\xe3\x7e\xdb\x18\xf0\x02\x00\x00")
+
+func _000013_create_token_revocation_tableUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000013_create_token_revocation_tableUpSql,
+		"000013_create_token_revocation_table.up.sql",
+	)
+}
+
+func _000013_create_token_revocation_tableUpSql() (*asset, error) {
+	bytes, err := _000013_create_token_revocation_tableUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000013_create_token_revocation_table.up.sql", size: 752, mode: os.FileMode(420), modTime: time.Unix(1792433069, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
@@ -296,14 +674,32 @@ func AssetNames() []string {
 
 // _bindata is a table, holding each asset generator, mapped to its name.
 var _bindata = map[string]func() (*asset, error){
-	"000001_create_users_table.down.sql":     _000001_create_users_tableDownSql,
-	"000001_create_users_table.up.sql":       _000001_create_users_tableUpSql,
-	"000002_create_gpgkey_table.down.sql":    _000002_create_gpgkey_tableDownSql,
-	"000002_create_gpgkey_table.up.sql":      _000002_create_gpgkey_tableUpSql,
-	"000003_create_gpgkeyuid_table.down.sql": _000003_create_gpgkeyuid_tableDownSql,
-	"000003_create_gpgkeyuid_table.up.sql":   _000003_create_gpgkeyuid_tableUpSql,
-	"000004_add_username_to_user.down.sql":   _000004_add_username_to_userDownSql,
-	"000004_add_username_to_user.up.sql":     _000004_add_username_to_userUpSql,
+	"000001_create_users_table.down.sql":                   _000001_create_users_tableDownSql,
+	"000001_create_users_table.up.sql":                     _000001_create_users_tableUpSql,
+	"000002_create_gpgkey_table.down.sql":                  _000002_create_gpgkey_tableDownSql,
+	"000002_create_gpgkey_table.up.sql":                    _000002_create_gpgkey_tableUpSql,
+	"000003_create_gpgkeyuid_table.down.sql":               _000003_create_gpgkeyuid_tableDownSql,
+	"000003_create_gpgkeyuid_table.up.sql":                 _000003_create_gpgkeyuid_tableUpSql,
+	"000004_add_username_to_user.down.sql":                 _000004_add_username_to_userDownSql,
+	"000004_add_username_to_user.up.sql":                   _000004_add_username_to_userUpSql,
+	"000005_create_user_token_table.down.sql":              _000005_create_user_token_tableDownSql,
+	"000005_create_user_token_table.up.sql":                _000005_create_user_token_tableUpSql,
+	"000006_create_gpg_key_history_table.down.sql":         _000006_create_gpg_key_history_tableDownSql,
+	"000006_create_gpg_key_history_table.up.sql":           _000006_create_gpg_key_history_tableUpSql,
+	"000007_add_gpg_key_algorithm_and_expiration.down.sql": _000007_add_gpg_key_algorithm_and_expirationDownSql,
+	"000007_add_gpg_key_algorithm_and_expiration.up.sql":   _000007_add_gpg_key_algorithm_and_expirationUpSql,
+	"000008_create_gpg_key_uid_search_indexes.down.sql":    _000008_create_gpg_key_uid_search_indexesDownSql,
+	"000008_create_gpg_key_uid_search_indexes.up.sql":      _000008_create_gpg_key_uid_search_indexesUpSql,
+	"000009_add_user_disabled.down.sql":                    _000009_add_user_disabledDownSql,
+	"000009_add_user_disabled.up.sql":                      _000009_add_user_disabledUpSql,
+	"000010_add_user_roles.down.sql":                       _000010_add_user_rolesDownSql,
+	"000010_add_user_roles.up.sql":                         _000010_add_user_rolesUpSql,
+	"000011_add_user_totp.down.sql":                        _000011_add_user_totpDownSql,
+	"000011_add_user_totp.up.sql":                          _000011_add_user_totpUpSql,
+	"000012_add_user_totp_last_step.down.sql":              _000012_add_user_totp_last_stepDownSql,
+	"000012_add_user_totp_last_step.up.sql":                _000012_add_user_totp_last_stepUpSql,
+	"000013_create_token_revocation_table.down.sql":        _000013_create_token_revocation_tableDownSql,
+	"000013_create_token_revocation_table.up.sql":          _000013_create_token_revocation_tableUpSql,
 }
 
 // AssetDir returns the file names below a certain
@@ -347,14 +743,32 @@ type bintree struct {
 }
 
 var _bintree = &bintree{nil, map[string]*bintree{
-	"000001_create_users_table.down.sql":     &bintree{_000001_create_users_tableDownSql, map[string]*bintree{}},
-	"000001_create_users_table.up.sql":       &bintree{_000001_create_users_tableUpSql, map[string]*bintree{}},
-	"000002_create_gpgkey_table.down.sql":    &bintree{_000002_create_gpgkey_tableDownSql, map[string]*bintree{}},
-	"000002_create_gpgkey_table.up.sql":      &bintree{_000002_create_gpgkey_tableUpSql, map[string]*bintree{}},
-	"000003_create_gpgkeyuid_table.down.sql": &bintree{_000003_create_gpgkeyuid_tableDownSql, map[string]*bintree{}},
-	"000003_create_gpgkeyuid_table.up.sql":   &bintree{_000003_create_gpgkeyuid_tableUpSql, map[string]*bintree{}},
-	"000004_add_username_to_user.down.sql":   &bintree{_000004_add_username_to_userDownSql, map[string]*bintree{}},
-	"000004_add_username_to_user.up.sql":     &bintree{_000004_add_username_to_userUpSql, map[string]*bintree{}},
+	"000001_create_users_table.down.sql":                   &bintree{_000001_create_users_tableDownSql, map[string]*bintree{}},
+	"000001_create_users_table.up.sql":                     &bintree{_000001_create_users_tableUpSql, map[string]*bintree{}},
+	"000002_create_gpgkey_table.down.sql":                  &bintree{_000002_create_gpgkey_tableDownSql, map[string]*bintree{}},
+	"000002_create_gpgkey_table.up.sql":                    &bintree{_000002_create_gpgkey_tableUpSql, map[string]*bintree{}},
+	"000003_create_gpgkeyuid_table.down.sql":               &bintree{_000003_create_gpgkeyuid_tableDownSql, map[string]*bintree{}},
+	"000003_create_gpgkeyuid_table.up.sql":                 &bintree{_000003_create_gpgkeyuid_tableUpSql, map[string]*bintree{}},
+	"000004_add_username_to_user.down.sql":                 &bintree{_000004_add_username_to_userDownSql, map[string]*bintree{}},
+	"000004_add_username_to_user.up.sql":                   &bintree{_000004_add_username_to_userUpSql, map[string]*bintree{}},
+	"000005_create_user_token_table.down.sql":              &bintree{_000005_create_user_token_tableDownSql, map[string]*bintree{}},
+	"000005_create_user_token_table.up.sql":                &bintree{_000005_create_user_token_tableUpSql, map[string]*bintree{}},
+	"000006_create_gpg_key_history_table.down.sql":         &bintree{_000006_create_gpg_key_history_tableDownSql, map[string]*bintree{}},
+	"000006_create_gpg_key_history_table.up.sql":           &bintree{_000006_create_gpg_key_history_tableUpSql, map[string]*bintree{}},
+	"000007_add_gpg_key_algorithm_and_expiration.down.sql": &bintree{_000007_add_gpg_key_algorithm_and_expirationDownSql, map[string]*bintree{}},
+	"000007_add_gpg_key_algorithm_and_expiration.up.sql":   &bintree{_000007_add_gpg_key_algorithm_and_expirationUpSql, map[string]*bintree{}},
+	"000008_create_gpg_key_uid_search_indexes.down.sql":    &bintree{_000008_create_gpg_key_uid_search_indexesDownSql, map[string]*bintree{}},
+	"000008_create_gpg_key_uid_search_indexes.up.sql":      &bintree{_000008_create_gpg_key_uid_search_indexesUpSql, map[string]*bintree{}},
+	"000009_add_user_disabled.down.sql":                    &bintree{_000009_add_user_disabledDownSql, map[string]*bintree{}},
+	"000009_add_user_disabled.up.sql":                      &bintree{_000009_add_user_disabledUpSql, map[string]*bintree{}},
+	"000010_add_user_roles.down.sql":                       &bintree{_000010_add_user_rolesDownSql, map[string]*bintree{}},
+	"000010_add_user_roles.up.sql":                         &bintree{_000010_add_user_rolesUpSql, map[string]*bintree{}},
+	"000011_add_user_totp.down.sql":                        &bintree{_000011_add_user_totpDownSql, map[string]*bintree{}},
+	"000011_add_user_totp.up.sql":                          &bintree{_000011_add_user_totpUpSql, map[string]*bintree{}},
+	"000012_add_user_totp_last_step.down.sql":              &bintree{_000012_add_user_totp_last_stepDownSql, map[string]*bintree{}},
+	"000012_add_user_totp_last_step.up.sql":                &bintree{_000012_add_user_totp_last_stepUpSql, map[string]*bintree{}},
+	"000013_create_token_revocation_table.down.sql":        &bintree{_000013_create_token_revocation_tableDownSql, map[string]*bintree{}},
+	"000013_create_token_revocation_table.up.sql":          &bintree{_000013_create_token_revocation_tableUpSql, map[string]*bintree{}},
 }}
 
 // RestoreAsset restores an asset under the given directory
@@ -62,6 +62,24 @@ func (h *RethinkDBDriver) DeleteGPGKey(key models.GPGKey) error {
 		Exec(h.conn)
 }
 
+// RestoreGPGKey restores a previously deleted GPG key
+// Not supported on RethinkDB since keys are hard deleted
+func (h *RethinkDBDriver) RestoreGPGKey(key models.GPGKey) error {
+	return fmt.Errorf("not supported")
+}
+
+// FetchGPGKeyHistory returns all changes made to the key with the specified fingerprint
+// Not supported on RethinkDB
+func (h *RethinkDBDriver) FetchGPGKeyHistory(fingerprint string) ([]models.GPGKeyHistory, error) {
+	return nil, fmt.Errorf("not supported")
+}
+
+// SearchGPGKeys searches the key store using the specified query
+// Not supported on RethinkDB
+func (h *RethinkDBDriver) SearchGPGKeys(query models.GPGKeySearchQuery) (*models.GPGKeySearchResult, error) {
+	return nil, fmt.Errorf("not supported")
+}
+
 // AddGPGKey adds a GPG Key to the database or update an existing one by fingerprint
 // Returns generated id / hasBeenAdded / error
 func (h *RethinkDBDriver) AddGPGKey(key models.GPGKey) (string, bool, error) {
@@ -53,6 +53,7 @@ func (h *RethinkDBDriver) InitDatabase() error {
 		// Initializations
 		h.initUserTable,
 		h.initUserTokenTable,
+		h.initTokenRevocationTable,
 		h.initGPGKeyTable,
 
 		// Migrations
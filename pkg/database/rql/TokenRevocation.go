@@ -0,0 +1,93 @@
+package rql
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/quan-to/chevron/pkg/models"
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+var tokenRevocationTableInit = tableInitStruct{
+	TableName:    "tokenRevocations",
+	TableIndexes: []string{"TokenHash", "Username", "Expiration"},
+}
+
+func (h *RethinkDBDriver) initTokenRevocationTable() error {
+	return h.initFromStruct(tokenRevocationTableInit)
+}
+
+// AddTokenRevocation stores a token revocation and returns its ID
+func (h *RethinkDBDriver) AddTokenRevocation(tr models.TokenRevocation) (string, error) {
+	if tr.TokenHash == "" && tr.Username == "" {
+		return "", fmt.Errorf("empty revocation")
+	}
+
+	rtr, err := convertToRethinkDB(tr)
+	if err != nil {
+		return "", err
+	}
+
+	wr, err := r.Table(tokenRevocationTableInit.TableName).
+		Insert(rtr).
+		RunWrite(h.conn)
+
+	if err != nil {
+		return "", err
+	}
+
+	return wr.GeneratedKeys[0], err
+}
+
+// GetTokenRevocation fetches the revocation of the token with the specified hash
+func (h *RethinkDBDriver) GetTokenRevocation(tokenHash string) (*models.TokenRevocation, error) {
+	return h.getTokenRevocation(r.Table(tokenRevocationTableInit.TableName).
+		GetAllByIndex("TokenHash", tokenHash).
+		Limit(1).
+		CoerceTo("array"))
+}
+
+// GetUserTokenRevocation fetches the latest revocation of all tokens of the specified user
+func (h *RethinkDBDriver) GetUserTokenRevocation(username string) (*models.TokenRevocation, error) {
+	return h.getTokenRevocation(r.Table(tokenRevocationTableInit.TableName).
+		GetAllByIndex("Username", username).
+		Filter(r.Row.Field("TokenHash").Eq("")).
+		OrderBy(r.Desc("CreatedAt")).
+		Limit(1).
+		CoerceTo("array"))
+}
+
+func (h *RethinkDBDriver) getTokenRevocation(query r.Term) (tr *models.TokenRevocation, err error) {
+	res, err := query.Run(h.conn)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Close()
+
+	rdata := map[string]interface{}{}
+
+	if res.Next(&rdata) {
+		tr = &models.TokenRevocation{}
+		err = convertFromRethinkDB(rdata, &tr)
+		return tr, err
+	}
+
+	return nil, fmt.Errorf("not found")
+}
+
+// InvalidateTokenRevocations removes the revocations of tokens that had been already expired
+// Revocations of all tokens of a user are kept
+func (h *RethinkDBDriver) InvalidateTokenRevocations() (int, error) {
+	wr, err := r.Table(tokenRevocationTableInit.TableName).
+		Filter(r.Row.Field("TokenHash").Ne("").And(r.Row.Field("Expiration").Lt(time.Now()))).
+		Delete().
+		RunWrite(h.conn)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return wr.Deleted, nil
+}
@@ -0,0 +1,145 @@
+package rql
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/quan-to/chevron/pkg/models"
+	"github.com/quan-to/slog"
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+var testRevocation = models.TokenRevocation{
+	ID:         "abcd",
+	TokenHash:  "8a9bcf1e51e812d0af8465a8dbcc9f741064bf0af3b3d08e6b0246437c19f7fb",
+	Username:   "johnhuebr",
+	CreatedAt:  time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+	Expiration: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+}
+
+func TestRethinkDBDriver_AddTokenRevocation(t *testing.T) {
+	mock := r.NewMock()
+	h := MakeRethinkDBDriver(slog.Scope("TEST"))
+	h.conn = mock
+
+	m, _ := convertToRethinkDB(testRevocation)
+
+	mock.ExpectedQueries = append(mock.ExpectedQueries, mock.On(r.Table(tokenRevocationTableInit.TableName).
+		Insert(m)).
+		Return(r.WriteResponse{
+			GeneratedKeys: []string{testRevocation.ID},
+		}, nil))
+
+	id, err := h.AddTokenRevocation(testRevocation)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if id != testRevocation.ID {
+		t.Fatalf("expected revocation id to be %q but got %q", testRevocation.ID, id)
+	}
+
+	// Test empty revocation
+	_, err = h.AddTokenRevocation(models.TokenRevocation{})
+	if err == nil {
+		t.Fatalf("expected error but got nil")
+	}
+
+	mock.AssertExpectations(t)
+}
+
+func TestRethinkDBDriver_GetTokenRevocation(t *testing.T) {
+	mock := r.NewMock()
+	h := MakeRethinkDBDriver(slog.Scope("TEST"))
+	h.conn = mock
+
+	m, _ := convertToRethinkDB(testRevocation)
+
+	mock.ExpectedQueries = append(mock.ExpectedQueries, mock.On(r.Table(tokenRevocationTableInit.TableName).
+		GetAllByIndex("TokenHash", testRevocation.TokenHash).
+		Limit(1).
+		CoerceTo("array")).
+		Return([]map[string]interface{}{m}, nil))
+
+	mock.ExpectedQueries = append(mock.ExpectedQueries, mock.On(r.Table(tokenRevocationTableInit.TableName).
+		GetAllByIndex("TokenHash", "invalid").
+		Limit(1).
+		CoerceTo("array")).
+		Return([]map[string]interface{}{}, nil))
+
+	tr, err := h.GetTokenRevocation(testRevocation.TokenHash)
+	if err != nil {
+		t.Fatalf("unexpected error %q", err)
+	}
+
+	if diff := pretty.Compare(testRevocation, tr); diff != "" {
+		t.Errorf("Expected revocation to be the same. (-got +want)\\n%s", diff)
+	}
+
+	// Test not found
+	_, err = h.GetTokenRevocation("invalid")
+	if err == nil || !strings.EqualFold(err.Error(), "not found") {
+		t.Fatalf("expected error to be %q but got %v", "not found", err)
+	}
+
+	mock.AssertExpectations(t)
+}
+
+func TestRethinkDBDriver_GetUserTokenRevocation(t *testing.T) {
+	mock := r.NewMock()
+	h := MakeRethinkDBDriver(slog.Scope("TEST"))
+	h.conn = mock
+
+	expected := models.TokenRevocation{
+		ID:        "abcd",
+		Username:  testRevocation.Username,
+		CreatedAt: testRevocation.CreatedAt,
+	}
+
+	m, _ := convertToRethinkDB(expected)
+
+	mock.ExpectedQueries = append(mock.ExpectedQueries, mock.On(r.Table(tokenRevocationTableInit.TableName).
+		GetAllByIndex("Username", expected.Username).
+		Filter(r.Row.Field("TokenHash").Eq("")).
+		OrderBy(r.Desc("CreatedAt")).
+		Limit(1).
+		CoerceTo("array")).
+		Return([]map[string]interface{}{m}, nil))
+
+	tr, err := h.GetUserTokenRevocation(expected.Username)
+	if err != nil {
+		t.Fatalf("unexpected error %q", err)
+	}
+
+	if diff := pretty.Compare(expected, tr); diff != "" {
+		t.Errorf("Expected revocation to be the same. (-got +want)\\n%s", diff)
+	}
+
+	mock.AssertExpectations(t)
+}
+
+func TestRethinkDBDriver_InvalidateTokenRevocations(t *testing.T) {
+	mock := r.NewMock()
+	h := MakeRethinkDBDriver(slog.Scope("TEST"))
+	h.conn = mock
+
+	mock.ExpectedQueries = append(mock.ExpectedQueries, mock.On(r.Table(tokenRevocationTableInit.TableName).
+		Filter(r.Row.Field("TokenHash").Ne("").And(r.Row.Field("Expiration").Lt(r.MockAnything()))).
+		Delete()).
+		Return(r.WriteResponse{
+			Deleted: 2,
+		}, nil))
+
+	n, err := h.InvalidateTokenRevocations()
+	if err != nil {
+		t.Fatalf("unexpected error %q", err)
+	}
+
+	if n != 2 {
+		t.Fatalf("expected %d deletes got %d", 2, n)
+	}
+
+	mock.AssertExpectations(t)
+}
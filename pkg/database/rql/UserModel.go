@@ -123,3 +123,59 @@ func (h *RethinkDBDriver) UpdateUser(um models.User) error {
 
 	return nil
 }
+
+// ListUsers returns the users sorted by username in the specified page
+func (h *RethinkDBDriver) ListUsers(pageStart, pageEnd int) ([]models.User, error) {
+	if pageStart < 0 {
+		pageStart = models.DefaultPageStart
+	}
+
+	if pageEnd < 0 {
+		pageEnd = models.DefaultPageEnd
+	}
+
+	res, err := r.Table(userModelTableInit.TableName).
+		OrderBy("Username").
+		Slice(pageStart, pageEnd).
+		CoerceTo("array").
+		Run(h.conn)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Close()
+
+	users := make([]models.User, 0)
+	rdata := map[string]interface{}{}
+
+	for res.Next(&rdata) {
+		var um models.User
+		err = convertFromRethinkDB(rdata, &um)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, um)
+		rdata = map[string]interface{}{}
+	}
+
+	return users, nil
+}
+
+// DeleteUser deletes the specified user by using it's username
+func (h *RethinkDBDriver) DeleteUser(um models.User) error {
+	res, err := r.Table(userModelTableInit.TableName).
+		GetAllByIndex("Username", um.Username).
+		Delete().
+		RunWrite(h.conn)
+
+	if err != nil {
+		return err
+	}
+
+	if res.Deleted == 0 {
+		return fmt.Errorf("not found")
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/quan-to/chevron/pkg/models"
 	"github.com/quan-to/chevron/pkg/models/testmodels"
 
 	"github.com/kylelemons/godebug/pretty"
@@ -20,11 +21,16 @@ func TestRethinkDBDriver_AddUser(t *testing.T) {
 	userToAdd.ID = ""
 
 	mock.On(r.Table(userModelTableInit.TableName).Insert(map[string]interface{}{
-		"Fingerprint": userToAdd.Fingerprint,
-		"Username":    userToAdd.Username,
-		"Password":    userToAdd.Password,
-		"FullName":    userToAdd.FullName,
-		"CreatedAt":   r.MockAnything(),
+		"Fingerprint":  userToAdd.Fingerprint,
+		"Username":     userToAdd.Username,
+		"Password":     userToAdd.Password,
+		"FullName":     userToAdd.FullName,
+		"CreatedAt":    r.MockAnything(),
+		"Disabled":     false,
+		"Roles":        []interface{}{"signer"},
+		"TOTPSecret":   "",
+		"TOTPEnabled":  false,
+		"TOTPLastStep": float64(0),
 	})).
 		Return(r.WriteResponse{
 			Inserted:      1,
@@ -92,6 +98,7 @@ func TestRethinkDBDriver_GetUser(t *testing.T) {
 				"FullName":    expectedUser.FullName,
 				"Password":    expectedUser.Password,
 				"CreatedAt":   expectedUser.CreatedAt,
+				"Roles":       expectedUser.Roles,
 			},
 		}, nil))
 
@@ -167,3 +174,56 @@ func TestRethinkDBDriver_UpdateUser(t *testing.T) {
 
 	mock.AssertExpectations(t)
 }
+
+func TestRethinkDBDriver_ListUsers(t *testing.T) {
+	mock := r.NewMock()
+	h := MakeRethinkDBDriver(slog.Scope("TEST"))
+	h.conn = mock
+
+	m, _ := convertToRethinkDB(testmodels.User)
+
+	mock.ExpectedQueries = append(mock.ExpectedQueries, mock.On(r.Table(userModelTableInit.TableName).
+		OrderBy("Username").
+		Slice(0, 10).
+		CoerceTo("array")).
+		Return([]map[string]interface{}{m}, nil))
+
+	users, err := h.ListUsers(0, 10)
+	if err != nil {
+		t.Fatalf("unexpected error %q", err)
+	}
+
+	if diff := pretty.Compare([]models.User{testmodels.User}, users); diff != "" {
+		t.Errorf("Expected users to be the same. (-got +want)\\n%s", diff)
+	}
+
+	mock.AssertExpectations(t)
+}
+
+func TestRethinkDBDriver_DeleteUser(t *testing.T) {
+	mock := r.NewMock()
+	h := MakeRethinkDBDriver(slog.Scope("TEST"))
+	h.conn = mock
+
+	mock.ExpectedQueries = append(mock.ExpectedQueries, mock.On(r.Table(userModelTableInit.TableName).
+		GetAllByIndex("Username", testmodels.User.Username).
+		Delete()).
+		Return(r.WriteResponse{Deleted: 1}, nil))
+
+	mock.ExpectedQueries = append(mock.ExpectedQueries, mock.On(r.Table(userModelTableInit.TableName).
+		GetAllByIndex("Username", "huebr").
+		Delete()).
+		Return(r.WriteResponse{Deleted: 0}, nil))
+
+	err := h.DeleteUser(testmodels.User)
+	if err != nil {
+		t.Fatalf("unexpected error %q", err)
+	}
+
+	err = h.DeleteUser(models.User{Username: "huebr"})
+	if err == nil || !strings.EqualFold(err.Error(), "not found") {
+		t.Fatalf("Expected error to be %q but got %v", "not found", err)
+	}
+
+	mock.AssertExpectations(t)
+}
@@ -105,3 +105,46 @@ func (h *RethinkDBDriver) InvalidateUserTokens() (int, error) {
 
 	return wr.Deleted, nil
 }
+
+// FetchUserTokens returns all tokens of the specified user
+func (h *RethinkDBDriver) FetchUserTokens(username string) ([]models.UserToken, error) {
+	res, err := r.Table(userTokenTableInit.TableName).
+		GetAllByIndex("Username", username).
+		CoerceTo("array").
+		Run(h.conn)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Close()
+
+	tokens := make([]models.UserToken, 0)
+	rdata := map[string]interface{}{}
+
+	for res.Next(&rdata) {
+		var ut models.UserToken
+		err = convertFromRethinkDB(rdata, &ut)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, ut)
+		rdata = map[string]interface{}{}
+	}
+
+	return tokens, nil
+}
+
+// RemoveUserTokens removes all tokens of the specified user
+func (h *RethinkDBDriver) RemoveUserTokens(username string) (int, error) {
+	wr, err := r.Table(userTokenTableInit.TableName).
+		GetAllByIndex("Username", username).
+		Delete().
+		RunWrite(h.conn)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return wr.Deleted, nil
+}
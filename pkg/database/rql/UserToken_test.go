@@ -173,3 +173,49 @@ func TestRethinkDBDriver_RemoveUserToken(t *testing.T) {
 
 	mock.AssertExpectations(t)
 }
+
+func TestRethinkDBDriver_FetchUserTokens(t *testing.T) {
+	mock := r.NewMock()
+	h := MakeRethinkDBDriver(slog.Scope("TEST"))
+	h.conn = mock
+
+	m, _ := convertToRethinkDB(testmodels.Token)
+
+	mock.ExpectedQueries = append(mock.ExpectedQueries, mock.On(r.Table(userTokenTableInit.TableName).
+		GetAllByIndex("Username", testmodels.Token.Username).
+		CoerceTo("array")).
+		Return([]map[string]interface{}{m}, nil))
+
+	tokens, err := h.FetchUserTokens(testmodels.Token.Username)
+	if err != nil {
+		t.Fatalf("unexpected error %q", err)
+	}
+
+	if diff := pretty.Compare([]models.UserToken{testmodels.Token}, tokens); diff != "" {
+		t.Errorf("Expected tokens to be the same. (-got +want)\\n%s", diff)
+	}
+
+	mock.AssertExpectations(t)
+}
+
+func TestRethinkDBDriver_RemoveUserTokens(t *testing.T) {
+	mock := r.NewMock()
+	h := MakeRethinkDBDriver(slog.Scope("TEST"))
+	h.conn = mock
+
+	mock.ExpectedQueries = append(mock.ExpectedQueries, mock.On(r.Table(userTokenTableInit.TableName).
+		GetAllByIndex("Username", testmodels.Token.Username).
+		Delete()).
+		Return(r.WriteResponse{Deleted: 2}, nil))
+
+	n, err := h.RemoveUserTokens(testmodels.Token.Username)
+	if err != nil {
+		t.Fatalf("unexpected error %q", err)
+	}
+
+	if n != 2 {
+		t.Fatalf("expected %d deletes got %d", 2, n)
+	}
+
+	mock.AssertExpectations(t)
+}
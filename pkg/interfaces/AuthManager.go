@@ -1,5 +1,7 @@
 package interfaces
 
+import "github.com/quan-to/chevron/pkg/models"
+
 // AuthManager is an interface to a Authentication Manager
 // Used in Chevron Agent for Authentication StorageBackend
 type AuthManager interface {
@@ -11,4 +13,27 @@ type AuthManager interface {
 	LoginAdd(username, password, fullname, fingerprint string) error
 	// ChangePassword changes the password of the specified user
 	ChangePassword(username, password string) error
+	// ListUsers returns the users sorted by username in the specified page. Passwords are not returned
+	ListUsers(pageStart, pageEnd int) ([]models.User, error)
+	// GetUser returns the specified user. The password is not returned
+	GetUser(username string) (*models.User, error)
+	// SetUserDisabled disables or enables the specified user. Disabled users cannot login
+	SetUserDisabled(username string, disabled bool) error
+	// DeleteUser deletes the specified user
+	DeleteUser(username string) error
+	// UpdateFingerprint changes the fingerprint of the key the specified user has access
+	UpdateFingerprint(username, fingerprint string) error
+	// GrantRole adds the specified role to the user
+	GrantRole(username, role string) error
+	// RevokeRole removes the specified role from the user
+	RevokeRole(username, role string) error
+	// EnrollTOTP generates a new TOTP secret for the user and returns it
+	// The enrollment is only active after being confirmed by ConfirmTOTP
+	EnrollTOTP(username string) (secret string, err error)
+	// ConfirmTOTP activates the pending TOTP enrollment of the user if the code is valid
+	ConfirmTOTP(username, code string) error
+	// VerifyTOTP checks if the code is valid for the user TOTP enrollment
+	VerifyTOTP(username, code string) error
+	// DisableTOTP removes the TOTP enrollment of the user
+	DisableTOTP(username string) error
 }
@@ -0,0 +1,13 @@
+package interfaces
+
+import (
+	"context"
+)
+
+// PeerDiscovery is a interface for finding the other nodes of a cluster
+type PeerDiscovery interface {
+	// Name returns the name of the discovery method
+	Name() string
+	// Peers returns the base URL of the internal endpoint of the other cluster nodes
+	Peers(ctx context.Context) ([]string, error)
+}
@@ -0,0 +1,19 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+)
+
+// RateCounter is a interface for the counters used to rate limit the clients
+// Implementations shared by all replicas allow the limits to be applied to the whole cluster
+type RateCounter interface {
+	// Hit counts a request for key in a fixed window of the specified duration
+	// Returns the number of requests in the current window, including this one, and the time until it resets
+	Hit(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error)
+	// Acquire increments the in-flight requests of key and returns the new value
+	// ttl is a safety expiration for counters that were never released
+	Acquire(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	// Release decrements the in-flight requests of key
+	Release(ctx context.Context, key string) error
+}
@@ -10,10 +10,16 @@ type SecretsManager interface {
 	PutKeyPassword(ctx context.Context, fingerPrint, password string)
 	// PutEncryptedPassword stores in memory a master key encrypted password for the specified fingerprint
 	PutEncryptedPassword(ctx context.Context, fingerPrint, encryptedPassword string)
+	// DeleteKeyPassword removes the stored password of the specified key fingerprint from memory and from the key backend
+	DeleteKeyPassword(ctx context.Context, fingerPrint string) error
 	// GetPasswords returns a list of master key encrypted passwords stored in memory
 	GetPasswords(ctx context.Context) map[string]string
 	// UnlockLocalKeys unlocks the local private keys using memory stored master key encrypted passwords
 	UnlockLocalKeys(ctx context.Context, gpg PGPManager)
 	// GetMasterKeyFingerPrint returns the fingerprint of the master key
 	GetMasterKeyFingerPrint(ctx context.Context) string
+	// SignWithMasterKey signs the data with the master key and returns the armored signature
+	SignWithMasterKey(ctx context.Context, data []byte) (string, error)
+	// VerifyMasterKeySignature returns an error if the armored signature of the data was not made by the master key
+	VerifyMasterKeySignature(ctx context.Context, data []byte, signature string) error
 }
@@ -1,5 +1,7 @@
 package interfaces
 
+import "github.com/quan-to/chevron/pkg/models"
+
 // TokenManager is an interface to a Login Token Manager
 type TokenManager interface {
 	// AddUser adds a user to Token Manager and returns a login token
@@ -12,4 +14,9 @@ type TokenManager interface {
 	GetUserData(token string) UserData
 	// InvalidateToken invalidates the specified token
 	InvalidateToken(token string) error
+	// ActiveTokens returns the tokens of the specified user that are not expired
+	// The token values are not returned
+	ActiveTokens(username string) ([]models.UserToken, error)
+	// RevokeUserTokens invalidates all tokens of the specified user and returns how many were invalidated
+	RevokeUserTokens(username string) (int, error)
 }
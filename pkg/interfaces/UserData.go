@@ -19,3 +19,11 @@ type UserData interface {
 	// GetFingerPrint returns the user key fingerprint
 	GetFingerPrint() string
 }
+
+// RoleUserData is an UserData that carries its own roles
+// It is used by users authenticated by an external identity provider that are not stored in the AuthManager
+type RoleUserData interface {
+	UserData
+	// GetRoles returns the user roles
+	GetRoles() []string
+}
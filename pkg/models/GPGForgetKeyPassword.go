@@ -0,0 +1,5 @@
+package models
+
+type GPGForgetKeyPasswordData struct {
+	FingerPrint string `example:"0551F452ABE463A4"`
+}
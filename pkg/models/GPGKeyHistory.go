@@ -0,0 +1,137 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/quan-to/chevron/internal/tools"
+)
+
+// Key History Actions
+const (
+	KeyHistoryAdded    = "ADDED"
+	KeyHistoryUpdated  = "UPDATED"
+	KeyHistoryDeleted  = "DELETED"
+	KeyHistoryRestored = "RESTORED"
+)
+
+// GPGKeyHistory is a entry of the change history of a public key in the key store
+type GPGKeyHistory struct {
+	ID              string    `json:"id,omitempty"`
+	FullFingerprint string    `example:"0551F452ABE463A4"`
+	Action          string    `example:"UPDATED"`
+	Actor           string    `example:"10.0.0.1"` // user:<username> for requests with a valid token, otherwise the client address
+	Changes         []string  `example:"uid added: Remote Signer Test <test@quan.to>"`
+	Key             GPGKey    // Snapshot of the public key after the change
+	CreatedAt       time.Time `example:"2020-12-31T23:59:59Z"`
+}
+
+// MakeGPGKeyHistory creates a history entry with a snapshot of the specified key
+// The private key is never stored in the history
+func MakeGPGKeyHistory(key GPGKey, action string, changes []string) GPGKeyHistory {
+	snapshot := key
+	snapshot.ID = ""
+	snapshot.AsciiArmoredPrivateKey = ""
+	snapshot.ParentKey = nil
+	snapshot.Actor = ""
+
+	if changes == nil {
+		changes = []string{}
+	}
+
+	return GPGKeyHistory{
+		FullFingerprint: key.FullFingerprint,
+		Action:          action,
+		Actor:           key.Actor,
+		Changes:         changes,
+		Key:             snapshot,
+		CreatedAt:       time.Now(),
+	}
+}
+
+func uidToString(uid GPGKeyUid) string {
+	s := uid.Name
+	if uid.Description != "" {
+		s += fmt.Sprintf(" (%s)", uid.Description)
+	}
+	if uid.Email != "" {
+		s += fmt.Sprintf(" <%s>", uid.Email)
+	}
+	return strings.TrimSpace(s)
+}
+
+func containsUID(uids []GPGKeyUid, uid GPGKeyUid) bool {
+	for _, v := range uids {
+		if strings.EqualFold(v.Name, uid.Name) && strings.EqualFold(v.Email, uid.Email) && v.Description == uid.Description {
+			return true
+		}
+	}
+	return false
+}
+
+func containsSubkey(subkeys []string, fp string) bool {
+	for _, v := range subkeys {
+		if tools.CompareFingerPrint(v, fp) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameExpiration(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func expirationToString(t *time.Time) string {
+	if t == nil {
+		return "never"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// DiffGPGKeys returns a human readable list of changes between two versions of a public key
+func DiffGPGKeys(old, new GPGKey) []string {
+	changes := make([]string, 0)
+
+	if old.AsciiArmoredPublicKey != new.AsciiArmoredPublicKey {
+		changes = append(changes, "public key changed")
+	}
+
+	if old.KeyBits != new.KeyBits {
+		changes = append(changes, fmt.Sprintf("key bits changed: %d -> %d", old.KeyBits, new.KeyBits))
+	}
+
+	if !sameExpiration(old.ExpiresAt, new.ExpiresAt) {
+		changes = append(changes, fmt.Sprintf("expiration changed: %s -> %s", expirationToString(old.ExpiresAt), expirationToString(new.ExpiresAt)))
+	}
+
+	for _, uid := range new.KeyUids {
+		if !containsUID(old.KeyUids, uid) {
+			changes = append(changes, "uid added: "+uidToString(uid))
+		}
+	}
+
+	for _, uid := range old.KeyUids {
+		if !containsUID(new.KeyUids, uid) {
+			changes = append(changes, "uid removed: "+uidToString(uid))
+		}
+	}
+
+	for _, fp := range new.Subkeys {
+		if !containsSubkey(old.Subkeys, fp) {
+			changes = append(changes, "subkey added: "+fp)
+		}
+	}
+
+	for _, fp := range old.Subkeys {
+		if !containsSubkey(new.Subkeys, fp) {
+			changes = append(changes, "subkey removed: "+fp)
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,237 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Search sort fields
+const (
+	SearchSortRelevance   = "relevance"
+	SearchSortFingerPrint = "fingerPrint"
+	SearchSortName        = "name"
+	SearchSortEmail       = "email"
+	SearchSortKeyBits     = "keyBits"
+	SearchSortExpiresAt   = "expiresAt"
+)
+
+// Search sort orders
+const (
+	SearchOrderAsc  = "asc"
+	SearchOrderDesc = "desc"
+)
+
+// DefaultSearchLimit is the number of keys returned by a search if no limit is specified
+const DefaultSearchLimit = 100
+
+// MaxSearchLimit is the maximum number of keys returned by a single search call
+const MaxSearchLimit = 1000
+
+// DefaultFuzzyThreshold is the minimum trigram similarity for a fuzzy match. Same as PostgreSQL pg_trgm default
+const DefaultFuzzyThreshold = 0.3
+
+// NeverExpires is the expiration date used to sort keys that does not expire
+var NeverExpires = time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC)
+
+// GPGKeySearchQuery describes a search over the public key store
+//
+// Query is matched against the key UIDs and fingerprint:
+//   - "@partner.com" finds all keys with an email on that domain
+//   - every word of the query should prefix a word of the same UID name ("joh sm" finds "John Smith")
+//   - the whole query can prefix a UID email ("john.s" finds "john.smith@partner.com")
+//   - an hexadecimal query with 8 or more digits matches the end of the key fingerprint
+//   - when Fuzzy is true names and emails with a trigram similarity >= DefaultFuzzyThreshold also match
+//
+// An empty Query matches all keys, so the search can be used only with filters
+type GPGKeySearchQuery struct {
+	Query          string     `example:"@quan.to"`
+	Fuzzy          bool       `example:"false"`
+	MinKeyBits     int        `example:"2048"`
+	MaxKeyBits     int        `example:"0"`
+	Algorithm      string     `example:"RSA"`
+	ExpiresAfter   *time.Time `example:"2020-12-31T23:59:59Z"`
+	ExpiresBefore  *time.Time
+	ExcludeExpired bool   `example:"true"`
+	SortBy         string `example:"name"`
+	SortOrder      string `example:"asc"`
+	Cursor         string `example:""`
+	Limit          int    `example:"100"`
+}
+
+// GPGKeySearchResult is a page of a public key search
+type GPGKeySearchResult struct {
+	Keys       []GPGKey
+	Total      int    `example:"1"`
+	NextCursor string `json:",omitempty" example:""`
+}
+
+// Normalized returns a copy of the query with the default values filled
+func (q GPGKeySearchQuery) Normalized() GPGKeySearchQuery {
+	q.Query = strings.TrimSpace(q.Query)
+
+	if q.SortBy == "" {
+		q.SortBy = SearchSortFingerPrint
+		if q.Fuzzy && q.Query != "" {
+			q.SortBy = SearchSortRelevance
+		}
+	}
+
+	if q.SortOrder == "" {
+		q.SortOrder = SearchOrderAsc
+		if q.SortBy == SearchSortRelevance {
+			q.SortOrder = SearchOrderDesc
+		}
+	}
+
+	if q.Limit <= 0 {
+		q.Limit = DefaultSearchLimit
+	}
+
+	return q
+}
+
+// Validate checks if the query is valid. Should be called over a normalized query
+func (q GPGKeySearchQuery) Validate() error {
+	switch q.SortBy {
+	case SearchSortRelevance, SearchSortFingerPrint, SearchSortName, SearchSortEmail, SearchSortKeyBits, SearchSortExpiresAt:
+	default:
+		return fmt.Errorf("invalid sortBy %q", q.SortBy)
+	}
+
+	if q.SortOrder != SearchOrderAsc && q.SortOrder != SearchOrderDesc {
+		return fmt.Errorf("invalid sortOrder %q", q.SortOrder)
+	}
+
+	if q.Limit > MaxSearchLimit {
+		return fmt.Errorf("limit should be less or equal than %d", MaxSearchLimit)
+	}
+
+	if q.MinKeyBits < 0 || q.MaxKeyBits < 0 || (q.MaxKeyBits > 0 && q.MinKeyBits > q.MaxKeyBits) {
+		return fmt.Errorf("invalid key bits range")
+	}
+
+	if q.Cursor != "" {
+		_, err := q.DecodeCursor()
+		return err
+	}
+
+	return nil
+}
+
+// Domain returns the lowercase email domain (including the @) if the query is a domain query
+func (q GPGKeySearchQuery) Domain() string {
+	if len(q.Query) > 1 && q.Query[0] == '@' && !strings.ContainsAny(q.Query, " \t") {
+		return strings.ToLower(q.Query)
+	}
+	return ""
+}
+
+// Terms returns the lowercase words of the query, ignoring any non letter or digit characters
+func (q GPGKeySearchQuery) Terms() []string {
+	return SearchWords(q.Query)
+}
+
+// FingerPrint returns the uppercase fingerprint if the query looks like one (hexadecimal with at least 8 digits)
+func (q GPGKeySearchQuery) FingerPrint() string {
+	fp := strings.ToUpper(strings.ReplaceAll(q.Query, " ", ""))
+	fp = strings.TrimPrefix(fp, "0X")
+
+	if len(fp) < 8 {
+		return ""
+	}
+
+	for _, c := range fp {
+		if !strings.ContainsRune("0123456789ABCDEF", c) {
+			return ""
+		}
+	}
+
+	return fp
+}
+
+// SearchWords splits the value in lowercase words of letters and digits
+func SearchWords(value string) []string {
+	return strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// GPGKeySearchCursor is the decoded content of a search cursor
+// It points to the last key of the previous page
+type GPGKeySearchCursor struct {
+	SortBy      string `json:"s"`
+	Value       string `json:"v"`
+	FingerPrint string `json:"f"`
+}
+
+// Encode returns the opaque string representation of the cursor
+func (c GPGKeySearchCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor decodes the query cursor and checks if it was generated by a search with the same sorting
+func (q GPGKeySearchQuery) DecodeCursor() (*GPGKeySearchCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	var c GPGKeySearchCursor
+	err = json.Unmarshal(data, &c)
+	if err != nil || c.FingerPrint == "" {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	if c.SortBy != q.SortBy {
+		return nil, fmt.Errorf("cursor was generated for sortBy %q", c.SortBy)
+	}
+
+	_, err = ParseSearchSortValue(c.SortBy, c.Value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	return &c, nil
+}
+
+// FormatSearchSortValue formats a sort value to be stored in a cursor
+func FormatSearchSortValue(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case []byte:
+		return string(t)
+	case int:
+		return strconv.Itoa(t)
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case float32:
+		return strconv.FormatFloat(float64(t), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(t, 'g', -1, 64)
+	case time.Time:
+		return t.UTC().Format(time.RFC3339Nano)
+	}
+
+	return fmt.Sprintf("%v", v)
+}
+
+// ParseSearchSortValue parses a cursor sort value to the type used by the specified sort field
+func ParseSearchSortValue(sortBy, value string) (interface{}, error) {
+	switch sortBy {
+	case SearchSortKeyBits:
+		return strconv.Atoi(value)
+	case SearchSortRelevance:
+		return strconv.ParseFloat(value, 64)
+	case SearchSortExpiresAt:
+		return time.Parse(time.RFC3339Nano, value)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,23 @@
+package models
+
+// HealthStatus is the result of the liveness and readiness checks
+type HealthStatus struct {
+	Status     string                     `example:"ok"`
+	Components map[string]ComponentHealth `json:",omitempty"`
+}
+
+// ComponentHealth is the result of the check of a single component
+type ComponentHealth struct {
+	Status    string      `example:"ok"`
+	LatencyMs float64     `example:"1.25"`
+	Error     string      `json:",omitempty" example:"connection refused"`
+	Details   interface{} `json:",omitempty"`
+}
+
+// PrivateKeysHealth is the state of the private keys loaded in the server
+type PrivateKeysHealth struct {
+	Unlocked        int      `example:"1"`
+	Locked          int      `example:"0"`
+	RequiredLocked  []string `json:",omitempty" example:"0551F452ABE463A4"`
+	RequiredMissing []string `json:",omitempty" example:"0551F452ABE463A4"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// TokenRevocation invalidates tokens issued by an external identity provider
+// If TokenHash is set, only the token with that hash is revoked until Expiration.
+// Otherwise all tokens of Username issued before CreatedAt are revoked
+type TokenRevocation struct {
+	ID         string `json:"id,omitempty"`
+	TokenHash  string
+	Username   string
+	CreatedAt  time.Time
+	Expiration time.Time
+}
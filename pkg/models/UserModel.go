@@ -2,6 +2,29 @@ package models
 
 import "time"
 
+// Agent user roles
+const (
+	// RoleAdmin can manage users, roles and generate tokens for any key
+	RoleAdmin = "admin"
+	// RoleOperator can list users and tokens and generate tokens for its own key
+	RoleOperator = "operator"
+	// RoleSigner can only login and sign with its own key
+	RoleSigner = "signer"
+)
+
+// Roles is the list of all valid user roles
+var Roles = []string{RoleAdmin, RoleOperator, RoleSigner}
+
+// IsValidRole returns true if the role is one of the known roles
+func IsValidRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
 type User struct {
 	ID          string `json:"id,omitempty"`
 	Fingerprint string
@@ -9,6 +32,35 @@ type User struct {
 	Password    string
 	FullName    string
 	CreatedAt   time.Time
+	Disabled    bool
+	Roles       []string
+	// TOTPSecret is the encrypted TOTP secret of the user
+	TOTPSecret string
+	// TOTPEnabled is true when the user confirmed the TOTP enrollment and must inform an OTP to login
+	TOTPEnabled bool
+	// TOTPLastStep is the time step of the last accepted OTP. Codes of this or earlier steps are rejected
+	TOTPLastStep int64
+}
+
+// GetRoles returns the roles of the user
+// Users created before roles were introduced have no roles stored and are considered signers
+func (u User) GetRoles() []string {
+	if len(u.Roles) == 0 {
+		return []string{RoleSigner}
+	}
+	return u.Roles
+}
+
+// HasRole returns true if the user has any of the specified roles
+func (u User) HasRole(roles ...string) bool {
+	for _, ur := range u.GetRoles() {
+		for _, r := range roles {
+			if ur == r {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // GetID returns the id
@@ -0,0 +1,23 @@
+package graphql
+
+import "github.com/graphql-go/graphql"
+
+// TOTPEnrollment is the result of a TOTP enrollment
+type TOTPEnrollment struct {
+	Secret          string
+	ProvisioningURI string
+}
+
+var GraphQLTOTPEnrollment = graphql.NewObject(graphql.ObjectConfig{
+	Name: "TOTPEnrollment",
+	Fields: graphql.Fields{
+		"Secret": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Base32 encoded TOTP secret to be added in the authenticator app",
+		},
+		"ProvisioningURI": &graphql.Field{
+			Type:        graphql.String,
+			Description: "otpauth:// URI of the secret. Usually displayed as a QR Code",
+		},
+	},
+})
@@ -0,0 +1,66 @@
+package graphql
+
+import (
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/quan-to/chevron/pkg/models"
+)
+
+// TokenInfo describes an active token without its value
+type TokenInfo struct {
+	ID                    string
+	UserName              string
+	UserFullName          string
+	FingerPrint           string
+	CreatedAt             string
+	Expiration            int64
+	ExpirationDateTimeISO string
+}
+
+// TokenInfoFromModel converts a models.UserToken to its GraphQL representation
+func TokenInfoFromModel(ut models.UserToken) TokenInfo {
+	return TokenInfo{
+		ID:                    ut.ID,
+		UserName:              ut.Username,
+		UserFullName:          ut.Fullname,
+		FingerPrint:           ut.Fingerprint,
+		CreatedAt:             ut.CreatedAt.Format(time.RFC3339),
+		Expiration:            ut.Expiration.UnixNano() / 1e6, // ms
+		ExpirationDateTimeISO: ut.Expiration.Format(time.RFC3339),
+	}
+}
+
+var GraphQLTokenInfo = graphql.NewObject(graphql.ObjectConfig{
+	Name: "TokenInfo",
+	Fields: graphql.Fields{
+		"ID": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Token ID. Empty if the token manager does not store ids",
+		},
+		"UserName": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Name of the user this token belongs",
+		},
+		"UserFullName": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Full name of the user",
+		},
+		"FingerPrint": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Fingerprint of the key this token has access",
+		},
+		"CreatedAt": &graphql.Field{
+			Type:        graphql.String,
+			Description: "ISO DateTime when this token was created",
+		},
+		"Expiration": &graphql.Field{
+			Type:        graphql.Float,
+			Description: "Unix Epoch Timestamp when this token expires (in milisseconds)",
+		},
+		"ExpirationDateTimeISO": &graphql.Field{
+			Type:        graphql.String,
+			Description: "ISO DateTime when this token expires",
+		},
+	},
+})
@@ -0,0 +1,89 @@
+package graphql
+
+import (
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/quan-to/chevron/pkg/models"
+)
+
+type User struct {
+	Username    string
+	FullName    string
+	FingerPrint string
+	Disabled    bool
+	Roles       []string
+	TOTPEnabled bool
+	CreatedAt   string
+}
+
+// UserFromModel converts a models.User to its GraphQL representation
+func UserFromModel(um models.User) User {
+	u := User{
+		Username:    um.Username,
+		FullName:    um.FullName,
+		FingerPrint: um.Fingerprint,
+		Disabled:    um.Disabled,
+		Roles:       um.GetRoles(),
+		TOTPEnabled: um.TOTPEnabled,
+	}
+
+	if !um.CreatedAt.IsZero() {
+		u.CreatedAt = um.CreatedAt.Format(time.RFC3339)
+	}
+
+	return u
+}
+
+var GraphQLUser = graphql.NewObject(graphql.ObjectConfig{
+	Name: "User",
+	Fields: graphql.Fields{
+		"Username": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Login of the user",
+		},
+		"FullName": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Full name of the user",
+		},
+		"FingerPrint": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Fingerprint of the key user has access",
+		},
+		"Disabled": &graphql.Field{
+			Type:        graphql.Boolean,
+			Description: "If the user is disabled. Disabled users cannot login",
+		},
+		"Roles": &graphql.Field{
+			Type:        graphql.NewList(graphql.String),
+			Description: "Roles of the user",
+		},
+		"TOTPEnabled": &graphql.Field{
+			Type:        graphql.Boolean,
+			Description: "If the user is enrolled in TOTP and needs to inform an OTP to login",
+		},
+		"CreatedAt": &graphql.Field{
+			Type:        graphql.String,
+			Description: "ISO DateTime when the user was created. Empty if unknown",
+		},
+	},
+})
+
+var GraphQLRole = graphql.NewEnum(graphql.EnumConfig{
+	Name:        "Role",
+	Description: "Role of an agent user",
+	Values: graphql.EnumValueConfigMap{
+		models.RoleAdmin: &graphql.EnumValueConfig{
+			Value:       models.RoleAdmin,
+			Description: "Can manage users, roles and generate tokens for any key",
+		},
+		models.RoleOperator: &graphql.EnumValueConfig{
+			Value:       models.RoleOperator,
+			Description: "Can list users and tokens and generate tokens for its own key",
+		},
+		models.RoleSigner: &graphql.EnumValueConfig{
+			Value:       models.RoleSigner,
+			Description: "Can only login and sign with its own key",
+		},
+	},
+})
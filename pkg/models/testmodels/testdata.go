@@ -26,6 +26,7 @@ var GpgKey = models.GPGKey{
 		},
 	},
 	KeyBits:                1234,
+	KeyAlgorithm:           "RSA",
 	Subkeys:                []string{"BABABEBE"},
 	AsciiArmoredPublicKey:  "PUBKEY",
 	AsciiArmoredPrivateKey: "PRIVKEY",
@@ -39,6 +40,7 @@ var User = models.User{
 	Fingerprint: "DEADBEEFDEADBEEF",
 	Password:    "I think you will never guess",
 	CreatedAt:   time.Now().Truncate(time.Second),
+	Roles:       []string{models.RoleSigner},
 }
 
 // Time is a time constant used for tests
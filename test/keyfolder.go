@@ -0,0 +1,36 @@
+package test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+)
+
+// TestKeyFile is the file of the private test key in the test data folder
+const TestKeyFile = "testkey_privateTestKey.gpg"
+
+// TestKeyPasswordFile is the file with the password of the test key in the test data folder
+const TestKeyPasswordFile = "testprivatekeyPassword.txt"
+
+// MakeKeyFolder creates a temporary folder with a copy of the test key and its password from dataFolder.
+// Use it as the private key and master key folders, so the keys saved by the tests are not written in the test data folder
+func MakeKeyFolder(dataFolder string) (string, error) {
+	folder, err := ioutil.TempDir("", "chevron-test")
+	if err != nil {
+		return "", err
+	}
+
+	for _, name := range []string{TestKeyFile, TestKeyPasswordFile} {
+		data, err := ioutil.ReadFile(path.Join(dataFolder, name))
+		if err == nil {
+			err = ioutil.WriteFile(path.Join(folder, name), data, 0600)
+		}
+
+		if err != nil {
+			_ = os.RemoveAll(folder)
+			return "", err
+		}
+	}
+
+	return folder, nil
+}